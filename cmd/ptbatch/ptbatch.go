@@ -0,0 +1,293 @@
+package ptbatch
+
+/* ptbatch runs another pt command once for each of many objects. The IDs are the arguments before --,
+the IDs of an --ids-from file, or every object of the pairtree with --all, and the command and its
+arguments follow --, with {} standing for the ID. The runs happen --jobs at a time, and the output of
+each one is written once it finishes, followed by whether it succeeded, so the output of runs never
+interleaves. One run that fails does not stop the others. */
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pairtree"
+	"github.com/UCLALibrary/pt-tools/utils"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// placeholder is the argument of the command, or the part of one, that is replaced by each ID
+const placeholder = "{}"
+
+var (
+	// Logger is the logger each run of pt batch starts from, tests replace it to capture the logs
+	Logger *zap.Logger = utils.ConsoleLogger()
+)
+
+// Commands creates the pt commands that pt batch can run, writing their output to the writer. Each run
+// gets new commands, so the runs keep their flags and arguments apart.
+type Commands func(writer io.Writer) []*cobra.Command
+
+// command holds the flags and arguments of one run of pt batch so that runs can happen concurrently
+type command struct {
+	all        bool
+	jobs       int
+	outputJSON bool
+	ptRoot     string
+	ids        []string
+	args       []string
+	commands   Commands
+	logger     *zap.Logger
+	out        *utils.Output
+}
+
+func (c *command) initFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&c.all, "all", false, "run the command for every object of the pairtree")
+	cmd.Flags().IntVar(&c.jobs, "jobs", 1, "Commands run at once")
+	cmd.Flags().BoolVarP(&c.outputJSON, "j", "j", false, "output the report in JSON format")
+}
+
+// NewCommand creates the batch subcommand of pt that runs the commands made by commands and writes its
+// output to the writer
+func NewCommand(writer io.Writer, commands Commands) *cobra.Command {
+	c := &command{commands: commands, logger: Logger, out: utils.NewOutput(io.Discard, &utils.Styler{}, false)}
+
+	var cmd = &cobra.Command{
+		Use:   "batch [FLAGS] [ID]... -- [COMMAND] [ARGS]...",
+		Short: "pt batch runs a pt command for each of many objects",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return utils.ConfigureLogger(cmd, &c.logger)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+
+			c.out = utils.OutputFromFlags(cmd, writer)
+
+			if c.ptRoot, err = utils.GetPtRoot(cmd, writer); err != nil {
+				return err
+			}
+
+			dash := cmd.ArgsLenAtDash()
+			if dash < 0 || dash == len(args) {
+				c.out.Error("Please provide the command to run after --")
+				c.logger.Error("Error getting the command", zap.Error(error_msgs.Err17))
+
+				return fmt.Errorf("%w: the command to run must follow --", error_msgs.Err17)
+			}
+			c.ids, c.args = slices.Clone(args[:dash]), args[dash:]
+
+			if !slices.ContainsFunc(c.commands(io.Discard), func(sub *cobra.Command) bool {
+				return sub.Name() == c.args[0] || slices.Contains(sub.Aliases, c.args[0])
+			}) {
+				err := fmt.Errorf("%w: pt batch can not run %q", error_msgs.Err17, c.args[0])
+				c.logger.Error("Error parsing pt batch", zap.Error(err))
+
+				return err
+			}
+
+			if c.jobs < 1 {
+				err := fmt.Errorf("%w: --jobs must be at least 1", error_msgs.Err17)
+				c.logger.Error("Error parsing pt batch", zap.Error(err))
+
+				return err
+			}
+
+			ids, err := utils.IDsFromFlags(cmd)
+			if err != nil {
+				c.logger.Error("Error reading IDs", zap.Error(err))
+				return err
+			}
+			c.ids = unique(append(c.ids, ids...))
+
+			if len(c.ids) > 0 && c.all {
+				err := fmt.Errorf("%w: IDs can not be given with --all", error_msgs.Err17)
+				c.logger.Error("Error parsing pt batch", zap.Error(err))
+
+				return err
+			} else if len(c.ids) == 0 && !c.all {
+				c.out.Error("Please provide an ID for the pairtree")
+				c.logger.Error("Error getting ID", zap.Error(error_msgs.Err6))
+
+				return error_msgs.Err6
+			}
+
+			// The persistent --json flag is the same as -j
+			if jsonFlag, _ := cmd.Flags().GetBool(utils.JSONFlag); jsonFlag {
+				c.outputJSON = true
+			}
+
+			utils.SilenceUsage(cmd)
+
+			return c.batch(cmd, writer)
+		},
+	}
+
+	c.initFlags(cmd)
+	utils.AddIDsFromFlag(cmd)
+
+	return cmd
+}
+
+// Run executes pt batch with the given arguments, running the commands made by commands
+func Run(args []string, writer io.Writer, commands Commands) error {
+	if err := utils.RunSubcommand(NewCommand(writer, commands), args, writer); err != nil {
+		Logger.Error("Error running pt batch", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// batch runs the command for each ID as the operations of a Batch and reports each run as it finishes
+func (c *command) batch(cmd *cobra.Command, writer io.Writer) error {
+	ctx := cmd.Context()
+
+	if c.all {
+		pt, err := pairtree.Open(c.ptRoot)
+		if err != nil {
+			c.logger.Error("Error opening the pairtree", zap.Error(err))
+			return err
+		}
+
+		if err := pt.WalkObjectsCtx(ctx, func(id, objPath string) error {
+			c.ids = append(c.ids, id)
+			return nil
+		}); err != nil {
+			c.logger.Error("Error walking the pairtree", zap.Error(err))
+			return err
+		}
+	}
+
+	flags := c.forwardedFlags(cmd)
+	input := c.input(cmd)
+
+	// The output of each run is kept until it finishes, when it is written in one piece
+	outputs := make([]bytes.Buffer, len(c.ids))
+	indexes := make(map[string]int, len(c.ids))
+	operations := make([]pairtree.Operation, 0, len(c.ids))
+	for i, id := range c.ids {
+		indexes[id] = i
+		operations = append(operations, pairtree.Operation{ID: id, Run: func(ctx context.Context) error {
+			return c.run(ctx, append(slices.Clone(flags), expand(c.args, id)...), input, &outputs[i])
+		}})
+	}
+
+	name := "pt " + c.args[0]
+	batch := pairtree.NewBatch(c.jobs)
+	if !c.outputJSON {
+		batch.OnProgress = func(progress pairtree.Progress) {
+			last := progress.Last
+			if output := &outputs[indexes[last.ID]]; output.Len() > 0 {
+				_, _ = output.WriteTo(writer)
+			}
+
+			if last.Err != nil {
+				c.out.Error("Could not run %s for %s", name, last.ID)
+			} else {
+				c.out.Success("Ran %s for %s", name, last.ID)
+			}
+		}
+	}
+
+	results, err := batch.Run(ctx, operations)
+	if err != nil {
+		c.logger.Error("Error running the batch", zap.Error(err))
+	}
+
+	if c.outputJSON {
+		jsonData, jsonErr := json.MarshalIndent(results, "", "  ")
+		if jsonErr != nil {
+			c.logger.Error("Error converting the report to JSON", zap.Error(jsonErr))
+			return errors.Join(err, jsonErr)
+		}
+
+		fmt.Fprintln(writer, string(jsonData))
+	} else {
+		ran := 0
+		for _, result := range results {
+			if result.Err == nil {
+				ran++
+			}
+		}
+		c.out.Info("Ran %s for %d of %d objects", name, ran, len(results))
+	}
+
+	return err
+}
+
+// run executes the pt command with the arguments under a new root command that writes to the output
+func (c *command) run(ctx context.Context, args []string, input io.Reader, output io.Writer) error {
+	rootCmd := utils.NewRootCmd(output)
+	rootCmd.SetIn(input)
+	rootCmd.AddCommand(c.commands(output)...)
+	rootCmd.SetArgs(args)
+
+	_, err := utils.Execute(ctx, rootCmd)
+	return err
+}
+
+// forwardedFlags returns the flags of pt batch that each run is given too. The pairtree is always given,
+// so the runs use the root pt batch found even when it came from the environment.
+func (c *command) forwardedFlags(cmd *cobra.Command) []string {
+	flags := []string{"--" + utils.PairtreeFlag + "=" + c.ptRoot}
+
+	for _, name := range []string{utils.YesFlag, utils.QuietFlag, utils.NoColorFlag, utils.LangFlag, utils.LogLevelFlag, utils.VerboseFlag} {
+		if flag := cmd.Flags().Lookup(name); flag != nil && flag.Changed {
+			flags = append(flags, "--"+name+"="+flag.Value.String())
+		}
+	}
+
+	return flags
+}
+
+// input returns the input the runs read the answers to their prompts from. Runs can not share the terminal
+// to ask, so from a terminal they are only confirmed with -y. Otherwise they get the input of pt batch and
+// go ahead without asking, like the command does when it is not run from a terminal.
+func (c *command) input(cmd *cobra.Command) io.Reader {
+	if file, ok := cmd.InOrStdin().(*os.File); ok && !utils.IsTerminal(file) {
+		return file
+	}
+
+	return strings.NewReader("")
+}
+
+// expand returns the arguments of the command with {} replaced by the ID, or with the ID after them when
+// none of them has {}
+func expand(args []string, id string) []string {
+	expanded := make([]string, len(args))
+	replaced := false
+	for i, arg := range args {
+		if strings.Contains(arg, placeholder) {
+			arg, replaced = strings.ReplaceAll(arg, placeholder, id), true
+		}
+		expanded[i] = arg
+	}
+
+	if !replaced {
+		expanded = append(expanded, id)
+	}
+
+	return expanded
+}
+
+// unique returns the IDs without the ones listed before, so that no object is run more than once
+func unique(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	kept := ids[:0]
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			kept = append(kept, id)
+		}
+	}
+
+	return kept
+}
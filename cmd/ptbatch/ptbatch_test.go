@@ -0,0 +1,123 @@
+package ptbatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pairtree"
+	"github.com/UCLALibrary/pt-tools/pkg/pttest"
+	"github.com/UCLALibrary/pt-tools/utils"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const root = "--pairtree="
+
+// TestMain runs the tests with the logger of the test sink, it is set once so that the tests can run in parallel
+func TestMain(m *testing.M) {
+	logger, cleanup := pttest.SetupLogger()
+	Logger = logger
+
+	code := m.Run()
+	cleanup()
+	os.Exit(code)
+}
+
+// echoCommands makes an echo command that writes the pairtree root and its arguments, and that fails
+// for ark:/fail as if the object did not exist
+func echoCommands(writer io.Writer) []*cobra.Command {
+	return []*cobra.Command{{
+		Use: "echo",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if slices.Contains(args, "ark:/fail") {
+				return fmt.Errorf("%w: ark:/fail", error_msgs.Err47)
+			}
+
+			ptRoot, _ := cmd.Flags().GetString(utils.PairtreeFlag)
+			fmt.Fprintf(writer, "%s: %s\n", ptRoot, strings.Join(args, " "))
+			return nil
+		},
+	}}
+}
+
+// TestBatch tests that the command is run for each ID with {} replaced by it, and each run is reported
+func TestBatch(t *testing.T) {
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, afero.NewOsFs())
+
+	var buf bytes.Buffer
+	args := []string{root + ptRoot, "--jobs", "4", "ark:/a", "ark:/b", "ark:/a", "ark:/c", "--", "echo", "x", "{}/y"}
+	require.NoError(t, Run(args, &buf, echoCommands))
+	for _, id := range []string{"ark:/a", "ark:/b", "ark:/c"} {
+		assert.Contains(t, buf.String(), ptRoot+": x "+id+"/y\n")
+		assert.Contains(t, buf.String(), "Ran pt echo for "+id+"\n")
+	}
+	assert.Contains(t, buf.String(), "Ran pt echo for 3 of 3 objects\n")
+
+	// Without {} the ID follows the arguments, and --all runs every object of the pairtree
+	buf.Reset()
+	require.NoError(t, Run([]string{root + ptRoot, "--all", "--", "echo", "-"}, &buf, echoCommands))
+	for _, id := range []string{"ark:/a5388", "ark:/a5488", "ark:/a54892", "ark:/b5488"} {
+		assert.Contains(t, buf.String(), ptRoot+": - "+id+"\n")
+	}
+	assert.Contains(t, buf.String(), "Ran pt echo for 4 of 4 objects\n")
+}
+
+// TestBatchFailure tests that a run that fails does not stop the others and is returned with its ID
+func TestBatchFailure(t *testing.T) {
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, afero.NewOsFs())
+
+	var buf bytes.Buffer
+	err := Run([]string{root + ptRoot, "ark:/fail", "ark:/b", "--", "echo"}, &buf, echoCommands)
+	assert.ErrorIs(t, err, error_msgs.Err47)
+	assert.Contains(t, buf.String(), "Could not run pt echo for ark:/fail\n")
+	assert.Contains(t, buf.String(), ptRoot+": ark:/b\n")
+	assert.Contains(t, buf.String(), "Ran pt echo for 1 of 2 objects\n")
+
+	// The report is written as JSON with -j
+	buf.Reset()
+	err = Run([]string{root + ptRoot, "-j", "ark:/fail", "ark:/b", "--", "echo"}, &buf, echoCommands)
+	assert.ErrorIs(t, err, error_msgs.Err47)
+
+	var results []pairtree.Result
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &results))
+	require.Len(t, results, 2)
+	assert.Equal(t, "ark:/fail", results[0].ID)
+	assert.Contains(t, results[0].Error, error_msgs.Err47.Error())
+	assert.Empty(t, results[1].Error)
+}
+
+// TestCLIError tests that the IDs and the command are checked before anything is run
+func TestCLIError(t *testing.T) {
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, afero.NewOsFs())
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected error
+	}{
+		{name: "No command", args: []string{"ark:/b5488"}, expected: error_msgs.Err17},
+		{name: "Empty command", args: []string{"ark:/b5488", "--"}, expected: error_msgs.Err17},
+		{name: "Unknown command", args: []string{"ark:/b5488", "--", "unknown"}, expected: error_msgs.Err17},
+		{name: "No IDs", args: []string{"--", "echo"}, expected: error_msgs.Err6},
+		{name: "IDs with --all", args: []string{"--all", "ark:/b5488", "--", "echo"}, expected: error_msgs.Err17},
+		{name: "No jobs", args: []string{"--jobs", "0", "ark:/b5488", "--", "echo"}, expected: error_msgs.Err17},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := Run(append([]string{root + ptRoot}, test.args...), &buf, echoCommands)
+			assert.ErrorIs(t, err, test.expected)
+			assert.NotContains(t, buf.String(), "Ran pt echo")
+		})
+	}
+}
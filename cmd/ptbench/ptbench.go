@@ -0,0 +1,333 @@
+package ptbench
+
+/* ptbench generates a synthetic pairtree of a configurable size and measures how long ls, cp,
+archive, and rm take on each of its objects, so storage backends can be compared and performance
+regressions caught. The pairtree is created in a temporary directory, or in --dir to measure a
+particular filesystem, and is removed when the benchmark finishes. */
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pairtree"
+	"github.com/UCLALibrary/pt-tools/utils"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// Operations that can be benchmarked, in the order they are run
+const (
+	OpLs      = "ls"
+	OpCp      = "cp"
+	OpArchive = "archive"
+	OpRm      = "rm"
+)
+
+// prefix is the prefix of the generated pairtree
+const prefix = "ark:/"
+
+var (
+	// Logger is the logger each run of pt bench starts from, tests replace it to capture the logs
+	Logger *zap.Logger = utils.ConsoleLogger()
+)
+
+// command holds the flags of one run of pt bench so that runs can happen concurrently
+type command struct {
+	objects  int
+	files    int
+	size     int
+	ops      []string
+	benchDir string
+	logger   *zap.Logger
+	out      *utils.Output
+}
+
+// Result is the timing of one operation over every object in the pairtree
+type Result struct {
+	Op        string        `json:"op"`
+	Count     int           `json:"count"`
+	Bytes     int64         `json:"bytes"`
+	Total     time.Duration `json:"total_ns"`
+	P50       time.Duration `json:"p50_ns"`
+	P90       time.Duration `json:"p90_ns"`
+	P99       time.Duration `json:"p99_ns"`
+	Max       time.Duration `json:"max_ns"`
+	OpsPerSec float64       `json:"ops_per_sec"`
+	MBPerSec  float64       `json:"mb_per_sec"`
+}
+
+// Report is the benchmark settings and the result of each operation
+type Report struct {
+	Objects int      `json:"objects"`
+	Files   int      `json:"files"`
+	Size    int      `json:"size"`
+	Results []Result `json:"results"`
+}
+
+func (c *command) initFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVarP(&c.objects, "objects", "n", 100, "Number of objects to generate")
+	cmd.Flags().IntVarP(&c.files, "files", "f", 10, "Number of files in each object")
+	cmd.Flags().IntVarP(&c.size, "size", "s", 4096, "Size of each file in bytes")
+	cmd.Flags().StringSliceVarP(&c.ops, "ops", "o", []string{OpLs, OpCp, OpArchive, OpRm},
+		"Operations to measure (ls, cp, archive, rm)")
+	cmd.Flags().StringVar(&c.benchDir, "dir", "", "Directory to generate the pairtree in (defaults to a temporary directory)")
+}
+
+// NewCommand creates the bench subcommand of pt that writes its output to the writer
+func NewCommand(writer io.Writer) *cobra.Command {
+	c := &command{logger: Logger, out: utils.NewOutput(io.Discard, &utils.Styler{}, false)}
+
+	var cmd = &cobra.Command{
+		Use:   "bench [FLAGS]",
+		Short: "pt bench measures the speed of pt operations on a generated pairtree",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return utils.ConfigureLogger(cmd, &c.logger)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c.out = utils.OutputFromFlags(cmd, writer)
+
+			if len(args) > 0 {
+				c.out.Error("Too many arguments were provided to %s", "pt bench")
+				c.logger.Error("Error parsing pt bench", zap.Error(error_msgs.Err8))
+
+				return error_msgs.Err8
+			}
+
+			if c.objects < 1 || c.files < 0 || c.size < 0 {
+				c.logger.Error("Error parsing pt bench", zap.Error(error_msgs.Err25))
+				return error_msgs.Err25
+			}
+
+			for _, op := range c.ops {
+				if !isOp(op) {
+					c.logger.Error("Error parsing pt bench", zap.String("op", op), zap.Error(error_msgs.Err24))
+					return fmt.Errorf("%w: %s", error_msgs.Err24, op)
+				}
+			}
+
+			jsonFlag, _ := cmd.Flags().GetBool(utils.JSONFlag)
+
+			utils.SilenceUsage(cmd)
+
+			return c.bench(cmd.Context(), writer, jsonFlag)
+		},
+	}
+
+	c.initFlags(cmd)
+
+	return cmd
+}
+
+// Run executes pt bench with the given arguments
+func Run(args []string, writer io.Writer) error {
+	if err := utils.RunSubcommand(NewCommand(writer), args, writer); err != nil {
+		Logger.Error("Error running pt bench", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// isOp checks if the operation is one that can be benchmarked
+func isOp(op string) bool {
+	switch op {
+	case OpLs, OpCp, OpArchive, OpRm:
+		return true
+	default:
+		return false
+	}
+}
+
+// bench generates the pairtree, measures the operations, and writes the report to the writer
+func (c *command) bench(ctx context.Context, writer io.Writer, outputJSON bool) error {
+	workDir, err := os.MkdirTemp(c.benchDir, "pt-bench-")
+	if err != nil {
+		c.logger.Error("Error creating the benchmark directory", zap.Error(err))
+		return err
+	}
+	defer os.RemoveAll(workDir)
+
+	ptRoot := filepath.Join(workDir, "pairtree")
+
+	start := time.Now()
+	ids, err := c.generate(ptRoot)
+	if err != nil {
+		c.logger.Error("Error generating the benchmark pairtree", zap.Error(err))
+		return err
+	}
+
+	// The report is the only output in JSON so it can be parsed
+	if !outputJSON {
+		c.out.Info("Generated %d objects with %d files of %d bytes in %s", c.objects, c.files, c.size,
+			time.Since(start).Round(time.Millisecond))
+	}
+
+	report := Report{Objects: c.objects, Files: c.files, Size: c.size}
+
+	// rm runs last so the other operations have objects to work on
+	for _, op := range []string{OpLs, OpCp, OpArchive, OpRm} {
+		if !slices.Contains(c.ops, op) {
+			continue
+		}
+
+		result, err := c.measure(ctx, op, ptRoot, workDir, ids)
+		if err != nil {
+			c.logger.Error("Error benchmarking operation", zap.String("op", op), zap.Error(err))
+			return err
+		}
+		report.Results = append(report.Results, result)
+	}
+
+	if outputJSON {
+		jsonData, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			c.logger.Error("Error converting the benchmark report to JSON", zap.Error(err))
+			return err
+		}
+
+		fmt.Fprintln(writer, string(jsonData))
+		return nil
+	}
+
+	writeTable(writer, report.Results)
+	return nil
+}
+
+// generate creates a pairtree in ptRoot with the configured objects and returns their IDs
+func (c *command) generate(ptRoot string) ([]string, error) {
+	if err := pairtree.CreatePairtree(ptRoot, prefix); err != nil {
+		return nil, err
+	}
+
+	content := bytes.Repeat([]byte("x"), c.size)
+	ids := make([]string, 0, c.objects)
+
+	for i := 0; i < c.objects; i++ {
+		id := fmt.Sprintf("%sbench%06d", prefix, i)
+
+		pairPath, err := pairtree.CreatePP(id, ptRoot, prefix)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := pairtree.CreateDirNotExist(pairPath); err != nil {
+			return nil, err
+		}
+
+		for j := 0; j < c.files; j++ {
+			if err := os.WriteFile(filepath.Join(pairPath, fmt.Sprintf("file%03d.txt", j)), content, 0644); err != nil {
+				return nil, err
+			}
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// measure times the operation on each object and summarizes the timings
+func (c *command) measure(ctx context.Context, op, ptRoot, workDir string, ids []string) (Result, error) {
+	destDir := filepath.Join(workDir, op)
+	if err := pairtree.CreateDirNotExist(destDir); err != nil {
+		return Result{}, err
+	}
+
+	timings := make([]time.Duration, 0, len(ids))
+
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return Result{}, err
+		}
+
+		pairPath, err := pairtree.CreatePP(id, ptRoot, prefix)
+		if err != nil {
+			return Result{}, err
+		}
+
+		start := time.Now()
+
+		switch op {
+		case OpLs:
+			_, err = pairtree.RecursiveFilesCtx(ctx, pairPath, id)
+		case OpCp:
+			_, err = pairtree.CopyFileOrFolder(ctx, pairPath, destDir, false, pairtree.CopyOptions{})
+		case OpArchive:
+			err = pairtree.TarGz(ctx, pairPath, destDir, prefix, false, pairtree.ArchiveOptions{})
+		case OpRm:
+			err = pairtree.DeletePairtreeItem(pairPath)
+		}
+
+		timings = append(timings, time.Since(start))
+
+		if err != nil {
+			return Result{}, &error_msgs.PtError{ID: id, Path: pairPath, Err: err}
+		}
+	}
+
+	return summarize(op, timings, int64(c.files)*int64(c.size)), nil
+}
+
+// summarize computes the total, percentiles, and throughput of the timings of an operation
+// where each timing covered objBytes bytes
+func summarize(op string, timings []time.Duration, objBytes int64) Result {
+	result := Result{Op: op, Count: len(timings), Bytes: objBytes * int64(len(timings))}
+	if len(timings) == 0 {
+		return result
+	}
+
+	sorted := append([]time.Duration(nil), timings...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	for _, timing := range sorted {
+		result.Total += timing
+	}
+
+	result.P50 = percentile(sorted, 50)
+	result.P90 = percentile(sorted, 90)
+	result.P99 = percentile(sorted, 99)
+	result.Max = sorted[len(sorted)-1]
+
+	if seconds := result.Total.Seconds(); seconds > 0 {
+		result.OpsPerSec = float64(result.Count) / seconds
+		result.MBPerSec = float64(result.Bytes) / (1 << 20) / seconds
+	}
+
+	return result
+}
+
+// percentile returns the nearest-rank percentile of the sorted timings
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+
+	return sorted[rank-1]
+}
+
+// writeTable writes the results as aligned columns
+func writeTable(writer io.Writer, results []Result) {
+	fmt.Fprintf(writer, "%-8s %8s %12s %12s %12s %12s %12s %10s %10s\n",
+		"op", "count", "total", "p50", "p90", "p99", "max", "ops/s", "MB/s")
+
+	for _, r := range results {
+		fmt.Fprintf(writer, "%-8s %8d %12s %12s %12s %12s %12s %10.1f %10.2f\n",
+			r.Op, r.Count, round(r.Total), round(r.P50), round(r.P90), round(r.P99), round(r.Max),
+			r.OpsPerSec, r.MBPerSec)
+	}
+}
+
+// round shortens a duration to a precision that fits in a column
+func round(d time.Duration) time.Duration {
+	return d.Round(time.Microsecond)
+}
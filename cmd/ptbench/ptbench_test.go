@@ -0,0 +1,134 @@
+package ptbench
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pttest"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMain runs the tests with the logger of the test sink, it is set once so that the tests can run in parallel
+func TestMain(m *testing.M) {
+	logger, cleanup := pttest.SetupLogger()
+	Logger = logger
+
+	code := m.Run()
+	cleanup()
+	os.Exit(code)
+}
+
+// TestBench tests that each selected operation is measured over every generated object
+func TestBench(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		expectOps []string
+	}{
+		{name: "all operations", args: []string{}, expectOps: []string{OpLs, OpCp, OpArchive, OpRm}},
+		{name: "selected operations", args: []string{"--ops=rm,ls"}, expectOps: []string{OpLs, OpRm}},
+		{name: "empty objects", args: []string{"--files=0", "--ops=cp"}, expectOps: []string{OpCp}},
+	}
+
+	fs := afero.NewOsFs()
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			dir := pttest.CreateTempDir(t, fs)
+
+			args := append([]string{"--json", "--objects=5", "--files=3", "--size=64", "--dir=" + dir}, test.args...)
+			err := Run(args, &buf)
+			require.NoError(t, err)
+
+			var report Report
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &report))
+			assert.Equal(t, 5, report.Objects)
+
+			require.Len(t, report.Results, len(test.expectOps))
+			for i, result := range report.Results {
+				assert.Equal(t, test.expectOps[i], result.Op)
+				assert.Equal(t, 5, result.Count)
+				assert.Equal(t, int64(5*report.Files*64), result.Bytes)
+				assert.LessOrEqual(t, result.P50, result.Max)
+			}
+
+			// The generated pairtree is removed when the benchmark finishes
+			entries, err := afero.ReadDir(fs, dir)
+			require.NoError(t, err)
+			assert.Empty(t, entries)
+		})
+	}
+}
+
+// TestTable tests that the results are written as a table with a row for each operation
+func TestTable(t *testing.T) {
+	var buf bytes.Buffer
+	err := Run([]string{"--objects=2", "--files=1", "--ops=ls,archive"}, &buf)
+	require.NoError(t, err)
+
+	output := buf.String()
+	assert.Contains(t, output, "Generated 2 objects with 1 files of 4096 bytes")
+
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	require.Len(t, lines, 4)
+	assert.True(t, strings.HasPrefix(lines[1], "op"))
+	assert.True(t, strings.HasPrefix(lines[2], OpLs))
+	assert.True(t, strings.HasPrefix(lines[3], OpArchive))
+}
+
+// TestSummarize tests the percentiles and throughput computed from the timings
+func TestSummarize(t *testing.T) {
+	timings := make([]time.Duration, 0, 100)
+	for i := 100; i > 0; i-- {
+		timings = append(timings, time.Duration(i)*time.Millisecond)
+	}
+
+	result := summarize(OpCp, timings, 1<<20)
+
+	assert.Equal(t, 100, result.Count)
+	assert.Equal(t, int64(100<<20), result.Bytes)
+	assert.Equal(t, 5050*time.Millisecond, result.Total)
+	assert.Equal(t, 50*time.Millisecond, result.P50)
+	assert.Equal(t, 90*time.Millisecond, result.P90)
+	assert.Equal(t, 99*time.Millisecond, result.P99)
+	assert.Equal(t, 100*time.Millisecond, result.Max)
+	assert.InDelta(t, 100/5.05, result.OpsPerSec, 0.001)
+	assert.InDelta(t, 100/5.05, result.MBPerSec, 0.001)
+
+	assert.Equal(t, Result{Op: OpLs}, summarize(OpLs, nil, 10))
+}
+
+// TestCLIError tests if an error is thrown when the options are not valid
+func TestCLIError(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		expectErr error
+	}{
+		{name: "Too many arguments passed in", args: []string{"extra"}, expectErr: error_msgs.Err8},
+		{name: "Unknown operation", args: []string{"--ops=ls,mv"}, expectErr: error_msgs.Err24},
+		{name: "No objects", args: []string{"--objects=0"}, expectErr: error_msgs.Err25},
+		{name: "Negative size", args: []string{"--size=-1"}, expectErr: error_msgs.Err25},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+
+			err := Run(test.args, &buf)
+			assert.ErrorIs(t, err, test.expectErr)
+		})
+	}
+}
@@ -0,0 +1,453 @@
+package ptchecksum
+
+/* ptchecksum writes a checksum manifest of the files of a Pairtree object, with a line for each file
+with its checksum and its path relative to the object directory, like sha256sum and BagIt manifests.
+The manifest is written to standard output, or with -w into the object beside its files. Hidden files
+are only included with -a, and the manifests pt checksum writes into the object are never included.
+With --bagit the payload and tag manifests of a bag of the object are written into a directory instead,
+with paths in data/, so the directory becomes a bag once the files are copied into data/ beside them and
+bagit.txt is added. With --sign-key the manifest, or the tag manifest of the bag, is signed in a .sig file
+beside it. The files are first checked against a manifest of the same algorithm already in the object,
+which is recorded as a fixity check in the object's event history. */
+
+import (
+	"bufio"
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/UCLALibrary/pt-tools/pkg/audit"
+	"github.com/UCLALibrary/pt-tools/pkg/bagit"
+	"github.com/UCLALibrary/pt-tools/pkg/checksum"
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pairtree"
+	"github.com/UCLALibrary/pt-tools/pkg/premis"
+	"github.com/UCLALibrary/pt-tools/utils"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	// Logger is the logger each run of pt checksum starts from, tests replace it to capture the logs
+	Logger *zap.Logger = utils.ConsoleLogger()
+)
+
+// command holds the flags and arguments of one run of pt checksum so that runs can happen concurrently
+type command struct {
+	showAll  bool
+	write    bool
+	bagDir   string
+	key      ed25519.PrivateKey
+	ptRoot   string
+	id       string
+	hashOpts checksum.Options
+	logger   *zap.Logger
+	out      *utils.Output
+}
+
+func (c *command) initFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(&c.showAll, "a", "a", false, "include hidden files and directories")
+	cmd.Flags().BoolVarP(&c.write, "w", "w", false, "write the manifest into the object instead of to standard output")
+	cmd.Flags().StringVar(&c.bagDir, "bagit", "", "write the payload and tag manifests of a bag of the object, with its files in data/, into this directory")
+	cmd.Flags().StringVar(&c.hashOpts.Algorithm, "algorithm", checksum.SHA256,
+		"Algorithm the files are hashed with, one of "+strings.Join(checksum.Algorithms, ", "))
+	cmd.Flags().IntVar(&c.hashOpts.IOLimit, "io-limit", 0, "Reads from files that happen at once while hashing them (defaults to one per CPU)")
+}
+
+// NewCommand creates the checksum subcommand of pt that writes its output to the writer
+func NewCommand(writer io.Writer) *cobra.Command {
+	c := &command{logger: Logger, out: utils.NewOutput(io.Discard, &utils.Styler{}, false)}
+
+	var cmd = &cobra.Command{
+		Use:               "checksum [ID]",
+		Short:             "pt checksum writes a checksum manifest of the files of a Pairtree object",
+		ValidArgsFunction: utils.CompleteIDs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return utils.ConfigureLogger(cmd, &c.logger)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+
+			c.out = utils.OutputFromFlags(cmd, writer)
+
+			if c.ptRoot, err = utils.GetPtRoot(cmd, writer); err != nil {
+				return err
+			}
+
+			if len(args) < 1 {
+				c.out.Error("Please provide an ID for the pairtree")
+				c.logger.Error("Error getting ID", zap.Error(error_msgs.Err6))
+
+				return error_msgs.Err6
+			} else if len(args) > 1 {
+				c.out.Error("Too many arguments were provided to %s", "pt checksum")
+				c.logger.Error("Error parsing pt checksum", zap.Error(error_msgs.Err8))
+
+				return error_msgs.Err8
+			}
+			c.id = args[0]
+
+			if !checksum.Supported(c.hashOpts.Algorithm) {
+				err := fmt.Errorf("%w: --algorithm must be one of %s", error_msgs.Err17,
+					strings.Join(checksum.Algorithms, ", "))
+				c.logger.Error("Error parsing pt checksum", zap.Error(err))
+
+				return err
+			}
+
+			if c.write && c.bagDir != "" {
+				err := fmt.Errorf("%w: -w and --bagit can not be used together", error_msgs.Err17)
+				c.logger.Error("Error parsing pt checksum", zap.Error(err))
+
+				return err
+			}
+
+			if c.key, err = utils.SignKeyFromFlags(cmd); err != nil {
+				c.logger.Error("Error reading the signing key", zap.Error(err))
+				return err
+			} else if c.key != nil && !c.write && c.bagDir == "" {
+				err := fmt.Errorf("%w: --sign-key needs -w or --bagit", error_msgs.Err17)
+				c.logger.Error("Error parsing pt checksum", zap.Error(err))
+
+				return err
+			}
+
+			if c.hashOpts.IOLimit < 0 {
+				err := fmt.Errorf("%w: --io-limit must not be negative", error_msgs.Err17)
+				c.logger.Error("Error parsing pt checksum", zap.Error(err))
+
+				return err
+			}
+
+			utils.SilenceUsage(cmd)
+
+			return c.checksum(cmd.Context(), writer)
+		},
+	}
+
+	c.initFlags(cmd)
+	utils.AddSignKeyFlag(cmd)
+
+	return cmd
+}
+
+// Run executes pt checksum with the given arguments
+func Run(args []string, writer io.Writer) error {
+	if err := utils.RunSubcommand(NewCommand(writer), args, writer); err != nil {
+		Logger.Error("Error running pt checksum", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// checksum writes the manifest of the object to the writer, or into the object with -w
+func (c *command) checksum(ctx context.Context, writer io.Writer) (err error) {
+	// Open the pairtree, which checks its version file and reads its prefix
+	pt, err := pairtree.Open(c.ptRoot)
+	if err != nil {
+		c.logger.Error("Error opening the pairtree", zap.Error(err))
+		return err
+	}
+
+	pairPath, err := pt.PairPath(c.id)
+	if err != nil {
+		c.logger.Error("Error creating pairpath", zap.Error(err))
+		return &error_msgs.PtError{ID: c.id, Err: err}
+	}
+
+	relPaths, err := c.files(ctx, pt)
+	if err != nil {
+		c.logger.Error("Error listing the files of the object", zap.Error(err))
+		return &error_msgs.PtError{ID: c.id, Path: pairPath, Err: err}
+	}
+
+	paths := make([]string, len(relPaths))
+	for i, relPath := range relPaths {
+		paths[i] = filepath.Join(pairPath, filepath.FromSlash(relPath))
+	}
+
+	sums, err := checksum.Files(ctx, paths, c.hashOpts)
+	if err != nil {
+		c.logger.Error("Error hashing the files of the object", zap.Error(err))
+		return &error_msgs.PtError{ID: c.id, Path: pairPath, Err: err}
+	}
+
+	// The files are checked against a manifest already in the object before it is replaced
+	manifestPath := filepath.Join(pairPath, checksum.ManifestName(c.hashOpts.Algorithm))
+	fixityErr := c.checkFixity(ctx, pt, manifestPath, sums, relPaths)
+	if fixityErr != nil && !errors.Is(fixityErr, error_msgs.Err49) {
+		return &error_msgs.PtError{ID: c.id, Path: manifestPath, Err: fixityErr}
+	}
+
+	if c.bagDir != "" {
+		if err := c.writeBagManifests(ctx, pt, sums, relPaths); err != nil {
+			return err
+		}
+		// The tag manifest has the checksum of the payload manifest, so signing it signs both
+		if err := c.sign(filepath.Join(c.bagDir, "tag"+checksum.ManifestName(c.hashOpts.Algorithm))); err != nil {
+			return err
+		}
+		if fixityErr != nil {
+			return &error_msgs.PtError{ID: c.id, Path: manifestPath, Err: fixityErr}
+		}
+		return nil
+	}
+
+	if !c.write {
+		if err := writeManifest(writer, sums, relPaths); err != nil {
+			return err
+		}
+		if fixityErr != nil {
+			return &error_msgs.PtError{ID: c.id, Path: manifestPath, Err: fixityErr}
+		}
+		return nil
+	}
+
+	// A manifest that no longer matches is replaced as asked, since the files may have been changed on purpose
+	if fixityErr != nil {
+		c.out.Warning("The files of %s do not match %s: %v", c.id, manifestPath, fixityErr)
+	}
+
+	if err := c.writeObjectManifest(pt, manifestPath, sums, relPaths); err != nil {
+		return err
+	}
+
+	return c.sign(manifestPath)
+}
+
+// writeObjectManifest writes the manifest into the object, replacing the one that is there and its signature,
+// which would no longer match. Writing it is kept in the object's event history.
+func (c *command) writeObjectManifest(pt *pairtree.Pairtree, manifestPath string, sums []checksum.File,
+	relPaths []string) (err error) {
+	defer func() {
+		utils.RecordEvent(c.ptRoot, pt.Prefix(), premis.NewEvent(premis.MessageDigestCalculation, c.id,
+			"wrote "+checksum.ManifestName(c.hashOpts.Algorithm), err), c.out, c.logger)
+	}()
+
+	if err = writeSidecar(manifestPath, sums, relPaths); err == nil {
+		if err = os.Remove(manifestPath + audit.SignatureExt); errors.Is(err, fs.ErrNotExist) {
+			err = nil
+		}
+	}
+	if err != nil {
+		c.logger.Error("Error writing the manifest", zap.String("manifest", manifestPath), zap.Error(err))
+		return &error_msgs.PtError{ID: c.id, Path: manifestPath, Err: err}
+	}
+
+	c.out.Success("Wrote the %s manifest of %s to %s", c.hashOpts.Algorithm, c.id, manifestPath)
+	c.logger.Info("Wrote the manifest of the object", zap.String("id", c.id), zap.String("manifest", manifestPath),
+		zap.Int("files", len(sums)))
+
+	return nil
+}
+
+// sign writes a detached signature of the manifest beside it with the key of --sign-key, when it is set
+func (c *command) sign(manifestPath string) error {
+	if c.key == nil {
+		return nil
+	}
+
+	if err := utils.SignFile(c.key, manifestPath, c.out, c.logger); err != nil {
+		return &error_msgs.PtError{ID: c.id, Path: manifestPath, Err: err}
+	}
+
+	return nil
+}
+
+// writeBagManifests writes the payload and tag manifests a bag of the object would have into the --bagit
+// directory, which is made when it does not exist, and removes the signature of an earlier tag manifest.
+// Writing them is kept in the object's event history.
+func (c *command) writeBagManifests(ctx context.Context, pt *pairtree.Pairtree, sums []checksum.File, relPaths []string) (err error) {
+	defer func() {
+		utils.RecordEvent(c.ptRoot, pt.Prefix(), premis.NewEvent(premis.MessageDigestCalculation, c.id,
+			"wrote the BagIt manifests in "+c.bagDir, err), c.out, c.logger)
+	}()
+
+	if err = os.MkdirAll(c.bagDir, 0755); err == nil {
+		err = bagit.WriteManifests(ctx, c.bagDir, relPaths, sums, nil, c.hashOpts)
+	}
+	if err == nil {
+		tagManifest := filepath.Join(c.bagDir, "tag"+checksum.ManifestName(c.hashOpts.Algorithm))
+		if err = os.Remove(tagManifest + audit.SignatureExt); errors.Is(err, fs.ErrNotExist) {
+			err = nil
+		}
+	}
+	if err != nil {
+		c.logger.Error("Error writing the BagIt manifests", zap.String("bag", c.bagDir), zap.Error(err))
+		return &error_msgs.PtError{ID: c.id, Path: c.bagDir, Err: err}
+	}
+
+	c.out.Success("Wrote the BagIt manifests of %s to %s", c.id, c.bagDir)
+	c.logger.Info("Wrote the BagIt manifests of the object", zap.String("id", c.id), zap.String("bag", c.bagDir))
+
+	return nil
+}
+
+// checkFixity compares the checksums of the files with those of the manifest of the algorithm that is already
+// in the object, and records the comparison as a fixity check in the object's event history. An object
+// without a manifest has nothing to check. Files that are missing or differ are returned with Err49, and
+// files that are only in the object, like those added since the manifest was written, are not checked.
+func (c *command) checkFixity(ctx context.Context, pt *pairtree.Pairtree, manifestPath string, sums []checksum.File, relPaths []string) (err error) {
+	expected, err := readManifest(manifestPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		c.logger.Error("Error reading the manifest", zap.String("manifest", manifestPath), zap.Error(err))
+		return err
+	}
+
+	defer func() {
+		utils.RecordFixityCheck(c.ptRoot, pt.Prefix(), c.id, "checked against "+filepath.Base(manifestPath), err, c.out, c.logger)
+	}()
+
+	actual := make(map[string]string, len(sums))
+	for i, sum := range sums {
+		actual[relPaths[i]] = sum.Checksum
+	}
+
+	// Files of the manifest that were not hashed, like hidden ones without -a, are hashed when they exist
+	var unhashed []string
+	for _, relPath := range sortedKeys(expected) {
+		if _, ok := actual[relPath]; !ok && filepath.IsLocal(filepath.FromSlash(relPath)) {
+			unhashed = append(unhashed, relPath)
+		}
+	}
+	for _, relPath := range unhashed {
+		path := filepath.Join(filepath.Dir(manifestPath), filepath.FromSlash(relPath))
+		if _, statErr := os.Stat(path); statErr != nil {
+			continue
+		}
+
+		files, err := checksum.Files(ctx, []string{path}, c.hashOpts)
+		if err != nil {
+			return err
+		}
+		actual[relPath] = files[0].Checksum
+	}
+
+	var mismatched []string
+	for _, relPath := range sortedKeys(expected) {
+		if actual[relPath] != expected[relPath] {
+			mismatched = append(mismatched, relPath)
+		}
+	}
+
+	if len(mismatched) > 0 {
+		err = fmt.Errorf("%w: %s", error_msgs.Err49, strings.Join(mismatched, ", "))
+		c.logger.Error("Error checking the fixity of the object", zap.String("manifest", manifestPath), zap.Error(err))
+		return err
+	}
+
+	// Without -w the manifest is the output, so the check is only logged
+	if c.write {
+		c.out.Success("Checked the files of %s against %s", c.id, manifestPath)
+	}
+	c.logger.Info("Checked the fixity of the object", zap.String("id", c.id), zap.String("manifest", manifestPath))
+
+	return nil
+}
+
+// readManifest reads the checksums of a manifest pt checksum wrote by the path of each file
+func readManifest(manifestPath string) (map[string]string, error) {
+	file, err := os.Open(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	sums := map[string]string{}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		sum, relPath, ok := strings.Cut(strings.TrimSuffix(scanner.Text(), "\r"), "  ")
+		if !ok {
+			continue
+		}
+		sums[relPath] = strings.ToLower(sum)
+	}
+
+	return sums, scanner.Err()
+}
+
+// sortedKeys returns the keys of the map in order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// files returns the slash separated paths of the files of the object in order, leaving out the
+// manifests in the object directory and their signatures so that they are not checksums of each other
+func (c *command) files(ctx context.Context, pt *pairtree.Pairtree) ([]string, error) {
+	manifests := map[string]bool{}
+	for _, algorithm := range checksum.Algorithms {
+		manifests[checksum.ManifestName(algorithm)] = true
+		manifests[checksum.ManifestName(algorithm)+audit.SignatureExt] = true
+	}
+
+	var relPaths []string
+	err := pt.WalkCtx(ctx, c.id, pairtree.ListOptions{Recursive: true, ShowAll: c.showAll}, func(entry pairtree.Entry) error {
+		if !entry.IsDir() && !manifests[entry.Path] {
+			relPaths = append(relPaths, entry.Path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(relPaths)
+	return relPaths, nil
+}
+
+// writeManifest writes a line with the checksum and path of each file
+func writeManifest(writer io.Writer, sums []checksum.File, relPaths []string) error {
+	for i, sum := range sums {
+		if _, err := fmt.Fprintf(writer, "%s  %s\n", sum.Checksum, relPaths[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeSidecar writes the manifest to a hidden file beside manifestPath and then renames it, so a
+// manifest already in the object is only replaced by a complete one
+func writeSidecar(manifestPath string, sums []checksum.File, relPaths []string) (err error) {
+	out, err := os.CreateTemp(filepath.Dir(manifestPath), ".pt-manifest-*")
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, os.Remove(out.Name()))
+		}
+	}()
+
+	if err := writeManifest(out, sums, relPaths); err != nil {
+		return errors.Join(err, out.Close())
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	// A manifest is read like any other file of the object
+	if err := os.Chmod(out.Name(), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(out.Name(), manifestPath)
+}
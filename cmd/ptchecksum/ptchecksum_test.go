@@ -0,0 +1,251 @@
+package ptchecksum
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/UCLALibrary/pt-tools/pkg/audit"
+	"github.com/UCLALibrary/pt-tools/pkg/bagit"
+	"github.com/UCLALibrary/pt-tools/pkg/checksum"
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pairtree"
+	"github.com/UCLALibrary/pt-tools/pkg/premis"
+	"github.com/UCLALibrary/pt-tools/pkg/pttest"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	root = "--pairtree="
+	// SHA-256 and MD5 of "hello\n" and SHA-256 of nothing
+	helloSum    = "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"
+	helloMD5Sum = "b1946ac92492d2347c6235b4d2611184"
+	emptySum    = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// TestMain runs the tests with the logger of the test sink, it is set once so that the tests can run in parallel
+func TestMain(m *testing.M) {
+	logger, cleanup := pttest.SetupLogger()
+	Logger = logger
+
+	code := m.Run()
+	cleanup()
+	os.Exit(code)
+}
+
+// TestChecksum tests that the manifest has a line for each file of the object in order
+func TestChecksum(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		expected  string
+		expectErr error
+	}{
+		{name: "object", args: []string{"ark:/b5488"},
+			expected: helloSum + "  folder/innerb5488.txt\n" + emptySum + "  outerb5488.txt\n"},
+		{name: "hidden", args: []string{"-a", "ark:/a54892"},
+			expected: emptySum + "  .hidden.txt\n" + emptySum + "  .hidden/innerHidden.txt\n" + emptySum + "  a54892.txt\n"},
+		{name: "algorithm", args: []string{"--algorithm", "md5", "ark:/b5488"},
+			expected: helloMD5Sum + "  folder/innerb5488.txt\n" + "d41d8cd98f00b204e9800998ecf8427e  outerb5488.txt\n"},
+		{name: "not an object", args: []string{"ark:/notAnObject"}, expectErr: os.ErrNotExist},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ptRoot := pttest.StandardPairtree().WithFile("ark:/b5488", "folder/innerb5488.txt", []byte("hello\n")).
+				BuildTemp(t, afero.NewOsFs())
+
+			var buf bytes.Buffer
+			err := Run(append([]string{root + ptRoot}, test.args...), &buf)
+			if test.expectErr != nil {
+				assert.ErrorIs(t, err, test.expectErr)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expected, buf.String())
+		})
+	}
+}
+
+// TestSidecar tests that -w writes the manifest into the object, leaves it out of the next manifest,
+// and records the calculation in the object's event history
+func TestSidecar(t *testing.T) {
+	ptRoot := pttest.StandardPairtree().WithFile("ark:/b5488", "folder/innerb5488.txt", []byte("hello\n")).
+		BuildTemp(t, afero.NewOsFs())
+	pairPath, err := pairtree.CreatePP("ark:/b5488", ptRoot, "ark:/")
+	require.NoError(t, err)
+
+	var stdout bytes.Buffer
+	require.NoError(t, Run([]string{root + ptRoot, "ark:/b5488"}, &stdout))
+
+	for range 2 {
+		var buf bytes.Buffer
+		require.NoError(t, Run([]string{root + ptRoot, "-w", "ark:/b5488"}, &buf))
+
+		manifest, err := os.ReadFile(filepath.Join(pairPath, "manifest-sha256.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, stdout.String(), string(manifest))
+	}
+
+	// A manifest of another algorithm does not include the first
+	var buf bytes.Buffer
+	require.NoError(t, Run([]string{root + ptRoot, "-w", "--algorithm", "sha1", "ark:/b5488"}, &buf))
+	manifest, err := os.ReadFile(filepath.Join(pairPath, "manifest-sha1.txt"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(manifest), "manifest-sha256.txt")
+
+	// The second manifest was written once the files were checked against the first
+	events, err := premis.Events(ptRoot, "ark:/", "ark:/b5488")
+	require.NoError(t, err)
+	require.Len(t, events, 4)
+	for i, eventType := range []string{premis.MessageDigestCalculation, premis.FixityCheck,
+		premis.MessageDigestCalculation, premis.MessageDigestCalculation} {
+		assert.Equal(t, eventType, events[i].Type)
+		assert.Equal(t, premis.Success, events[i].Outcome)
+	}
+}
+
+// TestFixity tests that the files are checked against the manifest in the object, and that the check is
+// recorded in the object's event history whether or not they match
+func TestFixity(t *testing.T) {
+	ptRoot := pttest.StandardPairtree().WithFile("ark:/b5488", "folder/innerb5488.txt", []byte("hello\n")).
+		BuildTemp(t, afero.NewOsFs())
+	pairPath, err := pairtree.CreatePP("ark:/b5488", ptRoot, "ark:/")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, Run([]string{root + ptRoot, "-w", "ark:/b5488"}, &buf))
+
+	// The manifest is still written when the files do not match it
+	require.NoError(t, os.WriteFile(filepath.Join(pairPath, "outerb5488.txt"), []byte("changed"), 0644))
+	require.NoError(t, os.Remove(filepath.Join(pairPath, "folder", "innerb5488.txt")))
+	buf.Reset()
+	err = Run([]string{root + ptRoot, "ark:/b5488"}, &buf)
+	assert.ErrorIs(t, err, error_msgs.Err49)
+	assert.ErrorContains(t, err, "folder/innerb5488.txt, outerb5488.txt")
+	assert.Contains(t, buf.String(), "  outerb5488.txt\n")
+
+	// -w replaces a manifest that does not match, after which the files match again
+	require.NoError(t, Run([]string{root + ptRoot, "-w", "ark:/b5488"}, &buf))
+	require.NoError(t, Run([]string{root + ptRoot, "ark:/b5488"}, &buf))
+
+	var fixity []premis.Event
+	events, err := premis.Events(ptRoot, "ark:/", "ark:/b5488")
+	require.NoError(t, err)
+	for _, event := range events {
+		if event.Type == premis.FixityCheck {
+			fixity = append(fixity, event)
+		}
+	}
+
+	require.Len(t, fixity, 3)
+	assert.Equal(t, premis.Failure, fixity[0].Outcome)
+	assert.Contains(t, fixity[0].OutcomeDetail, "outerb5488.txt")
+	assert.Equal(t, "checked against manifest-sha256.txt", fixity[0].Detail)
+	assert.Equal(t, premis.Failure, fixity[1].Outcome)
+	assert.Equal(t, premis.Success, fixity[2].Outcome)
+}
+
+// TestBagIt tests that the manifests written with --bagit make a bag of the object once its files are
+// copied into data/ and bagit.txt is added
+func TestBagIt(t *testing.T) {
+	ptRoot := pttest.StandardPairtree().WithFile("ark:/b5488", "folder/innerb5488.txt", []byte("hello\n")).
+		BuildTemp(t, afero.NewOsFs())
+	pairPath, err := pairtree.CreatePP("ark:/b5488", ptRoot, "ark:/")
+	require.NoError(t, err)
+	bagDir := filepath.Join(t.TempDir(), "bag")
+
+	var buf bytes.Buffer
+	require.NoError(t, Run([]string{root + ptRoot, "-a", "--bagit", bagDir, "ark:/b5488"}, &buf))
+	assert.Contains(t, buf.String(), "Wrote the BagIt manifests of ark:/b5488 to "+bagDir)
+
+	manifest, err := os.ReadFile(filepath.Join(bagDir, "manifest-sha256.txt"))
+	require.NoError(t, err)
+	assert.Contains(t, string(manifest), helloSum+"  data/folder/innerb5488.txt\n")
+	assert.FileExists(t, filepath.Join(bagDir, "tagmanifest-sha256.txt"))
+
+	// The directory is a bag once it has the files of the object and a declaration
+	require.NoError(t, os.CopyFS(filepath.Join(bagDir, bagit.DataDir), os.DirFS(pairPath)))
+	require.NoError(t, os.WriteFile(filepath.Join(bagDir, bagit.DeclarationName),
+		[]byte("BagIt-Version: 1.0\nTag-File-Character-Encoding: UTF-8\n"), 0644))
+	bag, err := bagit.Check(context.Background(), bagDir, checksum.Options{})
+	require.NoError(t, err)
+	assert.Contains(t, bag.Files, "folder/innerb5488.txt")
+
+	events, err := premis.Events(ptRoot, "ark:/", "ark:/b5488")
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "wrote the BagIt manifests in "+bagDir, events[0].Detail)
+}
+
+// TestSign tests that --sign-key signs the manifest written into the object, or the tag manifest of --bagit,
+// and that the signature is left out of the manifest and removed when the manifest is replaced unsigned
+func TestSign(t *testing.T) {
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, afero.NewOsFs())
+	pairPath, err := pairtree.CreatePP("ark:/b5488", ptRoot, "ark:/")
+	require.NoError(t, err)
+	key, keyPath, _ := pttest.SigningKey(t, t.TempDir())
+	publicKey := key.Public().(ed25519.PublicKey)
+	manifestPath := filepath.Join(pairPath, "manifest-sha256.txt")
+
+	var buf bytes.Buffer
+	require.NoError(t, Run([]string{root + ptRoot, "-w", "--sign-key", keyPath, "ark:/b5488"}, &buf))
+	assert.Contains(t, buf.String(), "Signed "+manifestPath)
+	_, err = audit.VerifyFile(manifestPath, publicKey)
+	require.NoError(t, err)
+
+	// The signature is not a file of the object, so the manifest still matches
+	buf.Reset()
+	require.NoError(t, Run([]string{root + ptRoot, "ark:/b5488"}, &buf))
+	assert.NotContains(t, buf.String(), audit.SignatureExt)
+
+	require.NoError(t, Run([]string{root + ptRoot, "-w", "ark:/b5488"}, &buf))
+	assert.NoFileExists(t, manifestPath+audit.SignatureExt)
+
+	bagDir := filepath.Join(t.TempDir(), "bag")
+	require.NoError(t, Run([]string{root + ptRoot, "--bagit", bagDir, "--sign-key", keyPath, "ark:/b5488"}, &buf))
+	_, err = audit.VerifyFile(filepath.Join(bagDir, "tagmanifest-sha256.txt"), publicKey)
+	require.NoError(t, err)
+}
+
+// TestCLIError tests if an error is thrown when the arguments are not valid
+func TestCLIError(t *testing.T) {
+	dir := t.TempDir()
+	_, keyPath, _ := pttest.SigningKey(t, dir)
+	notKey := filepath.Join(dir, "notkey.pem")
+	require.NoError(t, os.WriteFile(notKey, []byte("not a key"), 0600))
+
+	tests := []struct {
+		name      string
+		args      []string
+		expectErr error
+	}{
+		{name: "No ID provided", args: []string{root + "root"}, expectErr: error_msgs.Err6},
+		{name: "No pairtree root provided", args: []string{"ID"}, expectErr: error_msgs.Err7},
+		{name: "Too many arguments passed in", args: []string{root + "root", "ark:/a5388", "extra"}, expectErr: error_msgs.Err8},
+		{name: "Unsupported algorithm", args: []string{root + "root", "ark:/a5388", "--algorithm=crc32"}, expectErr: error_msgs.Err17},
+		{name: "Negative I/O limit", args: []string{root + "root", "ark:/a5388", "--io-limit=-1"}, expectErr: error_msgs.Err17},
+		{name: "Both -w and --bagit", args: []string{root + "root", "ark:/a5388", "-w", "--bagit=bag"}, expectErr: error_msgs.Err17},
+		{name: "Signing standard output", args: []string{root + "root", "ark:/a5388", "--sign-key=" + keyPath}, expectErr: error_msgs.Err17},
+		{name: "Signing key not valid", args: []string{root + "root", "ark:/a5388", "-w", "--sign-key=" + notKey}, expectErr: error_msgs.Err38},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+
+			err := Run(test.args, &buf)
+			assert.ErrorIs(t, err, test.expectErr)
+		})
+	}
+}
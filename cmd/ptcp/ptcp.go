@@ -1,170 +1,678 @@
 package ptcp
 
 /* ptcp is a cp-like tool that can copy files in and out of the Pairtree structure.
-Unlike Linux's cp, the default is recursive */
+Unlike Linux's cp, the default is recursive. The IDs of objects copied out of the Pairtree can also
+be read from a file or standard input with --ids-from. */
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
+	"github.com/UCLALibrary/pt-tools/pkg/ark"
+	"github.com/UCLALibrary/pt-tools/pkg/checksum"
 	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
 	"github.com/UCLALibrary/pt-tools/pkg/pairtree"
+	"github.com/UCLALibrary/pt-tools/pkg/premis"
 	"github.com/UCLALibrary/pt-tools/utils"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 )
 
+// stdio is the source or destination of an archive that is read from standard input or written to standard output
+const stdio = "-"
+
 var (
-	overwrite bool
-	tar       bool
-	subpath   string
-	ptRoot    string
-	logFile   string      = "logs.log"
-	Logger    *zap.Logger = utils.Logger(logFile)
-	src       string      = ""
-	dest      string      = ""
+	// Logger is the logger each run of pt cp starts from, tests replace it to capture the logs
+	Logger *zap.Logger = utils.ConsoleLogger()
 )
 
-func initFlags(cmd *cobra.Command) {
-	cmd.Flags().StringVarP(&ptRoot, "pairtree", "p", "", "Set pairtree root directory")
-	cmd.Flags().BoolVarP(&overwrite, "d", "d", false, "Overwrite target files")
-	cmd.Flags().StringVarP(&subpath, "n", "n", "", "Create subpath to or rename the file or path")
-	cmd.Flags().BoolVarP(&tar, "a", "a", false, "Produce a tar/gzipped output or unpack a tar/gzipped")
+// command holds the flags and arguments of one run of pt cp so that runs can happen concurrently
+type command struct {
+	overwrite   bool
+	dryRun      bool
+	tar         bool
+	format      string
+	compress    string
+	subpath     string
+	copyOpts    pairtree.CopyOptions
+	archiveOpts pairtree.ArchiveOptions
+	warmCache   bool
+	ptRoot      string
+	srcRoot     string
+	destRoot    string
+	src         string
+	dest        string
+	ids         []string
+	resolver    *ark.Resolver
+	in          io.Reader
+	logger      *zap.Logger
+	out         *utils.Output
 }
 
-func Run(args []string, writer io.Writer) error {
-	var err error
+func (c *command) initFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(&c.overwrite, "d", "d", false, "Overwrite target files")
+	cmd.Flags().BoolVar(&c.dryRun, "dry-run", false, "Print what would be copied or overwritten without changing anything")
+	cmd.Flags().StringVarP(&c.subpath, "n", "n", "", "Create subpath to or rename the file or path")
+	cmd.Flags().BoolVarP(&c.tar, "a", "a", false, "Produce a tar/gzipped output or unpack a tar/gzipped")
+	cmd.Flags().StringVar(&c.format, "format", "", "Format of the archive of -a, one of "+strings.Join(pairtree.Formats, ", ")+" (defaults to the extension of the source, or tgz)")
+	cmd.Flags().StringVar(&c.compress, "compress", "", "Compression of a tar archive of -a, gzip or zstd")
+	cmd.Flags().IntVar(&c.copyOpts.BufferSize, "buffer-size", 0, "Bytes of the buffer each file is copied with instead of copying in the kernel")
+	cmd.Flags().BoolVar(&c.copyOpts.Direct, "direct", false, "Copy with O_DIRECT on Linux to bypass the page cache")
+	cmd.Flags().IntVar(&c.copyOpts.Jobs, "jobs", 1, "Files of a directory copied at once")
+	cmd.Flags().BoolVar(&c.copyOpts.Verify, "verify", false, "Compare the checksums of the copy with those of the source and fail if they differ")
+	cmd.Flags().IntVar(&c.archiveOpts.CompressWorkers, "compress-workers", 0, "Blocks of an archive compressed in parallel (defaults to the number of CPUs)")
+	cmd.Flags().BoolVar(&c.warmCache, "warm-cache", false, "Hash files as they are archived with -a and reuse the checksums to verify the archive, reading each file once")
+	cmd.Flags().StringVar(&c.srcRoot, "src-root", "", "Pairtree root to copy the source object from (defaults to the pairtree root)")
+	cmd.Flags().StringVar(&c.destRoot, "dest-root", "", "Pairtree root to copy into the destination object of (defaults to the pairtree root)")
+}
+
+// NewCommand creates the cp subcommand of pt that writes its output to the writer
+func NewCommand(writer io.Writer) *cobra.Command {
+	c := &command{logger: Logger, out: utils.NewOutput(io.Discard, &utils.Styler{}, false)}
 
-	var rootCmd = &cobra.Command{
-		Use:   "pt cp -p [PT_ROOT] [ID] [/path/to/output]",
-		Short: "pt cp is a tool to copy files and folders in and out of the Pairtree",
+	var cmd = &cobra.Command{
+		Use:               "cp [ID] [/path/to/output]",
+		Short:             "pt cp is a tool to copy files and folders in and out of the Pairtree",
+		ValidArgsFunction: utils.CompleteIDs,
+		Annotations:       map[string]string{utils.S3Annotation: "true"},
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return utils.ConfigureLogger(cmd, &c.logger)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			// If the root has not been set yet check the ENV vars
-			if ptRoot == "" {
-				if envVar := os.Getenv("PAIRTREE_ROOT"); envVar != "" {
-					ptRoot = envVar
-				} else {
-					fmt.Fprintln(writer, error_msgs.Err7)
-					return error_msgs.Err7
+			var err error
+
+			c.out = utils.OutputFromFlags(cmd, writer)
+			c.resolver = utils.ResolverFromFlags(cmd)
+
+			// The pairtree root is only needed for the roots that --src-root and --dest-root do not set
+			crossRoot := c.srcRoot != "" || c.destRoot != ""
+			if c.srcRoot == "" || c.destRoot == "" {
+				if c.ptRoot, err = utils.GetPtRoot(cmd, writer); err != nil {
+					return err
 				}
 			}
 
+			if c.ids, err = utils.IDsFromFlags(cmd); err != nil {
+				c.logger.Error("Error reading IDs", zap.Error(err))
+				return err
+			}
+
+			if crossRoot && len(c.ids) > 0 {
+				err := fmt.Errorf("%w: --ids-from can not be used with --src-root or --dest-root", error_msgs.Err17)
+				c.logger.Error("Error parsing ptcp", zap.Error(err))
+
+				return err
+			}
+
+			// An object copied to another pairtree keeps its ID unless another is given
+			if crossRoot && len(args) == 1 {
+				args = append(args, args[0])
+			}
+
+			// The IDs read with --ids-from are the sources, so the one argument is the destination
 			numArgs := len(args)
+			if len(c.ids) > 0 {
+				numArgs++
+			}
+
 			if numArgs < 2 {
-				fmt.Fprintln(writer, "Please provide a source and destination for copied files")
-				Logger.Error("There are not enough arguments to ptcp",
+				c.out.Error("Please provide a source and destination for copied files")
+				c.logger.Error("There are not enough arguments to ptcp",
 					zap.Error(error_msgs.Err9))
 
 				return error_msgs.Err9
 			}
 
-			if numArgs == 2 {
+			if len(c.ids) > 0 && numArgs == 2 {
+				c.dest = args[0]
+			} else if numArgs == 2 {
 				// Extract the ID and the dest from the arguments
-				src = args[numArgs-2]
-				dest = args[numArgs-1]
+				c.src = args[numArgs-2]
+				c.dest = args[numArgs-1]
 			} else {
-				fmt.Fprintln(writer, "Too many arguments were provided to ptcp")
-				Logger.Error("Error parsing ptcp", zap.Error(error_msgs.Err8))
+				c.out.Error("Too many arguments were provided to %s", "ptcp")
+				c.logger.Error("Error parsing ptcp", zap.Error(error_msgs.Err8))
 
 				return error_msgs.Err8
 			}
 
-			if tar && subpath != "" {
+			if c.tar && c.subpath != "" {
 				return error_msgs.Err11
 			}
 
-			Logger.Info("Pairtree root is",
-				zap.String("PAIRTREE_ROOT", ptRoot),
+			// Archives are made and unpacked on the local file system
+			if c.tar && pairtree.IsS3(c.ptRoot) {
+				err := fmt.Errorf("%w: -a", error_msgs.Err41)
+				c.logger.Error("Error parsing ptcp", zap.Error(err))
+
+				return err
+			}
+
+			if c.format != "" && !slices.Contains(pairtree.Formats, c.format) {
+				err := fmt.Errorf("%w: --format must be one of %s", error_msgs.Err17, strings.Join(pairtree.Formats, ", "))
+				c.logger.Error("Error parsing ptcp", zap.Error(err))
+
+				return err
+			}
+
+			// The compression chooses between the formats of tar archives
+			switch c.compress {
+			case "":
+			case "gzip", "zstd":
+				if c.format == pairtree.ZipFormat {
+					err := fmt.Errorf("%w: --compress can not be used with --format %s", error_msgs.Err17, pairtree.ZipFormat)
+					c.logger.Error("Error parsing ptcp", zap.Error(err))
+
+					return err
+				}
+
+				c.format = pairtree.TgzFormat
+				if c.compress == "zstd" {
+					c.format = pairtree.TzstFormat
+				}
+			default:
+				err := fmt.Errorf("%w: --compress must be gzip or zstd", error_msgs.Err17)
+				c.logger.Error("Error parsing ptcp", zap.Error(err))
+
+				return err
+			}
+
+			if c.archiveOpts.CompressWorkers < 0 {
+				err := fmt.Errorf("%w: --compress-workers must not be negative", error_msgs.Err17)
+				c.logger.Error("Error parsing ptcp", zap.Error(err))
+
+				return err
+			}
+
+			if c.copyOpts.Jobs < 1 {
+				err := fmt.Errorf("%w: --jobs must be at least 1", error_msgs.Err17)
+				c.logger.Error("Error parsing ptcp", zap.Error(err))
+
+				return err
+			}
+
+			// The copy is read back from the local file system to verify it
+			if c.copyOpts.Verify && (pairtree.IsS3(c.ptRoot) || pairtree.IsS3(c.srcRoot) || pairtree.IsS3(c.destRoot)) {
+				err := fmt.Errorf("%w: --verify", error_msgs.Err41)
+				c.logger.Error("Error parsing ptcp", zap.Error(err))
+
+				return err
+			}
+
+			if c.copyOpts.Verify && c.tar && (c.src == stdio || c.dest == stdio) {
+				err := fmt.Errorf("%w: --verify can not be used with an archive on standard input or output", error_msgs.Err17)
+				c.logger.Error("Error parsing ptcp", zap.Error(err))
+
+				return err
+			}
+
+			// The archives of more than one object can not be told apart on standard output
+			if c.tar && c.dest == stdio && len(c.ids) > 0 {
+				err := fmt.Errorf("%w: --ids-from can not be used to write archives to standard output", error_msgs.Err17)
+				c.logger.Error("Error parsing ptcp", zap.Error(err))
+
+				return err
+			}
+
+			// An archive written to standard output keeps the messages on standard error
+			c.in = cmd.InOrStdin()
+			if c.tar && c.dest == stdio {
+				c.out = utils.OutputFromFlags(cmd, cmd.ErrOrStderr())
+			}
+
+			c.logger.Info("Pairtree root is",
+				zap.String("PAIRTREE_ROOT", c.ptRoot),
 			)
 
-			return nil
+			if c.warmCache {
+				c.archiveOpts.Cache = checksum.NewCache()
+			}
+
+			utils.SilenceUsage(cmd)
+
+			if crossRoot {
+				if c.srcRoot == "" {
+					c.srcRoot = c.ptRoot
+				}
+				if c.destRoot == "" {
+					c.destRoot = c.ptRoot
+				}
+
+				srcPT, err := pairtree.Open(c.srcRoot)
+				if err != nil {
+					c.logger.Error("Error opening the source pairtree", zap.Error(err))
+					return err
+				}
+
+				destPT, err := pairtree.Open(c.destRoot)
+				if err != nil {
+					c.logger.Error("Error opening the destination pairtree", zap.Error(err))
+					return err
+				}
+
+				return c.copyBetweenObjects(cmd.Context(), srcPT, destPT)
+			}
+
+			// The version and prefix of the pairtree are read once for all of the IDs that are copied
+			pt, err := pairtree.Open(c.ptRoot)
+			if err != nil {
+				c.logger.Error("Error opening the pairtree", zap.Error(err))
+				return err
+			}
+
+			if len(c.ids) > 0 {
+				return c.copyAll(cmd.Context(), pt, writer)
+			}
+
+			return c.copyObject(cmd.Context(), pt, writer)
 		},
 	}
 
-	initFlags(rootCmd)
-	rootCmd.SetOut(writer)
-	rootCmd.SetErr(writer)
-	rootCmd.SetArgs(args)
+	c.initFlags(cmd)
+	utils.AddResolveFlags(cmd)
+	utils.AddIDsFromFlag(cmd)
 
-	utils.ApplyExitOnHelp(rootCmd, 0)
+	return cmd
+}
 
-	if err = rootCmd.Execute(); err != nil {
-		Logger.Error("Error setting command line", zap.Error(err))
+// Run executes pt cp with the given arguments
+func Run(args []string, writer io.Writer) error {
+	if err := utils.RunSubcommand(NewCommand(writer), args, writer); err != nil {
+		Logger.Error("Error running pt cp", zap.Error(err))
 		return err
 	}
 
-	// check if the pairtree version file exists and is populated
-	if err := pairtree.CheckPTVer(ptRoot); err != nil {
-		Logger.Error("Error with pairtree veresion file", zap.Error(err))
-		return err
+	return nil
+}
+
+// copyAll copies each of the objects read with --ids-from out of the pairtree to the destination. An
+// object that can not be copied does not stop the others, and its error is returned with theirs.
+func (c *command) copyAll(ctx context.Context, pt *pairtree.Pairtree, writer io.Writer) error {
+	dest := c.dest
+	if len(c.ids) == 1 {
+		c.src = c.ids[0]
+		return c.copyObject(ctx, pt, writer)
 	}
 
-	// Get the prefix from pairtree_prefix file
-	prefix, err := pairtree.GetPrefix(ptRoot)
+	var errs []error
+	for _, id := range c.ids {
+		// Stop before the next object once the context is canceled
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
 
-	if err != nil {
-		Logger.Error("Error retrieving prefix from pairtree_prefix file", zap.Error(err))
-		return err
+		c.src, c.dest = id, dest
+		errs = append(errs, c.copyObject(ctx, pt, writer))
 	}
 
-	if prefix == "" {
-		prefix = pairtree.PtPrefix
+	return errors.Join(errs...)
+}
+
+// copyObject copies the source to the destination where one of them is in the pairtree
+func (c *command) copyObject(ctx context.Context, pt *pairtree.Pairtree, writer io.Writer) (err error) {
+	prefix := pt.Prefix()
+
+	// One object of the pairtree is copied into another when both are IDs
+	if strings.HasPrefix(c.src, prefix) && strings.HasPrefix(c.dest, prefix) {
+		return c.copyBetweenObjects(ctx, pt, pt)
 	}
 
+	// The ID of the pairtree object is reported with any error
+	var id string
+
 	srcIsPairtree := false
 	// Determine if the src or dest is the pairtree
-	if strings.HasPrefix(src, prefix) {
-		if src, err = pairtree.CreatePP(src, ptRoot, prefix); err != nil {
-			Logger.Error("Error creating pairpath", zap.Error(err))
-			return err
+	if strings.HasPrefix(c.src, prefix) {
+		id = c.src
+		if c.src, err = pt.PairPath(c.src); err != nil {
+			c.logger.Error("Error creating pairpath", zap.Error(err))
+			return &error_msgs.PtError{ID: id, Err: err}
+		}
+		if c.src, err = pairtree.JoinSubpath(c.src, c.subpath); err != nil {
+			c.logger.Error("Error joining the subpath", zap.Error(err))
+			return &error_msgs.PtError{ID: id, Path: c.subpath, Err: err}
 		}
-		src = filepath.Join(src, subpath)
 		srcIsPairtree = true
-	} else if strings.HasPrefix(dest, prefix) {
-		if dest, err = pairtree.CreatePP(dest, ptRoot, prefix); err != nil {
-			Logger.Error("Error creating pairpath", zap.Error(err))
-			return err
+	} else if strings.HasPrefix(c.dest, prefix) {
+		id = c.dest
+		if c.dest, err = pt.PairPath(c.dest); err != nil {
+			c.logger.Error("Error creating pairpath", zap.Error(err))
+			return &error_msgs.PtError{ID: id, Err: err}
 		}
-		if err = pairtree.CreateDirNotExist(dest); err != nil {
-			return err
+		// The subpath is checked before anything is made in the pairtree
+		pairPath := c.dest
+		if c.dest, err = pairtree.JoinSubpath(pairPath, c.subpath); err != nil {
+			c.logger.Error("Error joining the subpath", zap.Error(err))
+			return &error_msgs.PtError{ID: id, Path: c.subpath, Err: err}
+		}
+		if err = utils.CheckARK(ctx, c.resolver, id, c.out, c.logger); err != nil {
+			return &error_msgs.PtError{ID: id, Err: err}
+		}
+		if !c.dryRun {
+			if err = pairtree.CreateDirNotExist(pairPath); err != nil {
+				return &error_msgs.PtError{ID: id, Path: pairPath, Err: err}
+			}
 		}
-		dest = filepath.Join(dest, subpath)
 	} else {
-		fmt.Fprintln(writer,
-			"Neither the source or destination contains a prefix and is not a part of the pairtree")
-		Logger.Error("Error verifying source and destination",
+		c.out.Error("Neither the source or destination contains a prefix and is not a part of the pairtree")
+		c.logger.Error("Error verifying source and destination",
 			zap.Error(error_msgs.Err10))
 		return error_msgs.Err10
 	}
 
-	fmt.Printf("This is the src: %s \n", src)
-	fmt.Printf("This is the dest: %s \n", dest)
+	objPath := c.dest
+	if srcIsPairtree {
+		objPath = c.src
+		// Comparing the checksums of the object with those of its copy is a fixity check of the object
+		if !c.dryRun && c.copyOpts.Verify {
+			dest := c.dest
+			defer func() {
+				utils.RecordFixityCheck(c.ptRoot, prefix, id, "checksums compared with the copy at "+dest, err,
+					c.out, c.logger)
+			}()
+		}
+	} else if !c.dryRun {
+		// Copying into the pairtree is an ingest that is kept in the object's event history
+		detail := "copied from " + c.src
+		if c.tar && c.src == stdio {
+			detail = "copied from standard input"
+		}
+		src := c.src
+		defer func() {
+			utils.RecordEvent(c.ptRoot, prefix, premis.NewEvent(premis.Ingestion, id, detail, err), c.out, c.logger)
+			if c.copyOpts.Verify {
+				utils.RecordFixityCheck(c.ptRoot, prefix, id, "checksums compared with "+src, err, c.out, c.logger)
+			}
+		}()
+	}
 
-	if tar {
-		if srcIsPairtree {
-			if err = pairtree.TarGz(src, dest, prefix, overwrite); err != nil {
-				Logger.Error("Error compressing pairtree object", zap.Error(err))
-				return err
+	c.out.Info("This is the src: %s", c.src)
+	c.out.Info("This is the dest: %s", c.dest)
+
+	// The format of an archive that is unpacked is found from its extension unless it is given
+	if c.format == "" && !srcIsPairtree {
+		c.format = pairtree.ArchiveFormat(c.src)
+	}
+
+	if !c.tar {
+		if err = c.checkDestination(ctx, c.src, c.dest); err != nil {
+			return &error_msgs.PtError{ID: id, Path: objPath, Err: err}
+		}
+	}
+
+	if c.dryRun {
+		return c.preview(ctx, srcIsPairtree, prefix, id, objPath)
+	}
+
+	if c.tar {
+		if srcIsPairtree && c.dest == stdio {
+			if err = pairtree.WriteArchive(ctx, writer, c.src, c.format, c.archiveOpts); err != nil {
+				c.logger.Error("Error compressing pairtree object", zap.Error(err))
+				return &error_msgs.PtError{ID: id, Path: objPath, Err: err}
+			}
+		} else if srcIsPairtree {
+			archive := pairtree.ArchiveDestination(c.src, c.dest, prefix, c.format, c.overwrite)
+			if err = pairtree.Archive(ctx, c.src, c.dest, prefix, c.format, c.overwrite, c.archiveOpts); err != nil {
+				c.logger.Error("Error compressing pairtree object", zap.Error(err))
+				return &error_msgs.PtError{ID: id, Path: objPath, Err: err}
+			}
+			if err = c.verifyArchive(ctx, archive, c.src); err != nil {
+				return &error_msgs.PtError{ID: id, Path: objPath, Err: err}
+			}
+		} else if c.src == stdio {
+			if err = pairtree.ReadArchive(ctx, c.in, c.dest, c.format); err != nil {
+				c.logger.Error("Error decompressing the archive", zap.Error(err))
+				return &error_msgs.PtError{ID: id, Path: objPath, Err: err}
 			}
 		} else {
-			if err = pairtree.UnTarGz(src, dest); err != nil {
-				Logger.Error("Error decompressing .tgz file", zap.Error(err))
-				return err
+			if err = pairtree.UnArchive(ctx, c.src, c.dest, c.format); err != nil {
+				c.logger.Error("Error decompressing the archive", zap.Error(err))
+				return &error_msgs.PtError{ID: id, Path: objPath, Err: err}
+			}
+			if err = c.verifyArchive(ctx, c.src, c.dest); err != nil {
+				return &error_msgs.PtError{ID: id, Path: objPath, Err: err}
 			}
 		}
 	} else {
-		finalDest, err := pairtree.CopyFileOrFolder(src, dest, overwrite)
+		finalDest, err := pairtree.CopyFileOrFolder(ctx, c.src, c.dest, c.overwrite, c.copyOpts)
 
 		if err != nil {
-			Logger.Error("Error copying source to destination", zap.Error(err))
-			return err
+			c.logger.Error("Error copying source to destination", zap.Error(err))
+			return &error_msgs.PtError{ID: id, Path: objPath, Err: err}
 		} else {
-			Logger.Info("Folder or file was successfully copied to",
+			c.logger.Info("Folder or file was successfully copied to",
 				zap.String("destination of File or Folder", finalDest))
 		}
+		if c.copyOpts.Verify {
+			c.out.Success("Verified the checksums of %s", finalDest)
+		}
+	}
+
+	return nil
+}
+
+// verifyArchive compares the checksums of the archive with those of the directory it was made from or
+// extracted to when --verify is used
+func (c *command) verifyArchive(ctx context.Context, archive, dir string) error {
+	if !c.copyOpts.Verify {
+		return nil
+	}
+
+	if err := pairtree.VerifyArchive(ctx, archive, c.format, dir, c.archiveOpts.Cache); err != nil {
+		c.logger.Error("Error verifying the archive", zap.Error(err))
+		return err
+	}
+
+	c.out.Success("Verified the checksums of %s", archive)
+
+	return nil
+}
+
+// copyBetweenObjects copies the source object in the source pairtree, or the subpath of it given with -n,
+// into the destination object in the destination pairtree, which can be the same one. The destination
+// object is made when it does not exist. The files and folders of a whole object are copied into the
+// destination object rather than the folder of the object itself, so the destination duplicates it.
+func (c *command) copyBetweenObjects(ctx context.Context, srcPT, destPT *pairtree.Pairtree) (err error) {
+	srcID, destID := c.src, c.dest
+	srcRoot, destRoot := srcPT.Root(), destPT.Root()
+
+	if c.tar {
+		err := fmt.Errorf("%w: -a can not be used to copy one pairtree object to another", error_msgs.Err17)
+		c.logger.Error("Error parsing ptcp", zap.Error(err))
+
+		return err
+	}
+
+	srcPath, err := srcPT.PairPath(srcID)
+	if err != nil {
+		c.logger.Error("Error creating pairpath", zap.Error(err))
+		return &error_msgs.PtError{ID: srcID, Err: err}
+	}
+	src, err := pairtree.JoinSubpath(srcPath, c.subpath)
+	if err != nil {
+		c.logger.Error("Error joining the subpath", zap.Error(err))
+		return &error_msgs.PtError{ID: srcID, Path: c.subpath, Err: err}
+	}
+
+	destPath, err := destPT.PairPath(destID)
+	if err != nil {
+		c.logger.Error("Error creating pairpath", zap.Error(err))
+		return &error_msgs.PtError{ID: destID, Err: err}
+	}
+
+	if srcPath == destPath && c.subpath == "" {
+		err := fmt.Errorf("%w: an object can not be copied into itself", error_msgs.Err17)
+		c.logger.Error("Error parsing ptcp", zap.Error(err))
+
+		return err
+	}
+
+	storage, err := pairtree.StorageFor(ctx, src)
+	if err != nil {
+		return err
+	}
+
+	// A subpath is copied as it is, and a whole object as each of its files and folders
+	sources := []string{src}
+	if c.subpath == "" {
+		entries, err := storage.ReadDir(src)
+		if err != nil {
+			c.logger.Error("Error reading the source object", zap.Error(err))
+			return &error_msgs.PtError{ID: srcID, Path: src, Err: err}
+		}
+
+		sources = sources[:0]
+		for _, entry := range entries {
+			sources = append(sources, pairtree.JoinPath(src, entry.Name()))
+		}
+	} else if _, err := storage.Stat(src); err != nil {
+		c.logger.Error("Error reading the source object", zap.Error(err))
+		return &error_msgs.PtError{ID: srcID, Path: src, Err: err}
+	}
+
+	// The sources are copied into the destination object whether or not it exists yet
+	destDir := destPath + string(os.PathSeparator)
+	if pairtree.IsS3(destPath) {
+		destDir = destPath + "/"
+	}
+
+	for _, source := range sources {
+		if err := c.checkDestination(ctx, source, destDir); err != nil {
+			return &error_msgs.PtError{ID: srcID, Path: source, Err: err}
+		}
+	}
+
+	if err = utils.CheckARK(ctx, c.resolver, destID, c.out, c.logger); err != nil {
+		return &error_msgs.PtError{ID: destID, Err: err}
+	}
+
+	if c.dryRun {
+		for _, c.src = range sources {
+			c.dest = destDir
+			if err := c.preview(ctx, true, srcPT.Prefix(), srcID, src); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if err = pairtree.CreateDirNotExist(destPath); err != nil {
+		return &error_msgs.PtError{ID: destID, Path: destPath, Err: err}
+	}
+
+	// The copy is an ingest into the destination object that is kept in its event history, along with the
+	// fixity check of the copy when it is verified
+	source := srcID
+	if c.subpath != "" {
+		source += " " + c.subpath
+	}
+	if srcRoot != destRoot {
+		source += " in " + srcRoot
+	}
+	defer func() {
+		utils.RecordEvent(destRoot, destPT.Prefix(), premis.NewEvent(premis.Ingestion, destID, "copied from "+source, err),
+			c.out, c.logger)
+		if c.copyOpts.Verify {
+			utils.RecordFixityCheck(destRoot, destPT.Prefix(), destID, "checksums compared with "+source, err,
+				c.out, c.logger)
+		}
+	}()
+
+	for _, source := range sources {
+		c.out.Info("This is the src: %s", source)
+		c.out.Info("This is the dest: %s", destPath)
+
+		finalDest, err := pairtree.CopyFileOrFolder(ctx, source, destDir, c.overwrite, c.copyOpts)
+		if err != nil {
+			c.logger.Error("Error copying source to destination", zap.Error(err))
+			return &error_msgs.PtError{ID: destID, Path: destPath, Err: err}
+		}
+
+		c.logger.Info("Folder or file was successfully copied to",
+			zap.String("destination of File or Folder", finalDest))
+		if c.copyOpts.Verify {
+			c.out.Success("Verified the checksums of %s", finalDest)
+		}
+	}
+
+	return nil
+}
+
+// checkDestination refuses a copy of the source that would end up at the source itself or inside it,
+// which would truncate a file that is overwritten with itself or copy a directory into itself endlessly
+func (c *command) checkDestination(ctx context.Context, src, dest string) error {
+	finalDest, err := pairtree.CopyDestination(ctx, src, dest, c.overwrite)
+	if err != nil {
+		return err
+	}
+
+	rel, err := filepath.Rel(src, finalDest)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil
+	}
+
+	err = fmt.Errorf("%w: %s can not be copied onto or into itself", error_msgs.Err17, src)
+	c.logger.Error("Error parsing ptcp", zap.Error(err))
+
+	return err
+}
+
+// preview reports what the copy would do for --dry-run without changing anything. It fails like the
+// copy would when the source does not exist.
+func (c *command) preview(ctx context.Context, srcIsPairtree bool, prefix, id, objPath string) error {
+	if !c.tar {
+		finalDest, err := pairtree.CopyDestination(ctx, c.src, c.dest, c.overwrite)
+		if err != nil {
+			return &error_msgs.PtError{ID: id, Path: objPath, Err: err}
+		}
+
+		storage, err := pairtree.StorageFor(ctx, finalDest)
+		if err != nil {
+			return err
+		}
+
+		if _, err := storage.Stat(finalDest); err == nil {
+			c.out.DryRun("Would overwrite %s", finalDest)
+		}
+		c.out.DryRun("Would copy %s to %s", c.src, finalDest)
+
+		return nil
+	}
+
+	if c.src != stdio {
+		if _, err := os.Stat(c.src); err != nil {
+			return &error_msgs.PtError{ID: id, Path: objPath, Err: err}
+		}
+	}
+
+	switch {
+	case srcIsPairtree && c.dest == stdio:
+		c.out.DryRun("Would write the archive of %s to standard output", c.src)
+	case srcIsPairtree:
+		archive := pairtree.ArchiveDestination(c.src, c.dest, prefix, c.format, c.overwrite)
+		if _, err := os.Stat(archive); err == nil {
+			c.out.DryRun("Would overwrite %s", archive)
+		}
+		c.out.DryRun("Would archive %s to %s", c.src, archive)
+	default:
+		// Extracting an archive replaces the whole destination
+		if _, err := os.Stat(c.dest); err == nil {
+			c.out.DryRun("Would replace %s", c.dest)
+		}
+
+		if c.src == stdio {
+			c.out.DryRun("Would extract the archive on standard input to %s", c.dest)
+		} else {
+			c.out.DryRun("Would extract %s to %s", c.src, c.dest)
+		}
 	}
 
 	return nil
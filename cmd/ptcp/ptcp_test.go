@@ -2,11 +2,19 @@ package ptcp
 
 import (
 	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
-	"github.com/UCLALibrary/pt-tools/testutils"
+	"github.com/UCLALibrary/pt-tools/pkg/pairtree"
+	"github.com/UCLALibrary/pt-tools/pkg/premis"
+	"github.com/UCLALibrary/pt-tools/pkg/pttest"
+	"github.com/UCLALibrary/pt-tools/utils"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -17,6 +25,16 @@ const (
 	rootDir = "pairtree_root"
 )
 
+// TestMain runs the tests with the logger of the test sink, it is set once so that the tests can run in parallel
+func TestMain(m *testing.M) {
+	logger, cleanup := pttest.SetupLogger()
+	Logger = logger
+
+	code := m.Run()
+	cleanup()
+	os.Exit(code)
+}
+
 // Test the basic copy functionality of PTCP
 func TestPTCP(t *testing.T) {
 	tests := []struct {
@@ -77,32 +95,29 @@ func TestPTCP(t *testing.T) {
 		},
 	}
 
-	// Create a logger instance using the registered sink.
-	logger, cleanup := testutils.SetupLogger(logFile)
-	defer cleanup()
-	Logger = logger
-
 	fs := afero.NewOsFs()
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
 			var buf bytes.Buffer
 			var args []string
 			var finalSrc string
 			var finalDest string
-			srcDir := testutils.CreateTempDir(t, fs)
-			destDir := testutils.CreateTempDir(t, fs)
+			srcDir := pttest.CreateTempDir(t, fs)
+			destDir := pttest.CreateTempDir(t, fs)
 			if test.src == "" {
 				//pairtree is the dest
-				testutils.CopyTestDirectory(t, testutils.TestPairtree, destDir)
+				pttest.StandardPairtree().Build(t, fs, destDir)
 				// create file to copy to dest
-				fileInSrc := testutils.CreateFileInDir(t, srcDir, "file.txt")
+				fileInSrc := pttest.CreateFileInDir(t, srcDir, "file.txt")
 				args = []string{root + destDir, fileInSrc, test.dest}
 				finalSrc = srcDir
 				finalDest = filepath.Join(destDir, rootDir, test.pairpath)
 			} else {
 				// pairtree is the src
-				testutils.CopyTestDirectory(t, testutils.TestPairtree, srcDir)
+				pttest.StandardPairtree().Build(t, fs, srcDir)
 				args = []string{root + srcDir, test.src, destDir}
 				finalSrc = filepath.Join(srcDir, rootDir, test.pairpath)
 				finalDest = filepath.Join(destDir, filepath.Base(test.pairpath))
@@ -116,7 +131,7 @@ func TestPTCP(t *testing.T) {
 			require.ErrorIs(t, err, test.expectErr)
 
 			if test.expectErr == nil {
-				err = testutils.CheckDirCopy(fs, finalSrc, finalDest, filepath.Base(test.pairpath))
+				err = pttest.CheckDirCopy(fs, finalSrc, finalDest, filepath.Base(test.pairpath))
 				assert.NoError(t, err, "Expected no error, but got one")
 			}
 		})
@@ -125,34 +140,318 @@ func TestPTCP(t *testing.T) {
 
 // TestTar tests if an object in the pairtree is properly tared outside of it
 func TestTar(t *testing.T) {
-	// Create a logger instance using the registered sink.
-	logger, cleanup := testutils.SetupLogger(logFile)
-	defer cleanup()
-	Logger = logger
-
 	src := "ark:/a5388"
 	tgzFile := "ark+=a5388.tgz"
 
-	err := testutils.TarCLI(t, Run, src, tgzFile)
+	err := pttest.TarCLI(t, Run, src, tgzFile)
 	assert.ErrorIs(t, err, nil, "There was an error with the Tar aspect of ptcp %v", err)
 
 }
 
 // TestUnTar tests untarring a .tgz into a pairtree object
 func TestUnTar(t *testing.T) {
-	// Create a logger instance using the registered sink.
-	logger, cleanup := testutils.SetupLogger(logFile)
-	defer cleanup()
-	Logger = logger
-
 	dest := "ark:/a5388"
 	pairpath := filepath.Join(rootDir, "a5", "38", "8", "a5388")
 	ppBase := "a5388"
 
-	err := testutils.UntarCLI(t, Run, dest, pairpath, ppBase, false)
+	err := pttest.UntarCLI(t, Run, dest, pairpath, ppBase, false)
 	assert.ErrorIs(t, err, nil)
 }
 
+// TestTarPipe tests that an object archived to standard output can be unpacked from standard input
+func TestTarPipe(t *testing.T) {
+	fs := afero.NewOsFs()
+	srcRoot := pttest.StandardPairtree().BuildTemp(t, fs)
+	destRoot := pttest.NewPairtreeBuilder().BuildTemp(t, fs)
+
+	var archive bytes.Buffer
+	err := utils.RunSubcommand(NewCommand(&archive), []string{root + srcRoot, "--quiet", "-a", "ark:/b5488", "-"}, &archive)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	err = utils.RunSubcommandWithInput(NewCommand(&buf), []string{root + destRoot, "-a", "-", "ark:/b5488"}, &archive, &buf)
+	require.NoError(t, err)
+
+	object := filepath.Join(destRoot, rootDir, "b5", "48", "8", "b5488")
+	for _, path := range []string{"outerb5488.txt", "folder/innerb5488.txt", "folder/.hiddenFile.txt", "folder/.hidden/inner.txt"} {
+		assert.FileExists(t, filepath.Join(object, path))
+	}
+
+	// Nothing is left beside the object from extracting it
+	entries, err := os.ReadDir(filepath.Dir(object))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "b5488", entries[0].Name())
+}
+
+// TestZip tests that an object copied out of the pairtree as a zip archive can be copied back into another
+func TestZip(t *testing.T) {
+	fs := afero.NewOsFs()
+	srcRoot := pttest.StandardPairtree().BuildTemp(t, fs)
+	destRoot := pttest.NewPairtreeBuilder().BuildTemp(t, fs)
+	archives := pttest.CreateTempDir(t, fs)
+
+	var buf bytes.Buffer
+	require.NoError(t, Run([]string{root + srcRoot, "-a", "--format", "zip", "ark:/b5488", archives}, &buf))
+
+	archive := filepath.Join(archives, "ark+=b5488.zip")
+	require.FileExists(t, archive)
+
+	// The format of the archive is found from its extension
+	require.NoError(t, Run([]string{root + destRoot, "-a", archive, "ark:/b5488"}, &buf))
+
+	object := filepath.Join(destRoot, rootDir, "b5", "48", "8", "b5488")
+	for _, path := range []string{"outerb5488.txt", "folder/innerb5488.txt", "folder/.hiddenFile.txt", "folder/.hidden/inner.txt"} {
+		assert.FileExists(t, filepath.Join(object, path))
+	}
+}
+
+// TestIDsFrom tests that each object with an ID read from standard input is archived to the destination
+func TestIDsFrom(t *testing.T) {
+	fs := afero.NewOsFs()
+	srcRoot := pttest.StandardPairtree().BuildTemp(t, fs)
+	archives := pttest.CreateTempDir(t, fs)
+
+	var buf bytes.Buffer
+	ids := strings.NewReader("ark:/a5388\nark:/notAnObject\nark:/b5488\n")
+	err := utils.RunSubcommandWithInput(NewCommand(&buf), []string{root + srcRoot, "-a", "--ids-from", "-", archives}, ids, &buf)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	var ptErr *error_msgs.PtError
+	require.ErrorAs(t, err, &ptErr)
+	assert.Equal(t, "ark:/notAnObject", ptErr.ID)
+
+	assert.FileExists(t, filepath.Join(archives, "ark+=a5388.tgz"))
+	assert.FileExists(t, filepath.Join(archives, "ark+=b5488.tgz"))
+
+	// The archives of more than one object are not written to standard output
+	ids = strings.NewReader("ark:/a5388\n")
+	err = utils.RunSubcommandWithInput(NewCommand(&buf), []string{root + srcRoot, "-a", "--ids-from", "-", "-"}, ids, &buf)
+	assert.ErrorIs(t, err, error_msgs.Err17)
+}
+
+// TestDryRun tests that what would be copied or overwritten is printed without changing anything
+func TestDryRun(t *testing.T) {
+	fs := afero.NewOsFs()
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, fs)
+	out := pttest.CreateTempDir(t, fs)
+	objPath := filepath.Join(ptRoot, rootDir, "b5", "48", "8", "b5488")
+	require.NoError(t, os.MkdirAll(filepath.Join(out, "b5488"), 0755))
+
+	var buf bytes.Buffer
+	require.NoError(t, Run([]string{root + ptRoot, "--dry-run", "-d", "ark:/b5488", out}, &buf))
+	assert.Contains(t, buf.String(), "Would overwrite "+filepath.Join(out, "b5488")+"\n")
+	assert.Contains(t, buf.String(), "Would copy "+objPath+" to "+filepath.Join(out, "b5488")+"\n")
+
+	buf.Reset()
+	require.NoError(t, Run([]string{root + ptRoot, "--dry-run", "-a", "--format", "zip", "ark:/b5488", out}, &buf))
+	assert.Contains(t, buf.String(), "Would archive "+objPath+" to "+filepath.Join(out, "ark+=b5488.zip")+"\n")
+
+	// An ingest does not make the object or record an event
+	buf.Reset()
+	require.NoError(t, Run([]string{root + ptRoot, "--dry-run", out, "ark:/c5498"}, &buf))
+	assert.Contains(t, buf.String(), "Would copy "+out+" to ")
+	assert.NoDirExists(t, filepath.Join(ptRoot, rootDir, "c5"))
+
+	entries, err := os.ReadDir(out)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	err = Run([]string{root + ptRoot, "--dry-run", "ark:/notAnObject", out}, &buf)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+// TestSubpathOutside tests that a subpath that reaches outside of the object is refused before copying
+func TestSubpathOutside(t *testing.T) {
+	fs := afero.NewOsFs()
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, fs)
+	out := pttest.CreateTempDir(t, fs)
+
+	var buf bytes.Buffer
+	err := Run([]string{root + ptRoot, "-n", "../../../..", "ark:/b5488", out}, &buf)
+	assert.ErrorIs(t, err, error_msgs.Err46)
+
+	// Nothing is made in the pairtree for an ingest
+	err = Run([]string{root + ptRoot, "-n", "../../../../escaped", out, "ark:/c5498"}, &buf)
+	assert.ErrorIs(t, err, error_msgs.Err46)
+	assert.NoDirExists(t, filepath.Join(ptRoot, rootDir, "c5"))
+	assert.NoDirExists(t, filepath.Join(ptRoot, "escaped"))
+}
+
+// TestObjectToObject tests that an object, or a subpath of it, is copied into another object of the pairtree
+func TestObjectToObject(t *testing.T) {
+	fs := afero.NewOsFs()
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, fs)
+	copyPath := filepath.Join(ptRoot, rootDir, "c5", "49", "8", "c5498")
+	a5388 := filepath.Join(ptRoot, rootDir, "a5", "38", "8", "a5388")
+
+	// The dry run reports the copies without making the object
+	var buf bytes.Buffer
+	require.NoError(t, Run([]string{root + ptRoot, "--dry-run", "ark:/b5488", "ark:/c5498"}, &buf))
+	assert.Contains(t, buf.String(), " to "+filepath.Join(copyPath, "outerb5488.txt")+"\n")
+	assert.NoDirExists(t, copyPath)
+
+	// The destination duplicates the source rather than holding its folder
+	require.NoError(t, Run([]string{root + ptRoot, "ark:/b5488", "ark:/c5498"}, &buf))
+	assert.FileExists(t, filepath.Join(copyPath, "outerb5488.txt"))
+	assert.FileExists(t, filepath.Join(copyPath, "folder", "innerb5488.txt"))
+	assert.FileExists(t, filepath.Join(copyPath, "folder", ".hidden", "inner.txt"))
+	assert.NoDirExists(t, filepath.Join(copyPath, "b5488"))
+
+	events, err := premis.Events(ptRoot, "ark:/", "ark:/c5498")
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, premis.Ingestion, events[0].Type)
+	assert.Equal(t, "copied from ark:/b5488", events[0].Detail)
+
+	// A subpath of the source is copied into the existing object, beside what is already there
+	require.NoError(t, Run([]string{root + ptRoot, "-n", "folder", "ark:/b5488", "ark:/a5388"}, &buf))
+	assert.FileExists(t, filepath.Join(a5388, "a5388.txt"))
+	assert.FileExists(t, filepath.Join(a5388, "folder", "innerb5488.txt"))
+
+	// Copying again does not overwrite without -d
+	require.NoError(t, Run([]string{root + ptRoot, "ark:/b5488", "ark:/c5498"}, &buf))
+	assert.FileExists(t, filepath.Join(copyPath, "outerb5488.1.txt"))
+
+	err = Run([]string{root + ptRoot, "ark:/b5488", "ark:/b5488"}, &buf)
+	assert.ErrorIs(t, err, error_msgs.Err17)
+
+	err = Run([]string{root + ptRoot, "-a", "ark:/b5488", "ark:/c5498"}, &buf)
+	assert.ErrorIs(t, err, error_msgs.Err17)
+
+	err = Run([]string{root + ptRoot, "ark:/notAnObject", "ark:/d5498"}, &buf)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	assert.NoDirExists(t, filepath.Join(ptRoot, rootDir, "d5"))
+}
+
+// TestCopyOntoItself tests that a copy that would end up at its source or inside it is refused before
+// anything is copied, rather than truncating the source by overwriting it with itself
+func TestCopyOntoItself(t *testing.T) {
+	fs := afero.NewOsFs()
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, fs)
+	b5488 := filepath.Join(ptRoot, rootDir, "b5", "48", "8", "b5488")
+	outer := filepath.Join(b5488, "outerb5488.txt")
+
+	before := []byte("outer")
+	require.NoError(t, os.WriteFile(outer, before, 0644))
+
+	var buf bytes.Buffer
+	for _, args := range [][]string{
+		{"-d", "-n", "outerb5488.txt", "ark:/b5488", "ark:/b5488"},
+		{"-d", "-n", "folder", "ark:/b5488", "ark:/b5488"},
+		{"-d", "ark:/b5488", filepath.Dir(b5488)},
+		{"-d", "ark:/b5488", filepath.Join(b5488, "folder")},
+	} {
+		err := Run(append([]string{root + ptRoot}, args...), &buf)
+		assert.ErrorIs(t, err, error_msgs.Err17, args)
+	}
+
+	after, err := os.ReadFile(outer)
+	require.NoError(t, err)
+	assert.Equal(t, before, after)
+	assert.NoDirExists(t, filepath.Join(b5488, "folder", "b5488"))
+	assert.FileExists(t, filepath.Join(b5488, "folder", "innerb5488.txt"))
+
+	// A subpath that is given a unique name beside itself is still copied
+	require.NoError(t, Run([]string{root + ptRoot, "-n", "outerb5488.txt", "ark:/b5488", "ark:/b5488"}, &buf))
+	assert.FileExists(t, filepath.Join(b5488, "outerb5488.1.txt"))
+}
+
+// TestRoots tests that an object is copied from one pairtree into another with --src-root and --dest-root
+func TestRoots(t *testing.T) {
+	fs := afero.NewOsFs()
+	staging := pttest.StandardPairtree().BuildTemp(t, fs)
+	prod := pttest.NewPairtreeBuilder().BuildTemp(t, fs)
+	prodPath := filepath.Join(prod, rootDir, "b5", "48", "8", "b5488")
+
+	// The object keeps its ID and pairpath, and the pairtree root is not needed when both roots are set
+	var buf bytes.Buffer
+	require.NoError(t, Run([]string{"--src-root", staging, "--dest-root", prod, "ark:/b5488"}, &buf))
+	assert.FileExists(t, filepath.Join(prodPath, "outerb5488.txt"))
+	assert.FileExists(t, filepath.Join(prodPath, "folder", "innerb5488.txt"))
+	assert.DirExists(t, filepath.Join(staging, rootDir, "b5", "48", "8", "b5488"))
+
+	events, err := premis.Events(prod, "ark:/", "ark:/b5488")
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "copied from ark:/b5488 in "+staging, events[0].Detail)
+
+	// The root that is not set is the pairtree root, and the object can be given another ID
+	require.NoError(t, Run([]string{root + prod, "--src-root", staging, "ark:/a5388", "ark:/c5498"}, &buf))
+	assert.FileExists(t, filepath.Join(prod, rootDir, "c5", "49", "8", "c5498", "a5388.txt"))
+
+	err = Run([]string{"--src-root", staging, "ark:/b5488"}, &buf)
+	assert.ErrorIs(t, err, error_msgs.Err7)
+
+	ids := filepath.Join(pttest.CreateTempDir(t, fs), "ids.txt")
+	require.NoError(t, os.WriteFile(ids, []byte("ark:/b5488\n"), 0644))
+	err = Run([]string{"--src-root", staging, "--dest-root", prod, "--ids-from", ids, "ark:/b5488"}, &buf)
+	assert.ErrorIs(t, err, error_msgs.Err17)
+}
+
+// TestVerify tests that copies and archives are verified against their sources with --verify
+func TestVerify(t *testing.T) {
+	fs := afero.NewOsFs()
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, fs)
+	prod := pttest.NewPairtreeBuilder().BuildTemp(t, fs)
+	dest := pttest.CreateTempDir(t, fs)
+
+	var buf bytes.Buffer
+	require.NoError(t, Run([]string{root + ptRoot, "--verify", "ark:/b5488", dest}, &buf))
+	assert.Contains(t, buf.String(), "Verified the checksums of "+filepath.Join(dest, "b5488"))
+
+	archive := filepath.Join(dest, "ark+=b5488.tgz")
+	require.NoError(t, Run([]string{root + ptRoot, "--verify", "-a", "ark:/b5488", dest}, &buf))
+	assert.Contains(t, buf.String(), "Verified the checksums of "+archive)
+
+	// The archive is verified against the object it is unpacked into
+	buf.Reset()
+	require.NoError(t, Run([]string{root + prod, "--verify", "-a", archive, "ark:/b5488"}, &buf))
+	assert.Contains(t, buf.String(), "Verified the checksums of "+archive)
+	assert.FileExists(t, filepath.Join(prod, rootDir, "b5", "48", "8", "b5488", "folder", "innerb5488.txt"))
+
+	// Each verified copy is a fixity check of the object in the pairtree
+	for ptRoot, details := range map[string][]string{
+		ptRoot: {"checksums compared with the copy at " + dest, "checksums compared with the copy at " + dest},
+		prod:   {"checksums compared with " + archive},
+	} {
+		var fixity []string
+		events, err := premis.Events(ptRoot, "ark:/", "ark:/b5488")
+		require.NoError(t, err)
+		for _, event := range events {
+			if event.Type == premis.FixityCheck {
+				assert.Equal(t, premis.Success, event.Outcome)
+				fixity = append(fixity, event.Detail)
+			}
+		}
+		assert.Equal(t, details, fixity)
+	}
+
+	// With --warm-cache the archive is verified against the checksums of the files taken as they were archived
+	buf.Reset()
+	zipArchive := filepath.Join(dest, "ark+=b5488.zip")
+	require.NoError(t, Run([]string{root + ptRoot, "--verify", "--warm-cache", "-a", "--format=zip", "ark:/b5488", dest}, &buf))
+	assert.Contains(t, buf.String(), "Verified the checksums of "+zipArchive)
+}
+
+// TestZstd tests that an object archived with Zstandard compression can be copied back into another pairtree
+func TestZstd(t *testing.T) {
+	fs := afero.NewOsFs()
+	srcRoot := pttest.StandardPairtree().BuildTemp(t, fs)
+	destRoot := pttest.NewPairtreeBuilder().BuildTemp(t, fs)
+	archives := pttest.CreateTempDir(t, fs)
+
+	var buf bytes.Buffer
+	require.NoError(t, Run([]string{root + srcRoot, "-a", "--compress", "zstd", "ark:/b5488", archives}, &buf))
+
+	archive := filepath.Join(archives, "ark+=b5488.tzst")
+	require.FileExists(t, archive)
+	require.NoError(t, Run([]string{root + destRoot, "-a", archive, "ark:/b5488"}, &buf))
+
+	object := filepath.Join(destRoot, rootDir, "b5", "48", "8", "b5488")
+	assert.FileExists(t, filepath.Join(object, "folder", "innerb5488.txt"))
+}
+
 // TestCLIError tests if an error is thrown when various CLI options are missing or are wrong
 func TestCLIError(t *testing.T) {
 	tests := []struct {
@@ -180,15 +479,37 @@ func TestCLIError(t *testing.T) {
 			args:      []string{root + "root", "ID", "Destination", "-a", "-n" + "subpath"},
 			expectErr: error_msgs.Err11,
 		},
+		{
+			name:      "Negative compress workers",
+			args:      []string{root + "root", "ID", "Destination", "-a", "--compress-workers=-1"},
+			expectErr: error_msgs.Err17,
+		},
+		{
+			name:      "Unknown archive format",
+			args:      []string{root + "root", "ID", "Destination", "-a", "--format=rar"},
+			expectErr: error_msgs.Err17,
+		},
+		{
+			name:      "Unknown compression",
+			args:      []string{root + "root", "ID", "Destination", "-a", "--compress=xz"},
+			expectErr: error_msgs.Err17,
+		},
+		{
+			name:      "Verify an archive on standard output",
+			args:      []string{root + "root", "ID", "-", "-a", "--verify"},
+			expectErr: error_msgs.Err17,
+		},
+		{
+			name:      "Compression of a zip archive",
+			args:      []string{root + "root", "ID", "Destination", "-a", "--format=zip", "--compress=zstd"},
+			expectErr: error_msgs.Err17,
+		},
 	}
 
-	// Create a logger instance using the registered sink.
-	logger, cleanup := testutils.SetupLogger(logFile)
-	defer cleanup()
-	Logger = logger
-
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
 			var buf bytes.Buffer
 
 			err := Run(test.args, &buf)
@@ -197,3 +518,110 @@ func TestCLIError(t *testing.T) {
 	}
 
 }
+
+// TestQuiet tests if the informational output is only written when --quiet is not used
+func TestQuiet(t *testing.T) {
+	tests := []struct {
+		name       string
+		quiet      bool
+		expectInfo bool
+	}{
+		{name: "informational output", quiet: false, expectInfo: true},
+		{name: "quiet", quiet: true, expectInfo: false},
+	}
+
+	fs := afero.NewOsFs()
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			srcDir := pttest.CreateTempDir(t, fs)
+			destDir := pttest.CreateTempDir(t, fs)
+			pttest.StandardPairtree().Build(t, fs, srcDir)
+
+			args := []string{root + srcDir, "ark:/a5388", destDir}
+			if test.quiet {
+				args = append(args, "--quiet")
+			}
+
+			err := Run(args, &buf)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expectInfo, strings.Contains(buf.String(), "This is the src"))
+		})
+	}
+}
+
+// TestResolve tests if an object is only copied into the pairtree when its ARK resolves with --resolve
+func TestResolve(t *testing.T) {
+	resolver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ark:/resolves" {
+			http.Redirect(w, r, "https://digital.library.ucla.edu/ark:/resolves", http.StatusFound)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer resolver.Close()
+
+	tests := []struct {
+		name      string
+		id        string
+		expectErr error
+	}{
+		{name: "resolves", id: "ark:/resolves", expectErr: nil},
+		{name: "does not resolve", id: "ark:/typo", expectErr: error_msgs.Err29},
+	}
+
+	fs := afero.NewOsFs()
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			srcDir := pttest.CreateTempDir(t, fs)
+			destDir := pttest.CreateTempDir(t, fs)
+			pttest.StandardPairtree().Build(t, fs, destDir)
+			fileInSrc := pttest.CreateFileInDir(t, srcDir, "file.txt")
+
+			args := []string{root + destDir, "--resolve", "--resolver=" + resolver.URL, fileInSrc, test.id}
+			err := Run(args, &buf)
+			require.ErrorIs(t, err, test.expectErr)
+
+			exists, err := afero.Exists(fs, pttest.StandardPairtree().ObjectPath(destDir, test.id))
+			require.NoError(t, err)
+			assert.Equal(t, test.expectErr == nil, exists)
+		})
+	}
+}
+
+// TestS3 tests if files are copied into and out of a pairtree in S3
+func TestS3(t *testing.T) {
+	fs := afero.NewOsFs()
+	fake := pttest.NewFakeS3()
+	fake.Upload(t, pttest.StandardPairtree().BuildTemp(t, fs), "s3://bucket/pt")
+
+	newClient := pairtree.NewS3Client
+	pairtree.NewS3Client = func(context.Context) (pairtree.S3Client, error) { return fake, nil }
+	t.Cleanup(func() { pairtree.NewS3Client = newClient })
+
+	srcDir := pttest.CreateTempDir(t, fs)
+	fileInSrc := pttest.CreateFileInDir(t, srcDir, "file.txt")
+	content, err := os.ReadFile(fileInSrc)
+	require.NoError(t, err)
+
+	// A new object is made for the copy like it is on the local file system
+	var buf bytes.Buffer
+	require.NoError(t, Run([]string{root + "s3://bucket/pt", fileInSrc, "ark:/b2345"}, &buf))
+	object, ok := fake.Object("bucket", "pt/pairtree_root/b2/34/5/b2345/file.txt")
+	require.True(t, ok)
+	assert.Equal(t, content, object)
+
+	destDir := pttest.CreateTempDir(t, fs)
+	require.NoError(t, Run([]string{root + "s3://bucket/pt", "ark:/b5488", destDir}, &buf))
+	assert.FileExists(t, filepath.Join(destDir, "b5488", "outerb5488.txt"))
+	assert.FileExists(t, filepath.Join(destDir, "b5488", "folder", "innerb5488.txt"))
+
+	err = Run([]string{root + "s3://bucket/pt", "ark:/b5488", destDir, "-a"}, &buf)
+	assert.ErrorIs(t, err, error_msgs.Err41)
+}
@@ -0,0 +1,106 @@
+package ptdocs
+
+/* ptdocs generates documentation for pt and each of its subcommands, starting with troff
+man pages so that pt can be packaged for Linux hosts */
+
+import (
+	"io"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pairtree"
+	"github.com/UCLALibrary/pt-tools/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/cobra/doc"
+	"go.uber.org/zap"
+)
+
+var (
+	// Logger is the logger each run of pt docs starts from, tests replace it to capture the logs
+	Logger *zap.Logger = utils.ConsoleLogger()
+)
+
+// command holds the flags of one run of pt docs so that runs can happen concurrently
+type command struct {
+	outputDir string
+	logger    *zap.Logger
+	out       *utils.Output
+}
+
+func (c *command) initManFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&c.outputDir, "dir", "o", ".", "Directory to write the man pages to")
+}
+
+// NewCommand creates the docs subcommand of pt that writes its output to the writer
+func NewCommand(writer io.Writer) *cobra.Command {
+	c := &command{logger: Logger, out: utils.NewOutput(io.Discard, &utils.Styler{}, false)}
+
+	var docsCmd = &cobra.Command{
+		Use:   "docs [command]",
+		Short: "pt docs generates documentation for pt",
+	}
+
+	var manCmd = &cobra.Command{
+		Use:   "man [FLAGS]",
+		Short: "pt docs man writes troff man pages for pt and each of its commands",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return utils.ConfigureLogger(cmd, &c.logger)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c.out = utils.OutputFromFlags(cmd, writer)
+
+			if len(args) > 0 {
+				c.out.Error("Too many arguments were provided to %s", "pt docs man")
+				c.logger.Error("Error parsing pt docs man", zap.Error(error_msgs.Err8))
+
+				return error_msgs.Err8
+			}
+
+			utils.SilenceUsage(cmd)
+
+			return c.writeManPages(cmd.Root(), writer)
+		},
+	}
+
+	c.initManFlags(manCmd)
+	docsCmd.AddCommand(manCmd)
+
+	return docsCmd
+}
+
+// Run executes pt docs with the given arguments
+func Run(args []string, writer io.Writer) error {
+	if err := utils.RunSubcommand(NewCommand(writer), args, writer); err != nil {
+		Logger.Error("Error running pt docs", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// writeManPages writes a man page for the root command and every command below it
+func (c *command) writeManPages(rootCmd *cobra.Command, writer io.Writer) error {
+	if err := pairtree.CreateDirNotExist(c.outputDir); err != nil {
+		c.logger.Error("Error creating man page directory", zap.Error(err))
+		return err
+	}
+
+	header := &doc.GenManHeader{
+		Title:   "PT",
+		Section: "1",
+		Source:  "pt-tools",
+		Manual:  "pt Manual",
+	}
+
+	// Leave out the "Auto generated by spf13/cobra" footer added to each page
+	rootCmd.DisableAutoGenTag = true
+
+	if err := doc.GenManTree(rootCmd, header, c.outputDir); err != nil {
+		c.logger.Error("Error generating man pages", zap.Error(err))
+		return err
+	}
+
+	c.logger.Info("Man pages were written to", zap.String("directory", c.outputDir))
+	c.out.Success("Man pages were written to %s", c.outputDir)
+
+	return nil
+}
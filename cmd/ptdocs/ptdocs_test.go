@@ -0,0 +1,48 @@
+package ptdocs
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pttest"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMain runs the tests with the logger of the test sink, it is set once so that the tests can run in parallel
+func TestMain(m *testing.M) {
+	logger, cleanup := pttest.SetupLogger()
+	Logger = logger
+
+	code := m.Run()
+	cleanup()
+	os.Exit(code)
+}
+
+// TestMan tests if a man page is written for pt and each of its commands
+func TestMan(t *testing.T) {
+	fs := afero.NewOsFs()
+	tempDir := pttest.CreateTempDir(t, fs)
+	manDir := filepath.Join(tempDir, "man1")
+
+	var buf bytes.Buffer
+	err := Run([]string{"man", "--dir", manDir}, &buf)
+	require.NoError(t, err)
+
+	for _, page := range []string{"pt.1", "pt-docs.1", "pt-docs-man.1"} {
+		content, err := pttest.OpenFileAndCheck(fs, filepath.Join(manDir, page))
+		require.NoError(t, err)
+		assert.Contains(t, string(content), ".TH \"PT\" \"1\"")
+	}
+}
+
+// TestCLIError tests if an error is thrown when too many arguments are passed
+func TestCLIError(t *testing.T) {
+	var buf bytes.Buffer
+	err := Run([]string{"man", "extra"}, &buf)
+	assert.ErrorIs(t, err, error_msgs.Err8)
+}
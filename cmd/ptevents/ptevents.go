@@ -0,0 +1,166 @@
+package ptevents
+
+/* ptevents lists the preservation events, like ingests and deletions, that pt has recorded for a
+Pairtree object. The events are listed in the order they happened, as JSON with --json, or as a
+PREMIS 3 XML document with --xml. The history is kept after the object is deleted, so pt events
+still works on an ID that is no longer in the pairtree. */
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pairtree"
+	"github.com/UCLALibrary/pt-tools/pkg/premis"
+	"github.com/UCLALibrary/pt-tools/utils"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	// Logger is the logger each run of pt events starts from, tests replace it to capture the logs
+	Logger *zap.Logger = utils.ConsoleLogger()
+)
+
+// command holds the flags and arguments of one run of pt events so that runs can happen concurrently
+type command struct {
+	outputXML bool
+	ptRoot    string
+	id        string
+	logger    *zap.Logger
+	out       *utils.Output
+}
+
+func (c *command) initFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&c.outputXML, "xml", false, "Output the events as a PREMIS XML document")
+}
+
+// NewCommand creates the events subcommand of pt that writes its output to the writer
+func NewCommand(writer io.Writer) *cobra.Command {
+	c := &command{logger: Logger, out: utils.NewOutput(io.Discard, &utils.Styler{}, false)}
+
+	var cmd = &cobra.Command{
+		Use:               "events [ID]",
+		Short:             "pt events lists the preservation events recorded for a Pairtree object",
+		ValidArgsFunction: utils.CompleteIDs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return utils.ConfigureLogger(cmd, &c.logger)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+
+			c.out = utils.OutputFromFlags(cmd, writer)
+
+			if c.ptRoot, err = utils.GetPtRoot(cmd, writer); err != nil {
+				return err
+			}
+
+			if len(args) < 1 {
+				c.out.Error("Please provide an ID for the pairtree")
+				c.logger.Error("Error getting ID", zap.Error(error_msgs.Err6))
+
+				return error_msgs.Err6
+			} else if len(args) > 1 {
+				c.out.Error("Too many arguments were provided to %s", "pt events")
+				c.logger.Error("Error parsing pt events", zap.Error(error_msgs.Err8))
+
+				return error_msgs.Err8
+			}
+			c.id = args[0]
+
+			jsonFlag, _ := cmd.Flags().GetBool(utils.JSONFlag)
+			if jsonFlag && c.outputXML {
+				c.logger.Error("Error parsing pt events", zap.Error(error_msgs.Err27))
+				return error_msgs.Err27
+			}
+
+			utils.SilenceUsage(cmd)
+
+			return c.list(writer, jsonFlag)
+		},
+	}
+
+	c.initFlags(cmd)
+
+	return cmd
+}
+
+// Run executes pt events with the given arguments
+func Run(args []string, writer io.Writer) error {
+	if err := utils.RunSubcommand(NewCommand(writer), args, writer); err != nil {
+		Logger.Error("Error running pt events", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// list writes the recorded events of the object to the writer
+func (c *command) list(writer io.Writer, outputJSON bool) error {
+	// check if the pairtree version file exists and is populated
+	if err := pairtree.CheckPTVer(c.ptRoot); err != nil {
+		c.logger.Error("Error with pairtree veresion file", zap.Error(err))
+		return err
+	}
+
+	// Get the prefix from pairtree_prefix file
+	prefix, err := pairtree.GetPrefix(c.ptRoot)
+	if err != nil {
+		c.logger.Error("Error retrieving prefix from pairtree_prefix file", zap.Error(err))
+		return err
+	}
+
+	if prefix == "" {
+		prefix = pairtree.PtPrefix
+	}
+
+	events, err := premis.Events(c.ptRoot, prefix, c.id)
+	if err != nil {
+		c.logger.Error("Error reading the events of the object", zap.Error(err))
+		return &error_msgs.PtError{ID: c.id, Err: err}
+	}
+
+	switch {
+	case c.outputXML:
+		xmlData, err := premis.ToXML(c.id, events)
+		if err != nil {
+			c.logger.Error("Error converting the events to PREMIS XML", zap.Error(err))
+			return err
+		}
+		fmt.Fprintln(writer, string(xmlData))
+	case outputJSON:
+		jsonData, err := json.MarshalIndent(events, "", "  ")
+		if err != nil {
+			c.logger.Error("Error converting the events to JSON", zap.Error(err))
+			return err
+		}
+		fmt.Fprintln(writer, string(jsonData))
+	case len(events) == 0:
+		c.out.Info("No events have been recorded for %s", c.id)
+	default:
+		c.writeEvents(writer, events)
+	}
+
+	return nil
+}
+
+// writeEvents writes a line for each event with its time, type, outcome, and detail
+func (c *command) writeEvents(writer io.Writer, events []premis.Event) {
+	style := c.out.Style()
+
+	for _, event := range events {
+		outcome := style.Success(fmt.Sprintf("%-7s", event.Outcome))
+		if event.Outcome != premis.Success {
+			outcome = style.Error(fmt.Sprintf("%-7s", event.Outcome))
+		}
+
+		fmt.Fprintf(writer, "%s  %-12s  %s  %s\n", event.DateTime.Local().Format(time.RFC3339), event.Type,
+			outcome, event.Detail)
+
+		if event.OutcomeDetail != "" {
+			fmt.Fprintf(writer, "  %s\n", event.OutcomeDetail)
+		}
+	}
+}
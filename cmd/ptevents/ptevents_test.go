@@ -0,0 +1,118 @@
+package ptevents
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/premis"
+	"github.com/UCLALibrary/pt-tools/pkg/pttest"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	root = "--pairtree="
+)
+
+// TestMain runs the tests with the logger of the test sink, it is set once so that the tests can run in parallel
+func TestMain(m *testing.M) {
+	logger, cleanup := pttest.SetupLogger()
+	Logger = logger
+
+	code := m.Run()
+	cleanup()
+	os.Exit(code)
+}
+
+// recordEvents builds a pairtree with an ingest and a failed deletion recorded for ark:/a5388
+func recordEvents(t *testing.T) (string, []premis.Event) {
+	fs := afero.NewOsFs()
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, fs)
+
+	events := []premis.Event{
+		premis.NewEvent(premis.Ingestion, "ark:/a5388", "copied from /tmp/a5388", nil),
+		premis.NewEvent(premis.Deletion, "ark:/a5388", "deleted the object", errors.New("permission denied")),
+	}
+	for _, event := range events {
+		require.NoError(t, premis.Record(ptRoot, "ark:/", event))
+	}
+
+	return ptRoot, events
+}
+
+// TestEvents tests if the recorded events of an object are listed in each output format
+func TestEvents(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected []string
+	}{
+		{name: "plain", args: []string{"ark:/a5388"},
+			expected: []string{"ingestion", "copied from /tmp/a5388", "deletion", "failure", "permission denied"}},
+		{name: "xml", args: []string{"--xml", "ark:/a5388"},
+			expected: []string{`<premis xmlns="http://www.loc.gov/premis/v3"`, "<eventType>ingestion</eventType>",
+				"<objectIdentifierValue>ark:/a5388</objectIdentifierValue>"}},
+		{name: "no events", args: []string{"ark:/b5488"}, expected: []string{"No events have been recorded for ark:/b5488"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ptRoot, _ := recordEvents(t)
+
+			var buf bytes.Buffer
+			err := Run(append([]string{root + ptRoot}, test.args...), &buf)
+			require.NoError(t, err)
+
+			for _, expected := range test.expected {
+				assert.Contains(t, buf.String(), expected)
+			}
+		})
+	}
+}
+
+// TestEventsJSON tests if the events are listed as JSON with --json
+func TestEventsJSON(t *testing.T) {
+	ptRoot, recorded := recordEvents(t)
+
+	var buf bytes.Buffer
+	err := Run([]string{root + ptRoot, "--json", "ark:/a5388"}, &buf)
+	require.NoError(t, err)
+
+	var events []premis.Event
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &events))
+	require.Len(t, events, 2)
+	assert.Equal(t, recorded[0].Identifier, events[0].Identifier)
+	assert.Equal(t, premis.Failure, events[1].Outcome)
+}
+
+// TestCLIError tests if an error is thrown when the arguments are not valid
+func TestCLIError(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		expectErr error
+	}{
+		{name: "No ID provided", args: []string{root + "root"}, expectErr: error_msgs.Err6},
+		{name: "No pairtree root provided", args: []string{"ID"}, expectErr: error_msgs.Err7},
+		{name: "Too many arguments passed in", args: []string{root + "root", "ark:/a5388", "extra"}, expectErr: error_msgs.Err8},
+		{name: "XML and JSON", args: []string{root + "root", "--xml", "--json", "ark:/a5388"}, expectErr: error_msgs.Err27},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+
+			err := Run(test.args, &buf)
+			assert.ErrorIs(t, err, test.expectErr)
+		})
+	}
+}
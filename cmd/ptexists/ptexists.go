@@ -0,0 +1,119 @@
+package ptexists
+
+/* ptexists checks if a Pairtree object exists for scripts, answering with its exit code alone: 0 when
+the object's directory exists, 1 when it does not, and a larger code, as listed in the README, when the
+pairtree or the ID can not be checked. Nothing is written unless --print is given. */
+
+import (
+	"io"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pairtree"
+	"github.com/UCLALibrary/pt-tools/utils"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	// Logger is the logger each run of pt exists starts from, tests replace it to capture the logs
+	Logger *zap.Logger = utils.ConsoleLogger()
+)
+
+// command holds the flags and arguments of one run of pt exists so that runs can happen concurrently
+type command struct {
+	print  bool
+	ptRoot string
+	id     string
+	logger *zap.Logger
+	out    *utils.Output
+}
+
+func (c *command) initFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&c.print, "print", false, "write whether the object exists")
+}
+
+// NewCommand creates the exists subcommand of pt that writes its output to the writer
+func NewCommand(writer io.Writer) *cobra.Command {
+	c := &command{logger: Logger, out: utils.NewOutput(io.Discard, &utils.Styler{}, false)}
+
+	var cmd = &cobra.Command{
+		Use:               "exists [FLAGS] [ID]",
+		Short:             "pt exists checks if a Pairtree object exists, answering with its exit code",
+		ValidArgsFunction: utils.CompleteIDs,
+		Annotations:       map[string]string{utils.S3Annotation: "true"},
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return utils.ConfigureLogger(cmd, &c.logger)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+
+			c.out = utils.OutputFromFlags(cmd, writer)
+
+			if c.ptRoot, err = utils.GetPtRoot(cmd, writer); err != nil {
+				return err
+			}
+
+			if len(args) < 1 {
+				c.out.Error("Please provide an ID for the pairtree")
+				c.logger.Error("Error getting ID", zap.Error(error_msgs.Err6))
+
+				return error_msgs.Err6
+			} else if len(args) > 1 {
+				c.out.Error("Too many arguments were provided to %s", "pt exists")
+				c.logger.Error("Error parsing pt exists", zap.Error(error_msgs.Err8))
+
+				return error_msgs.Err8
+			}
+			c.id = args[0]
+
+			utils.SilenceUsage(cmd)
+
+			return c.exists()
+		},
+	}
+
+	c.initFlags(cmd)
+
+	return cmd
+}
+
+// Run executes pt exists with the given arguments
+func Run(args []string, writer io.Writer) error {
+	if err := utils.RunSubcommand(NewCommand(writer), args, writer); err != nil {
+		Logger.Error("Error running pt exists", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// exists returns Err47 when the object does not exist, which is not written as an error
+func (c *command) exists() error {
+	// Open the pairtree, which checks its version file and reads its prefix
+	pt, err := pairtree.Open(c.ptRoot)
+	if err != nil {
+		c.logger.Error("Error opening the pairtree", zap.Error(err))
+		return err
+	}
+
+	found, err := pt.Exists(c.id)
+	if err != nil {
+		c.logger.Error("Error checking the object", zap.Error(err))
+		return &error_msgs.PtError{ID: c.id, Err: err}
+	}
+
+	if !found {
+		if c.print {
+			c.out.Info("%s does not exist", c.id)
+		}
+		c.logger.Debug("The object does not exist", zap.String("id", c.id))
+
+		return &error_msgs.PtError{ID: c.id, Err: error_msgs.Err47}
+	}
+
+	if c.print {
+		c.out.Info("%s exists", c.id)
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package ptexists
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pttest"
+	"github.com/UCLALibrary/pt-tools/utils"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const root = "--pairtree="
+
+// TestMain runs the tests with the logger of the test sink, it is set once so that the tests can run in parallel
+func TestMain(m *testing.M) {
+	logger, cleanup := pttest.SetupLogger()
+	Logger = logger
+
+	code := m.Run()
+	cleanup()
+	os.Exit(code)
+}
+
+// TestExists tests that whether the object exists is answered with the exit code alone
+func TestExists(t *testing.T) {
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, afero.NewOsFs())
+
+	var buf bytes.Buffer
+	require.NoError(t, Run([]string{root + ptRoot, "ark:/a5388"}, &buf))
+	assert.Empty(t, buf.String())
+
+	err := Run([]string{root + ptRoot, "ark:/a5389"}, &buf)
+	assert.ErrorIs(t, err, error_msgs.Err47)
+	assert.Equal(t, utils.ExitFailure, utils.ExitCode(err))
+	assert.Empty(t, buf.String())
+
+	// --print writes the answer as well
+	require.NoError(t, Run([]string{root + ptRoot, "--print", "ark:/a5388"}, &buf))
+	assert.Equal(t, "ark:/a5388 exists\n", buf.String())
+
+	buf.Reset()
+	assert.ErrorIs(t, Run([]string{root + ptRoot, "--print", "ark:/a5389"}, &buf), error_msgs.Err47)
+	assert.Equal(t, "ark:/a5389 does not exist\n", buf.String())
+}
+
+// TestExistsErrors tests that a pairtree or ID that can not be checked exits with a code above 1
+func TestExistsErrors(t *testing.T) {
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, afero.NewOsFs())
+
+	var buf bytes.Buffer
+	err := Run([]string{root + filepath.Join(ptRoot, "missing"), "ark:/a5388"}, &buf)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	assert.Equal(t, utils.ExitNotFound, utils.ExitCode(err))
+
+	err = Run([]string{root + ptRoot}, &buf)
+	assert.Equal(t, utils.ExitUsage, utils.ExitCode(err))
+
+	err = Run([]string{root + ptRoot, "ark:/a5388", "ark:/b5488"}, &buf)
+	assert.Equal(t, utils.ExitUsage, utils.ExitCode(err))
+}
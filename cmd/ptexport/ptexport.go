@@ -0,0 +1,467 @@
+package ptexport
+
+/* ptexport writes a Pairtree object in a packaging format other repositories accept. With --bagit the
+object is written as a BagIt bag, a directory named like the archives of pt cp with the files of the
+object in data/, a bag-info.txt with its ID, and payload and tag manifests. With --ocfl the destination
+is an OCFL storage root, created when it is empty, that the object is added to with an inventory.json
+and its files in v1/content. With --dirs the object is copied to a directory, and with --archive it is
+written as an archive, both named like the archives of pt cp. With --all every object of the pairtree
+is exported, --jobs at a time. The objects that were exported are kept in the --state file so that an
+export that was stopped can be run again without exporting them twice, and what happened to each
+object is written to the --manifest file. Hidden files are only exported in bags and OCFL with -a. */
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/UCLALibrary/pt-tools/pkg/bagit"
+	"github.com/UCLALibrary/pt-tools/pkg/checksum"
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/ocfl"
+	"github.com/UCLALibrary/pt-tools/pkg/pairtree"
+	"github.com/UCLALibrary/pt-tools/pkg/premis"
+	"github.com/UCLALibrary/pt-tools/utils"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	// Logger is the logger each run of pt export starts from, tests replace it to capture the logs
+	Logger *zap.Logger = utils.ConsoleLogger()
+)
+
+// command holds the flags and arguments of one run of pt export so that runs can happen concurrently
+type command struct {
+	bagit    bool
+	ocfl     bool
+	dirs     bool
+	archive  bool
+	format   string
+	all      bool
+	showAll  bool
+	jobs     int
+	state    string
+	manifest string
+	ptRoot   string
+	id       string
+	dest     string
+	hashOpts checksum.Options
+	logger   *zap.Logger
+	out      *utils.Output
+}
+
+// object is an object of the pairtree that is exported
+type object struct {
+	id       string
+	pairPath string
+}
+
+// The statuses of the objects in the manifest of an export
+const (
+	exported = "exported"
+	skipped  = "skipped"
+	failed   = "failed"
+)
+
+func (c *command) initFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&c.bagit, "bagit", false, "export the object as a BagIt bag")
+	cmd.Flags().BoolVar(&c.ocfl, "ocfl", false, "export the object into an OCFL storage root")
+	cmd.Flags().BoolVar(&c.dirs, "dirs", false, "export the object as a directory named by its encoded ID")
+	cmd.Flags().BoolVar(&c.archive, "archive", false, "export the object as an archive named by its encoded ID")
+	cmd.Flags().StringVar(&c.format, "format", "", "Format of the archive of --archive, one of "+strings.Join(pairtree.Formats, ", ")+" (defaults to tgz)")
+	cmd.Flags().BoolVar(&c.all, "all", false, "export every object of the pairtree")
+	cmd.Flags().IntVar(&c.jobs, "jobs", 1, "Objects exported at once")
+	cmd.Flags().StringVar(&c.state, "state", "", "File of the objects that were exported, which are skipped when the export is run again")
+	cmd.Flags().StringVar(&c.manifest, "manifest", "", "CSV file to write the ID, destination, and status of each object to")
+	cmd.Flags().BoolVarP(&c.showAll, "a", "a", false, "export hidden files and directories")
+	cmd.Flags().StringVar(&c.hashOpts.Algorithm, "algorithm", "",
+		"Algorithm of the manifests, one of "+strings.Join(checksum.Algorithms, ", ")+
+			" (defaults to sha256 for BagIt and sha512 for OCFL, which only allows sha256 or sha512)")
+	cmd.Flags().IntVar(&c.hashOpts.IOLimit, "io-limit", 0, "Reads from files that happen at once while hashing them (defaults to one per CPU)")
+}
+
+// NewCommand creates the export subcommand of pt that writes its output to the writer
+func NewCommand(writer io.Writer) *cobra.Command {
+	c := &command{logger: Logger, out: utils.NewOutput(io.Discard, &utils.Styler{}, false)}
+
+	var cmd = &cobra.Command{
+		Use:               "export --bagit|--ocfl|--dirs|--archive [ID] [/path/to/destination]",
+		Short:             "pt export writes Pairtree objects as BagIt bags, directories, or archives, or into an OCFL storage root",
+		ValidArgsFunction: utils.CompleteIDs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return utils.ConfigureLogger(cmd, &c.logger)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+
+			c.out = utils.OutputFromFlags(cmd, writer)
+
+			if c.ptRoot, err = utils.GetPtRoot(cmd, writer); err != nil {
+				return err
+			}
+
+			// With --all there is no ID, only the destination
+			maxArgs := 2
+			if c.all {
+				maxArgs = 1
+			}
+
+			if len(args) < 1 && !c.all {
+				c.out.Error("Please provide an ID for the pairtree")
+				c.logger.Error("Error getting ID", zap.Error(error_msgs.Err6))
+
+				return error_msgs.Err6
+			} else if len(args) > maxArgs {
+				c.out.Error("Too many arguments were provided to %s", "pt export")
+				c.logger.Error("Error parsing pt export", zap.Error(error_msgs.Err8))
+
+				return error_msgs.Err8
+			}
+
+			if !c.all {
+				c.id, args = args[0], args[1:]
+			}
+
+			c.dest = "."
+			if len(args) == 1 {
+				c.dest = args[0]
+			}
+
+			formats := 0
+			for _, format := range []bool{c.bagit, c.ocfl, c.dirs, c.archive} {
+				if format {
+					formats++
+				}
+			}
+			if formats != 1 {
+				err := fmt.Errorf("%w: the format of the export must be set with one of --bagit, --ocfl, --dirs, or --archive", error_msgs.Err17)
+				c.logger.Error("Error parsing pt export", zap.Error(err))
+
+				return err
+			}
+
+			if c.format != "" && !c.archive {
+				err := fmt.Errorf("%w: --format can only be used with --archive", error_msgs.Err17)
+				c.logger.Error("Error parsing pt export", zap.Error(err))
+
+				return err
+			} else if c.format != "" && !slices.Contains(pairtree.Formats, c.format) {
+				err := fmt.Errorf("%w: --format must be one of %s", error_msgs.Err17, strings.Join(pairtree.Formats, ", "))
+				c.logger.Error("Error parsing pt export", zap.Error(err))
+
+				return err
+			}
+
+			if c.jobs < 1 {
+				err := fmt.Errorf("%w: --jobs must be at least 1", error_msgs.Err17)
+				c.logger.Error("Error parsing pt export", zap.Error(err))
+
+				return err
+			}
+
+			if c.hashOpts.Algorithm != "" && !checksum.Supported(c.hashOpts.Algorithm) {
+				err := fmt.Errorf("%w: --algorithm must be one of %s", error_msgs.Err17,
+					strings.Join(checksum.Algorithms, ", "))
+				c.logger.Error("Error parsing pt export", zap.Error(err))
+
+				return err
+			}
+
+			if c.ocfl && c.hashOpts.Algorithm != "" && !ocfl.Supported(c.hashOpts.Algorithm) {
+				err := fmt.Errorf("%w: --algorithm of an OCFL export must be sha512 or sha256", error_msgs.Err17)
+				c.logger.Error("Error parsing pt export", zap.Error(err))
+
+				return err
+			}
+
+			if c.hashOpts.IOLimit < 0 {
+				err := fmt.Errorf("%w: --io-limit must not be negative", error_msgs.Err17)
+				c.logger.Error("Error parsing pt export", zap.Error(err))
+
+				return err
+			}
+
+			utils.SilenceUsage(cmd)
+
+			return c.export(cmd.Context())
+		},
+	}
+
+	c.initFlags(cmd)
+
+	return cmd
+}
+
+// Run executes pt export with the given arguments
+func Run(args []string, writer io.Writer) error {
+	if err := utils.RunSubcommand(NewCommand(writer), args, writer); err != nil {
+		Logger.Error("Error running pt export", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// export writes the object, or every object with --all, into the destination directory. An object that
+// can not be exported does not stop the export of the others.
+func (c *command) export(ctx context.Context) error {
+	// Open the pairtree, which checks its version file and reads its prefix
+	pt, err := pairtree.Open(c.ptRoot)
+	if err != nil {
+		c.logger.Error("Error opening the pairtree", zap.Error(err))
+		return err
+	}
+
+	if c.ocfl {
+		err = ocfl.CreateRoot(c.dest)
+	} else {
+		err = os.MkdirAll(c.dest, 0755)
+	}
+	if err != nil {
+		c.logger.Error("Error creating the destination directory", zap.Error(err))
+		return err
+	}
+
+	var objects []object
+	var errs []error
+	if !c.all {
+		pairPath, err := pt.PairPath(c.id)
+		if err != nil {
+			c.logger.Error("Error creating pairpath", zap.Error(err))
+			return &error_msgs.PtError{ID: c.id, Err: err}
+		}
+
+		if _, err := os.Stat(pairPath); err != nil {
+			c.logger.Error("Error reading the object", zap.Error(err))
+			return &error_msgs.PtError{ID: c.id, Path: pairPath, Err: err}
+		}
+
+		objects = append(objects, object{id: c.id, pairPath: pairPath})
+	} else if err := pt.WalkObjectsCtx(ctx, func(id, objPath string) error {
+		objects = append(objects, object{id: id, pairPath: objPath})
+		return nil
+	}); err != nil {
+		c.logger.Error("Error walking the pairtree", zap.Error(err))
+		errs = append(errs, err)
+	}
+
+	done, err := c.readState()
+	if err != nil {
+		c.logger.Error("Error reading the state file", zap.Error(err))
+		return err
+	}
+
+	// The objects are written to the state file as they are exported, so it is opened for the whole export
+	var state *csv.Writer
+	if c.state != "" {
+		file, err := os.OpenFile(c.state, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			c.logger.Error("Error opening the state file", zap.Error(err))
+			return err
+		}
+		defer file.Close()
+		state = csv.NewWriter(file)
+	}
+
+	destinations := make([]string, len(objects))
+	statuses := make([]string, len(objects))
+	indexes := map[string]int{}
+	operations := []pairtree.Operation{}
+	for i, obj := range objects {
+		if dest, ok := done[obj.id]; ok {
+			destinations[i], statuses[i] = dest, skipped
+			c.out.Info("Skipped %s, which was exported to %s", obj.id, dest)
+			continue
+		}
+
+		indexes[obj.id] = i
+		operations = append(operations, pairtree.Operation{ID: obj.id, Path: obj.pairPath, Run: func(ctx context.Context) error {
+			var err error
+			destinations[i], err = c.exportObject(ctx, pt, obj.id, obj.pairPath)
+			return err
+		}})
+	}
+
+	var stateErr error
+	batch := pairtree.NewBatch(c.jobs)
+	batch.OnProgress = func(progress pairtree.Progress) {
+		i := indexes[progress.Last.ID]
+		if progress.Last.Err != nil {
+			statuses[i] = failed
+			return
+		}
+
+		statuses[i] = exported
+		c.reportExport(progress.Last.ID, destinations[i])
+
+		if state != nil && stateErr == nil {
+			if stateErr = state.Write([]string{progress.Last.ID, destinations[i]}); stateErr == nil {
+				state.Flush()
+				stateErr = state.Error()
+			}
+		}
+	}
+
+	results, err := batch.Run(ctx, operations)
+	errs = append(errs, err, stateErr)
+
+	if c.manifest != "" {
+		errs = append(errs, c.writeManifest(objects, destinations, statuses, results))
+	}
+
+	return errors.Join(errs...)
+}
+
+// readState returns the destinations of the objects in the state file by their IDs, which is empty
+// when there is no state file or it has not been written yet
+func (c *command) readState() (map[string]string, error) {
+	done := map[string]string{}
+	if c.state == "" {
+		return done, nil
+	}
+
+	file, err := os.Open(c.state)
+	if os.IsNotExist(err) {
+		return done, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	csvReader := csv.NewReader(file)
+	csvReader.FieldsPerRecord = 2
+
+	for {
+		record, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			return done, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		done[record[0]] = record[1]
+	}
+}
+
+// writeManifest writes the ID, destination, and status of each object, and the error of one that failed,
+// to the manifest file
+func (c *command) writeManifest(objects []object, destinations, statuses []string, results []pairtree.Result) error {
+	failures := map[string]string{}
+	for _, result := range results {
+		failures[result.ID] = result.Error
+	}
+
+	file, err := os.Create(c.manifest)
+	if err != nil {
+		c.logger.Error("Error creating the manifest", zap.Error(err))
+		return err
+	}
+	defer file.Close()
+
+	csvWriter := csv.NewWriter(file)
+	if err := csvWriter.Write([]string{"id", "destination", "status", "error"}); err != nil {
+		return err
+	}
+
+	for i, obj := range objects {
+		// An object that was not run before the export was canceled has failed
+		status := statuses[i]
+		if status == "" {
+			status = failed
+		}
+
+		if err := csvWriter.Write([]string{obj.id, destinations[i], status, failures[obj.id]}); err != nil {
+			return err
+		}
+	}
+
+	csvWriter.Flush()
+	if err := csvWriter.Error(); err != nil {
+		return err
+	}
+
+	return file.Close()
+}
+
+// reportExport writes where the object with the ID was exported to in the format of the export
+func (c *command) reportExport(id, dest string) {
+	switch {
+	case c.ocfl:
+		c.out.Success("Exported %s to the OCFL object %s", id, dest)
+	case c.dirs:
+		c.out.Success("Exported %s to the directory %s", id, dest)
+	case c.archive:
+		c.out.Success("Exported %s as the archive %s", id, dest)
+	default:
+		c.out.Success("Exported %s as the bag %s", id, dest)
+	}
+}
+
+// exportObject writes the object with the ID at pairPath in the format of the export and returns where
+// it was written
+func (c *command) exportObject(ctx context.Context, pt *pairtree.Pairtree, id, pairPath string) (string, error) {
+	if c.ocfl {
+		objRoot, err := ocfl.Write(ctx, c.dest, pairPath, id, "exported by pt "+utils.Version, c.showAll, c.hashOpts)
+
+		// Adding the object to an OCFL storage root is a migration that is kept in its event history
+		utils.RecordEvent(pt.Root(), pt.Prefix(), premis.NewEvent(premis.Migration, id,
+			"exported to the OCFL storage root "+c.dest, err), c.out, c.logger)
+		if err != nil {
+			c.logger.Error("Error exporting the object", zap.String("id", id), zap.Error(err))
+			return "", &error_msgs.PtError{ID: id, Path: pairPath, Err: err}
+		}
+
+		c.logger.Info("Exported the object", zap.String("id", id), zap.String("object", objRoot))
+
+		return objRoot, nil
+	}
+
+	if c.archive {
+		format := c.format
+		if format == "" {
+			format = pairtree.TgzFormat
+		}
+
+		archive := pairtree.ArchiveDestination(pairPath, c.dest, pt.Prefix(), format, false)
+		if err := pairtree.Archive(ctx, pairPath, c.dest, pt.Prefix(), format, false, pairtree.ArchiveOptions{}); err != nil {
+			c.logger.Error("Error exporting the object", zap.String("id", id), zap.Error(err))
+			return "", &error_msgs.PtError{ID: id, Path: pairPath, Err: err}
+		}
+
+		c.logger.Info("Exported the object", zap.String("id", id), zap.String("archive", archive))
+
+		return archive, nil
+	}
+
+	// The directory or bag is named like the archives pt cp writes, after the encoded prefix and ID
+	dir := pairtree.GetUniqueDestination(filepath.Join(c.dest, pairtree.ArchiveName(pt.Prefix(), pairPath, "")))
+
+	if c.dirs {
+		if _, err := pairtree.CopyFileOrFolder(ctx, pairPath, dir, false, pairtree.CopyOptions{}); err != nil {
+			c.logger.Error("Error exporting the object", zap.String("id", id), zap.Error(err))
+			return "", &error_msgs.PtError{ID: id, Path: pairPath, Err: err}
+		}
+
+		c.logger.Info("Exported the object", zap.String("id", id), zap.String("directory", dir))
+
+		return dir, nil
+	}
+
+	// A bag that is not finished is removed so it is not mistaken for a complete one
+	if err := bagit.Write(ctx, dir, pairPath, id, "pt "+utils.Version, c.showAll, c.hashOpts); err != nil {
+		err = errors.Join(err, os.RemoveAll(dir))
+		c.logger.Error("Error exporting the object", zap.String("id", id), zap.Error(err))
+		return "", &error_msgs.PtError{ID: id, Path: pairPath, Err: err}
+	}
+
+	c.logger.Info("Exported the object", zap.String("id", id), zap.String("bag", dir))
+
+	return dir, nil
+}
@@ -0,0 +1,256 @@
+package ptexport
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/UCLALibrary/pt-tools/pkg/bagit"
+	"github.com/UCLALibrary/pt-tools/pkg/checksum"
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/ocfl"
+	"github.com/UCLALibrary/pt-tools/pkg/premis"
+	"github.com/UCLALibrary/pt-tools/pkg/pttest"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	root = "--pairtree="
+)
+
+// TestMain runs the tests with the logger of the test sink, it is set once so that the tests can run in parallel
+func TestMain(m *testing.M) {
+	logger, cleanup := pttest.SetupLogger()
+	Logger = logger
+
+	code := m.Run()
+	cleanup()
+	os.Exit(code)
+}
+
+// TestExport tests if the object is exported as a bag in the destination that passes its checks
+func TestExport(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		files     []string
+		manifest  string
+		expectErr error
+	}{
+		{name: "bag", args: []string{"ark:/b5488"}, files: []string{"folder/innerb5488.txt", "outerb5488.txt"},
+			manifest: "manifest-sha256.txt"},
+		{name: "hidden files", args: []string{"-a", "ark:/a54892"},
+			files: []string{".hidden/innerHidden.txt", ".hidden.txt", "a54892.txt"}, manifest: "manifest-sha256.txt"},
+		{name: "algorithm", args: []string{"--algorithm", "sha512", "ark:/a5388"}, files: []string{"a5388.txt"},
+			manifest: "manifest-sha512.txt"},
+		{name: "not an object", args: []string{"ark:/notAnObject"}, expectErr: os.ErrNotExist},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			fs := afero.NewOsFs()
+			ptRoot := pttest.StandardPairtree().BuildTemp(t, fs)
+			dest := pttest.CreateTempDir(t, fs)
+
+			var buf bytes.Buffer
+			err := Run(append(append([]string{root + ptRoot, "--bagit"}, test.args...), dest), &buf)
+			if test.expectErr != nil {
+				assert.ErrorIs(t, err, test.expectErr)
+				return
+			}
+			require.NoError(t, err)
+
+			entries, err := os.ReadDir(dest)
+			require.NoError(t, err)
+			require.Len(t, entries, 1)
+			bagDir := filepath.Join(dest, entries[0].Name())
+
+			assert.FileExists(t, filepath.Join(bagDir, test.manifest))
+			bag, err := bagit.Check(context.Background(), bagDir, checksum.Options{})
+			require.NoError(t, err)
+			assert.Equal(t, test.files, bag.Files)
+			assert.Equal(t, test.args[len(test.args)-1], bag.ID())
+		})
+	}
+}
+
+// TestExportOCFL tests if the objects are added to the OCFL storage root of the destination
+func TestExportOCFL(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		ids       []string
+		expectErr error
+	}{
+		{name: "object", args: []string{"ark:/b5488"}, ids: []string{"ark:/b5488"}},
+		{name: "all objects", args: []string{"--all"}, ids: []string{"ark:/a5388", "ark:/a54892", "ark:/b5488"}},
+		{name: "not an object", args: []string{"ark:/notAnObject"}, expectErr: os.ErrNotExist},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			fs := afero.NewOsFs()
+			ptRoot := pttest.StandardPairtree().BuildTemp(t, fs)
+			dest := filepath.Join(pttest.CreateTempDir(t, fs), "ocfl")
+
+			var buf bytes.Buffer
+			err := Run(append(append([]string{root + ptRoot, "--ocfl"}, test.args...), dest), &buf)
+			if test.expectErr != nil {
+				assert.ErrorIs(t, err, test.expectErr)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.FileExists(t, filepath.Join(dest, ocfl.RootDeclaration))
+			for _, id := range test.ids {
+				objRoot := filepath.Join(dest, filepath.FromSlash(ocfl.ObjectPath(id)))
+				assert.FileExists(t, filepath.Join(objRoot, ocfl.InventoryName+".sha512"))
+				assert.Contains(t, buf.String(), objRoot)
+
+				// The export is a migration of the object that is kept in its event history
+				events, err := premis.Events(ptRoot, "ark:/", id)
+				require.NoError(t, err)
+				require.Len(t, events, 1)
+				assert.Equal(t, premis.Migration, events[0].Type)
+				assert.Equal(t, premis.Success, events[0].Outcome)
+			}
+		})
+	}
+}
+
+// TestExportOCFLNotRoot tests that objects are not exported into a directory that is not an OCFL storage root
+func TestExportOCFLNotRoot(t *testing.T) {
+	fs := afero.NewOsFs()
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, fs)
+
+	var buf bytes.Buffer
+	err := Run([]string{root + ptRoot, "--ocfl", "--all", ptRoot}, &buf)
+	assert.ErrorIs(t, err, error_msgs.Err43)
+}
+
+// TestExportBulk tests if the objects are exported as directories or archives named by their encoded IDs
+func TestExportBulk(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		files []string
+	}{
+		{name: "directory", args: []string{"--dirs", "ark:/b5488"},
+			files: []string{"ark+=b5488/folder/innerb5488.txt", "ark+=b5488/outerb5488.txt"}},
+		{name: "all directories", args: []string{"--dirs", "--all", "--jobs", "3"},
+			files: []string{"ark+=a5388/a5388.txt", "ark+=a54892/a54892.txt", "ark+=b5488/outerb5488.txt"}},
+		{name: "archive", args: []string{"--archive", "ark:/a5388"}, files: []string{"ark+=a5388.tgz"}},
+		{name: "all archives", args: []string{"--archive", "--format", "zip", "--all", "--jobs", "2"},
+			files: []string{"ark+=a5388.zip", "ark+=a5488.zip", "ark+=a54892.zip", "ark+=b5488.zip"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			fs := afero.NewOsFs()
+			ptRoot := pttest.StandardPairtree().BuildTemp(t, fs)
+			dest := pttest.CreateTempDir(t, fs)
+
+			var buf bytes.Buffer
+			err := Run(append(append([]string{root + ptRoot}, test.args...), dest), &buf)
+			require.NoError(t, err)
+
+			for _, file := range test.files {
+				assert.FileExists(t, filepath.Join(dest, filepath.FromSlash(file)))
+			}
+		})
+	}
+}
+
+// TestExportState tests if the objects in the state file are skipped and the manifest records each object
+func TestExportState(t *testing.T) {
+	fs := afero.NewOsFs()
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, fs)
+	dest := pttest.CreateTempDir(t, fs)
+	state := filepath.Join(pttest.CreateTempDir(t, fs), "state.csv")
+	manifest := filepath.Join(pttest.CreateTempDir(t, fs), "manifest.csv")
+	require.NoError(t, os.WriteFile(state, []byte("ark:/a5388,earlier\n"), 0644))
+
+	args := []string{root + ptRoot, "--dirs", "--all", "--jobs", "2", "--state", state, "--manifest", manifest, dest}
+
+	var buf bytes.Buffer
+	require.NoError(t, Run(args, &buf))
+	assert.Contains(t, buf.String(), "Skipped ark:/a5388, which was exported to earlier")
+
+	entries, err := os.ReadDir(dest)
+	require.NoError(t, err)
+	assert.Len(t, entries, 3)
+
+	// The objects of the first run are in the state file, so running it again exports nothing
+	buf.Reset()
+	require.NoError(t, Run(args, &buf))
+
+	entries, err = os.ReadDir(dest)
+	require.NoError(t, err)
+	assert.Len(t, entries, 3)
+
+	file, err := os.Open(manifest)
+	require.NoError(t, err)
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 5)
+	assert.Equal(t, []string{"id", "destination", "status", "error"}, records[0])
+	for _, record := range records[1:] {
+		assert.Equal(t, skipped, record[2])
+	}
+	assert.Equal(t, "earlier", records[1][1])
+}
+
+// TestCLIError tests if an error is thrown when the arguments are not valid
+func TestCLIError(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		expectErr error
+	}{
+		{name: "No ID provided", args: []string{root + "root", "--bagit"}, expectErr: error_msgs.Err6},
+		{name: "No pairtree root provided", args: []string{"--bagit", "ID"}, expectErr: error_msgs.Err7},
+		{name: "Too many arguments passed in", args: []string{root + "root", "--bagit", "ark:/a5388", "dest", "extra"},
+			expectErr: error_msgs.Err8},
+		{name: "Too many arguments with --all", args: []string{root + "root", "--bagit", "--all", "dest", "extra"},
+			expectErr: error_msgs.Err8},
+		{name: "No format", args: []string{root + "root", "ark:/a5388"}, expectErr: error_msgs.Err17},
+		{name: "Both formats", args: []string{root + "root", "--bagit", "--ocfl", "ark:/a5388"}, expectErr: error_msgs.Err17},
+		{name: "Directories and archives", args: []string{root + "root", "--dirs", "--archive", "ark:/a5388"},
+			expectErr: error_msgs.Err17},
+		{name: "Format without archive", args: []string{root + "root", "--dirs", "--format=zip", "ark:/a5388"},
+			expectErr: error_msgs.Err17},
+		{name: "Unsupported format", args: []string{root + "root", "--archive", "--format=rar", "ark:/a5388"},
+			expectErr: error_msgs.Err17},
+		{name: "No jobs", args: []string{root + "root", "--dirs", "--jobs=0", "ark:/a5388"}, expectErr: error_msgs.Err17},
+		{name: "Algorithm OCFL does not allow", args: []string{root + "root", "--ocfl", "--algorithm=md5", "ark:/a5388"},
+			expectErr: error_msgs.Err17},
+		{name: "Unsupported algorithm", args: []string{root + "root", "--bagit", "--algorithm=crc32", "ark:/a5388"},
+			expectErr: error_msgs.Err17},
+		{name: "Negative I/O limit", args: []string{root + "root", "--bagit", "--io-limit=-1", "ark:/a5388"},
+			expectErr: error_msgs.Err17},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+
+			err := Run(test.args, &buf)
+			assert.ErrorIs(t, err, test.expectErr)
+		})
+	}
+}
@@ -0,0 +1,299 @@
+package ptfind
+
+/* ptfind searches the files and directories of Pairtree objects like find, writing the path of each
+one that matches all of the tests that are given: --name for a glob pattern of its name, --type for
+files or directories, --size for its size, and --mtime for the days since it was modified. It searches
+the objects with the IDs that are given, or every object of the pairtree, and --print0 ends each path
+with a NUL for xargs -0. Hidden files and directories are only searched with -a. */
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pairtree"
+	"github.com/UCLALibrary/pt-tools/utils"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	// Logger is the logger each run of pt find starts from, tests replace it to capture the logs
+	Logger *zap.Logger = utils.ConsoleLogger()
+)
+
+// sizeUnits are the suffixes of --size and the bytes they stand for
+var sizeUnits = map[string]int64{"": 1, "c": 1, "k": 1 << 10, "M": 1 << 20, "G": 1 << 30, "T": 1 << 40}
+
+// command holds the flags and arguments of one run of pt find so that runs can happen concurrently
+type command struct {
+	showAll  bool
+	name     string
+	fileType string
+	size     string
+	mtime    string
+	print0   bool
+	ptRoot   string
+	ids      []string
+	criteria criteria
+	logger   *zap.Logger
+	out      *utils.Output
+}
+
+// criteria are the parsed tests that an entry has to pass to be written
+type criteria struct {
+	name     string
+	fileType string
+	size     *comparison
+	mtime    *comparison
+	now      time.Time
+}
+
+// comparison is a number that a value is more than, less than, or equal to, written like +N, -N, or N
+type comparison struct {
+	sign  int
+	value int64
+}
+
+func (c *command) initFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(&c.showAll, "a", "a", false, "search hidden files and directories")
+	cmd.Flags().StringVar(&c.name, "name", "", "find entries whose name matches the glob pattern")
+	cmd.Flags().StringVar(&c.fileType, "type", "", "find only files (f) or directories (d)")
+	cmd.Flags().StringVar(&c.size, "size", "", "find entries of more (+N), less (-N), or exactly N bytes, or k, M, G, or T with a suffix")
+	cmd.Flags().StringVar(&c.mtime, "mtime", "", "find entries modified more (+N), less (-N), or exactly N days ago")
+	cmd.Flags().BoolVar(&c.print0, "print0", false, "end each path with a NUL instead of a newline, for xargs -0")
+}
+
+// NewCommand creates the find subcommand of pt that writes its output to the writer
+func NewCommand(writer io.Writer) *cobra.Command {
+	c := &command{logger: Logger, out: utils.NewOutput(io.Discard, &utils.Styler{}, false)}
+
+	var cmd = &cobra.Command{
+		Use:               "find [FLAGS] [ID]...",
+		Short:             "pt find searches the files and directories of Pairtree objects",
+		ValidArgsFunction: utils.CompleteIDs,
+		Annotations:       map[string]string{utils.S3Annotation: "true"},
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return utils.ConfigureLogger(cmd, &c.logger)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+
+			c.out = utils.OutputFromFlags(cmd, writer)
+
+			if c.ptRoot, err = utils.GetPtRoot(cmd, writer); err != nil {
+				return err
+			}
+			c.ids = args
+
+			if c.criteria, err = c.parseCriteria(); err != nil {
+				c.logger.Error("Error parsing pt find", zap.Error(err))
+				return err
+			}
+
+			utils.SilenceUsage(cmd)
+
+			return c.find(cmd.Context(), writer)
+		},
+	}
+
+	c.initFlags(cmd)
+
+	return cmd
+}
+
+// Run executes pt find with the given arguments
+func Run(args []string, writer io.Writer) error {
+	if err := utils.RunSubcommand(NewCommand(writer), args, writer); err != nil {
+		Logger.Error("Error running pt find", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// parseCriteria checks the flags of the tests and returns the criteria they make
+func (c *command) parseCriteria() (criteria, error) {
+	crit := criteria{name: c.name, fileType: c.fileType, now: time.Now()}
+
+	if _, err := path.Match(c.name, ""); err != nil {
+		return criteria{}, fmt.Errorf("%w: --name is not a valid glob pattern", error_msgs.Err17)
+	}
+
+	if c.fileType != "" && c.fileType != "f" && c.fileType != "d" {
+		return criteria{}, fmt.Errorf("%w: --type must be f or d", error_msgs.Err17)
+	}
+
+	if c.size != "" {
+		number, unit := c.size, ""
+		if last := c.size[len(c.size)-1:]; strings.ContainsAny(last, "ckMGT") {
+			number, unit = c.size[:len(c.size)-1], last
+		}
+
+		size, err := parseComparison(number)
+		if err != nil {
+			return criteria{}, fmt.Errorf("%w: --size must be a number of bytes like +10M or -1k", error_msgs.Err17)
+		}
+		size.value *= sizeUnits[unit]
+		crit.size = &size
+	}
+
+	if c.mtime != "" {
+		mtime, err := parseComparison(c.mtime)
+		if err != nil {
+			return criteria{}, fmt.Errorf("%w: --mtime must be a number of days like +7 or -1", error_msgs.Err17)
+		}
+		crit.mtime = &mtime
+	}
+
+	return crit, nil
+}
+
+// parseComparison parses a number that starts with + for more than it or - for less than it
+func parseComparison(text string) (comparison, error) {
+	var cmp comparison
+	if strings.HasPrefix(text, "+") {
+		cmp.sign, text = 1, text[1:]
+	} else if strings.HasPrefix(text, "-") {
+		cmp.sign, text = -1, text[1:]
+	}
+
+	value, err := strconv.ParseInt(text, 10, 64)
+	if err != nil || value < 0 {
+		return comparison{}, error_msgs.Err17
+	}
+	cmp.value = value
+
+	return cmp, nil
+}
+
+// matches checks if the value passes the comparison
+func (cmp comparison) matches(value int64) bool {
+	switch cmp.sign {
+	case 1:
+		return value > cmp.value
+	case -1:
+		return value < cmp.value
+	default:
+		return value == cmp.value
+	}
+}
+
+// matches checks if the entry passes all of the tests. Its info is only read when a test needs it.
+func (crit criteria) matches(entry pairtree.Entry) (bool, error) {
+	if (crit.fileType == "f" && entry.IsDir()) || (crit.fileType == "d" && !entry.IsDir()) {
+		return false, nil
+	}
+
+	if crit.name != "" {
+		if matched, _ := path.Match(crit.name, entry.Name()); !matched {
+			return false, nil
+		}
+	}
+
+	if crit.size == nil && crit.mtime == nil {
+		return true, nil
+	}
+
+	info, err := entry.Info()
+	if err != nil {
+		return false, err
+	}
+
+	if crit.size != nil && !crit.size.matches(info.Size()) {
+		return false, nil
+	}
+
+	// Like find, the days since an entry was modified are whole days, with any part of a day left off
+	days := int64(crit.now.Sub(info.ModTime()) / (24 * time.Hour))
+	return crit.mtime == nil || crit.mtime.matches(days), nil
+}
+
+// find writes the path of each entry of the objects that matches the criteria. An object that can not
+// be searched does not stop the others, and its error is returned with theirs.
+func (c *command) find(ctx context.Context, writer io.Writer) error {
+	// Open the pairtree, which checks its version file and reads its prefix
+	pt, err := pairtree.Open(c.ptRoot)
+	if err != nil {
+		c.logger.Error("Error opening the pairtree", zap.Error(err))
+		return err
+	}
+
+	buffered := bufio.NewWriter(writer)
+	var errs []error
+
+	search := func(id, objPath string) error {
+		err := pt.WalkCtx(ctx, id, pairtree.ListOptions{Recursive: true, ShowAll: c.showAll}, func(entry pairtree.Entry) error {
+			matched, err := c.criteria.matches(entry)
+			if err != nil || !matched {
+				return err
+			}
+
+			return c.write(buffered, pairtree.JoinPath(objPath, entry.Path))
+		})
+		if err != nil {
+			c.logger.Error("Error searching the object", zap.String("id", id), zap.Error(err))
+			return &error_msgs.PtError{ID: id, Path: objPath, Err: err}
+		}
+
+		return nil
+	}
+
+	if len(c.ids) == 0 {
+		// Every object is searched as it is found, so the IDs of a large pairtree are not held in memory
+		err := pt.WalkObjectsCtx(ctx, func(id, objPath string) error {
+			if err := search(id, objPath); err != nil {
+				if ctx.Err() != nil {
+					return err
+				}
+				errs = append(errs, err)
+			}
+			return nil
+		})
+		if err != nil {
+			c.logger.Error("Error walking the pairtree", zap.Error(err))
+			errs = append(errs, err)
+		}
+	}
+
+	for _, id := range c.ids {
+		// Stop before the next object once the context is canceled
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+
+		objPath, err := pt.PairPath(id)
+		if err != nil {
+			c.logger.Error("Error creating pairpath", zap.String("id", id), zap.Error(err))
+			errs = append(errs, &error_msgs.PtError{ID: id, Err: err})
+			continue
+		}
+
+		errs = append(errs, search(id, objPath))
+	}
+
+	if err := buffered.Flush(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// write writes the path and the separator that ends it
+func (c *command) write(w io.Writer, path string) error {
+	separator := "\n"
+	if c.print0 {
+		separator = "\x00"
+	}
+
+	_, err := fmt.Fprint(w, path, separator)
+	return err
+}
@@ -0,0 +1,107 @@
+package ptfind
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pttest"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const root = "--pairtree="
+
+// TestMain runs the tests with the logger of the test sink, it is set once so that the tests can run in parallel
+func TestMain(m *testing.M) {
+	logger, cleanup := pttest.SetupLogger()
+	Logger = logger
+
+	code := m.Run()
+	cleanup()
+	os.Exit(code)
+}
+
+// TestFind tests that the paths of the entries that pass every test are written
+func TestFind(t *testing.T) {
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, afero.NewOsFs())
+	a5388 := filepath.Join(ptRoot, "pairtree_root", "a5", "38", "8", "a5388")
+	b5488 := filepath.Join(ptRoot, "pairtree_root", "b5", "48", "8", "b5488")
+
+	// The outer file of b5488 is large and was modified ten days ago
+	outer := filepath.Join(b5488, "outerb5488.txt")
+	require.NoError(t, os.WriteFile(outer, make([]byte, 2<<10), 0644))
+	modTime := time.Now().Add(-10*24*time.Hour - time.Hour)
+	require.NoError(t, os.Chtimes(outer, modTime, modTime))
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected []string
+	}{
+		{
+			name:     "Name in every object",
+			args:     []string{"--name", "*b5488.txt"},
+			expected: []string{filepath.Join(b5488, "folder", "innerb5488.txt"), outer},
+		},
+		{
+			name:     "Name in an object",
+			args:     []string{"--name", "*.txt", "ark:/a5388"},
+			expected: []string{filepath.Join(a5388, "a5388.txt")},
+		},
+		{
+			name:     "Directories",
+			args:     []string{"--type", "d", "-a", "ark:/b5488"},
+			expected: []string{filepath.Join(b5488, "folder"), filepath.Join(b5488, "folder", ".hidden")},
+		},
+		{
+			name:     "Size",
+			args:     []string{"--type", "f", "--size", "+1k"},
+			expected: []string{outer},
+		},
+		{
+			name:     "Modification time",
+			args:     []string{"--type", "f", "--mtime", "+7"},
+			expected: []string{outer},
+		},
+		{
+			name:     "Recently modified",
+			args:     []string{"--type", "f", "--mtime", "-1", "--name", "*b5488*"},
+			expected: []string{filepath.Join(b5488, "folder", "innerb5488.txt")},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, Run(append([]string{root + ptRoot}, test.args...), &buf))
+			assert.ElementsMatch(t, test.expected, strings.Fields(buf.String()))
+		})
+	}
+
+	// --print0 ends each path with a NUL
+	var buf bytes.Buffer
+	require.NoError(t, Run([]string{root + ptRoot, "--print0", "--name", "*b5488.txt", "ark:/b5488"}, &buf))
+	assert.ElementsMatch(t, []string{filepath.Join(b5488, "folder", "innerb5488.txt"), outer},
+		strings.Split(strings.TrimSuffix(buf.String(), "\x00"), "\x00"))
+}
+
+// TestFindErrors tests that invalid tests are refused and that a missing object does not stop the others
+func TestFindErrors(t *testing.T) {
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, afero.NewOsFs())
+
+	for _, args := range [][]string{{"--name", "["}, {"--type", "l"}, {"--size", "ten"}, {"--mtime", "+x"}} {
+		var buf bytes.Buffer
+		assert.ErrorIs(t, Run(append([]string{root + ptRoot}, args...), &buf), error_msgs.Err17, args)
+	}
+
+	var buf bytes.Buffer
+	err := Run([]string{root + ptRoot, "--name", "*.txt", "ark:/missing", "ark:/a5388"}, &buf)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	assert.Contains(t, buf.String(), filepath.Join("a5388", "a5388.txt")+"\n")
+}
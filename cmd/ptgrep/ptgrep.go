@@ -0,0 +1,258 @@
+package ptgrep
+
+/* ptgrep searches the content of the files of a Pairtree object for lines that match a regular
+expression, like grep -r, so that records can be found in an object without exporting it first. It
+searches the whole object, or the file or directory at a subpath of it. Binary files, which have a NUL
+byte in their first 8000 bytes like grep decides, are skipped. With -c the number of matching lines of
+each file is written instead of the lines. */
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pairtree"
+	"github.com/UCLALibrary/pt-tools/utils"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+const (
+	// binaryCheckSize is how much of the start of a file is checked for a NUL byte
+	binaryCheckSize = 8000
+	// maxLineSize is the longest line that is searched
+	maxLineSize = 64 << 20
+)
+
+var (
+	// Logger is the logger each run of pt grep starts from, tests replace it to capture the logs
+	Logger *zap.Logger = utils.ConsoleLogger()
+)
+
+// command holds the flags and arguments of one run of pt grep so that runs can happen concurrently
+type command struct {
+	ignoreCase  bool
+	count       bool
+	lineNumbers bool
+	showAll     bool
+	ptRoot      string
+	pattern     *regexp.Regexp
+	id          string
+	subpath     string
+	logger      *zap.Logger
+	out         *utils.Output
+}
+
+func (c *command) initFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(&c.ignoreCase, "ignore-case", "i", false, "match upper and lower case letters alike")
+	cmd.Flags().BoolVarP(&c.count, "count", "c", false, "write the number of matching lines of each file instead of the lines")
+	cmd.Flags().BoolVarP(&c.lineNumbers, "line-number", "n", false, "write the line number of each matching line")
+	cmd.Flags().BoolVarP(&c.showAll, "a", "a", false, "search hidden files and directories")
+}
+
+// NewCommand creates the grep subcommand of pt that writes its output to the writer
+func NewCommand(writer io.Writer) *cobra.Command {
+	c := &command{logger: Logger, out: utils.NewOutput(io.Discard, &utils.Styler{}, false)}
+
+	var cmd = &cobra.Command{
+		Use:         "grep [FLAGS] [PATTERN] [ID] [SUBPATH]",
+		Short:       "pt grep searches the files of a Pairtree object for lines that match a regular expression",
+		Annotations: map[string]string{utils.S3Annotation: "true"},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			// Only the ID follows the pattern
+			if len(args) != 1 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return utils.CompleteIDs(cmd, nil, toComplete)
+		},
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return utils.ConfigureLogger(cmd, &c.logger)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+
+			c.out = utils.OutputFromFlags(cmd, writer)
+
+			if c.ptRoot, err = utils.GetPtRoot(cmd, writer); err != nil {
+				return err
+			}
+
+			if len(args) < 2 {
+				c.out.Error("Please provide a pattern and an ID for the pairtree")
+				c.logger.Error("Error getting ID", zap.Error(error_msgs.Err6))
+
+				return error_msgs.Err6
+			} else if len(args) > 3 {
+				c.out.Error("Too many arguments were provided to %s", "pt grep")
+				c.logger.Error("Error parsing pt grep", zap.Error(error_msgs.Err8))
+
+				return error_msgs.Err8
+			}
+
+			expr := args[0]
+			if c.ignoreCase {
+				expr = "(?i)" + expr
+			}
+			if c.pattern, err = regexp.Compile(expr); err != nil {
+				err = fmt.Errorf("%w: the pattern is not a valid regular expression: %v", error_msgs.Err17, err)
+				c.logger.Error("Error parsing pt grep", zap.Error(err))
+
+				return err
+			}
+
+			c.id = args[1]
+			if len(args) == 3 {
+				c.subpath = args[2]
+			}
+
+			utils.SilenceUsage(cmd)
+
+			return c.grep(cmd.Context(), writer)
+		},
+	}
+
+	c.initFlags(cmd)
+
+	return cmd
+}
+
+// Run executes pt grep with the given arguments
+func Run(args []string, writer io.Writer) error {
+	if err := utils.RunSubcommand(NewCommand(writer), args, writer); err != nil {
+		Logger.Error("Error running pt grep", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// grep searches the file at the subpath of the object, or the files of the directory there. A directory's
+// files are searched in name order before the directories under it.
+func (c *command) grep(ctx context.Context, writer io.Writer) error {
+	// Open the pairtree, which checks its version file and reads its prefix
+	pt, err := pairtree.Open(c.ptRoot)
+	if err != nil {
+		c.logger.Error("Error opening the pairtree", zap.Error(err))
+		return err
+	}
+
+	pairPath, err := pt.PairPath(c.id)
+	if err != nil {
+		c.logger.Error("Error creating pairpath", zap.Error(err))
+		return &error_msgs.PtError{ID: c.id, Err: err}
+	}
+
+	target, err := pairtree.JoinSubpath(pairPath, c.subpath)
+	if err != nil {
+		c.logger.Error("Error joining the subpath", zap.Error(err))
+		return &error_msgs.PtError{ID: c.id, Path: c.subpath, Err: err}
+	}
+
+	storage, err := pairtree.StorageFor(ctx, target)
+	if err != nil {
+		c.logger.Error("Error opening the storage", zap.Error(err))
+		return err
+	}
+
+	info, err := storage.Stat(target)
+	if err != nil {
+		c.logger.Error("Error finding the files to search", zap.Error(err))
+		return &error_msgs.PtError{ID: c.id, Path: target, Err: err}
+	}
+
+	buffered := bufio.NewWriter(writer)
+	search := func(path string) error {
+		if err := c.grepFile(storage, buffered, pairPath, path); err != nil {
+			c.logger.Error("Error searching the file", zap.String("path", path), zap.Error(err))
+			return &error_msgs.PtError{ID: c.id, Path: path, Err: err}
+		}
+		return nil
+	}
+
+	if !info.IsDir() {
+		err = search(target)
+	} else {
+		err = pt.WalkListingCtx(ctx, target, pairtree.ListOptions{Recursive: true, ShowAll: c.showAll},
+			func(dir string, entries []fs.DirEntry) error {
+				for _, entry := range entries {
+					// Stop before the next file once the context is canceled
+					if err := ctx.Err(); err != nil {
+						return err
+					}
+
+					if !entry.IsDir() {
+						if err := search(pairtree.JoinPath(dir, entry.Name())); err != nil {
+							return err
+						}
+					}
+				}
+				return nil
+			})
+	}
+	if err != nil {
+		return err
+	}
+
+	return buffered.Flush()
+}
+
+// grepFile writes the lines of the file that match the pattern, or their number with -c, after the
+// path of the file in the object. A binary file is skipped.
+func (c *command) grepFile(storage pairtree.Storage, w io.Writer, pairPath, path string) error {
+	file, err := storage.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	rel, err := filepath.Rel(pairPath, path)
+	if err != nil {
+		return err
+	}
+	rel = filepath.ToSlash(rel)
+
+	reader := bufio.NewReaderSize(file, binaryCheckSize)
+	start, err := reader.Peek(binaryCheckSize)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if bytes.IndexByte(start, 0) >= 0 {
+		c.logger.Debug("Skipped a binary file", zap.String("path", path))
+		return nil
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxLineSize)
+
+	count := 0
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := scanner.Bytes()
+		if !c.pattern.Match(line) {
+			continue
+		}
+		count++
+
+		switch {
+		case c.count:
+		case c.lineNumbers:
+			fmt.Fprintf(w, "%s:%d:%s\n", rel, lineNumber, line)
+		default:
+			fmt.Fprintf(w, "%s:%s\n", rel, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if c.count && count > 0 {
+		fmt.Fprintf(w, "%s:%d\n", rel, count)
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package ptgrep
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pttest"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const root = "--pairtree="
+
+// TestMain runs the tests with the logger of the test sink, it is set once so that the tests can run in parallel
+func TestMain(m *testing.M) {
+	logger, cleanup := pttest.SetupLogger()
+	Logger = logger
+
+	code := m.Run()
+	cleanup()
+	os.Exit(code)
+}
+
+// TestGrep tests that the matching lines of the files of an object are written with their paths
+func TestGrep(t *testing.T) {
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, afero.NewOsFs())
+	b5488 := filepath.Join(ptRoot, "pairtree_root", "b5", "48", "8", "b5488")
+
+	write := func(path, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(b5488, path), []byte(content), 0644))
+	}
+	write("outerb5488.txt", "title: Outer\ncreator: UCLA\nTitle: Second\n")
+	write(filepath.Join("folder", "innerb5488.txt"), "title: Inner\n")
+	write(filepath.Join("folder", ".hiddenFile.txt"), "title: Hidden\n")
+	write("image.bin", "title: Binary\x00\n")
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "Object",
+			args:     []string{"^title", "ark:/b5488"},
+			expected: "outerb5488.txt:title: Outer\nfolder/innerb5488.txt:title: Inner\n",
+		},
+		{
+			name:     "Ignore case",
+			args:     []string{"-i", "^title", "ark:/b5488"},
+			expected: "outerb5488.txt:title: Outer\nouterb5488.txt:Title: Second\nfolder/innerb5488.txt:title: Inner\n",
+		},
+		{
+			name:     "Line numbers",
+			args:     []string{"-n", "-i", "^title", "ark:/b5488", "outerb5488.txt"},
+			expected: "outerb5488.txt:1:title: Outer\nouterb5488.txt:3:Title: Second\n",
+		},
+		{
+			name:     "Counts",
+			args:     []string{"-c", "-i", "-a", "title", "ark:/b5488"},
+			expected: "outerb5488.txt:2\nfolder/.hiddenFile.txt:1\nfolder/innerb5488.txt:1\n",
+		},
+		{
+			name:     "Subpath",
+			args:     []string{"title", "ark:/b5488", "folder"},
+			expected: "folder/innerb5488.txt:title: Inner\n",
+		},
+		{
+			name:     "No matches",
+			args:     []string{"missing", "ark:/b5488"},
+			expected: "",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, Run(append([]string{root + ptRoot}, test.args...), &buf))
+			assert.Equal(t, test.expected, buf.String())
+		})
+	}
+}
+
+// TestGrepErrors tests that missing arguments, patterns that are not valid, and missing paths are errors
+func TestGrepErrors(t *testing.T) {
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, afero.NewOsFs())
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected error
+	}{
+		{name: "No ID", args: []string{"title"}, expected: error_msgs.Err6},
+		{name: "Too many arguments", args: []string{"title", "ark:/b5488", "folder", "extra"}, expected: error_msgs.Err8},
+		{name: "Pattern", args: []string{"(title", "ark:/b5488"}, expected: error_msgs.Err17},
+		{name: "Outside the object", args: []string{"title", "ark:/b5488", "../.."}, expected: error_msgs.Err46},
+		{name: "Missing subpath", args: []string{"title", "ark:/b5488", "missing"}, expected: os.ErrNotExist},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := Run(append([]string{root + ptRoot}, test.args...), &buf)
+			assert.ErrorIs(t, err, test.expected)
+			assert.False(t, strings.Contains(buf.String(), "title:"))
+		})
+	}
+}
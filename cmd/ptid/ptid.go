@@ -0,0 +1,126 @@
+package ptid
+
+/* ptid writes the ID of the object that each path is in, decoded from its pairpath, so that an ID can
+be found from a location on disk. The path can be the object's directory or anything inside it, and a
+relative path is taken from the current directory. pt path does the reverse. */
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pairtree"
+	"github.com/UCLALibrary/pt-tools/utils"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	// Logger is the logger each run of pt id starts from, tests replace it to capture the logs
+	Logger *zap.Logger = utils.ConsoleLogger()
+)
+
+// command holds the arguments of one run of pt id so that runs can happen concurrently
+type command struct {
+	ptRoot string
+	paths  []string
+	logger *zap.Logger
+	out    *utils.Output
+}
+
+// NewCommand creates the id subcommand of pt that writes its output to the writer
+func NewCommand(writer io.Writer) *cobra.Command {
+	c := &command{logger: Logger, out: utils.NewOutput(io.Discard, &utils.Styler{}, false)}
+
+	var cmd = &cobra.Command{
+		Use:   "id [PATH]...",
+		Short: "pt id writes the ID of the object each path is in",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return utils.ConfigureLogger(cmd, &c.logger)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+
+			c.out = utils.OutputFromFlags(cmd, writer)
+
+			if c.ptRoot, err = utils.GetPtRoot(cmd, writer); err != nil {
+				return err
+			}
+
+			if len(args) < 1 {
+				c.out.Error("Please provide a path in the pairtree")
+				c.logger.Error("Error getting path", zap.Error(error_msgs.Err15))
+
+				return error_msgs.Err15
+			}
+			c.paths = args
+
+			utils.SilenceUsage(cmd)
+
+			return c.ids(cmd.Context(), writer)
+		},
+	}
+
+	return cmd
+}
+
+// Run executes pt id with the given arguments
+func Run(args []string, writer io.Writer) error {
+	if err := utils.RunSubcommand(NewCommand(writer), args, writer); err != nil {
+		Logger.Error("Error running pt id", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// ids writes the ID of each path on a line. A path that is not in an object does not stop the others,
+// and its error is returned with theirs.
+func (c *command) ids(ctx context.Context, writer io.Writer) error {
+	// Paths are compared with the root as absolute paths, so either can be given relative to here
+	absRoot, err := filepath.Abs(c.ptRoot)
+	if err != nil {
+		c.logger.Error("Error finding the pairtree root", zap.Error(err))
+		return err
+	}
+
+	// Open the pairtree, which checks its version file and reads its prefix
+	pt, err := pairtree.Open(absRoot)
+	if err != nil {
+		c.logger.Error("Error opening the pairtree", zap.Error(err))
+		return err
+	}
+
+	var errs []error
+	for _, path := range c.paths {
+		// Stop before the next path once the context is canceled
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+
+		id, err := decode(pt, path)
+		if err != nil {
+			c.logger.Error("Error decoding the pairpath", zap.String("path", path), zap.Error(err))
+			errs = append(errs, &error_msgs.PtError{Path: path, Err: err})
+			continue
+		}
+
+		fmt.Fprintln(writer, id)
+	}
+
+	return errors.Join(errs...)
+}
+
+// decode returns the ID of the object the path is in
+func decode(pt *pairtree.Pairtree, path string) (string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	return pt.ID(absPath)
+}
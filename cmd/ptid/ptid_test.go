@@ -0,0 +1,46 @@
+package ptid
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pttest"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const root = "--pairtree="
+
+// TestMain runs the tests with the logger of the test sink, it is set once so that the tests can run in parallel
+func TestMain(m *testing.M) {
+	logger, cleanup := pttest.SetupLogger()
+	Logger = logger
+
+	code := m.Run()
+	cleanup()
+	os.Exit(code)
+}
+
+// TestID tests that the ID of the object each path is in is written
+func TestID(t *testing.T) {
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, afero.NewOsFs())
+	objPath := filepath.Join(ptRoot, "pairtree_root", "b5", "48", "8", "b5488")
+
+	var buf bytes.Buffer
+	require.NoError(t, Run([]string{root + ptRoot, objPath, filepath.Join(objPath, "folder", "innerb5488.txt"),
+		filepath.Join(ptRoot, "pairtree_root", "ne", "w^", "2b", "id", "new^2bid")}, &buf))
+	assert.Equal(t, "ark:/b5488\nark:/b5488\nark:/new+id\n", buf.String())
+
+	// A path that is not in an object does not stop the others
+	buf.Reset()
+	err := Run([]string{root + ptRoot, filepath.Join(ptRoot, "elsewhere"), objPath}, &buf)
+	assert.ErrorIs(t, err, error_msgs.Err35)
+	assert.True(t, strings.HasPrefix(buf.String(), "ark:/b5488\n"))
+
+	assert.ErrorIs(t, Run([]string{root + ptRoot}, &buf), error_msgs.Err15)
+}
@@ -0,0 +1,147 @@
+package ptids
+
+/* ptids lists the IDs of every object in a pairtree, one per line or as JSON, so that bulk
+operations can be scripted over the whole pairtree */
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pairtree"
+	"github.com/UCLALibrary/pt-tools/utils"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	// Logger is the logger each run of pt ids starts from, tests replace it to capture the logs
+	Logger *zap.Logger = utils.ConsoleLogger()
+)
+
+// command holds the flags and arguments of one run of pt ids so that runs can happen concurrently
+type command struct {
+	outputJSON bool
+	ptRoot     string
+	logger     *zap.Logger
+	out        *utils.Output
+}
+
+func (c *command) initFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(&c.outputJSON, "j", "j", false, "output in JSON format")
+}
+
+// NewCommand creates the ids subcommand of pt that writes its output to the writer
+func NewCommand(writer io.Writer) *cobra.Command {
+	c := &command{logger: Logger, out: utils.NewOutput(io.Discard, &utils.Styler{}, false)}
+
+	var cmd = &cobra.Command{
+		Use:   "ids [FLAGS]",
+		Short: "pt ids lists the IDs of every object in the pairtree",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return utils.ConfigureLogger(cmd, &c.logger)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+
+			c.out = utils.OutputFromFlags(cmd, writer)
+
+			if c.ptRoot, err = utils.GetPtRoot(cmd, writer); err != nil {
+				return err
+			}
+
+			if len(args) > 0 {
+				c.out.Error("Too many arguments were provided to %s", "pt ids")
+				c.logger.Error("Error parsing pt ids", zap.Error(error_msgs.Err8))
+
+				return error_msgs.Err8
+			}
+
+			// The persistent --json flag is the same as -j
+			if jsonFlag, _ := cmd.Flags().GetBool(utils.JSONFlag); jsonFlag {
+				c.outputJSON = true
+			}
+
+			utils.SilenceUsage(cmd)
+
+			return c.listIDs(cmd.Context(), writer)
+		},
+	}
+
+	c.initFlags(cmd)
+
+	return cmd
+}
+
+// Run executes pt ids with the given arguments
+func Run(args []string, writer io.Writer) error {
+	if err := utils.RunSubcommand(NewCommand(writer), args, writer); err != nil {
+		Logger.Error("Error running pt ids", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// listIDs writes the ID of each object in the pairtree as it is found, so the IDs of a large
+// pairtree are not all held in memory
+func (c *command) listIDs(ctx context.Context, writer io.Writer) error {
+	// check if the pairtree version file exists and is populated
+	if err := pairtree.CheckPTVer(c.ptRoot); err != nil {
+		c.logger.Error("Error with pairtree veresion file", zap.Error(err))
+		return err
+	}
+
+	// Get the prefix from pairtree_prefix file
+	prefix, err := pairtree.GetPrefix(c.ptRoot)
+	if err != nil {
+		c.logger.Error("Error retrieving prefix from pairtree_prefix file", zap.Error(err))
+		return err
+	}
+
+	if prefix == "" {
+		prefix = pairtree.PtPrefix
+	}
+
+	buffered := bufio.NewWriter(writer)
+	count := 0
+
+	// The JSON is the array of IDs, written like json.MarshalIndent would
+	err = pairtree.WalkObjectsCtx(ctx, c.ptRoot, prefix, func(id, objPath string) error {
+		count++
+
+		if !c.outputJSON {
+			_, err := fmt.Fprintln(buffered, id)
+			return err
+		}
+
+		jsonID, err := json.Marshal(id)
+		if err != nil {
+			return err
+		}
+
+		separator := ",\n  "
+		if count == 1 {
+			separator = "[\n  "
+		}
+		_, err = fmt.Fprintf(buffered, "%s%s", separator, jsonID)
+		return err
+	})
+	if err != nil {
+		c.logger.Error("Error walking the pairtree", zap.Error(err))
+		return err
+	}
+
+	if c.outputJSON && count == 0 {
+		fmt.Fprintln(buffered, "[]")
+	} else if c.outputJSON {
+		fmt.Fprintln(buffered, "\n]")
+	}
+
+	c.logger.Info("Listed the IDs of the pairtree", zap.Int("objects", count))
+
+	return buffered.Flush()
+}
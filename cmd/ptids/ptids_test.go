@@ -0,0 +1,84 @@
+package ptids
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pttest"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const root = "--pairtree="
+
+// TestMain runs the tests with the logger of the test sink, it is set once so that the tests can run in parallel
+func TestMain(m *testing.M) {
+	logger, cleanup := pttest.SetupLogger()
+	Logger = logger
+
+	code := m.Run()
+	cleanup()
+	os.Exit(code)
+}
+
+// TestIDs tests if the ID of every object in the pairtree is listed
+func TestIDs(t *testing.T) {
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, afero.NewOsFs())
+	expected := []string{"ark:/a5388", "ark:/a5488", "ark:/a54892", "ark:/b5488"}
+
+	var buf bytes.Buffer
+	require.NoError(t, Run([]string{root + ptRoot}, &buf))
+	assert.Equal(t, "ark:/a5388\nark:/a5488\nark:/a54892\nark:/b5488\n", buf.String())
+
+	for _, flag := range []string{"-j", "--json"} {
+		buf.Reset()
+		require.NoError(t, Run([]string{root + ptRoot, flag}, &buf))
+
+		var ids []string
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &ids))
+		assert.Equal(t, expected, ids)
+
+		// The streamed JSON is the same as marshalling the IDs
+		jsonData, err := json.MarshalIndent(expected, "", "  ")
+		require.NoError(t, err)
+		assert.Equal(t, string(jsonData)+"\n", buf.String())
+	}
+}
+
+// TestIDsEmpty tests if a pairtree without objects lists no IDs
+func TestIDsEmpty(t *testing.T) {
+	ptRoot := pttest.NewPairtreeBuilder().BuildTemp(t, afero.NewOsFs())
+
+	var buf bytes.Buffer
+	require.NoError(t, Run([]string{root + ptRoot}, &buf))
+	assert.Empty(t, buf.String())
+
+	buf.Reset()
+	require.NoError(t, Run([]string{root + ptRoot, "-j"}, &buf))
+	assert.Equal(t, "[]\n", buf.String())
+}
+
+// TestCLIError tests if an error is thrown when the arguments are not valid
+func TestCLIError(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		expectErr error
+	}{
+		{name: "Too many arguments", args: []string{root + "root", "ark:/a5388"}, expectErr: error_msgs.Err8},
+		{name: "Not a pairtree", args: []string{root + filepath.Join(t.TempDir(), "missing")}, expectErr: os.ErrNotExist},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := Run(test.args, &buf)
+			assert.ErrorIs(t, err, test.expectErr)
+		})
+	}
+}
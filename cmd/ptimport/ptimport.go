@@ -0,0 +1,374 @@
+package ptimport
+
+/* ptimport reads a package written in another format into a Pairtree object. With --bagit the package
+is a BagIt bag, which is checked against its manifests before the files of its data/ directory become
+the object. The ID of the object is the External-Identifier of bag-info.txt unless one is given. With
+--dirs each folder of a directory becomes an object, named by the folder or by a CSV file that maps the
+folders to IDs, and the objects are imported --jobs at a time with a report of each one. */
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/UCLALibrary/pt-tools/pkg/bagit"
+	"github.com/UCLALibrary/pt-tools/pkg/checksum"
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pairtree"
+	"github.com/UCLALibrary/pt-tools/pkg/premis"
+	"github.com/UCLALibrary/pt-tools/utils"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	// Logger is the logger each run of pt import starts from, tests replace it to capture the logs
+	Logger *zap.Logger = utils.ConsoleLogger()
+)
+
+// command holds the flags and arguments of one run of pt import so that runs can happen concurrently
+type command struct {
+	bagit      bool
+	dirs       bool
+	mapFile    string
+	header     bool
+	jobs       int
+	outputJSON bool
+	ptRoot     string
+	src        string
+	id         string
+	hashOpts   checksum.Options
+	logger     *zap.Logger
+	out        *utils.Output
+}
+
+// folder is a folder of the directory imported with --dirs and the ID of the object it becomes, or why
+// its name is not an ID
+type folder struct {
+	name string
+	id   string
+	err  error
+}
+
+func (c *command) initFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&c.bagit, "bagit", false, "import a BagIt bag")
+	cmd.Flags().BoolVar(&c.dirs, "dirs", false, "import each folder of a directory as an object")
+	cmd.Flags().StringVar(&c.mapFile, "map", "", "CSV file of the folders to import with --dirs and the IDs of their objects")
+	cmd.Flags().BoolVar(&c.header, "header", false, "skip the first row of the --map file")
+	cmd.Flags().IntVar(&c.jobs, "jobs", 1, "Objects imported at once with --dirs")
+	cmd.Flags().BoolVarP(&c.outputJSON, "j", "j", false, "output the report of --dirs in JSON format")
+	cmd.Flags().IntVar(&c.hashOpts.IOLimit, "io-limit", 0, "Reads from files that happen at once while hashing them (defaults to one per CPU)")
+}
+
+// NewCommand creates the import subcommand of pt that writes its output to the writer
+func NewCommand(writer io.Writer) *cobra.Command {
+	c := &command{logger: Logger, out: utils.NewOutput(io.Discard, &utils.Styler{}, false)}
+
+	var cmd = &cobra.Command{
+		Use:   "import --bagit [/path/to/bag] [ID] | --dirs [/path/to/directory]",
+		Short: "pt import reads a BagIt bag, or each folder of a directory, into a Pairtree object",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return utils.ConfigureLogger(cmd, &c.logger)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+
+			c.out = utils.OutputFromFlags(cmd, writer)
+
+			if c.ptRoot, err = utils.GetPtRoot(cmd, writer); err != nil {
+				return err
+			}
+
+			if len(args) < 1 {
+				c.out.Error("Please provide the bag or directory to import")
+				c.logger.Error("Error getting the bag", zap.Error(error_msgs.Err15))
+
+				return error_msgs.Err15
+			} else if len(args) > 2 || (c.dirs && len(args) > 1) {
+				c.out.Error("Too many arguments were provided to %s", "pt import")
+				c.logger.Error("Error parsing pt import", zap.Error(error_msgs.Err8))
+
+				return error_msgs.Err8
+			}
+			c.src = args[0]
+
+			if len(args) == 2 {
+				c.id = args[1]
+			}
+
+			// The format is a flag so that others can be added beside them
+			if c.bagit == c.dirs {
+				err := fmt.Errorf("%w: the format of the import must be set with one of --bagit or --dirs", error_msgs.Err17)
+				c.logger.Error("Error parsing pt import", zap.Error(err))
+
+				return err
+			}
+
+			if c.mapFile != "" && !c.dirs {
+				err := fmt.Errorf("%w: --map can only be used with --dirs", error_msgs.Err17)
+				c.logger.Error("Error parsing pt import", zap.Error(err))
+
+				return err
+			}
+
+			if c.jobs < 1 {
+				err := fmt.Errorf("%w: --jobs must be at least 1", error_msgs.Err17)
+				c.logger.Error("Error parsing pt import", zap.Error(err))
+
+				return err
+			}
+
+			// The persistent --json flag is the same as -j
+			if jsonFlag, _ := cmd.Flags().GetBool(utils.JSONFlag); jsonFlag {
+				c.outputJSON = true
+			}
+
+			if c.hashOpts.IOLimit < 0 {
+				err := fmt.Errorf("%w: --io-limit must not be negative", error_msgs.Err17)
+				c.logger.Error("Error parsing pt import", zap.Error(err))
+
+				return err
+			}
+
+			utils.SilenceUsage(cmd)
+
+			if c.dirs {
+				return c.importDirs(cmd.Context(), writer)
+			}
+
+			return c.importBag(cmd.Context())
+		},
+	}
+
+	c.initFlags(cmd)
+
+	return cmd
+}
+
+// Run executes pt import with the given arguments
+func Run(args []string, writer io.Writer) error {
+	if err := utils.RunSubcommand(NewCommand(writer), args, writer); err != nil {
+		Logger.Error("Error running pt import", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// importBag checks the bag and copies its payload into a new object of the pairtree
+func (c *command) importBag(ctx context.Context) error {
+	// Open the pairtree, which checks its version file and reads its prefix
+	pt, err := pairtree.Open(c.ptRoot)
+	if err != nil {
+		c.logger.Error("Error opening the pairtree", zap.Error(err))
+		return err
+	}
+
+	bag, err := bagit.Check(ctx, c.src, c.hashOpts)
+	if err != nil {
+		c.logger.Error("Error checking the bag", zap.String("bag", c.src), zap.Error(err))
+		return &error_msgs.PtError{ID: c.id, Path: c.src, Err: err}
+	}
+
+	if c.id == "" {
+		if c.id = bag.ID(); c.id == "" {
+			c.out.Error("Please provide an ID for the pairtree")
+			c.logger.Error("Error getting ID, the bag does not have an External-Identifier", zap.Error(error_msgs.Err6))
+
+			return error_msgs.Err6
+		}
+	}
+
+	if err := c.importObject(ctx, pt, filepath.Join(bag.Dir, bagit.DataDir), c.id, "imported the bag "+c.src); err != nil {
+		return err
+	}
+
+	c.out.Success("Imported the bag %s as %s", c.src, c.id)
+	c.logger.Info("Imported the bag", zap.String("bag", c.src), zap.String("id", c.id),
+		zap.Int("files", len(bag.Files)))
+
+	return nil
+}
+
+// importDirs imports each folder of the directory, or each folder of the --map file, as a new object.
+// Each object is reported once it is imported, and one that fails does not stop the others.
+func (c *command) importDirs(ctx context.Context, writer io.Writer) error {
+	// Open the pairtree, which checks its version file and reads its prefix
+	pt, err := pairtree.Open(c.ptRoot)
+	if err != nil {
+		c.logger.Error("Error opening the pairtree", zap.Error(err))
+		return err
+	}
+
+	folders, err := c.folders(pt)
+	if err != nil {
+		c.logger.Error("Error finding the folders to import", zap.Error(err))
+		return &error_msgs.PtError{Path: c.src, Err: err}
+	}
+
+	// Two folders can not become the same object
+	names := map[string]string{}
+	operations := make([]pairtree.Operation, 0, len(folders))
+	for _, f := range folders {
+		if name, ok := names[f.id]; ok && f.err == nil {
+			err := fmt.Errorf("%w: the folders %s and %s would both be imported as %s", error_msgs.Err17, name, f.name, f.id)
+			c.logger.Error("Error finding the folders to import", zap.Error(err))
+
+			return err
+		}
+		names[f.id] = f.name
+
+		dir := filepath.Join(c.src, f.name)
+		operations = append(operations, pairtree.Operation{ID: f.id, Path: dir, Run: func(ctx context.Context) error {
+			if f.err != nil {
+				return f.err
+			}
+			return c.importObject(ctx, pt, dir, f.id, "imported the folder "+dir)
+		}})
+	}
+
+	batch := pairtree.NewBatch(c.jobs)
+	if !c.outputJSON {
+		batch.OnProgress = func(progress pairtree.Progress) {
+			if last := progress.Last; last.Err != nil {
+				c.out.Error("Could not import %s as %s: %s", last.Path, last.ID, last.Error)
+			} else {
+				c.out.Success("Imported %s as %s", last.Path, last.ID)
+			}
+		}
+	}
+
+	results, err := batch.Run(ctx, operations)
+
+	if c.outputJSON {
+		jsonData, jsonErr := json.MarshalIndent(results, "", "  ")
+		if jsonErr != nil {
+			c.logger.Error("Error converting the report to JSON", zap.Error(jsonErr))
+			return errors.Join(err, jsonErr)
+		}
+
+		fmt.Fprintln(writer, string(jsonData))
+	} else {
+		imported := 0
+		for _, result := range results {
+			if result.Err == nil {
+				imported++
+			}
+		}
+		c.out.Info("Imported %d of %d objects", imported, len(results))
+	}
+
+	return err
+}
+
+// folders returns the folders of the directory that are imported with their IDs, which are the IDs
+// their names encode or, with --map, the IDs the map gives them. Hidden folders are left out.
+func (c *command) folders(pt *pairtree.Pairtree) ([]folder, error) {
+	if c.mapFile != "" {
+		return c.mappedFolders(pt)
+	}
+
+	entries, err := os.ReadDir(c.src)
+	if err != nil {
+		return nil, err
+	}
+
+	folders := []folder{}
+	for _, entry := range entries {
+		if !entry.IsDir() || pairtree.IsHidden(entry.Name()) {
+			continue
+		}
+
+		id, err := pt.NameID(entry.Name())
+		if err != nil {
+			id = entry.Name()
+		}
+		folders = append(folders, folder{name: entry.Name(), id: id, err: err})
+	}
+
+	return folders, nil
+}
+
+// mappedFolders returns the folders of the rows of the --map file, whose first column is the name of the
+// folder and second is the ID of its object, which is given the prefix of the pairtree when it has none
+func (c *command) mappedFolders(pt *pairtree.Pairtree) ([]folder, error) {
+	file, err := os.Open(c.mapFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	csvReader := csv.NewReader(file)
+	csvReader.FieldsPerRecord = -1
+
+	folders := []folder{}
+	for row := 1; ; row++ {
+		record, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		if (row == 1 && c.header) || len(record) < 2 {
+			continue
+		}
+
+		name, id := strings.TrimSpace(record[0]), strings.TrimSpace(record[1])
+		if name == "" || id == "" {
+			continue
+		}
+
+		if !strings.HasPrefix(id, pt.Prefix()) {
+			id = pt.Prefix() + id
+		}
+		folders = append(folders, folder{name: name, id: id})
+	}
+
+	return folders, nil
+}
+
+// importObject copies the directory into a new object with the ID, recording the ingest with the detail
+// in the object's event history
+func (c *command) importObject(ctx context.Context, pt *pairtree.Pairtree, dir, id, detail string) (err error) {
+	pairPath, err := pt.PairPath(id)
+	if err != nil {
+		c.logger.Error("Error creating pairpath", zap.Error(err))
+		return &error_msgs.PtError{ID: id, Err: err}
+	}
+
+	// Nothing is recorded for a folder that is not there
+	if _, err := os.Stat(dir); err != nil {
+		c.logger.Error("Error importing the folder", zap.String("folder", dir), zap.Error(err))
+		return &error_msgs.PtError{ID: id, Path: dir, Err: err}
+	}
+
+	// An import becomes a new object, it is not merged into one that exists
+	if _, err := os.Stat(pairPath); err == nil {
+		c.logger.Error("Error importing, the object exists", zap.String("id", id))
+		return &error_msgs.PtError{ID: id, Path: pairPath, Err: os.ErrExist}
+	}
+
+	// Importing into the pairtree is an ingest that is kept in the object's event history
+	defer func() {
+		utils.RecordEvent(c.ptRoot, pt.Prefix(), premis.NewEvent(premis.Ingestion, id, detail, err), c.out, c.logger)
+	}()
+
+	if err := os.MkdirAll(filepath.Dir(pairPath), 0755); err != nil {
+		c.logger.Error("Error creating the pairpath", zap.Error(err))
+		return &error_msgs.PtError{ID: id, Path: pairPath, Err: err}
+	}
+
+	if _, err := pairtree.CopyFileOrFolder(ctx, dir, pairPath, false, pairtree.CopyOptions{}); err != nil {
+		c.logger.Error("Error copying into the object", zap.Error(err))
+		return &error_msgs.PtError{ID: id, Path: pairPath, Err: err}
+	}
+
+	return nil
+}
@@ -0,0 +1,189 @@
+package ptimport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/UCLALibrary/pt-tools/pkg/bagit"
+	"github.com/UCLALibrary/pt-tools/pkg/checksum"
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pairtree"
+	"github.com/UCLALibrary/pt-tools/pkg/premis"
+	"github.com/UCLALibrary/pt-tools/pkg/pttest"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	root = "--pairtree="
+)
+
+// TestMain runs the tests with the logger of the test sink, it is set once so that the tests can run in parallel
+func TestMain(m *testing.M) {
+	logger, cleanup := pttest.SetupLogger()
+	Logger = logger
+
+	code := m.Run()
+	cleanup()
+	os.Exit(code)
+}
+
+// writeBag writes the object ark:/b5488 of the standard pairtree as a bag with the ID and returns its directory
+func writeBag(t *testing.T, id string) string {
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, afero.NewOsFs())
+	pairPath, err := pairtree.CreatePP("ark:/b5488", ptRoot, "ark:/")
+	require.NoError(t, err)
+
+	bagDir := filepath.Join(t.TempDir(), "bag")
+	require.NoError(t, bagit.Write(context.Background(), bagDir, pairPath, id, "pt test", false, checksum.Options{}))
+
+	return bagDir
+}
+
+// TestImport tests if the payload of the bag becomes the object with the ID of the bag or the one given
+func TestImport(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		id        string
+		expectErr error
+	}{
+		{name: "ID of the bag", id: "ark:/c5488"},
+		{name: "ID given", args: []string{"ark:/d5488"}, id: "ark:/d5488"},
+		{name: "object exists", args: []string{"ark:/a5388"}, expectErr: os.ErrExist},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			bagDir := writeBag(t, "ark:/c5488")
+			ptRoot := pttest.StandardPairtree().BuildTemp(t, afero.NewOsFs())
+
+			var buf bytes.Buffer
+			err := Run(append([]string{root + ptRoot, "--bagit", bagDir}, test.args...), &buf)
+			if test.expectErr != nil {
+				assert.ErrorIs(t, err, test.expectErr)
+				return
+			}
+			require.NoError(t, err)
+
+			pairPath, err := pairtree.CreatePP(test.id, ptRoot, "ark:/")
+			require.NoError(t, err)
+			assert.FileExists(t, filepath.Join(pairPath, "outerb5488.txt"))
+			assert.FileExists(t, filepath.Join(pairPath, "folder", "innerb5488.txt"))
+
+			events, err := premis.Events(ptRoot, "ark:/", test.id)
+			require.NoError(t, err)
+			require.Len(t, events, 1)
+			assert.Equal(t, premis.Ingestion, events[0].Type)
+		})
+	}
+}
+
+// TestInvalidBag tests that a bag that does not pass its checks is not imported
+func TestInvalidBag(t *testing.T) {
+	bagDir := writeBag(t, "ark:/c5488")
+	require.NoError(t, os.WriteFile(filepath.Join(bagDir, bagit.DataDir, "outerb5488.txt"), []byte("changed"), 0644))
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, afero.NewOsFs())
+
+	var buf bytes.Buffer
+	err := Run([]string{root + ptRoot, "--bagit", bagDir}, &buf)
+	assert.ErrorIs(t, err, error_msgs.Err42)
+
+	pairPath, err := pairtree.CreatePP("ark:/c5488", ptRoot, "ark:/")
+	require.NoError(t, err)
+	assert.NoDirExists(t, pairPath)
+}
+
+// TestImportDirs tests that each folder of a directory becomes an object named by the folder or the map,
+// and that a folder that can not be imported does not stop the others
+func TestImportDirs(t *testing.T) {
+	fs := afero.NewOsFs()
+	src := pttest.CreateTempDir(t, fs)
+	for _, path := range []string{"c5488/file.txt", "ark+=d5488/sub/file.txt", "a5388/file.txt", ".hidden/file.txt"} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(src, path)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(src, path), []byte(path), 0644))
+	}
+
+	// The object a5388 exists, so only its folder fails
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, fs)
+	var buf bytes.Buffer
+	err := Run([]string{root + ptRoot, "--dirs", "--jobs", "2", src}, &buf)
+	assert.ErrorIs(t, err, os.ErrExist)
+	assert.Contains(t, buf.String(), "Imported "+filepath.Join(src, "c5488")+" as ark:/c5488\n")
+	assert.Contains(t, buf.String(), "Could not import "+filepath.Join(src, "a5388")+" as ark:/a5388: ")
+	assert.Contains(t, buf.String(), "Imported 2 of 3 objects\n")
+
+	for _, path := range []string{"c5/48/8/c5488/file.txt", "d5/48/8/d5488/sub/file.txt"} {
+		assert.FileExists(t, filepath.Join(ptRoot, "pairtree_root", path))
+	}
+	events, err := premis.Events(ptRoot, "ark:/", "ark:/c5488")
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "imported the folder "+filepath.Join(src, "c5488"), events[0].Detail)
+
+	// The map names the objects of the folders, and a folder that is not there fails
+	ptRoot = pttest.StandardPairtree().BuildTemp(t, fs)
+	mapFile := filepath.Join(pttest.CreateTempDir(t, fs), "map.csv")
+	require.NoError(t, os.WriteFile(mapFile, []byte("folder,id\nc5488,ark:/e5488\nark+=d5488,f5488\nmissing,g5488\n"), 0644))
+
+	buf.Reset()
+	err = Run([]string{root + ptRoot, "--dirs", "--map", mapFile, "--header", "-j", src}, &buf)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	var results []pairtree.Result
+	require.NoError(t, json.NewDecoder(&buf).Decode(&results))
+	require.Len(t, results, 3)
+	assert.Equal(t, "ark:/e5488", results[0].ID)
+	assert.Empty(t, results[0].Error)
+	assert.Equal(t, "ark:/f5488", results[1].ID)
+	assert.NotEmpty(t, results[2].Error)
+
+	assert.FileExists(t, filepath.Join(ptRoot, "pairtree_root", "e5", "48", "8", "e5488", "file.txt"))
+	assert.FileExists(t, filepath.Join(ptRoot, "pairtree_root", "f5", "48", "8", "f5488", "sub", "file.txt"))
+	assert.NoDirExists(t, filepath.Join(ptRoot, "pairtree_root", "g5"))
+
+	// Two folders can not be imported as the same object
+	require.NoError(t, os.WriteFile(mapFile, []byte("c5488,ark:/e5488\na5388,ark:/e5488\n"), 0644))
+	err = Run([]string{root + ptRoot, "--dirs", "--map", mapFile, src}, &buf)
+	assert.ErrorIs(t, err, error_msgs.Err17)
+}
+
+// TestCLIError tests if an error is thrown when the arguments are not valid
+func TestCLIError(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		expectErr error
+	}{
+		{name: "No bag provided", args: []string{root + "root", "--bagit"}, expectErr: error_msgs.Err15},
+		{name: "No pairtree root provided", args: []string{"--bagit", "bag"}, expectErr: error_msgs.Err7},
+		{name: "Too many arguments passed in", args: []string{root + "root", "--bagit", "bag", "ark:/a5388", "extra"},
+			expectErr: error_msgs.Err8},
+		{name: "No format", args: []string{root + "root", "bag"}, expectErr: error_msgs.Err17},
+		{name: "Negative I/O limit", args: []string{root + "root", "--bagit", "--io-limit=-1", "bag"},
+			expectErr: error_msgs.Err17},
+		{name: "Two formats", args: []string{root + "root", "--bagit", "--dirs", "dir"}, expectErr: error_msgs.Err17},
+		{name: "Map without --dirs", args: []string{root + "root", "--bagit", "--map", "map.csv", "bag"},
+			expectErr: error_msgs.Err17},
+		{name: "ID with --dirs", args: []string{root + "root", "--dirs", "dir", "ark:/a5388"}, expectErr: error_msgs.Err8},
+		{name: "No jobs", args: []string{root + "root", "--dirs", "--jobs", "0", "dir"}, expectErr: error_msgs.Err17},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+
+			err := Run(test.args, &buf)
+			assert.ErrorIs(t, err, test.expectErr)
+		})
+	}
+}
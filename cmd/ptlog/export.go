@@ -0,0 +1,185 @@
+package ptlog
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/UCLALibrary/pt-tools/pkg/audit"
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pairtree"
+	"github.com/UCLALibrary/pt-tools/pkg/premis"
+	"github.com/UCLALibrary/pt-tools/utils"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+const (
+	// dateLayout is the layout of the dates of the --since and --until flags
+	dateLayout = "2006-01-02"
+	// formatJSONL writes a record on each line, and the signature on the last line when signed
+	formatJSONL = "jsonl"
+	// formatJSON writes the records and the signature as one JSON object
+	formatJSON = "json"
+)
+
+// Export is the audit trail written by pt log export --format json
+type Export = audit.Trail
+
+// signatureLine is the last line of an audit trail written by pt log export --format jsonl --sign-key
+type signatureLine struct {
+	Signature audit.Signature `json:"signature"`
+}
+
+// exportCommand holds the flags and arguments of one run of pt log export
+type exportCommand struct {
+	format string
+	since  string
+	until  string
+	ptRoot string
+	logger *zap.Logger
+	out    *utils.Output
+}
+
+func (c *exportCommand) initFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&c.format, "format", formatJSONL, "Format of the audit trail, jsonl or json")
+	cmd.Flags().StringVar(&c.since, "since", "", "Only export the events from this date, like 2024-01-01")
+	cmd.Flags().StringVar(&c.until, "until", "", "Only export the events up to and including this date, like 2024-12-31")
+}
+
+// newExportCommand creates the export action of pt log
+func newExportCommand(writer io.Writer) *cobra.Command {
+	c := &exportCommand{logger: Logger, out: utils.NewOutput(io.Discard, &utils.Styler{}, false)}
+
+	var cmd = &cobra.Command{
+		Use:   "export",
+		Short: "pt log export writes the journal of a date range as a hash-chained, optionally signed, audit trail",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return utils.ConfigureLogger(cmd, &c.logger)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+
+			c.out = utils.OutputFromFlags(cmd, writer)
+
+			if c.ptRoot, err = utils.GetPtRoot(cmd, writer); err != nil {
+				return err
+			}
+
+			if len(args) > 0 {
+				c.out.Error("Too many arguments were provided to %s", "pt log export")
+				c.logger.Error("Error parsing pt log export", zap.Error(error_msgs.Err8))
+
+				return error_msgs.Err8
+			}
+
+			if c.format != formatJSONL && c.format != formatJSON {
+				err = fmt.Errorf("%w: --format must be %s or %s", error_msgs.Err17, formatJSONL, formatJSON)
+				c.logger.Error("Error parsing pt log export", zap.Error(err))
+
+				return err
+			}
+
+			since, until, err := c.dateRange()
+			if err != nil {
+				c.logger.Error("Error parsing pt log export", zap.Error(err))
+				return err
+			}
+
+			key, err := utils.SignKeyFromFlags(cmd)
+			if err != nil {
+				c.logger.Error("Error reading the signing key", zap.Error(err))
+				return err
+			}
+
+			utils.SilenceUsage(cmd)
+
+			return c.export(writer, since, until, key)
+		},
+	}
+
+	c.initFlags(cmd)
+	utils.AddSignKeyFlag(cmd)
+
+	return cmd
+}
+
+// dateRange returns the start of the --since date and the end of the --until date, which are zero
+// when the flags are not set
+func (c *exportCommand) dateRange() (since, until time.Time, err error) {
+	if c.since != "" {
+		if since, err = time.Parse(dateLayout, c.since); err != nil {
+			return since, until, fmt.Errorf("%w: --since must be a date like 2024-01-01: %w", error_msgs.Err17, err)
+		}
+	}
+
+	if c.until != "" {
+		if until, err = time.Parse(dateLayout, c.until); err != nil {
+			return since, until, fmt.Errorf("%w: --until must be a date like 2024-12-31: %w", error_msgs.Err17, err)
+		}
+		until = until.AddDate(0, 0, 1)
+	}
+
+	return since, until, nil
+}
+
+// export writes the events of the date range as an audit trail, signed with the key when it is set
+func (c *exportCommand) export(writer io.Writer, since, until time.Time, key ed25519.PrivateKey) error {
+	// check if the pairtree version file exists and is populated
+	if err := pairtree.CheckPTVer(c.ptRoot); err != nil {
+		c.logger.Error("Error with pairtree veresion file", zap.Error(err))
+		return err
+	}
+
+	events, err := premis.AllEvents(c.ptRoot)
+	if err != nil {
+		c.logger.Error("Error reading the events of the pairtree", zap.Error(err))
+		return err
+	}
+
+	inRange := []premis.Event{}
+	for _, event := range events {
+		if event.DateTime.Before(since) || (!until.IsZero() && !event.DateTime.Before(until)) {
+			continue
+		}
+		inRange = append(inRange, event)
+	}
+
+	records, err := audit.Chain(inRange)
+	if err != nil {
+		c.logger.Error("Error chaining the events", zap.Error(err))
+		return err
+	}
+
+	export := Export{Records: records}
+	if key != nil {
+		signature := audit.Sign(key, records)
+		export.Signature = &signature
+	}
+
+	c.logger.Info("Exported the audit trail", zap.Int("records", len(records)), zap.Bool("signed", key != nil))
+
+	if c.format == formatJSON {
+		jsonData, err := json.MarshalIndent(export, "", "  ")
+		if err != nil {
+			c.logger.Error("Error converting the audit trail to JSON", zap.Error(err))
+			return err
+		}
+		fmt.Fprintln(writer, string(jsonData))
+		return nil
+	}
+
+	encoder := json.NewEncoder(writer)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	if export.Signature != nil {
+		return encoder.Encode(signatureLine{Signature: *export.Signature})
+	}
+
+	return nil
+}
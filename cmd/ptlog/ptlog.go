@@ -0,0 +1,54 @@
+package ptlog
+
+/* ptlog works with the journal of the operations pt has performed on a pairtree, which is the
+preservation events recorded for its objects. pt log export writes the events of a date range as a
+hash-chained audit trail, signed with an Ed25519 key with --sign, so it can be kept by an
+institutional audit system and checked for tampering later. */
+
+import (
+	"fmt"
+	"io"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/utils"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	// Logger is the logger each run of pt log starts from, tests replace it to capture the logs
+	Logger *zap.Logger = utils.ConsoleLogger()
+)
+
+// NewCommand creates the log subcommand of pt, with a subcommand for each way of using the journal,
+// that writes its output to the writer
+func NewCommand(writer io.Writer) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "log [action]",
+		Short: "pt log works with the journal of the operations performed on the pairtree",
+		// Only runs when no action or an unknown one is given
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				return fmt.Errorf("%w: unknown action %q for %q", error_msgs.Err17, args[0], cmd.CommandPath())
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return fmt.Errorf("%w: an action must be provided", error_msgs.Err17)
+		},
+	}
+
+	cmd.AddCommand(newExportCommand(writer))
+
+	return cmd
+}
+
+// Run executes pt log with the given arguments, the first of which is the action
+func Run(args []string, writer io.Writer) error {
+	if err := utils.RunSubcommand(NewCommand(writer), args, writer); err != nil {
+		Logger.Error("Error running pt log", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,147 @@
+package ptlog
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/UCLALibrary/pt-tools/pkg/audit"
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/premis"
+	"github.com/UCLALibrary/pt-tools/pkg/pttest"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	root = "--pairtree="
+)
+
+// TestMain runs the tests with the logger of the test sink, it is set once so that the tests can run in parallel
+func TestMain(m *testing.M) {
+	logger, cleanup := pttest.SetupLogger()
+	Logger = logger
+
+	code := m.Run()
+	cleanup()
+	os.Exit(code)
+}
+
+// newJournalPairtree builds a pairtree with events recorded on 2024-01-10, 2024-02-10, and 2024-03-10
+func newJournalPairtree(t *testing.T) string {
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, afero.NewOsFs())
+
+	for i, id := range []string{"ark:/a5388", "ark:/b5488", "ark:/a5388"} {
+		event := premis.NewEvent(premis.Ingestion, id, "", nil)
+		event.DateTime = time.Date(2024, time.Month(i+1), 10, 12, 0, 0, 0, time.UTC)
+		require.NoError(t, premis.Record(ptRoot, "ark:/", event))
+	}
+
+	return ptRoot
+}
+
+// TestExportJSONL tests if the events of the date range are written a record per line with the signature last
+func TestExportJSONL(t *testing.T) {
+	ptRoot := newJournalPairtree(t)
+	key, keyPath, _ := pttest.SigningKey(t, t.TempDir())
+
+	var buf bytes.Buffer
+	err := Run([]string{"export", root + ptRoot, "--since=2024-02-01", "--until=2024-03-10", "--sign-key=" + keyPath}, &buf)
+	require.NoError(t, err)
+
+	scanner := bufio.NewScanner(&buf)
+	records := []audit.Record{}
+	var last signatureLine
+
+	for scanner.Scan() {
+		var record audit.Record
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+
+		if record.Seq == 0 {
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &last))
+			continue
+		}
+		records = append(records, record)
+	}
+
+	// The until date is included
+	require.Len(t, records, 2)
+	assert.Equal(t, "ark:/b5488", records[0].Event.Object)
+	assert.Equal(t, "ark:/a5388", records[1].Event.Object)
+
+	assert.NoError(t, audit.VerifySignature(last.Signature, records))
+	assert.Equal(t, base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey)), last.Signature.PublicKey)
+}
+
+// TestExportJSON tests if the audit trail is written as one JSON object with --format json
+func TestExportJSON(t *testing.T) {
+	ptRoot := newJournalPairtree(t)
+
+	var buf bytes.Buffer
+	err := Run([]string{"export", root + ptRoot, "--format=json", "--until=2024-02-09"}, &buf)
+	require.NoError(t, err)
+
+	var export Export
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &export))
+	require.Len(t, export.Records, 1)
+	assert.Nil(t, export.Signature)
+	assert.NoError(t, audit.Verify(export.Records))
+}
+
+// TestExportSignKey tests if an audit trail signed with --sign-key, in either format, can be read back and
+// checked against the public key
+func TestExportSignKey(t *testing.T) {
+	ptRoot := newJournalPairtree(t)
+	key, keyPath, _ := pttest.SigningKey(t, t.TempDir())
+
+	for _, format := range []string{formatJSONL, formatJSON} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, Run([]string{"export", root + ptRoot, "--format=" + format, "--sign-key", keyPath}, &buf))
+
+			trail, err := audit.ReadTrail(&buf)
+			require.NoError(t, err)
+			require.Len(t, trail.Records, 3)
+			require.NotNil(t, trail.Signature)
+			assert.NoError(t, audit.VerifyTrail(trail, key.Public().(ed25519.PublicKey)))
+		})
+	}
+}
+
+// TestCLIError tests if the arguments and flags of pt log are checked
+func TestCLIError(t *testing.T) {
+	notKey := filepath.Join(t.TempDir(), "notkey.pem")
+	require.NoError(t, os.WriteFile(notKey, []byte("not a key"), 0600))
+
+	tests := []struct {
+		name      string
+		args      []string
+		expectErr error
+	}{
+		{name: "No action", args: []string{root + "root"}, expectErr: error_msgs.Err17},
+		{name: "Unknown action", args: []string{"exprot", root + "root"}, expectErr: error_msgs.Err17},
+		{name: "No pairtree root provided", args: []string{"export"}, expectErr: error_msgs.Err7},
+		{name: "Too many arguments passed in", args: []string{"export", root + "root", "ark:/a5388"}, expectErr: error_msgs.Err8},
+		{name: "Unknown format", args: []string{"export", root + "root", "--format=csv"}, expectErr: error_msgs.Err17},
+		{name: "Date not valid", args: []string{"export", root + "root", "--since=01/02/2024"}, expectErr: error_msgs.Err17},
+		{name: "Key not valid", args: []string{"export", root + "root", "--sign-key=" + notKey}, expectErr: error_msgs.Err38},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+
+			err := Run(test.args, &buf)
+			assert.ErrorIs(t, err, test.expectErr)
+		})
+	}
+}
@@ -3,19 +3,28 @@ package ptls
 /*ptls: an ls-like tool that can display the contents of the Pairtree object; options
 include: -a (but have this work like ls' -A which does include the . and .. directories in the
 output), -d (which only lists directories of the object directory), -j (which returns output in a
-JSON structure instead of basic string output), and -R (for a recursive listing of the object directory,
-with the default being a non-recursive listing). The basic command is ptls [ID]
+JSON structure instead of basic string output), -R (for a recursive listing of the object directory,
+with the default being a non-recursive listing, and --depth limiting how many levels it goes down),
+and -l (which shows the mode, size, and modification time of each entry like ls -l). With -H sizes
+are in KiB, MiB, and larger units, and each listing ends with the total of its files, directories,
+and bytes. The entries of each directory are sorted by name, or by size or modification time with
+--sort, and --reverse lists them in the reverse order. The basic command is ptls [ID]
 (when an ENV PAIRTREE_ROOT is set) or ptls [PT_ROOT] [ID]) with the output listing the contents of
 the Pairtree object directory (doing all the navigation through the Pairtree structure behind the scenes).
+More than one ID can be given, or read from a file or standard input with --ids-from, and each
+object's listing is then labeled with its ID. An ID with *, ?, or [ is a glob pattern that lists
+every object whose ID matches it.
 It also supports -h for details about what it can do.*/
 
-// Just one ID
 import (
+	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
-	"os"
-	"path/filepath"
+	"slices"
+	"strconv"
 
 	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
 	"github.com/UCLALibrary/pt-tools/pkg/pairtree"
@@ -32,184 +41,330 @@ type FileInfo struct {
 }
 
 var (
+	// Logger is the logger each run of pt ls starts from, tests replace it to capture the logs
+	Logger *zap.Logger = utils.ConsoleLogger()
+)
+
+// command holds the flags and arguments of one run of pt ls so that runs can happen concurrently
+type command struct {
 	showAll      bool
 	showDirsOnly bool
 	outputJSON   bool
 	recursive    bool
+	unsorted     bool
+	long         bool
+	human        bool
+	sort         string
+	reverse      bool
+	depth        int
+	jobs         int
 	ptRoot       string
-	logFile      string      = "logs.log"
-	Logger       *zap.Logger = utils.Logger(logFile)
-	id           string      = ""
-)
-
-func initFlags(cmd *cobra.Command) {
-	cmd.Flags().BoolVarP(&showAll, "a", "a", false, "do not ignore entries starting with .")
-	cmd.Flags().BoolVarP(&showDirsOnly, "d", "d", false, "list directories only")
-	cmd.Flags().BoolVarP(&outputJSON, "j", "j", false, "output in JSON format")
-	cmd.Flags().BoolVarP(&recursive, "r", "r", false, "list directories recursively")
-	cmd.Flags().StringVarP(&ptRoot, "pairtree", "p", "", "Set pairtree root directory")
+	ids          []string
+	logger       *zap.Logger
+	out          *utils.Output
+}
 
+func (c *command) initFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(&c.showAll, "a", "a", false, "do not ignore entries starting with .")
+	cmd.Flags().BoolVarP(&c.showDirsOnly, "d", "d", false, "list directories only")
+	cmd.Flags().BoolVarP(&c.outputJSON, "j", "j", false, "output in JSON format")
+	cmd.Flags().BoolVarP(&c.recursive, "r", "r", false, "list directories recursively")
+	cmd.Flags().BoolVarP(&c.unsorted, "U", "U", false, "do not sort, list each entry as it is read with its path in the object")
+	cmd.Flags().BoolVarP(&c.long, "l", "l", false, "use a long listing format with the mode, size, and modification time of each entry")
+	cmd.Flags().BoolVarP(&c.human, "H", "H", false, "show sizes in KiB, MiB, and larger units and end each listing with a summary")
+	cmd.Flags().StringVar(&c.sort, "sort", string(pairtree.SortByName), "sort the entries of each directory by name, size, or mtime")
+	cmd.Flags().BoolVar(&c.reverse, "reverse", false, "list the entries of each directory in reverse order")
+	cmd.Flags().IntVar(&c.depth, "depth", 0, "list at most this many levels of directories with -r (0 is no limit)")
+	cmd.Flags().IntVar(&c.jobs, "jobs", 1, "directories of a recursive listing read at once")
 }
 
-func Run(args []string, writer io.Writer) error {
-	var ptMap map[string][]fs.DirEntry
-	var err error
-	var pairPath string
-
-	var rootCmd = &cobra.Command{
-		Use:   "pt ls -p [PT_ROOT] [FLAGS] [ID]",
-		Short: "pt ls is a tool to list Pairtree object directories.",
-		Long:  "A tool to list contents of Pairtree object directories with various options.",
+// NewCommand creates the ls subcommand of pt that writes its output to the writer
+func NewCommand(writer io.Writer) *cobra.Command {
+	c := &command{logger: Logger, out: utils.NewOutput(io.Discard, &utils.Styler{}, false)}
+
+	var cmd = &cobra.Command{
+		Use:               "ls [FLAGS] [ID|PATTERN]...",
+		Short:             "pt ls is a tool to list Pairtree object directories.",
+		ValidArgsFunction: utils.CompleteIDs,
+		Long:              "A tool to list contents of Pairtree object directories with various options.",
+		Annotations:       map[string]string{utils.S3Annotation: "true"},
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return utils.ConfigureLogger(cmd, &c.logger)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			// If the root has not been set yet check the ENV vars
-			if ptRoot == "" {
+			var err error
 
-				if envVar := os.Getenv("PAIRTREE_ROOT"); envVar != "" {
-					ptRoot = envVar
-				} else {
-					fmt.Fprintln(writer, error_msgs.Err7)
-					return error_msgs.Err7
-				}
+			c.out = utils.OutputFromFlags(cmd, writer)
+
+			if c.ptRoot, err = utils.GetPtRoot(cmd, writer); err != nil {
+				return err
 			}
 
-			if len(args) < 1 {
-				fmt.Fprintln(writer, "Please provide an ID for the pairtree")
-				Logger.Error("Error getting ID",
+			// The IDs of the arguments are listed before those read with --ids-from
+			fromIDs, err := utils.IDsFromFlags(cmd)
+			if err != nil {
+				c.logger.Error("Error reading IDs", zap.Error(err))
+				return err
+			}
+			c.ids = append(args, fromIDs...)
+
+			if len(c.ids) < 1 {
+				c.out.Error("Please provide an ID for the pairtree")
+				c.logger.Error("Error getting ID",
 					zap.Error(error_msgs.Err6))
 
 				return error_msgs.Err6
 			}
-			// Extract the ID from the final argument
-			id = args[len(args)-1]
 
-			Logger.Info("Pairtree root is",
-				zap.String("PAIRTREE_ROOT", ptRoot),
+			// The persistent --json flag is the same as -j
+			if jsonFlag, _ := cmd.Flags().GetBool(utils.JSONFlag); jsonFlag {
+				c.outputJSON = true
+			}
+
+			c.logger.Info("Pairtree root is",
+				zap.String("PAIRTREE_ROOT", c.ptRoot),
 			)
-			return nil
+
+			// The JSON of a listing is a tree, which can not be written in the order entries are read
+			if c.unsorted && c.outputJSON {
+				err := fmt.Errorf("%w: -U can not be used with -j", error_msgs.Err17)
+				c.logger.Error("Error checking the options", zap.Error(err))
+				return err
+			}
+
+			if !slices.Contains(pairtree.SortOrders, pairtree.SortOrder(c.sort)) {
+				err := fmt.Errorf("%w: --sort must be name, size, or mtime", error_msgs.Err17)
+				c.logger.Error("Error checking the options", zap.Error(err))
+				return err
+			}
+
+			// Entries are written as they are read with -U, so they can not be sorted
+			if c.unsorted && (cmd.Flags().Changed("sort") || c.reverse) {
+				err := fmt.Errorf("%w: -U can not be used with --sort or --reverse", error_msgs.Err17)
+				c.logger.Error("Error checking the options", zap.Error(err))
+				return err
+			}
+
+			// The sizes of JSON are numbers, so they are not made human-readable
+			if c.human && c.outputJSON {
+				err := fmt.Errorf("%w: -H can not be used with -j", error_msgs.Err17)
+				c.logger.Error("Error checking the options", zap.Error(err))
+				return err
+			}
+
+			if c.depth < 0 {
+				err := fmt.Errorf("%w: --depth must not be negative", error_msgs.Err17)
+				c.logger.Error("Error checking the options", zap.Error(err))
+				return err
+			}
+
+			if c.depth > 0 && !c.recursive {
+				err := fmt.Errorf("%w: --depth can only be used with -r", error_msgs.Err17)
+				c.logger.Error("Error checking the options", zap.Error(err))
+				return err
+			}
+
+			if c.jobs < 1 {
+				err := fmt.Errorf("%w: --jobs must be at least 1", error_msgs.Err17)
+				c.logger.Error("Error checking the options", zap.Error(err))
+				return err
+			}
+
+			utils.SilenceUsage(cmd)
+
+			return c.listAll(cmd.Context(), writer)
 		},
 	}
 
-	initFlags(rootCmd)
-	rootCmd.SetOut(writer)
-	rootCmd.SetErr(writer)
-	rootCmd.SetArgs(args)
+	c.initFlags(cmd)
+	utils.AddIDsFromFlag(cmd)
 
-	utils.ApplyExitOnHelp(rootCmd, 0)
+	return cmd
+}
 
-	if err = rootCmd.Execute(); err != nil {
-		Logger.Error("Error setting command line",
+// Run executes pt ls with the given arguments
+func Run(args []string, writer io.Writer) error {
+	if err := utils.RunSubcommand(NewCommand(writer), args, writer); err != nil {
+		Logger.Error("Error running pt ls",
 			zap.Error(err))
 		return err
 	}
 
-	// check if the pairtree version file exists and is populated
-	if err := pairtree.CheckPTVer(ptRoot); err != nil {
-		Logger.Error("Error with pairtree veresion file", zap.Error(err))
+	return nil
+}
+
+// listAll lists each of the objects, after a line with its ID when there is more than one. An object
+// that can not be listed does not stop the others, and its error is returned with theirs.
+func (c *command) listAll(ctx context.Context, writer io.Writer) error {
+	// Open the pairtree, which checks its version file and reads its prefix
+	pt, err := pairtree.Open(c.ptRoot)
+	if err != nil {
+		c.logger.Error("Error opening the pairtree", zap.Error(err))
 		return err
 	}
 
-	// Get the prefix from pairtree_prefix file
-	prefix, err := pairtree.GetPrefix(ptRoot)
-
+	ids, errs, err := c.expandIDs(ctx, pt)
 	if err != nil {
-		Logger.Error("Error retrieving prefix from pairtree_prefix file", zap.Error(err))
+		c.logger.Error("Error matching IDs", zap.Error(err))
 		return err
 	}
 
-	if prefix == "" {
-		prefix = pairtree.PtPrefix
+	// The listing of an object is labeled unless it is the one ID that was given
+	if len(ids) == 1 && len(c.ids) == 1 && !pairtree.IsIDPattern(c.ids[0]) {
+		return c.list(ctx, writer, pt, ids[0])
 	}
 
-	// create the pairpath
-	pairPath, err = pairtree.CreatePP(id, ptRoot, prefix)
+	for i, id := range ids {
+		// Stop before the next object once the context is canceled
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
 
-	if err != nil {
-		Logger.Error("Error creating pairpath", zap.Error(err))
-		return err
+		if i > 0 {
+			fmt.Fprintln(writer)
+		}
+		fmt.Fprintf(writer, "==> %s <==\n", id)
+
+		errs = append(errs, c.list(ctx, writer, pt, id))
 	}
 
-	if recursive {
-		ptMap, err = pairtree.RecursiveFiles(pairPath, id)
-		if err != nil {
-			Logger.Error("Error retrieving list of files recursively", zap.Error(err))
-			return err
+	return errors.Join(errs...)
+}
+
+// expandIDs replaces each glob pattern of the IDs with the IDs of the objects that match it. A pattern
+// that matches no objects is an error that does not stop the others, like an object that does not exist.
+func (c *command) expandIDs(ctx context.Context, pt *pairtree.Pairtree) ([]string, []error, error) {
+	if !slices.ContainsFunc(c.ids, pairtree.IsIDPattern) {
+		return c.ids, nil, nil
+	}
+
+	var ids []string
+	var errs []error
+	for _, arg := range c.ids {
+		if !pairtree.IsIDPattern(arg) {
+			ids = append(ids, arg)
+			continue
 		}
-	} else {
-		ptMap, err = pairtree.NonRecursiveFiles(pairPath)
+
+		matched, err := pt.MatchIDs(ctx, arg)
 		if err != nil {
-			Logger.Error("Error retrieving list of files recursively", zap.Error(err))
-			return err
+			return nil, nil, err
 		}
-	}
 
-	if showDirsOnly {
-		// Filter ptMap to only include directories
-		for key, entries := range ptMap {
-			var filteredEntries []fs.DirEntry
-			for _, entry := range entries {
-				if pairtree.IsDirectory(entry) {
-					filteredEntries = append(filteredEntries, entry)
-				}
-			}
-			if len(filteredEntries) > 0 {
-				ptMap[key] = filteredEntries
-			} else {
-				delete(ptMap, key)
-			}
+		if len(matched) == 0 {
+			c.out.Error("No objects match %s", arg)
+			errs = append(errs, &error_msgs.PtError{ID: arg, Err: error_msgs.Err45})
 		}
+		ids = append(ids, matched...)
 	}
 
-	// If hidden files and directories should be removed from the map
-	if !showAll {
-		for key, entries := range ptMap {
-			// Check if the key (directory name) is hidden
-			if pairtree.IsHidden(filepath.Base(key)) {
-				// If the key is hidden, remove it from the map
-				delete(ptMap, key)
-				continue
-			}
+	return ids, errs, nil
+}
 
-			// Filter out hidden entries within the directory
-			var filteredEntries []fs.DirEntry
-			for _, entry := range entries {
-				if !pairtree.IsHidden(entry.Name()) {
-					filteredEntries = append(filteredEntries, entry)
-				}
-			}
+// list writes the contents of the pairtree object to the writer a directory at a time, so objects
+// with millions of files are listed without holding them all in memory
+func (c *command) list(ctx context.Context, writer io.Writer, pt *pairtree.Pairtree, id string) error {
+	// create the pairpath
+	pairPath, err := pt.PairPath(id)
+	if err != nil {
+		c.logger.Error("Error creating pairpath", zap.Error(err))
+		return &error_msgs.PtError{ID: id, Err: err}
+	}
 
-			// Update the map with filtered entries or remove the key if no entries remain
-			if len(filteredEntries) > 0 {
-				ptMap[key] = filteredEntries
-			} else {
-				delete(ptMap, key)
+	opts := pairtree.ListOptions{Recursive: c.recursive, ShowAll: c.showAll, DirsOnly: c.showDirsOnly, Jobs: c.jobs,
+		Long: c.long, Sort: pairtree.SortOrder(c.sort), Reverse: c.reverse,
+		Depth: c.depth}
+	buffered := bufio.NewWriter(writer)
+	sum := &summary{}
+
+	if c.unsorted {
+		// Entries are written as they are read, so a directory of millions of files is not held in memory
+		err = pt.WalkCtx(ctx, id, opts, func(entry pairtree.Entry) error {
+			details, err := c.details(entry, sum)
+			if err != nil {
+				return err
 			}
-		}
-	}
 
-	if outputJSON {
-		dirTree := pairtree.BuildDirectoryTree(pairPath, ptMap, true)
+			if pairtree.IsDirectory(entry) {
+				_, err := fmt.Fprintln(buffered, details+c.out.Style().Directory(entry.Path+"/"))
+				return err
+			}
 
-		recursiveJSON, err := pairtree.ToJSONStructure(dirTree)
-		if err != nil {
-			Logger.Error("Error converting to Json", zap.Error(err))
+			_, err = fmt.Fprintln(buffered, details+entry.Path)
 			return err
-		}
-		fmt.Fprintf(writer, "JSON structure:\n%s\n", string(recursiveJSON))
+		})
+	} else if c.outputJSON {
+		fmt.Fprintf(buffered, "%s\n", c.out.T("JSON structure:"))
+		err = pt.WriteListingJSONCtx(ctx, buffered, pairPath, opts)
+		fmt.Fprintln(buffered)
 	} else {
-
-		// Display the directory structure
-		for dir, entries := range ptMap {
-			fmt.Fprintln(writer, dir+":")
+		// Directories are listed depth first in name order so the output is the same on every run
+		err = pt.WalkListingCtx(ctx, pairPath, opts, func(dir string, entries []fs.DirEntry) error {
+			fmt.Fprintln(buffered, c.out.Style().Directory(dir)+":")
 			for _, entry := range entries {
+				details, err := c.details(entry, sum)
+				if err != nil {
+					return err
+				}
+
 				if pairtree.IsDirectory(entry) {
-					fmt.Fprintf(writer, "  %s/\n", entry.Name())
+					fmt.Fprintf(buffered, "  %s%s\n", details, c.out.Style().Directory(entry.Name()+"/"))
 				} else {
-					fmt.Fprintf(writer, "  %s\n", entry.Name())
+					fmt.Fprintf(buffered, "  %s%s\n", details, entry.Name())
 				}
 			}
-		}
+			return nil
+		})
+	}
 
+	if err != nil {
+		c.logger.Error("Error listing the files of the object", zap.Error(err))
+		return &error_msgs.PtError{ID: id, Path: pairPath, Err: err}
 	}
 
-	return nil
+	if c.human {
+		fmt.Fprintln(buffered, c.out.T("Total: %d files, %d directories, %s", sum.files, sum.dirs,
+			utils.FormatSize(sum.bytes)))
+	}
+
+	return buffered.Flush()
+}
+
+// summary is the number of files and directories of a listing and the bytes of its files
+type summary struct {
+	files int
+	dirs  int
+	bytes int64
+}
+
+// details returns the mode, size, and modification time that come before the name of the entry in a
+// long listing, or nothing when the listing is not long. With -H the entry is added to the summary.
+func (c *command) details(entry fs.DirEntry, sum *summary) (string, error) {
+	if !c.long && !c.human {
+		return "", nil
+	}
+
+	info, err := entry.Info()
+	if err != nil {
+		return "", err
+	}
+
+	if pairtree.IsDirectory(entry) {
+		sum.dirs++
+	} else {
+		sum.files++
+		sum.bytes += info.Size()
+	}
+
+	if !c.long {
+		return "", nil
+	}
+
+	size := strconv.FormatInt(info.Size(), 10)
+	if c.human {
+		size = utils.FormatSize(info.Size())
+	}
+
+	return fmt.Sprintf("%s %12s %s ", info.Mode(), size, info.ModTime().Format("2006-01-02 15:04")), nil
 }
@@ -1,22 +1,43 @@
 package ptls
 
-// The test-dir that is copied and used throughout this test. Both the pairtree_version0_1
+// The pairtree built by pttest.StandardPairtree is used throughout this test. Both the pairtree_version0_1
 // and the pairtree_prefix are populated. The pairtree_prefix is populated with the prefix ark:/
 // unless the test removes or changes that.
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
-	"github.com/UCLALibrary/pt-tools/testutils"
+	"github.com/UCLALibrary/pt-tools/pkg/pairtree"
+	"github.com/UCLALibrary/pt-tools/pkg/pttest"
+	"github.com/UCLALibrary/pt-tools/utils"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const (
 	root = "--pairtree="
 )
 
+// TestMain runs the tests with the logger of the test sink, it is set once so that the tests can run in parallel
+func TestMain(m *testing.M) {
+	logger, cleanup := pttest.SetupLogger()
+	Logger = logger
+
+	code := m.Run()
+	cleanup()
+	os.Exit(code)
+}
+
 // runTestWithArgs
 func runTestWithArgs(t *testing.T, args, expected []string) {
 	var buf bytes.Buffer
@@ -45,18 +66,15 @@ func TestNonRecursive(t *testing.T) {
 		{id: "ark:/b5488", expected: []string{"outerb5488.txt", "folder/"}},
 	}
 
-	// Create a logger instance using the registered sink.
-	logger, cleanup := testutils.SetupLogger(logFile)
-	defer cleanup()
-	Logger = logger
-
 	for _, test := range tests {
 		t.Run(test.id, func(t *testing.T) {
+			t.Parallel()
+
 			// var buf bytes.Buffer
 
 			fs := afero.NewOsFs()
-			tempDir := testutils.CreateTempDir(t, fs)
-			testutils.CopyTestDirectory(t, testutils.TestPairtree, tempDir)
+			tempDir := pttest.CreateTempDir(t, fs)
+			pttest.StandardPairtree().Build(t, fs, tempDir)
 
 			args := []string{root + tempDir, test.id}
 			runTestWithArgs(t, args, test.expected)
@@ -76,17 +94,13 @@ func TestRecursive(t *testing.T) {
 		{id: "ark:/b5488", expected: []string{"outerb5488.txt", "folder/", "innerb5488.txt"}},
 	}
 
-	// Create a logger instance using the registered sink.
-	logger, cleanup := testutils.SetupLogger(logFile)
-	defer cleanup()
-
-	Logger = logger
-
 	for _, test := range tests {
 		t.Run(test.id, func(t *testing.T) {
+			t.Parallel()
+
 			fs := afero.NewOsFs()
-			tempDir := testutils.CreateTempDir(t, fs)
-			testutils.CopyTestDirectory(t, testutils.TestPairtree, tempDir)
+			tempDir := pttest.CreateTempDir(t, fs)
+			pttest.StandardPairtree().Build(t, fs, tempDir)
 
 			args := []string{root + tempDir, "-r", test.id}
 			runTestWithArgs(t, args, test.expected)
@@ -105,16 +119,13 @@ func TestDirOnly(t *testing.T) {
 		{id: "ark:/b5488", expected: []string{"folder/"}},
 	}
 
-	// Create a logger instance using the registered sink.
-	logger, cleanup := testutils.SetupLogger(logFile)
-	defer cleanup()
-	Logger = logger
-
 	for _, test := range tests {
 		t.Run(test.id, func(t *testing.T) {
+			t.Parallel()
+
 			fs := afero.NewOsFs()
-			tempDir := testutils.CreateTempDir(t, fs)
-			testutils.CopyTestDirectory(t, testutils.TestPairtree, tempDir)
+			tempDir := pttest.CreateTempDir(t, fs)
+			pttest.StandardPairtree().Build(t, fs, tempDir)
 
 			args := []string{root + tempDir, "-d", test.id}
 			runTestWithArgs(t, args, test.expected)
@@ -133,16 +144,13 @@ func TestShowAll(t *testing.T) {
 		{id: "ark:/a54892", expected: []string{".hidden/", ".hidden.txt", "a54892.txt"}},
 	}
 
-	// Create a logger instance using the registered sink.
-	logger, cleanup := testutils.SetupLogger(logFile)
-	defer cleanup()
-	Logger = logger
-
 	for _, test := range tests {
 		t.Run(test.id, func(t *testing.T) {
+			t.Parallel()
+
 			fs := afero.NewOsFs()
-			tempDir := testutils.CreateTempDir(t, fs)
-			testutils.CopyTestDirectory(t, testutils.TestPairtree, tempDir)
+			tempDir := pttest.CreateTempDir(t, fs)
+			pttest.StandardPairtree().Build(t, fs, tempDir)
 
 			args := []string{root + tempDir, "-a", test.id}
 			runTestWithArgs(t, args, test.expected)
@@ -162,16 +170,13 @@ func TestShowAllAndDironly(t *testing.T) {
 		{id: "ark:/b5488", expected: []string{"folder/"}},
 	}
 
-	// Create a logger instance using the registered sink.
-	logger, cleanup := testutils.SetupLogger(logFile)
-	defer cleanup()
-	Logger = logger
-
 	for _, test := range tests {
 		t.Run(test.id, func(t *testing.T) {
+			t.Parallel()
+
 			fs := afero.NewOsFs()
-			tempDir := testutils.CreateTempDir(t, fs)
-			testutils.CopyTestDirectory(t, testutils.TestPairtree, tempDir)
+			tempDir := pttest.CreateTempDir(t, fs)
+			pttest.StandardPairtree().Build(t, fs, tempDir)
 			args := []string{root + tempDir, "-a", "-d", test.id}
 			runTestWithArgs(t, args, test.expected)
 		})
@@ -189,16 +194,13 @@ func TestShowAllRecursive(t *testing.T) {
 		{id: "ark:/b5488", expected: []string{"folder/", "outerb5488.txt", ".hidden/", ".hiddenFile.txt", "innerb5488.txt", "inner.txt"}},
 	}
 
-	// Create a logger instance using the registered sink.
-	logger, cleanup := testutils.SetupLogger(logFile)
-	defer cleanup()
-	Logger = logger
-
 	for _, test := range tests {
 		t.Run(test.id, func(t *testing.T) {
+			t.Parallel()
+
 			af := afero.NewOsFs()
-			tempDir := testutils.CreateTempDir(t, af)
-			testutils.CopyTestDirectory(t, testutils.TestPairtree, tempDir)
+			tempDir := pttest.CreateTempDir(t, af)
+			pttest.StandardPairtree().Build(t, af, tempDir)
 
 			args := []string{root + tempDir, "-r", "-a", test.id}
 			runTestWithArgs(t, args, test.expected)
@@ -217,16 +219,13 @@ func TestDirOnlyRecursive(t *testing.T) {
 		{id: "ark:/b5488", expected: []string{"folder/", ".hidden/"}},
 	}
 
-	// Create a logger instance using the registered sink.
-	logger, cleanup := testutils.SetupLogger(logFile)
-	defer cleanup()
-	Logger = logger
-
 	for _, test := range tests {
 		t.Run(test.id, func(t *testing.T) {
+			t.Parallel()
+
 			fs := afero.NewOsFs()
-			tempDir := testutils.CreateTempDir(t, fs)
-			testutils.CopyTestDirectory(t, testutils.TestPairtree, tempDir)
+			tempDir := pttest.CreateTempDir(t, fs)
+			pttest.StandardPairtree().Build(t, fs, tempDir)
 			args := []string{root + tempDir, "-r", "-a", "-d", test.id}
 			runTestWithArgs(t, args, test.expected)
 		})
@@ -245,13 +244,10 @@ func TestCLIError(t *testing.T) {
 		{name: "noRoot", args: "ID", expectErr: error_msgs.Err7},
 	}
 
-	// Create a logger instance using the registered sink.
-	logger, cleanup := testutils.SetupLogger(logFile)
-	defer cleanup()
-	Logger = logger
-
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
 			var buf bytes.Buffer
 
 			args := []string{root + "dir"}
@@ -261,3 +257,375 @@ func TestCLIError(t *testing.T) {
 	}
 
 }
+
+// TestPersistentJSON tests if the persistent --json flag outputs the same JSON structure as -j
+func TestPersistentJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		flag string
+	}{
+		{name: "short flag", flag: "-j"},
+		{name: "persistent flag", flag: "--json"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			fs := afero.NewOsFs()
+			tempDir := pttest.CreateTempDir(t, fs)
+			pttest.StandardPairtree().Build(t, fs, tempDir)
+
+			args := []string{root + tempDir, test.flag, "ark:/a5388"}
+			runTestWithArgs(t, args, []string{"JSON structure:", `"name": "a5388.txt"`})
+		})
+	}
+}
+
+// TestJSONErrors tests if the error is left to the caller when errors are written as JSON
+func TestJSONErrors(t *testing.T) {
+	fs := afero.NewOsFs()
+	tempDir := pttest.CreateTempDir(t, fs)
+	pttest.StandardPairtree().Build(t, fs, tempDir)
+
+	var buf bytes.Buffer
+	err := Run([]string{root + tempDir, "--errors=json", "ark:/notAnObject"}, &buf)
+
+	var ptErr *error_msgs.PtError
+	assert.ErrorAs(t, err, &ptErr)
+	assert.Equal(t, "ark:/notAnObject", ptErr.ID)
+	assert.NotContains(t, buf.String(), "Error:")
+}
+
+// TestUnsorted tests that -U lists each entry with its path in the object, one per line
+func TestUnsorted(t *testing.T) {
+	fs := afero.NewOsFs()
+	tempDir := pttest.CreateTempDir(t, fs)
+	pttest.StandardPairtree().Build(t, fs, tempDir)
+
+	var buf bytes.Buffer
+	err := Run([]string{root + tempDir, "-U", "-r", "ark:/b5488"}, &buf)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.ElementsMatch(t, []string{"folder/", "folder/innerb5488.txt", "outerb5488.txt"}, lines)
+
+	err = Run([]string{root + tempDir, "-U", "-j", "ark:/b5488"}, &buf)
+	assert.ErrorIs(t, err, error_msgs.Err17)
+}
+
+// TestLong tests that a long listing shows the mode, size, and modification time of each entry in
+// text and in JSON
+func TestLong(t *testing.T) {
+	fs := afero.NewOsFs()
+	tempDir := pttest.CreateTempDir(t, fs)
+	pttest.StandardPairtree().Build(t, fs, tempDir)
+
+	pt, err := pairtree.Open(tempDir)
+	require.NoError(t, err)
+	pairPath, err := pt.PairPath("ark:/a5388")
+	require.NoError(t, err)
+
+	file := filepath.Join(pairPath, "a5388.txt")
+	modTime := time.Date(2024, time.March, 5, 14, 30, 0, 0, time.Local)
+	require.NoError(t, os.WriteFile(file, []byte("content"), 0644))
+	require.NoError(t, os.Chtimes(file, modTime, modTime))
+	info, err := os.Stat(file)
+	require.NoError(t, err)
+
+	for _, flag := range []string{"-r", "-U"} {
+		var buf bytes.Buffer
+		require.NoError(t, Run([]string{root + tempDir, "-l", flag, "ark:/a5388"}, &buf))
+		assert.Contains(t, buf.String(), fmt.Sprintf("%s            7 2024-03-05 14:30 a5388.txt\n", info.Mode()))
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, Run([]string{root + tempDir, "-l", "-j", "ark:/a5388"}, &buf))
+	assert.Contains(t, buf.String(), `"size": 7,`)
+	assert.Contains(t, buf.String(), fmt.Sprintf(`"mode": "%s"`, info.Mode()))
+
+	modTimeJSON, err := json.Marshal(modTime)
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), fmt.Sprintf(`"modTime": %s,`, modTimeJSON))
+}
+
+// TestHumanReadable tests that -H shows sizes in binary units and ends each listing with a summary
+func TestHumanReadable(t *testing.T) {
+	fs := afero.NewOsFs()
+	tempDir := pttest.CreateTempDir(t, fs)
+	pttest.StandardPairtree().Build(t, fs, tempDir)
+
+	pt, err := pairtree.Open(tempDir)
+	require.NoError(t, err)
+	pairPath, err := pt.PairPath("ark:/b5488")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(pairPath, "outerb5488.txt"), make([]byte, 2048), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(pairPath, "folder", "innerb5488.txt"), make([]byte, 1024), 0644))
+
+	for _, flags := range [][]string{{"-r"}, {"-r", "-U"}} {
+		var buf bytes.Buffer
+		require.NoError(t, Run(append([]string{root + tempDir, "-l", "-H", "ark:/b5488"}, flags...), &buf))
+		assert.Regexp(t, ` 2\.0 KiB \S+ \S+ outerb5488\.txt\n`, buf.String())
+		assert.True(t, strings.HasSuffix(buf.String(), "Total: 2 files, 1 directories, 3.0 KiB\n"), flags)
+	}
+
+	// Only the entries that are listed are in the summary
+	var buf bytes.Buffer
+	require.NoError(t, Run([]string{root + tempDir, "-H", "ark:/b5488"}, &buf))
+	assert.True(t, strings.HasSuffix(buf.String(), "  outerb5488.txt\nTotal: 1 files, 1 directories, 2.0 KiB\n"))
+
+	err = Run([]string{root + tempDir, "-H", "-j", "ark:/b5488"}, &buf)
+	assert.ErrorIs(t, err, error_msgs.Err17)
+}
+
+// TestDepth tests that a recursive listing goes down only as many levels as --depth
+func TestDepth(t *testing.T) {
+	fs := afero.NewOsFs()
+	tempDir := pttest.CreateTempDir(t, fs)
+	pttest.StandardPairtree().Build(t, fs, tempDir)
+
+	var buf bytes.Buffer
+	require.NoError(t, Run([]string{root + tempDir, "-r", "-a", "--depth", "2", "ark:/b5488"}, &buf))
+	assert.Contains(t, buf.String(), "  .hidden/\n")
+	assert.NotContains(t, buf.String(), "inner.txt")
+
+	buf.Reset()
+	require.NoError(t, Run([]string{root + tempDir, "-r", "-a", "-U", "--depth", "2", "ark:/b5488"}, &buf))
+	assert.Contains(t, buf.String(), "folder/.hidden/\n")
+	assert.NotContains(t, buf.String(), "folder/.hidden/inner.txt")
+
+	err := Run([]string{root + tempDir, "--depth", "2", "ark:/b5488"}, &buf)
+	assert.ErrorIs(t, err, error_msgs.Err17)
+
+	err = Run([]string{root + tempDir, "-r", "--depth", "-1", "ark:/b5488"}, &buf)
+	assert.ErrorIs(t, err, error_msgs.Err17)
+}
+
+// TestSort tests that the entries of each directory are listed in the order of --sort and --reverse
+func TestSort(t *testing.T) {
+	fs := afero.NewOsFs()
+	tempDir := pttest.CreateTempDir(t, fs)
+	pttest.StandardPairtree().Build(t, fs, tempDir)
+
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{args: nil, expected: "  folder/\n  outerb5488.txt\n"},
+		{args: []string{"--reverse"}, expected: "  outerb5488.txt\n  folder/\n"},
+		{args: []string{"--sort", "name", "--reverse"}, expected: "  outerb5488.txt\n  folder/\n"},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		require.NoError(t, Run(append([]string{root + tempDir, "ark:/b5488"}, test.args...), &buf))
+		assert.Contains(t, buf.String(), test.expected, test.args)
+	}
+
+	var buf bytes.Buffer
+	err := Run([]string{root + tempDir, "--sort", "color", "ark:/b5488"}, &buf)
+	assert.ErrorIs(t, err, error_msgs.Err17)
+
+	err = Run([]string{root + tempDir, "-U", "--reverse", "ark:/b5488"}, &buf)
+	assert.ErrorIs(t, err, error_msgs.Err17)
+}
+
+// TestMultipleIDs tests that each object is listed after a line with its ID, and that one that does not
+// exist does not stop the others
+func TestMultipleIDs(t *testing.T) {
+	fs := afero.NewOsFs()
+	tempDir := pttest.CreateTempDir(t, fs)
+	pttest.StandardPairtree().Build(t, fs, tempDir)
+
+	var buf bytes.Buffer
+	err := Run([]string{root + tempDir, "-U", "ark:/a5388", "ark:/notAnObject", "ark:/b5488"}, &buf)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	var ptErr *error_msgs.PtError
+	require.ErrorAs(t, err, &ptErr)
+	assert.Equal(t, "ark:/notAnObject", ptErr.ID)
+
+	output := buf.String()
+	assert.True(t, strings.HasPrefix(output, "==> ark:/a5388 <==\na5388.txt\n\n==> ark:/notAnObject <==\n\n==> ark:/b5488 <==\n"))
+	assert.Contains(t, output, "\nfolder/\n")
+	assert.Contains(t, output, "\nouterb5488.txt\n")
+}
+
+// TestIDsFrom tests that the IDs read from standard input are listed after those of the arguments
+func TestIDsFrom(t *testing.T) {
+	fs := afero.NewOsFs()
+	tempDir := pttest.CreateTempDir(t, fs)
+	pttest.StandardPairtree().Build(t, fs, tempDir)
+
+	var buf bytes.Buffer
+	args := []string{root + tempDir, "-U", "--ids-from", "-", "ark:/a5388"}
+	err := utils.RunSubcommandWithInput(NewCommand(&buf), args, strings.NewReader("# objects\nark:/b5488\n"), &buf)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(buf.String(), "==> ark:/a5388 <==\na5388.txt\n\n==> ark:/b5488 <==\n"))
+
+	// A file that can not be read is an error before anything is listed
+	buf.Reset()
+	err = Run([]string{root + tempDir, "--ids-from", "missing.txt"}, &buf)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	assert.NotContains(t, buf.String(), "==>")
+}
+
+// TestPattern tests that the objects whose IDs match a glob pattern are each listed after their ID
+func TestPattern(t *testing.T) {
+	fs := afero.NewOsFs()
+	tempDir := pttest.CreateTempDir(t, fs)
+	pttest.StandardPairtree().Build(t, fs, tempDir)
+
+	var buf bytes.Buffer
+	require.NoError(t, Run([]string{root + tempDir, "ark:/a53*"}, &buf))
+	assert.Equal(t, "==> ark:/a5388 <==\n", strings.SplitAfter(buf.String(), "\n")[0])
+
+	// A pattern that matches nothing does not stop the other objects
+	buf.Reset()
+	err := Run([]string{root + tempDir, "-U", "ark:/c5*", "ark:/b5488"}, &buf)
+	assert.ErrorIs(t, err, error_msgs.Err45)
+	assert.Equal(t, utils.ExitNotFound, utils.ExitCode(err))
+	assert.Contains(t, buf.String(), "==> ark:/b5488 <==\n")
+
+	buf.Reset()
+	err = Run([]string{root + tempDir, "ark:/[a5"}, &buf)
+	assert.ErrorIs(t, err, error_msgs.Err44)
+}
+
+// TestJobs tests that a recursive listing read with more than one job is the same as one read serially
+func TestJobs(t *testing.T) {
+	fs := afero.NewOsFs()
+	tempDir := pttest.CreateTempDir(t, fs)
+	pttest.StandardPairtree().Build(t, fs, tempDir)
+
+	for _, flags := range [][]string{{"-r", "-a"}, {"-r", "-j"}} {
+		var serial, parallel bytes.Buffer
+		require.NoError(t, Run(append([]string{root + tempDir, "ark:/b5488"}, flags...), &serial))
+		require.NoError(t, Run(append([]string{root + tempDir, "--jobs", "4", "ark:/b5488"}, flags...), &parallel))
+		assert.Equal(t, serial.String(), parallel.String())
+	}
+
+	var buf bytes.Buffer
+	err := Run([]string{root + tempDir, "--jobs", "0", "ark:/b5488"}, &buf)
+	assert.ErrorIs(t, err, error_msgs.Err17)
+}
+
+// TestConcurrentRuns tests that runs with different flags at the same time do not share their state
+func TestConcurrentRuns(t *testing.T) {
+	fs := afero.NewOsFs()
+	tempDir := pttest.CreateTempDir(t, fs)
+	pttest.StandardPairtree().Build(t, fs, tempDir)
+
+	var wg sync.WaitGroup
+	outputs := make([]bytes.Buffer, 8)
+	errs := make([]error, len(outputs))
+
+	for i := range outputs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			args := []string{root + tempDir, "ark:/b5488"}
+			if i%2 == 1 {
+				args = append(args, "-r")
+			}
+			errs[i] = Run(args, &outputs[i])
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range outputs {
+		require.NoError(t, errs[i])
+		assert.Equal(t, i%2 == 1, strings.Contains(outputs[i].String(), "innerb5488.txt"))
+	}
+}
+
+// TestGolden tests the output of pt ls against the golden files in testdata, go test -update rewrites
+// them when a change to the output is intended
+func TestGolden(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		expectErr  bool
+		jsonErrors bool
+	}{
+		{name: "plain", args: []string{"ark:/b5488"}},
+		{name: "recursive", args: []string{"-r", "ark:/b5488"}},
+		{name: "all_recursive", args: []string{"-r", "-a", "ark:/b5488"}},
+		{name: "dirs_only", args: []string{"-d", "ark:/b5488"}},
+		{name: "json", args: []string{"-j", "ark:/b5488"}},
+		{name: "json_recursive", args: []string{"-j", "-r", "ark:/b5488"}},
+		{name: "error_not_found", args: []string{"ark:/notAnObject"}, expectErr: true},
+		{name: "error_not_found_json", args: []string{"ark:/notAnObject"}, expectErr: true, jsonErrors: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+
+			fs := afero.NewOsFs()
+			tempDir := pttest.CreateTempDir(t, fs)
+			pttest.StandardPairtree().Build(t, fs, tempDir)
+
+			args := []string{root + tempDir, "--no-color"}
+			if test.jsonErrors {
+				args = append(args, "--errors="+utils.ErrorsJSON)
+			}
+
+			err := Run(append(args, test.args...), &buf)
+			if test.expectErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			// pt writes errors as JSON in main, so the test does the same
+			if test.jsonErrors {
+				require.NoError(t, utils.WriteJSONError(&buf, err))
+			}
+
+			pttest.Golden(t, "ls_"+test.name, pttest.ScrubDir(buf.Bytes(), tempDir, "$PAIRTREE_ROOT"))
+		})
+	}
+}
+
+// TestS3 tests if a pairtree in S3 is listed like the same pairtree on the local file system
+func TestS3(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		expectErr error
+	}{
+		{name: "object", args: []string{"ark:/b5488"}},
+		{name: "recursive", args: []string{"-r", "ark:/b5488"}},
+		{name: "json", args: []string{"-j", "-r", "ark:/b5488"}},
+		{name: "notFound", args: []string{"ark:/notAnObject"}, expectErr: os.ErrNotExist},
+	}
+
+	tempDir := pttest.StandardPairtree().BuildTemp(t, afero.NewOsFs())
+	fake := pttest.NewFakeS3()
+	fake.Upload(t, tempDir, "s3://bucket/pt")
+
+	newClient := pairtree.NewS3Client
+	pairtree.NewS3Client = func(context.Context) (pairtree.S3Client, error) { return fake, nil }
+	t.Cleanup(func() { pairtree.NewS3Client = newClient })
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var local, s3 bytes.Buffer
+
+			err := Run(append([]string{root + tempDir}, test.args...), &local)
+			assert.ErrorIs(t, err, test.expectErr)
+
+			err = Run(append([]string{root + "s3://bucket/pt"}, test.args...), &s3)
+			assert.ErrorIs(t, err, test.expectErr)
+
+			// The errors of the local file system and of S3 are worded differently
+			if test.expectErr == nil {
+				assert.Equal(t, local.String(), strings.ReplaceAll(s3.String(), "s3://bucket/pt", tempDir))
+			}
+		})
+	}
+}
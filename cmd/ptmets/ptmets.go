@@ -0,0 +1,145 @@
+package ptmets
+
+/* ptmets writes a METS document describing the files of a Pairtree object, with the size, SHA-256
+checksum, and MIME type of each file in a fileSec and the directories of the object in a structMap,
+so the object can be fed to ingest pipelines that require METS. Hidden files are only described
+with -a. */
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/UCLALibrary/pt-tools/pkg/checksum"
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/mets"
+	"github.com/UCLALibrary/pt-tools/pkg/pairtree"
+	"github.com/UCLALibrary/pt-tools/utils"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	// Logger is the logger each run of pt mets starts from, tests replace it to capture the logs
+	Logger *zap.Logger = utils.ConsoleLogger()
+)
+
+// command holds the flags and arguments of one run of pt mets so that runs can happen concurrently
+type command struct {
+	showAll  bool
+	ptRoot   string
+	id       string
+	hashOpts checksum.Options
+	logger   *zap.Logger
+	out      *utils.Output
+}
+
+func (c *command) initFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(&c.showAll, "a", "a", false, "describe hidden files and directories")
+	cmd.Flags().IntVar(&c.hashOpts.IOLimit, "io-limit", 0, "Reads from files that happen at once while hashing them (defaults to one per CPU)")
+}
+
+// NewCommand creates the mets subcommand of pt that writes its output to the writer
+func NewCommand(writer io.Writer) *cobra.Command {
+	c := &command{logger: Logger, out: utils.NewOutput(io.Discard, &utils.Styler{}, false)}
+
+	var cmd = &cobra.Command{
+		Use:               "mets [ID]",
+		Short:             "pt mets writes a METS document describing the files of a Pairtree object",
+		ValidArgsFunction: utils.CompleteIDs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return utils.ConfigureLogger(cmd, &c.logger)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+
+			c.out = utils.OutputFromFlags(cmd, writer)
+
+			if c.ptRoot, err = utils.GetPtRoot(cmd, writer); err != nil {
+				return err
+			}
+
+			if len(args) < 1 {
+				c.out.Error("Please provide an ID for the pairtree")
+				c.logger.Error("Error getting ID", zap.Error(error_msgs.Err6))
+
+				return error_msgs.Err6
+			} else if len(args) > 1 {
+				c.out.Error("Too many arguments were provided to %s", "pt mets")
+				c.logger.Error("Error parsing pt mets", zap.Error(error_msgs.Err8))
+
+				return error_msgs.Err8
+			}
+			c.id = args[0]
+
+			if c.hashOpts.IOLimit < 0 {
+				err := fmt.Errorf("%w: --io-limit must not be negative", error_msgs.Err17)
+				c.logger.Error("Error parsing pt mets", zap.Error(err))
+
+				return err
+			}
+
+			utils.SilenceUsage(cmd)
+
+			return c.describe(cmd.Context(), writer)
+		},
+	}
+
+	c.initFlags(cmd)
+
+	return cmd
+}
+
+// Run executes pt mets with the given arguments
+func Run(args []string, writer io.Writer) error {
+	if err := utils.RunSubcommand(NewCommand(writer), args, writer); err != nil {
+		Logger.Error("Error running pt mets", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// describe writes the METS document of the object to the writer
+func (c *command) describe(ctx context.Context, writer io.Writer) error {
+	// check if the pairtree version file exists and is populated
+	if err := pairtree.CheckPTVer(c.ptRoot); err != nil {
+		c.logger.Error("Error with pairtree veresion file", zap.Error(err))
+		return err
+	}
+
+	// Get the prefix from pairtree_prefix file
+	prefix, err := pairtree.GetPrefix(c.ptRoot)
+	if err != nil {
+		c.logger.Error("Error retrieving prefix from pairtree_prefix file", zap.Error(err))
+		return err
+	}
+
+	if prefix == "" {
+		prefix = pairtree.PtPrefix
+	}
+
+	pairPath, err := pairtree.CreatePP(c.id, c.ptRoot, prefix)
+	if err != nil {
+		c.logger.Error("Error creating pairpath", zap.Error(err))
+		return &error_msgs.PtError{ID: c.id, Err: err}
+	}
+
+	doc, err := mets.Build(ctx, c.id, pairPath, "pt "+utils.Version, c.showAll, c.hashOpts)
+	if err != nil {
+		c.logger.Error("Error describing the object", zap.Error(err))
+		return &error_msgs.PtError{ID: c.id, Path: pairPath, Err: err}
+	}
+
+	xmlData, err := doc.XML()
+	if err != nil {
+		c.logger.Error("Error converting the object description to METS XML", zap.Error(err))
+		return err
+	}
+
+	fmt.Fprintln(writer, string(xmlData))
+	c.logger.Info("Described the object", zap.String("id", c.id),
+		zap.Int("files", len(doc.FileSec.FileGrp.Files)))
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package ptmets
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pttest"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	root = "--pairtree="
+)
+
+// TestMain runs the tests with the logger of the test sink, it is set once so that the tests can run in parallel
+func TestMain(m *testing.M) {
+	logger, cleanup := pttest.SetupLogger()
+	Logger = logger
+
+	code := m.Run()
+	cleanup()
+	os.Exit(code)
+}
+
+// TestMets tests if the files of the object are described in the METS document
+func TestMets(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		expected  []string
+		missing   []string
+		expectErr error
+	}{
+		{name: "object", args: []string{"ark:/b5488"},
+			expected: []string{`<mets xmlns="http://www.loc.gov/METS/"`, `OBJID="ark:/b5488"`,
+				`xlink:href="outerb5488.txt"`, `xlink:href="folder/innerb5488.txt"`, `LABEL="folder"`},
+			missing: []string{".hiddenFile.txt"}},
+		{name: "hidden", args: []string{"-a", "ark:/b5488"},
+			expected: []string{`xlink:href="folder/.hiddenFile.txt"`, `xlink:href="folder/.hidden/inner.txt"`}},
+		{name: "not an object", args: []string{"ark:/notAnObject"}, expectErr: os.ErrNotExist},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ptRoot := pttest.StandardPairtree().BuildTemp(t, afero.NewOsFs())
+
+			var buf bytes.Buffer
+			err := Run(append([]string{root + ptRoot}, test.args...), &buf)
+			if test.expectErr != nil {
+				assert.ErrorIs(t, err, test.expectErr)
+				return
+			}
+			require.NoError(t, err)
+
+			for _, expected := range test.expected {
+				assert.Contains(t, buf.String(), expected)
+			}
+			for _, missing := range test.missing {
+				assert.NotContains(t, buf.String(), missing)
+			}
+		})
+	}
+}
+
+// TestCLIError tests if an error is thrown when the arguments are not valid
+func TestCLIError(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		expectErr error
+	}{
+		{name: "No ID provided", args: []string{root + "root"}, expectErr: error_msgs.Err6},
+		{name: "No pairtree root provided", args: []string{"ID"}, expectErr: error_msgs.Err7},
+		{name: "Too many arguments passed in", args: []string{root + "root", "ark:/a5388", "extra"}, expectErr: error_msgs.Err8},
+		{name: "Negative I/O limit", args: []string{root + "root", "ark:/a5388", "--io-limit=-1"}, expectErr: error_msgs.Err17},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+
+			err := Run(test.args, &buf)
+			assert.ErrorIs(t, err, test.expectErr)
+		})
+	}
+}
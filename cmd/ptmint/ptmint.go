@@ -0,0 +1,221 @@
+package ptmint
+
+/* ptmint requests a new identifier from a NOID or ARK minter service and immediately creates the
+Pairtree object for it, either empty or with the contents of a directory, so that an identifier is
+never minted without somewhere to store its object. The minted ID is written to the output so that
+scripts can use it. */
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/minter"
+	"github.com/UCLALibrary/pt-tools/pkg/pairtree"
+	"github.com/UCLALibrary/pt-tools/pkg/premis"
+	"github.com/UCLALibrary/pt-tools/utils"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	// Logger is the logger each run of pt mint starts from, tests replace it to capture the logs
+	Logger *zap.Logger = utils.ConsoleLogger()
+)
+
+// Minted is the identifier that was minted and the path of the object created for it
+type Minted struct {
+	ID   string `json:"id"`
+	Path string `json:"path"`
+}
+
+// command holds the flags and arguments of one run of pt mint so that runs can happen concurrently
+type command struct {
+	minterURL string
+	ptRoot    string
+	src       string
+	logger    *zap.Logger
+	out       *utils.Output
+}
+
+func (c *command) initFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&c.minterURL, "minter", "", "URL of the minter service to request an identifier from (defaults to ENV PT_MINTER)")
+}
+
+// NewCommand creates the mint subcommand of pt that writes its output to the writer
+func NewCommand(writer io.Writer) *cobra.Command {
+	c := &command{logger: Logger, out: utils.NewOutput(io.Discard, &utils.Styler{}, false)}
+
+	var cmd = &cobra.Command{
+		Use:   "mint [/path/to/directory]",
+		Short: "pt mint mints a new identifier and creates its Pairtree object",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return utils.ConfigureLogger(cmd, &c.logger)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+
+			c.out = utils.OutputFromFlags(cmd, writer)
+
+			if c.ptRoot, err = utils.GetPtRoot(cmd, writer); err != nil {
+				return err
+			}
+
+			if len(args) > 1 {
+				c.out.Error("Too many arguments were provided to %s", "pt mint")
+				c.logger.Error("Error parsing pt mint", zap.Error(error_msgs.Err8))
+
+				return error_msgs.Err8
+			} else if len(args) == 1 {
+				c.src = args[0]
+			}
+
+			if c.minterURL == "" {
+				c.minterURL = os.Getenv("PT_MINTER")
+			}
+			if c.minterURL == "" {
+				c.logger.Error("Error parsing pt mint", zap.Error(error_msgs.Err31))
+				return error_msgs.Err31
+			}
+
+			jsonFlag, _ := cmd.Flags().GetBool(utils.JSONFlag)
+
+			utils.SilenceUsage(cmd)
+
+			return c.mint(cmd.Context(), writer, jsonFlag)
+		},
+	}
+
+	c.initFlags(cmd)
+
+	return cmd
+}
+
+// Run executes pt mint with the given arguments
+func Run(args []string, writer io.Writer) error {
+	if err := utils.RunSubcommand(NewCommand(writer), args, writer); err != nil {
+		Logger.Error("Error running pt mint", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// mint mints an identifier, creates its object, and writes the identifier to the writer
+func (c *command) mint(ctx context.Context, writer io.Writer, outputJSON bool) error {
+	// check if the pairtree version file exists and is populated
+	if err := pairtree.CheckPTVer(c.ptRoot); err != nil {
+		c.logger.Error("Error with pairtree veresion file", zap.Error(err))
+		return err
+	}
+
+	// Get the prefix from pairtree_prefix file
+	prefix, err := pairtree.GetPrefix(c.ptRoot)
+	if err != nil {
+		c.logger.Error("Error retrieving prefix from pairtree_prefix file", zap.Error(err))
+		return err
+	}
+
+	if prefix == "" {
+		prefix = pairtree.PtPrefix
+	}
+
+	// The directory is checked before minting so an identifier is not used up on a typo
+	if c.src != "" {
+		info, err := os.Stat(c.src)
+		if err == nil && !info.IsDir() {
+			err = &fs.PathError{Op: "mint", Path: c.src, Err: syscall.ENOTDIR}
+		}
+		if err != nil {
+			c.logger.Error("Error reading the directory to ingest", zap.Error(err))
+			return err
+		}
+	}
+
+	minted, err := minter.New(c.minterURL).Mint(ctx)
+	if err != nil {
+		c.logger.Error("Error minting an identifier", zap.String("minter", redact(c.minterURL)), zap.Error(err))
+		return err
+	}
+
+	// A NOID minter returns the identifier without the prefix of the pairtree
+	id := minted
+	if !strings.HasPrefix(id, prefix) {
+		id = prefix + id
+	}
+	c.logger.Info("Minted identifier", zap.String("id", id))
+
+	objPath, err := c.create(ctx, id, prefix)
+	if err != nil {
+		return &error_msgs.PtError{ID: id, Path: objPath, Err: err}
+	}
+
+	if outputJSON {
+		jsonData, err := json.MarshalIndent(Minted{ID: id, Path: objPath}, "", "  ")
+		if err != nil {
+			c.logger.Error("Error converting the minted identifier to JSON", zap.Error(err))
+			return err
+		}
+		fmt.Fprintln(writer, string(jsonData))
+		return nil
+	}
+
+	// The identifier is written even with --quiet since it is what the command produces
+	fmt.Fprintln(writer, id)
+	return nil
+}
+
+// create creates the object for the minted identifier, with the contents of the source directory
+// if one was given, and records the creation in the object's event history
+func (c *command) create(ctx context.Context, id, prefix string) (objPath string, err error) {
+	if objPath, err = pairtree.CreatePP(id, c.ptRoot, prefix); err != nil {
+		c.logger.Error("Error creating pairpath", zap.Error(err))
+		return "", err
+	}
+
+	// A minter should never return an identifier twice, but an existing object is never replaced
+	if _, statErr := os.Stat(objPath); statErr == nil {
+		c.logger.Error("Error creating the object", zap.String("id", id), zap.Error(fs.ErrExist))
+		return objPath, &fs.PathError{Op: "mint", Path: objPath, Err: fs.ErrExist}
+	}
+
+	eventType, detail := premis.Creation, "minted from "+redact(c.minterURL)
+	if c.src != "" {
+		eventType, detail = premis.Ingestion, detail+" and copied from "+c.src
+	}
+	defer func() {
+		utils.RecordEvent(c.ptRoot, prefix, premis.NewEvent(eventType, id, detail, err), c.out, c.logger)
+	}()
+
+	if c.src == "" {
+		if err = pairtree.CreateDirNotExist(objPath); err != nil {
+			c.logger.Error("Error creating the object", zap.Error(err))
+		}
+		return objPath, err
+	}
+
+	// The object does not exist yet so the directory is copied as the object rather than into it
+	if _, err = pairtree.CopyFileOrFolder(ctx, c.src, objPath, false, pairtree.CopyOptions{}); err != nil {
+		c.logger.Error("Error copying the directory into the object", zap.Error(err))
+	}
+
+	return objPath, err
+}
+
+// redact removes any credentials from the minter URL so they are not kept in the event history
+func redact(minterURL string) string {
+	u, err := url.Parse(minterURL)
+	if err != nil {
+		return minterURL
+	}
+
+	u.User = nil
+	return u.String()
+}
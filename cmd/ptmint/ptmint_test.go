@@ -0,0 +1,142 @@
+package ptmint
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/premis"
+	"github.com/UCLALibrary/pt-tools/pkg/pttest"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	root = "--pairtree="
+)
+
+// TestMain runs the tests with the logger of the test sink, it is set once so that the tests can run in parallel
+func TestMain(m *testing.M) {
+	logger, cleanup := pttest.SetupLogger()
+	Logger = logger
+
+	code := m.Run()
+	cleanup()
+	os.Exit(code)
+}
+
+// newMinter starts a NOID-like minter that mints fk4 identifiers in sequence
+func newMinter(t *testing.T) *httptest.Server {
+	var next atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "id: fk4%04d\n", next.Add(1))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// TestMint tests if an object is created for the minted identifier, empty or with a directory's contents
+func TestMint(t *testing.T) {
+	fs := afero.NewOsFs()
+	minter := newMinter(t)
+	ptRoot := pttest.NewPairtreeBuilder().BuildTemp(t, fs)
+	srcDir := pttest.CreateTempDir(t, fs)
+	pttest.CreateFileInDir(t, srcDir, "file.txt")
+
+	var buf bytes.Buffer
+	err := Run([]string{root + ptRoot, "--minter=" + minter.URL}, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, "ark:/fk40001\n", buf.String())
+
+	emptyPath := pttest.NewPairtreeBuilder().ObjectPath(ptRoot, "ark:/fk40001")
+	exists, err := afero.DirExists(fs, emptyPath)
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	buf.Reset()
+	err = Run([]string{root + ptRoot, "--minter=" + minter.URL, "--json", srcDir}, &buf)
+	require.NoError(t, err)
+
+	var minted Minted
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &minted))
+	assert.Equal(t, "ark:/fk40002", minted.ID)
+
+	exists, err = afero.Exists(fs, filepath.Join(minted.Path, "file.txt"))
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	events, err := premis.Events(ptRoot, "ark:/", "ark:/fk40002")
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, premis.Ingestion, events[0].Type)
+}
+
+// TestMintExisting tests if an object that already exists is not replaced when it is minted again
+func TestMintExisting(t *testing.T) {
+	minter := newMinter(t)
+	ptRoot := pttest.NewPairtreeBuilder().WithObject("ark:/fk40001", "keep.txt").BuildTemp(t, afero.NewOsFs())
+
+	var buf bytes.Buffer
+	err := Run([]string{root + ptRoot, "--minter=" + minter.URL}, &buf)
+	assert.ErrorIs(t, err, fs.ErrExist)
+
+	var ptErr *error_msgs.PtError
+	require.ErrorAs(t, err, &ptErr)
+	assert.Equal(t, "ark:/fk40001", ptErr.ID)
+}
+
+// TestCLIError tests if an error is thrown when the arguments are not valid
+func TestCLIError(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		expectErr error
+	}{
+		{name: "No minter", args: []string{root + "root"}, expectErr: error_msgs.Err31},
+		{name: "No pairtree root provided", args: []string{"--minter=http://localhost"}, expectErr: error_msgs.Err7},
+		{name: "Too many arguments passed in", args: []string{root + "root", "--minter=http://localhost", "a", "b"}, expectErr: error_msgs.Err8},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+
+			err := Run(test.args, &buf)
+			assert.ErrorIs(t, err, test.expectErr)
+		})
+	}
+}
+
+// TestNotADirectory tests if nothing is minted when the directory to ingest is not a directory
+func TestNotADirectory(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		fmt.Fprintln(w, "id: fk40001")
+	}))
+	defer server.Close()
+
+	fs := afero.NewOsFs()
+	ptRoot := pttest.NewPairtreeBuilder().BuildTemp(t, fs)
+	file := pttest.CreateFileInDir(t, pttest.CreateTempDir(t, fs), "file.txt")
+
+	var buf bytes.Buffer
+	err := Run([]string{root + ptRoot, "--minter=" + server.URL, file}, &buf)
+	require.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "not a directory"))
+	assert.Zero(t, requests.Load())
+}
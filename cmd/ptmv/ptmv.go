@@ -3,55 +3,103 @@ package ptmv
 /* ptmv is a tool that can move files in and out of the Pairtree structure */
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"syscall"
 
+	"github.com/UCLALibrary/pt-tools/pkg/ark"
+	"github.com/UCLALibrary/pt-tools/pkg/checksum"
 	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
 	"github.com/UCLALibrary/pt-tools/pkg/pairtree"
+	"github.com/UCLALibrary/pt-tools/pkg/premis"
 	"github.com/UCLALibrary/pt-tools/utils"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 )
 
 var (
-	tar     bool
-	ptRoot  string
-	logFile string      = "logs.log"
-	Logger  *zap.Logger = utils.Logger(logFile)
-	src     string      = ""
-	dest    string      = ""
+	// Logger is the logger each run of pt mv starts from, tests replace it to capture the logs
+	Logger *zap.Logger = utils.ConsoleLogger()
+
+	// rename renames the source of a move, tests replace it to move across devices
+	rename = os.Rename
 )
 
-func initFlags(cmd *cobra.Command) {
-	cmd.Flags().StringVarP(&ptRoot, "pairtree", "p", "", "Set pairtree root directory")
-	cmd.Flags().BoolVarP(&tar, "a", "a", false, "Produce a tar/gzipped output or unpack a tar/gzipped")
+// command holds the flags and arguments of one run of pt mv so that runs can happen concurrently
+type command struct {
+	dryRun      bool
+	tar         bool
+	format      string
+	compress    string
+	copyOpts    pairtree.CopyOptions
+	archiveOpts pairtree.ArchiveOptions
+	warmCache   bool
+	ptRoot      string
+	srcRoot     string
+	destRoot    string
+	src         string
+	dest        string
+	resolver    *ark.Resolver
+	logger      *zap.Logger
+	out         *utils.Output
 }
 
-func Run(args []string, writer io.Writer) error {
-	var err error
+func (c *command) initFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&c.dryRun, "dry-run", false, "Print what would be moved or deleted without changing anything")
+	cmd.Flags().BoolVarP(&c.tar, "a", "a", false, "Produce a tar/gzipped output or unpack a tar/gzipped")
+	cmd.Flags().StringVar(&c.format, "format", "", "Format of the archive of -a, one of "+strings.Join(pairtree.Formats, ", ")+" (defaults to the extension of the source, or tgz)")
+	cmd.Flags().StringVar(&c.compress, "compress", "", "Compression of a tar archive of -a, gzip or zstd")
+	cmd.Flags().IntVar(&c.copyOpts.BufferSize, "buffer-size", 0, "Bytes of the buffer each file is copied with instead of copying in the kernel")
+	cmd.Flags().BoolVar(&c.copyOpts.Direct, "direct", false, "Copy with O_DIRECT on Linux to bypass the page cache")
+	cmd.Flags().IntVar(&c.copyOpts.Jobs, "jobs", 1, "Files of a directory copied at once")
+	cmd.Flags().BoolVar(&c.copyOpts.Verify, "verify", false, "Compare the checksums of the copy with those of the source and keep the source if they differ")
+	cmd.Flags().IntVar(&c.archiveOpts.CompressWorkers, "compress-workers", 0, "Blocks of an archive compressed in parallel (defaults to the number of CPUs)")
+	cmd.Flags().BoolVar(&c.warmCache, "warm-cache", false, "Hash files as they are archived with -a and reuse the checksums to verify the archive, reading each file once")
+	cmd.Flags().StringVar(&c.srcRoot, "src-root", "", "Pairtree root to move the source object from (defaults to the pairtree root)")
+	cmd.Flags().StringVar(&c.destRoot, "dest-root", "", "Pairtree root to move the object into (defaults to the pairtree root)")
+}
 
-	var rootCmd = &cobra.Command{
-		Use:   "pt mv [PT_ROOT] [ID] [/path/to/output/]",
-		Short: "Pt mv is a tool that can move files in and out of the Pairtree structure",
+// NewCommand creates the mv subcommand of pt that writes its output to the writer
+func NewCommand(writer io.Writer) *cobra.Command {
+	c := &command{logger: Logger, out: utils.NewOutput(io.Discard, &utils.Styler{}, false)}
+
+	var cmd = &cobra.Command{
+		Use:               "mv [ID] [/path/to/output/]",
+		Short:             "Pt mv is a tool that can move files in and out of the Pairtree structure",
+		ValidArgsFunction: utils.CompleteIDs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return utils.ConfigureLogger(cmd, &c.logger)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			// If the root has not been set yet check the ENV vars
-			if ptRoot == "" {
-
-				if envVar := os.Getenv("PAIRTREE_ROOT"); envVar != "" {
-					ptRoot = envVar
-				} else {
-					fmt.Fprintln(writer, error_msgs.Err7)
-					return error_msgs.Err7
+			var err error
+
+			c.out = utils.OutputFromFlags(cmd, writer)
+			defer c.out.Close()
+			c.resolver = utils.ResolverFromFlags(cmd)
+
+			// The pairtree root is only needed for the roots that --src-root and --dest-root do not set
+			crossRoot := c.srcRoot != "" || c.destRoot != ""
+			if c.srcRoot == "" || c.destRoot == "" {
+				if c.ptRoot, err = utils.GetPtRoot(cmd, writer); err != nil {
+					return err
 				}
 			}
 
+			// An object moved to another pairtree keeps its ID unless another is given
+			if crossRoot && len(args) == 1 {
+				args = append(args, args[0])
+			}
+
 			numArgs := len(args)
 			if numArgs < 2 {
-				fmt.Fprintln(writer, "Please provide a source and destination for copied files")
-				Logger.Error("There are not enough arguments to ptmv",
+				c.out.Error("Please provide a source and destination for copied files")
+				c.logger.Error("There are not enough arguments to ptmv",
 					zap.Error(error_msgs.Err9))
 
 				return error_msgs.Err9
@@ -59,111 +107,455 @@ func Run(args []string, writer io.Writer) error {
 
 			if numArgs == 2 {
 				// Extract the src and dest
-				src = args[numArgs-2]
-				dest = args[numArgs-1]
+				c.src = args[numArgs-2]
+				c.dest = args[numArgs-1]
 			} else {
-				fmt.Fprintln(writer, "Too many arguments were provided to ptmv")
-				Logger.Error("Error parsing ptmv", zap.Error(error_msgs.Err8))
+				c.out.Error("Too many arguments were provided to %s", "ptmv")
+				c.logger.Error("Error parsing ptmv", zap.Error(error_msgs.Err8))
 
 				return error_msgs.Err8
 			}
 
-			Logger.Info("Pairtree root is", zap.String("PAIRTREE_ROOT", ptRoot))
+			if c.format != "" && !slices.Contains(pairtree.Formats, c.format) {
+				err := fmt.Errorf("%w: --format must be one of %s", error_msgs.Err17, strings.Join(pairtree.Formats, ", "))
+				c.logger.Error("Error parsing ptmv", zap.Error(err))
+
+				return err
+			}
+
+			// The compression chooses between the formats of tar archives
+			switch c.compress {
+			case "":
+			case "gzip", "zstd":
+				if c.format == pairtree.ZipFormat {
+					err := fmt.Errorf("%w: --compress can not be used with --format %s", error_msgs.Err17, pairtree.ZipFormat)
+					c.logger.Error("Error parsing ptmv", zap.Error(err))
+
+					return err
+				}
+
+				c.format = pairtree.TgzFormat
+				if c.compress == "zstd" {
+					c.format = pairtree.TzstFormat
+				}
+			default:
+				err := fmt.Errorf("%w: --compress must be gzip or zstd", error_msgs.Err17)
+				c.logger.Error("Error parsing ptmv", zap.Error(err))
+
+				return err
+			}
+
+			if c.archiveOpts.CompressWorkers < 0 {
+				err := fmt.Errorf("%w: --compress-workers must not be negative", error_msgs.Err17)
+				c.logger.Error("Error parsing ptmv", zap.Error(err))
+
+				return err
+			}
+
+			if c.copyOpts.Jobs < 1 {
+				err := fmt.Errorf("%w: --jobs must be at least 1", error_msgs.Err17)
+				c.logger.Error("Error parsing ptmv", zap.Error(err))
+
+				return err
+			}
+
+			c.logger.Info("Pairtree root is", zap.String("PAIRTREE_ROOT", c.ptRoot))
+
+			if c.warmCache {
+				c.archiveOpts.Cache = checksum.NewCache()
+			}
+
+			utils.SilenceUsage(cmd)
+
+			if crossRoot {
+				if c.srcRoot == "" {
+					c.srcRoot = c.ptRoot
+				}
+				if c.destRoot == "" {
+					c.destRoot = c.ptRoot
+				}
+
+				srcPT, err := pairtree.Open(c.srcRoot)
+				if err != nil {
+					c.logger.Error("Error opening the source pairtree", zap.Error(err))
+					return err
+				}
+
+				destPT, err := pairtree.Open(c.destRoot)
+				if err != nil {
+					c.logger.Error("Error opening the destination pairtree", zap.Error(err))
+					return err
+				}
+
+				return c.renameObject(cmd.Context(), srcPT, destPT)
+			}
+
+			pt, err := pairtree.Open(c.ptRoot)
+			if err != nil {
+				c.logger.Error("Error opening the pairtree", zap.Error(err))
+				return err
+			}
 
-			return nil
+			return c.moveObject(cmd.Context(), pt, writer)
 		},
 	}
 
-	initFlags(rootCmd)
-	rootCmd.SetOut(writer)
-	rootCmd.SetErr(writer)
-	rootCmd.SetArgs(args)
+	c.initFlags(cmd)
+	utils.AddResolveFlags(cmd)
+	utils.AddInteractiveFlag(cmd)
 
-	utils.ApplyExitOnHelp(rootCmd, 0)
+	return cmd
+}
 
-	if err = rootCmd.Execute(); err != nil {
-		Logger.Error("Error setting command line", zap.Error(err))
+// Run executes pt mv with the given arguments
+func Run(args []string, writer io.Writer) error {
+	if err := utils.RunSubcommand(NewCommand(writer), args, writer); err != nil {
+		Logger.Error("Error running pt mv", zap.Error(err))
 		return err
 	}
 
-	// check if the pairtree version file exists and is populated
-	if err := pairtree.CheckPTVer(ptRoot); err != nil {
-		Logger.Error("Error with pairtree veresion file", zap.Error(err))
-		return err
-	}
+	return nil
+}
 
-	// Get the prefix from pairtree_prefix file
-	prefix, err := pairtree.GetPrefix(ptRoot)
+// moveObject moves the source to the destination where one of them is in the pairtree
+func (c *command) moveObject(ctx context.Context, pt *pairtree.Pairtree, writer io.Writer) (err error) {
+	prefix := pt.Prefix()
 
-	if err != nil {
-		Logger.Error("Error retrieving prefix from pairtree_prefix file", zap.Error(err))
-		return err
+	// An object of the pairtree is renamed when both are IDs
+	if strings.HasPrefix(c.src, prefix) && strings.HasPrefix(c.dest, prefix) {
+		return c.renameObject(ctx, pt, pt)
 	}
 
-	if prefix == "" {
-		prefix = pairtree.PtPrefix
-	}
+	// The ID of the pairtree object is reported with any error
+	var id string
 
 	srcIsPairtree := false
 	// Determine if the src or dest is the pairtree
-	if strings.HasPrefix(src, prefix) {
-		if src, err = pairtree.CreatePP(src, ptRoot, prefix); err != nil {
-			Logger.Error("Error creating pairpath", zap.Error(err))
-			return err
+	if strings.HasPrefix(c.src, prefix) {
+		id = c.src
+		if c.src, err = pt.PairPath(c.src); err != nil {
+			c.logger.Error("Error creating pairpath", zap.Error(err))
+			return &error_msgs.PtError{ID: id, Err: err}
 		}
-		src = filepath.Join(src)
+		c.src = filepath.Join(c.src)
 		srcIsPairtree = true
-	} else if strings.HasPrefix(dest, prefix) {
-		if dest, err = pairtree.CreatePP(dest, ptRoot, prefix); err != nil {
-			Logger.Error("Error creating pairpath", zap.Error(err))
+		if err = c.confirmOverwrite(id); err != nil {
 			return err
 		}
-		if err = pairtree.CreateDirNotExist(dest); err != nil {
+		// Moving an object out of the pairtree deletes it, which is confirmed with -i
+		if c.out.Interactive() && !c.dryRun {
+			if err = c.out.Confirm("Delete the pairtree object %s once it is moved?", id); err != nil {
+				return &error_msgs.PtError{ID: id, Path: c.src, Err: err}
+			}
+		}
+	} else if strings.HasPrefix(c.dest, prefix) {
+		id = c.dest
+		if c.dest, err = pt.PairPath(c.dest); err != nil {
+			c.logger.Error("Error creating pairpath", zap.Error(err))
+			return &error_msgs.PtError{ID: id, Err: err}
+		}
+		if err = utils.CheckARK(ctx, c.resolver, id, c.out, c.logger); err != nil {
+			return &error_msgs.PtError{ID: id, Err: err}
+		}
+		if err = c.confirmOverwrite(id); err != nil {
 			return err
 		}
-		dest = filepath.Join(dest)
+		c.dest = filepath.Join(c.dest)
 	} else {
-		fmt.Fprintln(writer,
-			"Neither the source or destination contains a prefix and is not a part of the pairtree")
-		Logger.Error("Error verifying source and destination",
+		c.out.Error("Neither the source or destination contains a prefix and is not a part of the pairtree")
+		c.logger.Error("Error verifying source and destination",
 			zap.Error(error_msgs.Err10))
 		return error_msgs.Err10
 	}
 
-	fmt.Printf("This is the src: %s \n", src)
-	fmt.Printf("This is the dest: %s \n", dest)
+	objPath := c.dest
+	eventType, detail := premis.Ingestion, "moved from "+c.src
+	fixityDetail := "checksums compared with " + c.src
+	if srcIsPairtree {
+		objPath = c.src
+		eventType, detail = premis.Deletion, "moved to "+c.dest
+		fixityDetail = "checksums compared with the copy at " + c.dest
+	}
+
+	c.out.Info("This is the src: %s", c.src)
+	c.out.Info("This is the dest: %s", c.dest)
 
-	if err := os.RemoveAll(dest); err != nil {
-		return fmt.Errorf("failed to remove %s: %w", dest, err)
+	// The format of an archive that is unpacked is found from its extension unless it is given
+	if c.format == "" && !srcIsPairtree {
+		c.format = pairtree.ArchiveFormat(c.src)
 	}
 
-	if tar {
-		if srcIsPairtree {
-			if err = pairtree.TarGz(src, dest, prefix, true); err != nil {
-				Logger.Error("Error compressing pairtree object", zap.Error(err))
-				return err
-			}
-		} else {
-			if err = pairtree.UnTarGz(src, dest); err != nil {
-				Logger.Error("Error decompressing .tgz file", zap.Error(err))
-				return err
+	if c.dryRun {
+		return c.preview(srcIsPairtree, prefix, id, objPath)
+	}
+
+	// Moving into or out of the pairtree is kept in the object's event history, along with the fixity
+	// check of a copy whose checksums were compared with those of the object
+	var checked bool
+	defer func() {
+		utils.RecordEvent(c.ptRoot, prefix, premis.NewEvent(eventType, id, detail, err), c.out, c.logger)
+		if checked {
+			utils.RecordFixityCheck(c.ptRoot, prefix, id, fixityDetail, err, c.out, c.logger)
+		}
+	}()
+
+	// The source is renamed, or when it is on another device written, to a temporary sibling of the
+	// destination, and only replaces the destination once it is verified. The source is deleted last, so
+	// a move that fails or is interrupted before then leaves the source and the destination as they were.
+	// A destination that ends in a separator, and the directory an archive is written to with -a, get the
+	// source inside them, so only what is moved is replaced and not the rest of the directory
+	dest := filepath.Clean(c.dest)
+	if c.tar && srcIsPairtree {
+		dest = pairtree.ArchiveDestination(c.src, dest, prefix, c.format, true)
+	} else if strings.HasSuffix(c.dest, string(os.PathSeparator)) {
+		dest = filepath.Join(dest, filepath.Base(c.src))
+	}
+	if err = os.MkdirAll(filepath.Dir(dest), os.ModePerm); err != nil {
+		c.logger.Error("Error creating the parent of the destination", zap.Error(err))
+		return &error_msgs.PtError{ID: id, Path: objPath, Err: err}
+	}
+
+	staging, err := os.MkdirTemp(filepath.Dir(dest), "."+filepath.Base(dest)+".pt-mv-")
+	if err != nil {
+		c.logger.Error("Error creating the staging directory", zap.Error(err))
+		return &error_msgs.PtError{ID: id, Path: objPath, Err: err}
+	}
+
+	// The staging directory is kept if the renamed source can not be put back, so it is not lost
+	var renamed string
+	defer func() {
+		if _, statErr := os.Stat(renamed); renamed == "" || statErr != nil {
+			os.RemoveAll(staging)
+		}
+	}()
+
+	staged := filepath.Join(staging, filepath.Base(dest))
+	var verified string
+	if err = ctx.Err(); err == nil && !c.tar {
+		renamed, err = c.rename(staged)
+	}
+	if err == nil && renamed == "" {
+		checked = c.tar || c.copyOpts.Verify
+		verified, err = c.stage(ctx, srcIsPairtree, prefix, staged)
+	}
+	if err != nil {
+		// An interrupted move says that nothing was changed, since its partial copy is removed
+		if errors.Is(err, context.Canceled) {
+			err = fmt.Errorf("the move was interrupted and %s and %s were not changed: %w", c.src, c.dest, err)
+		}
+		return &error_msgs.PtError{ID: id, Path: objPath, Err: err}
+	}
+
+	if err = pairtree.Replace(staged, dest, staged+".old"); err != nil {
+		c.logger.Error("Error replacing the destination", zap.Error(err))
+		if renamed != "" {
+			if restoreErr := os.Rename(renamed, c.src); restoreErr != nil {
+				err = errors.Join(err, fmt.Errorf("the source was left at %s: %w", renamed, restoreErr))
 			}
 		}
-	} else {
+		return &error_msgs.PtError{ID: id, Path: objPath, Err: err}
+	}
 
-		finalDest, err := pairtree.CopyFileOrFolder(src, dest, true)
+	// A renamed source is already gone from where it was and had nothing copied to verify
+	if renamed != "" {
+		c.logger.Info("Source was renamed to the destination", zap.String("destination", c.dest))
+		return c.pruneSource(pt, srcIsPairtree, id)
+	}
 
-		if err != nil {
-			Logger.Error("Error copying source to destination", zap.Error(err))
-			return err
-		} else {
-			Logger.Info("Folder or file was successfully copied to",
-				zap.String("destination of File or Folder", finalDest))
+	// The paths in the staging directory are reported where they were moved to
+	if rel, relErr := filepath.Rel(staged, verified); relErr == nil && !strings.HasPrefix(rel, "..") {
+		verified = filepath.Join(dest, rel)
+	}
+	if c.tar || c.copyOpts.Verify {
+		c.out.Success("Verified the checksums of %s", verified)
+	}
+
+	if err = os.RemoveAll(c.src); err != nil {
+		c.logger.Error("Error removing the source", zap.Error(err))
+		return &error_msgs.PtError{ID: id, Path: objPath,
+			Err: fmt.Errorf("%s was moved to %s but could not be removed: %w", c.src, c.dest, err)}
+	}
+
+	return c.pruneSource(pt, srcIsPairtree, id)
+}
+
+// pruneSource removes the shorties above an object that was moved out of the pairtree that it left empty
+func (c *command) pruneSource(pt *pairtree.Pairtree, srcIsPairtree bool, id string) error {
+	if !srcIsPairtree {
+		return nil
+	}
+
+	if err := pt.PruneShorties(c.src); err != nil {
+		c.logger.Error("Error removing the empty shorties of the source", zap.Error(err))
+		return &error_msgs.PtError{ID: id, Path: c.src, Err: err}
+	}
+
+	return nil
+}
+
+// rename moves the source to the staged path when they are on the same file system, which is a single
+// rename that needs nothing to be copied or verified. It returns where the source was renamed to, or
+// nothing when they are on different devices and the source has to be copied.
+func (c *command) rename(staged string) (string, error) {
+	if err := rename(c.src, staged); errors.Is(err, syscall.EXDEV) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	return staged, nil
+}
+
+// stage writes the source to the staged path, as an archive or its extraction with -a, and verifies it
+// before the source is deleted. An archive is always verified by its checksums, and a copy by the sizes
+// of its files, or by their checksums with --verify. It returns the path that was verified.
+func (c *command) stage(ctx context.Context, srcIsPairtree bool, prefix, staged string) (string, error) {
+	if c.tar && srcIsPairtree {
+		// The archive is staged with the name it has in the destination directory
+		if err := pairtree.Archive(ctx, c.src, filepath.Dir(staged), prefix, c.format, true, c.archiveOpts); err != nil {
+			c.logger.Error("Error compressing pairtree object", zap.Error(err))
+			return "", err
+		}
+
+		return staged, c.verifyArchive(ctx, staged, c.src)
+	} else if c.tar {
+		if err := pairtree.UnArchive(ctx, c.src, staged, c.format); err != nil {
+			c.logger.Error("Error decompressing the archive", zap.Error(err))
+			return "", err
+		}
+
+		return c.src, c.verifyArchive(ctx, c.src, staged)
+	}
+
+	// A copy that does not match the source with --verify is removed by the copy
+	finalDest, err := pairtree.CopyFileOrFolder(ctx, c.src, staged, true, c.copyOpts)
+	if err != nil {
+		c.logger.Error("Error copying source to destination", zap.Error(err))
+		return "", err
+	}
+
+	if !c.copyOpts.Verify {
+		if err := pairtree.VerifySize(c.src, finalDest); err != nil {
+			c.logger.Error("Error verifying the copy", zap.Error(err))
+			return "", err
 		}
 	}
 
-	if err := os.RemoveAll(src); err != nil {
-		return fmt.Errorf("failed to remove %s: %w", src, err)
+	c.logger.Info("Folder or file was successfully copied to", zap.String("destination of File or Folder", finalDest))
+
+	return finalDest, nil
+}
+
+// verifyArchive compares the checksums of the archive with those of the directory it was made from or
+// extracted to, so that the source is only deleted when they match
+func (c *command) verifyArchive(ctx context.Context, archive, dir string) error {
+	if err := pairtree.VerifyArchive(ctx, archive, c.format, dir, c.archiveOpts.Cache); err != nil {
+		c.logger.Error("Error verifying the archive", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// renameObject moves the source object in the source pairtree to the pairpath of the destination ID in
+// the destination pairtree, which can be the same one, replacing the object that is there. The move is
+// kept in the event history of both IDs.
+func (c *command) renameObject(ctx context.Context, srcPT, destPT *pairtree.Pairtree) (err error) {
+	oldID, newID := c.src, c.dest
+	srcRoot, destRoot := srcPT.Root(), destPT.Root()
+
+	if c.tar {
+		err := fmt.Errorf("%w: -a can not be used to rename a pairtree object", error_msgs.Err17)
+		c.logger.Error("Error parsing ptmv", zap.Error(err))
+
+		return err
+	}
+
+	if c.src, err = srcPT.PairPath(oldID); err != nil {
+		c.logger.Error("Error creating pairpath", zap.Error(err))
+		return &error_msgs.PtError{ID: oldID, Err: err}
+	}
+	if c.dest, err = destPT.PairPath(newID); err != nil {
+		c.logger.Error("Error creating pairpath", zap.Error(err))
+		return &error_msgs.PtError{ID: newID, Err: err}
+	}
+
+	if c.src == c.dest {
+		err := fmt.Errorf("%w: an object can not be renamed to its own ID", error_msgs.Err17)
+		c.logger.Error("Error parsing ptmv", zap.Error(err))
+
+		return err
+	}
+
+	if err = utils.CheckARK(ctx, c.resolver, newID, c.out, c.logger); err != nil {
+		return &error_msgs.PtError{ID: newID, Err: err}
+	}
+	if err = c.confirmOverwrite(newID); err != nil {
+		return err
+	}
+
+	c.out.Info("This is the src: %s", c.src)
+	c.out.Info("This is the dest: %s", c.dest)
+
+	if c.dryRun {
+		return c.preview(true, srcPT.Prefix(), oldID, c.src)
+	}
+
+	movedTo, movedFrom := "moved to "+newID, "moved from "+oldID
+	if srcRoot != destRoot {
+		movedTo += " in " + destRoot
+		movedFrom += " in " + srcRoot
 	}
+	defer func() {
+		utils.RecordEvent(srcRoot, srcPT.Prefix(), premis.NewEvent(premis.Deletion, oldID, movedTo, err), c.out, c.logger)
+		utils.RecordEvent(destRoot, destPT.Prefix(), premis.NewEvent(premis.Ingestion, newID, movedFrom, err), c.out, c.logger)
+	}()
+
+	if err = srcPT.Move(ctx, oldID, destPT, newID, c.copyOpts); err != nil {
+		c.logger.Error("Error renaming the pairtree object", zap.Error(err))
+		return &error_msgs.PtError{ID: oldID, Path: c.src, Err: err}
+	}
+
+	c.logger.Info("Pairtree object was successfully renamed", zap.String("id", newID))
+
+	return nil
+}
+
+// confirmOverwrite asks before the existing destination is deleted to make room for the move. A dry run
+// does not delete it, so it is not asked.
+func (c *command) confirmOverwrite(id string) error {
+	if _, err := os.Stat(c.dest); err != nil || c.dryRun {
+		return nil
+	}
+
+	if err := c.out.Confirm("Overwrite %s?", c.dest); err != nil {
+		return &error_msgs.PtError{ID: id, Path: c.dest, Err: err}
+	}
+
+	return nil
+}
+
+// preview reports what the move would do for --dry-run without changing anything, starting with the
+// destination that is replaced. It fails like the move would when the source does not exist.
+func (c *command) preview(srcIsPairtree bool, prefix, id, objPath string) error {
+	if _, err := os.Stat(c.src); err != nil {
+		return &error_msgs.PtError{ID: id, Path: objPath, Err: err}
+	}
+
+	if _, err := os.Stat(c.dest); err == nil {
+		c.out.DryRun("Would delete %s", c.dest)
+	}
+
+	switch {
+	case c.tar && srcIsPairtree:
+		c.out.DryRun("Would archive %s to %s", c.src, pairtree.ArchiveDestination(c.src, c.dest, prefix, c.format, true))
+		c.out.DryRun("Would delete %s", c.src)
+	case c.tar:
+		c.out.DryRun("Would extract %s to %s", c.src, c.dest)
+		c.out.DryRun("Would delete %s", c.src)
+	default:
+		c.out.DryRun("Would move %s to %s", c.src, c.dest)
+	}
+
 	return nil
 }
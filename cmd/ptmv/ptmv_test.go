@@ -2,12 +2,19 @@ package ptmv
 
 import (
 	"bytes"
+	"context"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"syscall"
 	"testing"
 
 	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
-	"github.com/UCLALibrary/pt-tools/testutils"
+	"github.com/UCLALibrary/pt-tools/pkg/pairtree"
+	"github.com/UCLALibrary/pt-tools/pkg/premis"
+	"github.com/UCLALibrary/pt-tools/pkg/pttest"
+	"github.com/UCLALibrary/pt-tools/utils"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -18,6 +25,24 @@ const (
 	rootDir = "pairtree_root"
 )
 
+// TestMain runs the tests with the logger of the test sink, it is set once so that the tests can run in parallel
+func TestMain(m *testing.M) {
+	logger, cleanup := pttest.SetupLogger()
+	Logger = logger
+
+	code := m.Run()
+	cleanup()
+	os.Exit(code)
+}
+
+// crossDevice makes renaming the source of a move fail like it does across devices until the test ends
+func crossDevice(t *testing.T) {
+	rename = func(oldpath, newpath string) error {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: syscall.EXDEV}
+	}
+	t.Cleanup(func() { rename = os.Rename })
+}
+
 // Test the basic functionality of ptmv
 func TestPTMV(t *testing.T) {
 	tests := []struct {
@@ -57,34 +82,34 @@ func TestPTMV(t *testing.T) {
 		},
 	}
 
-	// Create a logger instance using the registered sink.
-	logger, cleanup := testutils.SetupLogger(logFile)
-	defer cleanup()
-	Logger = logger
-
 	fs := afero.NewOsFs()
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
 			var buf bytes.Buffer
 			var args []string
 			var finalSrc string
-			srcDir := testutils.CreateTempDir(t, fs)
-			destDir := testutils.CreateTempDir(t, fs)
+			srcDir := pttest.CreateTempDir(t, fs)
+			destDir := pttest.CreateTempDir(t, fs)
 			if test.src == "" {
 				//pairtree is the dest
-				testutils.CopyTestDirectory(t, testutils.TestPairtree, destDir)
+				pttest.StandardPairtree().Build(t, fs, destDir)
 				// create file to copy to dest
-				fileInSrc := testutils.CreateFileInDir(t, srcDir, "file.txt")
+				fileInSrc := pttest.CreateFileInDir(t, srcDir, "file.txt")
 				args = []string{root + destDir, fileInSrc, test.dest}
 				finalSrc = fileInSrc
 			} else {
 				// pairtree is the src
-				testutils.CopyTestDirectory(t, testutils.TestPairtree, srcDir)
+				pttest.StandardPairtree().Build(t, fs, srcDir)
 				args = []string{root + srcDir, test.src, destDir}
 				finalSrc = filepath.Join(srcDir, rootDir, test.pairpath)
 			}
 
+			// The destination is overwritten so the confirmation prompt is skipped
+			args = append(args, "--yes")
+
 			err := Run(args, &buf)
 			require.ErrorIs(t, err, test.expectErr)
 
@@ -93,37 +118,33 @@ func TestPTMV(t *testing.T) {
 				_, err = os.Stat(finalSrc)
 				assert.True(t, os.IsNotExist(err), "Expected path to not exist, but got: %v", err)
 			}
+
+			// The shorties of an object moved out of the pairtree are removed with it
+			if test.src != "" && test.expectErr == nil {
+				_, err = os.Stat(filepath.Join(srcDir, rootDir, "b5"))
+				assert.True(t, os.IsNotExist(err), "Expected the shorties to not exist, but got: %v", err)
+			}
 		})
 	}
 }
 
 // TestTar tests if an object in the pairtree is properly tared outside of it
 func TestTar(t *testing.T) {
-	// Create a logger instance using the registered sink.
-	logger, cleanup := testutils.SetupLogger(logFile)
-	defer cleanup()
-	Logger = logger
-
 	src := "ark:/a5388"
 	tgzFile := "ark+=a5388.tgz"
 
-	err := testutils.TarCLI(t, Run, src, tgzFile)
+	err := pttest.TarCLI(t, Run, src, tgzFile)
 	assert.ErrorIs(t, err, nil, "There was an error with the Tar aspect of ptmv %v", err)
 
 }
 
 // TestUnTar tests a .tgz file is properly untarred into the pairtree
 func TestUnTar(t *testing.T) {
-	// Create a logger instance using the registered sink.
-	logger, cleanup := testutils.SetupLogger(logFile)
-	defer cleanup()
-	Logger = logger
-
 	dest := "ark:/a5388"
 	pairpath := filepath.Join(rootDir, "a5", "38", "8", "a5388")
 	ppBase := "a5388"
 
-	err := testutils.UntarCLI(t, Run, dest, pairpath, ppBase, true)
+	err := pttest.UntarCLI(t, Run, dest, pairpath, ppBase, true)
 	assert.ErrorIs(t, err, nil)
 }
 
@@ -149,15 +170,22 @@ func TestCLIError(t *testing.T) {
 			args:      []string{root + "root", "ID"},
 			expectErr: error_msgs.Err9,
 		},
+		{
+			name:      "Negative compress workers",
+			args:      []string{root + "root", "ID", "Destination", "-a", "--compress-workers=-1"},
+			expectErr: error_msgs.Err17,
+		},
+		{
+			name:      "Unknown archive format",
+			args:      []string{root + "root", "ID", "Destination", "-a", "--format=rar"},
+			expectErr: error_msgs.Err17,
+		},
 	}
 
-	// Create a logger instance using the registered sink.
-	logger, cleanup := testutils.SetupLogger(logFile)
-	defer cleanup()
-	Logger = logger
-
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
 			var buf bytes.Buffer
 
 			err := Run(test.args, &buf)
@@ -166,3 +194,251 @@ func TestCLIError(t *testing.T) {
 	}
 
 }
+
+// TestDryRun tests that the destination that would be deleted and the move are printed without changing anything
+func TestDryRun(t *testing.T) {
+	fs := afero.NewOsFs()
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, fs)
+	src := pttest.CreateTempDir(t, fs)
+	pttest.CreateFileInDir(t, src, "file.txt")
+	objPath := filepath.Join(ptRoot, rootDir, "b5", "48", "8", "b5488")
+
+	// The existing object would be deleted, which is not asked about
+	var buf bytes.Buffer
+	require.NoError(t, Run([]string{root + ptRoot, "--dry-run", src, "ark:/b5488"}, &buf))
+	assert.Contains(t, buf.String(), "Would delete "+objPath+"\n")
+	assert.Contains(t, buf.String(), "Would move "+src+" to "+objPath+"\n")
+	assert.NotContains(t, buf.String(), "Overwrite")
+
+	assert.FileExists(t, filepath.Join(objPath, "outerb5488.txt"))
+	assert.FileExists(t, filepath.Join(src, "file.txt"))
+	assert.NoFileExists(t, filepath.Join(objPath, "file.txt"))
+
+	err := Run([]string{root + ptRoot, "--dry-run", filepath.Join(src, "missing"), "ark:/b5488"}, &buf)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+// TestRename tests that an object is moved to another ID of the pairtree and that the move is kept in
+// the event history of both IDs
+func TestRename(t *testing.T) {
+	fs := afero.NewOsFs()
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, fs)
+	oldPath := filepath.Join(ptRoot, rootDir, "b5", "48", "8", "b5488")
+	newPath := filepath.Join(ptRoot, rootDir, "c5", "49", "8", "c5498")
+
+	var buf bytes.Buffer
+	require.NoError(t, Run([]string{root + ptRoot, "--dry-run", "ark:/b5488", "ark:/c5498"}, &buf))
+	assert.Contains(t, buf.String(), "Would move "+oldPath+" to "+newPath+"\n")
+	assert.DirExists(t, oldPath)
+	assert.NoDirExists(t, newPath)
+
+	require.NoError(t, Run([]string{root + ptRoot, "ark:/b5488", "ark:/c5498"}, &buf))
+	assert.NoDirExists(t, oldPath)
+	assert.FileExists(t, filepath.Join(newPath, "outerb5488.txt"))
+	assert.FileExists(t, filepath.Join(newPath, "folder", ".hidden", "inner.txt"))
+
+	for id, expected := range map[string]premis.Event{
+		"ark:/b5488": {Type: premis.Deletion, Detail: "moved to ark:/c5498"},
+		"ark:/c5498": {Type: premis.Ingestion, Detail: "moved from ark:/b5488"},
+	} {
+		events, err := premis.Events(ptRoot, "ark:/", id)
+		require.NoError(t, err)
+		require.Len(t, events, 1, id)
+		assert.Equal(t, expected.Type, events[0].Type)
+		assert.Equal(t, expected.Detail, events[0].Detail)
+	}
+
+	err := Run([]string{root + ptRoot, "ark:/c5498", "ark:/c5498"}, &buf)
+	assert.ErrorIs(t, err, error_msgs.Err17)
+
+	err = Run([]string{root + ptRoot, "-a", "ark:/c5498", "ark:/b5488"}, &buf)
+	assert.ErrorIs(t, err, error_msgs.Err17)
+
+	err = Run([]string{root + ptRoot, "ark:/b5488", "ark:/d5498"}, &buf)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+// TestVerify tests that the source is only deleted once the copy or archive is verified with --verify
+func TestVerify(t *testing.T) {
+	crossDevice(t)
+
+	fs := afero.NewOsFs()
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, fs)
+	dest := pttest.CreateTempDir(t, fs)
+
+	var buf bytes.Buffer
+	require.NoError(t, Run([]string{root + ptRoot, "--verify", "ark:/b5488", filepath.Join(dest, "b5488")}, &buf))
+	assert.Contains(t, buf.String(), "Verified the checksums of "+filepath.Join(dest, "b5488"))
+	assert.FileExists(t, filepath.Join(dest, "b5488", "folder", "innerb5488.txt"))
+	assert.NoDirExists(t, filepath.Join(ptRoot, rootDir, "b5", "48", "8", "b5488"))
+
+	archive := filepath.Join(dest, "archives", "ark+=a5388.tgz")
+	require.NoError(t, Run([]string{root + ptRoot, "--verify", "-a", "ark:/a5388", filepath.Join(dest, "archives")}, &buf))
+	assert.Contains(t, buf.String(), "Verified the checksums of "+archive)
+	assert.FileExists(t, archive)
+	assert.NoDirExists(t, filepath.Join(ptRoot, rootDir, "a5", "38", "8", "a5388"))
+
+	// The copy and the archive were each checked against the object before it was deleted
+	for id, detail := range map[string]string{
+		"ark:/b5488": "checksums compared with the copy at " + filepath.Join(dest, "b5488"),
+		"ark:/a5388": "checksums compared with the copy at " + filepath.Join(dest, "archives"),
+	} {
+		events, err := premis.Events(ptRoot, "ark:/", id)
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+		assert.Equal(t, premis.Deletion, events[0].Type)
+		assert.Equal(t, premis.FixityCheck, events[1].Type)
+		assert.Equal(t, premis.Success, events[1].Outcome)
+		assert.Equal(t, detail, events[1].Detail)
+	}
+}
+
+// TestRenameSource tests that a source on the same device is renamed to the destination rather than copied,
+// and is copied when it is on another device
+func TestRenameSource(t *testing.T) {
+	fs := afero.NewOsFs()
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, fs)
+	destDir := pttest.CreateTempDir(t, fs)
+	srcFile := filepath.Join(ptRoot, rootDir, "b5", "48", "8", "b5488", "outerb5488.txt")
+
+	srcInfo, err := os.Stat(srcFile)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, Run([]string{root + ptRoot, "ark:/b5488", filepath.Join(destDir, "b5488")}, &buf))
+	destInfo, err := os.Stat(filepath.Join(destDir, "b5488", "outerb5488.txt"))
+	require.NoError(t, err)
+	assert.True(t, os.SameFile(srcInfo, destInfo))
+	assert.NoFileExists(t, srcFile)
+
+	// A destination that ends in a separator gets the source inside it
+	require.NoError(t, Run([]string{root + ptRoot, "ark:/a5388", filepath.Join(destDir, "out") + string(os.PathSeparator)}, &buf))
+	assert.FileExists(t, filepath.Join(destDir, "out", "a5388", "a5388.txt"))
+
+	crossDevice(t)
+	srcFile = filepath.Join(ptRoot, rootDir, "a5", "48", "92", "a54892", "a54892.txt")
+	srcInfo, err = os.Stat(srcFile)
+	require.NoError(t, err)
+
+	require.NoError(t, Run([]string{root + ptRoot, "ark:/a54892", filepath.Join(destDir, "a54892")}, &buf))
+	destInfo, err = os.Stat(filepath.Join(destDir, "a54892", "a54892.txt"))
+	require.NoError(t, err)
+	assert.False(t, os.SameFile(srcInfo, destInfo))
+	assert.NoFileExists(t, srcFile)
+
+	// Nothing is left of the staging directories
+	entries, err := os.ReadDir(destDir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 3)
+}
+
+// TestMoveIntoDirectory tests that a move into an existing directory, given with a trailing separator or as
+// where an archive is written, only adds the source to it and leaves the rest of the directory as it was
+func TestMoveIntoDirectory(t *testing.T) {
+	for _, copied := range []bool{false, true} {
+		t.Run(fmt.Sprintf("copied=%t", copied), func(t *testing.T) {
+			if copied {
+				crossDevice(t)
+			}
+
+			fs := afero.NewOsFs()
+			ptRoot := pttest.StandardPairtree().BuildTemp(t, fs)
+			destDir := pttest.CreateTempDir(t, fs)
+			require.NoError(t, os.WriteFile(filepath.Join(destDir, "keep.txt"), []byte("keep"), 0644))
+
+			var buf bytes.Buffer
+			require.NoError(t, Run([]string{root + ptRoot, "ark:/b5488", destDir + string(os.PathSeparator)}, &buf))
+			assert.FileExists(t, filepath.Join(destDir, "b5488", "outerb5488.txt"))
+			assert.FileExists(t, filepath.Join(destDir, "keep.txt"))
+
+			require.NoError(t, Run([]string{root + ptRoot, "-a", "ark:/a5388", destDir}, &buf))
+			assert.FileExists(t, filepath.Join(destDir, "ark+=a5388.tgz"))
+			assert.FileExists(t, filepath.Join(destDir, "keep.txt"))
+			assert.DirExists(t, filepath.Join(destDir, "b5488"))
+
+			// Nothing is left of the staging directories
+			entries, err := os.ReadDir(destDir)
+			require.NoError(t, err)
+			assert.Len(t, entries, 3)
+		})
+	}
+}
+
+// TestFailedMove tests that a move that fails leaves both the source and the destination as they were
+func TestFailedMove(t *testing.T) {
+	fs := afero.NewOsFs()
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, fs)
+	shard := filepath.Join(ptRoot, rootDir, "b5", "48", "8")
+
+	archive := filepath.Join(pttest.CreateTempDir(t, fs), "b5488.tgz")
+	require.NoError(t, os.WriteFile(archive, []byte("not an archive"), 0644))
+
+	var buf bytes.Buffer
+	require.Error(t, Run([]string{root + ptRoot, "-a", "--yes", archive, "ark:/b5488"}, &buf))
+	assert.FileExists(t, archive)
+	assert.FileExists(t, filepath.Join(shard, "b5488", "folder", "innerb5488.txt"))
+
+	// Nothing is left of the staged copy
+	entries, err := os.ReadDir(shard)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+// TestInterruptedMove tests that an interrupted move says that it did not change the source or destination
+func TestInterruptedMove(t *testing.T) {
+	fs := afero.NewOsFs()
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, fs)
+	destDir := pttest.CreateTempDir(t, fs)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	pt, err := pairtree.Open(ptRoot)
+	require.NoError(t, err)
+
+	c := &command{ptRoot: ptRoot, src: "ark:/b5488", dest: filepath.Join(destDir, "b5488"), logger: Logger,
+		out: utils.NewOutput(io.Discard, &utils.Styler{}, false)}
+	err = c.moveObject(ctx, pt, io.Discard)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorContains(t, err, "the move was interrupted")
+
+	assert.DirExists(t, filepath.Join(ptRoot, rootDir, "b5", "48", "8", "b5488"))
+	entries, err := os.ReadDir(destDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+// TestRoots tests that an object is moved from one pairtree to another with --src-root and --dest-root
+func TestRoots(t *testing.T) {
+	fs := afero.NewOsFs()
+	staging := pttest.StandardPairtree().BuildTemp(t, fs)
+	prod := pttest.NewPairtreeBuilder().BuildTemp(t, fs)
+	stagingPath := filepath.Join(staging, rootDir, "b5", "48", "8", "b5488")
+	prodPath := filepath.Join(prod, rootDir, "b5", "48", "8", "b5488")
+
+	var buf bytes.Buffer
+	require.NoError(t, Run([]string{"--src-root", staging, "--dest-root", prod, "--dry-run", "ark:/b5488"}, &buf))
+	assert.Contains(t, buf.String(), "Would move "+stagingPath+" to "+prodPath+"\n")
+	assert.DirExists(t, stagingPath)
+
+	require.NoError(t, Run([]string{"--src-root", staging, "--dest-root", prod, "ark:/b5488"}, &buf))
+	assert.NoDirExists(t, stagingPath)
+	assert.FileExists(t, filepath.Join(prodPath, "folder", "innerb5488.txt"))
+
+	for ptRoot, detail := range map[string]string{
+		staging: "moved to ark:/b5488 in " + prod,
+		prod:    "moved from ark:/b5488 in " + staging,
+	} {
+		events, err := premis.Events(ptRoot, "ark:/", "ark:/b5488")
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		assert.Equal(t, detail, events[0].Detail)
+	}
+
+	// The root that is not set is the pairtree root
+	require.NoError(t, Run([]string{root + staging, "--dest-root", prod, "ark:/a5388", "ark:/c5498"}, &buf))
+	assert.FileExists(t, filepath.Join(prod, rootDir, "c5", "49", "8", "c5498", "a5388.txt"))
+
+	err := Run([]string{"--dest-root", prod, "ark:/a54892"}, &buf)
+	assert.ErrorIs(t, err, error_msgs.Err7)
+}
@@ -3,9 +3,7 @@ package ptnew
 /* ptnew is a tool that creates the basic structure of a pairtree including the pairtree_version file, the pairtree_prefix file, and the pairtree_root folder */
 
 import (
-	"fmt"
 	"io"
-	"os"
 
 	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
 	"github.com/UCLALibrary/pt-tools/pkg/pairtree"
@@ -22,67 +20,71 @@ type FileInfo struct {
 }
 
 var (
-	ptRoot  string
-	prefix  string
-	logFile string      = "logs.log"
-	Logger  *zap.Logger = utils.Logger(logFile)
+	// Logger is the logger each run of pt new starts from, tests replace it to capture the logs
+	Logger *zap.Logger = utils.ConsoleLogger()
 )
 
-func initFlags(cmd *cobra.Command) {
-	cmd.Flags().StringVarP(&ptRoot, "pairtree", "p", "", "Set pairtree root directory")
-	cmd.Flags().StringVarP(&prefix, "prefix", "x", "", "Set pairtree prefix")
+// command holds the flags of one run of pt new so that runs can happen concurrently
+type command struct {
+	ptRoot string
+	prefix string
+	logger *zap.Logger
+	out    *utils.Output
+}
+
+func (c *command) initFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&c.prefix, "prefix", "x", "", "Set pairtree prefix")
 
 }
 
-func Run(args []string, writer io.Writer) error {
-	var err error
+// NewCommand creates the new subcommand of pt that writes its output to the writer
+func NewCommand(writer io.Writer) *cobra.Command {
+	c := &command{logger: Logger, out: utils.NewOutput(io.Discard, &utils.Styler{}, false)}
 
-	var rootCmd = &cobra.Command{
-		Use:   "pt new -p [PT_ROOT]",
+	var cmd = &cobra.Command{
+		Use:   "new -p [PT_ROOT]",
 		Short: "pt new is a tool to create a Pairtree",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return utils.ConfigureLogger(cmd, &c.logger)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			// If the root has not been set yet check the ENV vars
-			if ptRoot == "" {
-
-				if envVar := os.Getenv("PAIRTREE_ROOT"); envVar != "" {
-					ptRoot = envVar
-				} else {
-					fmt.Fprintln(writer, error_msgs.Err7)
-					return error_msgs.Err7
-				}
+			var err error
+
+			c.out = utils.OutputFromFlags(cmd, writer)
+
+			if c.ptRoot, err = utils.GetPtRoot(cmd, writer); err != nil {
+				return err
 			}
 
 			numArgs := len(args)
 			if numArgs > 0 {
-				fmt.Fprintln(writer, "There are too many arguments to ptcreate")
-				Logger.Error("ptcreate should only have the pairtree root set and a possible prefix ",
+				c.out.Error("Too many arguments were provided to %s", "ptnew")
+				c.logger.Error("ptcreate should only have the pairtree root set and a possible prefix ",
 					zap.Error(error_msgs.Err8))
 
 				return error_msgs.Err8
 			}
 
-			Logger.Info("Pairtree root is",
-				zap.String("PAIRTREE_ROOT", ptRoot),
+			c.logger.Info("Pairtree root is",
+				zap.String("PAIRTREE_ROOT", c.ptRoot),
 			)
 
-			return nil
+			utils.SilenceUsage(cmd)
+
+			// create the pairtree root directory if it does not exist
+			return pairtree.CreatePairtree(c.ptRoot, c.prefix)
 		},
 	}
 
-	initFlags(rootCmd)
-	rootCmd.SetOut(writer)
-	rootCmd.SetErr(writer)
-	rootCmd.SetArgs(args)
-
-	utils.ApplyExitOnHelp(rootCmd, 0)
+	c.initFlags(cmd)
 
-	if err = rootCmd.Execute(); err != nil {
-		Logger.Error("Error setting command line", zap.Error(err))
-		return err
-	}
+	return cmd
+}
 
-	// create the pairtree root directory if it does not exist
-	if err = pairtree.CreatePairtree(ptRoot, prefix); err != nil {
+// Run executes pt new with the given arguments
+func Run(args []string, writer io.Writer) error {
+	if err := utils.RunSubcommand(NewCommand(writer), args, writer); err != nil {
+		Logger.Error("Error running pt new", zap.Error(err))
 		return err
 	}
 
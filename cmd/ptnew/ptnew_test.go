@@ -1,16 +1,17 @@
 package ptnew
 
-// The test-dir that is copied and used throughout this test. Both the pairtree_version0_1
+// The pairtree built by pttest.StandardPairtree is used throughout this test. Both the pairtree_version0_1
 // and the pairtree_prefix are populated. The pairtree_prefix is populated with the prefix ark:/
 // unless the test removes or changes that.
 import (
 	"bytes"
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
 	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
-	"github.com/UCLALibrary/pt-tools/testutils"
+	"github.com/UCLALibrary/pt-tools/pkg/pttest"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 )
@@ -21,6 +22,16 @@ const (
 	pre     = "--prefix="
 )
 
+// TestMain runs the tests with the logger of the test sink, it is set once so that the tests can run in parallel
+func TestMain(m *testing.M) {
+	logger, cleanup := pttest.SetupLogger()
+	Logger = logger
+
+	code := m.Run()
+	cleanup()
+	os.Exit(code)
+}
+
 // TestPtnew tests if an error is thrown when various CLI options are missing
 func TestPtnew(t *testing.T) {
 	tests := []struct {
@@ -45,21 +56,18 @@ func TestPtnew(t *testing.T) {
 		},
 	}
 
-	// Create a logger instance using the registered sink.
-	logger, cleanup := testutils.SetupLogger(logFile)
-	defer cleanup()
-	Logger = logger
-
 	fs := afero.NewOsFs()
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
 			var buf bytes.Buffer
 			var rootDir string
 			if strings.TrimSpace(test.pairtreeRoot) == "" {
 				rootDir = test.pairtreeRoot
 			} else {
-				rootDir = testutils.CreateTempDir(t, fs)
+				rootDir = pttest.CreateTempDir(t, fs)
 				rootDir = filepath.Join(rootDir, test.pairtreeRoot)
 			}
 			args := []string{root + rootDir, pre + "ark:/"}
@@ -93,13 +101,10 @@ func TestCLIError(t *testing.T) {
 		},
 	}
 
-	// Create a logger instance using the registered sink.
-	logger, cleanup := testutils.SetupLogger(logFile)
-	defer cleanup()
-	Logger = logger
-
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
 			var buf bytes.Buffer
 
 			err := Run(test.args, &buf)
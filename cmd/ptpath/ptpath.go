@@ -0,0 +1,120 @@
+package ptpath
+
+/* ptpath writes the pairpath that each ID is encoded to in the pairtree, whether or not the object
+exists, so that an object can be found on disk from its ID. pt id does the reverse. */
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pairtree"
+	"github.com/UCLALibrary/pt-tools/utils"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	// Logger is the logger each run of pt path starts from, tests replace it to capture the logs
+	Logger *zap.Logger = utils.ConsoleLogger()
+)
+
+// command holds the arguments of one run of pt path so that runs can happen concurrently
+type command struct {
+	ptRoot string
+	ids    []string
+	logger *zap.Logger
+	out    *utils.Output
+}
+
+// NewCommand creates the path subcommand of pt that writes its output to the writer
+func NewCommand(writer io.Writer) *cobra.Command {
+	c := &command{logger: Logger, out: utils.NewOutput(io.Discard, &utils.Styler{}, false)}
+
+	var cmd = &cobra.Command{
+		Use:               "path [ID]...",
+		Short:             "pt path writes the pairpath of each ID in the pairtree",
+		ValidArgsFunction: utils.CompleteIDs,
+		Annotations:       map[string]string{utils.S3Annotation: "true"},
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return utils.ConfigureLogger(cmd, &c.logger)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+
+			c.out = utils.OutputFromFlags(cmd, writer)
+
+			if c.ptRoot, err = utils.GetPtRoot(cmd, writer); err != nil {
+				return err
+			}
+
+			if len(args) < 1 {
+				c.out.Error("Please provide an ID for the pairtree")
+				c.logger.Error("Error getting ID", zap.Error(error_msgs.Err6))
+
+				return error_msgs.Err6
+			}
+			c.ids = args
+
+			utils.SilenceUsage(cmd)
+
+			return c.paths(cmd.Context(), writer)
+		},
+	}
+
+	return cmd
+}
+
+// Run executes pt path with the given arguments
+func Run(args []string, writer io.Writer) error {
+	if err := utils.RunSubcommand(NewCommand(writer), args, writer); err != nil {
+		Logger.Error("Error running pt path", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// paths writes the pairpath of each ID on a line. An ID that can not be encoded does not stop the
+// others, and its error is returned with theirs.
+func (c *command) paths(ctx context.Context, writer io.Writer) error {
+	// The pairpaths of a local pairtree are written in full wherever pt is run from
+	if !pairtree.IsS3(c.ptRoot) {
+		absRoot, err := filepath.Abs(c.ptRoot)
+		if err != nil {
+			c.logger.Error("Error finding the pairtree root", zap.Error(err))
+			return err
+		}
+		c.ptRoot = absRoot
+	}
+
+	// Open the pairtree, which checks its version file and reads its prefix
+	pt, err := pairtree.Open(c.ptRoot)
+	if err != nil {
+		c.logger.Error("Error opening the pairtree", zap.Error(err))
+		return err
+	}
+
+	var errs []error
+	for _, id := range c.ids {
+		// Stop before the next ID once the context is canceled
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+
+		pairPath, err := pt.PairPath(id)
+		if err != nil {
+			c.logger.Error("Error creating pairpath", zap.String("id", id), zap.Error(err))
+			errs = append(errs, &error_msgs.PtError{ID: id, Err: err})
+			continue
+		}
+
+		fmt.Fprintln(writer, pairPath)
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,61 @@
+package ptpath
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pttest"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const root = "--pairtree="
+
+// TestMain runs the tests with the logger of the test sink, it is set once so that the tests can run in parallel
+func TestMain(m *testing.M) {
+	logger, cleanup := pttest.SetupLogger()
+	Logger = logger
+
+	code := m.Run()
+	cleanup()
+	os.Exit(code)
+}
+
+// TestPath tests that the pairpath of each ID is written whether or not its object exists
+func TestPath(t *testing.T) {
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, afero.NewOsFs())
+
+	var buf bytes.Buffer
+	require.NoError(t, Run([]string{root + ptRoot, "ark:/a5388", "ark:/new+id"}, &buf))
+	assert.Equal(t, filepath.Join(ptRoot, "pairtree_root", "a5", "38", "8", "a5388")+"\n"+
+		filepath.Join(ptRoot, "pairtree_root", "ne", "w^", "2b", "id", "new^2bid")+"\n", buf.String())
+
+	// An ID without the prefix does not stop the others
+	buf.Reset()
+	err := Run([]string{root + ptRoot, "doi:/a5388", "ark:/b5488"}, &buf)
+	assert.ErrorIs(t, err, error_msgs.Err5)
+	assert.True(t, strings.HasPrefix(buf.String(), filepath.Join(ptRoot, "pairtree_root", "b5", "48", "8", "b5488")+"\n"))
+
+	assert.ErrorIs(t, Run([]string{root + ptRoot}, &buf), error_msgs.Err6)
+}
+
+// TestPathRelativeRoot tests that the pairpath of a pairtree given relative to the current directory is absolute
+func TestPathRelativeRoot(t *testing.T) {
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, afero.NewOsFs())
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(filepath.Dir(ptRoot)))
+	t.Cleanup(func() { require.NoError(t, os.Chdir(wd)) })
+
+	var buf bytes.Buffer
+	require.NoError(t, Run([]string{root + filepath.Base(ptRoot), "ark:/a5388"}, &buf))
+
+	pairPath := strings.TrimSpace(buf.String())
+	assert.True(t, filepath.IsAbs(pairPath))
+	assert.DirExists(t, pairPath)
+}
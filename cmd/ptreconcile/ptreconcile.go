@@ -0,0 +1,277 @@
+package ptreconcile
+
+/* ptreconcile compares the objects in a pairtree with an external list of IDs, like a catalog
+export, or with the files of a copy of the pairtree, like an S3 inventory report, and reports the
+IDs that are missing from either side. It exits with the verification exit code when the two do
+not have the same objects, so it can be used in scheduled audits. */
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pairtree"
+	"github.com/UCLALibrary/pt-tools/utils"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	// Logger is the logger each run of pt reconcile starts from, tests replace it to capture the logs
+	Logger *zap.Logger = utils.ConsoleLogger()
+)
+
+// Reconciliation is the IDs in the list that are not in the pairtree, and the IDs of the objects in
+// the pairtree that are not in the list
+type Reconciliation struct {
+	Missing []string `json:"missing"`
+	Extra   []string `json:"extra"`
+}
+
+// command holds the flags and arguments of one run of pt reconcile so that runs can happen concurrently
+type command struct {
+	against string
+	column  int
+	header  bool
+	paths   bool
+	ptRoot  string
+	logger  *zap.Logger
+	out     *utils.Output
+}
+
+func (c *command) initFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&c.against, "against", "", "CSV file, optionally gzipped, with the IDs to reconcile the pairtree with")
+	cmd.Flags().IntVar(&c.column, "column", 1, "Column of the CSV file that has the IDs, starting from 1")
+	cmd.Flags().BoolVar(&c.header, "header", false, "Skip the first row of the CSV file")
+	cmd.Flags().BoolVar(&c.paths, "paths", false, "The column has paths of files in a copy of the pairtree, like an S3 inventory, instead of IDs")
+}
+
+// NewCommand creates the reconcile subcommand of pt that writes its output to the writer
+func NewCommand(writer io.Writer) *cobra.Command {
+	c := &command{logger: Logger, out: utils.NewOutput(io.Discard, &utils.Styler{}, false)}
+
+	var cmd = &cobra.Command{
+		Use:   "reconcile --against [/path/to/list.csv]",
+		Short: "pt reconcile reports the IDs that are missing from the pairtree or from a list of IDs",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return utils.ConfigureLogger(cmd, &c.logger)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+
+			c.out = utils.OutputFromFlags(cmd, writer)
+
+			if c.ptRoot, err = utils.GetPtRoot(cmd, writer); err != nil {
+				return err
+			}
+
+			if len(args) > 0 {
+				c.out.Error("Too many arguments were provided to %s", "pt reconcile")
+				c.logger.Error("Error parsing pt reconcile", zap.Error(error_msgs.Err8))
+
+				return error_msgs.Err8
+			}
+
+			if c.against == "" {
+				c.logger.Error("Error parsing pt reconcile", zap.Error(error_msgs.Err33))
+				return error_msgs.Err33
+			}
+
+			if c.column < 1 {
+				err := fmt.Errorf("%w: --column must be at least 1", error_msgs.Err17)
+				c.logger.Error("Error parsing pt reconcile", zap.Error(err))
+
+				return err
+			}
+
+			jsonFlag, _ := cmd.Flags().GetBool(utils.JSONFlag)
+
+			utils.SilenceUsage(cmd)
+
+			return c.reconcile(cmd.Context(), writer, jsonFlag)
+		},
+	}
+
+	c.initFlags(cmd)
+
+	return cmd
+}
+
+// Run executes pt reconcile with the given arguments
+func Run(args []string, writer io.Writer) error {
+	if err := utils.RunSubcommand(NewCommand(writer), args, writer); err != nil {
+		Logger.Error("Error running pt reconcile", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// reconcile compares the objects in the pairtree with the IDs in the list and writes the differences
+func (c *command) reconcile(ctx context.Context, writer io.Writer, outputJSON bool) error {
+	// check if the pairtree version file exists and is populated
+	if err := pairtree.CheckPTVer(c.ptRoot); err != nil {
+		c.logger.Error("Error with pairtree veresion file", zap.Error(err))
+		return err
+	}
+
+	// Get the prefix from pairtree_prefix file
+	prefix, err := pairtree.GetPrefix(c.ptRoot)
+	if err != nil {
+		c.logger.Error("Error retrieving prefix from pairtree_prefix file", zap.Error(err))
+		return err
+	}
+
+	if prefix == "" {
+		prefix = pairtree.PtPrefix
+	}
+
+	listed, err := c.readList(prefix)
+	if err != nil {
+		c.logger.Error("Error reading the list of IDs", zap.String("list", c.against), zap.Error(err))
+		return err
+	}
+
+	inList := make(map[string]bool, len(listed))
+	for _, id := range listed {
+		inList[id] = true
+	}
+
+	result := Reconciliation{Missing: []string{}, Extra: []string{}}
+	inPairtree := map[string]bool{}
+
+	err = pairtree.WalkObjectsCtx(ctx, c.ptRoot, prefix, func(id, objPath string) error {
+		inPairtree[id] = true
+		if !inList[id] {
+			result.Extra = append(result.Extra, id)
+		}
+		return nil
+	})
+	if err != nil {
+		c.logger.Error("Error walking the pairtree", zap.Error(err))
+		return err
+	}
+
+	for _, id := range listed {
+		if !inPairtree[id] {
+			result.Missing = append(result.Missing, id)
+		}
+	}
+
+	if outputJSON {
+		jsonData, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			c.logger.Error("Error converting the reconciliation to JSON", zap.Error(err))
+			return err
+		}
+		fmt.Fprintln(writer, string(jsonData))
+	} else {
+		c.writeResult(writer, result, len(inPairtree), len(listed))
+	}
+
+	if len(result.Missing) > 0 || len(result.Extra) > 0 {
+		c.logger.Warn("The pairtree does not match the list of IDs", zap.Int("missing", len(result.Missing)),
+			zap.Int("extra", len(result.Extra)))
+		return error_msgs.Err34
+	}
+
+	return nil
+}
+
+// writeResult writes a line for each ID that is missing from the pairtree or from the list, followed
+// by a summary
+func (c *command) writeResult(writer io.Writer, result Reconciliation, objects, listed int) {
+	style := c.out.Style()
+
+	for _, id := range result.Missing {
+		fmt.Fprintf(writer, "%s  %s\n", style.Error("missing"), id)
+	}
+	for _, id := range result.Extra {
+		fmt.Fprintf(writer, "%s    %s\n", style.Warning("extra"), id)
+	}
+
+	if len(result.Missing) == 0 && len(result.Extra) == 0 {
+		c.out.Success("The pairtree and %s have the same %d objects", c.against, objects)
+		return
+	}
+
+	c.out.Info("%d of the %d IDs in %s are missing from the pairtree, %d of its %d objects are not in the list",
+		len(result.Missing), listed, c.against, len(result.Extra), objects)
+}
+
+// readList reads the IDs from the column of the CSV file, each listed once in the order they are first
+// found. An ID without the pairtree prefix has it added. With --paths the column has the paths of
+// files, percent-encoded like the keys of an S3 inventory, and paths outside of an object are skipped.
+func (c *command) readList(prefix string) ([]string, error) {
+	file, err := os.Open(c.against)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if strings.HasSuffix(c.against, ".gz") {
+		gzReader, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, err
+		}
+		defer gzReader.Close()
+		reader = gzReader
+	}
+
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	csvReader.ReuseRecord = true
+
+	ids := []string{}
+	seen := map[string]bool{}
+
+	for row := 1; ; row++ {
+		record, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		if (row == 1 && c.header) || len(record) < c.column {
+			continue
+		}
+
+		id := strings.TrimSpace(record[c.column-1])
+		if id == "" {
+			continue
+		}
+
+		if c.paths {
+			if strings.Contains(id, "%") {
+				if id, err = url.PathUnescape(id); err != nil {
+					return nil, fmt.Errorf("row %d: %w", row, err)
+				}
+			}
+
+			if id, err = pairtree.PathID(id, prefix); errors.Is(err, error_msgs.Err35) {
+				continue
+			} else if err != nil {
+				return nil, fmt.Errorf("row %d: %w", row, err)
+			}
+		} else if !strings.HasPrefix(id, prefix) {
+			id = prefix + id
+		}
+
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
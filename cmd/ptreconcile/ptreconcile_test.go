@@ -0,0 +1,136 @@
+package ptreconcile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pttest"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	root    = "--pairtree="
+	against = "--against="
+)
+
+// TestMain runs the tests with the logger of the test sink, it is set once so that the tests can run in parallel
+func TestMain(m *testing.M) {
+	logger, cleanup := pttest.SetupLogger()
+	Logger = logger
+
+	code := m.Run()
+	cleanup()
+	os.Exit(code)
+}
+
+// writeList writes the content to a file in a new temporary directory, gzipped if the name ends in .gz
+func writeList(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+
+	data := []byte(content)
+	if filepath.Ext(name) == ".gz" {
+		var buf bytes.Buffer
+		gzWriter := gzip.NewWriter(&buf)
+		_, err := gzWriter.Write(data)
+		require.NoError(t, err)
+		require.NoError(t, gzWriter.Close())
+		data = buf.Bytes()
+	}
+
+	require.NoError(t, os.WriteFile(path, data, 0644))
+	return path
+}
+
+// TestReconcile tests if the IDs missing from the pairtree or from the list are reported
+func TestReconcile(t *testing.T) {
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, afero.NewOsFs())
+
+	tests := []struct {
+		name          string
+		file          string
+		content       string
+		args          []string
+		expectMissing []string
+		expectExtra   []string
+		expectErr     error
+	}{
+		{name: "same objects", file: "ids.csv", content: "ark:/a5388\nark:/a5488\nark:/a54892\nark:/b5488\n",
+			expectMissing: []string{}, expectExtra: []string{}},
+		{name: "missing and extra", file: "ids.csv", content: "ark:/a5388\nark:/a5488\nark:/z9999\nark:/z9999\n",
+			expectMissing: []string{"ark:/z9999"}, expectExtra: []string{"ark:/a54892", "ark:/b5488"},
+			expectErr: error_msgs.Err34},
+		{name: "catalog export", file: "catalog.csv", args: []string{"--column=2", "--header"},
+			content:       "title,ark\nFirst,a5388\nSecond,a5488\nThird,ark:/a54892\nFourth,b5488\n",
+			expectMissing: []string{}, expectExtra: []string{}},
+		{name: "s3 inventory", file: "inventory.csv.gz", args: []string{"--column=2", "--paths"},
+			content: "\"bucket\",\"pt/pairtree_prefix\"\n" +
+				"\"bucket\",\"pt/pairtree_root/a5/38/8/a5388/a5388.txt\"\n" +
+				"\"bucket\",\"pt/pairtree_root/a5/48/8/a5488/a5488.txt\"\n" +
+				"\"bucket\",\"pt/pairtree_root/b5/48/8/b5488/folder/inner%20b5488.txt\"\n" +
+				"\"bucket\",\"pt/pairtree_root/c5/48/8/c5488/c5488.txt\"\n",
+			expectMissing: []string{"ark:/c5488"}, expectExtra: []string{"ark:/a54892"}, expectErr: error_msgs.Err34},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			list := writeList(t, test.file, test.content)
+			args := append([]string{root + ptRoot, against + list, "--json"}, test.args...)
+
+			var buf bytes.Buffer
+			err := Run(args, &buf)
+			assert.ErrorIs(t, err, test.expectErr)
+
+			// The error that follows the JSON on a mismatch is not decoded
+			var result Reconciliation
+			require.NoError(t, json.NewDecoder(&buf).Decode(&result))
+			assert.Equal(t, test.expectMissing, result.Missing)
+			assert.Equal(t, test.expectExtra, result.Extra)
+		})
+	}
+}
+
+// TestReconcileText tests if each difference is written on its own line
+func TestReconcileText(t *testing.T) {
+	ptRoot := pttest.NewPairtreeBuilder().WithObject("ark:/a5388", "a5388.txt").BuildTemp(t, afero.NewOsFs())
+	list := writeList(t, "ids.txt", "ark:/b5488\n")
+
+	var buf bytes.Buffer
+	err := Run([]string{root + ptRoot, against + list, "--no-color"}, &buf)
+	assert.ErrorIs(t, err, error_msgs.Err34)
+	assert.Contains(t, buf.String(), "missing  ark:/b5488\n")
+	assert.Contains(t, buf.String(), "extra    ark:/a5388\n")
+}
+
+// TestCLIError tests if an error is thrown when the arguments are not valid
+func TestCLIError(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		expectErr error
+	}{
+		{name: "No list", args: []string{root + "root"}, expectErr: error_msgs.Err33},
+		{name: "No pairtree root provided", args: []string{against + "ids.csv"}, expectErr: error_msgs.Err7},
+		{name: "Too many arguments passed in", args: []string{root + "root", against + "ids.csv", "ark:/a5388"}, expectErr: error_msgs.Err8},
+		{name: "Invalid column", args: []string{root + "root", against + "ids.csv", "--column=0"}, expectErr: error_msgs.Err17},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+
+			err := Run(test.args, &buf)
+			assert.ErrorIs(t, err, test.expectErr)
+		})
+	}
+}
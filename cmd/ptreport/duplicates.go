@@ -0,0 +1,283 @@
+package ptreport
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/UCLALibrary/pt-tools/pkg/checksum"
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pairtree"
+	"github.com/UCLALibrary/pt-tools/utils"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// duplicatesHeader is the header row of the duplicates CSV, which has a row for each duplicated file
+var duplicatesHeader = []string{"checksum", "bytes", "copies", "wasted_bytes", "id", "path"}
+
+// DuplicateFile is a file in an object, with its path relative to the object directory
+type DuplicateFile struct {
+	ID   string `json:"id"`
+	Path string `json:"path"`
+}
+
+// Duplicate is content that is in files of more than one object, the bytes of all but one of the
+// files are wasted
+type Duplicate struct {
+	Checksum    string          `json:"checksum"`
+	Bytes       int64           `json:"bytes"`
+	WastedBytes int64           `json:"wasted_bytes"`
+	Files       []DuplicateFile `json:"files"`
+}
+
+// Duplicates is the duplicated content of the pairtree and the total bytes wasted by it
+type Duplicates struct {
+	WastedBytes int64       `json:"wasted_bytes"`
+	Duplicates  []Duplicate `json:"duplicates"`
+}
+
+// duplicatesCommand holds the flags and arguments of one run of pt report duplicates
+type duplicatesCommand struct {
+	showAll bool
+	ptRoot  string
+	logger  *zap.Logger
+	out     *utils.Output
+}
+
+func (c *duplicatesCommand) initFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(&c.showAll, "a", "a", false, "compare hidden files and directories")
+}
+
+// newDuplicatesCommand creates the duplicates report of pt report
+func newDuplicatesCommand(writer io.Writer) *cobra.Command {
+	c := &duplicatesCommand{logger: Logger, out: utils.NewOutput(io.Discard, &utils.Styler{}, false)}
+
+	var cmd = &cobra.Command{
+		Use:   "duplicates",
+		Short: "pt report duplicates lists the files whose content is in more than one object",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return utils.ConfigureLogger(cmd, &c.logger)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+
+			c.out = utils.OutputFromFlags(cmd, writer)
+
+			if c.ptRoot, err = utils.GetPtRoot(cmd, writer); err != nil {
+				return err
+			}
+
+			if len(args) > 0 {
+				c.out.Error("Too many arguments were provided to %s", "pt report duplicates")
+				c.logger.Error("Error parsing pt report duplicates", zap.Error(error_msgs.Err8))
+
+				return error_msgs.Err8
+			}
+
+			jsonFlag, _ := cmd.Flags().GetBool(utils.JSONFlag)
+
+			utils.SilenceUsage(cmd)
+
+			return c.report(cmd.Context(), writer, jsonFlag)
+		},
+	}
+
+	c.initFlags(cmd)
+
+	return cmd
+}
+
+// sizedFile is a file found while walking the pairtree, with where it is and how big it is
+type sizedFile struct {
+	DuplicateFile
+	fullPath string
+	size     int64
+}
+
+// report writes the content that is duplicated across objects to the writer, the content wasting the
+// most bytes first
+func (c *duplicatesCommand) report(ctx context.Context, writer io.Writer, outputJSON bool) error {
+	prefix, err := reportPrefix(c.ptRoot, c.logger)
+	if err != nil {
+		return err
+	}
+
+	// Only files with the same size can have the same content, so the rest are never read
+	bySize := map[int64][]sizedFile{}
+
+	err = pairtree.WalkObjectsCtx(ctx, c.ptRoot, prefix, func(id, objPath string) error {
+		if err := c.collect(ctx, id, objPath, bySize); err != nil {
+			return &error_msgs.PtError{ID: id, Path: objPath, Err: err}
+		}
+		return nil
+	})
+	if err != nil {
+		c.logger.Error("Error finding the files of the pairtree", zap.Error(err))
+		return err
+	}
+
+	result, err := c.findDuplicates(ctx, bySize)
+	if err != nil {
+		c.logger.Error("Error comparing the files of the pairtree", zap.Error(err))
+		return err
+	}
+
+	c.logger.Info("Found duplicated content", zap.Int("duplicates", len(result.Duplicates)),
+		zap.Int64("wasted_bytes", result.WastedBytes))
+
+	if outputJSON {
+		jsonData, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			c.logger.Error("Error converting the duplicates to JSON", zap.Error(err))
+			return err
+		}
+		fmt.Fprintln(writer, string(jsonData))
+		return nil
+	}
+
+	csvWriter := csv.NewWriter(writer)
+	if err := csvWriter.Write(duplicatesHeader); err != nil {
+		return err
+	}
+	for _, duplicate := range result.Duplicates {
+		for _, file := range duplicate.Files {
+			if err := csvWriter.Write(duplicate.row(file)); err != nil {
+				return err
+			}
+		}
+	}
+
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// collect adds the files of the object, except empty ones, to the files of their size
+func (c *duplicatesCommand) collect(ctx context.Context, id, objPath string, bySize map[int64][]sizedFile) error {
+	return filepath.WalkDir(objPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if path != objPath && !c.showAll && pairtree.IsHidden(d.Name()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.Size() == 0 {
+			return nil
+		}
+
+		rel, err := filepath.Rel(objPath, path)
+		if err != nil {
+			return err
+		}
+
+		bySize[info.Size()] = append(bySize[info.Size()], sizedFile{
+			DuplicateFile: DuplicateFile{ID: id, Path: filepath.ToSlash(rel)},
+			fullPath:      path,
+			size:          info.Size(),
+		})
+
+		return nil
+	})
+}
+
+// findDuplicates hashes the files that share a size with a file of another object and returns the
+// content that is in more than one object
+func (c *duplicatesCommand) findDuplicates(ctx context.Context, bySize map[int64][]sizedFile) (Duplicates, error) {
+	result := Duplicates{Duplicates: []Duplicate{}}
+
+	// The files of every size are hashed together so that many small groups still hash in parallel
+	var candidates []sizedFile
+	for _, files := range bySize {
+		if inSeveralObjects(files) {
+			candidates = append(candidates, files...)
+		}
+	}
+
+	paths := make([]string, len(candidates))
+	for i, file := range candidates {
+		paths[i] = file.fullPath
+	}
+
+	sums, err := checksum.Files(ctx, paths, checksum.Options{})
+	if err != nil {
+		return result, err
+	}
+
+	type content struct {
+		checksum string
+		size     int64
+	}
+
+	var contents []content
+	byContent := map[content][]sizedFile{}
+	for i, file := range candidates {
+		key := content{checksum: sums[i].Checksum, size: file.size}
+		if _, ok := byContent[key]; !ok {
+			contents = append(contents, key)
+		}
+		byContent[key] = append(byContent[key], file)
+	}
+
+	for _, key := range contents {
+		same := byContent[key]
+		if !inSeveralObjects(same) {
+			continue
+		}
+
+		duplicate := Duplicate{Checksum: key.checksum, Bytes: key.size, WastedBytes: key.size * int64(len(same)-1)}
+		for _, file := range same {
+			duplicate.Files = append(duplicate.Files, file.DuplicateFile)
+		}
+
+		result.Duplicates = append(result.Duplicates, duplicate)
+		result.WastedBytes += duplicate.WastedBytes
+	}
+
+	sort.Slice(result.Duplicates, func(i, j int) bool {
+		if result.Duplicates[i].WastedBytes != result.Duplicates[j].WastedBytes {
+			return result.Duplicates[i].WastedBytes > result.Duplicates[j].WastedBytes
+		}
+		return result.Duplicates[i].Checksum < result.Duplicates[j].Checksum
+	})
+
+	return result, nil
+}
+
+// inSeveralObjects checks if the files are in more than one object
+func inSeveralObjects(files []sizedFile) bool {
+	for _, file := range files[1:] {
+		if file.ID != files[0].ID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// row returns the file of the duplicated content as a row of the duplicates CSV
+func (d Duplicate) row(file DuplicateFile) []string {
+	return []string{d.Checksum, strconv.FormatInt(d.Bytes, 10), strconv.Itoa(len(d.Files)),
+		strconv.FormatInt(d.WastedBytes, 10), file.ID, file.Path}
+}
@@ -0,0 +1,238 @@
+package ptreport
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pairtree"
+	"github.com/UCLALibrary/pt-tools/utils"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+const (
+	// unknownType is the MIME type of content that could not be identified
+	unknownType = "application/octet-stream"
+	// sniffLen is how much of a file is read to identify its format when its extension is unknown
+	sniffLen = 512
+)
+
+// formatsHeader is the header row of the formats CSV, in the order of the Format fields
+var formatsHeader = []string{"mime_type", "extension", "files", "bytes", "identified"}
+
+// Format is the number of files, and their total bytes, with a MIME type and extension. A format is
+// not identified when neither the extension nor the content of its files is recognized.
+type Format struct {
+	MimeType   string `json:"mime_type"`
+	Extension  string `json:"extension"`
+	Files      int    `json:"files"`
+	Bytes      int64  `json:"bytes"`
+	Identified bool   `json:"identified"`
+}
+
+// formatsCommand holds the flags and arguments of one run of pt report formats
+type formatsCommand struct {
+	showAll bool
+	ptRoot  string
+	logger  *zap.Logger
+	out     *utils.Output
+}
+
+func (c *formatsCommand) initFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(&c.showAll, "a", "a", false, "count hidden files and directories")
+}
+
+// newFormatsCommand creates the formats report of pt report
+func newFormatsCommand(writer io.Writer) *cobra.Command {
+	c := &formatsCommand{logger: Logger, out: utils.NewOutput(io.Discard, &utils.Styler{}, false)}
+
+	var cmd = &cobra.Command{
+		Use:   "formats",
+		Short: "pt report formats counts the files and bytes of each file format in the pairtree",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return utils.ConfigureLogger(cmd, &c.logger)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+
+			c.out = utils.OutputFromFlags(cmd, writer)
+
+			if c.ptRoot, err = utils.GetPtRoot(cmd, writer); err != nil {
+				return err
+			}
+
+			if len(args) > 0 {
+				c.out.Error("Too many arguments were provided to %s", "pt report formats")
+				c.logger.Error("Error parsing pt report formats", zap.Error(error_msgs.Err8))
+
+				return error_msgs.Err8
+			}
+
+			jsonFlag, _ := cmd.Flags().GetBool(utils.JSONFlag)
+
+			utils.SilenceUsage(cmd)
+
+			return c.report(cmd.Context(), writer, jsonFlag)
+		},
+	}
+
+	c.initFlags(cmd)
+
+	return cmd
+}
+
+// report writes the formats of the files in the pairtree to the writer, the formats with the most
+// bytes first
+func (c *formatsCommand) report(ctx context.Context, writer io.Writer, outputJSON bool) error {
+	prefix, err := reportPrefix(c.ptRoot, c.logger)
+	if err != nil {
+		return err
+	}
+
+	counts := map[Format]*Format{}
+
+	err = pairtree.WalkObjectsCtx(ctx, c.ptRoot, prefix, func(id, objPath string) error {
+		if err := c.count(ctx, objPath, counts); err != nil {
+			return &error_msgs.PtError{ID: id, Path: objPath, Err: err}
+		}
+		return nil
+	})
+	if err != nil {
+		c.logger.Error("Error counting the formats of the pairtree", zap.Error(err))
+		return err
+	}
+
+	formats := make([]Format, 0, len(counts))
+	unidentified := 0
+	for _, format := range counts {
+		formats = append(formats, *format)
+		if !format.Identified {
+			unidentified += format.Files
+		}
+	}
+
+	sort.Slice(formats, func(i, j int) bool {
+		if formats[i].Bytes != formats[j].Bytes {
+			return formats[i].Bytes > formats[j].Bytes
+		}
+		if formats[i].MimeType != formats[j].MimeType {
+			return formats[i].MimeType < formats[j].MimeType
+		}
+		return formats[i].Extension < formats[j].Extension
+	})
+
+	if unidentified > 0 {
+		c.logger.Warn("Files with formats that were not identified", zap.Int("files", unidentified))
+	}
+
+	if outputJSON {
+		jsonData, err := json.MarshalIndent(formats, "", "  ")
+		if err != nil {
+			c.logger.Error("Error converting the formats to JSON", zap.Error(err))
+			return err
+		}
+		fmt.Fprintln(writer, string(jsonData))
+		return nil
+	}
+
+	csvWriter := csv.NewWriter(writer)
+	if err := csvWriter.Write(formatsHeader); err != nil {
+		return err
+	}
+	for _, format := range formats {
+		if err := csvWriter.Write(format.row()); err != nil {
+			return err
+		}
+	}
+
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// count adds the files of the object to the counts of their formats
+func (c *formatsCommand) count(ctx context.Context, objPath string, counts map[Format]*Format) error {
+	return filepath.WalkDir(objPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if path != objPath && !c.showAll && pairtree.IsHidden(d.Name()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		key, err := identify(path)
+		if err != nil {
+			return err
+		}
+
+		format, ok := counts[key]
+		if !ok {
+			format = &Format{MimeType: key.MimeType, Extension: key.Extension, Identified: key.Identified}
+			counts[key] = format
+		}
+		format.Files++
+		format.Bytes += info.Size()
+
+		return nil
+	})
+}
+
+// identify returns the format of the file, with no counts, from its extension or, when the extension
+// is not known, from the start of its content
+func identify(path string) (Format, error) {
+	format := Format{Extension: strings.ToLower(filepath.Ext(path)), Identified: true}
+
+	if format.MimeType = mime.TypeByExtension(format.Extension); format.MimeType != "" {
+		return format, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return format, err
+	}
+	defer file.Close()
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return format, err
+	}
+
+	format.MimeType = http.DetectContentType(head[:n])
+	format.Identified = format.MimeType != unknownType
+
+	return format, nil
+}
+
+// row returns the format as a row of the formats CSV
+func (f Format) row() []string {
+	return []string{f.MimeType, f.Extension, strconv.Itoa(f.Files), strconv.FormatInt(f.Bytes, 10),
+		strconv.FormatBool(f.Identified)}
+}
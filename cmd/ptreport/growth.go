@@ -0,0 +1,314 @@
+package ptreport
+
+import (
+	"bufio"
+	"context"
+	"crypto/ed25519"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pairtree"
+	"github.com/UCLALibrary/pt-tools/utils"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+const (
+	// SnapshotsFile is the file beside pairtree_root that keeps a snapshot of the size of the pairtree
+	// on each line
+	SnapshotsFile = "pairtree_snapshots.jsonl"
+	// sinceLayout is the layout of the date of the --since flag
+	sinceLayout = "2006-01-02"
+	// monthLayout is the layout of the month of each row of the growth report
+	monthLayout = "2006-01"
+)
+
+// growthHeader is the header row of the growth CSV, in the order of the Growth fields
+var growthHeader = []string{"month", "objects", "bytes", "object_growth", "byte_growth"}
+
+// Snapshot is the number of objects in the pairtree and their total bytes at a time
+type Snapshot struct {
+	Time    time.Time `json:"time"`
+	Objects int       `json:"objects"`
+	Bytes   int64     `json:"bytes"`
+}
+
+// Growth is the size of the pairtree at the last snapshot of a month and how much it grew since the
+// last snapshot of the month before
+type Growth struct {
+	Month        string `json:"month"`
+	Objects      int    `json:"objects"`
+	Bytes        int64  `json:"bytes"`
+	ObjectGrowth int    `json:"object_growth"`
+	ByteGrowth   int64  `json:"byte_growth"`
+}
+
+// growthCommand holds the flags and arguments of one run of pt report growth
+type growthCommand struct {
+	snapshot bool
+	since    string
+	key      ed25519.PrivateKey
+	ptRoot   string
+	logger   *zap.Logger
+	out      *utils.Output
+}
+
+func (c *growthCommand) initFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&c.snapshot, "snapshot", false, "Record a snapshot of the size of the pairtree instead of reporting its growth")
+	cmd.Flags().StringVar(&c.since, "since", "", "Only report the growth of the months from this date, like 2024-01-01")
+}
+
+// newGrowthCommand creates the growth report of pt report
+func newGrowthCommand(writer io.Writer) *cobra.Command {
+	c := &growthCommand{logger: Logger, out: utils.NewOutput(io.Discard, &utils.Styler{}, false)}
+
+	var cmd = &cobra.Command{
+		Use:   "growth",
+		Short: "pt report growth shows the growth of the pairtree each month from the snapshots of its size",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return utils.ConfigureLogger(cmd, &c.logger)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+
+			c.out = utils.OutputFromFlags(cmd, writer)
+
+			if c.ptRoot, err = utils.GetPtRoot(cmd, writer); err != nil {
+				return err
+			}
+
+			if len(args) > 0 {
+				c.out.Error("Too many arguments were provided to %s", "pt report growth")
+				c.logger.Error("Error parsing pt report growth", zap.Error(error_msgs.Err8))
+
+				return error_msgs.Err8
+			}
+
+			var since time.Time
+			if c.since != "" {
+				if since, err = time.Parse(sinceLayout, c.since); err != nil {
+					err = fmt.Errorf("%w: --since must be a date like 2024-01-01: %w", error_msgs.Err17, err)
+					c.logger.Error("Error parsing pt report growth", zap.Error(err))
+
+					return err
+				}
+			}
+
+			if c.key, err = utils.SignKeyFromFlags(cmd); err != nil {
+				c.logger.Error("Error reading the signing key", zap.Error(err))
+				return err
+			} else if c.key != nil && !c.snapshot {
+				err = fmt.Errorf("%w: --sign-key needs --snapshot", error_msgs.Err17)
+				c.logger.Error("Error parsing pt report growth", zap.Error(err))
+
+				return err
+			}
+
+			jsonFlag, _ := cmd.Flags().GetBool(utils.JSONFlag)
+
+			utils.SilenceUsage(cmd)
+
+			if c.snapshot {
+				return c.record(cmd.Context())
+			}
+
+			return c.report(writer, since, jsonFlag)
+		},
+	}
+
+	c.initFlags(cmd)
+	utils.AddSignKeyFlag(cmd)
+
+	return cmd
+}
+
+// record appends a snapshot of the current size of the pairtree to its snapshots file, and signs the file
+// with --sign-key
+func (c *growthCommand) record(ctx context.Context) error {
+	prefix, err := reportPrefix(c.ptRoot, c.logger)
+	if err != nil {
+		return err
+	}
+
+	snapshot := Snapshot{Time: time.Now().UTC()}
+
+	err = pairtree.WalkObjectsCtx(ctx, c.ptRoot, prefix, func(id, objPath string) error {
+		_, bytes, _, err := measure(ctx, objPath)
+		if err != nil {
+			return &error_msgs.PtError{ID: id, Path: objPath, Err: err}
+		}
+
+		snapshot.Objects++
+		snapshot.Bytes += bytes
+		return nil
+	})
+	if err != nil {
+		c.logger.Error("Error measuring the pairtree", zap.Error(err))
+		return err
+	}
+
+	if err := appendSnapshot(c.ptRoot, snapshot); err != nil {
+		c.logger.Error("Error recording the snapshot", zap.Error(err))
+		return err
+	}
+
+	c.out.Success("Recorded a snapshot of %d objects with %d bytes", snapshot.Objects, snapshot.Bytes)
+
+	// The signature covers every snapshot recorded so far, so it is made again with each one
+	if c.key != nil {
+		return utils.SignFile(c.key, filepath.Join(c.ptRoot, SnapshotsFile), c.out, c.logger)
+	}
+
+	return nil
+}
+
+// report writes the growth of the pairtree in each month since the date that has snapshots
+func (c *growthCommand) report(writer io.Writer, since time.Time, outputJSON bool) error {
+	if _, err := reportPrefix(c.ptRoot, c.logger); err != nil {
+		return err
+	}
+
+	snapshots, err := readSnapshots(c.ptRoot)
+	if err != nil {
+		c.logger.Error("Error reading the snapshots", zap.Error(err))
+		return err
+	}
+
+	if len(snapshots) == 0 {
+		c.logger.Warn("No snapshots have been recorded, record them with pt report growth --snapshot")
+	}
+
+	growth := monthlyGrowth(snapshots, since)
+
+	if outputJSON {
+		jsonData, err := json.MarshalIndent(growth, "", "  ")
+		if err != nil {
+			c.logger.Error("Error converting the growth to JSON", zap.Error(err))
+			return err
+		}
+		fmt.Fprintln(writer, string(jsonData))
+		return nil
+	}
+
+	csvWriter := csv.NewWriter(writer)
+	if err := csvWriter.Write(growthHeader); err != nil {
+		return err
+	}
+	for _, month := range growth {
+		if err := csvWriter.Write(month.row()); err != nil {
+			return err
+		}
+	}
+
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// monthlyGrowth returns the growth of each month since the date that has snapshots. The growth of the
+// first of those months is measured from the last snapshot before it, or from its own first snapshot
+// when there is none.
+func monthlyGrowth(snapshots []Snapshot, since time.Time) []Growth {
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		return snapshots[i].Time.Before(snapshots[j].Time)
+	})
+
+	growth := []Growth{}
+	var previous *Snapshot
+
+	for i := range snapshots {
+		snapshot := snapshots[i]
+		month := snapshot.Time.UTC().Format(monthLayout)
+		lastOfMonth := i == len(snapshots)-1 || snapshots[i+1].Time.UTC().Format(monthLayout) != month
+
+		if snapshot.Time.Before(since) {
+			if lastOfMonth {
+				previous = &snapshots[i]
+			}
+			continue
+		}
+
+		if previous == nil {
+			previous = &snapshots[i]
+		}
+
+		if lastOfMonth {
+			growth = append(growth, Growth{
+				Month:        month,
+				Objects:      snapshot.Objects,
+				Bytes:        snapshot.Bytes,
+				ObjectGrowth: snapshot.Objects - previous.Objects,
+				ByteGrowth:   snapshot.Bytes - previous.Bytes,
+			})
+			previous = &snapshots[i]
+		}
+	}
+
+	return growth
+}
+
+// appendSnapshot appends the snapshot to the snapshots file of the pairtree
+func appendSnapshot(ptRoot string, snapshot Snapshot) error {
+	line, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(filepath.Join(ptRoot, SnapshotsFile), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+
+	// The line is written in one call so that snapshots recorded at the same time are not interleaved
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// readSnapshots returns the snapshots recorded for the pairtree, a pairtree without a snapshots file
+// has none
+func readSnapshots(ptRoot string) ([]Snapshot, error) {
+	path := filepath.Join(ptRoot, SnapshotsFile)
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return []Snapshot{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	snapshots := []Snapshot{}
+	scanner := bufio.NewScanner(file)
+
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var snapshot Snapshot
+		if err := json.Unmarshal(scanner.Bytes(), &snapshot); err != nil {
+			return nil, fmt.Errorf("%w: %s line %d: %v", error_msgs.Err36, path, line, err)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	return snapshots, scanner.Err()
+}
+
+// row returns the growth as a row of the growth CSV
+func (g Growth) row() []string {
+	return []string{g.Month, strconv.Itoa(g.Objects), strconv.FormatInt(g.Bytes, 10),
+		strconv.Itoa(g.ObjectGrowth), strconv.FormatInt(g.ByteGrowth, 10)}
+}
@@ -0,0 +1,194 @@
+package ptreport
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pairtree"
+	"github.com/UCLALibrary/pt-tools/pkg/premis"
+	"github.com/UCLALibrary/pt-tools/utils"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// inventoryHeader is the header row of the inventory CSV, in the order of the InventoryItem fields
+var inventoryHeader = []string{"id", "path", "files", "bytes", "modified", "fixity", "fixity_checked"}
+
+// InventoryItem is an object in the inventory, with the outcome and time of its latest fixity check
+// if one has been recorded in its event history
+type InventoryItem struct {
+	ID            string     `json:"id"`
+	Path          string     `json:"path"`
+	Files         int        `json:"files"`
+	Bytes         int64      `json:"bytes"`
+	Modified      time.Time  `json:"modified"`
+	Fixity        string     `json:"fixity,omitempty"`
+	FixityChecked *time.Time `json:"fixity_checked,omitempty"`
+}
+
+// inventoryCommand holds the flags and arguments of one run of pt report inventory
+type inventoryCommand struct {
+	ptRoot string
+	logger *zap.Logger
+	out    *utils.Output
+}
+
+// newInventoryCommand creates the inventory report of pt report
+func newInventoryCommand(writer io.Writer) *cobra.Command {
+	c := &inventoryCommand{logger: Logger, out: utils.NewOutput(io.Discard, &utils.Styler{}, false)}
+
+	var cmd = &cobra.Command{
+		Use:   "inventory",
+		Short: "pt report inventory lists every object with its files, size, and latest fixity check",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return utils.ConfigureLogger(cmd, &c.logger)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+
+			c.out = utils.OutputFromFlags(cmd, writer)
+
+			if c.ptRoot, err = utils.GetPtRoot(cmd, writer); err != nil {
+				return err
+			}
+
+			if len(args) > 0 {
+				c.out.Error("Too many arguments were provided to %s", "pt report inventory")
+				c.logger.Error("Error parsing pt report inventory", zap.Error(error_msgs.Err8))
+
+				return error_msgs.Err8
+			}
+
+			jsonFlag, _ := cmd.Flags().GetBool(utils.JSONFlag)
+
+			utils.SilenceUsage(cmd)
+
+			return c.report(cmd.Context(), writer, jsonFlag)
+		},
+	}
+
+	return cmd
+}
+
+// report writes the inventory of the pairtree to the writer, as CSV rows while the pairtree is
+// walked or as a JSON array once it has been
+func (c *inventoryCommand) report(ctx context.Context, writer io.Writer, outputJSON bool) error {
+	prefix, err := reportPrefix(c.ptRoot, c.logger)
+	if err != nil {
+		return err
+	}
+
+	csvWriter := csv.NewWriter(writer)
+	if !outputJSON {
+		if err := csvWriter.Write(inventoryHeader); err != nil {
+			return err
+		}
+	}
+
+	items := []InventoryItem{}
+	err = pairtree.WalkObjectsCtx(ctx, c.ptRoot, prefix, func(id, objPath string) error {
+		item, err := c.inventory(ctx, prefix, id, objPath)
+		if err != nil {
+			return &error_msgs.PtError{ID: id, Path: objPath, Err: err}
+		}
+
+		if outputJSON {
+			items = append(items, item)
+			return nil
+		}
+
+		return csvWriter.Write(item.row())
+	})
+	if err != nil {
+		c.logger.Error("Error taking the inventory of the pairtree", zap.Error(err))
+		return err
+	}
+
+	if outputJSON {
+		jsonData, err := json.MarshalIndent(items, "", "  ")
+		if err != nil {
+			c.logger.Error("Error converting the inventory to JSON", zap.Error(err))
+			return err
+		}
+		fmt.Fprintln(writer, string(jsonData))
+		return nil
+	}
+
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// inventory counts the files of the object and finds its latest fixity check
+func (c *inventoryCommand) inventory(ctx context.Context, prefix, id, objPath string) (InventoryItem, error) {
+	item := InventoryItem{ID: id, Path: objPath}
+
+	var err error
+	if item.Files, item.Bytes, item.Modified, err = measure(ctx, objPath); err != nil {
+		return item, err
+	}
+
+	events, err := premis.Events(c.ptRoot, prefix, id)
+	if err != nil {
+		return item, err
+	}
+
+	for i := len(events) - 1; i >= 0; i-- {
+		if events[i].Type == premis.FixityCheck {
+			checked := events[i].DateTime.UTC()
+			item.Fixity, item.FixityChecked = events[i].Outcome, &checked
+			break
+		}
+	}
+
+	return item, nil
+}
+
+// measure returns the number of files in the object, their total bytes, and when the object was last
+// modified. The object directory is included so an object whose files were deleted shows when it changed.
+func measure(ctx context.Context, objPath string) (files int, bytes int64, modified time.Time, err error) {
+	err = filepath.WalkDir(objPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		if info.ModTime().After(modified) {
+			modified = info.ModTime()
+		}
+		if info.Mode().IsRegular() {
+			files++
+			bytes += info.Size()
+		}
+
+		return nil
+	})
+
+	return files, bytes, modified.UTC(), err
+}
+
+// row returns the item as a row of the inventory CSV, an object that has never had its fixity
+// checked has empty fixity columns
+func (i InventoryItem) row() []string {
+	checked := ""
+	if i.FixityChecked != nil {
+		checked = i.FixityChecked.Format(time.RFC3339)
+	}
+
+	return []string{i.ID, i.Path, strconv.Itoa(i.Files), strconv.FormatInt(i.Bytes, 10),
+		i.Modified.Format(time.RFC3339), i.Fixity, checked}
+}
@@ -0,0 +1,79 @@
+package ptreport
+
+/* ptreport groups the reports that describe a whole pairtree for collection managers, like pt report
+inventory that lists every object with its file count, size, last modification, and latest fixity
+check, pt report formats that counts the files and bytes of each file format, pt report duplicates
+that finds content stored in more than one object, and pt report growth that shows how the pairtree
+grew each month. Reports are written as CSV for spreadsheets, or as JSON with --json for dashboards. */
+
+import (
+	"fmt"
+	"io"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pairtree"
+	"github.com/UCLALibrary/pt-tools/utils"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	// Logger is the logger each run of pt report starts from, tests replace it to capture the logs
+	Logger *zap.Logger = utils.ConsoleLogger()
+)
+
+// NewCommand creates the report subcommand of pt, with a subcommand for each report, that writes
+// its output to the writer
+func NewCommand(writer io.Writer) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "report [report]",
+		Short: "pt report writes reports that describe the whole pairtree",
+		// Only runs when no report or an unknown one is given
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				return fmt.Errorf("%w: unknown report %q for %q", error_msgs.Err17, args[0], cmd.CommandPath())
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return fmt.Errorf("%w: a report must be provided", error_msgs.Err17)
+		},
+	}
+
+	cmd.AddCommand(newInventoryCommand(writer), newFormatsCommand(writer), newDuplicatesCommand(writer),
+		newGrowthCommand(writer))
+
+	return cmd
+}
+
+// Run executes pt report with the given arguments, the first of which is the report
+func Run(args []string, writer io.Writer) error {
+	if err := utils.RunSubcommand(NewCommand(writer), args, writer); err != nil {
+		Logger.Error("Error running pt report", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// reportPrefix checks the pairtree at the root and returns its prefix
+func reportPrefix(ptRoot string, logger *zap.Logger) (string, error) {
+	// check if the pairtree version file exists and is populated
+	if err := pairtree.CheckPTVer(ptRoot); err != nil {
+		logger.Error("Error with pairtree veresion file", zap.Error(err))
+		return "", err
+	}
+
+	// Get the prefix from pairtree_prefix file
+	prefix, err := pairtree.GetPrefix(ptRoot)
+	if err != nil {
+		logger.Error("Error retrieving prefix from pairtree_prefix file", zap.Error(err))
+		return "", err
+	}
+
+	if prefix == "" {
+		prefix = pairtree.PtPrefix
+	}
+
+	return prefix, nil
+}
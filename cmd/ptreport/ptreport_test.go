@@ -0,0 +1,367 @@
+package ptreport
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/UCLALibrary/pt-tools/pkg/audit"
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/premis"
+	"github.com/UCLALibrary/pt-tools/pkg/pttest"
+	"github.com/UCLALibrary/pt-tools/utils"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	root = "--pairtree="
+)
+
+// TestMain runs the tests with the logger of the test sink, it is set once so that the tests can run in parallel
+func TestMain(m *testing.M) {
+	logger, cleanup := pttest.SetupLogger()
+	Logger = logger
+
+	code := m.Run()
+	cleanup()
+	os.Exit(code)
+}
+
+// newInventoryPairtree builds a pairtree with three objects, the second of which has had its fixity checked twice
+func newInventoryPairtree(t *testing.T) (*pttest.PairtreeBuilder, string) {
+	builder := pttest.NewPairtreeBuilder().
+		WithFile("ark:/a5388", "a5388.txt", []byte("12345")).
+		WithFile("ark:/b5488", "outer.txt", []byte("123")).
+		WithFile("ark:/b5488", "folder/inner.txt", []byte("1234567")).
+		WithObject("ark:/c5488", "empty/")
+	ptRoot := builder.BuildTemp(t, afero.NewOsFs())
+
+	// The checks are recorded the way pt checksum records them, the second finding a file that changed
+	out := utils.NewOutput(io.Discard, utils.NewStyler(io.Discard, true), true)
+	utils.RecordFixityCheck(ptRoot, "ark:/", "ark:/b5488", "checked against manifest-sha256.txt", nil, out, Logger)
+	utils.RecordFixityCheck(ptRoot, "ark:/", "ark:/b5488", "checked against manifest-sha256.txt",
+		fmt.Errorf("%w: outer.txt", error_msgs.Err49), out, Logger)
+
+	return builder, ptRoot
+}
+
+// TestInventoryCSV tests if every object is listed in the CSV with its files, size, and latest fixity check
+func TestInventoryCSV(t *testing.T) {
+	builder, ptRoot := newInventoryPairtree(t)
+
+	var buf bytes.Buffer
+	err := Run([]string{"inventory", root + ptRoot}, &buf)
+	require.NoError(t, err)
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 4)
+	assert.Equal(t, inventoryHeader, rows[0])
+
+	assert.Equal(t, []string{"ark:/a5388", builder.ObjectPath(ptRoot, "ark:/a5388"), "1", "5"}, rows[1][:4])
+	assert.Equal(t, []string{"", ""}, rows[1][5:])
+	assert.NotEmpty(t, rows[1][4])
+
+	assert.Equal(t, []string{"ark:/b5488", builder.ObjectPath(ptRoot, "ark:/b5488"), "2", "10"}, rows[2][:4])
+	assert.Equal(t, premis.Failure, rows[2][5])
+	assert.NotEmpty(t, rows[2][6])
+
+	assert.Equal(t, []string{"ark:/c5488", builder.ObjectPath(ptRoot, "ark:/c5488"), "0", "0"}, rows[3][:4])
+}
+
+// TestInventoryJSON tests if the inventory is written as a JSON array with --json
+func TestInventoryJSON(t *testing.T) {
+	_, ptRoot := newInventoryPairtree(t)
+
+	var buf bytes.Buffer
+	err := Run([]string{"inventory", root + ptRoot, "--json"}, &buf)
+	require.NoError(t, err)
+
+	var items []InventoryItem
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &items))
+	require.Len(t, items, 3)
+
+	assert.Equal(t, "ark:/b5488", items[1].ID)
+	assert.Equal(t, 2, items[1].Files)
+	assert.Equal(t, int64(10), items[1].Bytes)
+	assert.False(t, items[1].Modified.IsZero())
+	assert.Equal(t, premis.Failure, items[1].Fixity)
+	assert.NotNil(t, items[1].FixityChecked)
+
+	assert.Empty(t, items[0].Fixity)
+	assert.Nil(t, items[0].FixityChecked)
+}
+
+// TestInventoryEmpty tests if an empty pairtree has an inventory with no objects
+func TestInventoryEmpty(t *testing.T) {
+	ptRoot := pttest.NewPairtreeBuilder().BuildTemp(t, afero.NewOsFs())
+
+	var buf bytes.Buffer
+	require.NoError(t, Run([]string{"inventory", root + ptRoot, "--json"}, &buf))
+	assert.JSONEq(t, "[]", buf.String())
+}
+
+// TestCLIError tests if an error is thrown when the arguments are not valid
+func TestCLIError(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		expectErr error
+	}{
+		{name: "No report", args: []string{root + "root"}, expectErr: error_msgs.Err17},
+		{name: "Unknown report", args: []string{"inventroy", root + "root"}, expectErr: error_msgs.Err17},
+		{name: "No pairtree root provided", args: []string{"inventory"}, expectErr: error_msgs.Err7},
+		{name: "Too many arguments passed in", args: []string{"inventory", root + "root", "ark:/a5388"}, expectErr: error_msgs.Err8},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+
+			err := Run(test.args, &buf)
+			assert.ErrorIs(t, err, test.expectErr)
+		})
+	}
+}
+
+// TestFormats tests if the files are counted by format and formats that were not recognized are flagged
+func TestFormats(t *testing.T) {
+	ptRoot := pttest.NewPairtreeBuilder().
+		WithFile("ark:/a5388", "data.json", []byte("{}")).
+		WithFile("ark:/a5388", "notes.zzz", []byte("plain text")).
+		WithFile("ark:/a5388", ".hidden.json", []byte("{}")).
+		WithFile("ark:/b5488", "more.JSON", []byte("[1, 2]")).
+		WithFile("ark:/b5488", "blob.zzz", []byte{0x00, 0x01, 0x02, 0xff}).
+		BuildTemp(t, afero.NewOsFs())
+
+	tests := []struct {
+		name          string
+		args          []string
+		expectFormats []Format
+	}{
+		{name: "visible files", expectFormats: []Format{
+			{MimeType: "text/plain; charset=utf-8", Extension: ".zzz", Files: 1, Bytes: 10, Identified: true},
+			{MimeType: "application/json", Extension: ".json", Files: 2, Bytes: 8, Identified: true},
+			{MimeType: "application/octet-stream", Extension: ".zzz", Files: 1, Bytes: 4, Identified: false},
+		}},
+		{name: "hidden files", args: []string{"-a"}, expectFormats: []Format{
+			{MimeType: "application/json", Extension: ".json", Files: 3, Bytes: 10, Identified: true},
+			{MimeType: "text/plain; charset=utf-8", Extension: ".zzz", Files: 1, Bytes: 10, Identified: true},
+			{MimeType: "application/octet-stream", Extension: ".zzz", Files: 1, Bytes: 4, Identified: false},
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			err := Run(append([]string{"formats", root + ptRoot, "--json"}, test.args...), &buf)
+			require.NoError(t, err)
+
+			var formats []Format
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &formats))
+			assert.Equal(t, test.expectFormats, formats)
+		})
+	}
+}
+
+// TestFormatsCSV tests if the formats are written as CSV
+func TestFormatsCSV(t *testing.T) {
+	ptRoot := pttest.NewPairtreeBuilder().WithFile("ark:/a5388", "blob", []byte{0x00, 0xff}).BuildTemp(t, afero.NewOsFs())
+
+	var buf bytes.Buffer
+	require.NoError(t, Run([]string{"formats", root + ptRoot}, &buf))
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{formatsHeader, {"application/octet-stream", "", "1", "2", "false"}}, rows)
+}
+
+// TestDuplicates tests if content that is in more than one object is listed with the bytes it wastes
+func TestDuplicates(t *testing.T) {
+	ptRoot := pttest.NewPairtreeBuilder().
+		WithFile("ark:/a5388", "image.tif", []byte("same image")).
+		WithFile("ark:/a5388", "copy/image.tif", []byte("same image")).
+		WithFile("ark:/a5388", "notes.txt", []byte("only here!")).
+		WithFile("ark:/a5388", "empty.txt", []byte{}).
+		WithFile("ark:/b5488", "scan.tif", []byte("same image")).
+		WithFile("ark:/b5488", "empty.txt", []byte{}).
+		WithFile("ark:/b5488", ".hidden.txt", []byte("hi")).
+		WithFile("ark:/c5488", "a.txt", []byte("hi")).
+		WithFile("ark:/c5488", "b.txt", []byte("ho")).
+		BuildTemp(t, afero.NewOsFs())
+
+	tests := []struct {
+		name         string
+		args         []string
+		expectWasted int64
+		expectFiles  [][]DuplicateFile
+	}{
+		{name: "visible files", expectWasted: 20, expectFiles: [][]DuplicateFile{
+			{{ID: "ark:/a5388", Path: "copy/image.tif"}, {ID: "ark:/a5388", Path: "image.tif"},
+				{ID: "ark:/b5488", Path: "scan.tif"}},
+		}},
+		{name: "hidden files", args: []string{"-a"}, expectWasted: 22, expectFiles: [][]DuplicateFile{
+			{{ID: "ark:/a5388", Path: "copy/image.tif"}, {ID: "ark:/a5388", Path: "image.tif"},
+				{ID: "ark:/b5488", Path: "scan.tif"}},
+			{{ID: "ark:/b5488", Path: ".hidden.txt"}, {ID: "ark:/c5488", Path: "a.txt"}},
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			err := Run(append([]string{"duplicates", root + ptRoot, "--json"}, test.args...), &buf)
+			require.NoError(t, err)
+
+			var result Duplicates
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &result))
+			assert.Equal(t, test.expectWasted, result.WastedBytes)
+
+			require.Len(t, result.Duplicates, len(test.expectFiles))
+			for i, files := range test.expectFiles {
+				assert.Equal(t, files, result.Duplicates[i].Files)
+			}
+			assert.Equal(t, int64(10), result.Duplicates[0].Bytes)
+			assert.Equal(t, int64(20), result.Duplicates[0].WastedBytes)
+		})
+	}
+}
+
+// TestDuplicatesCSV tests if each copy of duplicated content is a row of the CSV
+func TestDuplicatesCSV(t *testing.T) {
+	ptRoot := pttest.NewPairtreeBuilder().
+		WithFile("ark:/a5388", "a.txt", []byte("hello\n")).
+		WithFile("ark:/b5488", "b.txt", []byte("hello\n")).
+		BuildTemp(t, afero.NewOsFs())
+
+	var buf bytes.Buffer
+	require.NoError(t, Run([]string{"duplicates", root + ptRoot}, &buf))
+
+	checksum := "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"
+	rows, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		duplicatesHeader,
+		{checksum, "6", "2", "6", "ark:/a5388", "a.txt"},
+		{checksum, "6", "2", "6", "ark:/b5488", "b.txt"},
+	}, rows)
+}
+
+// TestMonthlyGrowth tests if the growth of each month is measured from the last snapshot of the month before
+func TestMonthlyGrowth(t *testing.T) {
+	snapshot := func(date string, objects int, bytes int64) Snapshot {
+		snapshotTime, err := time.Parse(time.RFC3339, date)
+		require.NoError(t, err)
+		return Snapshot{Time: snapshotTime, Objects: objects, Bytes: bytes}
+	}
+
+	snapshots := []Snapshot{
+		snapshot("2024-01-15T00:00:00Z", 10, 1000),
+		snapshot("2023-12-31T00:00:00Z", 8, 800),
+		snapshot("2024-01-31T00:00:00Z", 12, 1500),
+		snapshot("2024-03-01T00:00:00Z", 15, 2000),
+		snapshot("2024-03-31T00:00:00Z", 14, 1900),
+	}
+
+	tests := []struct {
+		name         string
+		since        string
+		expectGrowth []Growth
+	}{
+		{name: "all months", expectGrowth: []Growth{
+			{Month: "2023-12", Objects: 8, Bytes: 800},
+			{Month: "2024-01", Objects: 12, Bytes: 1500, ObjectGrowth: 4, ByteGrowth: 700},
+			{Month: "2024-03", Objects: 14, Bytes: 1900, ObjectGrowth: 2, ByteGrowth: 400},
+		}},
+		{name: "since", since: "2024-01-01", expectGrowth: []Growth{
+			{Month: "2024-01", Objects: 12, Bytes: 1500, ObjectGrowth: 4, ByteGrowth: 700},
+			{Month: "2024-03", Objects: 14, Bytes: 1900, ObjectGrowth: 2, ByteGrowth: 400},
+		}},
+		{name: "since without earlier snapshots", since: "2024-02-01", expectGrowth: []Growth{
+			{Month: "2024-03", Objects: 14, Bytes: 1900, ObjectGrowth: 2, ByteGrowth: 400},
+		}},
+		{name: "no snapshots since", since: "2025-01-01", expectGrowth: []Growth{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var since time.Time
+			if test.since != "" {
+				var err error
+				since, err = time.Parse(sinceLayout, test.since)
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, test.expectGrowth, monthlyGrowth(append([]Snapshot{}, snapshots...), since))
+		})
+	}
+}
+
+// TestGrowth tests if snapshots are recorded and reported
+func TestGrowth(t *testing.T) {
+	ptRoot := pttest.NewPairtreeBuilder().
+		WithFile("ark:/a5388", "a5388.txt", []byte("12345")).
+		WithFile("ark:/b5488", "b5488.txt", []byte("123")).
+		BuildTemp(t, afero.NewOsFs())
+
+	var buf bytes.Buffer
+	require.NoError(t, Run([]string{"growth", root + ptRoot, "--snapshot"}, &buf))
+	assert.Contains(t, buf.String(), "2 objects with 8 bytes")
+
+	buf.Reset()
+	require.NoError(t, Run([]string{"growth", root + ptRoot}, &buf))
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{growthHeader, {time.Now().UTC().Format(monthLayout), "2", "8", "0", "0"}}, rows)
+}
+
+// TestGrowthSign tests if the snapshots file is signed again with each snapshot recorded with --sign-key
+func TestGrowthSign(t *testing.T) {
+	ptRoot := pttest.NewPairtreeBuilder().WithFile("ark:/a5388", "a5388.txt", []byte("12345")).
+		BuildTemp(t, afero.NewOsFs())
+	key, keyPath, _ := pttest.SigningKey(t, t.TempDir())
+	snapshots := filepath.Join(ptRoot, SnapshotsFile)
+
+	var buf bytes.Buffer
+	for range 2 {
+		require.NoError(t, Run([]string{"growth", root + ptRoot, "--snapshot", "--sign-key", keyPath}, &buf))
+		_, err := audit.VerifyFile(snapshots, key.Public().(ed25519.PublicKey))
+		require.NoError(t, err)
+	}
+	assert.Contains(t, buf.String(), "Signed "+snapshots+" in "+snapshots+audit.SignatureExt)
+
+	// The key only signs snapshots
+	err := Run([]string{"growth", root + ptRoot, "--sign-key", keyPath}, &buf)
+	assert.ErrorIs(t, err, error_msgs.Err17)
+}
+
+// TestGrowthError tests if an invalid date or snapshots file is an error
+func TestGrowthError(t *testing.T) {
+	ptRoot := pttest.NewPairtreeBuilder().BuildTemp(t, afero.NewOsFs())
+
+	var buf bytes.Buffer
+	err := Run([]string{"growth", root + ptRoot, "--since=January"}, &buf)
+	assert.ErrorIs(t, err, error_msgs.Err17)
+
+	require.NoError(t, os.WriteFile(filepath.Join(ptRoot, SnapshotsFile), []byte("not json\n"), 0644))
+	err = Run([]string{"growth", root + ptRoot}, &buf)
+	assert.ErrorIs(t, err, error_msgs.Err36)
+}
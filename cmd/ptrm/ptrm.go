@@ -2,16 +2,21 @@ package ptrm
 
 /*ptrm is a rm-like tool that can delete things from within a Pairtree object or
 remove a Pairtree object altogether. There is also the ability to delete files and
-directories in the object as long as the subpath to that file or directory is provided. */
+directories in the object as long as the subpath to that file or directory is provided.
+More than one object can be removed at once by giving their IDs, or by reading them from a file
+or standard input with --ids-from. */
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"io"
-	"os"
-	"path/filepath"
+	"io/fs"
+	"slices"
+	"strings"
 
 	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
 	"github.com/UCLALibrary/pt-tools/pkg/pairtree"
+	"github.com/UCLALibrary/pt-tools/pkg/premis"
 	"github.com/UCLALibrary/pt-tools/utils"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
@@ -25,114 +30,207 @@ type FileInfo struct {
 }
 
 var (
-	ptRoot  string
-	logFile string      = "logs.log"
-	Logger  *zap.Logger = utils.Logger(logFile)
-	id      string      = ""
-	subpath string      = ""
+	// Logger is the logger each run of pt rm starts from, tests replace it to capture the logs
+	Logger *zap.Logger = utils.ConsoleLogger()
 )
 
-func initFlags(cmd *cobra.Command) {
-	cmd.Flags().StringVarP(&ptRoot, "pairtree", "p", "", "Set pairtree root directory")
-
+// command holds the flags and arguments of one run of pt rm so that runs can happen concurrently
+type command struct {
+	dryRun bool
+	ptRoot string
+	args   []string
+	ids    []string
+	logger *zap.Logger
+	out    *utils.Output
 }
 
-func Run(args []string, writer io.Writer) error {
-	var err error
-	var pairPath string
+func (c *command) initFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&c.dryRun, "dry-run", false, "Print what would be deleted without deleting anything")
+}
 
-	var rootCmd = &cobra.Command{
-		Use:   "pt rm -p [PT_ROOT] [ID] [subpath/to/file.txt]",
-		Short: "pt rm is a tool to remove Pairtree objects, files, and directores",
+// NewCommand creates the rm subcommand of pt that writes its output to the writer
+func NewCommand(writer io.Writer) *cobra.Command {
+	c := &command{logger: Logger, out: utils.NewOutput(io.Discard, &utils.Styler{}, false)}
+
+	var cmd = &cobra.Command{
+		Use:               "rm [ID] [subpath/to/file.txt] | rm [ID]...",
+		Short:             "pt rm is a tool to remove Pairtree objects, files, and directores",
+		ValidArgsFunction: utils.CompleteIDs,
+		Annotations:       map[string]string{utils.S3Annotation: "true"},
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return utils.ConfigureLogger(cmd, &c.logger)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			// If the root has not been set yet check the ENV vars
-			if ptRoot == "" {
-
-				if envVar := os.Getenv("PAIRTREE_ROOT"); envVar != "" {
-					ptRoot = envVar
-				} else {
-					fmt.Fprintln(writer, error_msgs.Err7)
-					return error_msgs.Err7
-				}
+			var err error
+
+			c.out = utils.OutputFromFlags(cmd, writer)
+			defer c.out.Close()
+
+			if c.ptRoot, err = utils.GetPtRoot(cmd, writer); err != nil {
+				return err
+			}
+
+			if c.ids, err = utils.IDsFromFlags(cmd); err != nil {
+				c.logger.Error("Error reading IDs", zap.Error(err))
+				return err
 			}
 
-			numArgs := len(args)
-			if numArgs < 1 {
-				fmt.Fprintln(writer, "Please provide an ID for the pairtree")
-				Logger.Error("Error getting ID",
+			if len(args) < 1 && len(c.ids) == 0 {
+				c.out.Error("Please provide an ID for the pairtree")
+				c.logger.Error("Error getting ID",
 					zap.Error(error_msgs.Err6))
 
 				return error_msgs.Err6
 			}
 
-			if numArgs == 1 {
-				// Extract the ID from the final argument
-				id = args[numArgs-1]
-			} else if numArgs == 2 {
-				// Extract the ID and the subpath from the arguments
-				id = args[numArgs-2]
-				subpath = args[numArgs-1]
-			} else {
-				fmt.Fprintln(writer, "Too many arguments were provided to ptrm")
-				Logger.Error("Error parsing ptrm",
-					zap.Error(error_msgs.Err8))
-
-				return error_msgs.Err8
-			}
+			// Whether the arguments are IDs or an ID and a subpath is known once the prefix is read
+			c.args = args
 
-			Logger.Info("Pairtree root is",
-				zap.String("PAIRTREE_ROOT", ptRoot),
+			c.logger.Info("Pairtree root is",
+				zap.String("PAIRTREE_ROOT", c.ptRoot),
 			)
 
-			return nil
+			utils.SilenceUsage(cmd)
+
+			return c.removeAll(cmd.Context())
 		},
 	}
 
-	initFlags(rootCmd)
-	rootCmd.SetOut(writer)
-	rootCmd.SetErr(writer)
-	rootCmd.SetArgs(args)
+	c.initFlags(cmd)
+	utils.AddIDsFromFlag(cmd)
+	utils.AddInteractiveFlag(cmd)
 
-	utils.ApplyExitOnHelp(rootCmd, 0)
+	return cmd
+}
 
-	if err = rootCmd.Execute(); err != nil {
-		Logger.Error("Error setting command line",
+// Run executes pt rm with the given arguments
+func Run(args []string, writer io.Writer) error {
+	if err := utils.RunSubcommand(NewCommand(writer), args, writer); err != nil {
+		Logger.Error("Error running pt rm",
 			zap.Error(err))
 		return err
 	}
 
-	// check if the pairtree version file exists and is populated
-	if err := pairtree.CheckPTVer(ptRoot); err != nil {
-		Logger.Error("Error with pairtree veresion file", zap.Error(err))
+	return nil
+}
+
+// removeAll deletes the subpath of the object, or each of the objects when every argument is an ID. An
+// object that can not be deleted does not stop the others, and its error is returned with theirs.
+func (c *command) removeAll(ctx context.Context) error {
+	// Open the pairtree, which checks its version file and reads its prefix
+	pt, err := pairtree.Open(c.ptRoot)
+	if err != nil {
+		c.logger.Error("Error opening the pairtree", zap.Error(err))
 		return err
 	}
 
-	// Get the prefix from pairtree_prefix file
-	prefix, err := pairtree.GetPrefix(ptRoot)
+	// The first argument is always an ID, and the rest are IDs as well or the one subpath. Objects are
+	// only removed whole when IDs are read with --ids-from.
+	ids, subpath := c.args, ""
+	if len(c.ids) > 0 {
+		ids = append(slices.Clip(c.args), c.ids...)
+	} else if len(c.args) > 1 && !allIDs(c.args[1:], pt.Prefix()) {
+		if len(c.args) > 2 {
+			c.out.Error("Too many arguments were provided to %s", "ptrm")
+			c.logger.Error("Error parsing ptrm",
+				zap.Error(error_msgs.Err8))
+
+			return error_msgs.Err8
+		}
+
+		// Extract the ID and the subpath from the arguments
+		ids, subpath = c.args[:1], c.args[1]
+	}
 
-	if err != nil {
-		Logger.Error("Error retrieving prefix from pairtree_prefix file", zap.Error(err))
-		return err
+	if len(ids) == 1 {
+		return c.remove(ctx, pt, ids[0], subpath)
+	}
+
+	var errs []error
+	for _, id := range ids {
+		// Stop before the next object once the context is canceled
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+
+		errs = append(errs, c.remove(ctx, pt, id, ""))
 	}
 
-	if prefix == "" {
-		prefix = pairtree.PtPrefix
+	return errors.Join(errs...)
+}
+
+// allIDs reports whether every argument is an ID, which starts with the prefix of the pairtree
+func allIDs(args []string, prefix string) bool {
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, prefix) {
+			return false
+		}
 	}
+
+	return true
+}
+
+// remove deletes the pairtree object with the ID or the subpath within it
+func (c *command) remove(ctx context.Context, pt *pairtree.Pairtree, id, subpath string) (err error) {
 	// create the pairpath
-	pairPath, err = pairtree.CreatePP(id, ptRoot, prefix)
+	pairPath, err := pt.PairPath(id)
+	if err != nil {
+		c.logger.Error("Error creating pairpath", zap.Error(err))
+		return &error_msgs.PtError{ID: id, Err: err}
+	}
 
+	// The subpath can not reach outside of the object
+	fullPath, err := pairtree.JoinSubpath(pairPath, subpath)
 	if err != nil {
-		Logger.Error("Error creating pairpath", zap.Error(err))
-		return err
+		c.logger.Error("Error joining the subpath", zap.Error(err))
+		return &error_msgs.PtError{ID: id, Path: subpath, Err: err}
 	}
 
-	fullPath := filepath.Join(pairPath, subpath)
-	if err := pairtree.DeletePairtreeItem(fullPath); err != nil {
-		Logger.Error("Error deleting pairpath", zap.Error(err))
+	storage, err := pairtree.StorageFor(ctx, fullPath)
+	if err != nil {
+		c.logger.Error("Error opening the storage of the pairtree", zap.Error(err))
 		return err
 	}
 
-	fmt.Printf("Successfully deleted: %s\n", fullPath)
+	// A dry run reports what would be deleted, failing like a deletion would when it does not exist
+	if c.dryRun {
+		if _, err := storage.Stat(fullPath); errors.Is(err, fs.ErrNotExist) {
+			return &error_msgs.PtError{ID: id, Path: fullPath, Err: err}
+		}
+
+		c.out.DryRun("Would delete %s", fullPath)
+		return nil
+	}
+
+	if _, err := storage.Stat(fullPath); err == nil {
+		// Deleting a whole object can not be undone so it is confirmed first, as is deleting anything with -i
+		if subpath == "" {
+			if err := c.out.Confirm("Delete the pairtree object %s and everything in it?", id); err != nil {
+				return &error_msgs.PtError{ID: id, Path: fullPath, Err: err}
+			}
+		} else if c.out.Interactive() {
+			if err := c.out.Confirm("Delete %s?", fullPath); err != nil {
+				return &error_msgs.PtError{ID: id, Path: fullPath, Err: err}
+			}
+		}
+
+		// Deleting what is in the pairtree is kept in the object's event history
+		detail := "deleted the object"
+		if subpath != "" {
+			detail = "deleted " + subpath
+		}
+		defer func() {
+			utils.RecordEvent(c.ptRoot, pt.Prefix(), premis.NewEvent(premis.Deletion, id, detail, err), c.out, c.logger)
+		}()
+	}
+
+	if err := pt.DeletePairtreeItem(fullPath); err != nil {
+		c.logger.Error("Error deleting pairpath", zap.Error(err))
+		return &error_msgs.PtError{ID: id, Path: fullPath, Err: err}
+	}
+
+	c.out.Success("Successfully deleted: %s", fullPath)
 
 	return nil
 }
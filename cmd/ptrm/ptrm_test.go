@@ -1,17 +1,24 @@
 package ptrm
 
-// The test-dir that is copied and used throughout this test. Both the pairtree_version0_1
+// The pairtree built by pttest.StandardPairtree is used throughout this test. Both the pairtree_version0_1
 // and the pairtree_prefix are populated. The pairtree_prefix is populated with the prefix ark:/
 // unless the test removes or changes that.
 import (
 	"bytes"
+	"context"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
-	"github.com/UCLALibrary/pt-tools/testutils"
+	"github.com/UCLALibrary/pt-tools/pkg/pairtree"
+	"github.com/UCLALibrary/pt-tools/pkg/premis"
+	"github.com/UCLALibrary/pt-tools/pkg/pttest"
+	"github.com/UCLALibrary/pt-tools/utils"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const (
@@ -19,6 +26,16 @@ const (
 	root    = "--pairtree="
 )
 
+// TestMain runs the tests with the logger of the test sink, it is set once so that the tests can run in parallel
+func TestMain(m *testing.M) {
+	logger, cleanup := pttest.SetupLogger()
+	Logger = logger
+
+	code := m.Run()
+	cleanup()
+	os.Exit(code)
+}
+
 // TestDelete tests if objects, files, and directories are deleted by ptrm
 func TestDelete(t *testing.T) {
 	tests := []struct {
@@ -33,27 +50,206 @@ func TestDelete(t *testing.T) {
 		{id: "tooManyArgs", path: []string{"ark:/idNotExist", "folder", "toomanyargs"}, expectedError: error_msgs.Err8},
 	}
 
-	// Create a logger instance using the registered sink.
-	logger, cleanup := testutils.SetupLogger(logFile)
-	defer cleanup()
-	Logger = logger
-
 	for _, test := range tests {
 		t.Run(test.id, func(t *testing.T) {
+			t.Parallel()
+
 			fs := afero.NewOsFs()
-			tempDir := testutils.CreateTempDir(t, fs)
-			testutils.CopyTestDirectory(t, testutils.TestPairtree, tempDir)
+			tempDir := pttest.CreateTempDir(t, fs)
+			pttest.StandardPairtree().Build(t, fs, tempDir)
 
-			args := append([]string{root + tempDir}, test.path...)
+			args := append([]string{root + tempDir, "--yes"}, test.path...)
 			var buf bytes.Buffer
 
 			err := Run(args, &buf)
 			assert.ErrorIs(t, err, test.expectedError)
+
+			// The success message is written to the writer rather than to stdout
+			assert.Equal(t, test.expectedError == nil, strings.Contains(buf.String(), "Successfully deleted"))
+
+			// A deletion is recorded in the object's events, nothing is recorded for an object that does not exist
+			events, err := premis.Events(tempDir, "ark:/", test.path[0])
+			require.NoError(t, err)
+			if test.expectedError == nil {
+				require.Len(t, events, 1)
+				assert.Equal(t, premis.Deletion, events[0].Type)
+				assert.Equal(t, premis.Success, events[0].Outcome)
+			} else {
+				assert.Empty(t, events)
+			}
 		})
 	}
 
 }
 
+// TestDeleteMany tests that each of the objects is deleted and that one that does not exist does not stop the others
+func TestDeleteMany(t *testing.T) {
+	fs := afero.NewOsFs()
+	tempDir := pttest.CreateTempDir(t, fs)
+	pttest.StandardPairtree().Build(t, fs, tempDir)
+
+	var buf bytes.Buffer
+	err := Run([]string{root + tempDir, "--yes", "ark:/a5388", "ark:/idNotExist", "ark:/b5488"}, &buf)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	var ptErr *error_msgs.PtError
+	require.ErrorAs(t, err, &ptErr)
+	assert.Equal(t, "ark:/idNotExist", ptErr.ID)
+
+	for _, id := range []string{"ark:/a5388", "ark:/b5488"} {
+		pairPath, err := pairtree.CreatePP(id, tempDir, "ark:/")
+		require.NoError(t, err)
+		assert.NoDirExists(t, pairPath)
+		assert.Contains(t, buf.String(), "Successfully deleted: "+pairPath)
+
+		events, err := premis.Events(tempDir, "ark:/", id)
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		assert.Equal(t, premis.Deletion, events[0].Type)
+	}
+
+	// The other object of the pairtree is kept
+	pairPath, err := pairtree.CreatePP("ark:/a54892", tempDir, "ark:/")
+	require.NoError(t, err)
+	assert.DirExists(t, pairPath)
+}
+
+// TestDeleteIDsFrom tests that the objects with the IDs of a file are deleted whole
+func TestDeleteIDsFrom(t *testing.T) {
+	fs := afero.NewOsFs()
+	tempDir := pttest.CreateTempDir(t, fs)
+	pttest.StandardPairtree().Build(t, fs, tempDir)
+
+	idsFile := filepath.Join(t.TempDir(), "ids.txt")
+	require.NoError(t, os.WriteFile(idsFile, []byte("ark:/a5388\nark:/b5488\n"), 0644))
+
+	var buf bytes.Buffer
+	require.NoError(t, Run([]string{root + tempDir, "--yes", "--ids-from", idsFile}, &buf))
+
+	for _, id := range []string{"ark:/a5388", "ark:/b5488"} {
+		pairPath, err := pairtree.CreatePP(id, tempDir, "ark:/")
+		require.NoError(t, err)
+		assert.NoDirExists(t, pairPath)
+	}
+
+	pairPath, err := pairtree.CreatePP("ark:/a54892", tempDir, "ark:/")
+	require.NoError(t, err)
+	assert.DirExists(t, pairPath)
+}
+
+// TestDryRun tests that what would be deleted is printed without deleting it or asking to
+func TestDryRun(t *testing.T) {
+	fs := afero.NewOsFs()
+	tempDir := pttest.CreateTempDir(t, fs)
+	pttest.StandardPairtree().Build(t, fs, tempDir)
+
+	objPath, err := pairtree.CreatePP("ark:/b5488", tempDir, "ark:/")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, Run([]string{root + tempDir, "--quiet", "--dry-run", "ark:/a5388", "ark:/b5488"}, &buf))
+	assert.Contains(t, buf.String(), "Would delete "+objPath+"\n")
+	assert.NotContains(t, buf.String(), "Delete the pairtree object")
+	assert.DirExists(t, objPath)
+
+	buf.Reset()
+	require.NoError(t, Run([]string{root + tempDir, "--dry-run", "ark:/b5488", "folder"}, &buf))
+	assert.Contains(t, buf.String(), "Would delete "+filepath.Join(objPath, "folder")+"\n")
+	assert.DirExists(t, filepath.Join(objPath, "folder"))
+
+	// Nothing that was not deleted is recorded in the event history
+	events, err := premis.Events(tempDir, "ark:/", "ark:/b5488")
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	err = Run([]string{root + tempDir, "--dry-run", "ark:/b5488", "missing.txt"}, &buf)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+// TestSubpathOutside tests that a subpath that reaches outside of the object deletes nothing
+func TestSubpathOutside(t *testing.T) {
+	fs := afero.NewOsFs()
+	tempDir := pttest.CreateTempDir(t, fs)
+	pttest.StandardPairtree().Build(t, fs, tempDir)
+
+	for _, subpath := range []string{"../../..", "folder/../../../../..", "."} {
+		var buf bytes.Buffer
+		err := Run([]string{root + tempDir, "--yes", "ark:/b5488", subpath}, &buf)
+		assert.ErrorIs(t, err, error_msgs.Err46)
+		assert.Equal(t, utils.ExitUsage, utils.ExitCode(err))
+
+		var ptErr *error_msgs.PtError
+		require.ErrorAs(t, err, &ptErr)
+		assert.Equal(t, "ark:/b5488", ptErr.ID)
+	}
+
+	for _, id := range []string{"ark:/a5388", "ark:/a54892", "ark:/b5488"} {
+		pairPath, err := pairtree.CreatePP(id, tempDir, "ark:/")
+		require.NoError(t, err)
+		assert.DirExists(t, pairPath)
+	}
+}
+
+// TestConfirm tests if deleting a whole object is only done once it is confirmed
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		name          string
+		answer        string
+		expectDeleted bool
+		expectedError error
+	}{
+		{name: "yes", answer: "y\n", expectDeleted: true, expectedError: nil},
+		{name: "no", answer: "n\n", expectDeleted: false, expectedError: error_msgs.Err23},
+		{name: "default", answer: "\n", expectDeleted: false, expectedError: error_msgs.Err23},
+		{name: "no answer", answer: "", expectDeleted: false, expectedError: error_msgs.Err23},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			fs := afero.NewOsFs()
+			tempDir := pttest.CreateTempDir(t, fs)
+			pttest.StandardPairtree().Build(t, fs, tempDir)
+			objPath := filepath.Join(tempDir, "pairtree_root", "a5", "38", "8", "a5388")
+
+			var buf bytes.Buffer
+			args := []string{root + tempDir, "ark:/a5388"}
+
+			err := utils.RunSubcommandWithInput(NewCommand(&buf), args, strings.NewReader(test.answer), &buf)
+			assert.ErrorIs(t, err, test.expectedError)
+			assert.Contains(t, buf.String(), "Delete the pairtree object ark:/a5388")
+
+			exists, err := afero.DirExists(fs, objPath)
+			require.NoError(t, err)
+			assert.Equal(t, test.expectDeleted, !exists)
+		})
+	}
+}
+
+// TestBatch tests that a whole object is deleted without a prompt when the input is not a terminal, like
+// when pt rm is run from a script
+func TestBatch(t *testing.T) {
+	fs := afero.NewOsFs()
+	tempDir := pttest.CreateTempDir(t, fs)
+	pttest.StandardPairtree().Build(t, fs, tempDir)
+
+	input, err := os.Open(os.DevNull)
+	require.NoError(t, err)
+	defer input.Close()
+
+	var buf bytes.Buffer
+	args := []string{root + tempDir, "ark:/a5388", "ark:/b5488"}
+	require.NoError(t, utils.RunSubcommandWithInput(NewCommand(&buf), args, input, &buf))
+	assert.NotContains(t, buf.String(), "Delete the pairtree object")
+
+	for _, id := range []string{"ark:/a5388", "ark:/b5488"} {
+		pairPath, err := pairtree.CreatePP(id, tempDir, "ark:/")
+		require.NoError(t, err)
+		assert.NoDirExists(t, pairPath)
+	}
+}
+
 // TestCLIError tests if an error is thrown when various CLI options are missing
 func TestCLIError(t *testing.T) {
 	tests := []struct {
@@ -65,13 +261,10 @@ func TestCLIError(t *testing.T) {
 		{name: "No pairtree root provided", args: []string{"ID"}, expectErr: error_msgs.Err7},
 	}
 
-	// Create a logger instance using the registered sink.
-	logger, cleanup := testutils.SetupLogger(logFile)
-	defer cleanup()
-	Logger = logger
-
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
 			var buf bytes.Buffer
 
 			err := Run(test.args, &buf)
@@ -80,3 +273,45 @@ func TestCLIError(t *testing.T) {
 	}
 
 }
+
+// TestS3 tests if objects, files, and directories are deleted from a pairtree in S3
+func TestS3(t *testing.T) {
+	tests := []struct {
+		id      string
+		path    []string
+		removed string
+	}{
+		{id: "object", path: []string{"ark:/a54892"}, removed: "pt/pairtree_root/a5/48/92/a54892/"},
+		{id: "directory", path: []string{"ark:/b5488", "folder"}, removed: "pt/pairtree_root/b5/48/8/b5488/folder/"},
+		{id: "file", path: []string{"ark:/a5388", "a5388.txt"}, removed: "pt/pairtree_root/a5/38/8/a5388/a5388.txt"},
+	}
+
+	fake := pttest.NewFakeS3()
+	newClient := pairtree.NewS3Client
+	pairtree.NewS3Client = func(context.Context) (pairtree.S3Client, error) { return fake, nil }
+	t.Cleanup(func() { pairtree.NewS3Client = newClient })
+
+	for _, test := range tests {
+		t.Run(test.id, func(t *testing.T) {
+			fake.Upload(t, pttest.StandardPairtree().BuildTemp(t, afero.NewOsFs()), "s3://bucket/pt")
+
+			var buf bytes.Buffer
+			err := Run(append([]string{root + "s3://bucket/pt", "--yes"}, test.path...), &buf)
+			require.NoError(t, err)
+			assert.Contains(t, buf.String(), "Successfully deleted")
+
+			for _, key := range fake.Keys("bucket") {
+				assert.False(t, strings.HasPrefix(key, test.removed), key)
+			}
+
+			events, err := premis.Events("s3://bucket/pt", "ark:/", test.path[0])
+			require.NoError(t, err)
+			require.Len(t, events, 1)
+			assert.Equal(t, premis.Deletion, events[0].Type)
+		})
+	}
+
+	var buf bytes.Buffer
+	err := Run([]string{root + "s3://bucket/pt", "--yes", "ark:/idNotExist"}, &buf)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,132 @@
+package ptselfupdate
+
+/* ptselfupdate replaces the running pt with the latest release from GitHub, for servers where
+pt was not installed with a package manager. The release archive is checked against the release
+checksums before the binary is replaced. */
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/selfupdate"
+	"github.com/UCLALibrary/pt-tools/utils"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	// Logger is the logger each run of pt self-update starts from, tests replace it to capture the logs
+	Logger  *zap.Logger            = utils.ConsoleLogger()
+	updater *selfupdate.Updater    = selfupdate.New()
+	exePath func() (string, error) = os.Executable
+)
+
+// command holds the flags of one run of pt self-update so that runs can happen concurrently
+type command struct {
+	checkOnly bool
+	force     bool
+	logger    *zap.Logger
+	out       *utils.Output
+}
+
+func (c *command) initFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(&c.checkOnly, "check", "c", false, "Only report if a newer release is available")
+	cmd.Flags().BoolVarP(&c.force, "force", "f", false, "Install the latest release even if it is not newer")
+}
+
+// NewCommand creates the self-update subcommand of pt that writes its output to the writer
+func NewCommand(writer io.Writer) *cobra.Command {
+	c := &command{logger: Logger, out: utils.NewOutput(io.Discard, &utils.Styler{}, false)}
+
+	var cmd = &cobra.Command{
+		Use:   "self-update [FLAGS]",
+		Short: "pt self-update replaces pt with the latest release",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return utils.ConfigureLogger(cmd, &c.logger)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c.out = utils.OutputFromFlags(cmd, writer)
+
+			if len(args) > 0 {
+				c.out.Error("Too many arguments were provided to %s", "pt self-update")
+				c.logger.Error("Error parsing pt self-update", zap.Error(error_msgs.Err8))
+
+				return error_msgs.Err8
+			}
+
+			utils.SilenceUsage(cmd)
+
+			return c.update(cmd, writer)
+		},
+	}
+
+	c.initFlags(cmd)
+
+	return cmd
+}
+
+// Run executes pt self-update with the given arguments
+func Run(args []string, writer io.Writer) error {
+	if err := utils.RunSubcommand(NewCommand(writer), args, writer); err != nil {
+		Logger.Error("Error running pt self-update", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// update replaces the running binary with the latest release when it is newer
+func (c *command) update(cmd *cobra.Command, writer io.Writer) error {
+	release, err := updater.Latest(cmd.Context())
+	if err != nil {
+		c.logger.Error("Error finding the latest release", zap.Error(err))
+		return err
+	}
+
+	c.logger.Info("Latest release is", zap.String("version", release.TagName),
+		zap.String("current", utils.Version))
+
+	newer := selfupdate.IsNewer(release.TagName, utils.Version)
+
+	if c.checkOnly {
+		if newer {
+			c.out.Warning("pt %s is available, %s is installed", release.TagName, utils.Version)
+		} else {
+			c.out.Info("pt %s is the latest release", utils.Version)
+		}
+		return nil
+	}
+
+	if !newer && !c.force {
+		c.out.Info("pt %s is the latest release", utils.Version)
+		return nil
+	}
+
+	binary, err := updater.Download(cmd.Context(), release)
+	if err != nil {
+		c.logger.Error("Error downloading the release", zap.Error(err))
+		return err
+	}
+
+	path, err := exePath()
+	if err != nil {
+		return err
+	}
+
+	// Replace the binary a symlink points to rather than the symlink
+	if path, err = filepath.EvalSymlinks(path); err != nil {
+		return err
+	}
+
+	if err := selfupdate.Replace(path, binary); err != nil {
+		c.logger.Error("Error replacing the pt binary", zap.Error(err), zap.String("path", path))
+		return &error_msgs.PtError{Path: path, Err: err}
+	}
+
+	c.logger.Info("pt was updated", zap.String("version", release.TagName), zap.String("path", path))
+	c.out.Success("pt was updated to %s", release.TagName)
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package ptselfupdate
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pttest"
+	"github.com/UCLALibrary/pt-tools/pkg/selfupdate"
+	"github.com/UCLALibrary/pt-tools/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMain runs the tests with the logger of the test sink, it is set once so that the tests can run in parallel
+func TestMain(m *testing.M) {
+	logger, cleanup := pttest.SetupLogger()
+	Logger = logger
+
+	code := m.Run()
+	cleanup()
+	os.Exit(code)
+}
+
+// setupRelease serves a v1.2.0 release of a linux/amd64 binary and installs an old binary to update
+func setupRelease(t *testing.T) string {
+	var archive bytes.Buffer
+	gzipWriter := gzip.NewWriter(&archive)
+	tarWriter := tar.NewWriter(gzipWriter)
+	require.NoError(t, tarWriter.WriteHeader(&tar.Header{Name: "pt", Mode: 0755, Size: 13}))
+	_, err := tarWriter.Write([]byte("new pt binary"))
+	require.NoError(t, err)
+	require.NoError(t, tarWriter.Close())
+	require.NoError(t, gzipWriter.Close())
+
+	archiveName := selfupdate.ArchiveName("linux", "amd64")
+	sum := sha256.Sum256(archive.Bytes())
+	checksums := []byte(fmt.Sprintf("%s  %s\n", hex.EncodeToString(sum[:]), archiveName))
+
+	// The release is signed with a key of the test, which the updater trusts instead of the pt release key
+	publicKey, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	mux.HandleFunc("/latest", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(selfupdate.Release{
+			TagName: "v1.2.0",
+			Assets: []selfupdate.Asset{
+				{Name: archiveName, URL: server.URL + "/archive"},
+				{Name: selfupdate.ChecksumsAsset, URL: server.URL + "/checksums"},
+				{Name: selfupdate.SignatureAsset, URL: server.URL + "/signature"},
+			},
+		})
+	})
+	mux.HandleFunc("/archive", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(archive.Bytes())
+	})
+	mux.HandleFunc("/checksums", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(checksums)
+	})
+	mux.HandleFunc("/signature", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(ed25519.Sign(key, checksums))
+	})
+
+	updater = &selfupdate.Updater{Client: server.Client(), ReleaseURL: server.URL + "/latest", GOOS: "linux", GOARCH: "amd64",
+		PublicKey: publicKey}
+	t.Cleanup(func() { updater = selfupdate.New() })
+
+	binPath := filepath.Join(t.TempDir(), "pt")
+	require.NoError(t, os.WriteFile(binPath, []byte("old pt binary"), 0755))
+
+	exePath = func() (string, error) { return binPath, nil }
+	t.Cleanup(func() { exePath = os.Executable })
+
+	return binPath
+}
+
+// TestSelfUpdate tests if pt is only replaced by a newer release unless it is forced
+func TestSelfUpdate(t *testing.T) {
+	tests := []struct {
+		name     string
+		current  string
+		args     []string
+		expected string
+	}{
+		{name: "newer release", current: "v1.1.0", args: []string{}, expected: "new pt binary"},
+		{name: "check only", current: "v1.1.0", args: []string{"--check"}, expected: "old pt binary"},
+		{name: "up to date", current: "v1.2.0", args: []string{}, expected: "old pt binary"},
+		{name: "forced", current: "v1.2.0", args: []string{"--force"}, expected: "new pt binary"},
+	}
+
+	defer func(version string) { utils.Version = version }(utils.Version)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			binPath := setupRelease(t)
+			utils.Version = test.current
+
+			err := Run(test.args, &buf)
+			require.NoError(t, err)
+
+			contents, err := os.ReadFile(binPath)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, string(contents))
+		})
+	}
+}
+
+// TestCLIError tests if an error is thrown when too many arguments are passed
+func TestCLIError(t *testing.T) {
+	var buf bytes.Buffer
+	err := Run([]string{"extra"}, &buf)
+	assert.ErrorIs(t, err, error_msgs.Err8)
+}
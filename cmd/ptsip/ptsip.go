@@ -0,0 +1,213 @@
+package ptsip
+
+/* ptsip packages a Pairtree object as a zipped submission information package for repository
+ingest, with the files of the object in objects/, a SHA-256 checksum manifest, and a metadata stub.
+The stub is a Dublin Core record unless a template is given with --template. The package is written
+to the destination directory, or the current directory, and is named like the archives of pt cp. */
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/UCLALibrary/pt-tools/pkg/checksum"
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pairtree"
+	"github.com/UCLALibrary/pt-tools/pkg/sip"
+	"github.com/UCLALibrary/pt-tools/utils"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	// Logger is the logger each run of pt sip starts from, tests replace it to capture the logs
+	Logger *zap.Logger = utils.ConsoleLogger()
+)
+
+// command holds the flags and arguments of one run of pt sip so that runs can happen concurrently
+type command struct {
+	showAll   bool
+	template  string
+	ptRoot    string
+	id        string
+	dest      string
+	hashOpts  checksum.Options
+	warmCache bool
+	logger    *zap.Logger
+	out       *utils.Output
+}
+
+func (c *command) initFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(&c.showAll, "a", "a", false, "package hidden files and directories")
+	cmd.Flags().StringVar(&c.template, "template", "", "Go template for the metadata stub, which is named after the template without .tmpl")
+	cmd.Flags().IntVar(&c.hashOpts.IOLimit, "io-limit", 0, "Reads from files that happen at once while hashing them (defaults to one per CPU)")
+	cmd.Flags().BoolVar(&c.warmCache, "warm-cache", false, "Hash files as they are zipped and reuse the checksums for the manifest, reading each file once")
+}
+
+// NewCommand creates the sip subcommand of pt that writes its output to the writer
+func NewCommand(writer io.Writer) *cobra.Command {
+	c := &command{logger: Logger, out: utils.NewOutput(io.Discard, &utils.Styler{}, false)}
+
+	var cmd = &cobra.Command{
+		Use:               "sip [ID] [/path/to/destination]",
+		Short:             "pt sip packages a Pairtree object as a zipped submission information package",
+		ValidArgsFunction: utils.CompleteIDs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return utils.ConfigureLogger(cmd, &c.logger)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+
+			c.out = utils.OutputFromFlags(cmd, writer)
+
+			if c.ptRoot, err = utils.GetPtRoot(cmd, writer); err != nil {
+				return err
+			}
+
+			if len(args) < 1 {
+				c.out.Error("Please provide an ID for the pairtree")
+				c.logger.Error("Error getting ID", zap.Error(error_msgs.Err6))
+
+				return error_msgs.Err6
+			} else if len(args) > 2 {
+				c.out.Error("Too many arguments were provided to %s", "pt sip")
+				c.logger.Error("Error parsing pt sip", zap.Error(error_msgs.Err8))
+
+				return error_msgs.Err8
+			}
+			c.id = args[0]
+
+			c.dest = "."
+			if len(args) == 2 {
+				c.dest = args[1]
+			}
+
+			if c.hashOpts.IOLimit < 0 {
+				err := fmt.Errorf("%w: --io-limit must not be negative", error_msgs.Err17)
+				c.logger.Error("Error parsing pt sip", zap.Error(err))
+
+				return err
+			}
+
+			if c.warmCache {
+				c.hashOpts.Cache = checksum.NewCache()
+			}
+
+			utils.SilenceUsage(cmd)
+
+			return c.pack(cmd.Context())
+		},
+	}
+
+	c.initFlags(cmd)
+
+	return cmd
+}
+
+// Run executes pt sip with the given arguments
+func Run(args []string, writer io.Writer) error {
+	if err := utils.RunSubcommand(NewCommand(writer), args, writer); err != nil {
+		Logger.Error("Error running pt sip", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// pack writes the package of the object into the destination directory
+func (c *command) pack(ctx context.Context) error {
+	// check if the pairtree version file exists and is populated
+	if err := pairtree.CheckPTVer(c.ptRoot); err != nil {
+		c.logger.Error("Error with pairtree veresion file", zap.Error(err))
+		return err
+	}
+
+	// Get the prefix from pairtree_prefix file
+	prefix, err := pairtree.GetPrefix(c.ptRoot)
+	if err != nil {
+		c.logger.Error("Error retrieving prefix from pairtree_prefix file", zap.Error(err))
+		return err
+	}
+
+	if prefix == "" {
+		prefix = pairtree.PtPrefix
+	}
+
+	pairPath, err := pairtree.CreatePP(c.id, c.ptRoot, prefix)
+	if err != nil {
+		c.logger.Error("Error creating pairpath", zap.Error(err))
+		return &error_msgs.PtError{ID: c.id, Err: err}
+	}
+
+	if _, err := os.Stat(pairPath); err != nil {
+		c.logger.Error("Error reading the object", zap.Error(err))
+		return &error_msgs.PtError{ID: c.id, Path: pairPath, Err: err}
+	}
+
+	tmpl, metadataName, err := c.metadataTemplate()
+	if err != nil {
+		c.logger.Error("Error reading the metadata template", zap.String("template", c.template), zap.Error(err))
+		return err
+	}
+
+	// The package is named like the archives pt cp writes, after the encoded prefix and ID
+	zipPath := filepath.Join(c.dest, pairtree.ArchiveName(prefix, pairPath, ".zip"))
+
+	if err := os.MkdirAll(c.dest, 0755); err != nil {
+		c.logger.Error("Error creating the destination directory", zap.Error(err))
+		return err
+	}
+	zipPath = pairtree.GetUniqueDestination(zipPath)
+
+	if err := c.write(ctx, zipPath, pairPath, tmpl, metadataName); err != nil {
+		c.logger.Error("Error packaging the object", zap.Error(err))
+		return &error_msgs.PtError{ID: c.id, Path: pairPath, Err: err}
+	}
+
+	c.out.Success("Packaged %s as %s", c.id, zipPath)
+	c.logger.Info("Packaged the object", zap.String("id", c.id), zap.String("package", zipPath))
+
+	return nil
+}
+
+// write writes the package to zipPath, removing what was written if packaging fails
+func (c *command) write(ctx context.Context, zipPath, pairPath string, tmpl *template.Template,
+	metadataName string) (err error) {
+	out, err := os.Create(zipPath)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		err = errors.Join(err, out.Close())
+		if err != nil {
+			err = errors.Join(err, os.Remove(zipPath))
+		}
+	}()
+
+	return sip.Write(ctx, out, c.id, filepath.Base(pairPath), pairPath, "pt "+utils.Version, tmpl, metadataName, c.showAll, c.hashOpts)
+}
+
+// metadataTemplate returns the template of the metadata stub and the name of the stub, which is the
+// name of the template file without .tmpl
+func (c *command) metadataTemplate() (*template.Template, string, error) {
+	if c.template == "" {
+		tmpl, err := sip.ParseTemplate(sip.DefaultMetadataName, sip.DefaultTemplate)
+		return tmpl, sip.DefaultMetadataName, err
+	}
+
+	text, err := os.ReadFile(c.template)
+	if err != nil {
+		return nil, "", err
+	}
+
+	name := strings.TrimSuffix(filepath.Base(c.template), ".tmpl")
+	tmpl, err := sip.ParseTemplate(name, string(text))
+
+	return tmpl, name, err
+}
@@ -0,0 +1,159 @@
+package ptsip
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pttest"
+	"github.com/UCLALibrary/pt-tools/pkg/sip"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	root = "--pairtree="
+)
+
+// TestMain runs the tests with the logger of the test sink, it is set once so that the tests can run in parallel
+func TestMain(m *testing.M) {
+	logger, cleanup := pttest.SetupLogger()
+	Logger = logger
+
+	code := m.Run()
+	cleanup()
+	os.Exit(code)
+}
+
+// zipNames returns the names of the files in the zip
+func zipNames(t *testing.T, path string) []string {
+	reader, err := zip.OpenReader(path)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	var names []string
+	for _, file := range reader.File {
+		names = append(names, file.Name)
+	}
+
+	return names
+}
+
+// TestSip tests if the object is packaged into the destination, with the metadata stub of the template
+func TestSip(t *testing.T) {
+	fs := afero.NewOsFs()
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, fs)
+
+	templateDir := pttest.CreateTempDir(t, fs)
+	template := filepath.Join(templateDir, "mods.xml.tmpl")
+	require.NoError(t, os.WriteFile(template, []byte("<mods><identifier>{{xml .ID}}</identifier></mods>\n"), 0644))
+
+	tests := []struct {
+		name        string
+		args        []string
+		expectNames []string
+	}{
+		{name: "default template", args: []string{"ark:/a54892"},
+			expectNames: []string{"a54892/objects/a54892.txt", "a54892/" + sip.DefaultMetadataName,
+				"a54892/" + sip.ManifestName}},
+		{name: "hidden files", args: []string{"ark:/a54892", "-a"},
+			expectNames: []string{"a54892/objects/.hidden/innerHidden.txt", "a54892/objects/.hidden.txt",
+				"a54892/objects/a54892.txt", "a54892/" + sip.DefaultMetadataName, "a54892/" + sip.ManifestName}},
+		{name: "custom template", args: []string{"ark:/a54892", "--template=" + template},
+			expectNames: []string{"a54892/objects/a54892.txt", "a54892/mods.xml", "a54892/" + sip.ManifestName}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			dest := t.TempDir()
+
+			var buf bytes.Buffer
+			err := Run(append([]string{root + ptRoot}, append(test.args, dest)...), &buf)
+			require.NoError(t, err)
+
+			zipPath := filepath.Join(dest, "ark+=a54892.zip")
+			assert.Contains(t, buf.String(), zipPath)
+			assert.Equal(t, test.expectNames, zipNames(t, zipPath))
+		})
+	}
+}
+
+// TestSipExisting tests if an existing package is not overwritten
+func TestSipExisting(t *testing.T) {
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, afero.NewOsFs())
+	dest := t.TempDir()
+
+	var buf bytes.Buffer
+	require.NoError(t, Run([]string{root + ptRoot, "ark:/a5388", dest}, &buf))
+	require.NoError(t, Run([]string{root + ptRoot, "ark:/a5388", dest}, &buf))
+
+	entries, err := os.ReadDir(dest)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+// TestSipError tests if nothing is written when the object can not be packaged
+func TestSipError(t *testing.T) {
+	fs := afero.NewOsFs()
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, fs)
+
+	templateDir := pttest.CreateTempDir(t, fs)
+	badTemplate := filepath.Join(templateDir, "bad.xml.tmpl")
+	require.NoError(t, os.WriteFile(badTemplate, []byte("{{.Missing}}"), 0644))
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "object does not exist", args: []string{"ark:/z9999"}},
+		{name: "template does not exist", args: []string{"ark:/a5388", "--template=" + filepath.Join(templateDir, "none")}},
+		{name: "template fails", args: []string{"ark:/a5388", "--template=" + badTemplate}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			dest := t.TempDir()
+
+			var buf bytes.Buffer
+			err := Run(append([]string{root + ptRoot}, append(test.args, dest)...), &buf)
+			assert.Error(t, err)
+
+			entries, err := os.ReadDir(dest)
+			require.NoError(t, err)
+			assert.Empty(t, entries)
+		})
+	}
+}
+
+// TestCLIError tests if an error is thrown when the arguments are not valid
+func TestCLIError(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		expectErr error
+	}{
+		{name: "No ID", args: []string{root + "root"}, expectErr: error_msgs.Err6},
+		{name: "No pairtree root provided", args: []string{"ark:/a5388"}, expectErr: error_msgs.Err7},
+		{name: "Too many arguments passed in", args: []string{root + "root", "ark:/a5388", "dest", "extra"}, expectErr: error_msgs.Err8},
+		{name: "Negative I/O limit", args: []string{root + "root", "ark:/a5388", "--io-limit=-1"}, expectErr: error_msgs.Err17},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+
+			err := Run(test.args, &buf)
+			assert.ErrorIs(t, err, test.expectErr)
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package ptstat
+
+/* ptstat describes a Pairtree object without reading the content of its files: the pairpath its ID
+resolves to, the number of its files and directories, the total size of its files, the modification
+times of its newest and oldest files, and the fixity manifests pt checksum -w wrote into it. It is
+written as lines of text, or as JSON with -j, for services that check on an object before fetching it. */
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/UCLALibrary/pt-tools/pkg/checksum"
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pairtree"
+	"github.com/UCLALibrary/pt-tools/utils"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	// Logger is the logger each run of pt stat starts from, tests replace it to capture the logs
+	Logger *zap.Logger = utils.ConsoleLogger()
+)
+
+// Stat is what pt stat reports of an object. Hidden files and directories are counted with the others,
+// and the modification times are left out of an object without files.
+type Stat struct {
+	ID          string     `json:"id"`
+	PairPath    string     `json:"pairPath"`
+	Files       int        `json:"files"`
+	Directories int        `json:"directories"`
+	Size        int64      `json:"size"`
+	Newest      *time.Time `json:"newest,omitempty"`
+	Oldest      *time.Time `json:"oldest,omitempty"`
+	Manifests   []string   `json:"manifests"`
+}
+
+// command holds the flags and arguments of one run of pt stat so that runs can happen concurrently
+type command struct {
+	outputJSON bool
+	ptRoot     string
+	id         string
+	logger     *zap.Logger
+	out        *utils.Output
+}
+
+func (c *command) initFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(&c.outputJSON, "j", "j", false, "output in JSON format")
+}
+
+// NewCommand creates the stat subcommand of pt that writes its output to the writer
+func NewCommand(writer io.Writer) *cobra.Command {
+	c := &command{logger: Logger, out: utils.NewOutput(io.Discard, &utils.Styler{}, false)}
+
+	var cmd = &cobra.Command{
+		Use:               "stat [FLAGS] [ID]",
+		Short:             "pt stat describes the files, size, and manifests of a Pairtree object",
+		ValidArgsFunction: utils.CompleteIDs,
+		Annotations:       map[string]string{utils.S3Annotation: "true"},
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return utils.ConfigureLogger(cmd, &c.logger)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+
+			c.out = utils.OutputFromFlags(cmd, writer)
+
+			if c.ptRoot, err = utils.GetPtRoot(cmd, writer); err != nil {
+				return err
+			}
+
+			if len(args) < 1 {
+				c.out.Error("Please provide an ID for the pairtree")
+				c.logger.Error("Error getting ID", zap.Error(error_msgs.Err6))
+
+				return error_msgs.Err6
+			} else if len(args) > 1 {
+				c.out.Error("Too many arguments were provided to %s", "pt stat")
+				c.logger.Error("Error parsing pt stat", zap.Error(error_msgs.Err8))
+
+				return error_msgs.Err8
+			}
+			c.id = args[0]
+
+			// The persistent --json flag is the same as -j
+			if jsonFlag, _ := cmd.Flags().GetBool(utils.JSONFlag); jsonFlag {
+				c.outputJSON = true
+			}
+
+			utils.SilenceUsage(cmd)
+
+			return c.stat(cmd.Context(), writer)
+		},
+	}
+
+	c.initFlags(cmd)
+
+	return cmd
+}
+
+// Run executes pt stat with the given arguments
+func Run(args []string, writer io.Writer) error {
+	if err := utils.RunSubcommand(NewCommand(writer), args, writer); err != nil {
+		Logger.Error("Error running pt stat", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// stat writes the Stat of the object to the writer
+func (c *command) stat(ctx context.Context, writer io.Writer) error {
+	// Open the pairtree, which checks its version file and reads its prefix
+	pt, err := pairtree.Open(c.ptRoot)
+	if err != nil {
+		c.logger.Error("Error opening the pairtree", zap.Error(err))
+		return err
+	}
+
+	stat, err := describe(ctx, pt, c.id)
+	if err != nil {
+		c.logger.Error("Error describing the object", zap.Error(err))
+		return &error_msgs.PtError{ID: c.id, Err: err}
+	}
+
+	if c.outputJSON {
+		jsonData, err := json.MarshalIndent(stat, "", "  ")
+		if err != nil {
+			c.logger.Error("Error converting the description to JSON", zap.Error(err))
+			return err
+		}
+		fmt.Fprintln(writer, string(jsonData))
+
+		return nil
+	}
+
+	manifests := "none"
+	if len(stat.Manifests) > 0 {
+		manifests = strings.Join(stat.Manifests, ", ")
+	}
+
+	fmt.Fprintf(writer, "ID:           %s\n", stat.ID)
+	fmt.Fprintf(writer, "Pairpath:     %s\n", stat.PairPath)
+	fmt.Fprintf(writer, "Files:        %d\n", stat.Files)
+	fmt.Fprintf(writer, "Directories:  %d\n", stat.Directories)
+	fmt.Fprintf(writer, "Size:         %d (%s)\n", stat.Size, utils.FormatSize(stat.Size))
+	if stat.Newest != nil {
+		fmt.Fprintf(writer, "Newest:       %s\n", stat.Newest.Local().Format(time.RFC3339))
+		fmt.Fprintf(writer, "Oldest:       %s\n", stat.Oldest.Local().Format(time.RFC3339))
+	}
+	fmt.Fprintf(writer, "Manifests:    %s\n", manifests)
+
+	return nil
+}
+
+// describe returns the Stat of the object with the ID, which is read a directory at a time
+func describe(ctx context.Context, pt *pairtree.Pairtree, id string) (Stat, error) {
+	pairPath, err := pt.PairPath(id)
+	if err != nil {
+		return Stat{}, err
+	}
+
+	manifestNames := map[string]bool{}
+	for _, algorithm := range checksum.Algorithms {
+		manifestNames[checksum.ManifestName(algorithm)] = true
+	}
+
+	stat := Stat{ID: id, PairPath: pairPath, Manifests: []string{}}
+	err = pt.WalkCtx(ctx, id, pairtree.ListOptions{Recursive: true, ShowAll: true}, func(entry pairtree.Entry) error {
+		if entry.IsDir() {
+			stat.Directories++
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		stat.Files++
+		stat.Size += info.Size()
+		if manifestNames[entry.Path] {
+			stat.Manifests = append(stat.Manifests, entry.Path)
+		}
+
+		modTime := info.ModTime()
+		if stat.Newest == nil || modTime.After(*stat.Newest) {
+			stat.Newest = &modTime
+		}
+		if stat.Oldest == nil || modTime.Before(*stat.Oldest) {
+			stat.Oldest = &modTime
+		}
+		return nil
+	})
+	if err != nil {
+		return Stat{}, err
+	}
+
+	// The manifests are found in the order the storage lists them
+	slices.Sort(stat.Manifests)
+	return stat, nil
+}
@@ -0,0 +1,87 @@
+package ptstat
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pairtree"
+	"github.com/UCLALibrary/pt-tools/pkg/pttest"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const root = "--pairtree="
+
+// TestMain runs the tests with the logger of the test sink, it is set once so that the tests can run in parallel
+func TestMain(m *testing.M) {
+	logger, cleanup := pttest.SetupLogger()
+	Logger = logger
+
+	code := m.Run()
+	cleanup()
+	os.Exit(code)
+}
+
+// TestStat tests that the files, size, modification times, and manifests of the object are described
+func TestStat(t *testing.T) {
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, afero.NewOsFs())
+	pt, err := pairtree.Open(ptRoot)
+	require.NoError(t, err)
+	pairPath, err := pt.PairPath("ark:/b5488")
+	require.NoError(t, err)
+
+	oldest := time.Date(2020, time.January, 2, 3, 4, 5, 0, time.UTC)
+	newest := time.Date(2024, time.March, 5, 14, 30, 0, 0, time.UTC)
+	require.NoError(t, os.WriteFile(filepath.Join(pairPath, "outerb5488.txt"), []byte("content"), 0644))
+	require.NoError(t, os.Chtimes(filepath.Join(pairPath, "outerb5488.txt"), oldest, oldest))
+	require.NoError(t, os.WriteFile(filepath.Join(pairPath, "manifest-sha256.txt"), []byte("sums"), 0644))
+	require.NoError(t, os.Chtimes(filepath.Join(pairPath, "manifest-sha256.txt"), newest, newest))
+	for _, path := range []string{"innerb5488.txt", ".hiddenFile.txt", filepath.Join(".hidden", "inner.txt")} {
+		path = filepath.Join(pairPath, "folder", path)
+		require.NoError(t, os.WriteFile(path, nil, 0644))
+		require.NoError(t, os.Chtimes(path, oldest.Add(time.Hour), oldest.Add(time.Hour)))
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, Run([]string{root + ptRoot, "-j", "ark:/b5488"}, &buf))
+
+	var stat Stat
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &stat))
+	assert.Equal(t, "ark:/b5488", stat.ID)
+	assert.Equal(t, pairPath, stat.PairPath)
+	assert.Equal(t, 5, stat.Files)
+	assert.Equal(t, 2, stat.Directories)
+	assert.Equal(t, int64(len("content")+len("sums")), stat.Size)
+	require.NotNil(t, stat.Newest)
+	assert.True(t, newest.Equal(*stat.Newest))
+	assert.True(t, oldest.Equal(*stat.Oldest))
+	assert.Equal(t, []string{"manifest-sha256.txt"}, stat.Manifests)
+
+	buf.Reset()
+	require.NoError(t, Run([]string{root + ptRoot, "ark:/a5388"}, &buf))
+	assert.Contains(t, buf.String(), "ID:           ark:/a5388\n")
+	assert.Contains(t, buf.String(), "Files:        1\n")
+	assert.Contains(t, buf.String(), "Manifests:    none\n")
+}
+
+// TestStatErrors tests that missing objects and arguments are refused
+func TestStatErrors(t *testing.T) {
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, afero.NewOsFs())
+
+	var buf bytes.Buffer
+	err := Run([]string{root + ptRoot, "ark:/notAnObject"}, &buf)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	var ptErr *error_msgs.PtError
+	require.ErrorAs(t, err, &ptErr)
+	assert.Equal(t, "ark:/notAnObject", ptErr.ID)
+
+	assert.ErrorIs(t, Run([]string{root + ptRoot}, &buf), error_msgs.Err6)
+	assert.ErrorIs(t, Run([]string{root + ptRoot, "ark:/a5388", "ark:/b5488"}, &buf), error_msgs.Err8)
+}
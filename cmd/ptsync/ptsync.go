@@ -0,0 +1,227 @@
+package ptsync
+
+/* ptsync makes a Pairtree object match a directory, or a directory match an object, like rsync. Files
+that are new are copied, files that changed in size or modification time, or in checksum with -c, are
+copied again, and with --delete what is not in the source is removed. Each change is written as it is
+made, followed by a summary of the changes, or the summary is written as JSON with -j. The files are
+compared and copied --jobs at a time. Updating an object from a directory is recorded in the object's
+event history. */
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/UCLALibrary/pt-tools/pkg/ark"
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pairtree"
+	"github.com/UCLALibrary/pt-tools/pkg/premis"
+	"github.com/UCLALibrary/pt-tools/utils"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	// Logger is the logger each run of pt sync starts from, tests replace it to capture the logs
+	Logger *zap.Logger = utils.ConsoleLogger()
+)
+
+// command holds the flags and arguments of one run of pt sync so that runs can happen concurrently
+type command struct {
+	opts       pairtree.SyncOptions
+	outputJSON bool
+	ptRoot     string
+	src        string
+	dest       string
+	resolver   *ark.Resolver
+	logger     *zap.Logger
+	out        *utils.Output
+}
+
+func (c *command) initFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(&c.opts.Checksum, "checksum", "c", false, "Compare the checksums of files of the same size instead of their modification times")
+	cmd.Flags().BoolVar(&c.opts.Delete, "delete", false, "Delete the files and folders of the destination that are not in the source")
+	cmd.Flags().BoolVar(&c.opts.DryRun, "dry-run", false, "Print what would be copied or deleted without changing anything")
+	cmd.Flags().IntVar(&c.opts.Jobs, "jobs", 1, "Files compared, copied, or deleted at once")
+	cmd.Flags().BoolVarP(&c.outputJSON, "j", "j", false, "output the summary in JSON format")
+}
+
+// NewCommand creates the sync subcommand of pt that writes its output to the writer
+func NewCommand(writer io.Writer) *cobra.Command {
+	c := &command{logger: Logger, out: utils.NewOutput(io.Discard, &utils.Styler{}, false)}
+
+	var cmd = &cobra.Command{
+		Use:               "sync [FLAGS] [SRC] [DEST]",
+		Short:             "pt sync makes a Pairtree object match a directory, or a directory match an object",
+		ValidArgsFunction: utils.CompleteIDs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return utils.ConfigureLogger(cmd, &c.logger)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+
+			c.out = utils.OutputFromFlags(cmd, writer)
+			c.resolver = utils.ResolverFromFlags(cmd)
+
+			if c.ptRoot, err = utils.GetPtRoot(cmd, writer); err != nil {
+				return err
+			}
+
+			if len(args) < 2 {
+				c.out.Error("Please provide a source and destination to sync")
+				c.logger.Error("There are not enough arguments to pt sync", zap.Error(error_msgs.Err9))
+
+				return error_msgs.Err9
+			} else if len(args) > 2 {
+				c.out.Error("Too many arguments were provided to %s", "pt sync")
+				c.logger.Error("Error parsing pt sync", zap.Error(error_msgs.Err8))
+
+				return error_msgs.Err8
+			}
+
+			if c.opts.Jobs < 1 {
+				err := fmt.Errorf("%w: --jobs must be at least 1", error_msgs.Err17)
+				c.logger.Error("Error parsing pt sync", zap.Error(err))
+
+				return err
+			}
+
+			c.src, c.dest = args[0], args[1]
+
+			// The persistent --json flag is the same as -j
+			if jsonFlag, _ := cmd.Flags().GetBool(utils.JSONFlag); jsonFlag {
+				c.outputJSON = true
+			}
+
+			utils.SilenceUsage(cmd)
+
+			return c.sync(cmd.Context(), writer)
+		},
+	}
+
+	c.initFlags(cmd)
+	utils.AddResolveFlags(cmd)
+
+	return cmd
+}
+
+// Run executes pt sync with the given arguments
+func Run(args []string, writer io.Writer) error {
+	if err := utils.RunSubcommand(NewCommand(writer), args, writer); err != nil {
+		Logger.Error("Error running pt sync", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// sync makes the destination match the source where one or both of them are objects of the pairtree
+func (c *command) sync(ctx context.Context, writer io.Writer) error {
+	// Open the pairtree, which checks its version file and reads its prefix
+	pt, err := pairtree.Open(c.ptRoot)
+	if err != nil {
+		c.logger.Error("Error opening the pairtree", zap.Error(err))
+		return err
+	}
+
+	srcID, destID := "", ""
+	if strings.HasPrefix(c.src, pt.Prefix()) {
+		srcID = c.src
+		if c.src, err = pt.PairPath(srcID); err != nil {
+			c.logger.Error("Error creating pairpath", zap.Error(err))
+			return &error_msgs.PtError{ID: srcID, Err: err}
+		}
+	}
+	if strings.HasPrefix(c.dest, pt.Prefix()) {
+		destID = c.dest
+		if c.dest, err = pt.PairPath(destID); err != nil {
+			c.logger.Error("Error creating pairpath", zap.Error(err))
+			return &error_msgs.PtError{ID: destID, Err: err}
+		}
+	}
+
+	if srcID == "" && destID == "" {
+		c.out.Error("Neither the source or destination contains a prefix and is not a part of the pairtree")
+		c.logger.Error("Error verifying source and destination", zap.Error(error_msgs.Err10))
+
+		return error_msgs.Err10
+	}
+
+	// The ID of the object that is reported with an error is the one that is changed
+	id := destID
+	if id == "" {
+		id = srcID
+	}
+
+	if destID != "" {
+		if err = utils.CheckARK(ctx, c.resolver, destID, c.out, c.logger); err != nil {
+			return &error_msgs.PtError{ID: destID, Err: err}
+		}
+	}
+
+	var report func(change pairtree.SyncChange, path string)
+	if !c.outputJSON {
+		report = c.report
+	}
+
+	result, err := pairtree.Sync(ctx, c.src, c.dest, c.opts, report)
+
+	// Updating an object is kept in its event history when anything was changed
+	changed := result.Added+result.Updated+result.Deleted > 0
+	if destID != "" && !c.opts.DryRun && (changed || err != nil) {
+		source := c.src
+		if srcID != "" {
+			source = srcID
+		}
+
+		detail := fmt.Sprintf("synced from %s: %d added, %d updated, %d deleted", source, result.Added,
+			result.Updated, result.Deleted)
+		utils.RecordEvent(c.ptRoot, pt.Prefix(), premis.NewEvent(premis.Ingestion, destID, detail, err), c.out, c.logger)
+	}
+
+	if err != nil {
+		c.logger.Error("Error syncing the source to the destination", zap.Error(err))
+		return &error_msgs.PtError{ID: id, Path: c.dest, Err: err}
+	}
+
+	if c.outputJSON {
+		jsonData, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			c.logger.Error("Error converting the summary to JSON", zap.Error(err))
+			return err
+		}
+
+		fmt.Fprintln(writer, string(jsonData))
+		return nil
+	}
+
+	if c.opts.DryRun {
+		c.out.DryRun("Would sync %s to %s: %d added, %d updated, %d deleted, %d unchanged", c.src, c.dest,
+			result.Added, result.Updated, result.Deleted, result.Unchanged)
+	} else {
+		c.out.Success("Synced %s to %s: %d added, %d updated, %d deleted, %d unchanged", c.src, c.dest,
+			result.Added, result.Updated, result.Deleted, result.Unchanged)
+	}
+
+	return nil
+}
+
+// report writes the change that is made to the destination, or that would be with --dry-run
+func (c *command) report(change pairtree.SyncChange, path string) {
+	switch {
+	case c.opts.DryRun && change == pairtree.SyncAdded:
+		c.out.DryRun("Would add %s", path)
+	case c.opts.DryRun && change == pairtree.SyncUpdated:
+		c.out.DryRun("Would update %s", path)
+	case c.opts.DryRun:
+		c.out.DryRun("Would delete %s", path)
+	case change == pairtree.SyncAdded:
+		c.out.Info("Added %s", path)
+	case change == pairtree.SyncUpdated:
+		c.out.Info("Updated %s", path)
+	default:
+		c.out.Info("Deleted %s", path)
+	}
+}
@@ -0,0 +1,106 @@
+package ptsync
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pairtree"
+	"github.com/UCLALibrary/pt-tools/pkg/premis"
+	"github.com/UCLALibrary/pt-tools/pkg/pttest"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const root = "--pairtree="
+
+// TestMain runs the tests with the logger of the test sink, it is set once so that the tests can run in parallel
+func TestMain(m *testing.M) {
+	logger, cleanup := pttest.SetupLogger()
+	Logger = logger
+
+	code := m.Run()
+	cleanup()
+	os.Exit(code)
+}
+
+// TestSync tests that an object is updated from a directory and that the changes are reported and recorded
+func TestSync(t *testing.T) {
+	fs := afero.NewOsFs()
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, fs)
+	objPath := filepath.Join(ptRoot, "pairtree_root", "b5", "48", "8", "b5488")
+
+	// The source has a new file and a changed one, and not the rest of the object
+	src := pttest.CreateTempDir(t, fs)
+	require.NoError(t, os.WriteFile(filepath.Join(src, "outerb5488.txt"), []byte("changed"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "new.txt"), []byte("new"), 0644))
+
+	var buf bytes.Buffer
+	require.NoError(t, Run([]string{root + ptRoot, "--dry-run", "--delete", src, "ark:/b5488"}, &buf))
+	assert.Contains(t, buf.String(), "Would add new.txt\n")
+	assert.Contains(t, buf.String(), "Would update outerb5488.txt\n")
+	assert.Contains(t, buf.String(), "Would delete folder\n")
+	assert.Contains(t, buf.String(), ": 1 added, 1 updated, 1 deleted, 0 unchanged\n")
+	assert.NoFileExists(t, filepath.Join(objPath, "new.txt"))
+
+	buf.Reset()
+	require.NoError(t, Run([]string{root + ptRoot, "--jobs", "4", src, "ark:/b5488"}, &buf))
+	assert.Contains(t, buf.String(), "Added new.txt\n")
+	assert.Contains(t, buf.String(), "Synced "+src+" to "+objPath+": 1 added, 1 updated, 0 deleted, 0 unchanged\n")
+	assert.FileExists(t, filepath.Join(objPath, "new.txt"))
+	assert.DirExists(t, filepath.Join(objPath, "folder"))
+
+	content, err := os.ReadFile(filepath.Join(objPath, "outerb5488.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "changed", string(content))
+
+	// Nothing is copied again, and the summary can be read as JSON
+	buf.Reset()
+	require.NoError(t, Run([]string{root + ptRoot, "-j", "--delete", src, "ark:/b5488"}, &buf))
+	var result pairtree.SyncResult
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &result))
+	assert.Equal(t, pairtree.SyncResult{Deleted: 1, Unchanged: 2}, result)
+	assert.NoDirExists(t, filepath.Join(objPath, "folder"))
+
+	// Only the syncs that changed the object are in its event history
+	require.NoError(t, Run([]string{root + ptRoot, src, "ark:/b5488"}, &buf))
+	events, err := premis.Events(ptRoot, "ark:/", "ark:/b5488")
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, "synced from "+src+": 1 added, 1 updated, 0 deleted", events[0].Detail)
+
+	// An object is synced out to a directory too
+	dest := filepath.Join(pttest.CreateTempDir(t, fs), "out")
+	require.NoError(t, Run([]string{root + ptRoot, "ark:/b5488", dest}, &buf))
+	assert.FileExists(t, filepath.Join(dest, "new.txt"))
+}
+
+// TestSyncErrors tests that arguments that are missing or not in the pairtree are errors
+func TestSyncErrors(t *testing.T) {
+	fs := afero.NewOsFs()
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, fs)
+	dir := pttest.CreateTempDir(t, fs)
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected error
+	}{
+		{name: "No destination", args: []string{dir}, expected: error_msgs.Err9},
+		{name: "Too many arguments", args: []string{dir, "ark:/b5488", "extra"}, expected: error_msgs.Err8},
+		{name: "Not in the pairtree", args: []string{dir, dir}, expected: error_msgs.Err10},
+		{name: "Missing object", args: []string{"ark:/missing", dir}, expected: os.ErrNotExist},
+		{name: "No jobs", args: []string{"--jobs", "0", dir, "ark:/b5488"}, expected: error_msgs.Err17},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			assert.ErrorIs(t, Run(append([]string{root + ptRoot}, test.args...), &buf), test.expected)
+		})
+	}
+}
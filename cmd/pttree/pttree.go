@@ -0,0 +1,179 @@
+package pttree
+
+/* pttree draws the files and directories of a Pairtree object as a tree, like the Unix tree command,
+with -a to include hidden entries, -d to draw only directories, and -L to limit how deep it goes */
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pairtree"
+	"github.com/UCLALibrary/pt-tools/utils"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	// Logger is the logger each run of pt tree starts from, tests replace it to capture the logs
+	Logger *zap.Logger = utils.ConsoleLogger()
+)
+
+// command holds the flags and arguments of one run of pt tree so that runs can happen concurrently
+type command struct {
+	showAll      bool
+	showDirsOnly bool
+	level        int
+	ptRoot       string
+	id           string
+	logger       *zap.Logger
+	out          *utils.Output
+}
+
+// counts are the number of directories and files that are drawn
+type counts struct {
+	dirs  int
+	files int
+}
+
+// node is a directory or file of the tree, in the order the entries of its directory are drawn in
+type node struct {
+	name string
+	dir  *pairtree.Directory
+}
+
+func (c *command) initFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(&c.showAll, "a", "a", false, "do not ignore entries starting with .")
+	cmd.Flags().BoolVarP(&c.showDirsOnly, "d", "d", false, "draw directories only")
+	cmd.Flags().IntVarP(&c.level, "level", "L", 0, "descend at most this many directories deep (0 is no limit)")
+}
+
+// NewCommand creates the tree subcommand of pt that writes its output to the writer
+func NewCommand(writer io.Writer) *cobra.Command {
+	c := &command{logger: Logger, out: utils.NewOutput(io.Discard, &utils.Styler{}, false)}
+
+	var cmd = &cobra.Command{
+		Use:               "tree [FLAGS] [ID]",
+		Short:             "pt tree draws the files and directories of a Pairtree object as a tree",
+		ValidArgsFunction: utils.CompleteIDs,
+		Annotations:       map[string]string{utils.S3Annotation: "true"},
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return utils.ConfigureLogger(cmd, &c.logger)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+
+			c.out = utils.OutputFromFlags(cmd, writer)
+
+			if c.ptRoot, err = utils.GetPtRoot(cmd, writer); err != nil {
+				return err
+			}
+
+			if len(args) < 1 {
+				c.out.Error("Please provide an ID for the pairtree")
+				c.logger.Error("Error getting ID", zap.Error(error_msgs.Err6))
+
+				return error_msgs.Err6
+			} else if len(args) > 1 {
+				c.out.Error("Too many arguments were provided to %s", "pt tree")
+				c.logger.Error("Error parsing pt tree", zap.Error(error_msgs.Err8))
+
+				return error_msgs.Err8
+			}
+			c.id = args[0]
+
+			if c.level < 0 {
+				err := fmt.Errorf("%w: -L must not be negative", error_msgs.Err17)
+				c.logger.Error("Error parsing pt tree", zap.Error(err))
+
+				return err
+			}
+
+			utils.SilenceUsage(cmd)
+
+			return c.tree(writer)
+		},
+	}
+
+	c.initFlags(cmd)
+
+	return cmd
+}
+
+// Run executes pt tree with the given arguments
+func Run(args []string, writer io.Writer) error {
+	if err := utils.RunSubcommand(NewCommand(writer), args, writer); err != nil {
+		Logger.Error("Error running pt tree", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// tree draws the Directory of the object under its ID, followed by the number of directories and
+// files that were drawn
+func (c *command) tree(writer io.Writer) error {
+	// Open the pairtree, which checks its version file and reads its prefix
+	pt, err := pairtree.Open(c.ptRoot)
+	if err != nil {
+		c.logger.Error("Error opening the pairtree", zap.Error(err))
+		return err
+	}
+
+	// The directories below the level are never read, so a shallow tree of a large object is drawn quickly
+	opts := pairtree.ListOptions{Recursive: true, ShowAll: c.showAll, DirsOnly: c.showDirsOnly, Depth: c.level}
+	dir, err := pt.Ls(c.id, "", opts)
+	if err != nil {
+		c.logger.Error("Error listing the files of the object", zap.Error(err))
+		return &error_msgs.PtError{ID: c.id, Err: err}
+	}
+
+	buffered := bufio.NewWriter(writer)
+	total := &counts{}
+
+	fmt.Fprintln(buffered, c.out.Style().Directory(c.id))
+	c.draw(buffered, dir, "", total)
+	fmt.Fprintf(buffered, "\n%s\n", c.out.T("%d directories, %d files", total.dirs, total.files))
+
+	return buffered.Flush()
+}
+
+// draw writes the entries of the directory, each after the prefix that draws the branches of the
+// directories above it, and the entries of its directories under them
+func (c *command) draw(w io.Writer, dir pairtree.Directory, prefix string, total *counts) {
+	nodes := entries(dir)
+	for i, entry := range nodes {
+		branch, indent := "├── ", "│   "
+		if i == len(nodes)-1 {
+			branch, indent = "└── ", "    "
+		}
+
+		if entry.dir == nil {
+			total.files++
+			fmt.Fprintf(w, "%s%s%s\n", prefix, branch, entry.name)
+			continue
+		}
+
+		total.dirs++
+		fmt.Fprintf(w, "%s%s%s\n", prefix, branch, c.out.Style().Directory(entry.name+"/"))
+		c.draw(w, *entry.dir, prefix+indent, total)
+	}
+}
+
+// entries returns the directories and files of the directory together in name order, as ls lists them
+func entries(dir pairtree.Directory) []node {
+	nodes := make([]node, 0, len(dir.Directories)+len(dir.Files))
+	d, f := 0, 0
+	for d < len(dir.Directories) || f < len(dir.Files) {
+		if f == len(dir.Files) || (d < len(dir.Directories) && dir.Directories[d].Name < dir.Files[f].Name) {
+			nodes = append(nodes, node{name: dir.Directories[d].Name, dir: &dir.Directories[d]})
+			d++
+		} else {
+			nodes = append(nodes, node{name: dir.Files[f].Name})
+			f++
+		}
+	}
+
+	return nodes
+}
@@ -0,0 +1,95 @@
+package pttree
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pttest"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const root = "--pairtree="
+
+// TestMain runs the tests with the logger of the test sink, it is set once so that the tests can run in parallel
+func TestMain(m *testing.M) {
+	logger, cleanup := pttest.SetupLogger()
+	Logger = logger
+
+	code := m.Run()
+	cleanup()
+	os.Exit(code)
+}
+
+// TestTree tests that the entries of the object are drawn as a tree with the filters and depth of the flags
+func TestTree(t *testing.T) {
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, afero.NewOsFs())
+
+	tests := []struct {
+		name     string
+		flags    []string
+		expected string
+	}{
+		{
+			name:  "Default",
+			flags: nil,
+			expected: "ark:/b5488\n" +
+				"├── folder/\n" +
+				"│   └── innerb5488.txt\n" +
+				"└── outerb5488.txt\n" +
+				"\n1 directories, 2 files\n",
+		},
+		{
+			name:  "All",
+			flags: []string{"-a"},
+			expected: "ark:/b5488\n" +
+				"├── folder/\n" +
+				"│   ├── .hidden/\n" +
+				"│   │   └── inner.txt\n" +
+				"│   ├── .hiddenFile.txt\n" +
+				"│   └── innerb5488.txt\n" +
+				"└── outerb5488.txt\n" +
+				"\n2 directories, 4 files\n",
+		},
+		{
+			name:  "Directories only",
+			flags: []string{"-a", "-d"},
+			expected: "ark:/b5488\n" +
+				"└── folder/\n" +
+				"    └── .hidden/\n" +
+				"\n2 directories, 0 files\n",
+		},
+		{
+			name:  "Level",
+			flags: []string{"-L", "1"},
+			expected: "ark:/b5488\n" +
+				"├── folder/\n" +
+				"└── outerb5488.txt\n" +
+				"\n1 directories, 1 files\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, Run(append([]string{root + ptRoot, "ark:/b5488"}, test.flags...), &buf))
+			assert.Equal(t, test.expected, buf.String())
+		})
+	}
+}
+
+// TestTreeErrors tests that missing objects and arguments are refused
+func TestTreeErrors(t *testing.T) {
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, afero.NewOsFs())
+
+	var buf bytes.Buffer
+	err := Run([]string{root + ptRoot, "ark:/notAnObject"}, &buf)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	assert.ErrorIs(t, Run([]string{root + ptRoot}, &buf), error_msgs.Err6)
+	assert.ErrorIs(t, Run([]string{root + ptRoot, "ark:/a5388", "ark:/b5488"}, &buf), error_msgs.Err8)
+	assert.ErrorIs(t, Run([]string{root + ptRoot, "-L", "-1", "ark:/a5388"}, &buf), error_msgs.Err17)
+}
@@ -0,0 +1,124 @@
+package ptvalidate
+
+/* ptvalidate walks a whole pairtree and reports where it does not conform to the pairtree
+specification, like a missing version file, malformed shorties, files in the branches of the tree,
+and object directories that do not decode to a valid ID. It exits with the verification exit code
+when there are violations, so it can be run from cron. */
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pairtree"
+	"github.com/UCLALibrary/pt-tools/utils"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	// Logger is the logger each run of pt validate starts from, tests replace it to capture the logs
+	Logger *zap.Logger = utils.ConsoleLogger()
+)
+
+// command holds the arguments of one run of pt validate so that runs can happen concurrently
+type command struct {
+	ptRoot string
+	logger *zap.Logger
+	out    *utils.Output
+}
+
+// NewCommand creates the validate subcommand of pt that writes its output to the writer
+func NewCommand(writer io.Writer) *cobra.Command {
+	c := &command{logger: Logger, out: utils.NewOutput(io.Discard, &utils.Styler{}, false)}
+
+	var cmd = &cobra.Command{
+		Use:   "validate",
+		Short: "pt validate reports where the pairtree does not conform to the pairtree specification",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return utils.ConfigureLogger(cmd, &c.logger)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+
+			c.out = utils.OutputFromFlags(cmd, writer)
+
+			if c.ptRoot, err = utils.GetPtRoot(cmd, writer); err != nil {
+				return err
+			}
+
+			if len(args) > 0 {
+				c.out.Error("Too many arguments were provided to %s", "pt validate")
+				c.logger.Error("Error parsing pt validate", zap.Error(error_msgs.Err8))
+
+				return error_msgs.Err8
+			}
+
+			jsonFlag, _ := cmd.Flags().GetBool(utils.JSONFlag)
+
+			utils.SilenceUsage(cmd)
+
+			return c.validate(cmd.Context(), writer, jsonFlag)
+		},
+	}
+
+	return cmd
+}
+
+// Run executes pt validate with the given arguments
+func Run(args []string, writer io.Writer) error {
+	if err := utils.RunSubcommand(NewCommand(writer), args, writer); err != nil {
+		Logger.Error("Error running pt validate", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// validate checks the whole pairtree against the specification and writes the violations
+func (c *command) validate(ctx context.Context, writer io.Writer, outputJSON bool) error {
+	result, err := pairtree.ValidateCtx(ctx, c.ptRoot)
+	if err != nil {
+		c.logger.Error("Error walking the pairtree", zap.Error(err))
+		return err
+	}
+
+	if outputJSON {
+		jsonData, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			c.logger.Error("Error converting the validation to JSON", zap.Error(err))
+			return err
+		}
+		fmt.Fprintln(writer, string(jsonData))
+	} else {
+		c.writeResult(writer, result)
+	}
+
+	if len(result.Violations) > 0 {
+		c.logger.Warn("The pairtree does not conform to the pairtree specification",
+			zap.Int("violations", len(result.Violations)))
+		return error_msgs.Err40
+	}
+
+	return nil
+}
+
+// writeResult writes a line for each violation with its kind, path, and explanation, followed by a summary
+func (c *command) writeResult(writer io.Writer, result pairtree.Validation) {
+	style := c.out.Style()
+
+	for _, violation := range result.Violations {
+		fmt.Fprintf(writer, "%s  %s  %s\n", style.Error(fmt.Sprintf("%-7s", violation.Kind)), violation.Path,
+			violation.Message)
+	}
+
+	if len(result.Violations) == 0 {
+		c.out.Success("The %d objects of the pairtree conform to the pairtree specification", result.Objects)
+		return
+	}
+
+	c.out.Info("Found %d violations of the pairtree specification in a pairtree of %d objects",
+		len(result.Violations), result.Objects)
+}
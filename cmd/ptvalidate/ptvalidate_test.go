@@ -0,0 +1,93 @@
+package ptvalidate
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pairtree"
+	"github.com/UCLALibrary/pt-tools/pkg/pttest"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const root = "--pairtree="
+
+// TestMain runs the tests with the logger of the test sink, it is set once so that the tests can run in parallel
+func TestMain(m *testing.M) {
+	logger, cleanup := pttest.SetupLogger()
+	Logger = logger
+
+	code := m.Run()
+	cleanup()
+	os.Exit(code)
+}
+
+// TestValidate tests if the violations of the pairtree specification are reported as JSON
+func TestValidate(t *testing.T) {
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, afero.NewOsFs())
+
+	var buf bytes.Buffer
+	require.NoError(t, Run([]string{root + ptRoot, "--json"}, &buf))
+
+	var result pairtree.Validation
+	require.NoError(t, json.NewDecoder(&buf).Decode(&result))
+	assert.Equal(t, 4, result.Objects)
+	assert.Empty(t, result.Violations)
+
+	stray := filepath.Join(ptRoot, "pairtree_root", "a5", "stray.txt")
+	require.NoError(t, os.WriteFile(stray, []byte("stray"), 0644))
+
+	buf.Reset()
+	err := Run([]string{root + ptRoot, "--json"}, &buf)
+	assert.ErrorIs(t, err, error_msgs.Err40)
+
+	// The error that follows the JSON on a violation is not decoded
+	require.NoError(t, json.NewDecoder(&buf).Decode(&result))
+	require.Len(t, result.Violations, 1)
+	assert.Equal(t, pairtree.FileViolation, result.Violations[0].Kind)
+	assert.Equal(t, stray, result.Violations[0].Path)
+}
+
+// TestValidateText tests if each violation is written on its own line
+func TestValidateText(t *testing.T) {
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, afero.NewOsFs())
+
+	var buf bytes.Buffer
+	require.NoError(t, Run([]string{root + ptRoot, "--no-color"}, &buf))
+	assert.Contains(t, buf.String(), "The 4 objects of the pairtree conform to the pairtree specification")
+
+	require.NoError(t, os.Remove(filepath.Join(ptRoot, "pairtree_version0_1")))
+
+	buf.Reset()
+	err := Run([]string{root + ptRoot, "--no-color"}, &buf)
+	assert.ErrorIs(t, err, error_msgs.Err40)
+	assert.Contains(t, buf.String(), "version  "+filepath.Join(ptRoot, "pairtree_version0_1")+
+		"  the pairtree version file is missing\n")
+	assert.Contains(t, buf.String(), "Found 1 violations of the pairtree specification in a pairtree of 4 objects")
+}
+
+// TestCLIError tests if an error is thrown when the arguments are not valid
+func TestCLIError(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		expectErr error
+	}{
+		{name: "Too many arguments", args: []string{root + "root", "extra"}, expectErr: error_msgs.Err8},
+		{name: "No pairtree root", args: []string{root + filepath.Join(t.TempDir(), "missing")}, expectErr: os.ErrNotExist},
+		{name: "Pairtree in S3", args: []string{root + "s3://bucket/pt"}, expectErr: error_msgs.Err41},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := Run(test.args, &buf)
+			assert.ErrorIs(t, err, test.expectErr)
+		})
+	}
+}
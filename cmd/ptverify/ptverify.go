@@ -0,0 +1,152 @@
+package ptverify
+
+/* ptverify checks what pt has signed with --sign-key. With --signature each file is checked against the
+detached signature beside it, like the manifests of pt checksum and the snapshots of pt report growth,
+or, when it has none, read as an audit trail of pt log export and checked against the signature in it.
+A signature only shows the file is unchanged since it was signed by the key in it, so --public-key gives
+the public key the signer is known by to check that the key is theirs. */
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/UCLALibrary/pt-tools/pkg/audit"
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/utils"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	// Logger is the logger each run of pt verify starts from, tests replace it to capture the logs
+	Logger *zap.Logger = utils.ConsoleLogger()
+)
+
+// command holds the flags and arguments of one run of pt verify so that runs can happen concurrently
+type command struct {
+	signature     bool
+	publicKeyPath string
+	publicKey     ed25519.PublicKey
+	paths         []string
+	logger        *zap.Logger
+	out           *utils.Output
+}
+
+func (c *command) initFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&c.signature, "signature", false, "check the files against their signatures")
+	cmd.Flags().StringVar(&c.publicKeyPath, "public-key", "", "PEM file of the Ed25519 public key the files must be signed with")
+}
+
+// NewCommand creates the verify subcommand of pt that writes its output to the writer
+func NewCommand(writer io.Writer) *cobra.Command {
+	c := &command{logger: Logger, out: utils.NewOutput(io.Discard, &utils.Styler{}, false)}
+
+	var cmd = &cobra.Command{
+		Use:   "verify --signature [FLAGS] [FILE]...",
+		Short: "pt verify checks the files pt signed against their signatures",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return utils.ConfigureLogger(cmd, &c.logger)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+
+			c.out = utils.OutputFromFlags(cmd, writer)
+
+			if !c.signature {
+				err = fmt.Errorf("%w: pt verify checks signatures with --signature", error_msgs.Err17)
+				c.logger.Error("Error parsing pt verify", zap.Error(err))
+
+				return err
+			}
+
+			if len(args) < 1 {
+				c.out.Error("Please provide the signed files to verify")
+				c.logger.Error("Error getting the files", zap.Error(error_msgs.Err17))
+
+				return fmt.Errorf("%w: no files to verify were given", error_msgs.Err17)
+			}
+
+			// A file can be given by its signature too
+			for _, path := range args {
+				c.paths = append(c.paths, strings.TrimSuffix(path, audit.SignatureExt))
+			}
+
+			if c.publicKeyPath != "" {
+				if c.publicKey, err = audit.LoadPublicKey(c.publicKeyPath); err != nil {
+					c.logger.Error("Error reading the public key", zap.Error(err))
+					return err
+				}
+			}
+
+			utils.SilenceUsage(cmd)
+
+			return c.verify()
+		},
+	}
+
+	c.initFlags(cmd)
+
+	return cmd
+}
+
+// Run executes pt verify with the given arguments
+func Run(args []string, writer io.Writer) error {
+	if err := utils.RunSubcommand(NewCommand(writer), args, writer); err != nil {
+		Logger.Error("Error running pt verify", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// verify checks each file against its signature, going on to the next file when one does not match so that
+// all of them are reported
+func (c *command) verify() error {
+	var errs []error
+
+	for _, path := range c.paths {
+		signer, err := c.verifyFile(path)
+		if err != nil {
+			c.out.Error("Could not verify %s: %v", path, err)
+			c.logger.Error("Error verifying the signature", zap.String("path", path), zap.Error(err))
+			errs = append(errs, &error_msgs.PtError{Path: path, Err: err})
+
+			continue
+		}
+
+		c.out.Success("%s matches its signature by %s", path, signer)
+		c.logger.Info("Verified the signature", zap.String("path", path), zap.String("publicKey", signer))
+	}
+
+	return errors.Join(errs...)
+}
+
+// verifyFile checks the file against its detached signature, or as a signed audit trail when it has none,
+// and returns the public key of the signature
+func (c *command) verifyFile(path string) (string, error) {
+	signature, err := audit.VerifyFile(path, c.publicKey)
+	if err == nil || !errors.Is(err, fs.ErrNotExist) {
+		return signature.PublicKey, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	trail, err := audit.ReadTrail(file)
+	if err != nil {
+		return "", err
+	}
+	if err := audit.VerifyTrail(trail, c.publicKey); err != nil {
+		return "", err
+	}
+
+	return trail.Signature.PublicKey, nil
+}
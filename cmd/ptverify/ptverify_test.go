@@ -0,0 +1,94 @@
+package ptverify
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/UCLALibrary/pt-tools/pkg/audit"
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/premis"
+	"github.com/UCLALibrary/pt-tools/pkg/pttest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMain runs the tests with the logger of the test sink, it is set once so that the tests can run in parallel
+func TestMain(m *testing.M) {
+	logger, cleanup := pttest.SetupLogger()
+	Logger = logger
+
+	code := m.Run()
+	cleanup()
+	os.Exit(code)
+}
+
+// TestSignature tests that signed files and audit trails are checked against their signatures and the
+// public key, and that every file is reported when one does not match
+func TestSignature(t *testing.T) {
+	dir := t.TempDir()
+	key, _, publicKeyPath := pttest.SigningKey(t, dir)
+	_, _, otherKeyPath := pttest.SigningKey(t, t.TempDir())
+
+	manifest := filepath.Join(dir, "manifest-sha256.txt")
+	require.NoError(t, os.WriteFile(manifest, []byte("abc  hello.txt\n"), 0644))
+	_, err := audit.SignFile(key, manifest)
+	require.NoError(t, err)
+
+	records, err := audit.Chain([]premis.Event{premis.NewEvent(premis.Ingestion, "ark:/a5388", "", nil)})
+	require.NoError(t, err)
+	signature := audit.Sign(key, records)
+	trailData, err := json.Marshal(audit.Trail{Records: records, Signature: &signature})
+	require.NoError(t, err)
+	trail := filepath.Join(dir, "audit.json")
+	require.NoError(t, os.WriteFile(trail, trailData, 0644))
+
+	var buf bytes.Buffer
+	require.NoError(t, Run([]string{"--signature", "--public-key", publicKeyPath, manifest + audit.SignatureExt, trail}, &buf))
+	assert.Contains(t, buf.String(), manifest+" matches its signature by "+signature.PublicKey)
+	assert.Contains(t, buf.String(), trail+" matches its signature by "+signature.PublicKey)
+
+	// The files are only trusted when they were signed with the public key
+	buf.Reset()
+	err = Run([]string{"--signature", "--public-key", otherKeyPath, manifest, trail}, &buf)
+	assert.ErrorIs(t, err, error_msgs.Err50)
+	assert.ErrorIs(t, err, error_msgs.Err39)
+
+	// A changed file does not match and the others are still checked
+	require.NoError(t, os.WriteFile(manifest, []byte("abd  hello.txt\n"), 0644))
+	buf.Reset()
+	err = Run([]string{"--signature", manifest, trail}, &buf)
+	assert.ErrorIs(t, err, error_msgs.Err50)
+	assert.Contains(t, buf.String(), "Could not verify "+manifest)
+	assert.Contains(t, buf.String(), trail+" matches its signature")
+}
+
+// TestCLIError tests that the flags and arguments of pt verify are checked
+func TestCLIError(t *testing.T) {
+	dir := t.TempDir()
+	notKey := filepath.Join(dir, "notkey.pem")
+	require.NoError(t, os.WriteFile(notKey, []byte("not a key"), 0600))
+	unsigned := filepath.Join(dir, "unsigned.txt")
+	require.NoError(t, os.WriteFile(unsigned, []byte("{}\n"), 0644))
+
+	tests := []struct {
+		name      string
+		args      []string
+		expectErr error
+	}{
+		{name: "No --signature", args: []string{unsigned}, expectErr: error_msgs.Err17},
+		{name: "No files", args: []string{"--signature"}, expectErr: error_msgs.Err17},
+		{name: "Public key not valid", args: []string{"--signature", "--public-key", notKey, unsigned}, expectErr: error_msgs.Err51},
+		{name: "Not signed", args: []string{"--signature", unsigned}, expectErr: error_msgs.Err39},
+		{name: "File does not exist", args: []string{"--signature", filepath.Join(dir, "missing.txt")}, expectErr: os.ErrNotExist},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			assert.ErrorIs(t, Run(test.args, &buf), test.expectErr)
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package ptversion
+
+/* ptversion reports the version of pt and, with --build, the commit, build date, and
+platform of the binary so that a problem can be traced to the build that produced it */
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/utils"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	// Logger is the logger each run of pt version starts from, tests replace it to capture the logs
+	Logger *zap.Logger = utils.ConsoleLogger()
+)
+
+// command holds the flags of one run of pt version so that runs can happen concurrently
+type command struct {
+	build  bool
+	logger *zap.Logger
+	out    *utils.Output
+}
+
+func (c *command) initFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(&c.build, "build", "b", false, "Include the commit, build date, and platform")
+}
+
+// NewCommand creates the version subcommand of pt that writes its output to the writer
+func NewCommand(writer io.Writer) *cobra.Command {
+	c := &command{logger: Logger, out: utils.NewOutput(io.Discard, &utils.Styler{}, false)}
+
+	var cmd = &cobra.Command{
+		Use:   "version [FLAGS]",
+		Short: "pt version reports the version of pt",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return utils.ConfigureLogger(cmd, &c.logger)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c.out = utils.OutputFromFlags(cmd, writer)
+
+			if len(args) > 0 {
+				c.out.Error("Too many arguments were provided to %s", "pt version")
+				c.logger.Error("Error parsing pt version", zap.Error(error_msgs.Err8))
+
+				return error_msgs.Err8
+			}
+
+			jsonFlag, _ := cmd.Flags().GetBool(utils.JSONFlag)
+
+			utils.SilenceUsage(cmd)
+
+			return c.printVersion(writer, jsonFlag)
+		},
+	}
+
+	c.initFlags(cmd)
+
+	return cmd
+}
+
+// Run executes pt version with the given arguments
+func Run(args []string, writer io.Writer) error {
+	if err := utils.RunSubcommand(NewCommand(writer), args, writer); err != nil {
+		Logger.Error("Error running pt version", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// printVersion writes the version, or all the build metadata when --build is used
+func (c *command) printVersion(writer io.Writer, outputJSON bool) error {
+	info := utils.GetBuildInfo()
+
+	if outputJSON {
+		if !c.build {
+			info = utils.BuildInfo{Version: info.Version}
+		}
+
+		jsonData, err := json.Marshal(info)
+		if err != nil {
+			c.logger.Error("Error converting the version to JSON", zap.Error(err))
+			return err
+		}
+
+		fmt.Fprintln(writer, string(jsonData))
+		return nil
+	}
+
+	if c.build {
+		fmt.Fprint(writer, info)
+	} else {
+		fmt.Fprintf(writer, "pt version %s\n", info.Version)
+	}
+
+	return nil
+}
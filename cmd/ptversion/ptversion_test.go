@@ -0,0 +1,97 @@
+package ptversion
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pttest"
+	"github.com/UCLALibrary/pt-tools/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMain runs the tests with the logger of the test sink, it is set once so that the tests can run in parallel
+func TestMain(m *testing.M) {
+	logger, cleanup := pttest.SetupLogger()
+	Logger = logger
+
+	code := m.Run()
+	cleanup()
+	os.Exit(code)
+}
+
+// TestVersion tests if the version and build metadata are reported
+func TestVersion(t *testing.T) {
+	// The build metadata is restored once the parallel subtests have finished
+	version, commit, date := utils.Version, utils.Commit, utils.BuildDate
+	t.Cleanup(func() { utils.Version, utils.Commit, utils.BuildDate = version, commit, date })
+
+	utils.Version = "v1.2.0"
+	utils.Commit = "3f2c1ab"
+	utils.BuildDate = "2025-01-31T12:00:00Z"
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected []string
+		missing  []string
+	}{
+		{
+			name:     "version",
+			args:     []string{},
+			expected: []string{"pt version v1.2.0\n"},
+			missing:  []string{"3f2c1ab"},
+		},
+		{
+			name:     "build metadata",
+			args:     []string{"--build"},
+			expected: []string{"pt version v1.2.0", "commit: 3f2c1ab", "built: 2025-01-31T12:00:00Z", "platform: "},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+
+			err := Run(test.args, &buf)
+			require.NoError(t, err)
+
+			for _, expected := range test.expected {
+				assert.Contains(t, buf.String(), expected)
+			}
+
+			for _, missing := range test.missing {
+				assert.NotContains(t, buf.String(), missing)
+			}
+		})
+	}
+}
+
+// TestVersionJSON tests if the build metadata is reported as JSON with --json
+func TestVersionJSON(t *testing.T) {
+	defer func(version, commit string) { utils.Version, utils.Commit = version, commit }(utils.Version, utils.Commit)
+
+	utils.Version = "v1.2.0"
+	utils.Commit = "3f2c1ab"
+
+	var buf bytes.Buffer
+	err := Run([]string{"--build", "--json"}, &buf)
+	require.NoError(t, err)
+
+	var info utils.BuildInfo
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &info))
+	assert.Equal(t, "v1.2.0", info.Version)
+	assert.Equal(t, "3f2c1ab", info.Commit)
+}
+
+// TestCLIError tests if an error is thrown when too many arguments are passed
+func TestCLIError(t *testing.T) {
+	var buf bytes.Buffer
+	err := Run([]string{"extra"}, &buf)
+	assert.ErrorIs(t, err, error_msgs.Err8)
+}
@@ -1,73 +1,99 @@
 package main
 
 import (
-	"fmt"
-	"log"
+	"io"
 	"os"
 
+	"github.com/UCLALibrary/pt-tools/cmd/ptbatch"
+	"github.com/UCLALibrary/pt-tools/cmd/ptbench"
+	"github.com/UCLALibrary/pt-tools/cmd/ptchecksum"
 	"github.com/UCLALibrary/pt-tools/cmd/ptcp"
+	"github.com/UCLALibrary/pt-tools/cmd/ptdocs"
+	"github.com/UCLALibrary/pt-tools/cmd/ptevents"
+	"github.com/UCLALibrary/pt-tools/cmd/ptexists"
+	"github.com/UCLALibrary/pt-tools/cmd/ptexport"
+	"github.com/UCLALibrary/pt-tools/cmd/ptfind"
+	"github.com/UCLALibrary/pt-tools/cmd/ptgrep"
+	"github.com/UCLALibrary/pt-tools/cmd/ptid"
+	"github.com/UCLALibrary/pt-tools/cmd/ptids"
+	"github.com/UCLALibrary/pt-tools/cmd/ptimport"
+	"github.com/UCLALibrary/pt-tools/cmd/ptlog"
 	"github.com/UCLALibrary/pt-tools/cmd/ptls"
+	"github.com/UCLALibrary/pt-tools/cmd/ptmets"
+	"github.com/UCLALibrary/pt-tools/cmd/ptmint"
 	"github.com/UCLALibrary/pt-tools/cmd/ptmv"
 	"github.com/UCLALibrary/pt-tools/cmd/ptnew"
+	"github.com/UCLALibrary/pt-tools/cmd/ptpath"
+	"github.com/UCLALibrary/pt-tools/cmd/ptreconcile"
+	"github.com/UCLALibrary/pt-tools/cmd/ptreport"
 	"github.com/UCLALibrary/pt-tools/cmd/ptrm"
+	"github.com/UCLALibrary/pt-tools/cmd/ptselfupdate"
+	"github.com/UCLALibrary/pt-tools/cmd/ptsip"
+	"github.com/UCLALibrary/pt-tools/cmd/ptstat"
+	"github.com/UCLALibrary/pt-tools/cmd/ptsync"
+	"github.com/UCLALibrary/pt-tools/cmd/pttree"
+	"github.com/UCLALibrary/pt-tools/cmd/ptvalidate"
+	"github.com/UCLALibrary/pt-tools/cmd/ptverify"
+	"github.com/UCLALibrary/pt-tools/cmd/ptversion"
+	"github.com/UCLALibrary/pt-tools/utils"
+	"github.com/spf13/cobra"
 )
 
-const help = `pt facilitates interactions with a Pairtree without the user needing to know about the Pairtree’s internal structure. 
-
-Please refer to the README(https://github.com/UCLALibrary/pt-tools) for more detailed instructions
-
-	Usage: pt [command] [options]
-	Commands:
-	  ls     List directories and files
-	  rm     Remove files or directories
-	  cp     Copy files or directories
-	  mv     Move files or directories
-	  new    Create a new pairtree object
-	
-	For more information on a specific command, run 'pt [command] --help'.`
-
 func main() {
-	// Basic command-line argument parsing
-	if len(os.Args) < 2 {
-		fmt.Println(help)
-		os.Exit(1)
-	}
-
-	command := os.Args[1]
-	// Pass in os.Args excluding the general and specifc program name
-	args := os.Args[2:]
-
 	// Use os.Stdout for standard output
 	writer := os.Stdout
 
-	switch command {
-	case "ls":
-		err := ptls.Run(args, writer)
-		if err != nil {
-			os.Exit(2)
-		}
-	case "rm":
-		err := ptrm.Run(args, writer)
-		if err != nil {
-			os.Exit(3)
-		}
-	case "cp":
-		err := ptcp.Run(args, writer)
-		if err != nil {
-			os.Exit(4)
-		}
-	case "mv":
-		err := ptmv.Run(args, writer)
-		if err != nil {
-			os.Exit(5)
-		}
-	case "new":
-		err := ptnew.Run(args, writer)
-		if err != nil {
-			os.Exit(6)
+	// Commands stop and clean up partially written files when pt is interrupted
+	ctx, stop := utils.SignalContext()
+
+	rootCmd := utils.NewRootCmd(writer)
+	rootCmd.AddCommand(commands(writer)...)
+	rootCmd.AddCommand(ptbatch.NewCommand(writer, commands))
+
+	// Exit with the code of the error's category, see utils.ExitCode
+	cmd, err := utils.Execute(ctx, rootCmd)
+	stop()
+
+	if err != nil {
+		if utils.ErrorsAsJSON(cmd) {
+			_ = utils.WriteJSONError(os.Stderr, err)
 		}
-	default:
-		fmt.Println(help)
-		log.Fatalf("Unknown command: %s", command)
+		os.Exit(utils.ExitCode(err))
+	}
+}
+
+// commands creates the pt subcommands that write their output to the writer, which pt batch also runs
+func commands(writer io.Writer) []*cobra.Command {
+	return []*cobra.Command{
+		ptls.NewCommand(writer),
+		ptrm.NewCommand(writer),
+		ptcp.NewCommand(writer),
+		ptmv.NewCommand(writer),
+		ptnew.NewCommand(writer),
+		ptdocs.NewCommand(writer),
+		ptversion.NewCommand(writer),
+		ptselfupdate.NewCommand(writer),
+		ptbench.NewCommand(writer),
+		ptevents.NewCommand(writer),
+		ptmets.NewCommand(writer),
+		ptchecksum.NewCommand(writer),
+		ptmint.NewCommand(writer),
+		ptreport.NewCommand(writer),
+		ptreconcile.NewCommand(writer),
+		ptsip.NewCommand(writer),
+		ptexport.NewCommand(writer),
+		ptimport.NewCommand(writer),
+		ptlog.NewCommand(writer),
+		ptvalidate.NewCommand(writer),
+		ptids.NewCommand(writer),
+		pttree.NewCommand(writer),
+		ptstat.NewCommand(writer),
+		ptexists.NewCommand(writer),
+		ptpath.NewCommand(writer),
+		ptid.NewCommand(writer),
+		ptfind.NewCommand(writer),
+		ptgrep.NewCommand(writer),
+		ptsync.NewCommand(writer),
+		ptverify.NewCommand(writer),
 	}
 }
@@ -0,0 +1,82 @@
+/*
+The ark package checks ARK identifiers against a resolver like N2T, so that an ID with a typo can be
+caught before an object is stored under it. An ARK resolves when the resolver redirects it to a
+target, and resolves elsewhere when that target is not where the ARK's objects are expected to be.
+*/
+package ark
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+)
+
+// DefaultResolver is the N2T resolver that ARKs are checked against unless another is configured
+const DefaultResolver = "https://n2t.net/"
+
+// Resolver checks ARKs against the resolver at BaseURL
+type Resolver struct {
+	Client  *http.Client
+	BaseURL string
+	// Target is the URL prefix that ARKs are expected to resolve to, any target is accepted when it is empty
+	Target string
+}
+
+// NewResolver creates a Resolver for the resolver at baseURL that expects ARKs to resolve to target
+func NewResolver(baseURL, target string) *Resolver {
+	return &Resolver{
+		Client: &http.Client{
+			// The redirect is the answer, so it is returned rather than followed
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		BaseURL: baseURL,
+		Target:  target,
+	}
+}
+
+// IsARK checks if the ID is an ARK
+func IsARK(id string) bool {
+	return strings.HasPrefix(id, "ark:")
+}
+
+// Resolve returns the URL the ARK resolves to. It returns Err29 if the resolver does not know the ARK
+// and Err30 if the ARK resolves somewhere other than the Resolver's Target.
+func (r *Resolver) Resolve(ctx context.Context, id string) (string, error) {
+	url := strings.TrimSuffix(r.BaseURL, "/") + "/" + id
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	var target string
+
+	switch {
+	case resp.StatusCode >= 300 && resp.StatusCode < 400 && resp.Header.Get("Location") != "":
+		target = resp.Header.Get("Location")
+	case resp.StatusCode == http.StatusOK:
+		// The resolver serves the ARK itself rather than redirecting it
+		target = url
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return "", fmt.Errorf("%w: %s", error_msgs.Err29, id)
+	default:
+		return "", fmt.Errorf("the resolver returned %s for %s", resp.Status, id)
+	}
+
+	if r.Target != "" && !strings.HasPrefix(target, r.Target) {
+		return target, fmt.Errorf("%w: %s resolves to %s", error_msgs.Err30, id, target)
+	}
+
+	return target, nil
+}
@@ -0,0 +1,91 @@
+package ark
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestResolver starts a resolver that redirects the ARKs in targets and does not know any others
+func newTestResolver(t *testing.T, targets map[string]string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ark:/13030/served" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		if target, ok := targets[r.URL.Path[1:]]; ok {
+			http.Redirect(w, r, target, http.StatusFound)
+			return
+		}
+
+		if r.URL.Path == "/ark:/13030/broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// TestResolve tests what an ARK resolves to and the errors for ARKs that do not resolve as expected
+func TestResolve(t *testing.T) {
+	server := newTestResolver(t, map[string]string{
+		"ark:/13030/m5br8st1":  "https://digital.library.ucla.edu/catalog/ark:/13030/m5br8st1",
+		"ark:/13030/elsewhere": "https://example.com/ark:/13030/elsewhere",
+	})
+
+	tests := []struct {
+		name         string
+		id           string
+		target       string
+		expectTarget string
+		expectErr    error
+	}{
+		{name: "resolves", id: "ark:/13030/m5br8st1",
+			expectTarget: "https://digital.library.ucla.edu/catalog/ark:/13030/m5br8st1"},
+		{name: "resolves to target", id: "ark:/13030/m5br8st1", target: "https://digital.library.ucla.edu/",
+			expectTarget: "https://digital.library.ucla.edu/catalog/ark:/13030/m5br8st1"},
+		{name: "resolves elsewhere", id: "ark:/13030/elsewhere", target: "https://digital.library.ucla.edu/",
+			expectTarget: "https://example.com/ark:/13030/elsewhere", expectErr: error_msgs.Err30},
+		{name: "served by the resolver", id: "ark:/13030/served", expectTarget: server.URL + "/ark:/13030/served"},
+		{name: "does not resolve", id: "ark:/13030/typo", expectErr: error_msgs.Err29},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			resolver := NewResolver(server.URL+"/", test.target)
+			target, err := resolver.Resolve(context.Background(), test.id)
+
+			assert.ErrorIs(t, err, test.expectErr)
+			assert.Equal(t, test.expectTarget, target)
+		})
+	}
+}
+
+// TestResolveUnexpectedResponse tests that a resolver error is not mistaken for an ARK that does not resolve
+func TestResolveUnexpectedResponse(t *testing.T) {
+	server := newTestResolver(t, nil)
+
+	_, err := NewResolver(server.URL, "").Resolve(context.Background(), "ark:/13030/broken")
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, error_msgs.Err29)
+	assert.Contains(t, err.Error(), "500")
+}
+
+// TestIsARK tests which IDs are ARKs
+func TestIsARK(t *testing.T) {
+	assert.True(t, IsARK("ark:/13030/m5br8st1"))
+	assert.True(t, IsARK("ark:13030/m5br8st1"))
+	assert.False(t, IsARK("pt://m5br8st1"))
+}
@@ -0,0 +1,172 @@
+/*
+The audit package turns the preservation events recorded for a pairtree into a tamper-evident audit
+trail. Each record of the trail holds the hash of the record before it, so changing, removing, or
+reordering a record breaks the chain, and the hash of the last record can be signed with an Ed25519
+key so the trail can be checked against the key's public half after it leaves the pairtree. Other
+files pt writes, like manifests and snapshots, are signed with the same keys in a detached signature.
+*/
+package audit
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/premis"
+)
+
+// Algorithm is the signature algorithm of a signed audit trail
+const Algorithm = "Ed25519"
+
+// GenesisHash is the previous hash of the first record of an audit trail
+var GenesisHash = strings.Repeat("0", sha256.Size*2)
+
+// Record is an event in the audit trail, chained to the record before it by that record's hash
+type Record struct {
+	Seq      int          `json:"seq"`
+	Event    premis.Event `json:"event"`
+	PrevHash string       `json:"prev_hash"`
+	Hash     string       `json:"hash"`
+}
+
+// Signature is the signature of the hash of the last record of an audit trail, with the public key
+// that checks it
+type Signature struct {
+	Algorithm string `json:"algorithm"`
+	PublicKey string `json:"public_key"`
+	Hash      string `json:"hash"`
+	Signature string `json:"signature"`
+}
+
+// Chain returns the events as the records of an audit trail, in the order they are given
+func Chain(events []premis.Event) ([]Record, error) {
+	records := make([]Record, 0, len(events))
+	prevHash := GenesisHash
+
+	for i, event := range events {
+		hash, err := hashRecord(prevHash, event)
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, Record{Seq: i + 1, Event: event, PrevHash: prevHash, Hash: hash})
+		prevHash = hash
+	}
+
+	return records, nil
+}
+
+// Verify checks that each record of the audit trail follows the record before it and that its hash
+// is the hash of its event
+func Verify(records []Record) error {
+	prevHash := GenesisHash
+
+	for i, record := range records {
+		if record.Seq != i+1 || record.PrevHash != prevHash {
+			return fmt.Errorf("%w: record %d does not follow the record before it", error_msgs.Err39, i+1)
+		}
+
+		hash, err := hashRecord(prevHash, record.Event)
+		if err != nil {
+			return err
+		}
+		if hash != record.Hash {
+			return fmt.Errorf("%w: record %d does not match its hash", error_msgs.Err39, i+1)
+		}
+
+		prevHash = hash
+	}
+
+	return nil
+}
+
+// LastHash returns the hash of the last record of the audit trail, which covers every record before
+// it, or the genesis hash when the trail has no records
+func LastHash(records []Record) string {
+	if len(records) == 0 {
+		return GenesisHash
+	}
+
+	return records[len(records)-1].Hash
+}
+
+// Sign signs the hash of the last record of the audit trail with the key
+func Sign(key ed25519.PrivateKey, records []Record) Signature {
+	hash := LastHash(records)
+
+	return Signature{
+		Algorithm: Algorithm,
+		PublicKey: base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey)),
+		Hash:      hash,
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, []byte(hash))),
+	}
+}
+
+// VerifySignature checks the hash chain of the audit trail and that the signature is the signature
+// of its last record by the signature's public key
+func VerifySignature(signature Signature, records []Record) error {
+	if err := Verify(records); err != nil {
+		return err
+	}
+
+	publicKey, err := base64.StdEncoding.DecodeString(signature.PublicKey)
+	if err != nil || signature.Algorithm != Algorithm || len(publicKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("%w: the signature does not have an %s public key", error_msgs.Err39, Algorithm)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(signature.Signature)
+	if err != nil || signature.Hash != LastHash(records) ||
+		!ed25519.Verify(ed25519.PublicKey(publicKey), []byte(signature.Hash), sig) {
+		return fmt.Errorf("%w: the signature does not match the last record", error_msgs.Err39)
+	}
+
+	return nil
+}
+
+// LoadKey reads an Ed25519 private key from a PEM encoded PKCS #8 file, like the one written by
+// openssl genpkey -algorithm ed25519
+func LoadKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%w: %s", error_msgs.Err38, path)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", error_msgs.Err38, path, err)
+	}
+
+	edKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", error_msgs.Err38, path)
+	}
+
+	return edKey, nil
+}
+
+// hashRecord returns the hex encoded SHA-256 hash of the previous hash and the event's JSON
+func hashRecord(prevHash string, event premis.Event) (string, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(prevHash + "\n"))
+	hash.Write(data)
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
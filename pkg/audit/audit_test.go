@@ -0,0 +1,92 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/premis"
+	"github.com/UCLALibrary/pt-tools/pkg/pttest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newEvents returns three events on two objects
+func newEvents() []premis.Event {
+	return []premis.Event{
+		premis.NewEvent(premis.Ingestion, "ark:/a5388", "copied from /tmp/a5388", nil),
+		premis.NewEvent(premis.Ingestion, "ark:/b5488", "copied from /tmp/b5488", nil),
+		premis.NewEvent(premis.Deletion, "ark:/a5388", "deleted the object", nil),
+	}
+}
+
+// TestChain tests that each record holds the hash of the record before it and that changing a record
+// breaks the chain
+func TestChain(t *testing.T) {
+	records, err := Chain(newEvents())
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+
+	assert.Equal(t, GenesisHash, records[0].PrevHash)
+	assert.Equal(t, records[0].Hash, records[1].PrevHash)
+	assert.Equal(t, records[1].Hash, records[2].PrevHash)
+	assert.Equal(t, 3, records[2].Seq)
+	assert.NoError(t, Verify(records))
+
+	tests := []struct {
+		name   string
+		tamper func([]Record) []Record
+	}{
+		{name: "Changed event", tamper: func(r []Record) []Record { r[1].Event.Outcome = premis.Failure; return r }},
+		{name: "Removed record", tamper: func(r []Record) []Record { return append(r[:1], r[2:]...) }},
+		{name: "Reordered records", tamper: func(r []Record) []Record { r[0], r[1] = r[1], r[0]; return r }},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			records, err := Chain(newEvents())
+			require.NoError(t, err)
+			assert.ErrorIs(t, Verify(test.tamper(records)), error_msgs.Err39)
+		})
+	}
+}
+
+// TestSign tests that a signature checks against the trail it signed and not against a changed one
+func TestSign(t *testing.T) {
+	key, _, _ := pttest.SigningKey(t, t.TempDir())
+
+	records, err := Chain(newEvents())
+	require.NoError(t, err)
+
+	signature := Sign(key, records)
+	assert.Equal(t, Algorithm, signature.Algorithm)
+	assert.Equal(t, records[2].Hash, signature.Hash)
+	assert.NoError(t, VerifySignature(signature, records))
+
+	// A trail with its last record removed is a valid chain, but not the one that was signed
+	assert.ErrorIs(t, VerifySignature(signature, records[:2]), error_msgs.Err39)
+
+	otherKey, _, _ := pttest.SigningKey(t, t.TempDir())
+	signature.Signature = Sign(otherKey, records).Signature
+	assert.ErrorIs(t, VerifySignature(signature, records), error_msgs.Err39)
+}
+
+// TestLoadKey tests that an Ed25519 key is read from a PKCS #8 PEM file and other files are reported
+func TestLoadKey(t *testing.T) {
+	dir := t.TempDir()
+
+	key, keyPath, _ := pttest.SigningKey(t, dir)
+
+	loaded, err := LoadKey(keyPath)
+	require.NoError(t, err)
+	assert.True(t, key.Equal(loaded))
+
+	notKeyPath := filepath.Join(dir, "notkey.pem")
+	require.NoError(t, os.WriteFile(notKeyPath, []byte("not a key"), 0600))
+
+	_, err = LoadKey(notKeyPath)
+	assert.ErrorIs(t, err, error_msgs.Err38)
+}
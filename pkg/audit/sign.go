@@ -0,0 +1,199 @@
+package audit
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+)
+
+// SignatureExt is the extension of the detached signature written beside a signed file
+const SignatureExt = ".sig"
+
+// Trail is an audit trail as pt log export writes it, with its signature when it was signed
+type Trail struct {
+	Records   []Record   `json:"records"`
+	Signature *Signature `json:"signature,omitempty"`
+}
+
+// SignFile signs the SHA-256 hash of the file with the key and writes the signature beside it, named
+// after the file with .sig added, returning the path of the signature
+func SignFile(key ed25519.PrivateKey, path string) (string, error) {
+	hash, err := hashFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	signature := Signature{
+		Algorithm: Algorithm,
+		PublicKey: base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey)),
+		Hash:      hash,
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, []byte(hash))),
+	}
+
+	data, err := json.MarshalIndent(signature, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	sigPath := path + SignatureExt
+	if err := os.WriteFile(sigPath, append(data, '\n'), 0644); err != nil {
+		return "", err
+	}
+
+	return sigPath, nil
+}
+
+// VerifyFile checks that the signature beside the file is the signature of its SHA-256 hash by the
+// signature's public key, and returns the signature. The file is not trusted unless the public key is
+// one the signer is known by, so it must be the public key that is given, when one is.
+func VerifyFile(path string, publicKey ed25519.PublicKey) (Signature, error) {
+	var signature Signature
+
+	data, err := os.ReadFile(path + SignatureExt)
+	if err != nil {
+		return signature, err
+	}
+	if err := json.Unmarshal(data, &signature); err != nil {
+		return signature, fmt.Errorf("%w: %s%s: %v", error_msgs.Err50, path, SignatureExt, err)
+	}
+
+	if err := checkKey(signature, publicKey); err != nil {
+		return signature, fmt.Errorf("%w: %s: %v", error_msgs.Err50, path, err)
+	}
+
+	hash, err := hashFile(path)
+	if err != nil {
+		return signature, err
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(signature.Signature)
+	if err != nil || signature.Hash != hash || !ed25519.Verify(signingKey(signature), []byte(hash), sig) {
+		return signature, fmt.Errorf("%w: %s", error_msgs.Err50, path)
+	}
+
+	return signature, nil
+}
+
+// VerifyTrail checks the hash chain and signature of an audit trail that was signed as it was exported,
+// and that the signature's public key is the one given, when one is
+func VerifyTrail(trail Trail, publicKey ed25519.PublicKey) error {
+	if trail.Signature == nil {
+		return fmt.Errorf("%w: the audit trail is not signed", error_msgs.Err39)
+	}
+
+	if err := checkKey(*trail.Signature, publicKey); err != nil {
+		return fmt.Errorf("%w: %v", error_msgs.Err39, err)
+	}
+
+	return VerifySignature(*trail.Signature, trail.Records)
+}
+
+// ReadTrail reads an audit trail written by pt log export, either one JSON object with the records and the
+// signature, or a record on each line followed by the signature on the last line
+func ReadTrail(reader io.Reader) (Trail, error) {
+	var trail Trail
+
+	decoder := json.NewDecoder(reader)
+	for {
+		// A value is the whole trail, a record, or the signature line
+		var value struct {
+			Record
+			Records   []Record   `json:"records"`
+			Signature *Signature `json:"signature"`
+		}
+
+		if err := decoder.Decode(&value); errors.Is(err, io.EOF) {
+			return trail, nil
+		} else if err != nil {
+			return trail, fmt.Errorf("%w: %v", error_msgs.Err39, err)
+		}
+
+		switch {
+		case value.Records != nil:
+			trail.Records = append(trail.Records, value.Records...)
+		case value.Signature == nil:
+			trail.Records = append(trail.Records, value.Record)
+		}
+		if value.Signature != nil {
+			trail.Signature = value.Signature
+		}
+	}
+}
+
+// LoadPublicKey reads an Ed25519 public key from a PEM encoded PKIX file, like the one written by
+// openssl pkey -pubout
+func LoadPublicKey(path string) (ed25519.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%w: %s", error_msgs.Err51, path)
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", error_msgs.Err51, path, err)
+	}
+
+	edKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", error_msgs.Err51, path)
+	}
+
+	return edKey, nil
+}
+
+// checkKey checks that the signature has an Ed25519 public key, and that it is the public key that is
+// given, when one is
+func checkKey(signature Signature, publicKey ed25519.PublicKey) error {
+	signer := signingKey(signature)
+	if signature.Algorithm != Algorithm || signer == nil {
+		return fmt.Errorf("the signature does not have an %s public key", Algorithm)
+	}
+
+	if publicKey != nil && !bytes.Equal(signer, publicKey) {
+		return errors.New("the signature was not made with the public key")
+	}
+
+	return nil
+}
+
+// signingKey returns the public key of the signature, or nil when it is not an Ed25519 public key
+func signingKey(signature Signature) ed25519.PublicKey {
+	publicKey, err := base64.StdEncoding.DecodeString(signature.PublicKey)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return nil
+	}
+
+	return ed25519.PublicKey(publicKey)
+}
+
+// hashFile returns the hex encoded SHA-256 hash of the file
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,96 @@
+package audit
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pttest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSignFile tests that a detached signature checks against the file it signed, and not against a changed
+// file or another public key
+func TestSignFile(t *testing.T) {
+	key, _, _ := pttest.SigningKey(t, t.TempDir())
+	publicKey := key.Public().(ed25519.PublicKey)
+
+	path := filepath.Join(t.TempDir(), "manifest-sha256.txt")
+	require.NoError(t, os.WriteFile(path, []byte("abc  hello.txt\n"), 0644))
+
+	sigPath, err := SignFile(key, path)
+	require.NoError(t, err)
+	assert.Equal(t, path+SignatureExt, sigPath)
+
+	signature, err := VerifyFile(path, publicKey)
+	require.NoError(t, err)
+	assert.Equal(t, Algorithm, signature.Algorithm)
+
+	// Without a public key the file is only checked against the key in its signature
+	_, err = VerifyFile(path, nil)
+	assert.NoError(t, err)
+
+	otherKey, _, _ := pttest.SigningKey(t, t.TempDir())
+	_, err = VerifyFile(path, otherKey.Public().(ed25519.PublicKey))
+	assert.ErrorIs(t, err, error_msgs.Err50)
+
+	require.NoError(t, os.WriteFile(path, []byte("abd  hello.txt\n"), 0644))
+	_, err = VerifyFile(path, publicKey)
+	assert.ErrorIs(t, err, error_msgs.Err50)
+
+	_, err = VerifyFile(filepath.Join(t.TempDir(), "unsigned.txt"), nil)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+// TestReadTrail tests that an audit trail is read back from both formats of pt log export
+func TestReadTrail(t *testing.T) {
+	key, _, _ := pttest.SigningKey(t, t.TempDir())
+	publicKey := key.Public().(ed25519.PublicKey)
+
+	records, err := Chain(newEvents())
+	require.NoError(t, err)
+	signature := Sign(key, records)
+
+	var jsonl bytes.Buffer
+	encoder := json.NewEncoder(&jsonl)
+	for _, record := range records {
+		require.NoError(t, encoder.Encode(record))
+	}
+	require.NoError(t, encoder.Encode(map[string]Signature{"signature": signature}))
+
+	object, err := json.MarshalIndent(Trail{Records: records, Signature: &signature}, "", "  ")
+	require.NoError(t, err)
+
+	for name, data := range map[string][]byte{"jsonl": jsonl.Bytes(), "json": object} {
+		t.Run(name, func(t *testing.T) {
+			trail, err := ReadTrail(bytes.NewReader(data))
+			require.NoError(t, err)
+			assert.Equal(t, records, trail.Records)
+			assert.NoError(t, VerifyTrail(trail, publicKey))
+		})
+	}
+
+	// A trail that was not signed has nothing to check it by
+	assert.ErrorIs(t, VerifyTrail(Trail{Records: records}, nil), error_msgs.Err39)
+}
+
+// TestLoadPublicKey tests that the public half of a key is read from PEM and anything else is refused
+func TestLoadPublicKey(t *testing.T) {
+	dir := t.TempDir()
+	key, _, keyPath := pttest.SigningKey(t, dir)
+	publicKey := key.Public().(ed25519.PublicKey)
+
+	loaded, err := LoadPublicKey(keyPath)
+	require.NoError(t, err)
+	assert.Equal(t, publicKey, loaded)
+
+	notKey := filepath.Join(dir, "notkey.pem")
+	require.NoError(t, os.WriteFile(notKey, []byte("not a key"), 0644))
+	_, err = LoadPublicKey(notKey)
+	assert.ErrorIs(t, err, error_msgs.Err51)
+}
@@ -0,0 +1,433 @@
+/*
+The bagit package writes a pairtree object as a bag, as described by the BagIt specification (RFC
+8493), and checks a bag before its payload is read back into a pairtree. A bag is a directory with
+the files of the object in data/, a bagit.txt declaration, a bag-info.txt with the ID of the object,
+a payload manifest with the checksum of each file, and a tag manifest with the checksum of each of
+the other files.
+*/
+package bagit
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/UCLALibrary/pt-tools/pkg/checksum"
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+)
+
+const (
+	// Version is the version of the BagIt specification the bags are written with
+	Version = "1.0"
+	// DataDir is the directory of the bag that has the files of the object
+	DataDir = "data"
+	// DeclarationName is the name of the file that declares the directory is a bag
+	DeclarationName = "bagit.txt"
+	// InfoName is the name of the file with the metadata of the bag
+	InfoName = "bag-info.txt"
+	// IDLabel is the label of bag-info.txt that has the ID of the object
+	IDLabel = "External-Identifier"
+)
+
+// Bag is a bag that has passed its checks
+type Bag struct {
+	Dir string
+	// Info is the metadata of bag-info.txt by label, with the first value of a label that is repeated
+	Info map[string]string
+	// Files are the slash separated paths of the files of the payload relative to the data directory
+	Files []string
+}
+
+// ID returns the ID of the object in the bag, which is empty when bag-info.txt does not have one
+func (b *Bag) ID() string {
+	return b.Info[IDLabel]
+}
+
+// Write writes the object at objPath with the ID as a bag in bagDir, which must not exist, created by
+// the agent. Hidden files are only in the bag when includeHidden is true. The files are hashed with
+// the algorithm of the options, after they are copied, so the manifest is of what is in the bag.
+func Write(ctx context.Context, bagDir, objPath, id, agent string, includeHidden bool, opts checksum.Options) error {
+	algorithm := opts.Algorithm
+	if algorithm == "" {
+		algorithm = checksum.SHA256
+	}
+
+	relPaths, err := payload(ctx, objPath, includeHidden)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Mkdir(bagDir, 0755); err != nil {
+		return err
+	}
+
+	paths := make([]string, len(relPaths))
+	var size int64
+	for i, relPath := range relPaths {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		paths[i] = filepath.Join(bagDir, DataDir, filepath.FromSlash(relPath))
+		written, err := copyFile(filepath.Join(objPath, filepath.FromSlash(relPath)), paths[i])
+		if err != nil {
+			return err
+		}
+		size += written
+	}
+
+	// An object without files still has a data directory
+	if err := os.MkdirAll(filepath.Join(bagDir, DataDir), 0755); err != nil {
+		return err
+	}
+
+	sums, err := checksum.Files(ctx, paths, opts)
+	if err != nil {
+		return err
+	}
+
+	info := fmt.Sprintf("Bag-Software-Agent: %s\nBagging-Date: %s\n%s: %s\nPayload-Oxum: %d.%d\n",
+		agent, time.Now().UTC().Format(time.DateOnly), IDLabel, id, size, len(relPaths))
+
+	tagFiles := []struct{ name, content string }{
+		{DeclarationName, "BagIt-Version: " + Version + "\nTag-File-Character-Encoding: UTF-8\n"},
+		{InfoName, info},
+	}
+
+	for _, tagFile := range tagFiles {
+		if err := os.WriteFile(filepath.Join(bagDir, tagFile.name), []byte(tagFile.content), 0644); err != nil {
+			return err
+		}
+	}
+
+	opts.Algorithm = algorithm
+	return WriteManifests(ctx, bagDir, relPaths, sums, []string{DeclarationName, InfoName}, opts)
+}
+
+// WriteManifests writes the payload manifest of the files of the payload, whose slash separated paths are
+// relative to the data directory, with their checksums in the algorithm of the options. It then writes
+// the tag manifest of the tag files of bagDir that are named and of the payload manifest. The manifests
+// are what a bag of the files has, so a directory with them and the files in data/ only needs bagit.txt.
+func WriteManifests(ctx context.Context, bagDir string, relPaths []string, sums []checksum.File, tagFiles []string, opts checksum.Options) error {
+	manifestName := checksum.ManifestName(opts.Algorithm)
+
+	var manifest strings.Builder
+	for i, sum := range sums {
+		fmt.Fprintf(&manifest, "%s  %s\n", sum.Checksum, encodePath(path.Join(DataDir, relPaths[i])))
+	}
+
+	if err := os.WriteFile(filepath.Join(bagDir, manifestName), []byte(manifest.String()), 0644); err != nil {
+		return err
+	}
+
+	tagFiles = append(slices.Clone(tagFiles), manifestName)
+	tagPaths := make([]string, len(tagFiles))
+	for i, tagFile := range tagFiles {
+		tagPaths[i] = filepath.Join(bagDir, tagFile)
+	}
+
+	tagSums, err := checksum.Files(ctx, tagPaths, opts)
+	if err != nil {
+		return err
+	}
+
+	var tagManifest strings.Builder
+	for i, sum := range tagSums {
+		fmt.Fprintf(&tagManifest, "%s  %s\n", sum.Checksum, encodePath(tagFiles[i]))
+	}
+
+	return os.WriteFile(filepath.Join(bagDir, "tag"+manifestName), []byte(tagManifest.String()), 0644)
+}
+
+// payload returns the slash separated paths of the regular files of the object, relative to objPath
+func payload(ctx context.Context, objPath string, includeHidden bool) ([]string, error) {
+	var relPaths []string
+
+	err := filepath.WalkDir(objPath, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// Stop before the next file once the context is canceled
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if filePath != objPath && !includeHidden && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(objPath, filePath)
+		if err != nil {
+			return err
+		}
+		relPaths = append(relPaths, filepath.ToSlash(rel))
+
+		return nil
+	})
+
+	return relPaths, err
+}
+
+// copyFile copies the file at src to dest, creating the directories of dest, and returns its size
+func copyFile(src, dest string) (written int64, err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return 0, err
+	}
+
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		err = errors.Join(err, out.Close())
+	}()
+
+	return io.Copy(out, in)
+}
+
+// Check checks the bag in bagDir against the BagIt specification before its payload is read. The bag
+// must have a declaration, and every payload manifest of an algorithm that is supported must list every
+// file of the payload with its checksum. The tag manifests and the Payload-Oxum of bag-info.txt are
+// checked when the bag has them. A bag that fails a check returns an error that wraps Err42.
+func Check(ctx context.Context, bagDir string, opts checksum.Options) (*Bag, error) {
+	declaration, err := readTags(filepath.Join(bagDir, DeclarationName))
+	if err != nil {
+		return nil, invalid(err)
+	}
+	if declaration["BagIt-Version"] == "" {
+		return nil, invalid(fmt.Errorf("%s does not have a BagIt-Version", DeclarationName))
+	}
+
+	// bag-info.txt is optional
+	bag := &Bag{Dir: bagDir}
+	if bag.Info, err = readTags(filepath.Join(bagDir, InfoName)); errors.Is(err, fs.ErrNotExist) {
+		bag.Info = map[string]string{}
+	} else if err != nil {
+		return nil, invalid(err)
+	}
+
+	dataDir := filepath.Join(bagDir, DataDir)
+	if bag.Files, err = payload(ctx, dataDir, true); err != nil {
+		return nil, invalid(err)
+	}
+
+	manifests := 0
+	for _, algorithm := range checksum.Algorithms {
+		opts.Algorithm = algorithm
+
+		manifestPath := filepath.Join(bagDir, checksum.ManifestName(algorithm))
+		sums, err := readManifest(manifestPath)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, invalid(err)
+		}
+		manifests++
+
+		for _, file := range bag.Files {
+			if _, ok := sums[path.Join(DataDir, file)]; !ok {
+				return nil, invalid(fmt.Errorf("%s is not in %s", path.Join(DataDir, file), filepath.Base(manifestPath)))
+			}
+		}
+		if err := checkSums(ctx, bagDir, sums, opts); err != nil {
+			return nil, err
+		}
+
+		tagSums, err := readManifest(filepath.Join(bagDir, "tag"+checksum.ManifestName(algorithm)))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, invalid(err)
+		}
+		if err := checkSums(ctx, bagDir, tagSums, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	if manifests == 0 {
+		return nil, invalid(fmt.Errorf("the bag does not have a manifest of %s", strings.Join(checksum.Algorithms, ", ")))
+	}
+
+	if oxum := bag.Info["Payload-Oxum"]; oxum != "" {
+		if err := checkOxum(dataDir, bag.Files, oxum); err != nil {
+			return nil, err
+		}
+	}
+
+	return bag, nil
+}
+
+// invalid wraps the reason the bag is not valid in Err42
+func invalid(err error) error {
+	return fmt.Errorf("%w: %w", error_msgs.Err42, err)
+}
+
+// readTags reads the labels and values of a tag file, where a line that starts with whitespace
+// continues the value of the line before it
+func readTags(tagPath string) (map[string]string, error) {
+	file, err := os.Open(tagPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	tags := map[string]string{}
+	var label string
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+
+		if (line[0] == ' ' || line[0] == '\t') && label != "" {
+			tags[label] += " " + strings.TrimSpace(line)
+			continue
+		}
+
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("%s has a line that is not a label and value: %q", filepath.Base(tagPath), line)
+		}
+
+		label = strings.TrimSpace(name)
+		if _, ok := tags[label]; !ok {
+			tags[label] = strings.TrimSpace(value)
+		} else {
+			// Only the first of a repeated label is kept, continuation lines are not added to it
+			label = ""
+		}
+	}
+
+	return tags, scanner.Err()
+}
+
+// readManifest reads the checksums of a manifest by the path of each file
+func readManifest(manifestPath string) (map[string]string, error) {
+	file, err := os.Open(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	sums := map[string]string{}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+
+		sum, filePath, ok := strings.Cut(line, " ")
+		if !ok {
+			return nil, fmt.Errorf("%s has a line that is not a checksum and path: %q", filepath.Base(manifestPath), line)
+		}
+		sums[decodePath(strings.TrimLeft(filePath, " *"))] = strings.ToLower(sum)
+	}
+
+	return sums, scanner.Err()
+}
+
+// checkSums checks that the files of the bag have the checksums of a manifest
+func checkSums(ctx context.Context, bagDir string, sums map[string]string, opts checksum.Options) error {
+	relPaths := make([]string, 0, len(sums))
+	for relPath := range sums {
+		// A manifest can not point outside the bag
+		if !filepath.IsLocal(filepath.FromSlash(relPath)) {
+			return invalid(fmt.Errorf("%s is not a path in the bag", relPath))
+		}
+		relPaths = append(relPaths, relPath)
+	}
+	sort.Strings(relPaths)
+
+	paths := make([]string, len(relPaths))
+	for i, relPath := range relPaths {
+		paths[i] = filepath.Join(bagDir, filepath.FromSlash(relPath))
+	}
+
+	files, err := checksum.Files(ctx, paths, opts)
+	if errors.Is(err, fs.ErrNotExist) {
+		return invalid(err)
+	} else if err != nil {
+		return err
+	}
+
+	for i, file := range files {
+		if file.Checksum != sums[relPaths[i]] {
+			return invalid(fmt.Errorf("the %s checksum of %s does not match", opts.Algorithm, relPaths[i]))
+		}
+	}
+
+	return nil
+}
+
+// checkOxum checks the Payload-Oxum, the number of bytes and files of the payload, against the files
+func checkOxum(dataDir string, files []string, oxum string) error {
+	bytesText, countText, _ := strings.Cut(oxum, ".")
+	expectedBytes, bytesErr := strconv.ParseInt(bytesText, 10, 64)
+	expectedCount, countErr := strconv.Atoi(countText)
+	if bytesErr != nil || countErr != nil {
+		return invalid(fmt.Errorf("the Payload-Oxum %q is not a number of bytes and files", oxum))
+	}
+
+	var size int64
+	for _, file := range files {
+		info, err := os.Stat(filepath.Join(dataDir, filepath.FromSlash(file)))
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+	}
+
+	if size != expectedBytes || len(files) != expectedCount {
+		return invalid(fmt.Errorf("the payload has %d bytes in %d files, not the Payload-Oxum %s", size, len(files), oxum))
+	}
+
+	return nil
+}
+
+// pathEncoder percent-encodes the characters of a path that can not be written as they are in a manifest
+var pathEncoder = strings.NewReplacer("%", "%25", "\n", "%0A", "\r", "%0D")
+
+// pathDecoder decodes a path of a manifest, where the hex digits may be upper or lower case
+var pathDecoder = strings.NewReplacer("%25", "%", "%0A", "\n", "%0a", "\n", "%0D", "\r", "%0d", "\r")
+
+func encodePath(p string) string {
+	return pathEncoder.Replace(p)
+}
+
+func decodePath(p string) string {
+	return pathDecoder.Replace(p)
+}
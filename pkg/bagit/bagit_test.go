@@ -0,0 +1,116 @@
+package bagit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/UCLALibrary/pt-tools/pkg/checksum"
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeObject creates the files of an object, with a hidden file and a file whose name has a percent
+// sign that is encoded in the manifests, and returns its directory
+func writeObject(t *testing.T) string {
+	objPath := t.TempDir()
+	for name, content := range map[string]string{"a.txt": "hello\n", "sub/b%.txt": "", ".hidden": "secret"} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(objPath, name)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(objPath, name), []byte(content), 0644))
+	}
+
+	return objPath
+}
+
+// TestWrite tests that a bag that is written passes its checks and has the files of the object
+func TestWrite(t *testing.T) {
+	objPath := writeObject(t)
+	bagDir := filepath.Join(t.TempDir(), "bag")
+
+	require.NoError(t, Write(context.Background(), bagDir, objPath, "ark:/a5388", "pt test", false, checksum.Options{}))
+
+	manifest, err := os.ReadFile(filepath.Join(bagDir, "manifest-sha256.txt"))
+	require.NoError(t, err)
+	assert.Contains(t, string(manifest), "  data/sub/b%25.txt\n")
+	assert.FileExists(t, filepath.Join(bagDir, "tagmanifest-sha256.txt"))
+	assert.NoFileExists(t, filepath.Join(bagDir, DataDir, ".hidden"))
+
+	bag, err := Check(context.Background(), bagDir, checksum.Options{})
+	require.NoError(t, err)
+	assert.Equal(t, "ark:/a5388", bag.ID())
+	assert.Equal(t, []string{"a.txt", "sub/b%.txt"}, bag.Files)
+	assert.Equal(t, "6.2", bag.Info["Payload-Oxum"])
+}
+
+// TestCheck tests that a bag that does not match its manifests or its Payload-Oxum fails its checks
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(t *testing.T, bagDir string)
+	}{
+		{name: "Changed file", change: func(t *testing.T, bagDir string) {
+			require.NoError(t, os.WriteFile(filepath.Join(bagDir, DataDir, "a.txt"), []byte("HELLO\n"), 0644))
+		}},
+		{name: "File not in the manifest", change: func(t *testing.T, bagDir string) {
+			require.NoError(t, os.WriteFile(filepath.Join(bagDir, DataDir, "extra.txt"), nil, 0644))
+		}},
+		{name: "Missing file", change: func(t *testing.T, bagDir string) {
+			require.NoError(t, os.Remove(filepath.Join(bagDir, DataDir, "a.txt")))
+		}},
+		{name: "Changed tag file", change: func(t *testing.T, bagDir string) {
+			info, err := os.ReadFile(filepath.Join(bagDir, InfoName))
+			require.NoError(t, err)
+			require.NoError(t, os.WriteFile(filepath.Join(bagDir, InfoName), append(info, "Source-Organization: UCLA\n"...), 0644))
+		}},
+		{name: "Wrong Payload-Oxum", change: func(t *testing.T, bagDir string) {
+			info, err := os.ReadFile(filepath.Join(bagDir, InfoName))
+			require.NoError(t, err)
+			require.NoError(t, os.WriteFile(filepath.Join(bagDir, InfoName),
+				[]byte(strings.Replace(string(info), "Payload-Oxum: 6.2", "Payload-Oxum: 7.2", 1)), 0644))
+			require.NoError(t, os.Remove(filepath.Join(bagDir, "tagmanifest-sha256.txt")))
+		}},
+		{name: "No manifest", change: func(t *testing.T, bagDir string) {
+			require.NoError(t, os.Remove(filepath.Join(bagDir, "manifest-sha256.txt")))
+		}},
+		{name: "No declaration", change: func(t *testing.T, bagDir string) {
+			require.NoError(t, os.Remove(filepath.Join(bagDir, DeclarationName)))
+		}},
+		{name: "Path outside the bag", change: func(t *testing.T, bagDir string) {
+			manifest, err := os.OpenFile(filepath.Join(bagDir, "manifest-sha256.txt"), os.O_APPEND|os.O_WRONLY, 0644)
+			require.NoError(t, err)
+			_, err = manifest.WriteString("e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855  ../outside.txt\n")
+			require.NoError(t, err)
+			require.NoError(t, manifest.Close())
+			require.NoError(t, os.Remove(filepath.Join(bagDir, "tagmanifest-sha256.txt")))
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			bagDir := filepath.Join(t.TempDir(), "bag")
+			require.NoError(t, Write(context.Background(), bagDir, writeObject(t), "ark:/a5388", "pt test", false,
+				checksum.Options{}))
+
+			test.change(t, bagDir)
+
+			_, err := Check(context.Background(), bagDir, checksum.Options{})
+			assert.ErrorIs(t, err, error_msgs.Err42)
+		})
+	}
+}
+
+// TestReadTags tests that the values of continued lines are joined and the first of a repeated label is kept
+func TestReadTags(t *testing.T) {
+	tagPath := filepath.Join(t.TempDir(), InfoName)
+	require.NoError(t, os.WriteFile(tagPath, []byte("External-Description: A long\n  description\r\n"+
+		"Contact-Name: First\nContact-Name: Second\n"), 0644))
+
+	tags, err := readTags(tagPath)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"External-Description": "A long description", "Contact-Name": "First"}, tags)
+}
@@ -0,0 +1,49 @@
+package checksum
+
+import (
+	"io/fs"
+	"sync"
+	"time"
+)
+
+// Cache keeps the checksums of files hashed in the process, so a command that passes over an object
+// more than once, such as to zip its files and then write their manifest, reads each file once. A file
+// is only taken from the cache while its size and modification time are those it was hashed with.
+type Cache struct {
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+// cacheEntry is a file in the cache with the size and modification time of the file when it was hashed
+type cacheEntry struct {
+	file    File
+	size    int64
+	modTime time.Time
+}
+
+// NewCache creates an empty cache that can be shared by the passes of a command
+func NewCache() *Cache {
+	return &Cache{entries: map[string]cacheEntry{}}
+}
+
+// Get returns the file at the path from the cache, if it is there and info shows it has not changed
+func (c *Cache) Get(path string, info fs.FileInfo) (File, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[path]
+	if !ok || entry.size != info.Size() || !entry.modTime.Equal(info.ModTime()) {
+		return File{}, false
+	}
+
+	return entry.file, true
+}
+
+// Put keeps the file in the cache with the size and modification time in info, which should be read
+// before the file was hashed so a change while it was hashed is not cached as the file's checksum
+func (c *Cache) Put(file File, info fs.FileInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[file.Path] = cacheEntry{file: file, size: info.Size(), modTime: info.ModTime()}
+}
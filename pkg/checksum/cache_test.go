@@ -0,0 +1,49 @@
+package checksum
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCache tests that a file is taken from the cache until its size or modification time changes
+func TestCache(t *testing.T) {
+	paths := createFiles(t, "hello\n")
+	modTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, os.Chtimes(paths[0], modTime, modTime))
+
+	cache := NewCache()
+	files, err := Files(context.Background(), paths, Options{Cache: cache})
+	require.NoError(t, err)
+	assert.Equal(t, helloSum, files[0].Checksum)
+
+	// Content of the same size and modification time is not read again
+	require.NoError(t, os.WriteFile(paths[0], []byte("HELLO\n"), 0644))
+	require.NoError(t, os.Chtimes(paths[0], modTime, modTime))
+
+	files, err = Files(context.Background(), paths, Options{Cache: cache})
+	require.NoError(t, err)
+	assert.Equal(t, helloSum, files[0].Checksum)
+
+	// A head that was not kept when the file was hashed is read
+	files, err = Files(context.Background(), paths, Options{Cache: cache, HeadSize: 512})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("HELLO\n"), files[0].Head)
+
+	// A file modified since it was hashed is hashed again
+	require.NoError(t, os.Chtimes(paths[0], modTime, modTime.Add(time.Second)))
+
+	files, err = Files(context.Background(), paths, Options{Cache: cache})
+	require.NoError(t, err)
+	assert.NotEqual(t, helloSum, files[0].Checksum)
+
+	info, err := os.Stat(paths[0])
+	require.NoError(t, err)
+	cached, ok := cache.Get(paths[0], info)
+	require.True(t, ok)
+	assert.Equal(t, files[0].Checksum, cached.Checksum)
+}
@@ -0,0 +1,244 @@
+/*
+The checksum package computes the checksums of files in parallel, with SHA-256 unless another
+algorithm is chosen. Files are hashed by a worker per CPU, and the number of reads that happen at
+once can be kept lower than the number of workers, so hashing a large object on storage that slows
+down with many readers still keeps the CPUs busy with the chunks that have been read.
+*/
+package checksum
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+)
+
+// chunkSize is the size of the chunks each worker reads files in
+const chunkSize = 1 << 20
+
+// The names of the algorithms files can be hashed with, which are those of BagIt manifests
+const (
+	MD5    = "md5"
+	SHA1   = "sha1"
+	SHA256 = "sha256"
+	SHA512 = "sha512"
+)
+
+// Algorithms are the names of the algorithms files can be hashed with, from the weakest
+var Algorithms = []string{MD5, SHA1, SHA256, SHA512}
+
+// hashes create the hash of each algorithm
+var hashes = map[string]func() hash.Hash{MD5: md5.New, SHA1: sha1.New, SHA256: sha256.New, SHA512: sha512.New}
+
+// Supported checks if files can be hashed with the algorithm
+func Supported(algorithm string) bool {
+	_, ok := hashes[algorithm]
+	return ok
+}
+
+// ManifestName is the name of the checksum manifest of the algorithm, like manifest-sha256.txt
+func ManifestName(algorithm string) string {
+	return "manifest-" + algorithm + ".txt"
+}
+
+// Options bounds how many files are hashed and read at once
+type Options struct {
+	// Workers is the number of files hashed at once, zero uses one worker per CPU
+	Workers int
+	// IOLimit is the number of reads that happen at once, zero lets every worker read at once
+	IOLimit int
+	// HeadSize is the number of bytes kept from the start of each file, such as to detect its type
+	HeadSize int
+	// Cache, when set, has the files that were already hashed in the process and keeps the files hashed
+	Cache *Cache
+	// Algorithm is the name of the algorithm the files are hashed with, SHA256 when it is empty
+	Algorithm string
+}
+
+// algorithm returns the name of the algorithm of the options
+func (o Options) algorithm() string {
+	if o.Algorithm == "" {
+		return SHA256
+	}
+	return o.Algorithm
+}
+
+// File is the checksum and size of a file
+type File struct {
+	Path string
+	// Checksum is the hex encoded checksum of the file with the Algorithm
+	Checksum  string
+	Algorithm string
+	Size      int64
+	// Head is the start of the file, up to the HeadSize of the options
+	Head []byte
+}
+
+// Files hashes the files at the paths in parallel and returns them in the order of the paths. The
+// first file that can not be read, or the context being canceled, stops the hashing of the rest.
+func Files(ctx context.Context, paths []string, opts Options) ([]File, error) {
+	if !Supported(opts.algorithm()) {
+		return nil, fmt.Errorf("%w: %q", error_msgs.Err53, opts.Algorithm)
+	}
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	workers = min(workers, len(paths))
+
+	var reads chan struct{}
+	if opts.IOLimit > 0 {
+		reads = make(chan struct{}, opts.IOLimit)
+	}
+
+	hashCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	files := make([]File, len(paths))
+	next := make(chan int)
+
+	var wg sync.WaitGroup
+	var failed sync.Once
+	var firstErr error
+
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			buf := make([]byte, chunkSize)
+			for i := range next {
+				file, err := cachedFile(hashCtx, paths[i], buf, reads, opts)
+				if err != nil {
+					failed.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					continue
+				}
+				files[i] = file
+			}
+		}()
+	}
+
+feed:
+	for i := range paths {
+		select {
+		case next <- i:
+		case <-hashCtx.Done():
+			break feed
+		}
+	}
+	close(next)
+	wg.Wait()
+
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	return files, nil
+}
+
+// cachedFile returns the file from the cache when it has not changed since it was hashed, and hashes
+// it and keeps it in the cache otherwise
+func cachedFile(ctx context.Context, path string, buf []byte, reads chan struct{}, opts Options) (File, error) {
+	if opts.Cache == nil {
+		return hashFile(ctx, path, buf, reads, opts)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return File{}, err
+	}
+
+	if file, ok := opts.Cache.Get(path, info); ok && file.Algorithm == opts.algorithm() &&
+		int64(len(file.Head)) >= min(int64(opts.HeadSize), file.Size) {
+		return file, nil
+	}
+
+	file, err := hashFile(ctx, path, buf, reads, opts)
+	if err != nil {
+		return File{}, err
+	}
+	opts.Cache.Put(file, info)
+
+	return file, nil
+}
+
+// Reader hashes what is read from the reader until its end, like a file of Files, and returns it as the
+// file at the path
+func Reader(ctx context.Context, r io.Reader, path string, opts Options) (File, error) {
+	if !Supported(opts.algorithm()) {
+		return File{}, fmt.Errorf("%w: %q", error_msgs.Err53, opts.Algorithm)
+	}
+
+	return hashReader(ctx, r, path, make([]byte, chunkSize), nil, opts)
+}
+
+// hashFile reads the file at the path a chunk at a time into the buffer, waiting for a turn to read
+// when the reads are limited
+func hashFile(ctx context.Context, path string, buf []byte, reads chan struct{}, opts Options) (File, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return File{}, err
+	}
+	defer in.Close()
+
+	return hashReader(ctx, in, path, buf, reads, opts)
+}
+
+// hashReader reads the reader a chunk at a time into the buffer for hashFile and Reader
+func hashReader(ctx context.Context, in io.Reader, path string, buf []byte, reads chan struct{},
+	opts Options) (File, error) {
+	file := File{Path: path, Algorithm: opts.algorithm()}
+	hash := hashes[file.Algorithm]()
+	headSize := opts.HeadSize
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return File{}, err
+		}
+
+		if reads != nil {
+			select {
+			case reads <- struct{}{}:
+			case <-ctx.Done():
+				return File{}, ctx.Err()
+			}
+		}
+
+		n, readErr := io.ReadFull(in, buf)
+		if reads != nil {
+			<-reads
+		}
+
+		hash.Write(buf[:n])
+		file.Size += int64(n)
+		if room := headSize - len(file.Head); room > 0 {
+			file.Head = append(file.Head, buf[:min(room, n)]...)
+		}
+
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		} else if readErr != nil {
+			return File{}, readErr
+		}
+	}
+
+	file.Checksum = hex.EncodeToString(hash.Sum(nil))
+	return file, nil
+}
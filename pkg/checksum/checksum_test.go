@@ -0,0 +1,157 @@
+package checksum
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// SHA-256 of "hello\n" and of nothing
+const (
+	helloSum = "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"
+	emptySum = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// createFiles creates the files with the contents in a temporary directory and returns their paths
+func createFiles(t *testing.T, contents ...string) []string {
+	dir := t.TempDir()
+
+	paths := make([]string, len(contents))
+	for i, content := range contents {
+		paths[i] = filepath.Join(dir, fmt.Sprintf("file%d", i))
+		require.NoError(t, os.WriteFile(paths[i], []byte(content), 0644))
+	}
+
+	return paths
+}
+
+// TestFiles tests that files are returned with their checksums in the order of the paths however
+// many of them are hashed and read at once
+func TestFiles(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{name: "Worker per CPU", opts: Options{}},
+		{name: "One worker", opts: Options{Workers: 1}},
+		{name: "Reads limited below the workers", opts: Options{Workers: 8, IOLimit: 1}},
+		{name: "More workers than files", opts: Options{Workers: 100}},
+	}
+
+	var contents []string
+	for i := 0; i < 20; i++ {
+		contents = append(contents, "hello\n", "")
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			paths := createFiles(t, contents...)
+
+			files, err := Files(context.Background(), paths, test.opts)
+			require.NoError(t, err)
+			require.Len(t, files, len(paths))
+
+			for i, file := range files {
+				assert.Equal(t, paths[i], file.Path)
+				if i%2 == 0 {
+					assert.Equal(t, helloSum, file.Checksum)
+					assert.Equal(t, int64(6), file.Size)
+				} else {
+					assert.Equal(t, emptySum, file.Checksum)
+					assert.Zero(t, file.Size)
+				}
+				assert.Empty(t, file.Head)
+			}
+		})
+	}
+}
+
+// TestFilesLarge tests that a file of several chunks is hashed whole and that its head is kept
+func TestFilesLarge(t *testing.T) {
+	content := make([]byte, 2*chunkSize+7)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+
+	path := filepath.Join(t.TempDir(), "large")
+	require.NoError(t, os.WriteFile(path, content, 0644))
+
+	files, err := Files(context.Background(), []string{path}, Options{HeadSize: 512})
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	assert.Equal(t, int64(len(content)), files[0].Size)
+	sum := sha256.Sum256(content)
+	assert.Equal(t, hex.EncodeToString(sum[:]), files[0].Checksum)
+	assert.Equal(t, content[:512], files[0].Head)
+}
+
+// TestFilesError tests that a file that can not be read stops the hashing with its error
+func TestFilesError(t *testing.T) {
+	paths := createFiles(t, "hello\n", "hello\n")
+	paths = append(paths, filepath.Join(t.TempDir(), "missing"))
+
+	_, err := Files(context.Background(), paths, Options{Workers: 2})
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+// TestFilesCanceled tests that hashing stops when the context is canceled
+func TestFilesCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := Files(ctx, createFiles(t, "hello\n", ""), Options{})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+// TestFilesAlgorithms tests that files are hashed with the algorithm of the options
+func TestFilesAlgorithms(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		expected  string
+	}{
+		{algorithm: "", expected: helloSum},
+		{algorithm: MD5, expected: "b1946ac92492d2347c6235b4d2611184"},
+		{algorithm: SHA1, expected: "f572d396fae9206628714fb2ce00f72e94f2258f"},
+		{algorithm: SHA256, expected: helloSum},
+		{algorithm: SHA512, expected: "e7c22b994c59d9cf2b48e549b1e24666636045930d3da7c1acb299d1c3b7f931" +
+			"f94aae41edda2c2b207a36e10f8bcb8d45223e54878f5b316e7ce3b6bc019629"},
+	}
+
+	paths := createFiles(t, "hello\n")
+	cache := NewCache()
+
+	for _, test := range tests {
+		t.Run(test.algorithm, func(t *testing.T) {
+			// The cache does not return a checksum of another algorithm
+			files, err := Files(context.Background(), paths, Options{Algorithm: test.algorithm, Cache: cache})
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, files[0].Checksum)
+		})
+	}
+
+	_, err := Files(context.Background(), paths, Options{Algorithm: "crc32"})
+	assert.ErrorIs(t, err, error_msgs.Err53)
+	assert.False(t, Supported("crc32"))
+}
+
+// TestReader tests that content that is not in a file, like an entry of an archive, is hashed like the file
+func TestReader(t *testing.T) {
+	file, err := Reader(context.Background(), strings.NewReader("hello\n"), "entry", Options{HeadSize: 2})
+	require.NoError(t, err)
+	assert.Equal(t, File{Path: "entry", Size: 6, Checksum: helloSum, Algorithm: SHA256, Head: []byte("he")}, file)
+
+	_, err = Reader(context.Background(), strings.NewReader(""), "entry", Options{Algorithm: "crc32"})
+	assert.ErrorIs(t, err, error_msgs.Err53)
+}
@@ -14,7 +14,63 @@ var (
 	Err9  = errors.New("a source and destination path must be provided to ptcp")
 	Err10 = errors.New("neither the source or destination are a part of the pairtree because neither contains the pairtree prefix")
 	Err11 = errors.New("the -n and -a options can not be used together in ptcp")
-	Err12 = errors.New("temp directory does not contain exactly one folder")
+	Err12 = errors.New("the archive does not contain exactly one folder")
 	Err13 = errors.New("folder name does not match pairtree ID")
 	Err15 = errors.New("the path cannot be an empty string")
+	Err16 = errors.New("the log format must be json or console")
+	Err17 = errors.New("invalid usage")
+	Err18 = errors.New("the errors format must be text or json")
+	Err19 = errors.New("the command did not finish before the timeout")
+	Err20 = errors.New("the release archive does not match the release checksums")
+	Err21 = errors.New("the release does not include a build for this platform")
+	Err22 = errors.New("the release archive does not contain the pt binary")
+	Err23 = errors.New("the operation was not confirmed, use --yes to confirm it without a prompt")
+	Err24 = errors.New("the benchmark operation must be ls, cp, archive, or rm")
+	Err25 = errors.New("the benchmark needs at least one object and the file count and size can not be negative")
+	Err26 = errors.New("the name is not a valid pairtree encoding")
+	Err27 = errors.New("the --xml and --json options can not be used together in pt events")
+	Err28 = errors.New("the events file has an event that is not valid")
+	Err29 = errors.New("the ARK does not resolve")
+	Err30 = errors.New("the ARK does not resolve to the expected target")
+	Err31 = errors.New("--minter flag or PT_MINTER environment variable must be set")
+	Err32 = errors.New("the minter did not return an identifier")
+	Err33 = errors.New("the --against option must be set to the list of IDs to reconcile with")
+	Err34 = errors.New("the pairtree and the list of IDs do not have the same objects")
+	Err35 = errors.New("the path is not in a pairtree object")
+	Err36 = errors.New("the snapshots file has a snapshot that is not valid")
+	Err38 = errors.New("the signing key is not an Ed25519 private key in PEM format")
+	Err39 = errors.New("the audit trail does not match its hash chain or signature")
+	Err40 = errors.New("the pairtree does not conform to the pairtree specification")
+	Err41 = errors.New("the command or option can not be used with a pairtree in S3")
+	Err42 = errors.New("the bag does not conform to the BagIt specification")
+	Err43 = errors.New("the destination is not an OCFL storage root")
+	Err44 = errors.New("the ID pattern is not a valid glob pattern")
+	Err45 = errors.New("no object IDs match the pattern")
+	Err46 = errors.New("the subpath is not inside the pairtree object")
+	Err47 = errors.New("the pairtree object does not exist")
+	Err48 = errors.New("the copy does not match the checksums of its source")
+	Err49 = errors.New("the files of the object do not match its checksum manifest")
+	Err50 = errors.New("the file does not match its signature")
+	Err51 = errors.New("the public key is not an Ed25519 public key in PEM format")
+	Err52 = errors.New("the release checksums are not signed with the pt release key")
+	Err53 = errors.New("the checksum algorithm is not supported")
+	Err54 = errors.New("pt was built without a valid release key to check releases with")
 )
+
+// PtError is an error that occurred while working with a pairtree object. It records the
+// ID of the object and the path on disk so they can be reported separately from the message,
+// and the IDs that were likely meant when the object does not exist.
+type PtError struct {
+	ID          string
+	Path        string
+	Err         error
+	Suggestions []string
+}
+
+func (e *PtError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PtError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,140 @@
+package i18n
+
+// catalogs map the English text of a message to its translation in each locale, English
+// has no entries because the messages are already written in English
+var catalogs = map[string]map[string]string{
+	English: {},
+	Spanish: {
+		// Command output
+		"Error:":                                "Error:",
+		"Did you mean %s?":                      "¿Quiso decir %s?",
+		"Please provide an ID for the pairtree": "Proporcione un ID para el pairtree",
+		"Please provide a pattern and an ID for the pairtree":                 "Proporcione un patrón y un ID para el pairtree",
+		"Synced %s to %s: %d added, %d updated, %d deleted, %d unchanged":     "Se sincronizó %s con %s: %d agregados, %d actualizados, %d eliminados, %d sin cambios",
+		"Would sync %s to %s: %d added, %d updated, %d deleted, %d unchanged": "Se sincronizaría %s con %s: %d agregados, %d actualizados, %d eliminados, %d sin cambios",
+		"Deleted %s":      "Se eliminó %s",
+		"Updated %s":      "Se actualizó %s",
+		"Added %s":        "Se agregó %s",
+		"Would update %s": "Se actualizaría %s",
+		"Would add %s":    "Se agregaría %s",
+		"Please provide a source and destination to sync":                                       "Proporcione un origen y un destino para sincronizar",
+		"Please provide a source and destination for copied files":                              "Proporcione un origen y un destino para los archivos copiados",
+		"Too many arguments were provided to %s":                                                "Se proporcionaron demasiados argumentos a %s",
+		"Neither the source or destination contains a prefix and is not a part of the pairtree": "Ni el origen ni el destino contienen un prefijo y no forman parte del pairtree",
+		"This is the src: %s":      "Este es el origen: %s",
+		"This is the dest: %s":     "Este es el destino: %s",
+		"Successfully deleted: %s": "Eliminado correctamente: %s",
+		"No objects match %s":      "Ningún objeto coincide con %s",
+		"%s exists":                "%s existe",
+		"%s does not exist":        "%s no existe",
+		"Would delete %s":          "Se eliminaría %s",
+		"Would overwrite %s":       "Se sobrescribiría %s",
+		"Would replace %s":         "Se reemplazaría %s",
+		"Would copy %s to %s":      "Se copiaría %s a %s",
+		"Would move %s to %s":      "Se movería %s a %s",
+		"Would archive %s to %s":   "Se archivaría %s en %s",
+		"Would write the archive of %s to standard output":            "Se escribiría el archivo de %s en la salida estándar",
+		"Would extract %s to %s":                                      "Se extraería %s en %s",
+		"Would extract the archive on standard input to %s":           "Se extraería el archivo de la entrada estándar en %s",
+		"JSON structure:":                                             "Estructura JSON:",
+		"Total: %d files, %d directories, %s":                         "Total: %d archivos, %d directorios, %s",
+		"%d directories, %d files":                                    "%d directorios, %d archivos",
+		"pt %s is available, %s is installed":                         "pt %s está disponible, %s está instalado",
+		"pt %s is the latest release":                                 "pt %s es la versión más reciente",
+		"pt was updated to %s":                                        "pt se actualizó a %s",
+		"[y/N]:":                                                      "[s/N]:",
+		"Delete the pairtree object %s and everything in it?":         "¿Eliminar el objeto del pairtree %s y todo su contenido?",
+		"Delete %s?":                                                  "¿Eliminar %s?",
+		"Delete the pairtree object %s once it is moved?":             "¿Eliminar el objeto del pairtree %s una vez movido?",
+		"Overwrite %s?":                                               "¿Sobrescribir %s?",
+		"Generated %d objects with %d files of %d bytes in %s":        "Se generaron %d objetos con %d archivos de %d bytes en %s",
+		"No events have been recorded for %s":                         "No se han registrado eventos para %s",
+		"The %s of %s could not be recorded in its event history: %v": "No se pudo registrar %s de %s en su historial de eventos: %v",
+		"%s is not an ARK so it was not checked against the resolver": "%s no es un ARK, por lo que no se comprobó con el resolvedor",
+		"The pairtree and %s have the same %d objects":                "El pairtree y %s tienen los mismos %d objetos",
+		"%d of the %d IDs in %s are missing from the pairtree, %d of its %d objects are not in the list": "Faltan en el pairtree %d de los %d ID de %s, %d de sus %d objetos no están en la lista",
+		"Packaged %s as %s":                                                    "Se empaquetó %s como %s",
+		"Wrote the %s manifest of %s to %s":                                    "Se escribió el manifiesto %s de %s en %s",
+		"Exported %s as the bag %s":                                            "Se exportó %s como la bolsa %s",
+		"Imported the bag %s as %s":                                            "Se importó la bolsa %s como %s",
+		"Imported %s as %s":                                                    "Se importó %s como %s",
+		"Could not import %s as %s: %s":                                        "No se pudo importar %s como %s: %s",
+		"Imported %d of %d objects":                                            "Se importaron %d de %d objetos",
+		"Ran %s for %s":                                                        "Se ejecutó %s para %s",
+		"Could not run %s for %s":                                              "No se pudo ejecutar %s para %s",
+		"Ran %s for %d of %d objects":                                          "Se ejecutó %s para %d de %d objetos",
+		"Please provide the command to run after --":                           "Proporcione el comando que se va a ejecutar después de --",
+		"Exported %s to the OCFL object %s":                                    "Se exportó %s al objeto OCFL %s",
+		"Verified the checksums of %s":                                         "Se verificaron las sumas de verificación de %s",
+		"Checked the files of %s against %s":                                   "Se comprobaron los archivos de %s con %s",
+		"Signed %s in %s":                                                      "Se firmó %s en %s",
+		"Could not verify %s: %v":                                              "No se pudo verificar %s: %v",
+		"%s matches its signature by %s":                                       "%s coincide con su firma por %s",
+		"Please provide the signed files to verify":                            "Proporcione los archivos firmados que se verificarán",
+		"Wrote the BagIt manifests of %s to %s":                                "Se escribieron los manifiestos BagIt de %s en %s",
+		"The files of %s do not match %s: %v":                                  "Los archivos de %s no coinciden con %s: %v",
+		"Exported %s to the directory %s":                                      "Se exportó %s al directorio %s",
+		"Exported %s as the archive %s":                                        "Se exportó %s como el archivo comprimido %s",
+		"Skipped %s, which was exported to %s":                                 "Se omitió %s, que se exportó a %s",
+		"Please provide the bag or directory to import":                        "Proporcione la bolsa o el directorio que se va a importar",
+		"Please provide a path in the pairtree":                                "Proporcione una ruta del pairtree",
+		"Recorded a snapshot of %d objects with %d bytes":                      "Se registró una instantánea de %d objetos con %d bytes",
+		"Man pages were written to %s":                                         "Las páginas del manual se escribieron en %s",
+		"The %d objects of the pairtree conform to the pairtree specification": "Los %d objetos del pairtree cumplen la especificación de pairtree",
+		"Found %d violations of the pairtree specification in a pairtree of %d objects": "Se encontraron %d infracciones de la especificación de pairtree en un pairtree de %d objetos",
+
+		// Errors
+		"pairtree_prefix file exists, but is empty and must be populated":                                           "el archivo pairtree_prefix existe, pero está vacío y debe completarse",
+		"the pairtree version file is empty and must be populated":                                                  "el archivo de versión del pairtree está vacío y debe completarse",
+		"the pairtree root is empty and must be populated":                                                          "la raíz del pairtree está vacía y debe completarse",
+		"the pairtree id is empty and must be populated ":                                                           "el id del pairtree está vacío y debe completarse ",
+		"the pairtree id does not contain the pairtree_prefix or pt://":                                             "el id del pairtree no contiene el pairtree_prefix ni pt://",
+		"no ID was provided to process":                                                                             "no se proporcionó ningún ID para procesar",
+		"--pairtree flag or PAIRTREE_ROOT environment variable must be set":                                         "se debe establecer la opción --pairtree o la variable de entorno PAIRTREE_ROOT",
+		"too many arguments were passed":                                                                            "se pasaron demasiados argumentos",
+		"a source and destination path must be provided to ptcp":                                                    "se debe proporcionar una ruta de origen y de destino a ptcp",
+		"the -n and -a options can not be used together in ptcp":                                                    "las opciones -n y -a no se pueden usar juntas en ptcp",
+		"the archive does not contain exactly one folder":                                                           "el archivo comprimido no contiene exactamente una carpeta",
+		"folder name does not match pairtree ID":                                                                    "el nombre de la carpeta no coincide con el ID del pairtree",
+		"the path cannot be an empty string":                                                                        "la ruta no puede ser una cadena vacía",
+		"the log format must be json or console":                                                                    "el formato del registro debe ser json o console",
+		"invalid usage":                                                                                             "uso no válido",
+		"the command did not finish before the timeout":                                                             "el comando no terminó antes del tiempo límite",
+		"the release archive does not match the release checksums":                                                  "el archivo de la versión no coincide con las sumas de verificación de la versión",
+		"the release does not include a build for this platform":                                                    "la versión no incluye una compilación para esta plataforma",
+		"the release archive does not contain the pt binary":                                                        "el archivo de la versión no contiene el binario de pt",
+		"the operation was not confirmed, use --yes to confirm it without a prompt":                                 "la operación no fue confirmada, use --yes para confirmarla sin preguntar",
+		"the benchmark operation must be ls, cp, archive, or rm":                                                    "la operación de la prueba de rendimiento debe ser ls, cp, archive o rm",
+		"the benchmark needs at least one object and the file count and size can not be negative":                   "la prueba de rendimiento necesita al menos un objeto y el número y el tamaño de los archivos no pueden ser negativos",
+		"the name is not a valid pairtree encoding":                                                                 "el nombre no es una codificación de pairtree válida",
+		"the --xml and --json options can not be used together in pt events":                                        "las opciones --xml y --json no se pueden usar juntas en pt events",
+		"the events file has an event that is not valid":                                                            "el archivo de eventos tiene un evento que no es válido",
+		"the ARK does not resolve":                                                                                  "el ARK no se resuelve",
+		"the ARK does not resolve to the expected target":                                                           "el ARK no se resuelve al destino esperado",
+		"--minter flag or PT_MINTER environment variable must be set":                                               "se debe establecer la opción --minter o la variable de entorno PT_MINTER",
+		"the minter did not return an identifier":                                                                   "el minter no devolvió un identificador",
+		"the --against option must be set to the list of IDs to reconcile with":                                     "se debe establecer la opción --against con la lista de ID con la que conciliar",
+		"the pairtree and the list of IDs do not have the same objects":                                             "el pairtree y la lista de ID no tienen los mismos objetos",
+		"the path is not in a pairtree object":                                                                      "la ruta no está en un objeto del pairtree",
+		"the snapshots file has a snapshot that is not valid":                                                       "el archivo de instantáneas tiene una instantánea que no es válida",
+		"the signing key is not an Ed25519 private key in PEM format":                                               "la clave de firma no es una clave privada Ed25519 en formato PEM",
+		"the audit trail does not match its hash chain or signature":                                                "el registro de auditoría no coincide con su cadena de hashes o su firma",
+		"the pairtree does not conform to the pairtree specification":                                               "el pairtree no cumple la especificación de pairtree",
+		"the command or option can not be used with a pairtree in S3":                                               "el comando o la opción no se puede usar con un pairtree en S3",
+		"the bag does not conform to the BagIt specification":                                                       "la bolsa no cumple la especificación BagIt",
+		"the destination is not an OCFL storage root":                                                               "el destino no es una raíz de almacenamiento OCFL",
+		"the ID pattern is not a valid glob pattern":                                                                "el patrón de ID no es un patrón glob válido",
+		"no object IDs match the pattern":                                                                           "ningún ID de objeto coincide con el patrón",
+		"the subpath is not inside the pairtree object":                                                             "la subruta no está dentro del objeto del pairtree",
+		"the pairtree object does not exist":                                                                        "el objeto del pairtree no existe",
+		"the copy does not match the checksums of its source":                                                       "la copia no coincide con las sumas de verificación de su origen",
+		"the files of the object do not match its checksum manifest":                                                "los archivos del objeto no coinciden con su manifiesto de sumas de verificación",
+		"the file does not match its signature":                                                                     "el archivo no coincide con su firma",
+		"the release checksums are not signed with the pt release key":                                              "las sumas de verificación de la versión no están firmadas con la clave de versiones de pt",
+		"pt was built without a valid release key to check releases with":                                           "pt se compiló sin una clave de versiones válida para comprobar las versiones",
+		"the checksum algorithm is not supported":                                                                   "el algoritmo de suma de verificación no es compatible",
+		"the public key is not an Ed25519 public key in PEM format":                                                 "la clave pública no es una clave pública Ed25519 en formato PEM",
+		"the errors format must be text or json":                                                                    "el formato de los errores debe ser text o json",
+		"neither the source or destination are a part of the pairtree because neither contains the pairtree prefix": "ni el origen ni el destino forman parte del pairtree porque ninguno contiene el prefijo del pairtree",
+	},
+}
@@ -0,0 +1,151 @@
+package i18n
+
+/* i18n translates the messages pt shows to its users. Messages are looked up by their English
+text so a message without a translation is shown in English. */
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+)
+
+// Locales that messages can be shown in
+const (
+	English = "en"
+	Spanish = "es"
+)
+
+// environment is the locale of the environment pt was started in, which messages are shown in when
+// a run does not choose another one
+var environment = Printer{locale: FromEnv()}
+
+// yesAnswers are the answers to a prompt that mean yes in each locale
+var yesAnswers = map[string][]string{
+	English: {"y", "yes"},
+	Spanish: {"s", "si", "sí"},
+}
+
+// sentinels are the errors whose messages are translated when they are shown to the user
+var sentinels = []error{
+	error_msgs.Err1, error_msgs.Err2, error_msgs.Err3, error_msgs.Err4, error_msgs.Err5,
+	error_msgs.Err6, error_msgs.Err7, error_msgs.Err8, error_msgs.Err9, error_msgs.Err10,
+	error_msgs.Err11, error_msgs.Err12, error_msgs.Err13, error_msgs.Err15, error_msgs.Err16,
+	error_msgs.Err17, error_msgs.Err18, error_msgs.Err19, error_msgs.Err20, error_msgs.Err21,
+	error_msgs.Err22, error_msgs.Err23, error_msgs.Err24, error_msgs.Err25,
+	error_msgs.Err26, error_msgs.Err27, error_msgs.Err28, error_msgs.Err29, error_msgs.Err30,
+	error_msgs.Err31, error_msgs.Err32, error_msgs.Err33, error_msgs.Err34, error_msgs.Err35,
+	error_msgs.Err36, error_msgs.Err38, error_msgs.Err39, error_msgs.Err40,
+	error_msgs.Err41, error_msgs.Err42, error_msgs.Err43, error_msgs.Err44, error_msgs.Err45,
+	error_msgs.Err46, error_msgs.Err47, error_msgs.Err48, error_msgs.Err49, error_msgs.Err50,
+	error_msgs.Err51, error_msgs.Err52, error_msgs.Err53, error_msgs.Err54,
+}
+
+// Parse returns the supported locale for a language tag like es, es_MX or es_MX.UTF-8,
+// anything that is not supported falls back to English
+func Parse(lang string) string {
+	lang = strings.ToLower(lang)
+	if i := strings.IndexAny(lang, "_-.@"); i >= 0 {
+		lang = lang[:i]
+	}
+
+	if _, ok := catalogs[lang]; ok {
+		return lang
+	}
+
+	return English
+}
+
+// FromEnv returns the locale set by the LC_ALL, LC_MESSAGES or LANG environment variables
+func FromEnv() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if lang := os.Getenv(name); lang != "" {
+			return Parse(lang)
+		}
+	}
+
+	return English
+}
+
+// Printer shows messages in a locale. Each run of a command has its own, so runs in different
+// languages can happen at the same time. The zero Printer shows messages in English.
+type Printer struct {
+	locale string
+}
+
+// NewPrinter creates a Printer for the language, or for the locale of the environment when the
+// language is empty
+func NewPrinter(lang string) Printer {
+	if lang == "" {
+		return environment
+	}
+
+	return Printer{locale: Parse(lang)}
+}
+
+// Locale returns the language the Printer shows messages in
+func (p Printer) Locale() string {
+	if p.locale == "" {
+		return English
+	}
+
+	return p.locale
+}
+
+// T translates the message and formats it with the arguments when there are any
+func (p Printer) T(msg string, args ...any) string {
+	if translated, ok := catalogs[p.Locale()][msg]; ok {
+		msg = translated
+	}
+
+	if len(args) == 0 {
+		return msg
+	}
+
+	return fmt.Sprintf(msg, args...)
+}
+
+// Error translates the messages of the pt errors that the error wraps
+func (p Printer) Error(err error) string {
+	text := err.Error()
+
+	for _, sentinel := range sentinels {
+		if errors.Is(err, sentinel) {
+			text = strings.Replace(text, sentinel.Error(), p.T(sentinel.Error()), 1)
+		}
+	}
+
+	return text
+}
+
+// IsYes determines if the answer to a prompt means yes, English answers are accepted in every locale
+func (p Printer) IsYes(answer string) bool {
+	answer = strings.ToLower(strings.TrimSpace(answer))
+
+	for _, lang := range []string{English, p.Locale()} {
+		for _, yes := range yesAnswers[lang] {
+			if answer == yes {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// T translates the message into the locale of the environment, see Printer.T
+func T(msg string, args ...any) string {
+	return environment.T(msg, args...)
+}
+
+// Error translates the pt errors that the error wraps into the locale of the environment, see Printer.Error
+func Error(err error) string {
+	return environment.Error(err)
+}
+
+// IsYes determines if the answer means yes in the locale of the environment, see Printer.IsYes
+func IsYes(answer string) bool {
+	return environment.IsYes(answer)
+}
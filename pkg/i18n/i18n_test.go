@@ -0,0 +1,92 @@
+package i18n
+
+import (
+	"fmt"
+	"testing"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParse tests if language tags are mapped to a supported locale
+func TestParse(t *testing.T) {
+	tests := []struct {
+		lang     string
+		expected string
+	}{
+		{lang: "es", expected: Spanish},
+		{lang: "es_MX.UTF-8", expected: Spanish},
+		{lang: "ES-es", expected: Spanish},
+		{lang: "en_US.UTF-8", expected: English},
+		{lang: "C", expected: English},
+		{lang: "fr_FR", expected: English},
+		{lang: "", expected: English},
+	}
+
+	for _, test := range tests {
+		t.Run(test.lang, func(t *testing.T) {
+			assert.Equal(t, test.expected, Parse(test.lang))
+		})
+	}
+}
+
+// TestFromEnv tests if the locale is read from the environment in order of precedence
+func TestFromEnv(t *testing.T) {
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_MESSAGES", "")
+	t.Setenv("LANG", "es_ES.UTF-8")
+	assert.Equal(t, Spanish, FromEnv())
+
+	t.Setenv("LC_ALL", "en_US.UTF-8")
+	assert.Equal(t, English, FromEnv())
+}
+
+// TestT tests if messages are translated and formatted in the locale of the Printer
+func TestT(t *testing.T) {
+	assert.Equal(t, "This is the src: a5388", NewPrinter(English).T("This is the src: %s", "a5388"))
+	assert.Equal(t, "This is the src: a5388", Printer{}.T("This is the src: %s", "a5388"))
+
+	spanish := NewPrinter("es_MX.UTF-8")
+	assert.Equal(t, Spanish, spanish.Locale())
+	assert.Equal(t, "Este es el origen: a5388", spanish.T("This is the src: %s", "a5388"))
+	assert.Equal(t, "An untranslated message", spanish.T("An untranslated message"))
+}
+
+// TestError tests if the messages of wrapped pt errors are translated
+func TestError(t *testing.T) {
+	spanish := NewPrinter(Spanish)
+
+	err := fmt.Errorf("%w: unknown command %q", error_msgs.Err17, "lss")
+	assert.Equal(t, "uso no válido: unknown command \"lss\"", spanish.Error(err))
+
+	err = &error_msgs.PtError{ID: "ark:/a5388", Err: error_msgs.Err8}
+	assert.Equal(t, "se pasaron demasiados argumentos", spanish.Error(err))
+}
+
+// TestPrinters tests that Printers in different locales can be used at the same time
+func TestPrinters(t *testing.T) {
+	for _, lang := range []string{English, Spanish, English, Spanish} {
+		t.Run(lang, func(t *testing.T) {
+			t.Parallel()
+
+			printer := NewPrinter(lang)
+			expected := map[string]string{English: "Deleted a5388", Spanish: "Se eliminó a5388"}[lang]
+			for range 100 {
+				assert.Equal(t, expected, printer.T("Deleted %s", "a5388"))
+			}
+		})
+	}
+}
+
+// TestCatalogs tests if every pt error has a translation in each locale
+func TestCatalogs(t *testing.T) {
+	for locale, catalog := range catalogs {
+		if locale == English {
+			continue
+		}
+
+		for _, sentinel := range sentinels {
+			assert.Contains(t, catalog, sentinel.Error(), "%s is missing a translation", locale)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+/*
+The mets package describes the files of a pairtree object as a METS document, with a fileSec that
+lists the size, SHA-256 checksum, and MIME type of each file and a physical structMap that follows
+the directories of the object, for ingest pipelines that require METS.
+*/
+package mets
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/UCLALibrary/pt-tools/pkg/checksum"
+)
+
+const (
+	// Namespace is the namespace of METS XML
+	Namespace = "http://www.loc.gov/METS/"
+	// xlinkNamespace is the namespace of the href of each file location
+	xlinkNamespace = "http://www.w3.org/1999/xlink"
+	// ChecksumType is the METS name of the checksum algorithm used for the files
+	ChecksumType = "SHA-256"
+	// sniffLen is how much of a file is read to detect its MIME type when its extension is unknown
+	sniffLen = 512
+)
+
+// Document is a METS document describing the files of one pairtree object
+type Document struct {
+	XMLName   xml.Name  `xml:"mets"`
+	Xmlns     string    `xml:"xmlns,attr"`
+	XmlnsLink string    `xml:"xmlns:xlink,attr"`
+	ObjID     string    `xml:"OBJID,attr"`
+	Header    Header    `xml:"metsHdr"`
+	FileSec   FileSec   `xml:"fileSec"`
+	StructMap StructMap `xml:"structMap"`
+}
+
+// Header records when the document was created and by what
+type Header struct {
+	CreateDate string `xml:"CREATEDATE,attr"`
+	Agent      Agent  `xml:"agent"`
+}
+
+// Agent is the software that created the document
+type Agent struct {
+	Role      string `xml:"ROLE,attr"`
+	Type      string `xml:"TYPE,attr"`
+	OtherType string `xml:"OTHERTYPE,attr"`
+	Name      string `xml:"name"`
+}
+
+// FileSec lists every file of the object in one group
+type FileSec struct {
+	FileGrp FileGrp `xml:"fileGrp"`
+}
+
+// FileGrp is a group of files
+type FileGrp struct {
+	Use   string `xml:"USE,attr"`
+	Files []File `xml:"file"`
+}
+
+// File is a file of the object with its size, checksum, MIME type, and path in the object
+type File struct {
+	ID           string `xml:"ID,attr"`
+	MimeType     string `xml:"MIMETYPE,attr"`
+	Size         int64  `xml:"SIZE,attr"`
+	Checksum     string `xml:"CHECKSUM,attr"`
+	ChecksumType string `xml:"CHECKSUMTYPE,attr"`
+	FLocat       FLocat `xml:"FLocat"`
+}
+
+// FLocat is the location of a file as a URL relative to the object directory
+type FLocat struct {
+	LocType string `xml:"LOCTYPE,attr"`
+	Href    string `xml:"xlink:href,attr"`
+}
+
+// StructMap is the directory structure of the object
+type StructMap struct {
+	Type string `xml:"TYPE,attr"`
+	Div  Div    `xml:"div"`
+}
+
+// Div is a directory with pointers to its files followed by its subdirectories
+type Div struct {
+	Type  string `xml:"TYPE,attr"`
+	Label string `xml:"LABEL,attr"`
+	Fptrs []Fptr `xml:"fptr"`
+	Divs  []Div  `xml:"div"`
+}
+
+// Fptr points to a file in the fileSec
+type Fptr struct {
+	FileID string `xml:"FILEID,attr"`
+}
+
+// Build describes the files in objPath, the directory of the object with the ID, as created by the
+// agent. Hidden files and directories are only described when includeHidden is true. The files are
+// hashed in parallel as bounded by the options.
+func Build(ctx context.Context, id, objPath, agent string, includeHidden bool, opts checksum.Options) (*Document, error) {
+	doc := &Document{
+		Xmlns:     Namespace,
+		XmlnsLink: xlinkNamespace,
+		ObjID:     id,
+		Header: Header{
+			CreateDate: time.Now().UTC().Format(time.RFC3339),
+			Agent:      Agent{Role: "CREATOR", Type: "OTHER", OtherType: "SOFTWARE", Name: agent},
+		},
+		FileSec:   FileSec{FileGrp: FileGrp{Use: "original"}},
+		StructMap: StructMap{Type: "physical"},
+	}
+
+	var relPaths []string
+	div, err := doc.describeDir(ctx, objPath, "", includeHidden, &relPaths)
+	if err != nil {
+		return nil, err
+	}
+
+	div.Type, div.Label = "object", id
+	doc.StructMap.Div = div
+
+	if err := doc.describeFiles(ctx, objPath, relPaths, opts); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// XML returns the document as indented METS XML
+func (d *Document) XML() ([]byte, error) {
+	output, err := xml.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), output...), nil
+}
+
+// describeDir adds the files in the directory at relPath in the object to the fileSec, and their paths
+// to relPaths, and returns the div of the directory
+func (d *Document) describeDir(ctx context.Context, objPath, relPath string, includeHidden bool,
+	relPaths *[]string) (Div, error) {
+	div := Div{Type: "directory", Label: path.Base(relPath)}
+
+	entries, err := os.ReadDir(filepath.Join(objPath, filepath.FromSlash(relPath)))
+	if err != nil {
+		return div, err
+	}
+
+	var subdirs []string
+
+	for _, entry := range entries {
+		if !includeHidden && strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		entryPath := path.Join(relPath, entry.Name())
+
+		if entry.IsDir() {
+			subdirs = append(subdirs, entryPath)
+			continue
+		}
+
+		if err := ctx.Err(); err != nil {
+			return div, err
+		}
+
+		file := File{
+			ID:           fmt.Sprintf("FILE%04d", len(d.FileSec.FileGrp.Files)+1),
+			ChecksumType: ChecksumType,
+			FLocat:       FLocat{LocType: "URL", Href: (&url.URL{Path: entryPath}).EscapedPath()},
+		}
+		d.FileSec.FileGrp.Files = append(d.FileSec.FileGrp.Files, file)
+		*relPaths = append(*relPaths, entryPath)
+		div.Fptrs = append(div.Fptrs, Fptr{FileID: file.ID})
+	}
+
+	// METS puts the pointers to a directory's files before its subdirectories
+	for _, subdir := range subdirs {
+		subDiv, err := d.describeDir(ctx, objPath, subdir, includeHidden, relPaths)
+		if err != nil {
+			return div, err
+		}
+		div.Divs = append(div.Divs, subDiv)
+	}
+
+	return div, nil
+}
+
+// describeFiles hashes the files at relPaths in the object, which are the files of the fileSec in
+// order, for their size, checksum, and MIME type
+func (d *Document) describeFiles(ctx context.Context, objPath string, relPaths []string, opts checksum.Options) error {
+	paths := make([]string, len(relPaths))
+	for i, relPath := range relPaths {
+		paths[i] = filepath.Join(objPath, filepath.FromSlash(relPath))
+	}
+
+	// The start of each file is kept while hashing in case its type has to be detected from it
+	opts.HeadSize = sniffLen
+	sums, err := checksum.Files(ctx, paths, opts)
+	if err != nil {
+		return err
+	}
+
+	for i, sum := range sums {
+		file := &d.FileSec.FileGrp.Files[i]
+		file.Size = sum.Size
+		file.Checksum = sum.Checksum
+
+		file.MimeType = mime.TypeByExtension(path.Ext(relPaths[i]))
+		if file.MimeType == "" {
+			file.MimeType = http.DetectContentType(sum.Head)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package mets
+
+import (
+	"context"
+	"encoding/xml"
+	"testing"
+
+	"github.com/UCLALibrary/pt-tools/pkg/checksum"
+	"github.com/UCLALibrary/pt-tools/pkg/pttest"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// SHA-256 of "hello\n"
+const helloSum = "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"
+
+// buildObject builds an object with files at the top, in a directory, and hidden, and returns its path
+func buildObject(t *testing.T) string {
+	builder := pttest.NewPairtreeBuilder().
+		WithFile("ark:/b5488", "readme.txt", []byte("hello\n")).
+		WithFile("ark:/b5488", "data", []byte("%PDF-1.4 not really")).
+		WithFile("ark:/b5488", "images/page 1.png", []byte("\x89PNG\r\n\x1a\n")).
+		WithFile("ark:/b5488", ".hidden.txt", []byte("hidden"))
+
+	dir := builder.BuildTemp(t, afero.NewOsFs())
+	return builder.ObjectPath(dir, "ark:/b5488")
+}
+
+// TestBuild tests that each file is described in the fileSec and placed in the structMap
+func TestBuild(t *testing.T) {
+	doc, err := Build(context.Background(), "ark:/b5488", buildObject(t), "pt v1.2.0", false, checksum.Options{})
+	require.NoError(t, err)
+
+	files := doc.FileSec.FileGrp.Files
+	require.Len(t, files, 3)
+
+	assert.Equal(t, "FILE0001", files[0].ID)
+	assert.Equal(t, "data", files[0].FLocat.Href)
+	assert.Equal(t, "application/pdf", files[0].MimeType)
+
+	assert.Equal(t, "readme.txt", files[1].FLocat.Href)
+	assert.Equal(t, int64(6), files[1].Size)
+	assert.Equal(t, helloSum, files[1].Checksum)
+	assert.Equal(t, ChecksumType, files[1].ChecksumType)
+	assert.Contains(t, files[1].MimeType, "text/plain")
+
+	assert.Equal(t, "images/page%201.png", files[2].FLocat.Href)
+	assert.Equal(t, "image/png", files[2].MimeType)
+
+	root := doc.StructMap.Div
+	assert.Equal(t, "ark:/b5488", root.Label)
+	assert.Equal(t, []Fptr{{FileID: "FILE0001"}, {FileID: "FILE0002"}}, root.Fptrs)
+	require.Len(t, root.Divs, 1)
+	assert.Equal(t, "images", root.Divs[0].Label)
+	assert.Equal(t, []Fptr{{FileID: "FILE0003"}}, root.Divs[0].Fptrs)
+}
+
+// TestBuildHidden tests that hidden files are only described when they are included
+func TestBuildHidden(t *testing.T) {
+	doc, err := Build(context.Background(), "ark:/b5488", buildObject(t), "pt v1.2.0", true, checksum.Options{})
+	require.NoError(t, err)
+
+	require.Len(t, doc.FileSec.FileGrp.Files, 4)
+	assert.Equal(t, ".hidden.txt", doc.FileSec.FileGrp.Files[0].FLocat.Href)
+}
+
+// TestBuildCanceled tests that describing the object stops when the context is canceled
+func TestBuildCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := Build(ctx, "ark:/b5488", buildObject(t), "pt v1.2.0", false, checksum.Options{})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+// TestXML tests that the document is METS XML with the files' locations in the xlink namespace
+func TestXML(t *testing.T) {
+	doc, err := Build(context.Background(), "ark:/b5488", buildObject(t), "pt v1.2.0", false, checksum.Options{})
+	require.NoError(t, err)
+
+	output, err := doc.XML()
+	require.NoError(t, err)
+
+	var parsed struct {
+		XMLName xml.Name
+		ObjID   string `xml:"OBJID,attr"`
+		Agent   string `xml:"metsHdr>agent>name"`
+		Hrefs   []struct {
+			Href string `xml:"http://www.w3.org/1999/xlink href,attr"`
+		} `xml:"fileSec>fileGrp>file>FLocat"`
+	}
+	require.NoError(t, xml.Unmarshal(output, &parsed))
+
+	assert.Equal(t, Namespace, parsed.XMLName.Space)
+	assert.Equal(t, "ark:/b5488", parsed.ObjID)
+	assert.Equal(t, "pt v1.2.0", parsed.Agent)
+	require.Len(t, parsed.Hrefs, 3)
+	assert.Equal(t, "readme.txt", parsed.Hrefs[1].Href)
+}
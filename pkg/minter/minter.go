@@ -0,0 +1,89 @@
+/*
+The minter package requests new identifiers from a NOID or ARK minter service over HTTP, so an object
+can be created in the pairtree under an identifier as soon as it is minted.
+*/
+package minter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+)
+
+// maxResponse limits how much of the minter's response is read
+const maxResponse = 1 << 16
+
+// Minter mints identifiers by requesting the URL of a minter service
+type Minter struct {
+	Client *http.Client
+	URL    string
+}
+
+// New creates a Minter for the minter service at the URL, like a NOID minter's
+// https://example.org/nd/noidu_fk4?mint+1
+func New(url string) *Minter {
+	return &Minter{Client: http.DefaultClient, URL: url}
+}
+
+// Mint requests a new identifier from the minter service
+func (m *Minter) Mint(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.URL, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := m.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponse))
+	if err != nil {
+		return "", err
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("the minter returned %s", resp.Status)
+	}
+
+	return ParseID(body)
+}
+
+// ParseID returns the identifier in a minter's response. NOID answers with a line like
+// "id: 13030/xt12t3" and EZID with "success: ark:/13030/xt12t3", a response that is only the
+// identifier is also accepted.
+func ParseID(body []byte) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		for _, label := range []string{"id:", "success:"} {
+			if len(line) >= len(label) && strings.EqualFold(line[:len(label)], label) {
+				line = strings.TrimSpace(line[len(label):])
+				break
+			}
+		}
+
+		// EZID can follow the identifier with other identifiers it created
+		if i := strings.IndexAny(line, " |"); i >= 0 {
+			line = line[:i]
+		}
+
+		if line != "" {
+			return line, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: %q", error_msgs.Err32, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,56 @@
+package minter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseID tests that the identifier is found in the responses of different minters
+func TestParseID(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		expectID  string
+		expectErr error
+	}{
+		{name: "noid", body: "id: 13030/fk4xt12t3\n", expectID: "13030/fk4xt12t3"},
+		{name: "ezid", body: "success: ark:/13030/fk4xt12t3 | doi:10.5072/FK2XT12T3\n", expectID: "ark:/13030/fk4xt12t3"},
+		{name: "plain", body: "\nark:/13030/fk4xt12t3\n", expectID: "ark:/13030/fk4xt12t3"},
+		{name: "uppercase label", body: "ID: 13030/fk4xt12t3", expectID: "13030/fk4xt12t3"},
+		{name: "empty", body: "\n\n", expectErr: error_msgs.Err32},
+		{name: "label only", body: "id:", expectErr: error_msgs.Err32},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			id, err := ParseID([]byte(test.body))
+			assert.ErrorIs(t, err, test.expectErr)
+			assert.Equal(t, test.expectID, id)
+		})
+	}
+}
+
+// TestMint tests that an identifier is requested from the minter and failed requests are errors
+func TestMint(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.RawQuery != "mint+1" {
+			http.Error(w, "unknown operation", http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte("id: 13030/fk4xt12t3\n"))
+	}))
+	defer server.Close()
+
+	id, err := New(server.URL + "/nd/noidu_fk4?mint+1").Mint(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "13030/fk4xt12t3", id)
+
+	_, err = New(server.URL + "/nd/noidu_fk4?fetch").Mint(context.Background())
+	assert.ErrorContains(t, err, "400")
+}
@@ -0,0 +1,294 @@
+/*
+The ocfl package converts pairtree objects into objects of an OCFL 1.1 storage root, for migrating a
+pairtree to the Oxford Common File Layout. Each object is written as a single version, v1, with its
+files in v1/content and an inventory.json of their digests. Files with the same content are stored
+once. Objects are placed in the storage root with the hashed n-tuple storage layout extension, so an
+ID like ark:/a5388 does not need to be a valid path.
+*/
+package ocfl
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/UCLALibrary/pt-tools/pkg/checksum"
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+)
+
+const (
+	// RootDeclaration is the name of the file that declares a directory is an OCFL 1.1 storage root
+	RootDeclaration = "0=ocfl_1.1"
+	// ObjectDeclaration is the name of the file that declares a directory is an OCFL 1.1 object
+	ObjectDeclaration = "0=ocfl_object_1.1"
+	// InventoryName is the name of the inventory of an object and of each of its versions
+	InventoryName = "inventory.json"
+	// InventoryType is the type of the inventories that are written
+	InventoryType = "https://ocfl.io/1.1/spec/#inventory"
+	// LayoutName is the name of the file that describes the storage layout of the storage root
+	LayoutName = "ocfl_layout.json"
+	// LayoutExtension is the storage layout extension the objects are placed with
+	LayoutExtension = "0004-hashed-n-tuple-storage-layout"
+	// Head is the version each object is written as
+	Head = "v1"
+	// ContentDir is the directory of a version with its files
+	ContentDir = "content"
+
+	// tupleSize and tuples are the default parameters of the hashed n-tuple storage layout
+	tupleSize = 3
+	tuples    = 3
+)
+
+// Inventory is the inventory.json of an OCFL object
+type Inventory struct {
+	ID              string `json:"id"`
+	Type            string `json:"type"`
+	DigestAlgorithm string `json:"digestAlgorithm"`
+	Head            string `json:"head"`
+	// Manifest has the content paths of the files with each digest
+	Manifest map[string][]string `json:"manifest"`
+	Versions map[string]Version  `json:"versions"`
+}
+
+// Version is a version of an OCFL object
+type Version struct {
+	Created time.Time `json:"created"`
+	// State has the logical paths of the files with each digest
+	State   map[string][]string `json:"state"`
+	Message string              `json:"message,omitempty"`
+}
+
+// Supported checks if the algorithm is one OCFL allows as the digest algorithm of an inventory
+func Supported(algorithm string) bool {
+	return algorithm == checksum.SHA512 || algorithm == checksum.SHA256
+}
+
+// ObjectPath returns the path of the object with the ID relative to the storage root, which with the
+// defaults of the hashed n-tuple storage layout is three directories from the start of the SHA-256 of
+// the ID followed by a directory named with the whole digest
+func ObjectPath(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	digest := hex.EncodeToString(sum[:])
+
+	parts := make([]string, 0, tuples+1)
+	for i := range tuples {
+		parts = append(parts, digest[i*tupleSize:(i+1)*tupleSize])
+	}
+
+	return path.Join(append(parts, digest)...)
+}
+
+// CreateRoot makes root an OCFL storage root, unless it already is one. The directory is created when
+// it does not exist, and a directory that has other files is not made a storage root.
+func CreateRoot(root string) error {
+	if _, err := os.Stat(filepath.Join(root, RootDeclaration)); err == nil {
+		return nil
+	}
+
+	entries, err := os.ReadDir(root)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if len(entries) > 0 {
+		return fmt.Errorf("%w: %s", error_msgs.Err43, root)
+	}
+
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return err
+	}
+
+	layout, err := json.MarshalIndent(map[string]string{
+		"extension":   LayoutExtension,
+		"description": "Hashed N-tuple Storage Layout, with the default parameters",
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(filepath.Join(root, LayoutName), append(layout, '\n'), 0644); err != nil {
+		return err
+	}
+
+	// The declaration is written last, so a root that is not finished is not taken for one
+	return os.WriteFile(filepath.Join(root, RootDeclaration), []byte("ocfl_1.1\n"), 0644)
+}
+
+// Write writes the object at objPath with the ID into the storage root, which must have been created
+// with CreateRoot, and returns the path of the OCFL object. The files are hashed with the algorithm of
+// the options, SHA512 when it is not set. Hidden files are only written when includeHidden is true.
+// An object that is already in the storage root is not written, and one that is not finished is removed.
+func Write(ctx context.Context, root, objPath, id, message string, includeHidden bool, opts checksum.Options) (objRoot string, err error) {
+	if opts.Algorithm == "" {
+		opts.Algorithm = checksum.SHA512
+	}
+	if !Supported(opts.Algorithm) {
+		return "", fmt.Errorf("%w: OCFL digests must be sha512 or sha256, not %s", error_msgs.Err17, opts.Algorithm)
+	}
+
+	relPaths, err := files(ctx, objPath, includeHidden)
+	if err != nil {
+		return "", err
+	}
+
+	paths := make([]string, len(relPaths))
+	for i, relPath := range relPaths {
+		paths[i] = filepath.Join(objPath, filepath.FromSlash(relPath))
+	}
+
+	sums, err := checksum.Files(ctx, paths, opts)
+	if err != nil {
+		return "", err
+	}
+
+	objRoot = filepath.Join(root, filepath.FromSlash(ObjectPath(id)))
+	if err := os.MkdirAll(filepath.Dir(objRoot), 0755); err != nil {
+		return "", err
+	}
+	if err := os.Mkdir(objRoot, 0755); err != nil {
+		return "", err
+	}
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, os.RemoveAll(objRoot))
+		}
+	}()
+
+	inventory := Inventory{
+		ID:              id,
+		Type:            InventoryType,
+		DigestAlgorithm: opts.Algorithm,
+		Head:            Head,
+		Manifest:        map[string][]string{},
+		Versions: map[string]Version{Head: {
+			Created: time.Now().UTC().Truncate(time.Second),
+			State:   map[string][]string{},
+			Message: message,
+		}},
+	}
+
+	// A file whose content is already in the object is only added to the state
+	for i, sum := range sums {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+
+		state := inventory.Versions[Head].State
+		state[sum.Checksum] = append(state[sum.Checksum], relPaths[i])
+		if _, ok := inventory.Manifest[sum.Checksum]; ok {
+			continue
+		}
+
+		contentPath := path.Join(Head, ContentDir, relPaths[i])
+		if err := copyFile(paths[i], filepath.Join(objRoot, filepath.FromSlash(contentPath))); err != nil {
+			return "", err
+		}
+		inventory.Manifest[sum.Checksum] = []string{contentPath}
+	}
+
+	if err := os.WriteFile(filepath.Join(objRoot, ObjectDeclaration), []byte("ocfl_object_1.1\n"), 0644); err != nil {
+		return "", err
+	}
+
+	// The inventory of the object is the same as that of its only version
+	for _, dir := range []string{objRoot, filepath.Join(objRoot, Head)} {
+		if err := writeInventory(dir, inventory); err != nil {
+			return "", err
+		}
+	}
+
+	return objRoot, nil
+}
+
+// files returns the slash separated paths of the regular files of the object, relative to objPath
+func files(ctx context.Context, objPath string, includeHidden bool) ([]string, error) {
+	var relPaths []string
+
+	err := filepath.WalkDir(objPath, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// Stop before the next file once the context is canceled
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if filePath != objPath && !includeHidden && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(objPath, filePath)
+		if err != nil {
+			return err
+		}
+		relPaths = append(relPaths, filepath.ToSlash(rel))
+
+		return nil
+	})
+
+	return relPaths, err
+}
+
+// copyFile copies the file at src to dest, creating the directories of dest
+func copyFile(src, dest string) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, out.Close())
+	}()
+
+	_, err = io.Copy(out, in)
+	return err
+}
+
+// writeInventory writes the inventory into the directory with the sidecar file of its digest
+func writeInventory(dir string, inventory Inventory) error {
+	data, err := json.MarshalIndent(inventory, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	inventoryPath := filepath.Join(dir, InventoryName)
+	if err := os.WriteFile(inventoryPath, data, 0644); err != nil {
+		return err
+	}
+
+	sums, err := checksum.Files(context.Background(), []string{inventoryPath},
+		checksum.Options{Algorithm: inventory.DigestAlgorithm})
+	if err != nil {
+		return err
+	}
+
+	sidecar := fmt.Sprintf("%s  %s\n", sums[0].Checksum, InventoryName)
+	return os.WriteFile(inventoryPath+"."+inventory.DigestAlgorithm, []byte(sidecar), 0644)
+}
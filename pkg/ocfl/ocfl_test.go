@@ -0,0 +1,125 @@
+package ocfl
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/UCLALibrary/pt-tools/pkg/checksum"
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeObject creates the files of an object, with two files that have the same content and a hidden
+// file, and returns its directory
+func writeObject(t *testing.T) string {
+	objPath := t.TempDir()
+	for name, content := range map[string]string{"a.txt": "hello\n", "sub/b.txt": "hello\n", "c.txt": "", ".hidden": "secret"} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(objPath, name)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(objPath, name), []byte(content), 0644))
+	}
+
+	return objPath
+}
+
+// readInventory reads the inventory in the directory
+func readInventory(t *testing.T, dir string) Inventory {
+	data, err := os.ReadFile(filepath.Join(dir, InventoryName))
+	require.NoError(t, err)
+
+	var inventory Inventory
+	require.NoError(t, json.Unmarshal(data, &inventory))
+
+	return inventory
+}
+
+// TestObjectPath tests that objects are placed with the defaults of the hashed n-tuple storage layout
+func TestObjectPath(t *testing.T) {
+	// The example of the extension, https://ocfl.github.io/extensions/0004-hashed-n-tuple-storage-layout.html
+	assert.Equal(t, "3c0/ff4/240/3c0ff4240c1e116dba14c7627f2319b58aa3d77606d0d90dfc6161608ac987d4",
+		ObjectPath("object-01"))
+
+	parts := strings.Split(ObjectPath("ark:/a5388"), "/")
+	require.Len(t, parts, 4)
+	assert.Equal(t, parts[0]+parts[1]+parts[2], parts[3][:9])
+}
+
+// TestCreateRoot tests that an empty or missing directory becomes a storage root and any other is refused
+func TestCreateRoot(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "ocfl")
+
+	require.NoError(t, CreateRoot(root))
+	assert.FileExists(t, filepath.Join(root, RootDeclaration))
+	assert.FileExists(t, filepath.Join(root, LayoutName))
+
+	// A storage root can be added to again
+	require.NoError(t, CreateRoot(root))
+
+	other := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(other, "file.txt"), nil, 0644))
+	assert.ErrorIs(t, CreateRoot(other), error_msgs.Err43)
+}
+
+// TestWrite tests that the inventory of a written object has its files and that their content is stored once
+func TestWrite(t *testing.T) {
+	objPath := writeObject(t)
+	root := filepath.Join(t.TempDir(), "ocfl")
+	require.NoError(t, CreateRoot(root))
+
+	objRoot, err := Write(context.Background(), root, objPath, "ark:/a5388", "pt test", false, checksum.Options{})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, filepath.FromSlash(ObjectPath("ark:/a5388"))), objRoot)
+	assert.FileExists(t, filepath.Join(objRoot, ObjectDeclaration))
+
+	inventory := readInventory(t, objRoot)
+	assert.Equal(t, readInventory(t, filepath.Join(objRoot, Head)), inventory)
+	assert.Equal(t, "ark:/a5388", inventory.ID)
+	assert.Equal(t, checksum.SHA512, inventory.DigestAlgorithm)
+	assert.Equal(t, Head, inventory.Head)
+	assert.Len(t, inventory.Manifest, 2)
+
+	sums, err := checksum.Files(context.Background(), []string{filepath.Join(objPath, "a.txt")},
+		checksum.Options{Algorithm: checksum.SHA512})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"v1/content/a.txt"}, inventory.Manifest[sums[0].Checksum])
+
+	version := inventory.Versions[Head]
+	assert.Equal(t, "pt test", version.Message)
+	assert.Equal(t, []string{"a.txt", "sub/b.txt"}, version.State[sums[0].Checksum])
+	assert.NoFileExists(t, filepath.Join(objRoot, Head, ContentDir, "sub", "b.txt"))
+	assert.NoFileExists(t, filepath.Join(objRoot, Head, ContentDir, ".hidden"))
+
+	// The sidecar has the digest of the inventory
+	sidecar, err := os.ReadFile(filepath.Join(objRoot, InventoryName+".sha512"))
+	require.NoError(t, err)
+	sums, err = checksum.Files(context.Background(), []string{filepath.Join(objRoot, InventoryName)},
+		checksum.Options{Algorithm: checksum.SHA512})
+	require.NoError(t, err)
+	assert.Equal(t, sums[0].Checksum+"  "+InventoryName+"\n", string(sidecar))
+
+	// An object that is already in the storage root is left as it is
+	_, err = Write(context.Background(), root, objPath, "ark:/a5388", "pt test", true, checksum.Options{})
+	assert.ErrorIs(t, err, os.ErrExist)
+	assert.FileExists(t, filepath.Join(objRoot, InventoryName))
+}
+
+// TestWriteAlgorithm tests that only the digest algorithms OCFL allows can be used
+func TestWriteAlgorithm(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, CreateRoot(root))
+
+	objRoot, err := Write(context.Background(), root, writeObject(t), "ark:/a5388", "", true,
+		checksum.Options{Algorithm: checksum.SHA256})
+	require.NoError(t, err)
+	assert.Equal(t, checksum.SHA256, readInventory(t, objRoot).DigestAlgorithm)
+	assert.FileExists(t, filepath.Join(objRoot, InventoryName+".sha256"))
+	assert.FileExists(t, filepath.Join(objRoot, Head, ContentDir, ".hidden"))
+
+	_, err = Write(context.Background(), root, writeObject(t), "ark:/b5488", "", false,
+		checksum.Options{Algorithm: checksum.MD5})
+	assert.ErrorIs(t, err, error_msgs.Err17)
+}
@@ -0,0 +1,524 @@
+package pairtree
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+
+	"github.com/UCLALibrary/pt-tools/pkg/checksum"
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/klauspost/compress/zstd"
+	"github.com/klauspost/pgzip"
+	"github.com/spf13/afero"
+)
+
+// compressBlockSize is the size of the blocks that are compressed in parallel
+const compressBlockSize = 1 << 20
+
+// ArchiveOptions tunes how an archive is written
+type ArchiveOptions struct {
+	// CompressWorkers is the number of blocks compressed in parallel, zero uses every CPU
+	CompressWorkers int
+	// Cache, when set, keeps the checksums of the files as they are archived, so verifying the archive
+	// with VerifyArchive does not read them again
+	Cache *checksum.Cache
+}
+
+const (
+	// TgzFormat is the format of the tar.gz archives of TarGz and UnTarGz
+	TgzFormat = "tgz"
+	// TzstFormat is the format of the tar archives compressed with Zstandard of TarZst and UnTarZst
+	TzstFormat = "tzst"
+	// ZipFormat is the format of the zip archives of Zip and UnZip
+	ZipFormat = "zip"
+)
+
+// Formats are the formats of the archives that can be written and extracted
+var Formats = []string{TgzFormat, TzstFormat, ZipFormat}
+
+// ArchiveFormat returns the format of the archive at the path from its extension, which is ZipFormat
+// for a .zip file, TzstFormat for a .tzst or .zst file, and TgzFormat for any other
+func ArchiveFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case zipExt:
+		return ZipFormat
+	case tzstExt, ".zst":
+		return TzstFormat
+	default:
+		return TgzFormat
+	}
+}
+
+// Archive compresses the source directory or file into an archive of the format in the destination
+// directory, with TarGz, TarZst, or Zip
+func Archive(ctx context.Context, src, dest, prefix, format string, overwrite bool, opts ArchiveOptions) error {
+	return New(afero.NewOsFs(), "").withPrefix(prefix).Archive(ctx, src, dest, format, overwrite, opts)
+}
+
+// Archive compresses the source directory or file of the file system of the pairtree into an archive
+// of the format, named with the prefix of the pairtree
+func (p *Pairtree) Archive(ctx context.Context, src, dest, format string, overwrite bool, opts ArchiveOptions) error {
+	switch format {
+	case ZipFormat:
+		return p.Zip(ctx, src, dest, overwrite, opts)
+	case TzstFormat:
+		return p.TarZst(ctx, src, dest, overwrite, opts)
+	default:
+		return p.TarGz(ctx, src, dest, overwrite, opts)
+	}
+}
+
+// ArchiveDestination returns the path of the archive that Archive would write, without writing it, so
+// that archiving can be previewed
+func ArchiveDestination(src, dest, prefix, format string, overwrite bool) string {
+	return New(afero.NewOsFs(), "").withPrefix(prefix).ArchiveDestination(src, dest, format, overwrite)
+}
+
+// ArchiveDestination returns the path of the archive that Archive would write in the file system of the pairtree
+func (p *Pairtree) ArchiveDestination(src, dest, format string, overwrite bool) string {
+	ext := tgzExt
+	switch format {
+	case ZipFormat:
+		ext = zipExt
+	case TzstFormat:
+		ext = tzstExt
+	}
+
+	return p.archivePath(src, dest, ext, overwrite)
+}
+
+// WriteArchive writes the source directory or file to the writer as an archive of the format, with
+// WriteTarGz, WriteTarZst, or WriteZip
+func WriteArchive(ctx context.Context, w io.Writer, src, format string, opts ArchiveOptions) error {
+	return New(afero.NewOsFs(), "").WriteArchive(ctx, w, src, format, opts)
+}
+
+// WriteArchive writes the source directory or file of the file system of the pairtree to the writer as
+// an archive of the format
+func (p *Pairtree) WriteArchive(ctx context.Context, w io.Writer, src, format string, opts ArchiveOptions) error {
+	switch format {
+	case ZipFormat:
+		return p.WriteZip(ctx, w, src)
+	case TzstFormat:
+		return p.WriteTarZst(ctx, w, src, opts)
+	default:
+		return p.WriteTarGz(ctx, w, src, opts)
+	}
+}
+
+// ReadArchive extracts the archive of the format read from the reader as the object directory at dest,
+// with ReadTarGz, ReadTarZst, or ReadZip
+func ReadArchive(ctx context.Context, r io.Reader, dest, format string) error {
+	return New(afero.NewOsFs(), "").ReadArchive(ctx, r, dest, format)
+}
+
+// ReadArchive extracts the archive of the format read from the reader as the object directory at dest
+// of the file system of the pairtree
+func (p *Pairtree) ReadArchive(ctx context.Context, r io.Reader, dest, format string) error {
+	switch format {
+	case ZipFormat:
+		return p.ReadZip(ctx, r, dest)
+	case TzstFormat:
+		return p.ReadTarZst(ctx, r, dest)
+	default:
+		return p.ReadTarGz(ctx, r, dest)
+	}
+}
+
+// UnArchive extracts the archive of the format to the destination directory, with UnTarGz, UnTarZst, or UnZip
+func UnArchive(ctx context.Context, src, dest, format string) error {
+	return New(afero.NewOsFs(), "").UnArchive(ctx, src, dest, format)
+}
+
+// UnArchive extracts the archive of the format of the file system of the pairtree to the destination directory
+func (p *Pairtree) UnArchive(ctx context.Context, src, dest, format string) error {
+	switch format {
+	case ZipFormat:
+		return p.UnZip(ctx, src, dest)
+	case TzstFormat:
+		return p.UnTarZst(ctx, src, dest)
+	default:
+		return p.UnTarGz(ctx, src, dest)
+	}
+}
+
+// WriteTarGz writes the source directory or file to the writer as a tar.gz archive, with the
+// archive's top level folder named after the source. The archive is written while the source is
+// walked, so it can be streamed to standard output or an HTTP response. Blocks of the archive are
+// compressed in parallel into a single gzip stream that any gzip reader can read.
+func WriteTarGz(ctx context.Context, w io.Writer, src string, opts ArchiveOptions) error {
+	return New(afero.NewOsFs(), "").WriteTarGz(ctx, w, src, opts)
+}
+
+// WriteTarGz writes the source directory or file of the file system of the pairtree to the writer as a
+// tar.gz archive
+func (p *Pairtree) WriteTarGz(ctx context.Context, w io.Writer, src string, opts ArchiveOptions) error {
+	return p.writeTarGz(ctx, w, src, "", opts)
+}
+
+// writeTarGz writes the tar.gz archive of the source, leaving out the file at skip. The archive is
+// not finished when the walk fails, so a reader of a partial archive sees that it is incomplete.
+func (p *Pairtree) writeTarGz(ctx context.Context, w io.Writer, src, skip string, opts ArchiveOptions) error {
+	out := &cutWriter{w: w}
+	gzipWriter := pgzip.NewWriter(out)
+	if err := gzipWriter.SetConcurrency(compressBlockSize, compressWorkers(opts)); err != nil {
+		return err
+	}
+
+	if err := p.writeTar(ctx, gzipWriter, src, skip, opts.Cache); err != nil {
+		// Stop the compressing goroutines without finishing the archive
+		out.cut.Store(true)
+		gzipWriter.Close()
+		return err
+	}
+
+	return gzipWriter.Close()
+}
+
+// compressWorkers returns the number of blocks of an archive compressed in parallel
+func compressWorkers(opts ArchiveOptions) int {
+	if opts.CompressWorkers <= 0 {
+		return runtime.GOMAXPROCS(0)
+	}
+
+	return opts.CompressWorkers
+}
+
+// writeTar writes the tar archive of the source to the writer, leaving out the file at skip, and keeps
+// the checksums of its files in the cache when there is one
+func (p *Pairtree) writeTar(ctx context.Context, w io.Writer, src, skip string, cache *checksum.Cache) error {
+	tarWriter := tar.NewWriter(w)
+
+	err := afero.Walk(p.fs, src, func(filePath string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// Stop before the next file once the context is canceled
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// Do not archive the archive into itself when it is written inside the source
+		if filePath == skip {
+			return nil
+		}
+
+		rel, err := filepath.Rel(src, filePath)
+		if err != nil {
+			return err
+		}
+
+		return p.addToTar(ctx, tarWriter, filePath, filepath.ToSlash(filepath.Join(filepath.Base(src), rel)), info, cache)
+	})
+	if err != nil {
+		return err
+	}
+
+	return tarWriter.Close()
+}
+
+// cutWriter passes writes through to the writer until it is cut and discards them after, so the
+// blocks still being compressed and the gzip trailer are not written once an archive fails
+type cutWriter struct {
+	w   io.Writer
+	cut atomic.Bool
+}
+
+func (c *cutWriter) Write(p []byte) (int, error) {
+	if c.cut.Load() {
+		return len(p), nil
+	}
+
+	return c.w.Write(p)
+}
+
+// addToTar writes the header of the file under the name, followed by its content if it is a regular file
+func (p *Pairtree) addToTar(ctx context.Context, tarWriter *tar.Writer, filePath, name string, info fs.FileInfo,
+	cache *checksum.Cache) error {
+	link := ""
+	if info.Mode()&fs.ModeSymlink != 0 {
+		reader, ok := p.fs.(afero.LinkReader)
+		if !ok {
+			return &fs.PathError{Op: "readlink", Path: filePath, Err: afero.ErrNoReadlink}
+		}
+
+		var err error
+		if link, err = reader.ReadlinkIfPossible(filePath); err != nil {
+			return err
+		}
+	}
+
+	header, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+
+	header.Name = name
+	if info.IsDir() {
+		header.Name += "/"
+	}
+
+	if err := tarWriter.WriteHeader(header); err != nil {
+		return err
+	}
+
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+
+	return p.copyContent(ctx, tarWriter, filePath, info, cache)
+}
+
+// copyContent copies the content of the regular file to the writer of its entry in an archive, hashing
+// it into the cache as it is copied when there is one
+func (p *Pairtree) copyContent(ctx context.Context, w io.Writer, filePath string, info fs.FileInfo,
+	cache *checksum.Cache) error {
+	file, err := p.fs.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if cache == nil {
+		_, err = io.Copy(w, file)
+		return err
+	}
+
+	sum, err := checksum.Reader(ctx, io.TeeReader(file, w), filePath, checksum.Options{})
+	if err != nil {
+		return err
+	}
+	cache.Put(sum, info)
+
+	return nil
+}
+
+// ReadTarGz extracts the tar.gz archive read from the reader as the object directory at dest. The
+// archive must have exactly one top level folder, named like the dest directory. The folder is
+// extracted beside dest and renamed into place once the whole archive has been read, so dest is only
+// replaced by a complete object, and the archive can be streamed from standard input or an HTTP request.
+func ReadTarGz(ctx context.Context, r io.Reader, dest string) error {
+	return New(afero.NewOsFs(), "").ReadTarGz(ctx, r, dest)
+}
+
+// ReadTarGz extracts the tar.gz archive read from the reader as the object directory at dest of the file
+// system of the pairtree
+func (p *Pairtree) ReadTarGz(ctx context.Context, r io.Reader, dest string) error {
+	gzipReader, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gzipReader.Close()
+
+	return p.readTar(ctx, gzipReader, dest)
+}
+
+// readTar extracts the tar archive read from the reader as the object directory at dest
+func (p *Pairtree) readTar(ctx context.Context, r io.Reader, dest string) error {
+	return p.extract(ctx, dest, tarEntries(r))
+}
+
+// nextEntry returns the header of the next entry of an archive and the reader of its content, or io.EOF
+// after the last entry. The entries of archives that are not tar archives are described with tar headers.
+type nextEntry func() (*tar.Header, io.Reader, error)
+
+// tarEntries returns the entries of the tar archive read from the reader
+func tarEntries(r io.Reader) nextEntry {
+	tarReader := tar.NewReader(r)
+
+	return func() (*tar.Header, io.Reader, error) {
+		header, err := tarReader.Next()
+		return header, tarReader, err
+	}
+}
+
+// readEntries calls fn with the entries of the archive of the format at src, which are read as fn asks for
+// them so the archive is never extracted
+func (p *Pairtree) readEntries(src, format string, fn func(next nextEntry) error) error {
+	in, err := p.fs.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	switch format {
+	case ZipFormat:
+		info, err := in.Stat()
+		if err != nil {
+			return err
+		}
+
+		zipReader, err := zip.NewReader(in, info.Size())
+		if err != nil {
+			return err
+		}
+
+		next, done := zipEntries(zipReader.File)
+		defer done()
+
+		return fn(next)
+	case TzstFormat:
+		zstdReader, err := zstd.NewReader(in)
+		if err != nil {
+			return err
+		}
+		defer zstdReader.Close()
+
+		return fn(tarEntries(zstdReader))
+	default:
+		gzipReader, err := gzip.NewReader(in)
+		if err != nil {
+			return err
+		}
+		defer gzipReader.Close()
+
+		return fn(tarEntries(gzipReader))
+	}
+}
+
+// extract extracts the entries of an archive as the object directory at dest. The archive's one top
+// level folder is extracted to a hidden directory beside dest and renamed into place once every entry
+// has been read.
+func (p *Pairtree) extract(ctx context.Context, dest string, next nextEntry) (err error) {
+	id := filepath.Base(dest)
+	parent := filepath.Dir(dest)
+
+	if err := p.fs.MkdirAll(parent, 0755); err != nil {
+		return err
+	}
+
+	// The folder is extracted to a hidden directory beside dest so it can be renamed into place
+	staging, err := afero.TempDir(p.fs, parent, "."+id+".extract-")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, p.fs.RemoveAll(staging))
+	}()
+
+	top := ""
+
+	for {
+		// Stop before the next entry once the context is canceled
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		header, content, err := next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		name := path.Clean(header.Name)
+		if name == "." {
+			continue
+		}
+		if !filepath.IsLocal(filepath.FromSlash(name)) {
+			return fmt.Errorf("%w: %s is outside of the folder", error_msgs.Err12, header.Name)
+		}
+
+		folder, rest, _ := strings.Cut(name, "/")
+		if (top != "" && folder != top) || (rest == "" && header.Typeflag != tar.TypeDir) {
+			return error_msgs.Err12
+		}
+		top = folder
+
+		// An archive of another object is reported once it is known to have only the one folder
+		if folder != id {
+			continue
+		}
+
+		if err := p.extractEntry(content, header, staging, name); err != nil {
+			return err
+		}
+	}
+
+	if top == "" {
+		return error_msgs.Err12
+	}
+	if top != id {
+		return error_msgs.Err13
+	}
+
+	if err := p.fs.MkdirAll(filepath.Join(staging, id), 0755); err != nil {
+		return err
+	}
+
+	// Remove what is at the destination to ensure a full overwrite
+	if err := p.fs.RemoveAll(dest); err != nil {
+		return err
+	}
+
+	return p.fs.Rename(filepath.Join(staging, id), dest)
+}
+
+// extractEntry writes the entry of the archive with the name to the staging directory. Directories,
+// regular files, and links that stay within the folder are extracted, other entries are skipped.
+func (p *Pairtree) extractEntry(content io.Reader, header *tar.Header, staging, name string) error {
+	target := filepath.Join(staging, filepath.FromSlash(name))
+
+	if err := p.fs.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	switch header.Typeflag {
+	case tar.TypeDir:
+		return p.fs.MkdirAll(target, 0755)
+	case tar.TypeReg:
+		file, err := p.fs.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, header.FileInfo().Mode().Perm())
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(file, content); err != nil {
+			file.Close()
+			return err
+		}
+		return file.Close()
+	case tar.TypeSymlink:
+		if path.IsAbs(header.Linkname) || !inFolder(name, path.Join(path.Dir(name), header.Linkname)) {
+			return fmt.Errorf("%w: the link %s points outside of the folder", error_msgs.Err12, header.Name)
+		}
+		linker, ok := p.fs.(afero.Linker)
+		if !ok {
+			return &os.LinkError{Op: "symlink", Old: header.Linkname, New: target, Err: afero.ErrNoSymlink}
+		}
+		return linker.SymlinkIfPossible(header.Linkname, target)
+	case tar.TypeLink:
+		if path.IsAbs(header.Linkname) || !inFolder(name, path.Clean(header.Linkname)) {
+			return fmt.Errorf("%w: the link %s points outside of the folder", error_msgs.Err12, header.Name)
+		}
+		return p.link(filepath.Join(staging, filepath.FromSlash(path.Clean(header.Linkname))), target)
+	default:
+		return nil
+	}
+}
+
+// link makes a hard link to the file on the local file system. Other file systems can not link files,
+// so the file is copied.
+func (p *Pairtree) link(oldname, newname string) error {
+	if _, ok := p.fs.(*afero.OsFs); ok {
+		return os.Link(oldname, newname)
+	}
+
+	return copyStorageFile(context.Background(), p.storage, oldname, p.storage, newname)
+}
+
+// inFolder reports whether the linked path is within the top level folder of the named entry
+func inFolder(name, linked string) bool {
+	folder, _, _ := strings.Cut(name, "/")
+	return linked == folder || strings.HasPrefix(linked, folder+"/")
+}
@@ -0,0 +1,190 @@
+package pairtree
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWriteReadTarGz tests that an object streamed through an archive is extracted as it was
+func TestWriteReadTarGz(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "a5388")
+	for _, path := range []string{"a5388.txt", "folder/inner.txt", "folder/.hidden/inner.txt", "empty/"} {
+		createPath(t, src, path)
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(src, "a5388.txt"), []byte("content"), 0644))
+	require.NoError(t, os.Chmod(filepath.Join(src, "a5388.txt"), 0640))
+	require.NoError(t, os.Symlink("folder/inner.txt", filepath.Join(src, "link.txt")))
+
+	var archive bytes.Buffer
+	require.NoError(t, WriteTarGz(context.Background(), &archive, src, ArchiveOptions{}))
+
+	// The destination is replaced by the object in the archive
+	dest := filepath.Join(t.TempDir(), "pairtree_root", "a5", "38", "8", "a5388")
+	createPath(t, dest, "old.txt")
+	require.NoError(t, ReadTarGz(context.Background(), &archive, dest))
+
+	content, err := os.ReadFile(filepath.Join(dest, "a5388.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "content", string(content))
+
+	info, err := os.Stat(filepath.Join(dest, "a5388.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+
+	assert.FileExists(t, filepath.Join(dest, "folder", ".hidden", "inner.txt"))
+	assert.DirExists(t, filepath.Join(dest, "empty"))
+	assert.NoFileExists(t, filepath.Join(dest, "old.txt"))
+
+	link, err := os.Readlink(filepath.Join(dest, "link.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "folder/inner.txt", link)
+
+	// Nothing is left beside the object from extracting it
+	entries, err := os.ReadDir(filepath.Dir(dest))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "a5388", entries[0].Name())
+}
+
+// TestArchiveFormat tests that the format of an archive is found from its extension
+func TestArchiveFormat(t *testing.T) {
+	assert.Equal(t, ZipFormat, ArchiveFormat("/archives/ark+=a5388.zip"))
+	assert.Equal(t, ZipFormat, ArchiveFormat("ARK+=A5388.ZIP"))
+	assert.Equal(t, TzstFormat, ArchiveFormat("ark+=a5388.tzst"))
+	assert.Equal(t, TzstFormat, ArchiveFormat("ark+=a5388.tar.zst"))
+	assert.Equal(t, TgzFormat, ArchiveFormat("ark+=a5388.tgz"))
+	assert.Equal(t, TgzFormat, ArchiveFormat("-"))
+}
+
+// TestWriteTarGzWorkers tests that an archive compressed by any number of workers is one gzip stream
+func TestWriteTarGzWorkers(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "a5388")
+	require.NoError(t, os.MkdirAll(src, 0755))
+
+	// The content spans several compressed blocks
+	content := make([]byte, 3*compressBlockSize+17)
+	for i := range content {
+		content[i] = byte(i * i >> 7)
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(src, "large.bin"), content, 0644))
+
+	for _, workers := range []int{0, 1, 4} {
+		var archive bytes.Buffer
+		require.NoError(t, WriteTarGz(context.Background(), &archive, src, ArchiveOptions{CompressWorkers: workers}))
+
+		gzipReader, err := gzip.NewReader(&archive)
+		require.NoError(t, err)
+		gzipReader.Multistream(false)
+
+		tarReader := tar.NewReader(gzipReader)
+		var names []string
+		for {
+			header, err := tarReader.Next()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			names = append(names, header.Name)
+
+			if header.Typeflag == tar.TypeReg {
+				extracted, err := io.ReadAll(tarReader)
+				require.NoError(t, err)
+				assert.Equal(t, content, extracted, "workers: %d", workers)
+			}
+		}
+		assert.Equal(t, []string{"a5388/", "a5388/large.bin"}, names)
+
+		// Nothing follows the end of the gzip stream
+		_, err = io.Copy(io.Discard, gzipReader)
+		require.NoError(t, err)
+		rest, err := io.ReadAll(&archive)
+		require.NoError(t, err)
+		assert.Empty(t, rest)
+	}
+}
+
+// TestReadTarGzNotValid tests that archives without exactly the destination's folder are not extracted
+func TestReadTarGzNotValid(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers []tar.Header
+		wantErr error
+	}{
+		{
+			name:    "Empty archive",
+			wantErr: error_msgs.Err12,
+		},
+		{
+			name: "More than one folder",
+			headers: []tar.Header{
+				{Name: "a5388/", Typeflag: tar.TypeDir},
+				{Name: "b5488/", Typeflag: tar.TypeDir},
+			},
+			wantErr: error_msgs.Err12,
+		},
+		{
+			name:    "File at the top level",
+			headers: []tar.Header{{Name: "a5388", Typeflag: tar.TypeReg}},
+			wantErr: error_msgs.Err12,
+		},
+		{
+			name:    "Path outside of the folder",
+			headers: []tar.Header{{Name: "a5388/../../escaped.txt", Typeflag: tar.TypeReg}},
+			wantErr: error_msgs.Err12,
+		},
+		{
+			name:    "Absolute path",
+			headers: []tar.Header{{Name: "/a5388/file.txt", Typeflag: tar.TypeReg}},
+			wantErr: error_msgs.Err12,
+		},
+		{
+			name:    "Link outside of the folder",
+			headers: []tar.Header{{Name: "a5388/link", Typeflag: tar.TypeSymlink, Linkname: "../../etc"}},
+			wantErr: error_msgs.Err12,
+		},
+		{
+			name:    "Folder of another object",
+			headers: []tar.Header{{Name: "b5488/file.txt", Typeflag: tar.TypeReg}},
+			wantErr: error_msgs.Err13,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var archive bytes.Buffer
+			gzipWriter := gzip.NewWriter(&archive)
+			tarWriter := tar.NewWriter(gzipWriter)
+			for _, header := range tt.headers {
+				header.Mode = 0644
+				require.NoError(t, tarWriter.WriteHeader(&header))
+			}
+			require.NoError(t, tarWriter.Close())
+			require.NoError(t, gzipWriter.Close())
+
+			// A failed extraction leaves the destination as it was
+			parent := t.TempDir()
+			dest := filepath.Join(parent, "a5388")
+			createPath(t, dest, "old.txt")
+
+			err := ReadTarGz(context.Background(), &archive, dest)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.FileExists(t, filepath.Join(dest, "old.txt"))
+
+			entries, err := os.ReadDir(parent)
+			require.NoError(t, err)
+			assert.Len(t, entries, 1)
+		})
+	}
+}
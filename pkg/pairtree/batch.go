@@ -0,0 +1,124 @@
+package pairtree
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"time"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+)
+
+// Operation is one item of a batch, like copying or deleting an object, that Run performs
+type Operation struct {
+	ID   string
+	Path string
+	Run  func(ctx context.Context) error
+}
+
+// Result is the outcome of an operation of a batch, Err is nil when the operation succeeded
+type Result struct {
+	ID       string        `json:"id"`
+	Path     string        `json:"path,omitempty"`
+	Err      error         `json:"-"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Progress is how far a batch has got, it is reported each time an operation finishes
+type Progress struct {
+	Done   int
+	Failed int
+	Total  int
+	Last   Result
+}
+
+// Batch runs operations with a bounded number of workers, so commands that work on many objects
+// share one engine for concurrency, results, and progress
+type Batch struct {
+	// Workers is the most operations run at once, runtime.NumCPU() when it is not positive
+	Workers int
+	// OnProgress is called each time an operation finishes, never by more than one worker at a time
+	OnProgress func(Progress)
+}
+
+// NewBatch creates a Batch that runs the given number of operations at once
+func NewBatch(workers int) *Batch {
+	return &Batch{Workers: workers}
+}
+
+// Run performs the operations and returns their results in the order of the operations. A failed
+// operation does not stop the others, and the returned error joins the error of each failed one as
+// a PtError with its ID and path. Operations that have not started when the context is canceled are
+// not run and fail with the context's error.
+func (b *Batch) Run(ctx context.Context, operations []Operation) ([]Result, error) {
+	workers := b.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > len(operations) {
+		workers = len(operations)
+	}
+
+	results := make([]Result, len(operations))
+	indexes := make(chan int)
+
+	var mu sync.Mutex
+	progress := Progress{Total: len(operations)}
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range indexes {
+				results[i] = run(ctx, operations[i])
+
+				mu.Lock()
+				progress.Done++
+				if results[i].Err != nil {
+					progress.Failed++
+				}
+				progress.Last = results[i]
+				if b.OnProgress != nil {
+					b.OnProgress(progress)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for i := range operations {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	var errs []error
+	for _, result := range results {
+		if result.Err != nil {
+			errs = append(errs, &error_msgs.PtError{ID: result.ID, Path: result.Path, Err: result.Err})
+		}
+	}
+
+	return results, errors.Join(errs...)
+}
+
+// run performs the operation unless the context has been canceled
+func run(ctx context.Context, operation Operation) Result {
+	result := Result{ID: operation.ID, Path: operation.Path}
+	start := time.Now()
+
+	if result.Err = ctx.Err(); result.Err == nil {
+		result.Err = operation.Run(ctx)
+	}
+
+	result.Duration = time.Since(start)
+	if result.Err != nil {
+		result.Error = result.Err.Error()
+	}
+
+	return result
+}
@@ -0,0 +1,95 @@
+package pairtree
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBatch tests that operations run at most the number of workers at once and their results and
+// errors are kept in order
+func TestBatch(t *testing.T) {
+	var running, most atomic.Int32
+	failure := errors.New("permission denied")
+
+	operations := make([]Operation, 20)
+	for i := range operations {
+		id := fmt.Sprintf("ark:/a%d", i)
+		operations[i] = Operation{ID: id, Path: "/pt/" + id, Run: func(ctx context.Context) error {
+			now := running.Add(1)
+			defer running.Add(-1)
+
+			for {
+				previous := most.Load()
+				if now <= previous || most.CompareAndSwap(previous, now) {
+					break
+				}
+			}
+
+			if i%5 == 0 {
+				return failure
+			}
+			return nil
+		}}
+	}
+
+	var reports []Progress
+	batch := NewBatch(3)
+	batch.OnProgress = func(progress Progress) {
+		reports = append(reports, progress)
+	}
+
+	results, err := batch.Run(context.Background(), operations)
+	require.Len(t, results, 20)
+	assert.LessOrEqual(t, most.Load(), int32(3))
+
+	for i, result := range results {
+		assert.Equal(t, operations[i].ID, result.ID)
+		if i%5 == 0 {
+			assert.ErrorIs(t, result.Err, failure)
+			assert.Equal(t, "permission denied", result.Error)
+		} else {
+			assert.NoError(t, result.Err)
+		}
+	}
+
+	assert.ErrorIs(t, err, failure)
+	var ptErr *error_msgs.PtError
+	require.ErrorAs(t, err, &ptErr)
+	assert.Equal(t, "ark:/a0", ptErr.ID)
+	assert.Equal(t, "/pt/ark:/a0", ptErr.Path)
+
+	require.Len(t, reports, 20)
+	assert.Equal(t, Progress{Done: 20, Failed: 4, Total: 20}, Progress{Done: reports[19].Done,
+		Failed: reports[19].Failed, Total: reports[19].Total})
+}
+
+// TestBatchCanceled tests that operations are not run once the context is canceled
+func TestBatchCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var ran atomic.Int32
+	operations := []Operation{
+		{ID: "ark:/a5388", Run: func(ctx context.Context) error { ran.Add(1); return nil }},
+		{ID: "ark:/b5488", Run: func(ctx context.Context) error { ran.Add(1); return nil }},
+	}
+
+	results, err := NewBatch(0).Run(ctx, operations)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Zero(t, ran.Load())
+	for _, result := range results {
+		assert.ErrorIs(t, result.Err, context.Canceled)
+	}
+
+	// A batch of no operations has no results
+	results, err = NewBatch(0).Run(context.Background(), nil)
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+}
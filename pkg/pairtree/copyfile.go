@@ -0,0 +1,153 @@
+package pairtree
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"unsafe"
+)
+
+// DefaultCopyBufferSize is the size of the buffer a file is copied with when CopyOptions copies with
+// O_DIRECT and does not set a size, a small buffer throttles copies of large files over fast networks
+const DefaultCopyBufferSize = 1 << 20
+
+// kernelCopyChunkSize is how much of a file is copied in the kernel between checks of the context
+const kernelCopyChunkSize = 64 << 20
+
+// CopyOptions tunes how CopyFileOrFolder copies files, the zero value has the kernel copy them
+type CopyOptions struct {
+	// BufferSize is the size in bytes of the buffer each file is copied with, setting it copies
+	// files through the buffer instead of in the kernel
+	BufferSize int
+	// Direct copies with O_DIRECT on Linux so a large copy does not fill the page cache, it is ignored
+	// on file systems and platforms that do not support it
+	Direct bool
+	// Jobs is the number of files of a directory copied at once, with one or less copying them one at a time
+	Jobs int
+	// Verify compares the checksums of the copy with those of the source once it is made, with Verify, so
+	// that a copy that does not match fails. It only applies to copies on the local file system.
+	Verify bool
+}
+
+// inKernel reports whether files are copied by the kernel rather than through a buffer of the process,
+// which is not possible with O_DIRECT because it needs the buffer to be aligned
+func (o CopyOptions) inKernel() bool {
+	return o.BufferSize <= 0 && !o.Direct
+}
+
+// bufferSize returns the size of the buffer to copy a file of the size with. It is no bigger than
+// needed to read the whole file in one go, so copying many small files does not allocate a large
+// buffer for each, and is a whole number of blocks when copying with O_DIRECT.
+func (o CopyOptions) bufferSize(fileSize int64) int {
+	size := o.BufferSize
+	if size <= 0 {
+		size = DefaultCopyBufferSize
+	}
+
+	// One byte more than the file lets the first read reach the end of it
+	if fileSize < int64(size) {
+		size = int(fileSize) + 1
+	}
+
+	if o.Direct && size%directAlignment != 0 {
+		size += directAlignment - size%directAlignment
+	}
+
+	return size
+}
+
+// copyFile copies the regular file at src to dest in the kernel, or through a buffer of the size set
+// in the options, checking the context as it goes so a canceled copy of a large file stops promptly
+func copyFile(ctx context.Context, src, dest string, info os.FileInfo, opts CopyOptions) (err error) {
+	in, err := openFile(src, os.O_RDONLY, 0, opts.Direct)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dest), os.ModePerm); err != nil {
+		return err
+	}
+
+	out, err := openFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm(), opts.Direct)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, out.Close())
+	}()
+
+	if opts.inKernel() {
+		err = copyInKernel(ctx, out, in, kernelCopyChunkSize)
+	} else {
+		err = copyBuffered(ctx, out, in, alignedBuffer(opts.bufferSize(info.Size())))
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Chmod(dest, info.Mode())
+}
+
+// copyInKernel copies the file a chunk at a time with os.File.ReadFrom, which uses copy_file_range,
+// sendfile, or splice where the platform and file systems support them and copies through a buffer
+// where they do not
+func copyInKernel(ctx context.Context, out, in *os.File, chunkSize int64) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		n, err := out.ReadFrom(io.LimitReader(in, chunkSize))
+		if err != nil {
+			return err
+		}
+
+		// Only the last chunk is short
+		if n < chunkSize {
+			return nil
+		}
+	}
+}
+
+// copyBuffered copies the file through the buffer, checking the context between reads
+func copyBuffered(ctx context.Context, out, in *os.File, buf []byte) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		n, readErr := io.ReadFull(in, buf)
+		if n > 0 {
+			if n%directAlignment != 0 {
+				// O_DIRECT only writes whole blocks, so the end of the file is written through the page cache
+				if err := clearDirect(out); err != nil {
+					return err
+				}
+			}
+			if _, err := out.Write(buf[:n]); err != nil {
+				return err
+			}
+		}
+
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			return nil
+		} else if readErr != nil {
+			return readErr
+		}
+	}
+}
+
+// alignedBuffer returns a buffer of the size whose start is aligned for O_DIRECT
+func alignedBuffer(size int) []byte {
+	buf := make([]byte, size+directAlignment)
+
+	offset := 0
+	if remainder := int(uintptr(unsafe.Pointer(&buf[0])) % directAlignment); remainder != 0 {
+		offset = directAlignment - remainder
+	}
+
+	return buf[offset : offset+size]
+}
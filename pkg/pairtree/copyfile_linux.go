@@ -0,0 +1,43 @@
+package pairtree
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// directAlignment is the alignment of the buffer, offsets, and lengths of O_DIRECT reads and writes
+const directAlignment = 4096
+
+// openFile opens the file with O_DIRECT when direct is set, unless its file system does not support it
+func openFile(path string, flag int, perm os.FileMode, direct bool) (*os.File, error) {
+	if direct {
+		file, err := os.OpenFile(path, flag|syscall.O_DIRECT, perm)
+		if !errors.Is(err, syscall.EINVAL) {
+			return file, err
+		}
+	}
+
+	return os.OpenFile(path, flag, perm)
+}
+
+// clearDirect turns off O_DIRECT for the rest of the writes to the file
+func clearDirect(file *os.File) error {
+	conn, err := file.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var fcntlErr error
+	err = conn.Control(func(fd uintptr) {
+		flags, _, errno := syscall.Syscall(syscall.SYS_FCNTL, fd, syscall.F_GETFL, 0)
+		if errno == 0 && flags&syscall.O_DIRECT != 0 {
+			_, _, errno = syscall.Syscall(syscall.SYS_FCNTL, fd, syscall.F_SETFL, flags&^syscall.O_DIRECT)
+		}
+		if errno != 0 {
+			fcntlErr = errno
+		}
+	})
+
+	return errors.Join(err, fcntlErr)
+}
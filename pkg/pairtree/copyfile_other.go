@@ -0,0 +1,18 @@
+//go:build !linux
+
+package pairtree
+
+import "os"
+
+// directAlignment is 1 because O_DIRECT is only used on Linux, so buffers need no alignment
+const directAlignment = 1
+
+// openFile opens the file, O_DIRECT is not used on this platform
+func openFile(path string, flag int, perm os.FileMode, direct bool) (*os.File, error) {
+	return os.OpenFile(path, flag, perm)
+}
+
+// clearDirect does nothing because O_DIRECT is not used on this platform
+func clearDirect(file *os.File) error {
+	return nil
+}
@@ -0,0 +1,58 @@
+package pairtree
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	caltech_pairtree "github.com/caltechlibrary/pairtree"
+)
+
+// decodeName decodes the encoded name of an object directory back into the ID without its prefix. It reads
+// the name once from left to right, so an escaped ^ is never read as the start of another escape. A name
+// that is not exactly what encoding the ID would produce is rejected.
+func decodeName(name string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("%w: %q", error_msgs.Err26, name)
+	}
+
+	id := make([]byte, 0, len(name))
+	for i := 0; i < len(name); i++ {
+		switch c := name[i]; c {
+		case '^':
+			// Every ^ must start a two digit hex escape
+			if i+2 >= len(name) || !isHex(name[i+1]) || !isHex(name[i+2]) {
+				return "", fmt.Errorf("%w: %q", error_msgs.Err26, name)
+			}
+			id = append(id, unhex(name[i+1])<<4|unhex(name[i+2]))
+			i += 2
+		case '=':
+			id = append(id, '/')
+		case '+':
+			id = append(id, ':')
+		case ',':
+			id = append(id, '.')
+		default:
+			id = append(id, c)
+		}
+	}
+
+	if !utf8.Valid(id) || string(caltech_pairtree.CharEncode([]rune(string(id)))) != name {
+		return "", fmt.Errorf("%w: %q", error_msgs.Err26, name)
+	}
+
+	return string(id), nil
+}
+
+// isHex checks if the byte is a lowercase hex digit
+func isHex(b byte) bool {
+	return (b >= '0' && b <= '9') || (b >= 'a' && b <= 'f')
+}
+
+// unhex returns the value of a lowercase hex digit
+func unhex(b byte) byte {
+	if b >= 'a' {
+		return b - 'a' + 10
+	}
+	return b - '0'
+}
@@ -0,0 +1,419 @@
+package pairtree
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ListOptions chooses what is listed of an object by WalkListing and WriteListingJSON
+type ListOptions struct {
+	// Recursive lists the directories under the listed directory as well
+	Recursive bool
+	// ShowAll lists hidden files and directories
+	ShowAll bool
+	// DirsOnly lists only directories
+	DirsOnly bool
+	// Jobs is the number of directories a recursive listing reads at once. With more than one, the whole
+	// listing is read before any of it is passed on, in the same order as when it is read serially.
+	Jobs int
+	// Long adds the size, modification time, and mode of each entry to the JSON of a listing, like ls -l
+	Long bool
+	// Sort is the order of the entries of each directory, which is by name when it is empty. Walk passes
+	// entries on as they are read, so it does not sort them.
+	Sort SortOrder
+	// Reverse lists the entries of each directory in the reverse of their order
+	Reverse bool
+	// Depth is the number of levels of directories a recursive listing goes down, counting the listed
+	// directory as the first, so a depth of 1 lists only it. There is no limit when it is 0.
+	Depth int
+}
+
+// SortOrder is the order the entries of a directory are listed in
+type SortOrder string
+
+const (
+	// SortByName lists entries in the byte order of their names
+	SortByName SortOrder = "name"
+	// SortBySize lists the largest entries first, like ls -S
+	SortBySize SortOrder = "size"
+	// SortByModTime lists the most recently modified entries first, like ls -t
+	SortByModTime SortOrder = "mtime"
+)
+
+// SortOrders are the orders a listing can be sorted in
+var SortOrders = []SortOrder{SortByName, SortBySize, SortByModTime}
+
+// descend returns the options that the directories under a listed directory are listed with, and whether
+// they are listed at all, which they are when the listing is recursive and not yet at its depth
+func (o ListOptions) descend() (ListOptions, bool) {
+	if !o.Recursive || o.Depth == 1 {
+		return o, false
+	}
+
+	if o.Depth > 1 {
+		o.Depth--
+	}
+	return o, true
+}
+
+// filtered checks if the options leave any entries out of a listing
+func (o ListOptions) filtered() bool {
+	return !o.ShowAll || o.DirsOnly
+}
+
+// readListing returns the entries of the directory that the options list, in the order of the options.
+// Nothing is read once the context is canceled.
+func readListing(ctx context.Context, storage Storage, dir string, opts ListOptions) ([]fs.DirEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	entries, err := storage.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	listed := entries[:0]
+	for _, entry := range entries {
+		if (!opts.ShowAll && IsHidden(entry.Name())) || (opts.DirsOnly && !entry.IsDir()) {
+			continue
+		}
+		listed = append(listed, entry)
+	}
+
+	if err := sortListing(listed, opts); err != nil {
+		return nil, err
+	}
+
+	return listed, nil
+}
+
+// sortListing sorts the entries in the order of the options. Entries that are equal in the order are
+// sorted by name, so the same entries are always listed in the same order.
+func sortListing(entries []fs.DirEntry, opts ListOptions) error {
+	if (opts.Sort == "" || opts.Sort == SortByName) && !opts.Reverse {
+		return nil
+	}
+
+	infos := make(map[string]fs.FileInfo, len(entries))
+	if opts.Sort == SortBySize || opts.Sort == SortByModTime {
+		for _, entry := range entries {
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			infos[entry.Name()] = info
+		}
+	}
+
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int {
+		order := 0
+		switch opts.Sort {
+		case SortBySize:
+			order = cmp.Compare(infos[b.Name()].Size(), infos[a.Name()].Size())
+		case SortByModTime:
+			order = infos[b.Name()].ModTime().Compare(infos[a.Name()].ModTime())
+		}
+		if order == 0 {
+			order = strings.Compare(a.Name(), b.Name())
+		}
+
+		if opts.Reverse {
+			return -order
+		}
+		return order
+	})
+
+	return nil
+}
+
+// WalkListing calls fn with each directory that is listed under path, starting with path, and its
+// listed entries. Directories are walked depth first and one is read at a time, so the memory used
+// depends on the largest directory rather than on the number of files in the object. A directory
+// that has no listed entries is skipped when the options leave entries out.
+func WalkListing(path string, opts ListOptions, fn func(dir string, entries []fs.DirEntry) error) error {
+	return WalkListingCtx(context.Background(), path, opts, fn)
+}
+
+// WalkListingCtx is WalkListing that stops before the next directory once the context is canceled
+func WalkListingCtx(ctx context.Context, path string, opts ListOptions, fn func(dir string, entries []fs.DirEntry) error) error {
+	storage, err := StorageFor(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	if storage, err = listingStorage(ctx, storage, path, opts); err != nil {
+		return err
+	}
+	return walkListing(ctx, storage, path, opts, fn)
+}
+
+// WalkListing calls fn with the listing of path of the pairtree and of the directories under it
+func (p *Pairtree) WalkListing(path string, opts ListOptions, fn func(dir string, entries []fs.DirEntry) error) error {
+	return p.WalkListingCtx(context.Background(), path, opts, fn)
+}
+
+// WalkListingCtx is WalkListing that stops before the next directory once the context is canceled
+func (p *Pairtree) WalkListingCtx(ctx context.Context, path string, opts ListOptions, fn func(dir string, entries []fs.DirEntry) error) error {
+	storage, err := listingStorage(ctx, p.storage, path, opts)
+	if err != nil {
+		return err
+	}
+
+	return walkListing(ctx, storage, path, opts, fn)
+}
+
+// walkListing walks the listing of the path in the storage for WalkListing
+func walkListing(ctx context.Context, storage Storage, path string, opts ListOptions, fn func(dir string, entries []fs.DirEntry) error) error {
+	entries, err := readListing(ctx, storage, path, opts)
+	if err != nil {
+		return err
+	}
+
+	if len(entries) > 0 || !opts.filtered() {
+		if err := fn(path, entries); err != nil {
+			return err
+		}
+	}
+
+	subOpts, ok := opts.descend()
+	if !ok {
+		return nil
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			if err := walkListing(ctx, storage, JoinPath(path, entry.Name()), subOpts, fn); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// WriteListingJSON writes the listing of path as the indented JSON of its Directory. It is written a
+// directory at a time, so the tree of a large object is never held in memory.
+func WriteListingJSON(w io.Writer, path string, opts ListOptions) error {
+	return WriteListingJSONCtx(context.Background(), w, path, opts)
+}
+
+// WriteListingJSONCtx is WriteListingJSON that stops before the next directory once the context is
+// canceled, leaving the JSON unfinished
+func WriteListingJSONCtx(ctx context.Context, w io.Writer, path string, opts ListOptions) error {
+	storage, err := StorageFor(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	if !IsS3(path) {
+		path = filepath.FromSlash(path)
+	}
+	if storage, err = listingStorage(ctx, storage, path, opts); err != nil {
+		return err
+	}
+	return writeDirectoryJSON(ctx, w, storage, path, path, "", nil, true, opts)
+}
+
+// WriteListingJSON writes the listing of path of the pairtree as the indented JSON of its Directory
+func (p *Pairtree) WriteListingJSON(w io.Writer, path string, opts ListOptions) error {
+	return p.WriteListingJSONCtx(context.Background(), w, path, opts)
+}
+
+// WriteListingJSONCtx is WriteListingJSON that stops before the next directory once the context is canceled
+func (p *Pairtree) WriteListingJSONCtx(ctx context.Context, w io.Writer, path string, opts ListOptions) error {
+	if !IsS3(path) {
+		path = filepath.FromSlash(path)
+	}
+	storage, err := listingStorage(ctx, p.storage, path, opts)
+	if err != nil {
+		return err
+	}
+	return writeDirectoryJSON(ctx, w, storage, path, path, "", nil, true, opts)
+}
+
+// writeDirectoryJSON writes the directory with its indentation, reading its entries when read is true.
+// The info of a directory that is an entry of a long listing is written after its name.
+func writeDirectoryJSON(ctx context.Context, w io.Writer, storage Storage, path, name, indent string, info fs.FileInfo,
+	read bool, opts ListOptions) error {
+	var dirs, files []fs.DirEntry
+	if read {
+		entries, err := readListing(ctx, storage, path, opts)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() {
+				dirs = append(dirs, entry)
+			} else {
+				files = append(files, entry)
+			}
+		}
+	}
+
+	nameJSON, err := json.Marshal(name)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "{\n%s  \"name\": %s", indent, nameJSON)
+	if err := writeInfoJSON(w, indent+"  ", info); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, ",\n%s  \"directories\": ", indent)
+
+	subOpts, descend := opts.descend()
+	if len(dirs) == 0 {
+		fmt.Fprint(w, "null")
+	} else {
+		fmt.Fprint(w, "[\n")
+		for i, dir := range dirs {
+			dirInfo, err := entryInfo(dir, opts)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprint(w, indent+"    ")
+			if err := writeDirectoryJSON(ctx, w, storage, JoinPath(path, dir.Name()), dir.Name(), indent+"    ",
+				dirInfo, descend, subOpts); err != nil {
+				return err
+			}
+			fmt.Fprint(w, separator(i, len(dirs)))
+		}
+		fmt.Fprint(w, indent+"  ]")
+	}
+
+	fmt.Fprintf(w, ",\n%s  \"files\": ", indent)
+
+	if len(files) == 0 {
+		fmt.Fprint(w, "null")
+	} else {
+		fmt.Fprint(w, "[\n")
+		for i, file := range files {
+			fileJSON, err := json.Marshal(file.Name())
+			if err != nil {
+				return err
+			}
+			fileInfo, err := entryInfo(file, opts)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(w, "%s    {\n%s      \"name\": %s", indent, indent, fileJSON)
+			if err := writeInfoJSON(w, indent+"      ", fileInfo); err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "\n%s    }%s", indent, separator(i, len(files)))
+		}
+		fmt.Fprint(w, indent+"  ]")
+	}
+
+	_, err = fmt.Fprintf(w, "\n%s}", indent)
+	return err
+}
+
+// entryInfo returns the info of the entry of a long listing, or nil when the listing is not long
+func entryInfo(entry fs.DirEntry, opts ListOptions) (fs.FileInfo, error) {
+	if !opts.Long {
+		return nil, nil
+	}
+
+	return entry.Info()
+}
+
+// writeInfoJSON writes the fields of the EntryInfo of the info at the indentation, each after a comma
+// like json.MarshalIndent writes them. Nothing is written for nil info.
+func writeInfoJSON(w io.Writer, indent string, info fs.FileInfo) error {
+	if info == nil {
+		return nil
+	}
+
+	entry := NewEntryInfo(info)
+	modTime, err := json.Marshal(entry.ModTime)
+	if err != nil {
+		return err
+	}
+
+	mode, err := json.Marshal(entry.Mode)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, ",\n%s\"size\": %d,\n%s\"modTime\": %s,\n%s\"mode\": %s", indent, entry.Size, indent,
+		modTime, indent, mode)
+	return err
+}
+
+// Entry is a file or directory of an object that Walk found
+type Entry struct {
+	fs.DirEntry
+	// Path is the path of the entry in the object, separated with slashes
+	Path string
+}
+
+// Walk calls fn with each entry of the object with the ID that the options list. Entries are passed on
+// in the order the storage returns them as directories are read a batch at a time, so neither a whole
+// directory nor the whole object is held in memory, and a directory is walked right after its entry
+// when the listing is recursive.
+func (p *Pairtree) Walk(id string, opts ListOptions, fn func(Entry) error) error {
+	return p.WalkCtx(context.Background(), id, opts, fn)
+}
+
+// WalkCtx is Walk that stops before the next entry once the context is canceled
+func (p *Pairtree) WalkCtx(ctx context.Context, id string, opts ListOptions, fn func(Entry) error) error {
+	pairPath, err := p.PairPath(id)
+	if err != nil {
+		return err
+	}
+
+	return walkEntries(ctx, p.storage, pairPath, "", opts, fn)
+}
+
+// walkEntries walks the entries of the directory, which is at rel in the object, for Walk
+func walkEntries(ctx context.Context, storage Storage, dir, rel string, opts ListOptions, fn func(Entry) error) error {
+	return scanDir(storage, dir, func(entries []fs.DirEntry) error {
+		for _, entry := range entries {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			if !opts.ShowAll && IsHidden(entry.Name()) {
+				continue
+			}
+
+			path := entry.Name()
+			if rel != "" {
+				path = rel + "/" + path
+			}
+
+			if !opts.DirsOnly || entry.IsDir() {
+				if err := fn(Entry{DirEntry: entry, Path: path}); err != nil {
+					return err
+				}
+			}
+
+			if subOpts, ok := opts.descend(); ok && entry.IsDir() {
+				if err := walkEntries(ctx, storage, JoinPath(dir, entry.Name()), path, subOpts, fn); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// separator returns what follows the element at index i of a JSON array of n elements
+func separator(i, n int) string {
+	if i < n-1 {
+		return ",\n"
+	}
+	return "\n"
+}
@@ -0,0 +1,450 @@
+package pairtree
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"runtime/metrics"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/UCLALibrary/pt-tools/pkg/pttest"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// memoryBudget is how much more the heap may grow by while a whole-object operation runs on a large
+	// object than on an object of one directory, whatever the number of files in the large object
+	memoryBudget = 4 << 20
+	// filesPerDir is the number of files in each directory of a synthetic object
+	filesPerDir = 1000
+)
+
+// TestWalkListing tests that the directories are listed depth first with the entries the options list
+func TestWalkListing(t *testing.T) {
+	dir := t.TempDir()
+	for _, path := range []string{"b/inner.txt", "b/.hidden/secret.txt", "a.txt", ".hidden.txt", "c/"} {
+		createPath(t, dir, path)
+	}
+
+	tests := []struct {
+		name     string
+		opts     ListOptions
+		expected map[string][]string
+		order    []string
+	}{
+		{
+			name:     "Not recursive",
+			opts:     ListOptions{},
+			expected: map[string][]string{".": {"a.txt", "b", "c"}},
+			order:    []string{"."},
+		},
+		{
+			name:     "Recursive without the hidden or empty directories",
+			opts:     ListOptions{Recursive: true},
+			expected: map[string][]string{".": {"a.txt", "b", "c"}, "b": {"inner.txt"}},
+			order:    []string{".", "b"},
+		},
+		{
+			name: "Recursive with all",
+			opts: ListOptions{Recursive: true, ShowAll: true},
+			expected: map[string][]string{".": {".hidden.txt", "a.txt", "b", "c"}, "b": {".hidden", "inner.txt"},
+				"b/.hidden": {"secret.txt"}, "c": {}},
+			order: []string{".", "b", "b/.hidden", "c"},
+		},
+		{
+			name: "Recursive to a depth",
+			opts: ListOptions{Recursive: true, ShowAll: true, Depth: 2},
+			expected: map[string][]string{".": {".hidden.txt", "a.txt", "b", "c"}, "b": {".hidden", "inner.txt"},
+				"c": {}},
+			order: []string{".", "b", "c"},
+		},
+		{
+			name:     "Directories only",
+			opts:     ListOptions{Recursive: true, DirsOnly: true},
+			expected: map[string][]string{".": {"b", "c"}},
+			order:    []string{"."},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			listed := map[string][]string{}
+			order := []string{}
+			err := WalkListing(dir, test.opts, func(path string, entries []fs.DirEntry) error {
+				rel, err := filepath.Rel(dir, path)
+				require.NoError(t, err)
+
+				names := []string{}
+				for _, entry := range entries {
+					names = append(names, entry.Name())
+				}
+				listed[filepath.ToSlash(rel)] = names
+				order = append(order, filepath.ToSlash(rel))
+				return nil
+			})
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expected, listed)
+			assert.Equal(t, test.order, order)
+		})
+	}
+}
+
+// TestSortListing tests that the entries of a directory are listed in the order of the options, with
+// equal entries in name order
+func TestSortListing(t *testing.T) {
+	dir := t.TempDir()
+	modTime := time.Date(2024, time.March, 5, 14, 30, 0, 0, time.UTC)
+	for _, file := range []struct {
+		name    string
+		content string
+		age     time.Duration
+	}{
+		{name: "a.txt", content: "aa", age: time.Hour},
+		{name: "b.txt", content: "bbbb", age: 2 * time.Hour},
+		{name: "c.txt", content: "cc", age: 0},
+	} {
+		path := filepath.Join(dir, file.name)
+		require.NoError(t, os.WriteFile(path, []byte(file.content), 0644))
+		require.NoError(t, os.Chtimes(path, modTime.Add(-file.age), modTime.Add(-file.age)))
+	}
+
+	tests := []struct {
+		opts     ListOptions
+		expected []string
+	}{
+		{opts: ListOptions{}, expected: []string{"a.txt", "b.txt", "c.txt"}},
+		{opts: ListOptions{Sort: SortByName, Reverse: true}, expected: []string{"c.txt", "b.txt", "a.txt"}},
+		{opts: ListOptions{Sort: SortBySize}, expected: []string{"b.txt", "a.txt", "c.txt"}},
+		{opts: ListOptions{Sort: SortBySize, Reverse: true}, expected: []string{"c.txt", "a.txt", "b.txt"}},
+		{opts: ListOptions{Sort: SortByModTime}, expected: []string{"c.txt", "a.txt", "b.txt"}},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%+v", test.opts), func(t *testing.T) {
+			var names []string
+			require.NoError(t, WalkListing(dir, test.opts, func(_ string, entries []fs.DirEntry) error {
+				for _, entry := range entries {
+					names = append(names, entry.Name())
+				}
+				return nil
+			}))
+			assert.Equal(t, test.expected, names)
+		})
+	}
+}
+
+// createPath creates the file at the path in the directory, or the directory when the path ends in /
+func createPath(t *testing.T, dir, path string) {
+	if strings.HasSuffix(path, "/") {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, path), 0755))
+		return
+	}
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, path)), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, path), []byte("x"), 0644))
+}
+
+// TestWriteListingJSON tests that the JSON written a directory at a time is the JSON of the Directory
+func TestWriteListingJSON(t *testing.T) {
+	dir := t.TempDir()
+	for _, path := range []string{"folder/inner.txt", "folder/deeper/<&>.txt", "outer.txt", "empty/", ".hidden/x.txt"} {
+		createPath(t, dir, path)
+	}
+
+	for _, opts := range []ListOptions{{}, {Recursive: true}, {Recursive: true, ShowAll: true}, {DirsOnly: true},
+		{Recursive: true, Long: true}} {
+		t.Run(fmt.Sprintf("%+v", opts), func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			require.NoError(t, WriteListingJSON(&buf, dir, opts))
+
+			expected, err := ToJSONStructure(buildTree(t, dir, opts))
+			require.NoError(t, err)
+			assert.Equal(t, string(expected), buf.String())
+		})
+	}
+}
+
+// buildTree builds the Directory of the listing the way pt ls did before it was streamed
+func buildTree(t *testing.T, dir string, opts ListOptions) Directory {
+	entries := map[string][]fs.DirEntry{}
+	require.NoError(t, WalkListing(dir, ListOptions{Recursive: opts.Recursive, ShowAll: opts.ShowAll, DirsOnly: opts.DirsOnly},
+		func(path string, listed []fs.DirEntry) error {
+			entries[path] = listed
+			return nil
+		}))
+
+	// A long listing has the info of its entries, which the tree from before did not
+	if opts.Long {
+		tree, err := listingTree(dir, dir, entries, true)
+		require.NoError(t, err)
+		require.NotNil(t, tree.Files[0].EntryInfo)
+		return tree
+	}
+
+	return BuildDirectoryTree(dir, entries, true)
+}
+
+// TestMemoryFlat tests that listing, archiving, copying, and deleting an object use no more memory
+// for an object of many files than for an object of one directory, as they read a directory at a time.
+// Creating the object is slow, so the test only runs when PT_TEST_OBJECT_FILES sets its number of
+// files, like 50000 with make memory or 1000000.
+func TestMemoryFlat(t *testing.T) {
+	env := os.Getenv("PT_TEST_OBJECT_FILES")
+	if env == "" {
+		t.Skip("set PT_TEST_OBJECT_FILES to the number of files of the object to run it")
+	}
+
+	files, err := strconv.Atoi(env)
+	require.NoError(t, err)
+
+	small := syntheticObject(t, filesPerDir)
+	large := syntheticObject(t, files)
+
+	// Collecting garbage often makes the heap follow what is live rather than what has been allocated
+	defer debug.SetGCPercent(debug.SetGCPercent(1))
+
+	tests := []struct {
+		name string
+		run  func(objPath string) error
+	}{
+		{name: "Recursive listing", run: func(objPath string) error {
+			return WalkListing(objPath, ListOptions{Recursive: true}, func(string, []fs.DirEntry) error { return nil })
+		}},
+		{name: "JSON listing", run: func(objPath string) error {
+			return WriteListingJSON(io.Discard, objPath, ListOptions{Recursive: true})
+		}},
+		{name: "Archive", run: func(objPath string) error {
+			return TarGz(context.Background(), objPath, t.TempDir(), prefix, false, ArchiveOptions{})
+		}},
+		{name: "Copy", run: func(objPath string) error {
+			_, err := CopyFileOrFolder(context.Background(), objPath, t.TempDir(), false, CopyOptions{})
+			return err
+		}},
+		{name: "Delete", run: func(objPath string) error {
+			return DeletePairtreeItem(objPath)
+		}},
+	}
+
+	// The tests are not parallel so each has the heap to itself, and the objects are deleted last
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var smallErr, largeErr error
+			smallGrowth := heapGrowth(func() { smallErr = test.run(small) })
+			largeGrowth := heapGrowth(func() { largeErr = test.run(large) })
+
+			require.NoError(t, smallErr)
+			require.NoError(t, largeErr)
+			assert.Less(t, int64(largeGrowth)-int64(smallGrowth), int64(memoryBudget),
+				"%s grew the heap by %d bytes for %d files and %d bytes for %d files", test.name, largeGrowth,
+				files, smallGrowth, filesPerDir)
+		})
+	}
+}
+
+// syntheticObject creates an object with the number of empty files, filesPerDir in each directory.
+// The files are links to one file so that a large object is quick to create.
+func syntheticObject(t *testing.T, files int) string {
+	dir := t.TempDir()
+	objPath := filepath.Join(dir, "synthetic")
+
+	seed := filepath.Join(dir, "seed.txt")
+	require.NoError(t, os.WriteFile(seed, nil, 0644))
+
+	for i := 0; i < files; i++ {
+		subdir := filepath.Join(objPath, fmt.Sprintf("dir%04d", i/filesPerDir))
+		if i%filesPerDir == 0 {
+			require.NoError(t, os.MkdirAll(subdir, 0755))
+		}
+		require.NoError(t, os.Link(seed, filepath.Join(subdir, fmt.Sprintf("file%04d.txt", i%filesPerDir))))
+	}
+
+	return objPath
+}
+
+// heapGrowth runs fn and returns the most the heap grew by while it ran, sampled every millisecond
+func heapGrowth(fn func()) uint64 {
+	const heapObjects = "/memory/classes/heap/objects:bytes"
+
+	runtime.GC()
+	sample := []metrics.Sample{{Name: heapObjects}}
+	metrics.Read(sample)
+	base := sample[0].Value.Uint64()
+
+	done := make(chan struct{})
+	peak := make(chan uint64)
+
+	go func() {
+		sample := []metrics.Sample{{Name: heapObjects}}
+		ticker := time.NewTicker(time.Millisecond)
+		defer ticker.Stop()
+
+		most := base
+		for {
+			metrics.Read(sample)
+			most = max(most, sample[0].Value.Uint64())
+
+			select {
+			case <-done:
+				peak <- most
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	fn()
+	close(done)
+
+	return <-peak - base
+}
+
+// TestJSONListingValid tests that the streamed JSON of a synthetic object can be decoded
+func TestJSONListingValid(t *testing.T) {
+	objPath := syntheticObject(t, 2*filesPerDir+1)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteListingJSON(&buf, objPath, ListOptions{Recursive: true}))
+
+	var dir Directory
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &dir))
+	require.Len(t, dir.Directories, 3)
+	assert.Len(t, dir.Directories[0].Files, filesPerDir)
+	assert.Len(t, dir.Directories[2].Files, 1)
+}
+
+// TestWalk tests that every entry the options list is passed on with its path in the object
+func TestWalk(t *testing.T) {
+	fsys := afero.NewMemMapFs()
+	root := pttest.StandardPairtree().Build(t, fsys, "/pt")
+	pt, err := OpenFs(fsys, root)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		opts     ListOptions
+		expected []string
+	}{
+		{name: "Not recursive", opts: ListOptions{}, expected: []string{"folder", "outerb5488.txt"}},
+		{name: "Recursive", opts: ListOptions{Recursive: true},
+			expected: []string{"folder", "folder/innerb5488.txt", "outerb5488.txt"}},
+		{name: "Directories only", opts: ListOptions{Recursive: true, DirsOnly: true}, expected: []string{"folder"}},
+		{name: "Recursive to a depth", opts: ListOptions{Recursive: true, ShowAll: true, Depth: 2},
+			expected: []string{"folder", "folder/.hidden", "folder/.hiddenFile.txt", "folder/innerb5488.txt", "outerb5488.txt"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			paths := []string{}
+			err := pt.Walk("ark:/b5488", test.opts, func(entry Entry) error {
+				paths = append(paths, entry.Path)
+				return nil
+			})
+			require.NoError(t, err)
+
+			sort.Strings(paths)
+			assert.Equal(t, test.expected, paths)
+		})
+	}
+
+	t.Run("Show all", func(t *testing.T) {
+		hidden := 0
+		err := pt.Walk("ark:/b5488", ListOptions{Recursive: true, ShowAll: true}, func(entry Entry) error {
+			if IsHidden(entry.Name()) {
+				hidden++
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Positive(t, hidden)
+	})
+
+	t.Run("Missing object", func(t *testing.T) {
+		err := pt.Walk("ark:/missing", ListOptions{}, func(Entry) error { return nil })
+		assert.ErrorIs(t, err, fs.ErrNotExist)
+	})
+}
+
+// TestWalkBatches tests that a directory of more entries than a batch is walked in full
+func TestWalkBatches(t *testing.T) {
+	fsys := afero.NewMemMapFs()
+	root := pttest.StandardPairtree().Build(t, fsys, "/pt")
+	pt, err := OpenFs(fsys, root)
+	require.NoError(t, err)
+
+	pairPath, err := pt.PairPath("ark:/a5388")
+	require.NoError(t, err)
+	for i := 0; i < scanBatch*2+1; i++ {
+		require.NoError(t, afero.WriteFile(fsys, filepath.Join(pairPath, "f"+strconv.Itoa(i)), []byte("x"), 0644))
+	}
+
+	seen := map[string]bool{}
+	err = pt.Walk("ark:/a5388", ListOptions{}, func(entry Entry) error {
+		assert.False(t, seen[entry.Path], "%s was passed on twice", entry.Path)
+		seen[entry.Path] = true
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Len(t, seen, scanBatch*2+2)
+}
+
+// TestParallelListing tests that a listing read with more than one job is the same as one read serially
+func TestParallelListing(t *testing.T) {
+	dir := t.TempDir()
+	for i := range 20 {
+		for _, path := range []string{"a.txt", ".hidden/secret.txt", "b/c/inner.txt", "d/"} {
+			createPath(t, dir, filepath.Join("dir"+strconv.Itoa(i), path))
+		}
+	}
+
+	for _, opts := range []ListOptions{{Recursive: true}, {Recursive: true, ShowAll: true}, {Recursive: true, DirsOnly: true},
+		{Recursive: true, Sort: SortBySize, Reverse: true}, {Recursive: true, ShowAll: true, Depth: 2}} {
+		t.Run(fmt.Sprintf("%+v", opts), func(t *testing.T) {
+			serial, parallel := opts, opts
+			parallel.Jobs = 8
+
+			var serialDirs, parallelDirs []string
+			require.NoError(t, WalkListing(dir, serial, func(path string, _ []fs.DirEntry) error {
+				serialDirs = append(serialDirs, path)
+				return nil
+			}))
+			require.NoError(t, WalkListing(dir, parallel, func(path string, _ []fs.DirEntry) error {
+				parallelDirs = append(parallelDirs, path)
+				return nil
+			}))
+			assert.Equal(t, serialDirs, parallelDirs)
+
+			var serialJSON, parallelJSON bytes.Buffer
+			require.NoError(t, WriteListingJSON(&serialJSON, dir, serial))
+			require.NoError(t, WriteListingJSON(&parallelJSON, dir, parallel))
+			assert.Equal(t, serialJSON.String(), parallelJSON.String())
+		})
+	}
+
+	t.Run("Canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := WalkListingCtx(ctx, dir, ListOptions{Recursive: true, Jobs: 8}, func(string, []fs.DirEntry) error {
+			return nil
+		})
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
@@ -5,6 +5,7 @@ pairtree-service project
 package pairtree
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -13,10 +14,12 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
 
 	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
 	caltech_pairtree "github.com/caltechlibrary/pairtree"
-	"github.com/mholt/archiver/v3"
 	"github.com/otiai10/copy"
 	"github.com/spf13/afero"
 )
@@ -24,24 +27,385 @@ import (
 // File is the directory tree in JSON
 type File struct {
 	Name string `json:"name"`
+	*EntryInfo
 }
 
 // Directory is a directory file structure that can be nested
 type Directory struct {
-	Name        string      `json:"name"`
+	Name string `json:"name"`
+	*EntryInfo
 	Directories []Directory `json:"directories"`
 	Files       []File      `json:"files"`
 }
 
+// EntryInfo is the size, modification time, and mode of a file or directory in a long listing. It is
+// left out of the JSON of a listing that is not long.
+type EntryInfo struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	Mode    string    `json:"mode"`
+}
+
+// NewEntryInfo returns the EntryInfo of the file or directory
+func NewEntryInfo(info fs.FileInfo) *EntryInfo {
+	return &EntryInfo{Size: info.Size(), ModTime: info.ModTime(), Mode: info.Mode().String()}
+}
+
 const (
 	rootDir   = "pairtree_root"
 	prefixDir = "pairtree_prefix"
 	verDir    = "pairtree_version0_1"
 	PtPrefix  = "pt://"
-	tar       = ".tgz"
+	tgzExt    = ".tgz"
+	tzstExt   = ".tzst"
+	zipExt    = ".zip"
 	ptVerSpec = "This directory conforms to Pairtree Version 0.1. Updated spec: http://www.cdlib.org/inside/diglib/pairtree/pairtreespec.html "
 )
 
+// Pairtree is the pairtree at a root of a file system. The functions of the package that are given a
+// path work with the local file system, or with S3 for an s3:// URL, and each has a method of a Pairtree
+// of the same name that works with any afero.Fs, like an afero.MemMapFs in tests.
+type Pairtree struct {
+	root    string
+	fs      afero.Fs
+	storage Storage
+	// prefix and version are read once by Open and OpenFs
+	prefix  string
+	version string
+}
+
+// New returns the pairtree at the root of the file system
+func New(fsys afero.Fs, root string) *Pairtree {
+	return &Pairtree{root: root, fs: fsys, storage: NewFsStorage(fsys)}
+}
+
+// withPrefix sets the prefix of a pairtree that was not opened, for the functions of the package that
+// are given the prefix rather than reading it, and returns the pairtree
+func (p *Pairtree) withPrefix(prefix string) *Pairtree {
+	p.prefix = prefix
+	return p
+}
+
+// open returns the pairtree at the root on the local file system, or in S3 for an s3:// URL, which is
+// requested with the context
+func open(ctx context.Context, ptRoot string) (*Pairtree, error) {
+	if !IsS3(ptRoot) {
+		return New(afero.NewOsFs(), ptRoot), nil
+	}
+
+	storage, err := StorageFor(ctx, ptRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Pairtree{root: ptRoot, storage: storage}, nil
+}
+
+// Open opens the pairtree at the root on the local file system, or in S3 for an s3:// URL. The version
+// and prefix of the pairtree are checked and read once, so the methods that take an ID use its prefix.
+func Open(ptRoot string) (*Pairtree, error) {
+	pt, err := open(context.Background(), ptRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	return pt, pt.load()
+}
+
+// OpenFs opens the pairtree at the root of the file system like Open
+func OpenFs(fsys afero.Fs, root string) (*Pairtree, error) {
+	pt := New(fsys, root)
+	return pt, pt.load()
+}
+
+// load checks the version file of the pairtree and reads its prefix, which is PtPrefix when the
+// pairtree has no prefix file
+func (p *Pairtree) load() error {
+	if err := p.CheckPTVer(); err != nil {
+		return err
+	}
+
+	version, err := p.storage.Open(JoinPath(p.root, verDir))
+	if err != nil {
+		return err
+	}
+	defer version.Close()
+
+	content, err := io.ReadAll(version)
+	if err != nil {
+		return err
+	}
+	p.version = string(content)
+
+	if p.prefix, err = p.GetPrefix(); err != nil {
+		return err
+	}
+
+	if p.prefix == "" {
+		p.prefix = PtPrefix
+	}
+
+	return nil
+}
+
+// Root returns the root directory of the pairtree
+func (p *Pairtree) Root() string {
+	return p.root
+}
+
+// Prefix returns the prefix of the IDs of the pairtree that Open read
+func (p *Pairtree) Prefix() string {
+	return p.prefix
+}
+
+// Version returns the content of the version file of the pairtree that Open read
+func (p *Pairtree) Version() string {
+	return p.version
+}
+
+// PairPath returns the path of the object with the ID, which starts with the prefix of the pairtree
+func (p *Pairtree) PairPath(id string) (string, error) {
+	return CreatePP(id, p.root, p.prefix)
+}
+
+// ID returns the ID of the object at the pairpath, or that a path inside the object is in
+func (p *Pairtree) ID(pairPath string) (string, error) {
+	return PPathToID(pairPath, p.root, p.prefix)
+}
+
+// NameID returns the ID of the object a directory with the name is for, which is the encoded ID, like
+// the name of the object's directory in the pairtree, with or without the prefix of the pairtree
+func (p *Pairtree) NameID(name string) (string, error) {
+	id, err := decodeName(name)
+	if err != nil {
+		return "", err
+	}
+
+	if !strings.HasPrefix(id, p.prefix) {
+		id = p.prefix + id
+	}
+	return id, nil
+}
+
+// Exists checks if the object with the ID is in the pairtree. A file where the object's directory
+// would be is not an object, and only errors other than the object not existing are returned.
+func (p *Pairtree) Exists(id string) (bool, error) {
+	pairPath, err := p.PairPath(id)
+	if err != nil {
+		return false, err
+	}
+
+	info, err := p.storage.Stat(pairPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return info.IsDir(), nil
+}
+
+// Ls returns the listing of the object with the ID, or of the directory at the subpath of the object
+// when it is not empty. The top directory of the listing is named with its whole path. A subpath that
+// is not inside the object is refused like JoinSubpath refuses it.
+func (p *Pairtree) Ls(id, subpath string, opts ListOptions) (Directory, error) {
+	pairPath, err := p.PairPath(id)
+	if err != nil {
+		return Directory{}, err
+	}
+
+	path, err := JoinSubpath(pairPath, subpath)
+	if err != nil {
+		return Directory{}, err
+	}
+	listing := make(map[string][]fs.DirEntry)
+
+	err = walkListing(context.Background(), p.storage, path, opts, func(dir string, entries []fs.DirEntry) error {
+		listing[dir] = entries
+		return nil
+	})
+	if err != nil {
+		return Directory{}, err
+	}
+
+	return listingTree(path, path, listing, opts.Long)
+}
+
+// listingTree builds the Directory of the path from the entries of the directories of a listing, with
+// the EntryInfo of each entry when the listing is long
+func listingTree(path, name string, listing map[string][]fs.DirEntry, long bool) (Directory, error) {
+	dir := Directory{Name: name}
+
+	for _, entry := range listing[path] {
+		var info *EntryInfo
+		if long {
+			fileInfo, err := entry.Info()
+			if err != nil {
+				return Directory{}, err
+			}
+			info = NewEntryInfo(fileInfo)
+		}
+
+		if entry.IsDir() {
+			subDir, err := listingTree(JoinPath(path, entry.Name()), entry.Name(), listing, long)
+			if err != nil {
+				return Directory{}, err
+			}
+			subDir.EntryInfo = info
+			dir.Directories = append(dir.Directories, subDir)
+		} else {
+			dir.Files = append(dir.Files, File{Name: entry.Name(), EntryInfo: info})
+		}
+	}
+
+	return dir, nil
+}
+
+// Copy copies between an object of the pairtree and a path outside of it, like pt cp. Whichever of src
+// and dest starts with the prefix of the pairtree is the ID of the object, which is created when it is
+// the destination. It returns the path that was copied to.
+func (p *Pairtree) Copy(ctx context.Context, src, dest string, overwrite bool, opts CopyOptions) (string, error) {
+	var err error
+
+	if strings.HasPrefix(src, p.prefix) {
+		if src, err = p.PairPath(src); err != nil {
+			return "", err
+		}
+	} else if strings.HasPrefix(dest, p.prefix) {
+		if dest, err = p.PairPath(dest); err != nil {
+			return "", err
+		}
+		if err = createDirNotExist(p.storage, dest); err != nil {
+			return "", err
+		}
+	} else {
+		return "", error_msgs.Err10
+	}
+
+	return p.CopyFileOrFolder(ctx, src, dest, overwrite, opts)
+}
+
+// Delete deletes the object with the ID, or the file or directory at the subpath of the object when it
+// is not empty. A subpath that is not inside the object is refused like JoinSubpath refuses it.
+func (p *Pairtree) Delete(id, subpath string) error {
+	pairPath, err := p.PairPath(id)
+	if err != nil {
+		return err
+	}
+
+	path, err := JoinSubpath(pairPath, subpath)
+	if err != nil {
+		return err
+	}
+
+	return p.DeletePairtreeItem(path)
+}
+
+// Rename moves the object with the old ID to the pairpath of the new ID in the same pairtree like Move
+func (p *Pairtree) Rename(ctx context.Context, oldID, newID string, opts CopyOptions) error {
+	return p.Move(ctx, oldID, p, newID, opts)
+}
+
+// Move moves the object with the ID to the pairpath of the new ID in the destination pairtree, which is on
+// the same file system, replacing an object that is already there and making the directories of the
+// pairpath that are missing. The shorties of the old pairpath that are left empty are removed. Like pt mv, the object's directory is renamed into a hidden staging directory
+// beside the new pairpath, or copied and verified there when the pairpaths are on different devices, and
+// only then replaces the object at the new pairpath. A copied object is deleted last, so a move that fails
+// or is interrupted before then leaves both pairpaths as they were.
+func (p *Pairtree) Move(ctx context.Context, id string, dest *Pairtree, newID string, opts CopyOptions) (err error) {
+	// Objects in S3 have no directories to rename
+	if p.fs == nil || dest.fs == nil {
+		return fmt.Errorf("%w: move", error_msgs.Err41)
+	}
+
+	oldPath, err := p.PairPath(id)
+	if err != nil {
+		return err
+	}
+
+	newPath, err := dest.PairPath(newID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := p.storage.Stat(oldPath); err != nil {
+		return err
+	}
+
+	if err := dest.storage.MkdirAll(filepath.Dir(newPath)); err != nil {
+		return err
+	}
+
+	staging, err := afero.TempDir(dest.fs, filepath.Dir(newPath), "."+filepath.Base(newPath)+".pt-mv-")
+	if err != nil {
+		return err
+	}
+
+	// The staging directory is kept if the renamed object can not be put back, so it is not lost
+	keep := false
+	defer func() {
+		if !keep {
+			err = errors.Join(err, dest.storage.RemoveAll(staging))
+		}
+	}()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	staged := filepath.Join(staging, filepath.Base(newPath))
+	renamed := false
+	if err := p.fs.Rename(oldPath, staged); err == nil {
+		renamed = true
+	} else if !errors.Is(err, syscall.EXDEV) {
+		return err
+	} else if _, err := p.CopyFileOrFolder(ctx, oldPath, staged, true, opts); err != nil {
+		return err
+	} else if err := verifyStorageSize(p.storage, oldPath, staged); err != nil {
+		return err
+	}
+
+	if err := replace(dest.fs, staged, newPath, staged+".old"); err != nil {
+		if renamed {
+			if restoreErr := p.fs.Rename(staged, oldPath); restoreErr != nil {
+				keep = true
+				err = errors.Join(err, fmt.Errorf("the object was left at %s: %w", staged, restoreErr))
+			}
+		}
+		return err
+	}
+
+	if !renamed {
+		if err := p.storage.RemoveAll(oldPath); err != nil {
+			return err
+		}
+	}
+
+	return p.PruneShorties(oldPath)
+}
+
+// Replace swaps the staged file or directory in for the destination with renames on the local file system.
+// The destination is renamed to old first, and is renamed back when the staged one can not take its place.
+func Replace(staged, dest, old string) error {
+	return replace(afero.NewOsFs(), staged, dest, old)
+}
+
+// replace is Replace in the file system
+func replace(fsys afero.Fs, staged, dest, old string) error {
+	if err := fsys.Rename(dest, old); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	if err := fsys.Rename(staged, dest); err != nil {
+		if restoreErr := fsys.Rename(old, dest); restoreErr != nil && !errors.Is(restoreErr, fs.ErrNotExist) {
+			err = errors.Join(err, restoreErr)
+		}
+		return err
+	}
+
+	return nil
+}
+
 // IsHidden determines if a file is hidden based on its name.
 func IsHidden(name string) bool {
 	return strings.HasPrefix(name, ".")
@@ -54,12 +418,22 @@ func IsDirectory(obj fs.DirEntry) bool {
 
 // GetPrefix reads the content of the file at the pairtree prefix path and returns it as a string
 func GetPrefix(ptRoot string) (string, error) {
-	path := filepath.Join(ptRoot, prefixDir)
+	pt, err := open(context.Background(), ptRoot)
+	if err != nil {
+		return "", err
+	}
+
+	return pt.GetPrefix()
+}
+
+// GetPrefix reads the content of the pairtree prefix file of the pairtree
+func (p *Pairtree) GetPrefix() (string, error) {
+	path := JoinPath(p.root, prefixDir)
 
 	// Open the file
-	file, err := os.Open(path)
+	file, err := p.storage.Open(path)
 	if err != nil {
-		if os.IsNotExist(err) {
+		if errors.Is(err, fs.ErrNotExist) {
 			// File does not exist, return empty string and no error
 			return "", nil
 		}
@@ -84,16 +458,18 @@ func GetPrefix(ptRoot string) (string, error) {
 
 // CheckPTVer checks if the pairtree_version0_1 is populated
 func CheckPTVer(ptRoot string) error {
-	path := filepath.Join(ptRoot, verDir)
-	// Open the file
-	file, err := os.Open(path)
+	pt, err := open(context.Background(), ptRoot)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
 
+	return pt.CheckPTVer()
+}
+
+// CheckPTVer checks if the pairtree_version0_1 of the pairtree is populated
+func (p *Pairtree) CheckPTVer() error {
 	// Get file info
-	fileInfo, err := file.Stat()
+	fileInfo, err := p.storage.Stat(JoinPath(p.root, verDir))
 	if err != nil {
 		return err
 	}
@@ -111,9 +487,29 @@ func CreateDirNotExist(path string) error {
 	if strings.TrimSpace(path) == "" {
 		return error_msgs.Err15
 	}
+
+	storage, err := StorageFor(context.Background(), path)
+	if err != nil {
+		return err
+	}
+
+	return createDirNotExist(storage, path)
+}
+
+// CreateDirNotExist creates a directory of the file system of the pairtree if the path does not exist
+func (p *Pairtree) CreateDirNotExist(path string) error {
+	if strings.TrimSpace(path) == "" {
+		return error_msgs.Err15
+	}
+
+	return createDirNotExist(p.storage, path)
+}
+
+// createDirNotExist creates the directory in the storage if the path does not exist
+func createDirNotExist(storage Storage, path string) error {
 	// If the destination is a directory, ensure it has the correct path
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		if err := os.MkdirAll(path, 0755); err != nil {
+	if _, err := storage.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		if err := storage.MkdirAll(path); err != nil {
 			return err
 		}
 	}
@@ -126,45 +522,62 @@ func CreatePairtree(ptRoot, prefix string) error {
 		return error_msgs.Err15
 	}
 
-	// create the pairtree root directory if it does not exist
-	if err := CreateDirNotExist(ptRoot); err != nil {
-		return fmt.Errorf("there was an error creating the ptroot: %w", err)
+	pt, err := open(context.Background(), ptRoot)
+	if err != nil {
+		return err
 	}
 
-	ptPreFilePath := filepath.Join(ptRoot, prefixDir)
-	ptVerFilePath := filepath.Join(ptRoot, verDir)
-	ptRootDirPath := filepath.Join(ptRoot, rootDir)
+	return pt.CreatePairtree(prefix)
+}
 
-	// create the prefixFile
-	ptPreFile, err := os.Create(ptPreFilePath)
-	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
+// CreatePairtree creates the pairtree structure of the pairtree with the prefix
+func (p *Pairtree) CreatePairtree(prefix string) error {
+	if strings.TrimSpace(p.root) == "" {
+		return error_msgs.Err15
 	}
-	defer ptPreFile.Close()
 
-	if _, err := ptPreFile.WriteString(prefix); err != nil {
-		return fmt.Errorf("failed to write to pairtree_version file: %w", err)
+	// create the pairtree root directory if it does not exist
+	if err := createDirNotExist(p.storage, p.root); err != nil {
+		return fmt.Errorf("there was an error creating the ptroot: %w", err)
 	}
 
-	// create the version file
-	ptVerFile, err := os.Create(ptVerFilePath)
-	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
+	ptPreFilePath := JoinPath(p.root, prefixDir)
+	ptVerFilePath := JoinPath(p.root, verDir)
+	ptRootDirPath := JoinPath(p.root, rootDir)
+
+	// create the prefixFile
+	if err := writeFile(p.storage, ptPreFilePath, prefix); err != nil {
+		return fmt.Errorf("failed to write to pairtree_prefix file: %w", err)
 	}
-	defer ptVerFile.Close()
 
-	if _, err := ptVerFile.WriteString(ptVerSpec); err != nil {
+	// create the version file
+	if err := writeFile(p.storage, ptVerFilePath, ptVerSpec); err != nil {
 		return fmt.Errorf("failed to write to pairtree_version file: %w", err)
 	}
 
 	// create the pairtree_root dir
-	if err := CreateDirNotExist(ptRootDirPath); err != nil {
+	if err := createDirNotExist(p.storage, ptRootDirPath); err != nil {
 		return fmt.Errorf("there was an error creating the pt_root directory: %w", err)
 	}
 
 	return nil
 }
 
+// writeFile creates the file at the path in the storage with the content
+func writeFile(storage Storage, path, content string) error {
+	file, err := storage.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(file, content); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
 // CreatePP creates the full pairpath given the root, id, and prefix giving the pairpath to an object
 func CreatePP(id, ptRoot, prefix string) (string, error) {
 	if strings.TrimSpace(ptRoot) == "" {
@@ -182,54 +595,257 @@ func CreatePP(id, ptRoot, prefix string) (string, error) {
 		return "", fmt.Errorf("%w, id: '%s', prefix: '%s'", error_msgs.Err5, id, prefix)
 	}
 
-	ptRoot = filepath.Join(ptRoot, rootDir)
+	// An ID that is only the prefix would be the pairtree_root itself
+	if id == "" {
+		return "", error_msgs.Err4
+	}
+
 	pairPath := caltech_pairtree.Encode(id)
 
 	// enocde ID to add to end of pairpath
 	id = string(caltech_pairtree.CharEncode([]rune(id)))
 
-	pairPath = filepath.Join(pairPath, id)
-	pairPath = filepath.Join(ptRoot, pairPath)
-	return pairPath, nil
+	return JoinPath(ptRoot, rootDir, pairPath, id), nil
+}
+
+// CreatePP creates the full pairpath of the object with the ID, which starts with the prefix of the pairtree
+func (p *Pairtree) CreatePP(id string) (string, error) {
+	return CreatePP(id, p.root, p.prefix)
+}
+
+// WalkObjects calls fn with the ID and path of every object in the pairtree, in the order of their
+// pairpaths. An object is a directory whose name is the encoded ID that the shorties above it spell
+// out; shorties below an object with a short ID are still walked for objects whose IDs start with it.
+func WalkObjects(ptRoot, prefix string, fn func(id, objPath string) error) error {
+	return WalkObjectsCtx(context.Background(), ptRoot, prefix, fn)
+}
+
+// WalkObjectsCtx is WalkObjects that stops before the next directory once the context is canceled
+func WalkObjectsCtx(ctx context.Context, ptRoot, prefix string, fn func(id, objPath string) error) error {
+	pt, err := open(ctx, ptRoot)
+	if err != nil {
+		return err
+	}
+
+	return pt.withPrefix(prefix).WalkObjectsCtx(ctx, fn)
+}
+
+// WalkObjects calls fn with the ID and path of every object in the pairtree, in the order of their
+// pairpaths, with the prefix of the pairtree
+func (p *Pairtree) WalkObjects(fn func(id, objPath string) error) error {
+	return p.WalkObjectsCtx(context.Background(), fn)
+}
+
+// WalkObjectsCtx is WalkObjects that stops before the next directory once the context is canceled
+func (p *Pairtree) WalkObjectsCtx(ctx context.Context, fn func(id, objPath string) error) error {
+	return walkShorties(ctx, p.storage, JoinPath(p.root, rootDir), "", p.prefix, fn)
+}
+
+// walkShorties looks for objects in the directory reached by the shorties spelling out encoded
+func walkShorties(ctx context.Context, storage Storage, dir, encoded, prefix string, fn func(id, objPath string) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	entries, err := storage.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		name := entry.Name()
+		path := JoinPath(dir, name)
+
+		if encoded != "" && name == encoded {
+			id, err := decodeName(name)
+			if err != nil {
+				return &fs.PathError{Op: "walk", Path: path, Err: err}
+			}
+
+			if err := fn(prefix+id, path); err != nil {
+				return err
+			}
+		}
+
+		if utf8.RuneCountInString(name) <= 2 {
+			if err := walkShorties(ctx, storage, path, encoded+name, prefix, fn); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// PathID returns the ID of the object that a path is in, like the path of a file in a copy of the
+// pairtree. The path only has to contain pairtree_root and may use slashes on any platform.
+func PathID(path, prefix string) (string, error) {
+	parts := strings.Split(filepath.ToSlash(path), "/")
+
+	for i, part := range parts {
+		if part != rootDir {
+			continue
+		}
+
+		encoded := ""
+		for _, name := range parts[i+1:] {
+			if encoded != "" && name == encoded {
+				id, err := decodeName(name)
+				if err != nil {
+					return "", &fs.PathError{Op: "decode", Path: path, Err: err}
+				}
+
+				return prefix + id, nil
+			}
+
+			if name == "" || utf8.RuneCountInString(name) > 2 {
+				break
+			}
+			encoded += name
+		}
+		break
+	}
+
+	return "", &fs.PathError{Op: "decode", Path: path, Err: error_msgs.Err35}
+}
+
+// PPathToID returns the ID of the object at a pairpath under the pairtree_root of the root, the reverse of
+// CreatePP. A path inside the object returns the ID of the object it is in.
+func PPathToID(pairPath, ptRoot, prefix string) (string, error) {
+	rel, err := filepath.Rel(filepath.Join(ptRoot, rootDir), pairPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", &fs.PathError{Op: "decode", Path: pairPath, Err: error_msgs.Err35}
+	}
+
+	// Errors report the pairpath that was given rather than the part of it that was decoded
+	id, err := PathID(filepath.Join(rootDir, rel), prefix)
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		pathErr.Path = pairPath
+	}
+
+	return id, err
+}
+
+// PPathToID returns the ID of the object at a pairpath of the pairtree, with the prefix of the pairtree
+func (p *Pairtree) PPathToID(pairPath string) (string, error) {
+	return PPathToID(pairPath, p.root, p.prefix)
 }
 
 // RecursiveFiles traverses directories recursively starting from the given pairPath and ID, returning a map
 // where keys are directory paths and values are slices of fs.DirEntry. The traversal begins at the ID and
 // recursively searches from that ID.
 func RecursiveFiles(pairPath, id string) (map[string][]fs.DirEntry, error) {
+	return RecursiveFilesCtx(context.Background(), pairPath, id)
+}
+
+// RecursiveFilesCtx is RecursiveFiles that stops before the next directory once the context is canceled
+func RecursiveFilesCtx(ctx context.Context, pairPath, id string) (map[string][]fs.DirEntry, error) {
+	pt, err := open(ctx, pairPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return pt.RecursiveFilesCtx(ctx, pairPath, id)
+}
+
+// RecursiveFiles traverses the directories of the pairtree recursively starting from the given pairPath
+func (p *Pairtree) RecursiveFiles(pairPath, id string) (map[string][]fs.DirEntry, error) {
+	return p.RecursiveFilesCtx(context.Background(), pairPath, id)
+}
+
+// RecursiveFilesCtx is RecursiveFiles that stops before the next directory once the context is canceled
+func (p *Pairtree) RecursiveFilesCtx(ctx context.Context, pairPath, id string) (map[string][]fs.DirEntry, error) {
 	result := make(map[string][]fs.DirEntry)
 
-	err := filepath.WalkDir(pairPath, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
+	// The entries of a file are not walked, like filepath.WalkDir
+	if info, err := p.storage.Stat(pairPath); err != nil || !info.IsDir() {
+		return result, err
+	}
 
-		// Skip the root directory itself
-		if path == pairPath {
-			return nil
-		}
+	err := p.walkFiles(ctx, JoinPath(pairPath), result)
+	return result, err
+}
+
+// walkFiles adds the entries of the directory, and of the directories under it, to the result
+func (p *Pairtree) walkFiles(ctx context.Context, dir string, result map[string][]fs.DirEntry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
-		parentDir := filepath.Dir(path)
+	entries, err := p.storage.ReadDir(dir)
+	if err != nil {
+		return err
+	}
 
+	for _, entry := range entries {
 		// Add the directory entry to the map
-		result[parentDir] = append(result[parentDir], d)
+		result[dir] = append(result[dir], entry)
 
-		// If the entry is a directory, initialize its entry in the map
-		if d.IsDir() {
+		// If the entry is a directory, initialize its entry in the map and walk it
+		if entry.IsDir() {
+			path := JoinPath(dir, entry.Name())
 			result[path] = []fs.DirEntry{}
+
+			if err := p.walkFiles(ctx, path, result); err != nil {
+				return err
+			}
 		}
+	}
 
-		return nil
-	})
+	return nil
+}
 
-	return result, err
+// ParallelRecursiveFiles is RecursiveFilesCtx with the given number of directories read at once
+func ParallelRecursiveFiles(ctx context.Context, pairPath, id string, jobs int) (map[string][]fs.DirEntry, error) {
+	pt, err := open(ctx, pairPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return pt.ParallelRecursiveFiles(ctx, pairPath, id, jobs)
+}
+
+// ParallelRecursiveFiles is RecursiveFilesCtx with the given number of directories read at once, which
+// returns the same entries as when they are read one directory at a time
+func (p *Pairtree) ParallelRecursiveFiles(ctx context.Context, pairPath, id string, jobs int) (map[string][]fs.DirEntry, error) {
+	// The entries of a file are not walked, like filepath.WalkDir
+	if info, err := p.storage.Stat(pairPath); err != nil || !info.IsDir() {
+		return make(map[string][]fs.DirEntry), err
+	}
+
+	root := JoinPath(pairPath)
+	result, err := readTree(ctx, p.storage, root, jobs, ListOptions{Recursive: true, ShowAll: true})
+	if err != nil {
+		return result, err
+	}
+
+	// Like RecursiveFiles, the directory that was walked is only in the result when it has entries
+	if len(result[root]) == 0 {
+		delete(result, root)
+	}
+	return result, nil
 }
 
 // NonRecursiveFiles searches through a file structure non recursively
 func NonRecursiveFiles(pairPath string) (map[string][]fs.DirEntry, error) {
+	pt, err := open(context.Background(), pairPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return pt.NonRecursiveFiles(pairPath)
+}
+
+// NonRecursiveFiles searches through a directory of the pairtree non recursively
+func (p *Pairtree) NonRecursiveFiles(pairPath string) (map[string][]fs.DirEntry, error) {
 	result := make(map[string][]fs.DirEntry)
 
-	entries, err := os.ReadDir(pairPath)
+	entries, err := p.storage.ReadDir(pairPath)
 	if err != nil {
 		return nil, err
 	}
@@ -282,30 +898,91 @@ func ToJSONStructure(dirTree Directory) ([]byte, error) {
 // DeletePairtreeItem searches through a pairtree directory given the pairPath and subPath,
 // and deletes the given directory or file.
 func DeletePairtreeItem(fullPath string) error {
+	pt, err := open(context.Background(), fullPath)
+	if err != nil {
+		return err
+	}
+
+	return pt.DeletePairtreeItem(fullPath)
+}
+
+// DeletePairtreeItem deletes the directory or file at the path in the pairtree. When the path is the
+// pairpath of an object, the shorties above it that are left empty are removed too.
+func (p *Pairtree) DeletePairtreeItem(fullPath string) error {
 	// Check if the file or directory exists
-	if _, err := os.Stat(fullPath); os.IsNotExist(err) {
+	if _, err := p.storage.Stat(fullPath); errors.Is(err, fs.ErrNotExist) {
 		return err
 	}
 
 	// Attempt to remove the directory or file
-	err := os.RemoveAll(fullPath)
+	err := p.storage.RemoveAll(fullPath)
 	if err != nil {
 		return err
 	}
+
+	// A path inside an object leaves the object's directories in place
+	if id, err := p.ID(fullPath); err != nil {
+		return nil
+	} else if pairPath, err := p.PairPath(id); err != nil || pairPath != fullPath {
+		return nil
+	}
+
+	return p.PruneShorties(fullPath)
+}
+
+// PruneShorties removes the shorties above the pairpath of an object that was deleted or moved away, from
+// the nearest up to pairtree_root, until one is not empty because it leads to other objects. Objects in
+// S3 have no directories to remove.
+func (p *Pairtree) PruneShorties(pairPath string) error {
+	if p.fs == nil {
+		return nil
+	}
+
+	root := JoinPath(p.root, rootDir)
+	for dir := filepath.Dir(pairPath); strings.HasPrefix(dir, root+string(filepath.Separator)); dir = filepath.Dir(dir) {
+		entries, err := p.storage.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		if len(entries) > 0 {
+			return nil
+		}
+
+		if err := p.fs.Remove(dir); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
 // GetUniqueDestination checks if the destination path exists and appends ".x" (where x is an integer)
 // to avoid overwriting files or directories.
 func GetUniqueDestination(dest string) string {
+	storage, err := StorageFor(context.Background(), dest)
+	if err != nil {
+		return dest
+	}
+
+	return uniqueDestination(storage, dest)
+}
+
+// GetUniqueDestination returns the destination, or the first name with ".x" appended that is not in the
+// file system of the pairtree
+func (p *Pairtree) GetUniqueDestination(dest string) string {
+	return uniqueDestination(p.storage, dest)
+}
+
+// uniqueDestination returns the destination, or the first name with ".x" appended that is not in the storage
+func uniqueDestination(storage Storage, dest string) string {
 	// If the destination does not exist, return it as is.
-	if _, err := os.Stat(dest); os.IsNotExist(err) {
+	if _, err := storage.Stat(dest); os.IsNotExist(err) {
 		return dest
 	}
 
 	// Extract the directory and base name
-	dir := filepath.Dir(dest)
-	base := filepath.Base(dest)
+	dir := dirPath(dest)
+	base := basePath(dest)
 
 	// Strip the extension from the base name
 	ext := filepath.Ext(base)
@@ -317,10 +994,10 @@ func GetUniqueDestination(dest string) string {
 	for {
 		// Construct a new destination path by appending ".x" to the base name without extension
 		newBase := fmt.Sprintf("%s.%d%s", baseWithoutExt, counter, ext)
-		newDest := filepath.Join(dir, newBase)
+		newDest := JoinPath(dir, newBase)
 
 		// If the new destination does not exist, return it
-		if _, err := os.Stat(newDest); os.IsNotExist(err) {
+		if _, err := storage.Stat(newDest); os.IsNotExist(err) {
 			return newDest
 		}
 		counter++
@@ -328,8 +1005,14 @@ func GetUniqueDestination(dest string) string {
 }
 
 // CopyFileOrFolder copies a file or folder from src to dest, creating a unique destination if needed.
-// It follows the same behavior as Unix cp with directories.
-func CopyFileOrFolder(src, dest string, overwrite bool) (string, error) {
+// It follows the same behavior as Unix cp with directories. Files are copied with the buffer set in
+// the options, or through the storage when the source or destination is in S3. If the copy fails or
+// the context is canceled, a destination created by the copy is removed so no partial copy is left behind.
+func CopyFileOrFolder(ctx context.Context, src, dest string, overwrite bool, opts CopyOptions) (string, error) {
+	if IsS3(src) || IsS3(dest) {
+		return copyBetween(ctx, src, dest, overwrite, opts.Jobs)
+	}
+
 	// Get the source file or directory info
 	_, err := os.Stat(src)
 	if err != nil {
@@ -350,104 +1033,154 @@ func CopyFileOrFolder(src, dest string, overwrite bool) (string, error) {
 		dest = GetUniqueDestination(dest)
 	}
 
-	// Perform the copy operation using otiai10/copy
-	err = copy.Copy(src, dest)
-	if err != nil {
+	if err = copyContext(ctx, src, dest, opts); err != nil {
 		return "", err
 	}
 
 	return dest, nil
 }
 
-// TarGz compresses the source directory or file into a .tgz archive.
-// If the destination file already exists, it creates a unique destination.
-// The prefix of the pairtree ID will be appended to the .tgz
-func TarGz(src, dest, prefix string, overwrite bool) error {
-	prefix = string(caltech_pairtree.CharEncode([]rune(prefix)))
-
-	// Ensure the destination directory exists
-	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
-		return fmt.Errorf("could not create destination directory: %w", err)
+// CopyFileOrFolder copies a file or folder of the file system of the pairtree from src to dest. Files on
+// the local file system are copied like CopyFileOrFolder copies them, and through the file system otherwise.
+func (p *Pairtree) CopyFileOrFolder(ctx context.Context, src, dest string, overwrite bool, opts CopyOptions) (string, error) {
+	// The other path of a copy to or from S3 is on the local file system
+	if _, ok := p.fs.(*afero.OsFs); ok || p.fs == nil {
+		return CopyFileOrFolder(ctx, src, dest, overwrite, opts)
 	}
 
-	dest = filepath.Join(dest, prefix+filepath.Base(src)+tar)
-
-	if !overwrite {
-		// Generate a unique destination if the file already exists
-		dest = GetUniqueDestination(dest)
-	}
-
-	// Create a new archiver instance for tar.gz
-	tgz := archiver.NewTarGz()
-
-	// Archive the source directory
-	if err := tgz.Archive([]string{src}, dest); err != nil {
-		return fmt.Errorf("could not archive the source: %w", err)
-	}
-
-	return nil
+	return copyStorage(ctx, p.storage, src, p.storage, dest, overwrite, opts.Jobs)
 }
 
-// UnTarGz extracts a tar.gz archive to the specified destination directory.
-// UntarGZ assumes that within the source .tgz file there is a folder that matches the name of
-// the destination. If no such folder exists, UnTarGz will fail
-func UnTarGz(src, dest string) error {
-	id := filepath.Base(dest)
-	fs := afero.NewOsFs()
+// copyContext copies src to dest, stopping once the context is canceled. A single regular file is
+// copied with copyFile, and a directory or link with otiai10/copy, which is only given a buffer size
+// when one is set because it allocates the buffer for every file. A destination that did not exist
+// before the copy is removed when the copy does not finish, or does not match the source with Verify.
+func copyContext(ctx context.Context, src, dest string, opts CopyOptions) (err error) {
+	if _, statErr := os.Stat(dest); statErr != nil {
+		defer func() {
+			if err != nil {
+				err = errors.Join(err, os.RemoveAll(dest))
+			}
+		}()
+	}
 
-	tempDir, err := afero.TempDir(fs, "", "temporary")
+	info, err := os.Lstat(src)
 	if err != nil {
 		return err
 	}
 
-	defer func() {
-		err = errors.Join(err, fs.RemoveAll(tempDir))
-	}()
+	if info.Mode().IsRegular() {
+		err = copyFile(ctx, src, dest, info, opts)
+	} else {
+		options := copy.Options{Skip: contextSkip(ctx)}
+		if opts.BufferSize > 0 {
+			options.CopyBufferSize = uint(opts.BufferSize)
+		}
+		if opts.Jobs > 1 {
+			options.NumOfWorkers = int64(opts.Jobs)
+		}
 
-	// Create a TarGz archiver instance
-	tgz := archiver.TarGz{
-		Tar: &archiver.Tar{
-			OverwriteExisting: true, // Keep this to handle file overwrites in case any remain
-		},
+		err = copy.Copy(src, dest, options)
 	}
 
-	// Extract the tar.gz archive to the destination directory
-	if err := tgz.Unarchive(src, tempDir); err != nil {
+	if err != nil || !opts.Verify {
 		return err
 	}
 
-	// Check if tempDir contains a single folder that matches the pairtree ID
-	files, err := afero.ReadDir(fs, tempDir)
-	if err != nil {
-		return fmt.Errorf("could not read temp directory: %w", err)
+	return Verify(ctx, src, dest)
+}
+
+// contextSkip returns a copy.Options Skip function that aborts the copy once the context is canceled
+func contextSkip(ctx context.Context) func(os.FileInfo, string, string) (bool, error) {
+	return func(os.FileInfo, string, string) (bool, error) {
+		return false, ctx.Err()
 	}
+}
+
+// ArchiveName returns the file name of an archive of the object at objPath, which is the encoded prefix
+// and the object directory followed by the extension
+func ArchiveName(prefix, objPath, ext string) string {
+	return string(caltech_pairtree.CharEncode([]rune(prefix))) + filepath.Base(objPath) + ext
+}
 
-	if len(files) != 1 || !files[0].IsDir() {
-		return error_msgs.Err12
+// TarGz compresses the source directory or file into a .tgz archive.
+// If the destination file already exists, it creates a unique destination.
+// The prefix of the pairtree ID will be appended to the .tgz. If archiving fails or the context
+// is canceled, the partially written .tgz is removed.
+func TarGz(ctx context.Context, src, dest, prefix string, overwrite bool, opts ArchiveOptions) error {
+	return New(afero.NewOsFs(), "").withPrefix(prefix).TarGz(ctx, src, dest, overwrite, opts)
+}
+
+// TarGz compresses the source directory or file of the file system of the pairtree into a .tgz archive
+func (p *Pairtree) TarGz(ctx context.Context, src, dest string, overwrite bool, opts ArchiveOptions) error {
+	return p.archive(src, dest, tgzExt, overwrite, func(w io.Writer, skip string) error {
+		return p.writeTarGz(ctx, w, src, skip, opts)
+	})
+}
+
+// archive writes the archive of the source with the extension into the destination directory, named
+// after the prefix of the pairtree and the source, and removes it if write fails
+func (p *Pairtree) archive(src, dest, ext string, overwrite bool, write func(w io.Writer, skip string) error) (err error) {
+	// Ensure the destination directory exists
+	if err := p.fs.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return fmt.Errorf("could not create destination directory: %w", err)
 	}
 
-	// Check if the folder name matches the pairtree ID
-	if files[0].Name() != id {
-		return error_msgs.Err13
+	dest = p.archivePath(src, dest, ext, overwrite)
+
+	// Make the folder to contain the archive if it does not already exist
+	if err := p.fs.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return fmt.Errorf("could not create destination directory: %w", err)
 	}
 
-	// Ensure the source file exists
-	if _, err := os.Stat(src); os.IsNotExist(err) {
-		return err
+	out, err := p.fs.Create(dest)
+	if err != nil {
+		return fmt.Errorf("could not archive the source: %w", err)
 	}
 
-	// Check if destination directory exists
-	if _, err := os.Stat(dest); err == nil {
-		// If it exists, clean up the destination directory to ensure full overwrite
-		if err := os.RemoveAll(dest); err != nil {
-			return err
+	defer func() {
+		err = errors.Join(err, out.Close())
+		if err != nil {
+			err = errors.Join(err, p.fs.Remove(dest))
 		}
+	}()
+
+	// Archive the source, with the archive's top level folder named after the source
+	if err := write(out, dest); err != nil {
+		return fmt.Errorf("could not archive the source: %w", err)
+	}
+
+	return nil
+}
+
+// archivePath returns the path of the archive of the source with the extension in the destination
+// directory, which is made unique when the archive exists unless it is overwritten
+func (p *Pairtree) archivePath(src, dest, ext string, overwrite bool) string {
+	dest = filepath.Join(dest, ArchiveName(p.prefix, src, ext))
+
+	if !overwrite {
+		// Generate a unique destination if the file already exists
+		dest = p.GetUniqueDestination(dest)
 	}
 
-	// Now you can move the folder from tempDir to the final destination
-	if err := copy.Copy(filepath.Join(tempDir, id), dest); err != nil {
+	return dest
+}
+
+// UnTarGz extracts a tar.gz archive to the specified destination directory.
+// UntarGZ assumes that within the source .tgz file there is a folder that matches the name of
+// the destination. If no such folder exists, UnTarGz will fail. The destination is only replaced
+// once the whole archive has been extracted, so an error or the context being canceled leaves it as it was.
+func UnTarGz(ctx context.Context, src, dest string) error {
+	return New(afero.NewOsFs(), "").UnTarGz(ctx, src, dest)
+}
+
+// UnTarGz extracts a tar.gz archive of the file system of the pairtree to the destination directory
+func (p *Pairtree) UnTarGz(ctx context.Context, src, dest string) error {
+	in, err := p.fs.Open(src)
+	if err != nil {
 		return err
 	}
+	defer in.Close()
 
-	return err
+	return p.ReadTarGz(ctx, in, dest)
 }
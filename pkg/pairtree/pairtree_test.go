@@ -1,18 +1,24 @@
 package pairtree
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"syscall"
 	"testing"
+	"unicode/utf8"
 
 	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
-	"github.com/UCLALibrary/pt-tools/testutils"
+	"github.com/UCLALibrary/pt-tools/pkg/pttest"
+	caltech_pairtree "github.com/caltechlibrary/pairtree"
 	"github.com/mholt/archiver/v3"
-	"github.com/otiai10/copy"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -186,10 +192,10 @@ func TestGetPrefix(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			// Create a temporary directory for this test
-			tempDir := testutils.CreateTempDir(t, fs)
+			tempDir := pttest.CreateTempDir(t, fs)
 
-			// Copies entire directory in testutils.TestPairtree into the temporary directory
-			testutils.CopyTestDirectory(t, testutils.TestPairtree, tempDir)
+			// Builds the standard pairtree in the temporary directory
+			pttest.StandardPairtree().Build(t, fs, tempDir)
 
 			prefixFile := filepath.Join(tempDir, prefixDir)
 
@@ -238,6 +244,15 @@ func TestCreatePP(t *testing.T) {
 			expectErr: nil,
 			expectPP:  []string{"root", "pairtree_root", "34", "+6", "21", "34+621"},
 		},
+		{
+			// Characters outside of ASCII are kept as they are, which is where existing objects were written
+			name:      "multibyte",
+			id:        "ark:/été",
+			ptRoot:    "root",
+			prefix:    prefix,
+			expectErr: nil,
+			expectPP:  []string{"root", "pairtree_root", "ét", "é", "été"},
+		},
 		{
 			name:      "noPrefix",
 			id:        "34621",
@@ -270,6 +285,14 @@ func TestCreatePP(t *testing.T) {
 			expectErr: error_msgs.Err5,
 			expectPP:  nil,
 		},
+		{
+			name:      "onlyPrefix",
+			id:        prefix,
+			ptRoot:    "root",
+			prefix:    prefix,
+			expectErr: error_msgs.Err4,
+			expectPP:  nil,
+		},
 	}
 
 	for _, test := range tests {
@@ -289,6 +312,220 @@ func TestCreatePP(t *testing.T) {
 	}
 }
 
+// TestDecodeName tests that encoded names decode to their IDs and that invalid names are rejected
+func TestDecodeName(t *testing.T) {
+	tests := []struct {
+		name      string
+		encoded   string
+		expectID  string
+		expectErr error
+	}{
+		{name: "plain", encoded: "b5488", expectID: "b5488"},
+		{name: "substitutes", encoded: "13030=c8+xk,1", expectID: "13030/c8:xk.1"},
+		{name: "hex escape", encoded: "a^20b^2a", expectID: "a b*"},
+		{name: "multibyte", encoded: "été", expectID: "été"},
+		{name: "escaped multibyte", encoded: "^c3^a9", expectErr: error_msgs.Err26},
+		{name: "empty", encoded: "", expectErr: error_msgs.Err26},
+		{name: "slash", encoded: "a/b", expectErr: error_msgs.Err26},
+		{name: "dot", encoded: "..", expectErr: error_msgs.Err26},
+		{name: "colon", encoded: "a:b", expectErr: error_msgs.Err26},
+		{name: "space", encoded: "a b", expectErr: error_msgs.Err26},
+		{name: "truncated escape", encoded: "a^2", expectErr: error_msgs.Err26},
+		{name: "not hex", encoded: "a^zz", expectErr: error_msgs.Err26},
+		{name: "uppercase hex", encoded: "a^2A", expectErr: error_msgs.Err26},
+		{name: "needless escape", encoded: "^41", expectErr: error_msgs.Err26},
+		{name: "invalid utf8", encoded: "^ff", expectErr: error_msgs.Err26},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			id, err := decodeName(test.encoded)
+			assert.ErrorIs(t, err, test.expectErr)
+			assert.Equal(t, test.expectID, id)
+		})
+	}
+}
+
+// TestDecodeEscapedCaret tests that an escaped ^ followed by what looks like another escape is decoded as
+// the ^ and the characters after it, each time it is decoded
+func TestDecodeEscapedCaret(t *testing.T) {
+	for i := range 256 {
+		digits := fmt.Sprintf("%02x", i)
+
+		for range 10 {
+			id, err := decodeName("a^5e" + digits)
+			require.NoError(t, err)
+			assert.Equal(t, "a^"+digits, id)
+		}
+	}
+}
+
+// FuzzEncodeDecode tests that every ID encodes to a name that decodes back to the ID
+func FuzzEncodeDecode(f *testing.F) {
+	for _, seed := range []string{"b5488", "13030/c8:xk.1", "a b*", "été", "..", "^", "=+,", "\x00\n"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, id string) {
+		if id == "" || !utf8.ValidString(id) {
+			t.Skip()
+		}
+
+		encoded := string(caltech_pairtree.CharEncode([]rune(id)))
+		for _, c := range encoded {
+			if strings.ContainsRune(" /.:\"*<>?\\|", c) {
+				t.Fatalf("encoded name %q of %q contains %q", encoded, id, c)
+			}
+		}
+
+		decoded, err := decodeName(encoded)
+		require.NoError(t, err)
+		assert.Equal(t, id, decoded)
+	})
+}
+
+// FuzzDecodeName tests that a name is only accepted when it is exactly the encoding of what it decodes to
+func FuzzDecodeName(f *testing.F) {
+	for _, seed := range []string{"b5488", "13030=c8+xk,1", "a^20b", "^", "^4", "^41", "^ZZ", "a/b", "..", "^c3"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, name string) {
+		id, err := decodeName(name)
+		if err != nil {
+			assert.ErrorIs(t, err, error_msgs.Err26)
+			return
+		}
+
+		assert.Equal(t, name, string(caltech_pairtree.CharEncode([]rune(id))))
+	})
+}
+
+// FuzzCreatePP tests that the pairpath of any ID is an object directory below the pairtree_root
+func FuzzCreatePP(f *testing.F) {
+	for _, seed := range []string{"", "b5488", "..", "../..", "/", "a/../../b", ".", "=", "^2e^2e"} {
+		f.Add(seed)
+	}
+
+	ptRoot := filepath.Join("pt", "root")
+
+	f.Fuzz(func(t *testing.T, id string) {
+		pairPath, err := CreatePP(prefix+id, ptRoot, prefix)
+		if err != nil {
+			return
+		}
+
+		rel, err := filepath.Rel(filepath.Join(ptRoot, rootDir), pairPath)
+		require.NoError(t, err)
+
+		if rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			t.Fatalf("pairpath %q of %q is not below the pairtree_root", pairPath, id)
+		}
+
+		// The object directory is the encoded ID
+		if utf8.ValidString(id) {
+			decoded, err := decodeName(filepath.Base(pairPath))
+			require.NoError(t, err)
+			assert.Equal(t, id, decoded)
+		}
+	})
+}
+
+// TestWalkObjects tests that every object in the pairtree is found with its ID, and nothing else is
+func TestWalkObjects(t *testing.T) {
+	fs := afero.NewOsFs()
+	builder := pttest.NewPairtreeBuilder().
+		WithObject("ark:/13030/c8:xk.1", "file.txt").
+		WithObject("ark:/ab", "folder/file.txt").
+		WithObject("ark:/abcd", "file.txt").
+		WithObject("ark:/été", "file.txt")
+	ptRoot := builder.BuildTemp(t, fs)
+
+	// A directory that is not the encoded ID the shorties spell out is not an object
+	require.NoError(t, fs.MkdirAll(filepath.Join(ptRoot, rootDir, "zz", "notanobject"), 0755))
+
+	var ids []string
+	err := WalkObjects(ptRoot, "ark:/", func(id, objPath string) error {
+		assert.Equal(t, builder.ObjectPath(ptRoot, id), objPath)
+		ids = append(ids, id)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ark:/13030/c8:xk.1", "ark:/ab", "ark:/abcd", "ark:/été"}, ids)
+
+	// An error from fn stops the walk
+	stop := errors.New("stop")
+	err = WalkObjects(ptRoot, "ark:/", func(id, objPath string) error {
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+}
+
+// TestPathID tests that the ID of the object a path is in is found from its pairpath
+func TestPathID(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string
+		expectID  string
+		expectErr error
+	}{
+		{name: "object", path: "pairtree_root/a5/38/8/a5388", expectID: "ark:/a5388"},
+		{name: "file in object", path: "/data/pt/pairtree_root/a5/38/8/a5388/folder/a5388.txt", expectID: "ark:/a5388"},
+		{name: "encoded", path: "copy/pairtree_root/13/03/0=/c8/+x/k,/1/13030=c8+xk,1/file.txt", expectID: "ark:/13030/c8:xk.1"},
+		{name: "shorties only", path: "pairtree_root/a5/38/8", expectErr: error_msgs.Err35},
+		{name: "not in the pairtree", path: "pairtree_events/a5388.jsonl", expectErr: error_msgs.Err35},
+		{name: "wrong object", path: "pairtree_root/a5/38/8/b5488/file.txt", expectErr: error_msgs.Err35},
+		{name: "invalid encoding", path: "pairtree_root/^z/^z", expectErr: error_msgs.Err26},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			id, err := PathID(test.path, "ark:/")
+			assert.ErrorIs(t, err, test.expectErr)
+			assert.Equal(t, test.expectID, id)
+		})
+	}
+}
+
+// TestPPathToID tests that the ID of an object is found from the pairpath CreatePP gives it
+func TestPPathToID(t *testing.T) {
+	ptRoot := filepath.Join("data", "pt")
+
+	for _, id := range []string{"ark:/a5388", "ark:/a54892", "ark:/13030/c8:xk.1", "ark:/ab", "ark:/ä 1"} {
+		pairPath, err := CreatePP(id, ptRoot, "ark:/")
+		require.NoError(t, err)
+
+		decoded, err := PPathToID(pairPath, ptRoot, "ark:/")
+		require.NoError(t, err)
+		assert.Equal(t, id, decoded)
+
+		decoded, err = PPathToID(filepath.Join(pairPath, "folder", "file.txt"), ptRoot, "ark:/")
+		require.NoError(t, err)
+		assert.Equal(t, id, decoded)
+	}
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{name: "root", path: filepath.Join(ptRoot, rootDir)},
+		{name: "shorties only", path: filepath.Join(ptRoot, rootDir, "a5", "38")},
+		{name: "other pairtree", path: filepath.Join("data", "other", rootDir, "a5", "38", "8", "a5388")},
+		{name: "outside the root", path: filepath.Join(ptRoot, "pairtree_events", "a5388.jsonl")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := PPathToID(test.path, ptRoot, "ark:/")
+			assert.ErrorIs(t, err, error_msgs.Err35)
+
+			var pathErr *fs.PathError
+			require.ErrorAs(t, err, &pathErr)
+			assert.Equal(t, test.path, pathErr.Path)
+		})
+	}
+}
+
 // TestGetPrefix creates a temporary directory with Afero and alters the prefix file depending on test needs
 func TestRecursiveFiles(t *testing.T) {
 	// Define test cases
@@ -353,9 +590,9 @@ func TestRecursiveFiles(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.pairpath, func(t *testing.T) {
 			// Create a temporary directory for this test
-			tempDir := testutils.CreateTempDir(t, fs)
+			tempDir := pttest.CreateTempDir(t, fs)
 
-			testutils.CopyTestDirectory(t, testutils.TestPairtree, tempDir)
+			pttest.StandardPairtree().Build(t, fs, tempDir)
 
 			// Create the new testpath that has the full directory name
 			prefixPairtree := filepath.Join(tempDir, rootDir)
@@ -423,9 +660,9 @@ func TestNonRecursiveFiles(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.pairpath, func(t *testing.T) {
 			// Create a temporary directory for this test
-			tempDir := testutils.CreateTempDir(t, fs)
+			tempDir := pttest.CreateTempDir(t, fs)
 
-			testutils.CopyTestDirectory(t, testutils.TestPairtree, tempDir)
+			pttest.StandardPairtree().Build(t, fs, tempDir)
 			// Create the new testpath that has the full directory name
 			prefixPairtree := filepath.Join(tempDir, rootDir)
 			updatedMap := updateMapKeys(test.expectMap, prefixPairtree)
@@ -460,15 +697,13 @@ func TestCheckPTVer(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			// Create a temporary directory for this test
-			tempDir := testutils.CreateTempDir(t, fs)
+			tempDir := pttest.CreateTempDir(t, fs)
 
-			// testPath := filepath.Join(tempDir, test.name)
-			err := copy.Copy(testutils.TestPairtree, tempDir)
-			if err != nil {
-				t.Fatalf("Error copying directory: %v", err)
-			}
+			pttest.StandardPairtree().Build(t, fs, tempDir)
 			verFile := filepath.Join(tempDir, verDir)
 
+			var err error
+
 			if test.name == "noVerFile" {
 				err = fs.Remove(verFile)
 				if err != nil {
@@ -602,7 +837,7 @@ func TestCreatePairtree(t *testing.T) {
 			if strings.TrimSpace(test.path) == "" {
 				tempDir = test.path
 			} else {
-				tempDir = testutils.CreateTempDir(t, fs)
+				tempDir = pttest.CreateTempDir(t, fs)
 				tempDir = filepath.Join(tempDir, test.path)
 			}
 
@@ -615,13 +850,13 @@ func TestCreatePairtree(t *testing.T) {
 				ptRootDirPath := filepath.Join(tempDir, rootDir)
 
 				// check prefix
-				ptPre, err := testutils.OpenFileAndCheck(fs, ptPreFilePath)
+				ptPre, err := pttest.OpenFileAndCheck(fs, ptPreFilePath)
 				assert.ErrorIs(t, err, nil, "There was an error opening the prefix file")
 				ptPreStirng := string(ptPre)
 				assert.Equal(t, prefix, ptPreStirng, "The prefix in the file did not match the prefix given to CreatePairtree()")
 
 				// check version
-				ptVerContent, err := testutils.OpenFileAndCheck(fs, ptVerFilePath)
+				ptVerContent, err := pttest.OpenFileAndCheck(fs, ptVerFilePath)
 				assert.ErrorIs(t, err, nil, "There was an error opening the prefix file")
 				ptVerString := string(ptVerContent)
 				assert.Equal(t, ptVerSpec, ptVerString, "The version in the file did not match the expected version")
@@ -913,9 +1148,9 @@ func TestDeletePairtreeItem(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.pairpath, func(t *testing.T) {
 			// Create a temporary directory for this test
-			tempDir := testutils.CreateTempDir(t, fs)
+			tempDir := pttest.CreateTempDir(t, fs)
 
-			testutils.CopyTestDirectory(t, testutils.TestPairtree, tempDir)
+			pttest.StandardPairtree().Build(t, fs, tempDir)
 			// Create the new testpath that has the full directory name
 			prefixPairtree := filepath.Join(tempDir, rootDir)
 			fullPath := filepath.Join(prefixPairtree, test.pairpath)
@@ -924,6 +1159,29 @@ func TestDeletePairtreeItem(t *testing.T) {
 			assert.ErrorIs(t, err, test.expectError)
 		})
 	}
+
+	// Deleting an object removes the shorties left empty, and a path inside an object leaves the object
+	fsys := afero.NewMemMapFs()
+	pttest.StandardPairtree().WithObject("ark:/c1234", "content/c1234.txt").Build(t, fsys, "/pt")
+	pt, err := OpenFs(fsys, "/pt")
+	require.NoError(t, err)
+
+	c1234 := filepath.Join("/pt", rootDir, "c1", "23", "4", "c1234")
+	require.NoError(t, pt.Delete("ark:/c1234", "content"))
+	_, err = fsys.Stat(c1234)
+	assert.NoError(t, err)
+
+	require.NoError(t, pt.Delete("ark:/c1234", ""))
+	_, err = fsys.Stat(filepath.Join("/pt", rootDir, "c1"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	_, err = fsys.Stat(filepath.Join("/pt", rootDir))
+	assert.NoError(t, err)
+
+	require.NoError(t, pt.Delete("ark:/a5488", ""))
+	_, err = fsys.Stat(filepath.Join("/pt", rootDir, "a5", "48", "8"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	_, err = fsys.Stat(filepath.Join("/pt", rootDir, "a5", "48", "92", "a54892"))
+	assert.NoError(t, err)
 }
 
 // TestCopyFile tests copying files into directories
@@ -967,9 +1225,9 @@ func TestCopyFile(t *testing.T) {
 
 			destFilePath := ""
 			content := []byte("File contents")
-			tempFilePath := testutils.CreateTempFile(t, fs, content)
+			tempFilePath := pttest.CreateTempFile(t, fs, content)
 			tempFile := filepath.Base(tempFilePath)
-			dirDest := testutils.CreateTempDir(t, fs)
+			dirDest := pttest.CreateTempDir(t, fs)
 
 			if test.changeFileName {
 				destFilePath = filepath.Join(dirDest, test.fileName)
@@ -979,12 +1237,12 @@ func TestCopyFile(t *testing.T) {
 				destFilePath = filepath.Join(dirDest, tempFile)
 			}
 
-			_, err := CopyFileOrFolder(tempFilePath, dirDest, test.overwrite)
+			_, err := CopyFileOrFolder(context.Background(), tempFilePath, dirDest, test.overwrite, CopyOptions{})
 			assert.ErrorIs(t, err, test.expectError)
 
 			// if the .x naming convetion should be used, recopy the file
 			if !test.overwrite {
-				_, err = CopyFileOrFolder(tempFilePath, dirDest, test.overwrite)
+				_, err = CopyFileOrFolder(context.Background(), tempFilePath, dirDest, test.overwrite, CopyOptions{})
 				assert.ErrorIs(t, err, test.expectError)
 				destFilePath = destFilePath + test.fileName
 			}
@@ -1011,6 +1269,199 @@ func TestCopyFile(t *testing.T) {
 	}
 }
 
+// TestCopyFileOptions tests if a file is copied whole with buffers of any size, with and without O_DIRECT
+func TestCopyFileOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts CopyOptions
+	}{
+		{name: "Copied in the kernel", opts: CopyOptions{}},
+		{name: "Buffer smaller than the file", opts: CopyOptions{BufferSize: 1000}},
+		{name: "Direct with default buffer", opts: CopyOptions{Direct: true}},
+		{name: "Direct with buffer not a whole number of blocks", opts: CopyOptions{BufferSize: 5000, Direct: true}},
+	}
+
+	// The file does not end on a block boundary, so the end of it is written without O_DIRECT
+	content := make([]byte, 3*4096+123)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+
+	fs := afero.NewOsFs()
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			src := pttest.CreateTempFile(t, fs, content)
+			require.NoError(t, os.Chmod(src, 0640))
+
+			dest, err := CopyFileOrFolder(context.Background(), src, pttest.CreateTempDir(t, fs), false, test.opts)
+			require.NoError(t, err)
+
+			copied, err := os.ReadFile(dest)
+			require.NoError(t, err)
+			assert.Equal(t, content, copied)
+
+			info, err := os.Stat(dest)
+			require.NoError(t, err)
+			assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+		})
+	}
+}
+
+// TestParallelRecursiveFiles tests that the entries read with more than one job are the same as those
+// read one directory at a time
+func TestParallelRecursiveFiles(t *testing.T) {
+	root := pttest.StandardPairtree().BuildTemp(t, afero.NewOsFs())
+
+	for _, id := range []string{"ark:/a5388", "ark:/a54892", "ark:/b5488"} {
+		pairPath, err := CreatePP(id, root, prefix)
+		require.NoError(t, err)
+
+		serial, err := RecursiveFiles(pairPath, id)
+		require.NoError(t, err)
+		parallel, err := ParallelRecursiveFiles(context.Background(), pairPath, id, 4)
+		require.NoError(t, err)
+
+		assert.Equal(t, entryNames(serial), entryNames(parallel), id)
+	}
+
+	_, err := ParallelRecursiveFiles(context.Background(), filepath.Join(root, "doesNotExist"), "doesNotExist", 4)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+// entryNames returns the names of the entries of each directory, so listings can be compared
+func entryNames(tree map[string][]fs.DirEntry) map[string][]string {
+	names := map[string][]string{}
+	for dir, entries := range tree {
+		names[dir] = []string{}
+		for _, entry := range entries {
+			names[dir] = append(names[dir], entry.Name())
+		}
+		sort.Strings(names[dir])
+	}
+	return names
+}
+
+// TestCopyFolderJobs tests that a folder copied with more than one job is copied whole, on the local file
+// system, through another file system, and to S3
+func TestCopyFolderJobs(t *testing.T) {
+	var paths []string
+	for i := range 50 {
+		paths = append(paths, filepath.Join("object", "dir"+strconv.Itoa(i%5), "file"+strconv.Itoa(i)+".txt"))
+	}
+	paths = append(paths, filepath.Join("object", ".hidden", "secret.txt"), filepath.Join("object", "empty")+"/")
+
+	opts := CopyOptions{Jobs: 8}
+
+	t.Run("Local", func(t *testing.T) {
+		src, dest := t.TempDir(), t.TempDir()
+		for _, path := range paths {
+			createPath(t, src, path)
+		}
+
+		copied, err := CopyFileOrFolder(context.Background(), filepath.Join(src, "object"), dest, false, opts)
+		require.NoError(t, err)
+		for _, path := range paths {
+			_, err := os.Stat(filepath.Join(copied, "..", path))
+			assert.NoError(t, err, path)
+		}
+	})
+
+	t.Run("File system", func(t *testing.T) {
+		fsys := afero.NewMemMapFs()
+		for _, path := range paths {
+			if strings.HasSuffix(path, "/") {
+				require.NoError(t, fsys.MkdirAll(filepath.Join("/src", path), 0755))
+			} else {
+				require.NoError(t, afero.WriteFile(fsys, filepath.Join("/src", path), []byte("x"), 0644))
+			}
+		}
+
+		copied, err := New(fsys, "/").CopyFileOrFolder(context.Background(), "/src/object", "/dest/", false, opts)
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join("/dest", "object"), copied)
+		for _, path := range paths {
+			exists, err := afero.Exists(fsys, filepath.Join("/dest", path))
+			require.NoError(t, err)
+			assert.True(t, exists, path)
+		}
+	})
+
+	t.Run("S3", func(t *testing.T) {
+		fake := pttest.NewFakeS3()
+		useFakeS3(t, fake)
+
+		src := t.TempDir()
+		for _, path := range paths {
+			createPath(t, src, path)
+		}
+
+		_, err := CopyFileOrFolder(context.Background(), filepath.Join(src, "object"), bucket+"/pt/", false, opts)
+		require.NoError(t, err)
+		for _, path := range paths {
+			key := "pt/" + filepath.ToSlash(path)
+			assert.Contains(t, fake.Keys("bucket"), key)
+		}
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		src, dest := t.TempDir(), t.TempDir()
+		for _, path := range paths {
+			createPath(t, src, path)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := New(afero.NewBasePathFs(afero.NewOsFs(), "/"), "/").CopyFileOrFolder(ctx, filepath.Join(src, "object"),
+			dest+"/", false, opts)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.NoDirExists(t, filepath.Join(dest, "object"))
+	})
+}
+
+// TestCopyInKernel tests that a file copied in the kernel a chunk at a time is copied whole, and that
+// the copy stops once the context is canceled
+func TestCopyInKernel(t *testing.T) {
+	content := make([]byte, 3*4096+123)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+
+	fs := afero.NewOsFs()
+	src := pttest.CreateTempFile(t, fs, content)
+	dest := filepath.Join(pttest.CreateTempDir(t, fs), "copied")
+
+	in, err := os.Open(src)
+	require.NoError(t, err)
+	defer in.Close()
+
+	out, err := os.Create(dest)
+	require.NoError(t, err)
+	defer out.Close()
+
+	// The size of the file is a whole number of chunks and then some, or exactly one chunk
+	for _, chunkSize := range []int64{1000, int64(len(content))} {
+		_, err = in.Seek(0, io.SeekStart)
+		require.NoError(t, err)
+		require.NoError(t, out.Truncate(0))
+		_, err = out.Seek(0, io.SeekStart)
+		require.NoError(t, err)
+
+		require.NoError(t, copyInKernel(context.Background(), out, in, chunkSize))
+
+		copied, err := os.ReadFile(dest)
+		require.NoError(t, err)
+		assert.Equal(t, content, copied, "chunk size: %d", chunkSize)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, copyInKernel(ctx, out, in, 1000), context.Canceled)
+}
+
 // TestCopyFolder tests copying a directory into another directory
 func TestCopyFolder(t *testing.T) {
 	testFolders := []struct {
@@ -1061,27 +1512,27 @@ func TestCopyFolder(t *testing.T) {
 		t.Run(test.testName, func(t *testing.T) {
 			srcFolder := "folder"
 
-			dirSrc := testutils.CreateTempDir(t, fs)
-			dirDest := testutils.CreateTempDir(t, fs)
+			dirSrc := pttest.CreateTempDir(t, fs)
+			dirDest := pttest.CreateTempDir(t, fs)
 
-			dirSrc = testutils.CreateDirInDir(t, fs, dirSrc, srcFolder)
-			_ = testutils.CreateFileInDir(t, dirSrc, "file.txt")
+			dirSrc = pttest.CreateDirInDir(t, fs, dirSrc, srcFolder)
+			_ = pttest.CreateFileInDir(t, dirSrc, "file.txt")
 
 			if test.changeFolderName {
 				dirDest = filepath.Join(dirDest, test.folderName)
 			} else {
-				dirDest = testutils.CreateDirInDir(t, fs, dirDest, test.folderName)
+				dirDest = pttest.CreateDirInDir(t, fs, dirDest, test.folderName)
 			}
 
 			if strings.HasSuffix(test.folderName, string(os.PathSeparator)) {
 				dirDest += string(os.PathSeparator)
 			}
 
-			finalDest, err := CopyFileOrFolder(dirSrc, dirDest, test.overwrite)
+			finalDest, err := CopyFileOrFolder(context.Background(), dirSrc, dirDest, test.overwrite, CopyOptions{})
 			assert.ErrorIs(t, err, test.expectError, "Expected CopyFilrOrFolder to return %v", err)
 
 			if !test.overwrite {
-				finalDest, err = CopyFileOrFolder(dirSrc, dirDest, test.overwrite)
+				finalDest, err = CopyFileOrFolder(context.Background(), dirSrc, dirDest, test.overwrite, CopyOptions{})
 				assert.ErrorIs(t, err, test.expectError)
 			}
 			exists, err := afero.DirExists(fs, finalDest)
@@ -1145,7 +1596,7 @@ func TestGetUniqueDestination(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			// Create a temporary directory
-			tempDir := testutils.CreateTempDir(t, fs)
+			tempDir := pttest.CreateTempDir(t, fs)
 
 			// Define the destination file path
 			destPath := filepath.Join(tempDir, "file.txt")
@@ -1169,6 +1620,37 @@ func TestGetUniqueDestination(t *testing.T) {
 	}
 }
 
+// TestDestinations tests that the paths a copy or an archive would be written to are found without writing them
+func TestDestinations(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "a5388")
+	dest := filepath.Join(dir, "dest")
+	require.NoError(t, os.MkdirAll(src, 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dest, "a5388"), 0755))
+
+	// A directory destination gets the source in it, with a unique name unless it is overwritten
+	copyDest, err := CopyDestination(context.Background(), src, dest, false)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dest, "a5388.1"), copyDest)
+
+	copyDest, err = CopyDestination(context.Background(), src, dest, true)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dest, "a5388"), copyDest)
+
+	_, err = CopyDestination(context.Background(), filepath.Join(dir, "missing"), dest, false)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dest, "ark+=a5388.zip"), nil, 0644))
+	assert.Equal(t, filepath.Join(dest, "ark+=a5388.tgz"), ArchiveDestination(src, dest, prefix, TgzFormat, false))
+	assert.Equal(t, filepath.Join(dest, "ark+=a5388.1.zip"), ArchiveDestination(src, dest, prefix, ZipFormat, false))
+	assert.Equal(t, filepath.Join(dest, "ark+=a5388.tzst"), ArchiveDestination(src, dest, prefix, TzstFormat, true))
+
+	// Nothing was written
+	entries, err := os.ReadDir(dest)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
 // TestTarGz tests the TarGz function with different test cases using tabular testing and afero.
 func TestTarGz(t *testing.T) {
 	// Test cases for the TarGz function
@@ -1214,20 +1696,20 @@ func TestTarGz(t *testing.T) {
 	// Loop through each test case
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			dirSrc := testutils.CreateTempDir(t, fs)
-			dirDest := testutils.CreateTempDir(t, fs)
+			dirSrc := pttest.CreateTempDir(t, fs)
+			dirDest := pttest.CreateTempDir(t, fs)
 
-			_ = testutils.CreateFileInDir(t, dirSrc, "file.txt")
+			_ = pttest.CreateFileInDir(t, dirSrc, "file.txt")
 
 			// Call the TarGz function
-			err := TarGz(dirSrc, dirDest, test.prefix, test.overwrite)
+			err := TarGz(context.Background(), dirSrc, dirDest, test.prefix, test.overwrite, ArchiveOptions{})
 			assert.ErrorIs(t, err, test.expectErr, "There was an Error with TarGZ")
 
 			tarDest := filepath.Join(dirDest, test.encodedPre+filepath.Base(dirSrc)+".tgz")
 
 			// Check if overwrite behavior was respected
 			if !test.overwrite {
-				err = TarGz(dirSrc, dirDest, test.prefix, test.overwrite)
+				err = TarGz(context.Background(), dirSrc, dirDest, test.prefix, test.overwrite, ArchiveOptions{})
 				assert.ErrorIs(t, err, test.expectErr, "There was an Error with TarGZ")
 
 				tarDest = filepath.Join(dirDest, test.encodedPre+filepath.Base(dirSrc)+".1"+".tgz")
@@ -1240,6 +1722,29 @@ func TestTarGz(t *testing.T) {
 	}
 }
 
+// TestCanceled tests if a canceled copy or archive leaves no partial destination behind
+func TestCanceled(t *testing.T) {
+	fs := afero.NewOsFs()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	dirSrc := pttest.CreateTempDir(t, fs)
+	dirDest := pttest.CreateTempDir(t, fs)
+	_ = pttest.CreateFileInDir(t, dirSrc, "file.txt")
+
+	_, err := CopyFileOrFolder(ctx, dirSrc, dirDest, false, CopyOptions{})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	err = TarGz(ctx, dirSrc, dirDest, "", false, ArchiveOptions{})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// Neither the copied folder nor the .tgz should be left in the destination
+	entries, err := afero.ReadDir(fs, dirDest)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "A partial destination was left behind")
+}
+
 func TestUnTarGz(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -1278,23 +1783,23 @@ func TestUnTarGz(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 
-			dirDest := testutils.CreateTempDir(t, fs)
-			dirDest = testutils.CreateDirInDir(t, fs, dirDest, test.srcID)
+			dirDest := pttest.CreateTempDir(t, fs)
+			dirDest = pttest.CreateDirInDir(t, fs, dirDest, test.srcID)
 
 			//Create the .tgz in a temporary directory
-			tempDir := testutils.CreateTempDir(t, fs)
-			dirTGZ := testutils.CreateDirInDir(t, fs, tempDir, test.tgzID)
+			tempDir := pttest.CreateTempDir(t, fs)
+			dirTGZ := pttest.CreateDirInDir(t, fs, tempDir, test.tgzID)
 
 			dirSrcTGZ := filepath.Join(tempDir, test.tgzID+".tgz")
 
 			fileNames := []string{"file.txt", "file1.txt", "file2.txt"}
 			for _, fileName := range fileNames {
-				_ = testutils.CreateFileInDir(t, dirTGZ, fileName)
+				_ = pttest.CreateFileInDir(t, dirTGZ, fileName)
 			}
 			sourceFolders := []string{dirTGZ}
 
 			if test.addFolder {
-				pathToFolder := testutils.CreateDirInDir(t, fs, tempDir, "extraFolder")
+				pathToFolder := pttest.CreateDirInDir(t, fs, tempDir, "extraFolder")
 				sourceFolders = append(sourceFolders, pathToFolder)
 			}
 
@@ -1304,9 +1809,371 @@ func TestUnTarGz(t *testing.T) {
 			if err := tgz.Archive(sourceFolders, dirSrcTGZ); err != nil {
 				t.Fatalf("There was an error archiving the folder %v", err)
 			}
-			err := UnTarGz(dirSrcTGZ, dirDest)
+			err := UnTarGz(context.Background(), dirSrcTGZ, dirDest)
 
 			assert.ErrorIs(t, err, test.expectErr)
 		})
 	}
 }
+
+// benchmarkPairtree builds a pairtree with one object of files for the benchmarks and returns the
+// pairtree root and the object's pairpath
+func benchmarkPairtree(b *testing.B, files, size int) (string, string) {
+	b.Helper()
+
+	builder := pttest.NewPairtreeBuilder().
+		WithObjects(pttest.ObjectSpec{Count: 1, FilesPerObject: files, FileSize: size})
+	ptRoot := builder.Build(b, afero.NewOsFs(), b.TempDir())
+
+	return ptRoot, builder.ObjectPath(ptRoot, builder.IDs()[0])
+}
+
+// BenchmarkCreatePP measures creating the pairpath of an ID
+func BenchmarkCreatePP(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		if _, err := CreatePP("ark:/13030/c8xk8m7h", "root", prefix); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkRecursiveFiles measures listing an object with many files
+func BenchmarkRecursiveFiles(b *testing.B) {
+	_, pairPath := benchmarkPairtree(b, 1000, 0)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := RecursiveFiles(pairPath, filepath.Base(pairPath)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkCopyFolder measures copying an object out of the pairtree
+func BenchmarkCopyFolder(b *testing.B) {
+	_, pairPath := benchmarkPairtree(b, 100, 64<<10)
+	b.SetBytes(100 * 64 << 10)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := CopyFileOrFolder(context.Background(), pairPath, b.TempDir(), false, CopyOptions{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkTarGz measures archiving an object
+func BenchmarkTarGz(b *testing.B) {
+	_, pairPath := benchmarkPairtree(b, 100, 64<<10)
+	b.SetBytes(100 * 64 << 10)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := TarGz(context.Background(), pairPath, b.TempDir(), prefix, false, ArchiveOptions{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkDeletePairtreeItem measures deleting an object
+func BenchmarkDeletePairtreeItem(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		_, pairPath := benchmarkPairtree(b, 100, 0)
+		b.StartTimer()
+
+		if err := DeletePairtreeItem(pairPath); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// TestInMemory tests that a Pairtree works with a pairtree on a file system in memory
+func TestInMemory(t *testing.T) {
+	fsys := afero.NewMemMapFs()
+	pttest.StandardPairtree().WithFile("ark:/b5488", "folder/content.txt", []byte("content")).Build(t, fsys, "/pt")
+	pt, err := OpenFs(fsys, "/pt")
+	require.NoError(t, err)
+	assert.Equal(t, prefix, pt.Prefix())
+
+	require.NoError(t, pt.CheckPTVer())
+	ptPrefix, err := pt.GetPrefix()
+	require.NoError(t, err)
+	assert.Equal(t, prefix, ptPrefix)
+
+	var ids []string
+	require.NoError(t, pt.WalkObjects(func(id, _ string) error {
+		ids = append(ids, id)
+		return nil
+	}))
+	assert.Equal(t, []string{"ark:/a5388", "ark:/a5488", "ark:/a54892", "ark:/b5488"}, ids)
+
+	validation, err := pt.Validate()
+	require.NoError(t, err)
+	assert.Equal(t, 4, validation.Objects)
+	assert.Empty(t, validation.Violations)
+
+	suggestions, err := pt.SuggestIDs("ark:/a5389", 5)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ark:/a5388", "ark:/a5488"}, suggestions[:2])
+
+	objPath, err := pt.CreatePP("ark:/b5488")
+	require.NoError(t, err)
+	files, err := pt.RecursiveFiles(objPath, "ark:/b5488")
+	require.NoError(t, err)
+	assert.Len(t, files[filepath.Join(objPath, "folder")], 4)
+
+	// An object is copied, archived, and extracted without touching the disk
+	dest, err := pt.CopyFileOrFolder(context.Background(), objPath, "/copies/", false, CopyOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("/copies", "b5488"), dest)
+	content, err := afero.ReadFile(fsys, filepath.Join(dest, "folder", "content.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "content", string(content))
+
+	require.NoError(t, pt.TarGz(context.Background(), objPath, "/archives", false, ArchiveOptions{}))
+	archive := filepath.Join("/archives", ArchiveName(prefix, objPath, tgzExt))
+	require.NoError(t, pt.DeletePairtreeItem(objPath))
+	require.NoError(t, pt.UnTarGz(context.Background(), archive, objPath))
+
+	content, err = afero.ReadFile(fsys, filepath.Join(objPath, "folder", "content.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "content", string(content))
+
+	// Nothing was written to the local file system
+	_, err = os.Stat("/pt")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+// TestOpen tests that Open reads the prefix and version once and that the methods taking IDs use them
+func TestOpen(t *testing.T) {
+	fsys := afero.NewMemMapFs()
+	pttest.StandardPairtree().WithFile("ark:/b5488", "folder/content.txt", []byte("content")).Build(t, fsys, "/pt")
+
+	pt, err := OpenFs(fsys, "/pt")
+	require.NoError(t, err)
+	assert.Equal(t, prefix, pt.Prefix())
+	assert.Equal(t, pttest.VersionSpec, pt.Version())
+
+	pairPath, err := pt.PairPath("ark:/b5488")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("/pt", "pairtree_root", "b5", "48", "8", "b5488"), pairPath)
+	id, err := pt.ID(filepath.Join(pairPath, "folder"))
+	require.NoError(t, err)
+	assert.Equal(t, "ark:/b5488", id)
+
+	found, err := pt.Exists("ark:/b5488")
+	require.NoError(t, err)
+	assert.True(t, found)
+	found, err = pt.Exists("ark:/missing")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	for name, expected := range map[string]string{"b5488": "ark:/b5488", "ark+=b5488": "ark:/b5488", "a=b": "ark:/a/b"} {
+		id, err := pt.NameID(name)
+		require.NoError(t, err)
+		assert.Equal(t, expected, id)
+	}
+	_, err = pt.NameID("bad^zz")
+	assert.ErrorIs(t, err, error_msgs.Err26)
+
+	listing, err := pt.Ls("ark:/b5488", "", ListOptions{Recursive: true})
+	require.NoError(t, err)
+	assert.Equal(t, Directory{
+		Name:        pairPath,
+		Directories: []Directory{{Name: "folder", Files: []File{{Name: "content.txt"}, {Name: "innerb5488.txt"}}}},
+		Files:       []File{{Name: "outerb5488.txt"}},
+	}, listing)
+
+	// Copies go into an object, which is created, and out of one
+	require.NoError(t, afero.WriteFile(fsys, "/in/file.txt", []byte("in"), 0644))
+	dest, err := pt.Copy(context.Background(), "/in/file.txt", "ark:/c1", false, CopyOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("/pt", "pairtree_root", "c1", "c1", "file.txt"), dest)
+
+	dest, err = pt.Copy(context.Background(), "ark:/b5488", "/out/", false, CopyOptions{})
+	require.NoError(t, err)
+	content, err := afero.ReadFile(fsys, filepath.Join(dest, "folder", "content.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "content", string(content))
+
+	_, err = pt.Copy(context.Background(), "/in/file.txt", "/elsewhere", false, CopyOptions{})
+	assert.ErrorIs(t, err, error_msgs.Err10)
+
+	require.NoError(t, pt.Delete("ark:/b5488", "folder"))
+	exists, err := afero.DirExists(fsys, filepath.Join(pairPath, "folder"))
+	require.NoError(t, err)
+	assert.False(t, exists)
+	assert.ErrorIs(t, pt.Delete("ark:/missing", ""), fs.ErrNotExist)
+
+	// A pairtree without a prefix file has the pt:// prefix, and one without a version file is not opened
+	pttest.StandardPairtree().WithPrefix("").Build(t, fsys, "/noprefix")
+	pt, err = OpenFs(fsys, "/noprefix")
+	require.NoError(t, err)
+	assert.Equal(t, PtPrefix, pt.Prefix())
+
+	require.NoError(t, fsys.Remove(filepath.Join("/noprefix", verDir)))
+	_, err = OpenFs(fsys, "/noprefix")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+// TestRename tests that an object is moved to the pairpath of another ID, or of another pairtree, and
+// copied and deleted when it can not be renamed across devices
+func TestRename(t *testing.T) {
+	for _, renameErr := range []error{nil, syscall.EXDEV} {
+		// Only the object that is moved is on another device, so the staged copy can still replace the
+		// object at the new pairpath
+		a5388 := filepath.Join("/pt", "pairtree_root", "a5", "38", "8", "a5388")
+		fsys := pttest.NewFaultFs(afero.NewMemMapFs()).
+			FailRenameOf(filepath.Join("/pt", "pairtree_root", "b5", "48", "8", "b5488"), renameErr)
+		pttest.StandardPairtree().WithFile("ark:/b5488", "folder/content.txt", []byte("content")).Build(t, fsys, "/pt")
+
+		pt, err := OpenFs(fsys, "/pt")
+		require.NoError(t, err)
+
+		// The object replaces one that is already at the new ID
+		require.NoError(t, pt.Rename(context.Background(), "ark:/b5488", "ark:/a5388", CopyOptions{}))
+		fsys.FailRenameOf(a5388, renameErr)
+		require.NoError(t, pt.Rename(context.Background(), "ark:/a5388", "ark:/c1234", CopyOptions{}))
+
+		newPath := filepath.Join("/pt", "pairtree_root", "c1", "23", "4", "c1234")
+		content, err := afero.ReadFile(fsys, filepath.Join(newPath, "folder", "content.txt"))
+		require.NoError(t, err, renameErr)
+		assert.Equal(t, "content", string(content))
+
+		for _, id := range []string{"ark:/b5488", "ark:/a5388"} {
+			found, err := pt.Exists(id)
+			require.NoError(t, err)
+			assert.False(t, found, id)
+		}
+
+		assert.ErrorIs(t, pt.Rename(context.Background(), "ark:/missing", "ark:/c5678", CopyOptions{}), fs.ErrNotExist)
+
+		// No staging directories are left beside the new pairpath
+		entries, err := afero.ReadDir(fsys, filepath.Dir(newPath))
+		require.NoError(t, err)
+		for _, entry := range entries {
+			assert.False(t, IsHidden(entry.Name()), entry.Name())
+		}
+
+		// The shorties of the old pairpaths are removed up to the ones that lead to other objects
+		for _, dir := range []string{filepath.Join("/pt", "pairtree_root", "b5"), filepath.Join("/pt", "pairtree_root", "a5", "38")} {
+			_, err := fsys.Stat(dir)
+			assert.ErrorIs(t, err, fs.ErrNotExist, dir)
+		}
+		_, err = fsys.Stat(filepath.Join("/pt", "pairtree_root", "a5", "48", "8", "a5488"))
+		assert.NoError(t, err)
+	}
+
+	// An object is moved to another pairtree at the same pairpath under it
+	fsys := afero.NewMemMapFs()
+	pttest.StandardPairtree().Build(t, fsys, "/staging")
+	pttest.NewPairtreeBuilder().Build(t, fsys, "/prod")
+	staging, err := OpenFs(fsys, "/staging")
+	require.NoError(t, err)
+	prod, err := OpenFs(fsys, "/prod")
+	require.NoError(t, err)
+
+	require.NoError(t, staging.Move(context.Background(), "ark:/b5488", prod, "ark:/b5488", CopyOptions{}))
+	found, err := prod.Exists("ark:/b5488")
+	require.NoError(t, err)
+	assert.True(t, found)
+	found, err = staging.Exists("ark:/b5488")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	// Other errors renaming the object are returned
+	fsys = pttest.NewFaultFs(afero.NewMemMapFs()).FailRename(syscall.EACCES)
+	pttest.StandardPairtree().Build(t, fsys, "/pt")
+	pt, err := OpenFs(fsys, "/pt")
+	require.NoError(t, err)
+	assert.ErrorIs(t, pt.Rename(context.Background(), "ark:/b5488", "ark:/c1234", CopyOptions{}), syscall.EACCES)
+}
+
+// TestFailedRename tests that an object that can not be copied across devices, or whose move is canceled,
+// is left where it was and does not replace the object at the new ID
+func TestFailedRename(t *testing.T) {
+	oldPath := filepath.Join("/pt", "pairtree_root", "b5", "48", "8", "b5488")
+	newPath := filepath.Join("/pt", "pairtree_root", "a5", "38", "8", "a5388")
+
+	fsys := pttest.NewFaultFs(afero.NewMemMapFs()).FailRenameOf(oldPath, syscall.EXDEV)
+	pttest.StandardPairtree().WithFile("ark:/b5488", "content.txt", []byte("content")).Build(t, fsys, "/pt")
+	fsys.FailWrite(fsys.Writes()+1, syscall.ENOSPC)
+
+	pt, err := OpenFs(fsys, "/pt")
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, pt.Rename(context.Background(), "ark:/b5488", "ark:/a5388", CopyOptions{}), syscall.ENOSPC)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, pt.Rename(ctx, "ark:/b5488", "ark:/a5388", CopyOptions{}), context.Canceled)
+
+	for _, path := range []string{filepath.Join(oldPath, "outerb5488.txt"), filepath.Join(newPath, "a5388.txt")} {
+		exists, err := afero.Exists(fsys, path)
+		require.NoError(t, err)
+		assert.True(t, exists, path)
+	}
+
+	entries, err := afero.ReadDir(fsys, filepath.Dir(newPath))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "a5388", entries[0].Name())
+}
+
+// TestReplace tests that the destination is put back when the staged source can not replace it
+func TestReplace(t *testing.T) {
+	dir := t.TempDir()
+	staged, dest := filepath.Join(dir, "staged"), filepath.Join(dir, "dest")
+	require.NoError(t, os.WriteFile(dest, []byte("dest"), 0644))
+
+	assert.Error(t, Replace(staged, dest, dest+".old"))
+	assert.FileExists(t, dest)
+	assert.NoFileExists(t, dest+".old")
+
+	require.NoError(t, os.WriteFile(staged, []byte("staged"), 0644))
+	require.NoError(t, Replace(staged, dest, dest+".old"))
+	content, err := os.ReadFile(dest)
+	require.NoError(t, err)
+	assert.Equal(t, "staged", string(content))
+
+	// A destination that does not exist is not replaced
+	assert.NoError(t, Replace(dest, filepath.Join(dir, "new"), filepath.Join(dir, "new.old")))
+	assert.FileExists(t, filepath.Join(dir, "new"))
+}
+
+// TestCanceledWalk tests that the walks of a pairtree stop once their context is canceled
+func TestCanceledWalk(t *testing.T) {
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, afero.NewOsFs())
+	pairPath, err := CreatePP("ark:/b5488", ptRoot, prefix)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = RecursiveFilesCtx(ctx, pairPath, "ark:/b5488")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	err = WalkObjectsCtx(ctx, ptRoot, prefix, func(string, string) error { return nil })
+	assert.ErrorIs(t, err, context.Canceled)
+
+	err = WalkListingCtx(ctx, pairPath, ListOptions{Recursive: true}, func(string, []fs.DirEntry) error { return nil })
+	assert.ErrorIs(t, err, context.Canceled)
+
+	err = WriteListingJSONCtx(ctx, io.Discard, pairPath, ListOptions{Recursive: true})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = ValidateCtx(ctx, ptRoot)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// A walk is stopped between directories when the context is canceled during it
+	ctx, cancel = context.WithCancel(context.Background())
+	var ids []string
+	err = WalkObjectsCtx(ctx, ptRoot, prefix, func(id, _ string) error {
+		ids = append(ids, id)
+		cancel()
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, []string{"ark:/a5388"}, ids)
+}
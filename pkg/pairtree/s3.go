@@ -0,0 +1,428 @@
+package pairtree
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+const (
+	// s3PartSize is the size of the parts a file is uploaded to S3 in, larger files are uploaded as a
+	// multipart upload so that only a part is held in memory at a time
+	s3PartSize = 16 << 20
+	// s3DeleteBatch is the most keys S3 deletes in one request
+	s3DeleteBatch = 1000
+)
+
+// S3Client is the part of the S3 API that pairtrees in S3 are kept with
+type S3Client interface {
+	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+	DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error)
+	CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error)
+	UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error)
+	CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error)
+	AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error)
+}
+
+// NewS3Client creates the client that pairtrees in S3 are reached with, configured from the environment
+// like the AWS CLI with AWS_PROFILE, AWS_REGION, and AWS_ENDPOINT_URL. Tests replace it with a fake.
+var NewS3Client = func(ctx context.Context) (S3Client, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return s3.NewFromConfig(cfg), nil
+}
+
+// s3Storage keeps the files of a pairtree as the objects of an S3 bucket. S3 has no directories, so a
+// directory is the common prefix of the keys of the files in it. A directory that is made is kept as
+// an empty object with the key of the directory and a slash, like the S3 console makes, so that it
+// exists before it has files.
+type s3Storage struct {
+	ctx    context.Context
+	client S3Client
+}
+
+// NewS3Storage creates the storage of pairtrees in S3 that makes its requests with the client and context
+func NewS3Storage(ctx context.Context, client S3Client) Storage {
+	return &s3Storage{ctx: ctx, client: client}
+}
+
+// s3Info is the FileInfo of a file or directory in S3
+type s3Info struct {
+	name    string
+	size    int64
+	modTime time.Time
+	dir     bool
+}
+
+func (i s3Info) Name() string       { return i.name }
+func (i s3Info) Size() int64        { return i.size }
+func (i s3Info) ModTime() time.Time { return i.modTime }
+func (i s3Info) IsDir() bool        { return i.dir }
+func (i s3Info) Sys() any           { return nil }
+
+func (i s3Info) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0755
+	}
+	return 0644
+}
+
+// splitS3 splits an s3:// URL into its bucket and the key it names in the bucket
+func splitS3(p string) (string, string, error) {
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(p, s3Scheme), "/")
+	if bucket == "" {
+		return "", "", &fs.PathError{Op: "parse", Path: p, Err: fs.ErrInvalid}
+	}
+
+	key = strings.TrimPrefix(path.Clean("/"+key), "/")
+	return bucket, key, nil
+}
+
+// dirPrefix returns the prefix of the keys of the files in the directory with the key
+func dirPrefix(key string) string {
+	if key == "" {
+		return ""
+	}
+	return key + "/"
+}
+
+// isS3NotFound reports whether S3 returned the error because there is no object with the key
+func isS3NotFound(err error) bool {
+	var noSuchKey *types.NoSuchKey
+	var notFound *types.NotFound
+	return errors.As(err, &noSuchKey) || errors.As(err, &notFound)
+}
+
+func (s *s3Storage) Stat(p string) (fs.FileInfo, error) {
+	bucket, key, err := splitS3(p)
+	if err != nil {
+		return nil, err
+	}
+
+	if key == "" {
+		return s3Info{name: bucket, dir: true}, nil
+	}
+
+	head, err := s.client.HeadObject(s.ctx, &s3.HeadObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+	if err == nil {
+		return s3Info{name: path.Base(key), size: aws.ToInt64(head.ContentLength), modTime: aws.ToTime(head.LastModified)}, nil
+	} else if !isS3NotFound(err) {
+		return nil, &fs.PathError{Op: "stat", Path: p, Err: err}
+	}
+
+	// A key that is not a file is a directory when there are files under it
+	list, err := s.client.ListObjectsV2(s.ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket), Prefix: aws.String(dirPrefix(key)), MaxKeys: aws.Int32(1),
+	})
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: p, Err: err}
+	}
+	if len(list.Contents) == 0 {
+		return nil, &fs.PathError{Op: "stat", Path: p, Err: fs.ErrNotExist}
+	}
+
+	return s3Info{name: path.Base(key), dir: true}, nil
+}
+
+func (s *s3Storage) ReadDir(p string) ([]fs.DirEntry, error) {
+	var entries []fs.DirEntry
+	err := s.ScanDir(p, func(batch []fs.DirEntry) error {
+		entries = append(entries, batch...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// ScanDir lists the directory a page of keys at a time
+func (s *s3Storage) ScanDir(p string, fn func(entries []fs.DirEntry) error) error {
+	bucket, key, err := splitS3(p)
+	if err != nil {
+		return err
+	}
+
+	prefix := dirPrefix(key)
+	found := false
+
+	pages := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket), Prefix: aws.String(prefix), Delimiter: aws.String("/"),
+	})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(s.ctx)
+		if err != nil {
+			return &fs.PathError{Op: "readdir", Path: p, Err: err}
+		}
+
+		var entries []fs.DirEntry
+		for _, common := range page.CommonPrefixes {
+			name := strings.TrimSuffix(strings.TrimPrefix(aws.ToString(common.Prefix), prefix), "/")
+			entries = append(entries, fs.FileInfoToDirEntry(s3Info{name: name, dir: true}))
+		}
+
+		for _, object := range page.Contents {
+			// A key ending in a slash is the marker of a directory that was made, not a file, and
+			// the marker of this directory means that it exists while it is empty
+			name := strings.TrimPrefix(aws.ToString(object.Key), prefix)
+			if name == "" {
+				found = true
+			}
+			if name == "" || strings.HasSuffix(name, "/") {
+				continue
+			}
+
+			info := s3Info{name: name, size: aws.ToInt64(object.Size), modTime: aws.ToTime(object.LastModified)}
+			entries = append(entries, fs.FileInfoToDirEntry(info))
+		}
+
+		if len(entries) > 0 {
+			found = true
+			if err := fn(entries); err != nil {
+				return err
+			}
+		}
+	}
+
+	if !found && key != "" {
+		return &fs.PathError{Op: "readdir", Path: p, Err: fs.ErrNotExist}
+	}
+
+	return nil
+}
+
+func (s *s3Storage) Open(p string) (io.ReadCloser, error) {
+	bucket, key, err := splitS3(p)
+	if err != nil {
+		return nil, err
+	}
+
+	object, err := s.client.GetObject(s.ctx, &s3.GetObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+	if isS3NotFound(err) {
+		return nil, &fs.PathError{Op: "open", Path: p, Err: fs.ErrNotExist}
+	} else if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: p, Err: err}
+	}
+
+	return object.Body, nil
+}
+
+func (s *s3Storage) Create(p string) (io.WriteCloser, error) {
+	bucket, key, err := splitS3(p)
+	if err != nil {
+		return nil, err
+	}
+
+	return &s3Writer{storage: s, path: p, bucket: bucket, key: key}, nil
+}
+
+// Append reads the file and writes it back with the data added, because objects in S3 can not be
+// appended to. Appends to the same file at the same time can lose one of them.
+func (s *s3Storage) Append(p string, data []byte) error {
+	var existing []byte
+
+	in, err := s.Open(p)
+	if err == nil {
+		existing, err = io.ReadAll(in)
+		in.Close()
+	}
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	out, err := s.Create(p)
+	if err != nil {
+		return err
+	}
+
+	if _, err := out.Write(append(existing, data...)); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
+
+// MkdirAll writes the marker of the directory. The directories it is in exist once it does, since
+// the key of the marker starts with their prefixes.
+func (s *s3Storage) MkdirAll(p string) error {
+	bucket, key, err := splitS3(p)
+	if err != nil || key == "" {
+		return err
+	}
+
+	_, err = s.client.PutObject(s.ctx, &s3.PutObjectInput{
+		Bucket: aws.String(bucket), Key: aws.String(dirPrefix(key)), Body: bytes.NewReader(nil),
+	})
+	if err != nil {
+		return &fs.PathError{Op: "mkdir", Path: p, Err: err}
+	}
+
+	return nil
+}
+
+func (s *s3Storage) RemoveAll(p string) error {
+	bucket, key, err := splitS3(p)
+	if err != nil {
+		return err
+	}
+
+	// The key itself is removed with everything under it, since it may be a file or a directory
+	keys := []string{key}
+
+	pages := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket), Prefix: aws.String(dirPrefix(key)),
+	})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(s.ctx)
+		if err != nil {
+			return &fs.PathError{Op: "remove", Path: p, Err: err}
+		}
+
+		for _, object := range page.Contents {
+			keys = append(keys, aws.ToString(object.Key))
+		}
+	}
+
+	for start := 0; start < len(keys); start += s3DeleteBatch {
+		batch := keys[start:min(start+s3DeleteBatch, len(keys))]
+
+		objects := make([]types.ObjectIdentifier, len(batch))
+		for i, key := range batch {
+			objects[i] = types.ObjectIdentifier{Key: aws.String(key)}
+		}
+
+		deleted, err := s.client.DeleteObjects(s.ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(bucket), Delete: &types.Delete{Objects: objects, Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			return &fs.PathError{Op: "remove", Path: p, Err: err}
+		}
+		if len(deleted.Errors) > 0 {
+			failed := deleted.Errors[0]
+			return &fs.PathError{Op: "remove", Path: s3Scheme + bucket + "/" + aws.ToString(failed.Key),
+				Err: fmt.Errorf("%s: %s", aws.ToString(failed.Code), aws.ToString(failed.Message))}
+		}
+	}
+
+	return nil
+}
+
+// s3Writer writes a file to S3 a part at a time. A file of one part is uploaded when it is closed,
+// and a larger file is a multipart upload that is completed when it is closed.
+type s3Writer struct {
+	storage  *s3Storage
+	path     string
+	bucket   string
+	key      string
+	buf      []byte
+	uploadID *string
+	parts    []types.CompletedPart
+	err      error
+}
+
+func (w *s3Writer) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	w.buf = append(w.buf, p...)
+	for len(w.buf) >= s3PartSize && w.err == nil {
+		w.err = w.uploadPart(w.buf[:s3PartSize])
+		w.buf = w.buf[s3PartSize:]
+	}
+
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	return len(p), nil
+}
+
+// uploadPart uploads the next part of the file, starting the multipart upload with the first part
+func (w *s3Writer) uploadPart(part []byte) error {
+	s := w.storage
+
+	if w.uploadID == nil {
+		upload, err := s.client.CreateMultipartUpload(s.ctx, &s3.CreateMultipartUploadInput{
+			Bucket: aws.String(w.bucket), Key: aws.String(w.key),
+		})
+		if err != nil {
+			return &fs.PathError{Op: "write", Path: w.path, Err: err}
+		}
+		w.uploadID = upload.UploadId
+	}
+
+	number := aws.Int32(int32(len(w.parts) + 1))
+	uploaded, err := s.client.UploadPart(s.ctx, &s3.UploadPartInput{
+		Bucket: aws.String(w.bucket), Key: aws.String(w.key), UploadId: w.uploadID, PartNumber: number,
+		Body: bytes.NewReader(part),
+	})
+	if err != nil {
+		return &fs.PathError{Op: "write", Path: w.path, Err: err}
+	}
+
+	w.parts = append(w.parts, types.CompletedPart{ETag: uploaded.ETag, PartNumber: number})
+	return nil
+}
+
+// Close uploads what has not been uploaded of the file. A multipart upload that fails is aborted
+// so that its parts are not kept.
+func (w *s3Writer) Close() error {
+	s := w.storage
+
+	if w.uploadID == nil && w.err == nil {
+		_, err := s.client.PutObject(s.ctx, &s3.PutObjectInput{
+			Bucket: aws.String(w.bucket), Key: aws.String(w.key), Body: bytes.NewReader(w.buf),
+		})
+		if err != nil {
+			return &fs.PathError{Op: "write", Path: w.path, Err: err}
+		}
+		return nil
+	}
+
+	if w.err == nil && len(w.buf) > 0 {
+		w.err = w.uploadPart(w.buf)
+	}
+
+	if w.err == nil {
+		_, err := s.client.CompleteMultipartUpload(s.ctx, &s3.CompleteMultipartUploadInput{
+			Bucket: aws.String(w.bucket), Key: aws.String(w.key), UploadId: w.uploadID,
+			MultipartUpload: &types.CompletedMultipartUpload{Parts: w.parts},
+		})
+		if err == nil {
+			return nil
+		}
+		w.err = &fs.PathError{Op: "write", Path: w.path, Err: err}
+	}
+
+	if w.uploadID != nil {
+		// The context of the storage may be canceled, which is why the upload failed
+		_, abortErr := s.client.AbortMultipartUpload(context.WithoutCancel(s.ctx), &s3.AbortMultipartUploadInput{
+			Bucket: aws.String(w.bucket), Key: aws.String(w.key), UploadId: w.uploadID,
+		})
+		return errors.Join(w.err, abortErr)
+	}
+
+	return w.err
+}
@@ -0,0 +1,183 @@
+package pairtree
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"testing"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pttest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const bucket = "s3://bucket"
+
+// useFakeS3 makes the fake the S3 client of the package for the test
+func useFakeS3(t *testing.T, fake *pttest.FakeS3) {
+	newClient := NewS3Client
+	NewS3Client = func(context.Context) (S3Client, error) { return fake, nil }
+	t.Cleanup(func() { NewS3Client = newClient })
+}
+
+// readStorageFile returns the content of the file in the storage
+func readStorageFile(t *testing.T, storage Storage, path string) string {
+	in, err := storage.Open(path)
+	require.NoError(t, err)
+	defer in.Close()
+
+	data, err := io.ReadAll(in)
+	require.NoError(t, err)
+	return string(data)
+}
+
+// TestJoinPath tests that s3:// URLs are joined with slashes and local paths like filepath.Join
+func TestJoinPath(t *testing.T) {
+	assert.Equal(t, "s3://bucket/pt/pairtree_root/a5", JoinPath("s3://bucket/pt/", "pairtree_root", "a5"))
+	assert.Equal(t, "s3://bucket/a/c", JoinPath("s3://bucket/a", "b/../c", ""))
+	assert.Equal(t, filepath.Join("pt", "pairtree_root"), JoinPath("pt", "pairtree_root"))
+	assert.True(t, IsS3("s3://bucket"))
+	assert.False(t, IsS3(filepath.Join("s3:", "bucket")))
+}
+
+// TestJoinSubpath tests that subpaths that are not inside the object are refused
+func TestJoinSubpath(t *testing.T) {
+	pairPath := filepath.Join("pt", "pairtree_root", "a5", "38", "8", "a5388")
+
+	for _, subpath := range []string{"", "folder/inner.txt", "/folder", "folder/..inner", "..."} {
+		path, err := JoinSubpath(pairPath, subpath)
+		require.NoError(t, err, subpath)
+		assert.Equal(t, filepath.Join(pairPath, subpath), path)
+	}
+
+	for _, subpath := range []string{"..", "../../..", "folder/../../b5488", "folder/..", ".", "./"} {
+		_, err := JoinSubpath(pairPath, subpath)
+		assert.ErrorIs(t, err, error_msgs.Err46, subpath)
+	}
+
+	_, err := JoinSubpath("s3://bucket/pt/pairtree_root/a5/38/8/a5388", "../../..")
+	assert.ErrorIs(t, err, error_msgs.Err46)
+}
+
+// TestS3Storage tests that the files and directories of a pairtree in S3 are read and written like local ones
+func TestS3Storage(t *testing.T) {
+	fake := pttest.NewFakeS3()
+	fake.MaxKeys = 2
+	storage := NewS3Storage(context.Background(), fake)
+
+	for _, path := range []string{"a.txt", "b/c.txt", "b/d.txt", "b/e/f.txt", "g.txt"} {
+		out, err := storage.Create(JoinPath(bucket, "pt", path))
+		require.NoError(t, err)
+		_, err = out.Write([]byte(path))
+		require.NoError(t, err)
+		require.NoError(t, out.Close())
+	}
+
+	info, err := storage.Stat(bucket + "/pt/b/c.txt")
+	require.NoError(t, err)
+	assert.False(t, info.IsDir())
+	assert.Equal(t, int64(len("b/c.txt")), info.Size())
+
+	info, err = storage.Stat(bucket + "/pt/b")
+	require.NoError(t, err)
+	assert.True(t, info.IsDir())
+
+	_, err = storage.Stat(bucket + "/pt/missing")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	// The listing is paged, so the entries of the directory are collected across the pages
+	entries, err := storage.ReadDir(bucket + "/pt/")
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	assert.Equal(t, []string{"a.txt", "b", "g.txt"}, names)
+	assert.True(t, entries[1].IsDir())
+
+	_, err = storage.ReadDir(bucket + "/pt/missing")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	assert.Equal(t, "b/e/f.txt", readStorageFile(t, storage, bucket+"/pt/b/e/f.txt"))
+	_, err = storage.Open(bucket + "/pt/missing")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	require.NoError(t, storage.Append(bucket+"/pt/a.txt", []byte(" more")))
+	require.NoError(t, storage.Append(bucket+"/pt/new.txt", []byte("new")))
+	assert.Equal(t, "a.txt more", readStorageFile(t, storage, bucket+"/pt/a.txt"))
+	assert.Equal(t, "new", readStorageFile(t, storage, bucket+"/pt/new.txt"))
+
+	// A directory that is made exists while it is empty
+	require.NoError(t, storage.MkdirAll(bucket+"/pt/empty"))
+	entries, err = storage.ReadDir(bucket + "/pt/empty")
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	require.NoError(t, storage.RemoveAll(bucket+"/pt/b"))
+	require.NoError(t, storage.RemoveAll(bucket+"/pt/g.txt"))
+	assert.Equal(t, []string{"pt/a.txt", "pt/empty/", "pt/new.txt"}, fake.Keys("bucket"))
+}
+
+// TestS3Multipart tests that a file larger than a part is uploaded in parts and that a failed upload is aborted
+func TestS3Multipart(t *testing.T) {
+	fake := pttest.NewFakeS3()
+	storage := NewS3Storage(context.Background(), fake)
+	data := bytes.Repeat([]byte("0123456789abcdef"), s3PartSize/16*2+100)
+
+	out, err := storage.Create(bucket + "/large.bin")
+	require.NoError(t, err)
+	_, err = io.Copy(out, bytes.NewReader(data))
+	require.NoError(t, err)
+	require.NoError(t, out.Close())
+
+	object, ok := fake.Object("bucket", "large.bin")
+	require.True(t, ok)
+	assert.Equal(t, data, object)
+	assert.Zero(t, fake.Uploads())
+
+	// The upload is canceled with the context, and its parts are not kept
+	ctx, cancel := context.WithCancel(context.Background())
+	storage = NewS3Storage(ctx, fake)
+	out, err = storage.Create(bucket + "/canceled.bin")
+	require.NoError(t, err)
+	_, err = out.Write(data[:s3PartSize])
+	require.NoError(t, err)
+	cancel()
+	_, err = out.Write(data[s3PartSize:])
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorIs(t, out.Close(), context.Canceled)
+
+	_, ok = fake.Object("bucket", "canceled.bin")
+	assert.False(t, ok)
+	assert.Zero(t, fake.Uploads())
+}
+
+// TestCopyS3 tests that an object is copied from the local file system to S3 and back
+func TestCopyS3(t *testing.T) {
+	fake := pttest.NewFakeS3()
+	useFakeS3(t, fake)
+
+	src := t.TempDir()
+	for _, path := range []string{"object/a.txt", "object/sub/b.txt"} {
+		createPath(t, src, path)
+	}
+
+	dest, err := CopyFileOrFolder(context.Background(), filepath.Join(src, "object"), bucket+"/pt/", false, CopyOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, bucket+"/pt/object", dest)
+	assert.Equal(t, []string{"pt/object/", "pt/object/a.txt", "pt/object/sub/", "pt/object/sub/b.txt"}, fake.Keys("bucket"))
+
+	// A copy that would overwrite is given a unique name
+	dest, err = CopyFileOrFolder(context.Background(), filepath.Join(src, "object", "a.txt"), bucket+"/pt/object", false, CopyOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, bucket+"/pt/object/a.1.txt", dest)
+
+	back := t.TempDir()
+	dest, err = CopyFileOrFolder(context.Background(), bucket+"/pt/object", back, false, CopyOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(back, "object"), dest)
+	assert.FileExists(t, filepath.Join(back, "object", "sub", "b.txt"))
+}
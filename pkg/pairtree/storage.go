@@ -0,0 +1,395 @@
+package pairtree
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/spf13/afero"
+)
+
+// s3Scheme starts the root of a pairtree that is kept in an S3 bucket, like s3://bucket/prefix
+const s3Scheme = "s3://"
+
+// Storage is where the files of a pairtree are kept. Its paths are the paths the functions of the
+// package are given, a path on the local file system or an s3:// URL for S3.
+type Storage interface {
+	// Stat returns the FileInfo of the file or directory at the path
+	Stat(path string) (fs.FileInfo, error)
+	// ReadDir returns the entries of the directory at the path sorted by name
+	ReadDir(path string) ([]fs.DirEntry, error)
+	// Open opens the file at the path for reading
+	Open(path string) (io.ReadCloser, error)
+	// Create opens the file at the path for writing, creating the directories it is in. The file is
+	// only complete once it is closed without an error.
+	Create(path string) (io.WriteCloser, error)
+	// Append adds the data to the end of the file at the path, creating it if it does not exist
+	Append(path string, data []byte) error
+	// MkdirAll creates the directory at the path and the directories it is in
+	MkdirAll(path string) error
+	// RemoveAll removes the file or directory at the path and everything in it
+	RemoveAll(path string) error
+}
+
+// scanBatch is the most entries of a directory that are read at a time when a directory is scanned
+const scanBatch = 1000
+
+// dirScanner is a Storage that can read a directory a batch of entries at a time
+type dirScanner interface {
+	// ScanDir calls fn with the entries of the directory at the path a batch at a time, in the order
+	// the storage returns them
+	ScanDir(path string, fn func(entries []fs.DirEntry) error) error
+}
+
+// scanDir calls fn with the entries of the directory in batches when the storage can read them that
+// way, and with all of them at once when it can not
+func scanDir(storage Storage, path string, fn func(entries []fs.DirEntry) error) error {
+	if scanner, ok := storage.(dirScanner); ok {
+		return scanner.ScanDir(path, fn)
+	}
+
+	entries, err := storage.ReadDir(path)
+	if err != nil {
+		return err
+	}
+
+	return fn(entries)
+}
+
+// Local is the storage of pairtrees on the local file system
+var Local = NewFsStorage(afero.NewOsFs())
+
+// IsS3 reports whether the path is an s3:// URL of a file or directory in S3
+func IsS3(path string) bool {
+	return strings.HasPrefix(path, s3Scheme)
+}
+
+// StorageFor returns the storage that the path is kept in, which is S3 for an s3:// URL and the local
+// file system for anything else. Requests to S3 are made with the context.
+func StorageFor(ctx context.Context, path string) (Storage, error) {
+	if !IsS3(path) {
+		return Local, nil
+	}
+
+	client, err := NewS3Client(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewS3Storage(ctx, client), nil
+}
+
+// JoinPath joins the elements to the base path like filepath.Join, keeping the scheme of an s3:// URL
+// and separating the elements of its key with slashes
+func JoinPath(base string, elem ...string) string {
+	if !IsS3(base) {
+		return filepath.Join(append([]string{base}, elem...)...)
+	}
+
+	key := strings.TrimPrefix(base, s3Scheme)
+	for _, e := range elem {
+		key = path.Join(key, filepath.ToSlash(e))
+	}
+
+	return s3Scheme + key
+}
+
+// JoinSubpath joins the subpath of an object to its pairpath like JoinPath. A subpath with a .. element,
+// which could reach outside of the object and the pairtree, or that is the object itself is refused with
+// Err46, and an empty subpath is the object.
+func JoinSubpath(pairPath, subpath string) (string, error) {
+	if subpath == "" {
+		return pairPath, nil
+	}
+
+	elems := strings.FieldsFunc(subpath, func(r rune) bool {
+		return r == '/' || r == filepath.Separator
+	})
+	for _, elem := range elems {
+		if elem == ".." {
+			return "", fmt.Errorf("%w: %q", error_msgs.Err46, subpath)
+		}
+	}
+
+	path := JoinPath(pairPath, subpath)
+	if path == JoinPath(pairPath) {
+		return "", fmt.Errorf("%w: %q", error_msgs.Err46, subpath)
+	}
+
+	return path, nil
+}
+
+// basePath returns the last element of the path like filepath.Base, for s3:// URLs as well
+func basePath(p string) string {
+	if IsS3(p) {
+		return path.Base(strings.TrimPrefix(p, s3Scheme))
+	}
+
+	return filepath.Base(p)
+}
+
+// dirPath returns all but the last element of the path like filepath.Dir, for s3:// URLs as well
+func dirPath(p string) string {
+	if IsS3(p) {
+		return s3Scheme + path.Dir(strings.TrimPrefix(p, s3Scheme))
+	}
+
+	return filepath.Dir(p)
+}
+
+// copyBetween copies a file or folder from src to dest when one of them is not on the local file system,
+// like CopyFileOrFolder does on the local file system. Files are copied through their storage.
+func copyBetween(ctx context.Context, src, dest string, overwrite bool, jobs int) (string, error) {
+	srcStorage, err := StorageFor(ctx, src)
+	if err != nil {
+		return "", err
+	}
+
+	destStorage, err := StorageFor(ctx, dest)
+	if err != nil {
+		return "", err
+	}
+
+	return copyStorage(ctx, srcStorage, src, destStorage, dest, overwrite, jobs)
+}
+
+// copyStorage copies a file or folder from src in one storage to dest in another and returns where it was
+// copied to. The files of a folder are copied the given number at a time.
+func copyStorage(ctx context.Context, srcStorage Storage, src string, destStorage Storage, dest string, overwrite bool, jobs int) (string, error) {
+	info, err := srcStorage.Stat(src)
+	if err != nil {
+		return "", err
+	}
+
+	dest = copyDestination(destStorage, src, dest, overwrite)
+
+	// A destination that did not exist before the copy is removed when the copy does not finish
+	_, statErr := destStorage.Stat(dest)
+
+	if info.IsDir() && jobs > 1 {
+		err = copyTreeParallel(ctx, srcStorage, src, destStorage, dest, jobs)
+	} else {
+		err = copyTree(ctx, srcStorage, src, destStorage, dest, info)
+	}
+	if err != nil {
+		if statErr != nil {
+			err = errors.Join(err, destStorage.RemoveAll(dest))
+		}
+		return "", err
+	}
+
+	return dest, nil
+}
+
+// CopyDestination returns where CopyFileOrFolder would copy the source to, without copying it, so that
+// a copy can be previewed. The source has to exist.
+func CopyDestination(ctx context.Context, src, dest string, overwrite bool) (string, error) {
+	srcStorage, err := StorageFor(ctx, src)
+	if err != nil {
+		return "", err
+	}
+
+	destStorage, err := StorageFor(ctx, dest)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := srcStorage.Stat(src); err != nil {
+		return "", err
+	}
+
+	return copyDestination(destStorage, src, dest, overwrite), nil
+}
+
+// copyDestination returns where the source is copied to in the storage. A directory destination gets
+// the source in it, like cp, and a destination that exists is given a unique name unless it is overwritten.
+func copyDestination(destStorage Storage, src, dest string, overwrite bool) string {
+	if destInfo, err := destStorage.Stat(dest); err == nil && destInfo.IsDir() {
+		dest = JoinPath(dest, basePath(src))
+	} else if strings.HasSuffix(dest, "/") || strings.HasSuffix(dest, string(os.PathSeparator)) {
+		dest = JoinPath(dest, basePath(src))
+	}
+
+	if !overwrite {
+		dest = uniqueDestination(destStorage, dest)
+	}
+
+	return dest
+}
+
+// copyTree copies the file or the directory and everything in it from one storage to another
+func copyTree(ctx context.Context, srcStorage Storage, src string, destStorage Storage, dest string, info fs.FileInfo) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		return copyStorageFile(ctx, srcStorage, src, destStorage, dest)
+	}
+
+	if err := destStorage.MkdirAll(dest); err != nil {
+		return err
+	}
+
+	entries, err := srcStorage.ReadDir(src)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		entryInfo, err := srcStorage.Stat(JoinPath(src, entry.Name()))
+		if err != nil {
+			return err
+		}
+
+		if err := copyTree(ctx, srcStorage, JoinPath(src, entry.Name()), destStorage, JoinPath(dest, entry.Name()),
+			entryInfo); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// copyStorageFile copies a file from one storage to another, stopping once the context is canceled
+func copyStorageFile(ctx context.Context, srcStorage Storage, src string, destStorage Storage, dest string) (err error) {
+	in, err := srcStorage.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := destStorage.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, out.Close())
+	}()
+
+	_, err = io.Copy(out, &contextReader{ctx: ctx, r: in})
+	return err
+}
+
+// contextReader is a reader that stops reading once its context is canceled
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
+
+// fsStorage keeps the files of a pairtree on an afero.Fs, like the local file system or one in memory
+type fsStorage struct {
+	fs afero.Fs
+}
+
+// NewFsStorage creates the storage of pairtrees on the file system
+func NewFsStorage(fsys afero.Fs) Storage {
+	return fsStorage{fs: fsys}
+}
+
+func (s fsStorage) Stat(path string) (fs.FileInfo, error) {
+	return s.fs.Stat(path)
+}
+
+func (s fsStorage) ReadDir(path string) ([]fs.DirEntry, error) {
+	var entries []fs.DirEntry
+	err := s.ScanDir(path, func(batch []fs.DirEntry) error {
+		entries = append(entries, batch...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// ScanDir reads the entries of the directory without a stat of each entry when the file system can,
+// which the local file system can
+func (s fsStorage) ScanDir(path string, fn func(entries []fs.DirEntry) error) error {
+	dir, err := s.fs.Open(path)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+
+	for {
+		var entries []fs.DirEntry
+		if readDir, ok := dir.(fs.ReadDirFile); ok {
+			entries, err = readDir.ReadDir(scanBatch)
+		} else {
+			var infos []fs.FileInfo
+			infos, err = dir.Readdir(scanBatch)
+			for _, info := range infos {
+				entries = append(entries, fs.FileInfoToDirEntry(info))
+			}
+		}
+
+		if len(entries) > 0 {
+			if err := fn(entries); err != nil {
+				return err
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+func (s fsStorage) Open(path string) (io.ReadCloser, error) {
+	return s.fs.Open(path)
+}
+
+func (s fsStorage) Create(path string) (io.WriteCloser, error) {
+	if err := s.fs.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	return s.fs.Create(path)
+}
+
+func (s fsStorage) Append(path string, data []byte) error {
+	if err := s.fs.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	file, err := s.fs.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+
+	// The data is written in one call so that appends made at the same time are not interleaved
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+func (s fsStorage) MkdirAll(path string) error {
+	return s.fs.MkdirAll(path, 0755)
+}
+
+func (s fsStorage) RemoveAll(path string) error {
+	return s.fs.RemoveAll(path)
+}
@@ -0,0 +1,191 @@
+package pairtree
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	caltech_pairtree "github.com/caltechlibrary/pairtree"
+)
+
+// SuggestIDs returns up to limit IDs of objects in the pairtree that were likely meant by an ID that is
+// not in it, closest first. An ID is suggested when one of the IDs starts with the other, or when it
+// is a few edits away, which allows more edits for a longer ID.
+func SuggestIDs(ptRoot, prefix, id string, limit int) ([]string, error) {
+	pt, err := open(context.Background(), ptRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	return pt.withPrefix(prefix).SuggestIDs(id, limit)
+}
+
+// SuggestIDs returns up to limit IDs of objects in the pairtree that were likely meant by an ID that is not in it
+func (p *Pairtree) SuggestIDs(id string, limit int) ([]string, error) {
+	type suggestion struct {
+		id       string
+		distance int
+	}
+
+	bare := []rune(strings.TrimPrefix(id, p.prefix))
+	maxDistance := 2 + len(bare)/8
+
+	var suggestions []suggestion
+	err := p.WalkObjects(func(candidate, _ string) error {
+		if candidate == id {
+			return nil
+		}
+
+		distance := editDistance(bare, []rune(strings.TrimPrefix(candidate, p.prefix)))
+		if distance <= maxDistance || strings.HasPrefix(candidate, id) || strings.HasPrefix(id, candidate) {
+			suggestions = append(suggestions, suggestion{id: candidate, distance: distance})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].distance < suggestions[j].distance
+	})
+
+	var ids []string
+	for _, s := range suggestions[:min(limit, len(suggestions))] {
+		ids = append(ids, s.id)
+	}
+
+	return ids, nil
+}
+
+// CompleteIDs returns the IDs of the objects in the pairtree that start with partial, in the order of
+// their pairpaths. Only the shorties that partial spells out are walked, so completing an ID does not
+// read the whole pairtree. A partial that is the start of the prefix is completed to the prefix.
+func CompleteIDs(ptRoot, prefix, partial string) ([]string, error) {
+	pt, err := open(context.Background(), ptRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	return pt.withPrefix(prefix).CompleteIDs(partial)
+}
+
+// CompleteIDs returns the IDs of the objects in the pairtree that start with partial
+func (p *Pairtree) CompleteIDs(partial string) ([]string, error) {
+	if !strings.HasPrefix(partial, p.prefix) {
+		if strings.HasPrefix(p.prefix, partial) {
+			return []string{p.prefix}, nil
+		}
+		return nil, nil
+	}
+
+	var ids []string
+	err := p.walkIDsFrom(context.Background(), partial, func(id string) error {
+		if strings.HasPrefix(id, partial) {
+			ids = append(ids, id)
+		}
+		return nil
+	})
+
+	return ids, err
+}
+
+// IsIDPattern reports whether the argument is a glob pattern for IDs rather than an ID, which is when it
+// has one of the special characters of a pattern
+func IsIDPattern(arg string) bool {
+	return strings.ContainsAny(arg, "*?[")
+}
+
+// MatchIDs returns the IDs of the objects in the pairtree that match the glob pattern, in the order of
+// their pairpaths. Patterns are those of path.Match, except that * and ? match the slashes of an ID as
+// well. Only the shorties that the start of the pattern before its first special character spells out
+// are walked, so a pattern like ark:/b54* does not read the whole pairtree.
+func MatchIDs(ctx context.Context, ptRoot, prefix, pattern string) ([]string, error) {
+	pt, err := open(ctx, ptRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	return pt.withPrefix(prefix).MatchIDs(ctx, pattern)
+}
+
+// MatchIDs returns the IDs of the objects in the pairtree that match the glob pattern
+func (p *Pairtree) MatchIDs(ctx context.Context, pattern string) ([]string, error) {
+	// Slashes are swapped for a character IDs do not have so that path.Match does not treat them specially
+	glob := strings.ReplaceAll(pattern, "/", "\x00")
+	if _, err := path.Match(glob, ""); err != nil {
+		return nil, fmt.Errorf("%w: %q", error_msgs.Err44, pattern)
+	}
+
+	literal := pattern
+	if i := strings.IndexAny(pattern, "*?[\\"); i >= 0 {
+		literal = pattern[:i]
+	}
+
+	// A pattern that does not start with the prefix could still match any ID, so the whole pairtree is walked
+	if !strings.HasPrefix(literal, p.prefix) {
+		literal = p.prefix
+	}
+
+	var ids []string
+	err := p.walkIDsFrom(ctx, literal, func(id string) error {
+		// The pattern was checked, so matching does not fail
+		if matched, _ := path.Match(glob, strings.ReplaceAll(id, "/", "\x00")); matched {
+			ids = append(ids, id)
+		}
+		return nil
+	})
+
+	return ids, err
+}
+
+// walkIDsFrom calls fn with the ID of each object under the shorties of the whole pairs of the encoded
+// partial ID, which starts with the prefix of the pairtree. No IDs are walked when those shorties do not exist.
+func (p *Pairtree) walkIDsFrom(ctx context.Context, partial string, fn func(id string) error) error {
+	encoded := caltech_pairtree.CharEncode([]rune(strings.TrimPrefix(partial, p.prefix)))
+	whole := len(encoded) / 2 * 2
+
+	dir := JoinPath(p.root, rootDir)
+	for i := 0; i < whole; i += 2 {
+		dir = JoinPath(dir, string(encoded[i:i+2]))
+	}
+
+	err := walkShorties(ctx, p.storage, dir, string(encoded[:whole]), p.prefix, func(id, _ string) error {
+		return fn(id)
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+// editDistance returns the Levenshtein distance between a and b, the number of runes that have to be
+// inserted, deleted, or substituted to turn one into the other
+func editDistance(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
@@ -0,0 +1,108 @@
+package pairtree
+
+import (
+	"context"
+	"testing"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pttest"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSuggestIDs tests that the IDs close to an ID that is not in the pairtree are suggested closest first
+func TestSuggestIDs(t *testing.T) {
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, afero.NewOsFs())
+
+	tests := []struct {
+		name   string
+		id     string
+		limit  int
+		expect []string
+	}{
+		{name: "closest first", id: "ark:/a5389", limit: 5, expect: []string{"ark:/a5388", "ark:/a5488", "ark:/a54892"}},
+		{name: "start of an ID", id: "ark:/a5", limit: 5, expect: []string{"ark:/a5388", "ark:/a5488", "ark:/a54892"}},
+		{name: "limited", id: "ark:/a5389", limit: 1, expect: []string{"ark:/a5388"}},
+		{name: "nothing close", id: "ark:/zzzzzzzz", limit: 5, expect: nil},
+		{name: "existing ID is not suggested", id: "ark:/a5388", limit: 5, expect: []string{"ark:/a5488", "ark:/b5488"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ids, err := SuggestIDs(ptRoot, "ark:/", test.id, test.limit)
+			require.NoError(t, err)
+			assert.Equal(t, test.expect, ids)
+		})
+	}
+}
+
+// TestCompleteIDs tests that partial IDs are completed with the IDs of the pairtree that start with them
+func TestCompleteIDs(t *testing.T) {
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, afero.NewOsFs())
+
+	tests := []struct {
+		name    string
+		partial string
+		expect  []string
+	}{
+		{name: "start of the prefix", partial: "ar", expect: []string{"ark:/"}},
+		{name: "prefix", partial: "ark:/", expect: []string{"ark:/a5388", "ark:/a5488", "ark:/a54892", "ark:/b5488"}},
+		{name: "odd length", partial: "ark:/a", expect: []string{"ark:/a5388", "ark:/a5488", "ark:/a54892"}},
+		{name: "even length", partial: "ark:/a548", expect: []string{"ark:/a5488", "ark:/a54892"}},
+		{name: "whole ID", partial: "ark:/a54892", expect: []string{"ark:/a54892"}},
+		{name: "no match", partial: "ark:/c5", expect: nil},
+		{name: "not an ID", partial: "/tmp/", expect: nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ids, err := CompleteIDs(ptRoot, "ark:/", test.partial)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, test.expect, ids)
+		})
+	}
+}
+
+// TestMatchIDs tests that the IDs of the pairtree that match a glob pattern are found
+func TestMatchIDs(t *testing.T) {
+	ptRoot := pttest.StandardPairtree().WithObject("ark:/13030/m5b5488", "file.txt").BuildTemp(t, afero.NewOsFs())
+
+	tests := []struct {
+		name    string
+		pattern string
+		expect  []string
+	}{
+		{name: "star", pattern: "ark:/b54*", expect: []string{"ark:/b5488"}},
+		{name: "star in the middle", pattern: "ark:/a5*8", expect: []string{"ark:/a5388", "ark:/a5488"}},
+		{name: "question mark", pattern: "ark:/a5?88", expect: []string{"ark:/a5388", "ark:/a5488"}},
+		{name: "class", pattern: "ark:/[ab]54*", expect: []string{"ark:/a5488", "ark:/a54892", "ark:/b5488"}},
+		{name: "star matches slashes", pattern: "ark:/*b5488", expect: []string{"ark:/13030/m5b5488", "ark:/b5488"}},
+		{name: "start before the prefix", pattern: "*13030*", expect: []string{"ark:/13030/m5b5488"}},
+		{name: "no match", pattern: "ark:/c5*", expect: nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ids, err := MatchIDs(context.Background(), ptRoot, "ark:/", test.pattern)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, test.expect, ids)
+		})
+	}
+
+	_, err := MatchIDs(context.Background(), ptRoot, "ark:/", "ark:/[a5*")
+	assert.ErrorIs(t, err, error_msgs.Err44)
+
+	assert.True(t, IsIDPattern("ark:/a5*"))
+	assert.False(t, IsIDPattern("ark:/a5388"))
+}
+
+// TestEditDistance tests the number of edits between IDs
+func TestEditDistance(t *testing.T) {
+	assert.Equal(t, 0, editDistance([]rune("a5388"), []rune("a5388")))
+	assert.Equal(t, 1, editDistance([]rune("a5388"), []rune("a5389")))
+	assert.Equal(t, 1, editDistance([]rune("a5388"), []rune("a538")))
+	assert.Equal(t, 2, editDistance([]rune("a5388"), []rune("a3588")))
+	assert.Equal(t, 5, editDistance([]rune(""), []rune("a5388")))
+	assert.Equal(t, 1, editDistance([]rune("ä5388"), []rune("a5388")))
+}
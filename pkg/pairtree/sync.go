@@ -0,0 +1,305 @@
+package pairtree
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/UCLALibrary/pt-tools/pkg/checksum"
+)
+
+// SyncChange is a change Sync makes to a file or directory of the destination
+type SyncChange string
+
+// The changes Sync makes, which it reports with the path of the file or directory they are made to
+const (
+	SyncAdded   SyncChange = "added"
+	SyncUpdated SyncChange = "updated"
+	SyncDeleted SyncChange = "deleted"
+)
+
+// SyncOptions choose how Sync finds the files that changed and what it does with them
+type SyncOptions struct {
+	// Checksum compares the checksums of files of the same size instead of their modification times
+	Checksum bool
+	// Delete removes the files and directories of the destination that are not in the source
+	Delete bool
+	// DryRun reports the changes without making them
+	DryRun bool
+	// Jobs is the most files compared, copied, or deleted at once, runtime.NumCPU() when it is not positive
+	Jobs int
+	// Copy is how the files that are added or updated are copied
+	Copy CopyOptions
+}
+
+// SyncResult counts the files and directories Sync added, updated, and deleted, the files it left alone,
+// and the bytes of the files it copied
+type SyncResult struct {
+	Added     int   `json:"added"`
+	Updated   int   `json:"updated"`
+	Deleted   int   `json:"deleted"`
+	Unchanged int   `json:"unchanged"`
+	Bytes     int64 `json:"bytes"`
+}
+
+// Sync makes the destination directory on the local file system match the source directory, like rsync
+// does. Files that are not in the destination are added, and files that differ in size, or in modification
+// time or checksum, are updated and given the modification time of the source so they are found to be the
+// same the next time. With Delete what is not in the source is removed from the destination. The
+// directories are made first, and then the files are compared, copied, and deleted as the operations of a
+// Batch with Jobs workers. Each change is reported to fn, when it is set, with the path in the destination
+// relative to it once it is made. A file that fails does not stop the others, and its error is returned
+// joined with those of the rest.
+func Sync(ctx context.Context, src, dest string, opts SyncOptions, fn func(change SyncChange, path string)) (SyncResult, error) {
+	var result SyncResult
+
+	report := func(change SyncChange, path string) {
+		switch change {
+		case SyncAdded:
+			result.Added++
+		case SyncUpdated:
+			result.Updated++
+		case SyncDeleted:
+			result.Deleted++
+		}
+		if fn != nil {
+			fn(change, filepath.ToSlash(path))
+		}
+	}
+
+	srcEntries, err := syncEntries(src)
+	if err != nil {
+		return result, err
+	}
+
+	destEntries, err := syncEntries(dest)
+	if err != nil && !os.IsNotExist(err) {
+		return result, err
+	}
+
+	if !opts.DryRun {
+		if err := os.MkdirAll(dest, os.ModePerm); err != nil {
+			return result, err
+		}
+	}
+
+	// The directories are made before the files that go in them, in order so each parent comes first
+	var files []string
+	for _, rel := range sortedPaths(srcEntries) {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		srcInfo, destInfo := srcEntries[rel], destEntries[rel]
+		if !srcInfo.IsDir() {
+			files = append(files, rel)
+			continue
+		}
+		if destInfo != nil && destInfo.IsDir() {
+			continue
+		}
+
+		change := SyncAdded
+		if destInfo != nil {
+			change = SyncUpdated
+		}
+
+		if !opts.DryRun {
+			// A file where the directory belongs is replaced by it
+			destPath := filepath.Join(dest, rel)
+			if err := os.RemoveAll(destPath); err != nil {
+				return result, err
+			}
+			if err := os.MkdirAll(destPath, srcInfo.Mode().Perm()); err != nil {
+				return result, err
+			}
+		}
+		report(change, rel)
+	}
+
+	// Only the top of a directory that is removed is deleted, and what is in a directory that a file of the
+	// source replaces goes with it, so no two operations work on the same path
+	var deleted []string
+	if opts.Delete {
+		var removed []string
+		for _, rel := range sortedPaths(destEntries) {
+			if slices.ContainsFunc(removed, func(dir string) bool { return isUnder(rel, dir) }) {
+				continue
+			}
+			if srcInfo, ok := srcEntries[rel]; ok {
+				if !srcInfo.IsDir() && destEntries[rel].IsDir() {
+					removed = append(removed, rel)
+				}
+				continue
+			}
+
+			if destEntries[rel].IsDir() {
+				removed = append(removed, rel)
+			}
+			deleted = append(deleted, rel)
+		}
+	}
+
+	changes := make([]SyncChange, len(deleted)+len(files))
+	indexes := make(map[string]int, len(changes))
+	operations := make([]Operation, 0, len(changes))
+
+	for _, rel := range deleted {
+		i, destPath := len(operations), filepath.Join(dest, rel)
+		indexes[rel] = i
+		operations = append(operations, Operation{ID: rel, Path: destPath, Run: func(ctx context.Context) error {
+			changes[i] = SyncDeleted
+			if opts.DryRun {
+				return nil
+			}
+			return os.RemoveAll(destPath)
+		}})
+	}
+
+	for _, rel := range files {
+		i, srcPath, destPath := len(operations), filepath.Join(src, rel), filepath.Join(dest, rel)
+		indexes[rel] = i
+		operations = append(operations, Operation{ID: rel, Path: destPath, Run: func(ctx context.Context) (err error) {
+			changes[i], err = syncFile(ctx, srcPath, srcEntries[rel], destPath, destEntries[rel], opts)
+			return err
+		}})
+	}
+
+	// The changes are counted and reported as the operations finish, which is never more than one at a time
+	batch := NewBatch(opts.Jobs)
+	batch.OnProgress = func(progress Progress) {
+		rel := progress.Last.ID
+		change := changes[indexes[rel]]
+		switch {
+		case progress.Last.Err != nil:
+		case change == "":
+			result.Unchanged++
+		default:
+			if change != SyncDeleted {
+				result.Bytes += srcEntries[rel].Size()
+			}
+			report(change, rel)
+		}
+	}
+
+	_, err = batch.Run(ctx, operations)
+
+	return result, err
+}
+
+// syncFile compares the file of the source with the entry of the destination and copies it when they
+// differ, returning the change it made, which is empty when they are the same
+func syncFile(ctx context.Context, srcPath string, srcInfo fs.FileInfo, destPath string, destInfo fs.FileInfo, opts SyncOptions) (SyncChange, error) {
+	change := SyncAdded
+	if destInfo != nil {
+		same, err := sameEntry(ctx, srcPath, srcInfo, destPath, destInfo, opts)
+		if err != nil || same {
+			return "", err
+		}
+		change = SyncUpdated
+	}
+
+	if opts.DryRun {
+		return change, nil
+	}
+
+	// A directory where the file belongs is replaced by it
+	if destInfo != nil && destInfo.IsDir() {
+		if err := os.RemoveAll(destPath); err != nil {
+			return "", err
+		}
+	}
+	if err := copyFile(ctx, srcPath, destPath, srcInfo, opts.Copy); err != nil {
+		return "", err
+	}
+	if err := os.Chtimes(destPath, srcInfo.ModTime(), srcInfo.ModTime()); err != nil {
+		return "", err
+	}
+
+	return change, nil
+}
+
+// syncEntries returns the information of the regular files and directories under the directory by their
+// paths relative to it. Links and other special files are left out.
+func syncEntries(dir string) (map[string]fs.FileInfo, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, &fs.PathError{Op: "sync", Path: dir, Err: syscall.ENOTDIR}
+	}
+
+	entries := map[string]fs.FileInfo{}
+	err = filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || path == dir {
+			return err
+		}
+
+		if !entry.IsDir() && !entry.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		entries[rel] = info
+
+		return nil
+	})
+
+	return entries, err
+}
+
+// sameEntry checks if the entry of the destination is the same as that of the source. Directories are
+// the same as each other, and files are when they have the same size and modification time, or checksum.
+func sameEntry(ctx context.Context, srcPath string, srcInfo fs.FileInfo, destPath string, destInfo fs.FileInfo, opts SyncOptions) (bool, error) {
+	if srcInfo.IsDir() || destInfo.IsDir() {
+		return srcInfo.IsDir() == destInfo.IsDir(), nil
+	}
+
+	if srcInfo.Size() != destInfo.Size() {
+		return false, nil
+	}
+
+	if !opts.Checksum {
+		return srcInfo.ModTime().Equal(destInfo.ModTime()), nil
+	}
+
+	files, err := checksum.Files(ctx, []string{srcPath, destPath}, checksum.Options{})
+	if err != nil {
+		return false, err
+	}
+
+	return files[0].Checksum == files[1].Checksum, nil
+}
+
+// sortedPaths returns the paths of the entries in order, which puts each directory before what is in it
+func sortedPaths(entries map[string]fs.FileInfo) []string {
+	paths := make([]string, 0, len(entries))
+	for path := range entries {
+		paths = append(paths, path)
+	}
+
+	// Comparing the parts of the paths keeps a directory and what is in it together
+	slices.SortFunc(paths, func(a, b string) int {
+		return slices.Compare(strings.Split(filepath.ToSlash(a), "/"), strings.Split(filepath.ToSlash(b), "/"))
+	})
+
+	return paths
+}
+
+// isUnder checks if the relative path is inside the relative directory
+func isUnder(path, dir string) bool {
+	return strings.HasPrefix(path, dir+string(filepath.Separator))
+}
@@ -0,0 +1,117 @@
+package pairtree
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// syncChanges syncs the source to the destination and returns the result and the changes that were reported
+func syncChanges(t *testing.T, src, dest string, opts SyncOptions) (SyncResult, map[string]SyncChange) {
+	changes := map[string]SyncChange{}
+	result, err := Sync(context.Background(), src, dest, opts, func(change SyncChange, path string) {
+		changes[path] = change
+	})
+	require.NoError(t, err)
+
+	return result, changes
+}
+
+// TestSync tests that the destination is made to match the source and that only what changed is copied
+func TestSync(t *testing.T) {
+	src, dest := t.TempDir(), filepath.Join(t.TempDir(), "dest")
+	for _, path := range []string{"a.txt", "sub/b.txt", "sub/deeper/c.txt"} {
+		createPath(t, src, path)
+	}
+	require.NoError(t, os.Mkdir(filepath.Join(src, "empty"), 0755))
+
+	// Nothing is made in a dry run
+	result, changes := syncChanges(t, src, dest, SyncOptions{DryRun: true})
+	assert.Equal(t, 6, result.Added)
+	assert.Equal(t, SyncAdded, changes["sub/deeper/c.txt"])
+	assert.NoDirExists(t, dest)
+
+	result, _ = syncChanges(t, src, dest, SyncOptions{})
+	assert.Equal(t, SyncResult{Added: 6, Bytes: 3}, result)
+	assert.FileExists(t, filepath.Join(dest, "sub", "deeper", "c.txt"))
+	assert.DirExists(t, filepath.Join(dest, "empty"))
+
+	// A second sync finds every file the same
+	result, changes = syncChanges(t, src, dest, SyncOptions{})
+	assert.Equal(t, SyncResult{Unchanged: 3}, result)
+	assert.Empty(t, changes)
+
+	// A file that changed is updated, and extra files are only removed with Delete
+	later := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(src, "a.txt"), later, later))
+	createPath(t, dest, "extra/file.txt")
+	createPath(t, dest, "extra.txt")
+
+	result, changes = syncChanges(t, src, dest, SyncOptions{})
+	assert.Equal(t, map[string]SyncChange{"a.txt": SyncUpdated}, changes)
+	assert.Equal(t, 2, result.Unchanged)
+	assert.FileExists(t, filepath.Join(dest, "extra.txt"))
+
+	result, changes = syncChanges(t, src, dest, SyncOptions{Delete: true})
+	assert.Equal(t, map[string]SyncChange{"extra": SyncDeleted, "extra.txt": SyncDeleted}, changes)
+	assert.Equal(t, 2, result.Deleted)
+	assert.NoDirExists(t, filepath.Join(dest, "extra"))
+	assert.NoFileExists(t, filepath.Join(dest, "extra.txt"))
+
+	// With Checksum a file with a new modification time but the same content is not updated
+	require.NoError(t, os.Chtimes(filepath.Join(src, "sub", "b.txt"), later, later))
+	_, changes = syncChanges(t, src, dest, SyncOptions{Checksum: true})
+	assert.Empty(t, changes)
+
+	// A file with the same size and modification time but other content is only updated with Checksum
+	changed := filepath.Join(dest, "sub", "b.txt")
+	require.NoError(t, os.WriteFile(changed, []byte("y"), 0644))
+	require.NoError(t, os.Chtimes(changed, later, later))
+	_, changes = syncChanges(t, src, dest, SyncOptions{})
+	assert.Empty(t, changes)
+	_, changes = syncChanges(t, src, dest, SyncOptions{Checksum: true})
+	assert.Equal(t, map[string]SyncChange{"sub/b.txt": SyncUpdated}, changes)
+
+	// A file is replaced by the directory of the source
+	require.NoError(t, os.RemoveAll(filepath.Join(dest, "sub")))
+	createPath(t, dest, "sub")
+	_, changes = syncChanges(t, src, dest, SyncOptions{})
+	assert.Equal(t, SyncUpdated, changes["sub"])
+	assert.FileExists(t, filepath.Join(dest, "sub", "b.txt"))
+
+	_, err := Sync(context.Background(), filepath.Join(src, "missing"), dest, SyncOptions{}, nil)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+// TestSyncJobs tests that the files are synced by more than one worker with the same result as by one
+func TestSyncJobs(t *testing.T) {
+	src, dest := t.TempDir(), t.TempDir()
+	for i := range 50 {
+		createPath(t, src, filepath.Join(fmt.Sprintf("dir%d", i%5), fmt.Sprintf("file%d.txt", i)))
+		createPath(t, dest, fmt.Sprintf("extra%d.txt", i))
+	}
+
+	result, changes := syncChanges(t, src, dest, SyncOptions{Delete: true, Jobs: 8})
+	assert.Equal(t, SyncResult{Added: 55, Deleted: 50, Bytes: 50}, result)
+	assert.Len(t, changes, 105)
+	assert.FileExists(t, filepath.Join(dest, "dir4", "file49.txt"))
+	assert.NoFileExists(t, filepath.Join(dest, "extra49.txt"))
+
+	result, changes = syncChanges(t, src, dest, SyncOptions{Delete: true, Jobs: 8})
+	assert.Equal(t, SyncResult{Unchanged: 50}, result)
+	assert.Empty(t, changes)
+
+	// A canceled sync does not change anything and fails with the context's error
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	createPath(t, src, "late.txt")
+	_, err := Sync(ctx, src, dest, SyncOptions{Jobs: 8}, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.NoFileExists(t, filepath.Join(dest, "late.txt"))
+}
@@ -0,0 +1,200 @@
+package pairtree
+
+import (
+	"context"
+	"io/fs"
+	"sync"
+)
+
+// treeReader reads the directories of a tree with a fixed number of workers, which take the next
+// directory from a queue that the directories they read add their subdirectories to
+type treeReader struct {
+	storage Storage
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	queue  []queuedDir
+	active int
+	err    error
+	tree   map[string][]fs.DirEntry
+}
+
+// queuedDir is a directory of the tree that is waiting to be read with the options of its depth
+type queuedDir struct {
+	path string
+	opts ListOptions
+}
+
+// readTree reads the listing of root and of every directory under it with the given number of
+// workers, and returns the entries that the options list of each directory read, sorted by name. The
+// first directory that can not be read, or the context being canceled, stops the reading of the rest.
+func readTree(ctx context.Context, storage Storage, root string, jobs int, opts ListOptions) (map[string][]fs.DirEntry, error) {
+	r := &treeReader{storage: storage, queue: []queuedDir{{path: root, opts: opts}}, tree: map[string][]fs.DirEntry{}}
+	r.cond = sync.NewCond(&r.mu)
+
+	var wg sync.WaitGroup
+	for range max(jobs, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.work(ctx)
+		}()
+	}
+	wg.Wait()
+
+	if r.err != nil {
+		return nil, r.err
+	}
+	return r.tree, nil
+}
+
+// work reads directories from the queue until it is empty and no other worker can add to it
+func (r *treeReader) work(ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for {
+		for len(r.queue) == 0 && r.active > 0 && r.err == nil {
+			r.cond.Wait()
+		}
+		if len(r.queue) == 0 || r.err != nil {
+			r.cond.Broadcast()
+			return
+		}
+
+		dir := r.queue[len(r.queue)-1]
+		r.queue = r.queue[:len(r.queue)-1]
+		r.active++
+
+		r.mu.Unlock()
+		entries, err := readListing(ctx, r.storage, dir.path, dir.opts)
+		r.mu.Lock()
+
+		r.active--
+		if err != nil {
+			if r.err == nil {
+				r.err = err
+			}
+		} else {
+			if entries == nil {
+				entries = []fs.DirEntry{}
+			}
+			r.tree[dir.path] = entries
+
+			if subOpts, ok := dir.opts.descend(); ok {
+				for _, entry := range entries {
+					if entry.IsDir() {
+						r.queue = append(r.queue, queuedDir{path: JoinPath(dir.path, entry.Name()), opts: subOpts})
+					}
+				}
+			}
+		}
+		r.cond.Broadcast()
+	}
+}
+
+// treeStorage is a storage with the listing of a tree already read, so the listing is passed on in the
+// same order whether or not its directories were read in parallel
+type treeStorage struct {
+	Storage
+	tree map[string][]fs.DirEntry
+}
+
+func (s treeStorage) ReadDir(path string) ([]fs.DirEntry, error) {
+	if entries, ok := s.tree[path]; ok {
+		return entries, nil
+	}
+	return s.Storage.ReadDir(path)
+}
+
+// listingStorage returns the storage the listing of path is read from. When the options list
+// recursively with more than one job, the whole listing is read in parallel before it is returned.
+func listingStorage(ctx context.Context, storage Storage, path string, opts ListOptions) (Storage, error) {
+	if !opts.Recursive || opts.Jobs <= 1 {
+		return storage, nil
+	}
+
+	tree, err := readTree(ctx, storage, path, opts.Jobs, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return treeStorage{Storage: storage, tree: tree}, nil
+}
+
+// copyTreeParallel copies the directory src and everything in it with the given number of files copied
+// at once. The directories are read and created first, then the files are copied by the workers, and the
+// first file that can not be copied, or the context being canceled, stops the copying of the rest.
+func copyTreeParallel(ctx context.Context, srcStorage Storage, src string, destStorage Storage, dest string, jobs int) error {
+	tree, err := readTree(ctx, srcStorage, src, jobs, ListOptions{Recursive: true, ShowAll: true})
+	if err != nil {
+		return err
+	}
+
+	if err := destStorage.MkdirAll(dest); err != nil {
+		return err
+	}
+
+	type copyJob struct{ src, dest string }
+	var files []copyJob
+	var plan func(dir, destDir string) error
+	plan = func(dir, destDir string) error {
+		for _, entry := range tree[dir] {
+			srcPath, destPath := JoinPath(dir, entry.Name()), JoinPath(destDir, entry.Name())
+			if !entry.IsDir() {
+				files = append(files, copyJob{src: srcPath, dest: destPath})
+				continue
+			}
+
+			if err := destStorage.MkdirAll(destPath); err != nil {
+				return err
+			}
+			if err := plan(srcPath, destPath); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := plan(src, dest); err != nil {
+		return err
+	}
+
+	copyCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	next := make(chan copyJob)
+	var wg sync.WaitGroup
+	var failed sync.Once
+	var firstErr error
+
+	for range min(jobs, max(len(files), 1)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for job := range next {
+				if err := copyStorageFile(copyCtx, srcStorage, job.src, destStorage, job.dest); err != nil {
+					failed.Do(func() {
+						firstErr = err
+						cancel()
+					})
+				}
+			}
+		}()
+	}
+
+	for _, job := range files {
+		if copyCtx.Err() != nil {
+			break
+		}
+		next <- job
+	}
+	close(next)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
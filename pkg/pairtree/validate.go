@@ -0,0 +1,152 @@
+package pairtree
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"strings"
+	"unicode/utf8"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+)
+
+// The kinds of violations of the pairtree specification that Validate reports
+const (
+	VersionViolation = "version" // pairtree_version0_1 is missing or empty
+	ShortyViolation  = "shorty"  // a shorty directory is not part of a valid pairpath
+	FileViolation    = "file"    // a file is in a branch of the pairtree instead of in an object
+	IDViolation      = "id"      // an object directory does not decode to the ID of its pairpath
+)
+
+// Violation is a place where the pairtree does not conform to the pairtree specification
+type Violation struct {
+	Kind    string `json:"kind"`
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// Validation is the number of objects found in the pairtree and its violations of the specification
+type Validation struct {
+	Objects    int         `json:"objects"`
+	Violations []Violation `json:"violations"`
+}
+
+// Validate walks the whole pairtree and reports where it does not conform to the pairtree specification,
+// in the order of the pairpaths. An error is only returned when the pairtree can not be read.
+func Validate(ptRoot string) (Validation, error) {
+	return ValidateCtx(context.Background(), ptRoot)
+}
+
+// ValidateCtx is Validate that stops before the next directory once the context is canceled
+func ValidateCtx(ctx context.Context, ptRoot string) (Validation, error) {
+	pt, err := open(ctx, ptRoot)
+	if err != nil {
+		return Validation{Violations: []Violation{}}, err
+	}
+
+	return pt.ValidateCtx(ctx)
+}
+
+// Validate walks the whole pairtree and reports where it does not conform to the pairtree specification
+func (p *Pairtree) Validate() (Validation, error) {
+	return p.ValidateCtx(context.Background())
+}
+
+// ValidateCtx is Validate that stops before the next directory once the context is canceled
+func (p *Pairtree) ValidateCtx(ctx context.Context) (Validation, error) {
+	result := Validation{Violations: []Violation{}}
+
+	verPath := JoinPath(p.root, verDir)
+	if err := p.CheckPTVer(); errors.Is(err, fs.ErrNotExist) {
+		result.add(VersionViolation, verPath, "the pairtree version file is missing")
+	} else if errors.Is(err, error_msgs.Err2) {
+		result.add(VersionViolation, verPath, "the pairtree version file is empty")
+	} else if err != nil {
+		return result, err
+	}
+
+	if err := result.walk(ctx, p.storage, JoinPath(p.root, rootDir), "", false); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// add records a violation of the specification at the path
+func (v *Validation) add(kind, path, message string) {
+	v.Violations = append(v.Violations, Violation{Kind: kind, Path: path, Message: message})
+}
+
+// walk validates the branch directory reached by the shorties spelling out encoded. The directory of
+// an object whose encoded ID is a single shorty is walked for the objects of longer IDs below it, but
+// its content can not be told apart from shorties so it is not validated.
+func (v *Validation) walk(ctx context.Context, storage Storage, dir, encoded string, inObject bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	entries, err := storage.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	// A one-character shorty can only end a pairpath
+	lastShorty := basePath(dir)
+	endsPairpath := encoded != "" && utf8.RuneCountInString(lastShorty) == 1
+
+	for _, entry := range entries {
+		name := entry.Name()
+		path := JoinPath(dir, name)
+		short := utf8.RuneCountInString(name) <= 2
+
+		switch {
+		case !entry.IsDir():
+			if !inObject {
+				v.add(FileViolation, path, "the file is in a branch of the pairtree instead of in an object")
+			}
+		case encoded != "" && name == encoded:
+			if _, err := decodeName(name); err != nil {
+				v.add(IDViolation, path, "the object directory does not decode to a valid ID")
+			} else {
+				v.Objects++
+			}
+
+			if short {
+				if err := v.walk(ctx, storage, path, encoded+name, true); err != nil {
+					return err
+				}
+			}
+		case inObject:
+			// The rest of the directory of an object is its content, which may hide the shorties of longer IDs
+			if short && isShorty(name) {
+				if err := v.walk(ctx, storage, path, encoded+name, true); err != nil {
+					return err
+				}
+			}
+		case !short:
+			v.add(IDViolation, path, "the object directory is not named for the pairpath it is in")
+		case !isShorty(name):
+			v.add(ShortyViolation, path, "the shorty has characters that are not in the pairtree encoding")
+		case endsPairpath:
+			v.add(ShortyViolation, path, "the shorty follows a one-character shorty, which can only end a pairpath")
+		default:
+			if err := v.walk(ctx, storage, path, encoded+name, false); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// isShorty reports whether the name only has characters that encoding an ID can produce, which are
+// the visible ASCII characters other than those the encoding replaces
+func isShorty(name string) bool {
+	for _, r := range name {
+		if r < 0x21 || r > 0x7e || strings.ContainsRune("\"*./:<>?\\|", r) {
+			return false
+		}
+	}
+
+	return name != ""
+}
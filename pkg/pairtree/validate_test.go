@@ -0,0 +1,100 @@
+package pairtree
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/UCLALibrary/pt-tools/pkg/pttest"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestValidate tests that each kind of violation of the pairtree specification is reported where it is
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		paths  []string
+		expect []Violation
+	}{
+		{name: "conforms"},
+		{name: "file in a branch", paths: []string{"a5/stray.txt", "stray.txt"}, expect: []Violation{
+			{Kind: FileViolation, Path: "a5/stray.txt"},
+			{Kind: FileViolation, Path: "stray.txt"},
+		}},
+		{name: "shorty not encoded", paths: []string{"a5/4./a54,/a.txt", "c:/c+/c.txt"}, expect: []Violation{
+			{Kind: ShortyViolation, Path: "a5/4."},
+			{Kind: ShortyViolation, Path: "c:"},
+		}},
+		{name: "shorty after a one-character shorty", paths: []string{"a5/38/8/88/a53888/a.txt"}, expect: []Violation{
+			{Kind: ShortyViolation, Path: "a5/38/8/88"},
+		}},
+		{name: "misplaced object", paths: []string{"a5/38/a5399/a.txt"}, expect: []Violation{
+			{Kind: IDViolation, Path: "a5/38/a5399"},
+		}},
+		{name: "not decodable", paths: []string{"c^/zz/c^zz/c.txt"}, expect: []Violation{
+			{Kind: IDViolation, Path: "c^/zz/c^zz"},
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ptRoot := pttest.StandardPairtree().BuildTemp(t, afero.NewOsFs())
+			for _, path := range test.paths {
+				path = filepath.Join(ptRoot, rootDir, filepath.FromSlash(path))
+				require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+				require.NoError(t, os.WriteFile(path, []byte("stray"), 0644))
+			}
+
+			result, err := Validate(ptRoot)
+			require.NoError(t, err)
+			assert.Equal(t, 4, result.Objects)
+
+			var violations []Violation
+			for _, violation := range result.Violations {
+				rel, err := filepath.Rel(filepath.Join(ptRoot, rootDir), violation.Path)
+				require.NoError(t, err)
+				violations = append(violations, Violation{Kind: violation.Kind, Path: filepath.ToSlash(rel)})
+			}
+			assert.Equal(t, test.expect, violations)
+		})
+	}
+}
+
+// TestValidateVersion tests that a missing or empty version file is reported
+func TestValidateVersion(t *testing.T) {
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, afero.NewOsFs())
+	verPath := filepath.Join(ptRoot, verDir)
+
+	require.NoError(t, os.WriteFile(verPath, nil, 0644))
+	result, err := Validate(ptRoot)
+	require.NoError(t, err)
+	assert.Equal(t, []Violation{{Kind: VersionViolation, Path: verPath, Message: "the pairtree version file is empty"}},
+		result.Violations)
+
+	require.NoError(t, os.Remove(verPath))
+	result, err = Validate(ptRoot)
+	require.NoError(t, err)
+	assert.Equal(t, []Violation{{Kind: VersionViolation, Path: verPath, Message: "the pairtree version file is missing"}},
+		result.Violations)
+
+	// A pairtree without a pairtree_root can not be walked
+	require.NoError(t, os.RemoveAll(filepath.Join(ptRoot, rootDir)))
+	_, err = Validate(ptRoot)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+// TestValidateShortObject tests that objects are found below an object whose ID is a single shorty
+func TestValidateShortObject(t *testing.T) {
+	ptRoot := pttest.NewPairtreeBuilder().
+		WithFile("ark:/ab", "ab.txt", []byte("ab")).
+		WithFile("ark:/abab", "abab.txt", []byte("abab")).
+		WithFile("ark:/ab", "cd/inner.txt", []byte("content")).
+		BuildTemp(t, afero.NewOsFs())
+
+	result, err := Validate(ptRoot)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Objects)
+	assert.Empty(t, result.Violations)
+}
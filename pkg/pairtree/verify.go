@@ -0,0 +1,438 @@
+package pairtree
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/UCLALibrary/pt-tools/pkg/checksum"
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/spf13/afero"
+)
+
+// Verify checks that each file of the source on the local file system is in the destination with the
+// same checksum. The source can be a file or a directory, and files of the destination that are not in
+// the source are not checked, as when the source was copied into a directory that already had files.
+// The paths of the files that are missing or differ are returned with Err48.
+func Verify(ctx context.Context, src, dest string) error {
+	return verify(ctx, src, dest, false, true)
+}
+
+// VerifySize checks that each file of the source is in the destination with the same size, like Verify
+// but without reading the files, which finds a copy that did not finish
+func VerifySize(src, dest string) error {
+	return verify(context.Background(), src, dest, false, false)
+}
+
+// verifyStorageSize is VerifySize for a source and destination in the storage, which checks a copy made
+// through the storage of a file system other than the local one
+func verifyStorageSize(storage Storage, src, dest string) error {
+	var mismatched []string
+
+	var walk func(rel string) error
+	walk = func(rel string) error {
+		srcInfo, err := storage.Stat(JoinPath(src, rel))
+		if err != nil {
+			return err
+		}
+
+		destInfo, err := storage.Stat(JoinPath(dest, rel))
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			mismatched = append(mismatched, rel)
+			return nil
+		case err != nil:
+			return err
+		case srcInfo.IsDir() != destInfo.IsDir() || (!srcInfo.IsDir() && srcInfo.Size() != destInfo.Size()):
+			mismatched = append(mismatched, rel)
+			return nil
+		case !srcInfo.IsDir():
+			return nil
+		}
+
+		entries, err := storage.ReadDir(JoinPath(src, rel))
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := walk(path.Join(rel, entry.Name())); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if err := walk(""); err != nil {
+		return err
+	}
+
+	return mismatchError(mismatched, dest)
+}
+
+// VerifyArchive checks that the archive of the format has the same files as the directory, with the
+// same checksums. The archive is written by Archive from the directory or is extracted to it by
+// UnArchive, so its folder is named like the directory. Its entries are read and hashed one at a time
+// against the files of the directory, so nothing is extracted. The checksums of the files are taken from
+// the cache when they were kept in it as the archive was written.
+func VerifyArchive(ctx context.Context, archive, format, dir string, cache *checksum.Cache) error {
+	pt := New(afero.NewOsFs(), "")
+	folder := filepath.Base(dir)
+
+	// Each entry is compared with the file of the directory at its path, and the files of the directory
+	// that are not in the archive are known from how many of them the archive has
+	var mismatched []string
+	found := 0
+	err := pt.readEntries(archive, format, func(next nextEntry) error {
+		return archiveEntries(ctx, next, folder, func(header *tar.Header, content io.Reader, rel string) error {
+			info, err := os.Lstat(filepath.Join(dir, filepath.FromSlash(rel)))
+			switch {
+			case errors.Is(err, fs.ErrNotExist):
+				mismatched = append(mismatched, rel)
+				return nil
+			case err != nil:
+				return err
+			case header.Typeflag == tar.TypeDir:
+				if !info.IsDir() {
+					mismatched = append(mismatched, rel)
+					return nil
+				}
+			case !info.Mode().IsRegular():
+				mismatched = append(mismatched, rel)
+				return nil
+			default:
+				same, err := sameContent(ctx, content, filepath.Join(dir, filepath.FromSlash(rel)), cache)
+				if err != nil {
+					return err
+				}
+				if !same {
+					mismatched = append(mismatched, rel)
+				}
+			}
+
+			found++
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	count, err := countEntries(dir)
+	if err != nil {
+		return err
+	}
+
+	// Only an archive that is missing files, or has an entry more than once, is read a second time
+	if count != found {
+		missing, err := missingEntries(ctx, pt, archive, format, dir)
+		if err != nil {
+			return err
+		}
+		mismatched = append(mismatched, missing...)
+	}
+
+	return mismatchError(mismatched, archive)
+}
+
+// archiveEntries calls fn with each directory and regular file of the archive that is in its top level
+// folder, with its path relative to the folder. Links and other entries are left out, as they are from
+// the directories that are compared with the archive.
+func archiveEntries(ctx context.Context, next nextEntry, folder string,
+	fn func(header *tar.Header, content io.Reader, rel string) error) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		header, content, err := next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		name := path.Clean(header.Name)
+		if name == "." {
+			continue
+		}
+		if !filepath.IsLocal(filepath.FromSlash(name)) {
+			return fmt.Errorf("%w: %s is outside of the folder", error_msgs.Err12, header.Name)
+		}
+
+		top, rel, _ := strings.Cut(name, "/")
+		if top != folder {
+			return error_msgs.Err13
+		}
+		if rel == "" || (header.Typeflag != tar.TypeDir && header.Typeflag != tar.TypeReg) {
+			continue
+		}
+
+		if err := fn(header, content, rel); err != nil {
+			return err
+		}
+	}
+}
+
+// sameContent checks if the content of an archive entry has the checksum of the file
+func sameContent(ctx context.Context, content io.Reader, filePath string, cache *checksum.Cache) (bool, error) {
+	entry, err := checksum.Reader(ctx, content, filePath, checksum.Options{})
+	if err != nil {
+		return false, err
+	}
+
+	files, err := checksum.Files(ctx, []string{filePath}, checksum.Options{Cache: cache})
+	if err != nil {
+		return false, err
+	}
+
+	return entry.Checksum == files[0].Checksum, nil
+}
+
+// countEntries counts the regular files and directories under the directory, which are those an archive of
+// it has
+func countEntries(dir string) (int, error) {
+	count := 0
+	err := filepath.WalkDir(dir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if filePath != dir && (entry.IsDir() || entry.Type().IsRegular()) {
+			count++
+		}
+		return nil
+	})
+
+	return count, err
+}
+
+// missingEntries returns the relative paths of the regular files and directories under the directory that
+// are not in the archive. The paths of the archive are kept to look them up, along with the directories
+// they are in for archives without entries for their directories, so this is only done once an archive
+// is known to be missing files.
+func missingEntries(ctx context.Context, pt *Pairtree, archive, format, dir string) ([]string, error) {
+	inArchive := map[string]bool{}
+	err := pt.readEntries(archive, format, func(next nextEntry) error {
+		return archiveEntries(ctx, next, filepath.Base(dir), func(_ *tar.Header, _ io.Reader, rel string) error {
+			for ; rel != "."; rel = path.Dir(rel) {
+				inArchive[rel] = true
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	err = filepath.WalkDir(dir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil || filePath == dir || !(entry.IsDir() || entry.Type().IsRegular()) {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, filePath)
+		if err != nil {
+			return err
+		}
+		if rel = filepath.ToSlash(rel); !inArchive[rel] {
+			missing = append(missing, rel)
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+		}
+		return nil
+	})
+
+	return missing, err
+}
+
+// verify compares the files of the source with those of the destination by their sizes, and by their
+// checksums with checksums. With exact the destination must not have files that are not in the source.
+func verify(ctx context.Context, src, dest string, exact, checksums bool) error {
+	srcInfo, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	// A single file is compared with the file it was copied to, which is reported by the empty relative path
+	if !srcInfo.IsDir() {
+		destInfo, err := os.Stat(dest)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		var mismatched []string
+		if destInfo == nil || destInfo.IsDir() || srcInfo.Size() != destInfo.Size() {
+			mismatched = append(mismatched, "")
+		} else if checksums {
+			if mismatched, err = differentFiles(ctx, src, dest, []string{""}); err != nil {
+				return err
+			}
+		}
+
+		return mismatchError(mismatched, dest)
+	}
+
+	mismatched, err := verifyDir(ctx, src, dest, "", exact, checksums)
+	if err != nil {
+		return err
+	}
+
+	return mismatchError(mismatched, dest)
+}
+
+// verifyDir compares the directory at the relative path of the source with the one of the destination, and
+// the directories under them. The two are read a directory at a time and their entries, which are sorted by
+// name, are compared in step, so the memory used depends on the largest directory rather than on the number
+// of files. Links and other special files are left out, like they are by Sync.
+func verifyDir(ctx context.Context, src, dest, rel string, exact, checksums bool) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	srcEntries, err := verifyEntries(filepath.Join(src, rel))
+	if err != nil {
+		return nil, err
+	}
+
+	destEntries, err := verifyEntries(filepath.Join(dest, rel))
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, syscall.ENOTDIR) {
+		return []string{rel}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var mismatched, files, dirs []string
+	for i, j := 0, 0; i < len(srcEntries) || j < len(destEntries); {
+		switch {
+		case j == len(destEntries) || (i < len(srcEntries) && srcEntries[i].Name() < destEntries[j].Name()):
+			// Only in the source
+			mismatched = append(mismatched, filepath.Join(rel, srcEntries[i].Name()))
+			i++
+		case i == len(srcEntries) || destEntries[j].Name() < srcEntries[i].Name():
+			// Only in the destination
+			if exact {
+				mismatched = append(mismatched, filepath.Join(rel, destEntries[j].Name()))
+			}
+			j++
+		default:
+			entryRel := filepath.Join(rel, srcEntries[i].Name())
+			same, err := sameSize(srcEntries[i], destEntries[j])
+			switch {
+			case err != nil:
+				return nil, err
+			case !same:
+				mismatched = append(mismatched, entryRel)
+			case srcEntries[i].IsDir():
+				dirs = append(dirs, entryRel)
+			case checksums:
+				files = append(files, entryRel)
+			}
+			i++
+			j++
+		}
+	}
+
+	// The files of the directory are hashed together, before its directories are compared
+	if len(files) > 0 {
+		different, err := differentFiles(ctx, src, dest, files)
+		if err != nil {
+			return nil, err
+		}
+		mismatched = append(mismatched, different...)
+	}
+
+	for _, dir := range dirs {
+		different, err := verifyDir(ctx, src, dest, dir, exact, checksums)
+		if err != nil {
+			return nil, err
+		}
+		mismatched = append(mismatched, different...)
+	}
+
+	return mismatched, nil
+}
+
+// verifyEntries returns the regular files and directories of the directory, sorted by name
+func verifyEntries(dir string) ([]fs.DirEntry, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.DeleteFunc(entries, func(entry fs.DirEntry) bool {
+		return !entry.IsDir() && !entry.Type().IsRegular()
+	}), nil
+}
+
+// sameSize checks if the entries of the source and destination are both directories, or both files of the
+// same size
+func sameSize(srcEntry, destEntry fs.DirEntry) (bool, error) {
+	if srcEntry.IsDir() != destEntry.IsDir() {
+		return false, nil
+	}
+	if srcEntry.IsDir() {
+		return true, nil
+	}
+
+	srcInfo, err := srcEntry.Info()
+	if err != nil {
+		return false, err
+	}
+	destInfo, err := destEntry.Info()
+	if err != nil {
+		return false, err
+	}
+
+	return srcInfo.Size() == destInfo.Size(), nil
+}
+
+// differentFiles hashes the files at the relative paths in the source and the destination in parallel and
+// returns those whose checksums differ
+func differentFiles(ctx context.Context, src, dest string, files []string) ([]string, error) {
+	paths := make([]string, 0, 2*len(files))
+	for _, rel := range files {
+		paths = append(paths, filepath.Join(src, rel), filepath.Join(dest, rel))
+	}
+
+	sums, err := checksum.Files(ctx, paths, checksum.Options{})
+	if err != nil {
+		return nil, err
+	}
+
+	var different []string
+	for i, rel := range files {
+		if sums[2*i].Checksum != sums[2*i+1].Checksum {
+			different = append(different, rel)
+		}
+	}
+
+	return different, nil
+}
+
+// mismatchError returns Err48 with the sorted relative paths of the files that do not match, where the
+// empty path is the single file that was copied to dest, or nil when all of them match
+func mismatchError(mismatched []string, dest string) error {
+	if len(mismatched) == 0 {
+		return nil
+	}
+
+	for i, rel := range mismatched {
+		if rel == "" {
+			rel = filepath.Base(dest)
+		}
+		mismatched[i] = filepath.ToSlash(rel)
+	}
+	slices.Sort(mismatched)
+
+	return fmt.Errorf("%w: %s", error_msgs.Err48, strings.Join(mismatched, ", "))
+}
@@ -0,0 +1,82 @@
+package pairtree
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/UCLALibrary/pt-tools/pkg/checksum"
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestVerify tests that a copy only verifies when each file of the source is in it with the same content
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+
+	src := filepath.Join(t.TempDir(), "a5388")
+	for _, path := range []string{"a.txt", "sub/b.txt", "empty/"} {
+		createPath(t, src, path)
+	}
+
+	dest, err := CopyFileOrFolder(ctx, src, filepath.Join(t.TempDir(), "copy"), false, CopyOptions{Verify: true})
+	require.NoError(t, err)
+	require.NoError(t, Verify(ctx, src, dest))
+
+	// Files that are only in the copy are not checked
+	createPath(t, dest, "extra.txt")
+	assert.NoError(t, Verify(ctx, src, dest))
+
+	// A file with other content or that is missing is reported by its path, and only the missing file is
+	// found by its size
+	require.NoError(t, os.WriteFile(filepath.Join(dest, "sub", "b.txt"), []byte("y"), 0644))
+	require.NoError(t, os.Remove(filepath.Join(dest, "a.txt")))
+	err = Verify(ctx, src, dest)
+	assert.ErrorIs(t, err, error_msgs.Err48)
+	assert.ErrorContains(t, err, "a.txt, sub/b.txt")
+	assert.EqualError(t, VerifySize(src, dest), error_msgs.Err48.Error()+": a.txt")
+
+	// A single file is compared with its copy
+	assert.NoError(t, Verify(ctx, filepath.Join(src, "a.txt"), filepath.Join(src, "sub", "b.txt")))
+	assert.ErrorIs(t, Verify(ctx, filepath.Join(src, "a.txt"), filepath.Join(dest, "sub", "b.txt")), error_msgs.Err48)
+}
+
+// TestVerifyArchive tests that an archive only verifies when it has the same files as the directory
+func TestVerifyArchive(t *testing.T) {
+	ctx := context.Background()
+
+	for _, format := range Formats {
+		t.Run(format, func(t *testing.T) {
+			src := filepath.Join(t.TempDir(), "a5388")
+			for _, path := range []string{"a.txt", "sub/b.txt"} {
+				createPath(t, src, path)
+			}
+
+			// The files are hashed as they are archived, so the archive is verified against their checksums
+			cache := checksum.NewCache()
+			dest := t.TempDir()
+			archive := ArchiveDestination(src, dest, PtPrefix, format, false)
+			require.NoError(t, Archive(ctx, src, dest, PtPrefix, format, false, ArchiveOptions{Cache: cache}))
+			info, err := os.Stat(filepath.Join(src, "a.txt"))
+			require.NoError(t, err)
+			_, cached := cache.Get(filepath.Join(src, "a.txt"), info)
+			assert.True(t, cached)
+			require.NoError(t, VerifyArchive(ctx, archive, format, src, cache))
+			require.NoError(t, VerifyArchive(ctx, archive, format, src, nil))
+
+			// A file that is not in the archive is reported like one that differs
+			createPath(t, src, "new.txt")
+			err = VerifyArchive(ctx, archive, format, src, cache)
+			assert.ErrorIs(t, err, error_msgs.Err48)
+			assert.ErrorContains(t, err, "new.txt")
+
+			// A file whose content differs from its entry is found by the entry's checksum
+			require.NoError(t, os.Remove(filepath.Join(src, "new.txt")))
+			require.NoError(t, os.WriteFile(filepath.Join(src, "sub", "b.txt"), []byte("y"), 0644))
+			err = VerifyArchive(ctx, archive, format, src, cache)
+			assert.EqualError(t, err, error_msgs.Err48.Error()+": sub/b.txt")
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package pairtree
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"path/filepath"
+
+	"github.com/UCLALibrary/pt-tools/pkg/checksum"
+	"github.com/spf13/afero"
+)
+
+// maxZipLink is the longest target of a link in a zip archive that is extracted
+const maxZipLink = 4096
+
+// WriteZip writes the source directory or file to the writer as a zip archive, with the archive's top
+// level folder named after the source like WriteTarGz. Files are compressed as the source is walked,
+// so the archive can be streamed.
+func WriteZip(ctx context.Context, w io.Writer, src string) error {
+	return New(afero.NewOsFs(), "").WriteZip(ctx, w, src)
+}
+
+// WriteZip writes the source directory or file of the file system of the pairtree to the writer as a
+// zip archive
+func (p *Pairtree) WriteZip(ctx context.Context, w io.Writer, src string) error {
+	return p.writeZip(ctx, w, src, "", nil)
+}
+
+// writeZip writes the zip archive of the source, leaving out the file at skip, and keeps the checksums of
+// its files in the cache when there is one. The index of the archive is not written when the walk fails,
+// so a partial archive can not be read.
+func (p *Pairtree) writeZip(ctx context.Context, w io.Writer, src, skip string, cache *checksum.Cache) error {
+	zipWriter := zip.NewWriter(w)
+
+	err := afero.Walk(p.fs, src, func(filePath string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// Stop before the next file once the context is canceled
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// Do not archive the archive into itself when it is written inside the source
+		if filePath == skip {
+			return nil
+		}
+
+		rel, err := filepath.Rel(src, filePath)
+		if err != nil {
+			return err
+		}
+
+		return p.addToZip(ctx, zipWriter, filePath, filepath.ToSlash(filepath.Join(filepath.Base(src), rel)), info, cache)
+	})
+	if err != nil {
+		return err
+	}
+
+	return zipWriter.Close()
+}
+
+// addToZip writes the file under the name. Links are written with their target as their content, like
+// the zip tool does, and files that are not directories, regular files, or links are left out.
+func (p *Pairtree) addToZip(ctx context.Context, zipWriter *zip.Writer, filePath, name string, info fs.FileInfo,
+	cache *checksum.Cache) error {
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+
+	switch {
+	case info.IsDir():
+		header.Name += "/"
+		_, err := zipWriter.CreateHeader(header)
+		return err
+	case info.Mode()&fs.ModeSymlink != 0:
+		reader, ok := p.fs.(afero.LinkReader)
+		if !ok {
+			return &fs.PathError{Op: "readlink", Path: filePath, Err: afero.ErrNoReadlink}
+		}
+
+		link, err := reader.ReadlinkIfPossible(filePath)
+		if err != nil {
+			return err
+		}
+
+		writer, err := zipWriter.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.WriteString(writer, link)
+		return err
+	case !info.Mode().IsRegular():
+		return nil
+	}
+
+	header.Method = zip.Deflate
+	writer, err := zipWriter.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+
+	return p.copyContent(ctx, writer, filePath, info, cache)
+}
+
+// Zip compresses the source directory or file into a .zip archive in the destination directory, named
+// and written like the .tgz archive of TarGz
+func Zip(ctx context.Context, src, dest, prefix string, overwrite bool, opts ArchiveOptions) error {
+	return New(afero.NewOsFs(), "").withPrefix(prefix).Zip(ctx, src, dest, overwrite, opts)
+}
+
+// Zip compresses the source directory or file of the file system of the pairtree into a .zip archive
+func (p *Pairtree) Zip(ctx context.Context, src, dest string, overwrite bool, opts ArchiveOptions) error {
+	return p.archive(src, dest, zipExt, overwrite, func(w io.Writer, skip string) error {
+		return p.writeZip(ctx, w, src, skip, opts.Cache)
+	})
+}
+
+// ReadZip extracts the zip archive read from the reader as the object directory at dest, like
+// ReadTarGz. The index of a zip archive is at its end, so the archive is first written to a hidden
+// file beside dest that is removed once it has been extracted.
+func ReadZip(ctx context.Context, r io.Reader, dest string) error {
+	return New(afero.NewOsFs(), "").ReadZip(ctx, r, dest)
+}
+
+// ReadZip extracts the zip archive read from the reader as the object directory at dest of the file
+// system of the pairtree
+func (p *Pairtree) ReadZip(ctx context.Context, r io.Reader, dest string) (err error) {
+	parent := filepath.Dir(dest)
+
+	if err := p.fs.MkdirAll(parent, 0755); err != nil {
+		return err
+	}
+
+	spool, err := afero.TempFile(p.fs, parent, "."+filepath.Base(dest)+".zip-")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, spool.Close(), p.fs.Remove(spool.Name()))
+	}()
+
+	size, err := io.Copy(spool, r)
+	if err != nil {
+		return err
+	}
+
+	return p.readZip(ctx, spool, size, dest)
+}
+
+// UnZip extracts a zip archive to the destination directory, like UnTarGz
+func UnZip(ctx context.Context, src, dest string) error {
+	return New(afero.NewOsFs(), "").UnZip(ctx, src, dest)
+}
+
+// UnZip extracts a zip archive of the file system of the pairtree to the destination directory
+func (p *Pairtree) UnZip(ctx context.Context, src, dest string) error {
+	in, err := p.fs.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	return p.readZip(ctx, in, info.Size(), dest)
+}
+
+// readZip extracts the zip archive of the size as the object directory at dest. Its entries are
+// described with tar headers so they are extracted with the checks of a tar.gz archive.
+func (p *Pairtree) readZip(ctx context.Context, r io.ReaderAt, size int64, dest string) error {
+	zipReader, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+
+	next, done := zipEntries(zipReader.File)
+	defer done()
+
+	return p.extract(ctx, dest, next)
+}
+
+// zipEntries returns the files of a zip archive as entries described with tar headers, and a function
+// that closes the content of the last entry that was returned
+func zipEntries(files []*zip.File) (nextEntry, func()) {
+	var content io.ReadCloser
+	done := func() {
+		if content != nil {
+			content.Close()
+			content = nil
+		}
+	}
+
+	return func() (*tar.Header, io.Reader, error) {
+		done()
+
+		if len(files) == 0 {
+			return nil, nil, io.EOF
+		}
+		file := files[0]
+		files = files[1:]
+
+		var err error
+		if content, err = file.Open(); err != nil {
+			return nil, nil, err
+		}
+
+		mode := file.Mode()
+		header := &tar.Header{Name: file.Name, Mode: int64(mode.Perm()), Typeflag: tar.TypeReg}
+
+		switch {
+		case mode.IsDir():
+			header.Typeflag = tar.TypeDir
+		case mode&fs.ModeSymlink != 0:
+			link, err := io.ReadAll(io.LimitReader(content, maxZipLink))
+			if err != nil {
+				return nil, nil, err
+			}
+			header.Typeflag, header.Linkname = tar.TypeSymlink, string(link)
+		case !mode.IsRegular():
+			// Other entries are skipped, as they are in tar archives
+			header.Typeflag = tar.TypeFifo
+		}
+
+		return header, content, nil
+	}, done
+}
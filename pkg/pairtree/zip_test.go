@@ -0,0 +1,111 @@
+package pairtree
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestZipUnZip tests that an object written to a zip archive is extracted as it was
+func TestZipUnZip(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "a5388")
+	for _, path := range []string{"a5388.txt", "folder/inner.txt", "folder/.hidden/inner.txt", "empty/"} {
+		createPath(t, src, path)
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(src, "a5388.txt"), []byte("content"), 0644))
+	require.NoError(t, os.Chmod(filepath.Join(src, "a5388.txt"), 0640))
+	require.NoError(t, os.Symlink("folder/inner.txt", filepath.Join(src, "link.txt")))
+
+	archives := t.TempDir()
+	require.NoError(t, Zip(context.Background(), src, archives, "ark:/", false, ArchiveOptions{}))
+	archive := filepath.Join(archives, "ark+=a5388.zip")
+	assert.Equal(t, ZipFormat, ArchiveFormat(archive))
+
+	// The destination is replaced by the object in the archive
+	dest := filepath.Join(t.TempDir(), "pairtree_root", "a5", "38", "8", "a5388")
+	createPath(t, dest, "old.txt")
+	require.NoError(t, UnZip(context.Background(), archive, dest))
+
+	content, err := os.ReadFile(filepath.Join(dest, "a5388.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "content", string(content))
+
+	info, err := os.Stat(filepath.Join(dest, "a5388.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+
+	assert.FileExists(t, filepath.Join(dest, "folder", ".hidden", "inner.txt"))
+	assert.DirExists(t, filepath.Join(dest, "empty"))
+	assert.NoFileExists(t, filepath.Join(dest, "old.txt"))
+
+	link, err := os.Readlink(filepath.Join(dest, "link.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "folder/inner.txt", link)
+}
+
+// TestWriteReadZip tests that an object streamed through a zip archive is extracted without leaving
+// the spooled archive beside it
+func TestWriteReadZip(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "b5488")
+	createPath(t, src, "folder/inner.txt")
+
+	var archive bytes.Buffer
+	require.NoError(t, WriteZip(context.Background(), &archive, src))
+
+	dest := filepath.Join(t.TempDir(), "b5488")
+	require.NoError(t, ReadZip(context.Background(), &archive, dest))
+	assert.FileExists(t, filepath.Join(dest, "folder", "inner.txt"))
+
+	entries, err := os.ReadDir(filepath.Dir(dest))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "b5488", entries[0].Name())
+}
+
+// TestReadZipNotValid tests that zip archives without exactly the destination's folder are not extracted
+func TestReadZipNotValid(t *testing.T) {
+	tests := []struct {
+		name    string
+		names   []string
+		wantErr error
+	}{
+		{name: "Empty archive", wantErr: error_msgs.Err12},
+		{name: "More than one folder", names: []string{"a5388/", "b5488/"}, wantErr: error_msgs.Err12},
+		{name: "Path outside of the folder", names: []string{"a5388/../../escaped.txt"}, wantErr: error_msgs.Err12},
+		{name: "Folder of another object", names: []string{"b5488/file.txt"}, wantErr: error_msgs.Err13},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var archive bytes.Buffer
+			zipWriter := zip.NewWriter(&archive)
+			for _, name := range tt.names {
+				_, err := zipWriter.Create(name)
+				require.NoError(t, err)
+			}
+			require.NoError(t, zipWriter.Close())
+
+			// A failed extraction leaves the destination as it was
+			parent := t.TempDir()
+			dest := filepath.Join(parent, "a5388")
+			createPath(t, dest, "old.txt")
+
+			err := ReadZip(context.Background(), &archive, dest)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.FileExists(t, filepath.Join(dest, "old.txt"))
+
+			entries, err := os.ReadDir(parent)
+			require.NoError(t, err)
+			assert.Len(t, entries, 1)
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package pairtree
+
+import (
+	"context"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/spf13/afero"
+)
+
+// WriteTarZst writes the source directory or file to the writer as a tar archive compressed with
+// Zstandard, like WriteTarGz. Zstandard compresses and decompresses much faster than gzip, which
+// matters most for objects of many gigabytes, and its encoder compresses on every CPU as well.
+func WriteTarZst(ctx context.Context, w io.Writer, src string, opts ArchiveOptions) error {
+	return New(afero.NewOsFs(), "").WriteTarZst(ctx, w, src, opts)
+}
+
+// WriteTarZst writes the source directory or file of the file system of the pairtree to the writer as
+// a tar archive compressed with Zstandard
+func (p *Pairtree) WriteTarZst(ctx context.Context, w io.Writer, src string, opts ArchiveOptions) error {
+	return p.writeTarZst(ctx, w, src, "", opts)
+}
+
+// writeTarZst writes the tar.zst archive of the source, leaving out the file at skip. The archive is
+// not finished when the walk fails, so a reader of a partial archive sees that it is incomplete.
+func (p *Pairtree) writeTarZst(ctx context.Context, w io.Writer, src, skip string, opts ArchiveOptions) error {
+	out := &cutWriter{w: w}
+	zstdWriter, err := zstd.NewWriter(out, zstd.WithEncoderConcurrency(compressWorkers(opts)))
+	if err != nil {
+		return err
+	}
+
+	if err := p.writeTar(ctx, zstdWriter, src, skip, opts.Cache); err != nil {
+		// Stop the compressing goroutines without finishing the archive
+		out.cut.Store(true)
+		zstdWriter.Close()
+		return err
+	}
+
+	return zstdWriter.Close()
+}
+
+// TarZst compresses the source directory or file into a .tzst archive in the destination directory,
+// named and written like the .tgz archive of TarGz
+func TarZst(ctx context.Context, src, dest, prefix string, overwrite bool, opts ArchiveOptions) error {
+	return New(afero.NewOsFs(), "").withPrefix(prefix).TarZst(ctx, src, dest, overwrite, opts)
+}
+
+// TarZst compresses the source directory or file of the file system of the pairtree into a .tzst archive
+func (p *Pairtree) TarZst(ctx context.Context, src, dest string, overwrite bool, opts ArchiveOptions) error {
+	return p.archive(src, dest, tzstExt, overwrite, func(w io.Writer, skip string) error {
+		return p.writeTarZst(ctx, w, src, skip, opts)
+	})
+}
+
+// ReadTarZst extracts the tar archive compressed with Zstandard read from the reader as the object
+// directory at dest, like ReadTarGz
+func ReadTarZst(ctx context.Context, r io.Reader, dest string) error {
+	return New(afero.NewOsFs(), "").ReadTarZst(ctx, r, dest)
+}
+
+// ReadTarZst extracts the tar archive compressed with Zstandard read from the reader as the object
+// directory at dest of the file system of the pairtree
+func (p *Pairtree) ReadTarZst(ctx context.Context, r io.Reader, dest string) error {
+	zstdReader, err := zstd.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer zstdReader.Close()
+
+	return p.readTar(ctx, zstdReader, dest)
+}
+
+// UnTarZst extracts a tar archive compressed with Zstandard to the destination directory, like UnTarGz
+func UnTarZst(ctx context.Context, src, dest string) error {
+	return New(afero.NewOsFs(), "").UnTarZst(ctx, src, dest)
+}
+
+// UnTarZst extracts a tar archive compressed with Zstandard of the file system of the pairtree to the
+// destination directory
+func (p *Pairtree) UnTarZst(ctx context.Context, src, dest string) error {
+	in, err := p.fs.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	return p.ReadTarZst(ctx, in, dest)
+}
@@ -0,0 +1,66 @@
+package pairtree
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWriteReadTarZst tests that an object streamed through a tar.zst archive is extracted as it was
+func TestWriteReadTarZst(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "a5388")
+	for _, path := range []string{"a5388.txt", "folder/.hidden/inner.txt", "empty/"} {
+		createPath(t, src, path)
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(src, "a5388.txt"), []byte("content"), 0644))
+
+	var archive bytes.Buffer
+	require.NoError(t, WriteTarZst(context.Background(), &archive, src, ArchiveOptions{CompressWorkers: 2}))
+
+	// The archive is a Zstandard stream that any Zstandard reader can read
+	zstdReader, err := zstd.NewReader(nil)
+	require.NoError(t, err)
+	_, err = zstdReader.DecodeAll(archive.Bytes(), nil)
+	require.NoError(t, err)
+	zstdReader.Close()
+
+	dest := filepath.Join(t.TempDir(), "a5388")
+	require.NoError(t, ReadTarZst(context.Background(), &archive, dest))
+
+	content, err := os.ReadFile(filepath.Join(dest, "a5388.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "content", string(content))
+	assert.FileExists(t, filepath.Join(dest, "folder", ".hidden", "inner.txt"))
+	assert.DirExists(t, filepath.Join(dest, "empty"))
+}
+
+// TestArchiveFormats tests that an object is archived and extracted in each of the formats
+func TestArchiveFormats(t *testing.T) {
+	for _, format := range Formats {
+		t.Run(format, func(t *testing.T) {
+			t.Parallel()
+
+			src := filepath.Join(t.TempDir(), "b5488")
+			createPath(t, src, "folder/inner.txt")
+
+			archives := t.TempDir()
+			require.NoError(t, Archive(context.Background(), src, archives, "ark:/", format, false, ArchiveOptions{}))
+
+			entries, err := os.ReadDir(archives)
+			require.NoError(t, err)
+			require.Len(t, entries, 1)
+			archive := filepath.Join(archives, entries[0].Name())
+			assert.Equal(t, format, ArchiveFormat(archive))
+
+			dest := filepath.Join(t.TempDir(), "b5488")
+			require.NoError(t, UnArchive(context.Background(), archive, dest, format))
+			assert.FileExists(t, filepath.Join(dest, "folder", "inner.txt"))
+		})
+	}
+}
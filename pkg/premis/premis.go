@@ -0,0 +1,319 @@
+/*
+The premis package records the preservation events of pairtree objects, like ingesting or deleting
+them, as entries that map onto PREMIS 3 events. Each object's events are appended to its own file
+in the pairtree_events directory beside pairtree_root, so the history of an object is kept after the
+object itself is deleted.
+*/
+package premis
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pairtree"
+)
+
+// Event types from the Library of Congress PREMIS event type vocabulary
+const (
+	Creation    = "creation"
+	Ingestion   = "ingestion"
+	Deletion    = "deletion"
+	FixityCheck = "fixity check"
+	Migration   = "migration"
+	// MessageDigestCalculation is the writing of the checksums of an object's files
+	MessageDigestCalculation = "message digest calculation"
+)
+
+// Event outcomes
+const (
+	Success = "success"
+	Failure = "failure"
+)
+
+const (
+	// EventsDir is the directory beside pairtree_root that holds the events of each object
+	EventsDir = "pairtree_events"
+	// eventsExt is the extension of an object's events file, which has one JSON event per line
+	eventsExt = ".jsonl"
+	// Namespace is the namespace of PREMIS 3 XML
+	Namespace = "http://www.loc.gov/premis/v3"
+)
+
+// Event is a preservation action on a pairtree object, named after the PREMIS semantic units
+type Event struct {
+	Identifier    string    `json:"eventIdentifier"`
+	Type          string    `json:"eventType"`
+	DateTime      time.Time `json:"eventDateTime"`
+	Detail        string    `json:"eventDetail,omitempty"`
+	Outcome       string    `json:"eventOutcome"`
+	OutcomeDetail string    `json:"eventOutcomeDetail,omitempty"`
+	Object        string    `json:"linkingObjectIdentifier"`
+	Agent         string    `json:"linkingAgentIdentifier,omitempty"`
+}
+
+// NewEvent creates an event of the type on the object with the ID, the outcome is a failure
+// described by err when err is not nil
+func NewEvent(eventType, id, detail string, err error) Event {
+	event := Event{
+		Identifier: newUUID(),
+		Type:       eventType,
+		DateTime:   time.Now().UTC(),
+		Detail:     detail,
+		Outcome:    Success,
+		Object:     id,
+	}
+
+	if err != nil {
+		event.Outcome = Failure
+		event.OutcomeDetail = err.Error()
+	}
+
+	return event
+}
+
+// EventsPath returns the path of the events file of the object with the ID
+func EventsPath(ptRoot, prefix, id string) (string, error) {
+	pairPath, err := pairtree.CreatePP(id, ptRoot, prefix)
+	if err != nil {
+		return "", err
+	}
+
+	// The last directory of the pairpath is the encoded ID, which is safe to use as a file name
+	return pairtree.JoinPath(ptRoot, EventsDir, filepath.Base(pairPath)+eventsExt), nil
+}
+
+// Record appends the event to the events file of its object
+func Record(ptRoot, prefix string, event Event) error {
+	path, err := EventsPath(ptRoot, prefix, event.Object)
+	if err != nil {
+		return err
+	}
+
+	storage, err := pairtree.StorageFor(context.Background(), ptRoot)
+	if err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	// The line is appended in one call so that events recorded at the same time are not interleaved
+	return storage.Append(path, append(line, '\n'))
+}
+
+// Events returns the events of the object with the ID in the order they were recorded, an object
+// without recorded events has none
+func Events(ptRoot, prefix, id string) ([]Event, error) {
+	path, err := EventsPath(ptRoot, prefix, id)
+	if err != nil {
+		return nil, err
+	}
+
+	storage, err := pairtree.StorageFor(context.Background(), ptRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := readEvents(storage, path)
+	if errors.Is(err, os.ErrNotExist) {
+		return []Event{}, nil
+	}
+
+	return events, err
+}
+
+// AllEvents returns the events of every object, including deleted ones, in the order they happened
+func AllEvents(ptRoot string) ([]Event, error) {
+	paths, err := filepath.Glob(filepath.Join(ptRoot, EventsDir, "*"+eventsExt))
+	if err != nil {
+		return nil, err
+	}
+
+	all := []Event{}
+	for _, path := range paths {
+		events, err := readEvents(pairtree.Local, path)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, events...)
+	}
+
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].DateTime.Before(all[j].DateTime)
+	})
+
+	return all, nil
+}
+
+// readEvents reads the events in the events file at the path in the storage
+func readEvents(storage pairtree.Storage, path string) ([]Event, error) {
+	file, err := storage.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	events := []Event{}
+	scanner := bufio.NewScanner(file)
+
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, fmt.Errorf("%w: %s line %d: %v", error_msgs.Err28, path, line, err)
+		}
+		events = append(events, event)
+	}
+
+	return events, scanner.Err()
+}
+
+// newUUID returns a random version 4 UUID to identify an event
+func newUUID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// ToXML returns the events of the object with the ID as a PREMIS 3 XML document, which has the
+// object as an intellectual entity and pt as the agent of its events
+func ToXML(id string, events []Event) ([]byte, error) {
+	doc := xmlPremis{
+		Xmlns:    Namespace,
+		XmlnsXSI: "http://www.w3.org/2001/XMLSchema-instance",
+		Version:  "3.0",
+		Object: xmlObject{
+			Type:       "intellectualEntity",
+			Identifier: xmlIdentifier{Type: "local", Value: id},
+		},
+	}
+
+	agents := map[string]bool{}
+
+	for _, event := range events {
+		e := xmlEvent{
+			Identifier: xmlEventIdentifier{Type: "UUID", Value: event.Identifier},
+			Type:       event.Type,
+			DateTime:   event.DateTime.Format(time.RFC3339),
+			Outcome:    xmlOutcome{Outcome: event.Outcome},
+			Object:     &xmlLinkingObject{Type: "local", Value: event.Object},
+		}
+
+		if event.Detail != "" {
+			e.Detail = &xmlDetail{Detail: event.Detail}
+		}
+		if event.OutcomeDetail != "" {
+			e.Outcome.Detail = &xmlOutcomeDetail{Note: event.OutcomeDetail}
+		}
+		if event.Agent != "" {
+			e.Agent = &xmlLinkingAgent{Type: "software", Value: event.Agent}
+
+			if !agents[event.Agent] {
+				agents[event.Agent] = true
+				doc.Agents = append(doc.Agents, xmlAgent{
+					Identifier: xmlAgentIdentifier{Type: "software", Value: event.Agent},
+					Name:       event.Agent,
+					Type:       "software",
+				})
+			}
+		}
+
+		doc.Events = append(doc.Events, e)
+	}
+
+	output, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), output...), nil
+}
+
+// The PREMIS 3 XML elements the events are written as
+type xmlPremis struct {
+	XMLName  xml.Name   `xml:"premis"`
+	Xmlns    string     `xml:"xmlns,attr"`
+	XmlnsXSI string     `xml:"xmlns:xsi,attr"`
+	Version  string     `xml:"version,attr"`
+	Object   xmlObject  `xml:"object"`
+	Events   []xmlEvent `xml:"event"`
+	Agents   []xmlAgent `xml:"agent"`
+}
+
+type xmlObject struct {
+	Type       string        `xml:"xsi:type,attr"`
+	Identifier xmlIdentifier `xml:"objectIdentifier"`
+}
+
+type xmlIdentifier struct {
+	Type  string `xml:"objectIdentifierType"`
+	Value string `xml:"objectIdentifierValue"`
+}
+
+type xmlEvent struct {
+	Identifier xmlEventIdentifier `xml:"eventIdentifier"`
+	Type       string             `xml:"eventType"`
+	DateTime   string             `xml:"eventDateTime"`
+	Detail     *xmlDetail         `xml:"eventDetailInformation"`
+	Outcome    xmlOutcome         `xml:"eventOutcomeInformation"`
+	Agent      *xmlLinkingAgent   `xml:"linkingAgentIdentifier"`
+	Object     *xmlLinkingObject  `xml:"linkingObjectIdentifier"`
+}
+
+type xmlEventIdentifier struct {
+	Type  string `xml:"eventIdentifierType"`
+	Value string `xml:"eventIdentifierValue"`
+}
+
+type xmlDetail struct {
+	Detail string `xml:"eventDetail"`
+}
+
+type xmlOutcome struct {
+	Outcome string            `xml:"eventOutcome"`
+	Detail  *xmlOutcomeDetail `xml:"eventOutcomeDetail"`
+}
+
+type xmlOutcomeDetail struct {
+	Note string `xml:"eventOutcomeDetailNote"`
+}
+
+type xmlLinkingAgent struct {
+	Type  string `xml:"linkingAgentIdentifierType"`
+	Value string `xml:"linkingAgentIdentifierValue"`
+}
+
+type xmlLinkingObject struct {
+	Type  string `xml:"linkingObjectIdentifierType"`
+	Value string `xml:"linkingObjectIdentifierValue"`
+}
+
+type xmlAgent struct {
+	Identifier xmlAgentIdentifier `xml:"agentIdentifier"`
+	Name       string             `xml:"agentName"`
+	Type       string             `xml:"agentType"`
+}
+
+type xmlAgentIdentifier struct {
+	Type  string `xml:"agentIdentifierType"`
+	Value string `xml:"agentIdentifierValue"`
+}
@@ -0,0 +1,146 @@
+package premis
+
+import (
+	"encoding/xml"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pttest"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const prefix = "ark:/"
+
+// TestNewEvent tests that the outcome of an event follows the error of the action
+func TestNewEvent(t *testing.T) {
+	event := NewEvent(Ingestion, "ark:/a5388", "copied from /tmp/a5388", nil)
+	assert.Equal(t, Success, event.Outcome)
+	assert.Empty(t, event.OutcomeDetail)
+	assert.Regexp(t, regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`), event.Identifier)
+
+	event = NewEvent(Deletion, "ark:/a5388", "", errors.New("permission denied"))
+	assert.Equal(t, Failure, event.Outcome)
+	assert.Equal(t, "permission denied", event.OutcomeDetail)
+}
+
+// TestRecordAndEvents tests that events are kept per object in the order they were recorded
+func TestRecordAndEvents(t *testing.T) {
+	fs := afero.NewOsFs()
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, fs)
+
+	first := NewEvent(Ingestion, "ark:/a5388", "copied from /tmp/a5388", nil)
+	second := NewEvent(Deletion, "ark:/a5388", "deleted the object", nil)
+	other := NewEvent(Ingestion, "ark:/b5488", "", nil)
+
+	for _, event := range []Event{first, second, other} {
+		require.NoError(t, Record(ptRoot, prefix, event))
+	}
+
+	events, err := Events(ptRoot, prefix, "ark:/a5388")
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, first.Identifier, events[0].Identifier)
+	assert.Equal(t, second.Identifier, events[1].Identifier)
+	assert.True(t, first.DateTime.Equal(events[0].DateTime))
+
+	// The events file is beside the pairtree_root and named after the encoded ID
+	path, err := EventsPath(ptRoot, prefix, "ark:/a5388")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(ptRoot, EventsDir, "a5388.jsonl"), path)
+
+	events, err = Events(ptRoot, prefix, "ark:/notRecorded")
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+// TestAllEvents tests that the events of every object are returned in the order they happened
+func TestAllEvents(t *testing.T) {
+	fs := afero.NewOsFs()
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, fs)
+
+	events, err := AllEvents(ptRoot)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	first := NewEvent(Ingestion, "ark:/b5488", "", nil)
+	second := NewEvent(Ingestion, "ark:/a5388", "", nil)
+	third := NewEvent(Deletion, "ark:/b5488", "", nil)
+	second.DateTime = first.DateTime.Add(time.Second)
+	third.DateTime = first.DateTime.Add(2 * time.Second)
+
+	for _, event := range []Event{first, second, third} {
+		require.NoError(t, Record(ptRoot, prefix, event))
+	}
+
+	events, err = AllEvents(ptRoot)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.Equal(t, []string{first.Identifier, second.Identifier, third.Identifier},
+		[]string{events[0].Identifier, events[1].Identifier, events[2].Identifier})
+}
+
+// TestEventsNotValid tests that an events file with a line that is not an event is reported
+func TestEventsNotValid(t *testing.T) {
+	fs := afero.NewOsFs()
+	ptRoot := pttest.StandardPairtree().BuildTemp(t, fs)
+
+	path, err := EventsPath(ptRoot, prefix, "ark:/a5388")
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte("{\"eventType\":\"ingestion\"}\nnot json\n"), 0644))
+
+	_, err = Events(ptRoot, prefix, "ark:/a5388")
+	assert.ErrorIs(t, err, error_msgs.Err28)
+}
+
+// TestToXML tests that the events are written as PREMIS with the object, events, and agent
+func TestToXML(t *testing.T) {
+	ingest := NewEvent(Ingestion, "ark:/a5388", "copied from /tmp/a5388", nil)
+	ingest.Agent = "pt v1.2.0"
+	deletion := NewEvent(Deletion, "ark:/a5388", "deleted the object", errors.New("permission denied"))
+	deletion.Agent = "pt v1.2.0"
+
+	output, err := ToXML("ark:/a5388", []Event{ingest, deletion})
+	require.NoError(t, err)
+
+	var doc struct {
+		XMLName xml.Name
+		Object  struct {
+			Value string `xml:"objectIdentifier>objectIdentifierValue"`
+		} `xml:"object"`
+		Events []struct {
+			ID            string `xml:"eventIdentifier>eventIdentifierValue"`
+			Type          string `xml:"eventType"`
+			Outcome       string `xml:"eventOutcomeInformation>eventOutcome"`
+			OutcomeDetail string `xml:"eventOutcomeInformation>eventOutcomeDetail>eventOutcomeDetailNote"`
+			Agent         string `xml:"linkingAgentIdentifier>linkingAgentIdentifierValue"`
+		} `xml:"event"`
+		Agents []struct {
+			Name string `xml:"agentName"`
+		} `xml:"agent"`
+	}
+	require.NoError(t, xml.Unmarshal(output, &doc))
+
+	assert.Equal(t, Namespace, doc.XMLName.Space)
+	assert.Equal(t, "premis", doc.XMLName.Local)
+	assert.Equal(t, "ark:/a5388", doc.Object.Value)
+
+	require.Len(t, doc.Events, 2)
+	assert.Equal(t, ingest.Identifier, doc.Events[0].ID)
+	assert.Equal(t, Ingestion, doc.Events[0].Type)
+	assert.Equal(t, Success, doc.Events[0].Outcome)
+	assert.Equal(t, "pt v1.2.0", doc.Events[0].Agent)
+	assert.Equal(t, Failure, doc.Events[1].Outcome)
+	assert.Equal(t, "permission denied", doc.Events[1].OutcomeDetail)
+
+	// An agent is listed once however many of the events it performed
+	require.Len(t, doc.Agents, 1)
+	assert.Equal(t, "pt v1.2.0", doc.Agents[0].Name)
+}
@@ -0,0 +1,191 @@
+package pttest
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	caltech_pairtree "github.com/caltechlibrary/pairtree"
+	"github.com/spf13/afero"
+)
+
+// Names of the files and directories at the top of a pairtree
+const (
+	PrefixFile  = "pairtree_prefix"
+	VersionFile = "pairtree_version0_1"
+	RootDir     = "pairtree_root"
+)
+
+// VersionSpec is the content of the pairtree_version0_1 file
+const VersionSpec = "This directory conforms to Pairtree Version 0.1. Updated spec: http://www.cdlib.org/inside/diglib/pairtree/pairtreespec.html "
+
+// ObjectSpec describes a set of generated objects added with PairtreeBuilder.WithObjects
+type ObjectSpec struct {
+	Count          int
+	FilesPerObject int
+	FileSize       int
+	Hidden         bool
+}
+
+// PairtreeBuilder constructs a synthetic pairtree for tests
+type PairtreeBuilder struct {
+	prefix  string
+	version string
+	objects []builderObject
+}
+
+// builderObject is an object in the pairtree and the files in it
+type builderObject struct {
+	id    string
+	files []builderFile
+}
+
+// builderFile is a file, or a directory when its path ends in a slash, in an object
+type builderFile struct {
+	path    string
+	content []byte
+}
+
+// NewPairtreeBuilder creates a builder for an empty pairtree with the ark:/ prefix
+func NewPairtreeBuilder() *PairtreeBuilder {
+	return &PairtreeBuilder{prefix: "ark:/", version: VersionSpec}
+}
+
+// StandardPairtree creates a builder for the pairtree that the command tests share
+func StandardPairtree() *PairtreeBuilder {
+	return NewPairtreeBuilder().
+		WithObject("ark:/a5388", "a5388.txt").
+		WithObject("ark:/a5488", "a5488.txt").
+		WithObject("ark:/a54892", "a54892.txt", ".hidden.txt", ".hidden/innerHidden.txt").
+		WithObject("ark:/b5488", "outerb5488.txt", "folder/innerb5488.txt", "folder/.hiddenFile.txt",
+			"folder/.hidden/inner.txt")
+}
+
+// WithPrefix sets the prefix of the pairtree; an empty prefix leaves out the pairtree_prefix file
+func (b *PairtreeBuilder) WithPrefix(prefix string) *PairtreeBuilder {
+	b.prefix = prefix
+	return b
+}
+
+// WithVersion sets the content of the pairtree_version0_1 file
+func (b *PairtreeBuilder) WithVersion(version string) *PairtreeBuilder {
+	b.version = version
+	return b
+}
+
+// WithObject adds an object with empty files at the paths; paths ending in a slash are directories
+func (b *PairtreeBuilder) WithObject(id string, paths ...string) *PairtreeBuilder {
+	obj := builderObject{id: id}
+	for _, path := range paths {
+		obj.files = append(obj.files, builderFile{path: path})
+	}
+
+	b.objects = append(b.objects, obj)
+	return b
+}
+
+// WithFile adds a file with the content to the object, adding the object if it is not in the builder yet
+func (b *PairtreeBuilder) WithFile(id, path string, content []byte) *PairtreeBuilder {
+	for i := range b.objects {
+		if b.objects[i].id == id {
+			b.objects[i].files = append(b.objects[i].files, builderFile{path: path, content: content})
+			return b
+		}
+	}
+
+	b.objects = append(b.objects, builderObject{id: id, files: []builderFile{{path: path, content: content}}})
+	return b
+}
+
+// WithObjects adds generated objects whose IDs are the prefix followed by a sequence number
+func (b *PairtreeBuilder) WithObjects(spec ObjectSpec) *PairtreeBuilder {
+	content := bytes.Repeat([]byte("x"), spec.FileSize)
+
+	for i := 0; i < spec.Count; i++ {
+		obj := builderObject{id: fmt.Sprintf("%sobj%06d", b.prefix, len(b.objects))}
+		for j := 0; j < spec.FilesPerObject; j++ {
+			obj.files = append(obj.files, builderFile{path: fmt.Sprintf("file%03d.txt", j), content: content})
+		}
+		if spec.Hidden {
+			obj.files = append(obj.files, builderFile{path: ".hidden.txt", content: content})
+		}
+
+		b.objects = append(b.objects, obj)
+	}
+
+	return b
+}
+
+// IDs returns the IDs of the objects in the order they were added
+func (b *PairtreeBuilder) IDs() []string {
+	ids := make([]string, 0, len(b.objects))
+	for _, obj := range b.objects {
+		ids = append(ids, obj.id)
+	}
+
+	return ids
+}
+
+// ObjectPath returns the path of the object in a pairtree built in dir
+func (b *PairtreeBuilder) ObjectPath(dir, id string) string {
+	id = strings.TrimPrefix(id, b.prefix)
+	leaf := string(caltech_pairtree.CharEncode([]rune(id)))
+
+	return filepath.Join(dir, RootDir, caltech_pairtree.Encode(id), leaf)
+}
+
+// Build writes the pairtree into dir on the file system and returns dir
+func (b *PairtreeBuilder) Build(t testing.TB, fs afero.Fs, dir string) string {
+	t.Helper()
+
+	if err := fs.MkdirAll(filepath.Join(dir, RootDir), 0755); err != nil {
+		t.Fatalf("Failed to create the pairtree root in %s: %v", dir, err)
+	}
+
+	if b.prefix != "" {
+		writeBuilderFile(t, fs, filepath.Join(dir, PrefixFile), []byte(b.prefix))
+	}
+
+	writeBuilderFile(t, fs, filepath.Join(dir, VersionFile), []byte(b.version))
+
+	for _, obj := range b.objects {
+		objPath := b.ObjectPath(dir, obj.id)
+		if err := fs.MkdirAll(objPath, 0755); err != nil {
+			t.Fatalf("Failed to create object %s: %v", obj.id, err)
+		}
+
+		for _, file := range obj.files {
+			path := filepath.Join(objPath, file.path)
+			if strings.HasSuffix(file.path, "/") {
+				if err := fs.MkdirAll(path, 0755); err != nil {
+					t.Fatalf("Failed to create directory %s: %v", path, err)
+				}
+				continue
+			}
+
+			if err := fs.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatalf("Failed to create directory %s: %v", filepath.Dir(path), err)
+			}
+			writeBuilderFile(t, fs, path, file.content)
+		}
+	}
+
+	return dir
+}
+
+// BuildTemp writes the pairtree into a new temporary directory and returns its path
+func (b *PairtreeBuilder) BuildTemp(t *testing.T, fs afero.Fs) string {
+	t.Helper()
+	return b.Build(t, fs, CreateTempDir(t, fs))
+}
+
+// writeBuilderFile writes the content to the file or fails the test
+func writeBuilderFile(t testing.TB, fs afero.Fs, path string, content []byte) {
+	t.Helper()
+
+	if err := afero.WriteFile(fs, path, content, 0644); err != nil {
+		t.Fatalf("Failed to write file %s: %v", path, err)
+	}
+}
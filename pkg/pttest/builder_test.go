@@ -0,0 +1,111 @@
+package pttest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStandardPairtree tests that the standard pairtree has the objects the command tests use
+func TestStandardPairtree(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	builder := StandardPairtree()
+	dir := builder.Build(t, fs, "/pt")
+
+	prefix, err := afero.ReadFile(fs, filepath.Join(dir, PrefixFile))
+	require.NoError(t, err)
+	assert.Equal(t, "ark:/", string(prefix))
+
+	version, err := afero.ReadFile(fs, filepath.Join(dir, VersionFile))
+	require.NoError(t, err)
+	assert.Equal(t, VersionSpec, string(version))
+
+	files := []string{
+		filepath.Join("a5", "38", "8", "a5388", "a5388.txt"),
+		filepath.Join("a5", "48", "92", "a54892", ".hidden", "innerHidden.txt"),
+		filepath.Join("b5", "48", "8", "b5488", "folder", ".hidden", "inner.txt"),
+	}
+	for _, file := range files {
+		exists, err := afero.Exists(fs, filepath.Join(dir, RootDir, file))
+		require.NoError(t, err)
+		assert.True(t, exists, "Expected %s to exist", file)
+	}
+
+	assert.Equal(t, []string{"ark:/a5388", "ark:/a5488", "ark:/a54892", "ark:/b5488"}, builder.IDs())
+}
+
+// TestWithObjects tests that generated objects have the requested number, size, and kind of files
+func TestWithObjects(t *testing.T) {
+	tests := []struct {
+		name      string
+		prefix    string
+		spec      ObjectSpec
+		expectLen int
+	}{
+		{name: "empty", prefix: "ark:/", spec: ObjectSpec{}, expectLen: 0},
+		{name: "files", prefix: "ark:/", spec: ObjectSpec{Count: 3, FilesPerObject: 2, FileSize: 16}, expectLen: 2},
+		{name: "hidden", prefix: "ark:/", spec: ObjectSpec{Count: 2, FilesPerObject: 1, Hidden: true}, expectLen: 2},
+		{name: "other prefix", prefix: "doi:", spec: ObjectSpec{Count: 1, FilesPerObject: 1}, expectLen: 1},
+		{name: "scale", prefix: "ark:/", spec: ObjectSpec{Count: 1000, FilesPerObject: 1, FileSize: 1}, expectLen: 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			builder := NewPairtreeBuilder().WithPrefix(test.prefix).WithObjects(test.spec)
+			dir := builder.Build(t, fs, "/pt")
+
+			ids := builder.IDs()
+			require.Len(t, ids, test.spec.Count)
+
+			for _, id := range ids {
+				assert.Contains(t, id, test.prefix)
+
+				entries, err := afero.ReadDir(fs, builder.ObjectPath(dir, id))
+				require.NoError(t, err)
+				require.Len(t, entries, test.expectLen)
+
+				for _, entry := range entries {
+					assert.Equal(t, int64(test.spec.FileSize), entry.Size())
+				}
+			}
+		})
+	}
+}
+
+// TestWithFile tests that files with content and empty directories are written into objects
+func TestWithFile(t *testing.T) {
+	fs := afero.NewOsFs()
+	builder := NewPairtreeBuilder().
+		WithObject("ark:/c123", "empty/").
+		WithFile("ark:/c123", "data/file.txt", []byte("content"))
+	dir := builder.BuildTemp(t, fs)
+
+	objPath := builder.ObjectPath(dir, "ark:/c123")
+	assert.Equal(t, filepath.Join(dir, RootDir, "c1", "23", "c123"), objPath)
+
+	isDir, err := afero.DirExists(fs, filepath.Join(objPath, "empty"))
+	require.NoError(t, err)
+	assert.True(t, isDir)
+
+	content, err := afero.ReadFile(fs, filepath.Join(objPath, "data", "file.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "content", string(content))
+}
+
+// TestWithoutPrefix tests that an empty prefix leaves out the prefix file
+func TestWithoutPrefix(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	dir := NewPairtreeBuilder().WithPrefix("").WithObject("c123", "file.txt").Build(t, fs, "/pt")
+
+	exists, err := afero.Exists(fs, filepath.Join(dir, PrefixFile))
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	exists, err = afero.Exists(fs, filepath.Join(dir, RootDir, "c1", "23", "c123", "file.txt"))
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
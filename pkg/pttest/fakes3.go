@@ -0,0 +1,272 @@
+package pttest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// FakeS3 keeps S3 buckets in memory and answers the part of the S3 API that pairtrees in S3 are kept
+// with, so that code working with pairtrees in S3 can be tested without a network or credentials
+type FakeS3 struct {
+	mu       sync.Mutex
+	objects  map[string][]byte
+	uploads  map[string]map[int32][]byte
+	modTime  time.Time
+	uploadID int
+	// MaxKeys is the most keys listed in a page when a request does not ask for fewer
+	MaxKeys int32
+}
+
+// NewFakeS3 creates a FakeS3 without any objects
+func NewFakeS3() *FakeS3 {
+	return &FakeS3{
+		objects: map[string][]byte{},
+		uploads: map[string]map[int32][]byte{},
+		modTime: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		MaxKeys: 1000,
+	}
+}
+
+// Upload copies the files in the directory on the local file system to the s3:// URL
+func (f *FakeS3) Upload(t testing.TB, dir, url string) {
+	t.Helper()
+
+	bucket, prefix, _ := strings.Cut(strings.TrimPrefix(url, "s3://"), "/")
+	err := filepath.WalkDir(dir, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+
+		f.put(bucket, path.Join(prefix, filepath.ToSlash(rel)), data)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to upload %s: %v", dir, err)
+	}
+}
+
+// Object returns the content of the object with the key in the bucket and whether it exists
+func (f *FakeS3) Object(bucket, key string) ([]byte, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	data, ok := f.objects[bucket+"/"+key]
+	return data, ok
+}
+
+// Keys returns the keys of the objects in the bucket in order
+func (f *FakeS3) Keys(bucket string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.keys(bucket, "")
+}
+
+// Uploads returns the number of multipart uploads that have been started and not completed or aborted
+func (f *FakeS3) Uploads() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return len(f.uploads)
+}
+
+func (f *FakeS3) put(bucket, key string, data []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.objects[bucket+"/"+key] = data
+}
+
+// keys returns the keys in the bucket that start with the prefix in order
+func (f *FakeS3) keys(bucket, prefix string) []string {
+	var keys []string
+	for name := range f.objects {
+		if key, ok := strings.CutPrefix(name, bucket+"/"); ok && strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+func (f *FakeS3) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	data, ok := f.Object(aws.ToString(params.Bucket), aws.ToString(params.Key))
+	if !ok {
+		return nil, &types.NotFound{}
+	}
+
+	return &s3.HeadObjectOutput{ContentLength: aws.Int64(int64(len(data))), LastModified: aws.Time(f.modTime)}, nil
+}
+
+func (f *FakeS3) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	data, ok := f.Object(aws.ToString(params.Bucket), aws.ToString(params.Key))
+	if !ok {
+		return nil, &types.NoSuchKey{}
+	}
+
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(data)), ContentLength: aws.Int64(int64(len(data)))}, nil
+}
+
+func (f *FakeS3) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(params.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	f.put(aws.ToString(params.Bucket), aws.ToString(params.Key), data)
+	return &s3.PutObjectOutput{}, nil
+}
+
+// ListObjectsV2 lists the keys after the continuation token, where the token is the last key listed
+func (f *FakeS3) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	prefix := aws.ToString(params.Prefix)
+	delimiter := aws.ToString(params.Delimiter)
+	maxKeys := f.MaxKeys
+	if params.MaxKeys != nil && *params.MaxKeys < maxKeys {
+		maxKeys = *params.MaxKeys
+	}
+
+	output := &s3.ListObjectsV2Output{}
+	last := ""
+	for _, key := range f.keys(aws.ToString(params.Bucket), prefix) {
+		if token := aws.ToString(params.ContinuationToken); token != "" && (key <= token ||
+			delimiter != "" && strings.HasSuffix(token, delimiter) && strings.HasPrefix(key, token)) {
+			continue
+		}
+
+		// Keys with the delimiter after the prefix are listed once as their common prefix
+		common := ""
+		if i := strings.Index(key[len(prefix):], delimiter); delimiter != "" && i >= 0 {
+			common = key[:len(prefix)+i+len(delimiter)]
+			if common == last {
+				continue
+			}
+		}
+
+		if int32(len(output.Contents)+len(output.CommonPrefixes)) == maxKeys {
+			output.IsTruncated = aws.Bool(true)
+			output.NextContinuationToken = aws.String(last)
+			break
+		}
+
+		if common != "" {
+			output.CommonPrefixes = append(output.CommonPrefixes, types.CommonPrefix{Prefix: aws.String(common)})
+			last = common
+			continue
+		}
+
+		data := f.objects[aws.ToString(params.Bucket)+"/"+key]
+		output.Contents = append(output.Contents, types.Object{
+			Key: aws.String(key), Size: aws.Int64(int64(len(data))), LastModified: aws.Time(f.modTime),
+		})
+		last = key
+	}
+
+	output.KeyCount = aws.Int32(int32(len(output.Contents) + len(output.CommonPrefixes)))
+	return output, nil
+}
+
+func (f *FakeS3) DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, object := range params.Delete.Objects {
+		delete(f.objects, aws.ToString(params.Bucket)+"/"+aws.ToString(object.Key))
+	}
+
+	return &s3.DeleteObjectsOutput{}, nil
+}
+
+func (f *FakeS3) CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.uploadID++
+	id := fmt.Sprintf("upload-%d", f.uploadID)
+	f.uploads[id] = map[int32][]byte{}
+
+	return &s3.CreateMultipartUploadOutput{UploadId: aws.String(id)}, nil
+}
+
+func (f *FakeS3) UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(params.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	parts, ok := f.uploads[aws.ToString(params.UploadId)]
+	if !ok {
+		return nil, &types.NoSuchUpload{}
+	}
+	parts[aws.ToInt32(params.PartNumber)] = data
+
+	return &s3.UploadPartOutput{ETag: aws.String(fmt.Sprintf("etag-%d", aws.ToInt32(params.PartNumber)))}, nil
+}
+
+func (f *FakeS3) CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	parts, ok := f.uploads[aws.ToString(params.UploadId)]
+	if !ok {
+		return nil, &types.NoSuchUpload{}
+	}
+
+	var data []byte
+	for _, part := range params.MultipartUpload.Parts {
+		data = append(data, parts[aws.ToInt32(part.PartNumber)]...)
+	}
+
+	f.objects[aws.ToString(params.Bucket)+"/"+aws.ToString(params.Key)] = data
+	delete(f.uploads, aws.ToString(params.UploadId))
+
+	return &s3.CompleteMultipartUploadOutput{}, nil
+}
+
+func (f *FakeS3) AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.uploads, aws.ToString(params.UploadId))
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
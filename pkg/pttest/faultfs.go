@@ -0,0 +1,245 @@
+package pttest
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/spf13/afero"
+)
+
+// FaultFs wraps a file system and injects errors into its operations so that how code recovers
+// from a failing disk or network mount can be tested deterministically
+type FaultFs struct {
+	afero.Fs
+
+	mu          sync.Mutex
+	writes      int
+	writeFaults map[int]writeFault
+	readDirErr  error
+	renameErr   error
+	renameErrs  map[string]error
+	removeErr   error
+}
+
+// writeFault is the fault injected into one numbered write
+type writeFault struct {
+	err   error
+	short bool
+}
+
+// faultFile is a file opened through a FaultFs
+type faultFile struct {
+	afero.File
+	fs *FaultFs
+}
+
+// NewFaultFs creates a FaultFs that passes every operation to fs until faults are added
+func NewFaultFs(fs afero.Fs) *FaultFs {
+	return &FaultFs{Fs: fs, writeFaults: map[int]writeFault{}, renameErrs: map[string]error{}}
+}
+
+// FailWrite makes the nth write, counted from 1 across every file, fail with err without writing
+func (f *FaultFs) FailWrite(n int, err error) *FaultFs {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.writeFaults[n] = writeFault{err: err}
+	return f
+}
+
+// ShortWrite makes the nth write, counted from 1 across every file, write only half of its bytes
+func (f *FaultFs) ShortWrite(n int) *FaultFs {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.writeFaults[n] = writeFault{err: io.ErrShortWrite, short: true}
+	return f
+}
+
+// FailReadDir makes every directory listing fail with err, or stop failing when err is nil
+func (f *FaultFs) FailReadDir(err error) *FaultFs {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.readDirErr = err
+	return f
+}
+
+// FailRename makes every rename fail with err, or stop failing when err is nil
+func (f *FaultFs) FailRename(err error) *FaultFs {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.renameErr = err
+	return f
+}
+
+// FailRenameOf makes renames of the file or directory at the path fail with err, like renames of a path
+// on another device, or stop failing when err is nil
+func (f *FaultFs) FailRenameOf(name string, err error) *FaultFs {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.renameErrs[filepath.Clean(name)] = err
+	return f
+}
+
+// FailRemove makes every remove fail with err, or stop failing when err is nil
+func (f *FaultFs) FailRemove(err error) *FaultFs {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.removeErr = err
+	return f
+}
+
+// Writes returns the number of writes made through the file system so far
+func (f *FaultFs) Writes() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.writes
+}
+
+// Name returns the name of the file system
+func (f *FaultFs) Name() string {
+	return "FaultFs"
+}
+
+// Create creates the file in the wrapped file system
+func (f *FaultFs) Create(name string) (afero.File, error) {
+	return f.wrap(f.Fs.Create(name))
+}
+
+// Open opens the file in the wrapped file system
+func (f *FaultFs) Open(name string) (afero.File, error) {
+	return f.wrap(f.Fs.Open(name))
+}
+
+// OpenFile opens the file in the wrapped file system
+func (f *FaultFs) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+	return f.wrap(f.Fs.OpenFile(name, flag, perm))
+}
+
+// Rename renames the file unless renames are failing
+func (f *FaultFs) Rename(oldname, newname string) error {
+	if err := f.fault(&f.renameErr); err != nil {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: err}
+	}
+
+	f.mu.Lock()
+	err := f.renameErrs[filepath.Clean(oldname)]
+	f.mu.Unlock()
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: err}
+	}
+
+	return f.Fs.Rename(oldname, newname)
+}
+
+// Remove removes the file unless removes are failing
+func (f *FaultFs) Remove(name string) error {
+	if err := f.fault(&f.removeErr); err != nil {
+		return &fs.PathError{Op: "remove", Path: name, Err: err}
+	}
+
+	return f.Fs.Remove(name)
+}
+
+// RemoveAll removes the path and its contents unless removes are failing
+func (f *FaultFs) RemoveAll(path string) error {
+	if err := f.fault(&f.removeErr); err != nil {
+		return &fs.PathError{Op: "removeall", Path: path, Err: err}
+	}
+
+	return f.Fs.RemoveAll(path)
+}
+
+// wrap wraps an opened file so its writes and listings go through the faults
+func (f *FaultFs) wrap(file afero.File, err error) (afero.File, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	return &faultFile{File: file, fs: f}, nil
+}
+
+// fault returns the error of an operation's fault while holding the lock
+func (f *FaultFs) fault(err *error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return *err
+}
+
+// nextWrite counts a write and returns the fault injected into it, if any
+func (f *FaultFs) nextWrite() (writeFault, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.writes++
+	fault, ok := f.writeFaults[f.writes]
+	return fault, ok
+}
+
+// Write writes to the file unless the write has a fault
+func (file *faultFile) Write(p []byte) (int, error) {
+	fault, ok := file.fs.nextWrite()
+	if !ok {
+		return file.File.Write(p)
+	}
+
+	if fault.short {
+		n, err := file.File.Write(p[:len(p)/2])
+		if err != nil {
+			return n, err
+		}
+		return n, fault.err
+	}
+
+	return 0, &fs.PathError{Op: "write", Path: file.Name(), Err: fault.err}
+}
+
+// WriteAt writes to the file at the offset unless the write has a fault
+func (file *faultFile) WriteAt(p []byte, off int64) (int, error) {
+	fault, ok := file.fs.nextWrite()
+	if !ok {
+		return file.File.WriteAt(p, off)
+	}
+
+	if fault.short {
+		n, err := file.File.WriteAt(p[:len(p)/2], off)
+		if err != nil {
+			return n, err
+		}
+		return n, fault.err
+	}
+
+	return 0, &fs.PathError{Op: "write", Path: file.Name(), Err: fault.err}
+}
+
+// WriteString writes the string to the file unless the write has a fault
+func (file *faultFile) WriteString(s string) (int, error) {
+	return file.Write([]byte(s))
+}
+
+// Readdir lists the directory unless listings are failing
+func (file *faultFile) Readdir(count int) ([]os.FileInfo, error) {
+	if err := file.fs.fault(&file.fs.readDirErr); err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: file.Name(), Err: err}
+	}
+
+	return file.File.Readdir(count)
+}
+
+// Readdirnames lists the names in the directory unless listings are failing
+func (file *faultFile) Readdirnames(n int) ([]string, error) {
+	if err := file.fs.fault(&file.fs.readDirErr); err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: file.Name(), Err: err}
+	}
+
+	return file.File.Readdirnames(n)
+}
@@ -0,0 +1,92 @@
+package pttest
+
+import (
+	"io"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFailWrite tests that only the numbered write fails
+func TestFailWrite(t *testing.T) {
+	fs := NewFaultFs(afero.NewMemMapFs()).FailWrite(2, syscall.EIO)
+
+	file, err := fs.Create("/file.txt")
+	require.NoError(t, err)
+	defer file.Close()
+
+	_, err = file.Write([]byte("one"))
+	require.NoError(t, err)
+
+	n, err := file.WriteString("two")
+	assert.ErrorIs(t, err, syscall.EIO)
+	assert.Equal(t, 0, n)
+
+	_, err = file.Write([]byte("three"))
+	require.NoError(t, err)
+	assert.Equal(t, 3, fs.Writes())
+
+	content, err := afero.ReadFile(fs, "/file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "onethree", string(content))
+}
+
+// TestShortWrite tests that the numbered write only writes half of its bytes
+func TestShortWrite(t *testing.T) {
+	fs := NewFaultFs(afero.NewMemMapFs()).ShortWrite(1)
+
+	err := afero.WriteFile(fs, "/file.txt", []byte("abcdef"), 0644)
+	assert.ErrorIs(t, err, io.ErrShortWrite)
+
+	content, err := afero.ReadFile(fs, "/file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "abc", string(content))
+}
+
+// TestFailReadDir tests that directory listings fail until the fault is cleared
+func TestFailReadDir(t *testing.T) {
+	fs := NewFaultFs(afero.NewMemMapFs()).FailReadDir(syscall.ESTALE)
+	require.NoError(t, fs.MkdirAll("/dir/sub", 0755))
+
+	_, err := afero.ReadDir(fs, "/dir")
+	assert.ErrorIs(t, err, syscall.ESTALE)
+
+	err = afero.Walk(fs, "/dir", func(_ string, _ os.FileInfo, err error) error { return err })
+	assert.ErrorIs(t, err, syscall.ESTALE)
+
+	fs.FailReadDir(nil)
+	entries, err := afero.ReadDir(fs, "/dir")
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+// TestFailRenameAndRemove tests that renames and removes fail without changing the file system
+func TestFailRenameAndRemove(t *testing.T) {
+	fs := NewFaultFs(afero.NewMemMapFs()).FailRename(syscall.EXDEV).FailRemove(syscall.EACCES)
+	require.NoError(t, afero.WriteFile(fs, "/file.txt", []byte("content"), 0644))
+
+	assert.ErrorIs(t, fs.Rename("/file.txt", "/moved.txt"), syscall.EXDEV)
+	assert.ErrorIs(t, fs.Remove("/file.txt"), syscall.EACCES)
+	assert.ErrorIs(t, fs.RemoveAll("/file.txt"), syscall.EACCES)
+
+	exists, err := afero.Exists(fs, "/file.txt")
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+// TestFailRenameOf tests that only renames of the path fail
+func TestFailRenameOf(t *testing.T) {
+	fs := NewFaultFs(afero.NewMemMapFs()).FailRenameOf("/dir/file.txt", syscall.EXDEV)
+	require.NoError(t, afero.WriteFile(fs, "/dir/file.txt", []byte("content"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "/dir/other.txt", []byte("content"), 0644))
+
+	assert.ErrorIs(t, fs.Rename("/dir/./file.txt", "/moved.txt"), syscall.EXDEV)
+	assert.NoError(t, fs.Rename("/dir/other.txt", "/moved.txt"))
+
+	fs.FailRenameOf("/dir/file.txt", nil)
+	assert.NoError(t, fs.Rename("/dir/file.txt", "/dir/renamed.txt"))
+}
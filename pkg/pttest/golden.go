@@ -0,0 +1,49 @@
+package pttest
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// update is set with go test -update to rewrite the golden files with the current output
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// GoldenPath returns the path of the golden file with the name in the testdata directory
+func GoldenPath(name string) string {
+	return filepath.Join("testdata", name+".golden")
+}
+
+// Golden compares the output to the golden file with the name, or rewrites the file when -update is set
+func Golden(t *testing.T, name string, output []byte) {
+	t.Helper()
+
+	path := GoldenPath(name)
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create golden file directory: %v", err)
+		}
+		if err := os.WriteFile(path, output, 0644); err != nil {
+			t.Fatalf("Failed to update golden file %s: %v", path, err)
+		}
+		return
+	}
+
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read golden file %s, run go test with -update to create it: %v", path, err)
+	}
+
+	if !bytes.Equal(expected, output) {
+		t.Errorf("Output does not match golden file %s, run go test with -update if the change is intended\n"+
+			"--- expected\n%s\n--- actual\n%s", path, expected, output)
+	}
+}
+
+// ScrubDir replaces the directory in the output with a placeholder so it does not change between runs
+func ScrubDir(output []byte, dir, placeholder string) []byte {
+	return bytes.ReplaceAll(output, []byte(dir), []byte(placeholder))
+}
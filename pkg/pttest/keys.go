@@ -0,0 +1,42 @@
+package pttest
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// SigningKey writes a new Ed25519 private key, as --sign-key reads it, and its public key to PEM files
+// in the directory, returning the private key and the paths of the two files
+func SigningKey(t *testing.T, dir string) (key ed25519.PrivateKey, keyPath, publicKeyPath string) {
+	t.Helper()
+
+	publicKey, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate a signing key: %v", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to encode the signing key: %v", err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		t.Fatalf("Failed to encode the public key: %v", err)
+	}
+
+	keyPath = filepath.Join(dir, "key.pem")
+	publicKeyPath = filepath.Join(dir, "key.pub")
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatalf("Failed to write the signing key: %v", err)
+	}
+	if err := os.WriteFile(publicKeyPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0644); err != nil {
+		t.Fatalf("Failed to write the public key: %v", err)
+	}
+
+	return key, keyPath, publicKeyPath
+}
@@ -1,4 +1,9 @@
-package testutils
+/*
+Package pttest holds the helpers that the pt commands are tested with: the RunFunc harness for
+running a command with arguments, capturing stdout and logs, the pairtree fixture builder, and
+golden files. It is exported so projects that build on pt can test against pairtrees the same way.
+*/
+package pttest
 
 import (
 	"bytes"
@@ -22,11 +27,6 @@ const (
 	root = "--pairtree="
 )
 
-// Path to the test pairtree directory
-var (
-	TestPairtree = filepath.Join("..", "..", "test-dir", "test-pairtree")
-)
-
 // MemorySink implements zap.Sink by writing all messages to a buffer.
 type MemorySink struct {
 	*bytes.Buffer
@@ -48,16 +48,17 @@ func CreateLogger() (Logger *zap.Logger, sink *MemorySink) {
 		return sink, nil
 	})
 
-	// Create a logger instance using the registered sink.
+	// Create a logger instance using the registered sink, locked so parallel tests can share it
 	Logger = zap.New(zapcore.NewCore(
 		zapcore.NewJSONEncoder(zap.NewDevelopmentEncoderConfig()),
-		zapcore.AddSync(sink),
+		zapcore.Lock(zapcore.AddSync(sink)),
 		zapcore.DebugLevel,
 	))
 	return Logger, sink
 }
 
-func SetupLogger(logFile string) (*zap.Logger, func()) {
+// SetupLogger creates a test logger and a cleanup function that syncs it
+func SetupLogger() (*zap.Logger, func()) {
 	logger, _ := CreateLogger()
 
 	// Create a cleanup function to be deferred
@@ -66,7 +67,6 @@ func SetupLogger(logFile string) (*zap.Logger, func()) {
 			// handle the error
 			fmt.Printf("Failed to sync logger: %v\n", err)
 		}
-		CleanupFiles(logFile)
 	}
 
 	return logger, cleanup
@@ -240,7 +240,7 @@ func UntarCLI(t *testing.T, runFunc RunFunc, dest, pairpath, ppBase string, chec
 	destDir := CreateTempDir(t, fs)
 	pairpath = filepath.Join(destDir, pairpath)
 
-	CopyTestDirectory(t, TestPairtree, destDir)
+	StandardPairtree().Build(t, fs, destDir)
 
 	// Add files to src and .tgz file
 	dirTGZ := CreateDirInDir(t, fs, srcDir, ppBase)
@@ -259,7 +259,8 @@ func UntarCLI(t *testing.T, runFunc RunFunc, dest, pairpath, ppBase string, chec
 		return err
 	}
 
-	args := []string{root + destDir, dirSrcTGZ, dest, "-a"}
+	// The object in the pairtree is overwritten so the confirmation prompt is skipped
+	args := []string{root + destDir, dirSrcTGZ, dest, "-a", "--yes"}
 	err := runFunc(args, &buf)
 	if err != nil {
 		return err
@@ -296,7 +297,7 @@ func TarCLI(t *testing.T, runFunc RunFunc, src, tgzFile string) error {
 	fs := afero.NewOsFs()
 
 	srcDir := CreateTempDir(t, fs)
-	CopyTestDirectory(t, TestPairtree, srcDir)
+	StandardPairtree().Build(t, fs, srcDir)
 
 	destDir := CreateTempDir(t, fs)
 	destDir = filepath.Join(destDir, tgzFile)
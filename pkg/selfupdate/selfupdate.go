@@ -0,0 +1,407 @@
+/*
+The selfupdate package finds the latest pt release on GitHub, verifies the signature of the release
+checksums with the pt release key and the archive built for this platform against the checksums,
+and replaces the running binary with the one in it
+*/
+package selfupdate
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+)
+
+const (
+	// LatestReleaseURL is the GitHub API endpoint of the latest pt release
+	LatestReleaseURL = "https://api.github.com/repos/UCLALibrary/pt-tools/releases/latest"
+	// ChecksumsAsset is the name of the release asset that lists the SHA-256 of every archive
+	ChecksumsAsset = "checksums.txt"
+	// SignatureAsset is the name of the release asset with the Ed25519 signature of the checksums, as
+	// openssl pkeyutl -sign -rawin writes it when the release is made
+	SignatureAsset = ChecksumsAsset + ".sig"
+	// maxDownload limits the size of a downloaded release asset
+	maxDownload = 200 << 20
+)
+
+// releaseKeyPEM is the public key of the private key the checksums of pt releases are signed with. It is
+// added by the maintainers, who keep the private key, so a build without it can not update itself.
+//
+//go:embed release.pub
+var releaseKeyPEM []byte
+
+// Asset is a file attached to a release
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// Release is a published pt release
+type Release struct {
+	TagName string  `json:"tag_name"`
+	Assets  []Asset `json:"assets"`
+}
+
+// Updater downloads pt releases for a platform, trusting the releases signed with the public key, or with
+// the release key built into pt when it has none
+type Updater struct {
+	Client     *http.Client
+	ReleaseURL string
+	GOOS       string
+	GOARCH     string
+	PublicKey  ed25519.PublicKey
+}
+
+// New creates an Updater for the latest release built for the running platform
+func New() *Updater {
+	return &Updater{
+		Client:     http.DefaultClient,
+		ReleaseURL: LatestReleaseURL,
+		GOOS:       runtime.GOOS,
+		GOARCH:     runtime.GOARCH,
+	}
+}
+
+// Latest returns the latest release
+func (u *Updater) Latest(ctx context.Context) (*Release, error) {
+	body, err := u.get(ctx, u.ReleaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var release Release
+	if err := json.Unmarshal(body, &release); err != nil {
+		return nil, fmt.Errorf("could not read the release: %w", err)
+	}
+
+	return &release, nil
+}
+
+// Download returns the pt binary from the release's archive for the Updater's platform after
+// checking the signature of the release checksums and the archive against them
+func (u *Updater) Download(ctx context.Context, release *Release) ([]byte, error) {
+	archiveName := ArchiveName(u.GOOS, u.GOARCH)
+
+	archiveAsset, ok := release.Asset(archiveName)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", error_msgs.Err21, archiveName)
+	}
+
+	checksumsAsset, ok := release.Asset(ChecksumsAsset)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is missing", error_msgs.Err20, ChecksumsAsset)
+	}
+
+	signatureAsset, ok := release.Asset(SignatureAsset)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is missing", error_msgs.Err52, SignatureAsset)
+	}
+
+	checksums, err := u.get(ctx, checksumsAsset.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := u.get(ctx, signatureAsset.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	// The checksums are only trusted once they are known to come from the pt release process
+	if err := VerifySignature(checksums, signature, u.PublicKey); err != nil {
+		return nil, err
+	}
+
+	archive, err := u.get(ctx, archiveAsset.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := VerifyChecksum(archive, archiveName, checksums); err != nil {
+		return nil, err
+	}
+
+	return ExtractBinary(archive, archiveName, BinaryName(u.GOOS))
+}
+
+// Asset returns the release asset with the name
+func (r *Release) Asset(name string) (Asset, bool) {
+	for _, asset := range r.Assets {
+		if asset.Name == name {
+			return asset, true
+		}
+	}
+
+	return Asset{}, false
+}
+
+// ArchiveName returns the name of the release archive for a platform, it follows the
+// name_template in .goreleaser.yaml
+func ArchiveName(goos, goarch string) string {
+	arch := goarch
+	switch goarch {
+	case "amd64":
+		arch = "x86_64"
+	case "386":
+		arch = "i386"
+	}
+
+	// Only Linux archives are overridden to tar.gz, the rest use the zip default
+	ext := ".zip"
+	if goos == "linux" {
+		ext = ".tar.gz"
+	}
+
+	return "pt_" + strings.ToUpper(goos[:1]) + goos[1:] + "_" + arch + ext
+}
+
+// BinaryName returns the name of the pt binary for an operating system
+func BinaryName(goos string) string {
+	if goos == "windows" {
+		return "pt.exe"
+	}
+
+	return "pt"
+}
+
+// VerifyChecksum checks the SHA-256 of the archive against its line in the checksums file
+func VerifyChecksum(archive []byte, archiveName string, checksums []byte) error {
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || fields[1] != archiveName {
+			continue
+		}
+
+		sum := sha256.Sum256(archive)
+		if !strings.EqualFold(fields[0], hex.EncodeToString(sum[:])) {
+			return fmt.Errorf("%w: %s", error_msgs.Err20, archiveName)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s is not listed in %s", error_msgs.Err20, archiveName, ChecksumsAsset)
+}
+
+// VerifySignature checks that the signature is the Ed25519 signature of the checksums by the public key, or by
+// the release key built into pt when the public key is nil
+func VerifySignature(checksums, signature []byte, publicKey ed25519.PublicKey) error {
+	if publicKey == nil {
+		var err error
+		if publicKey, err = ReleaseKey(); err != nil {
+			return err
+		}
+	}
+
+	if len(publicKey) != ed25519.PublicKeySize || !ed25519.Verify(publicKey, checksums, signature) {
+		return fmt.Errorf("%w: %s", error_msgs.Err52, SignatureAsset)
+	}
+
+	return nil
+}
+
+// ExtractBinary reads the binary from a .tar.gz or .zip release archive
+func ExtractBinary(archive []byte, archiveName, binaryName string) ([]byte, error) {
+	if strings.HasSuffix(archiveName, ".zip") {
+		reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range reader.File {
+			if path.Base(file.Name) != binaryName || file.FileInfo().IsDir() {
+				continue
+			}
+
+			contents, err := file.Open()
+			if err != nil {
+				return nil, err
+			}
+			defer contents.Close()
+
+			return io.ReadAll(io.LimitReader(contents, maxDownload))
+		}
+	} else {
+		gzipReader, err := gzip.NewReader(bytes.NewReader(archive))
+		if err != nil {
+			return nil, err
+		}
+		defer gzipReader.Close()
+
+		tarReader := tar.NewReader(gzipReader)
+		for {
+			header, err := tarReader.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			if path.Base(header.Name) == binaryName && header.Typeflag == tar.TypeReg {
+				return io.ReadAll(io.LimitReader(tarReader, maxDownload))
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s", error_msgs.Err22, archiveName)
+}
+
+// Replace swaps the binary at exePath for the new one. The new binary is written next to
+// the old one and renamed over it so a failed update leaves the old binary in place.
+func Replace(exePath string, binary []byte) error {
+	info, err := os.Stat(exePath)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(exePath)
+	newFile, err := os.CreateTemp(dir, ".pt-update-*")
+	if err != nil {
+		return err
+	}
+	newPath := newFile.Name()
+
+	if _, err := newFile.Write(binary); err != nil {
+		newFile.Close()
+		return errors.Join(err, os.Remove(newPath))
+	}
+
+	if err := errors.Join(newFile.Close(), os.Chmod(newPath, info.Mode().Perm())); err != nil {
+		return errors.Join(err, os.Remove(newPath))
+	}
+
+	// A running binary can not be overwritten on Windows but it can be renamed
+	oldPath := filepath.Join(dir, "."+filepath.Base(exePath)+".old")
+	_ = os.Remove(oldPath)
+
+	if err := os.Rename(exePath, oldPath); err != nil {
+		return errors.Join(err, os.Remove(newPath))
+	}
+
+	if err := os.Rename(newPath, exePath); err != nil {
+		// Put the old binary back
+		return errors.Join(err, os.Rename(oldPath, exePath), os.Remove(newPath))
+	}
+
+	// This fails on Windows while the old binary is running, it is removed by the next update
+	_ = os.Remove(oldPath)
+
+	return nil
+}
+
+// IsNewer determines if the latest version is newer than the current one, versions are
+// compared as v-prefixed semantic versions and a development build is always older
+func IsNewer(latest, current string) bool {
+	latestParts, ok := parseVersion(latest)
+	if !ok {
+		return false
+	}
+
+	currentParts, ok := parseVersion(current)
+	if !ok {
+		return true
+	}
+
+	for i := range latestParts {
+		if latestParts[i] != currentParts[i] {
+			return latestParts[i] > currentParts[i]
+		}
+	}
+
+	return false
+}
+
+// parseVersion parses the major, minor, and patch numbers of a version like v1.2.3
+func parseVersion(version string) ([3]int, bool) {
+	var parts [3]int
+
+	version = strings.TrimPrefix(version, "v")
+	// Ignore pre-release and build suffixes
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		version = version[:i]
+	}
+
+	fields := strings.Split(version, ".")
+	if len(fields) != 3 {
+		return parts, false
+	}
+
+	for i, field := range fields {
+		number, err := strconv.Atoi(field)
+		if err != nil {
+			return parts, false
+		}
+		parts[i] = number
+	}
+
+	return parts, true
+}
+
+// ReleaseKey returns the public key the checksums of a release must be signed with to be trusted, which is
+// built into pt
+func ReleaseKey() (ed25519.PublicKey, error) {
+	return parsePublicKey(releaseKeyPEM)
+}
+
+// parsePublicKey parses a PEM encoded Ed25519 public key
+func parsePublicKey(data []byte) (ed25519.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%w: the release key is not in PEM format", error_msgs.Err54)
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", error_msgs.Err54, err)
+	}
+
+	edKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: the release key is not an Ed25519 public key", error_msgs.Err54)
+	}
+
+	return edKey, nil
+}
+
+// get downloads the URL
+func (u *Updater) get(ctx context.Context, url string) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := u.Client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not download %s: %s", url, response.Status)
+	}
+
+	return io.ReadAll(io.LimitReader(response.Body, maxDownload))
+}
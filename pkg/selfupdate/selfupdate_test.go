@@ -0,0 +1,253 @@
+package selfupdate
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tarGzArchive creates a release archive containing a file
+func tarGzArchive(t *testing.T, name string, contents []byte) []byte {
+	var buf bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buf)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	require.NoError(t, tarWriter.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(contents))}))
+	_, err := tarWriter.Write(contents)
+	require.NoError(t, err)
+	require.NoError(t, tarWriter.Close())
+	require.NoError(t, gzipWriter.Close())
+
+	return buf.Bytes()
+}
+
+// zipArchive creates a release archive containing a file
+func zipArchive(t *testing.T, name string, contents []byte) []byte {
+	var buf bytes.Buffer
+	zipWriter := zip.NewWriter(&buf)
+
+	file, err := zipWriter.Create(name)
+	require.NoError(t, err)
+	_, err = file.Write(contents)
+	require.NoError(t, err)
+	require.NoError(t, zipWriter.Close())
+
+	return buf.Bytes()
+}
+
+// checksum returns the checksums.txt line of an archive
+func checksum(archive []byte, name string) string {
+	sum := sha256.Sum256(archive)
+	return fmt.Sprintf("%s  %s\n", hex.EncodeToString(sum[:]), name)
+}
+
+// newReleaseServer serves a release with the archives, checksums, and the signature of the checksums when
+// there is one
+func newReleaseServer(t *testing.T, tag string, archives map[string][]byte, checksums string, signature []byte) *httptest.Server {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	release := Release{TagName: tag}
+	for name, archive := range archives {
+		release.Assets = append(release.Assets, Asset{Name: name, URL: server.URL + "/download/" + name})
+		mux.HandleFunc("/download/"+name, func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write(archive)
+		})
+	}
+
+	release.Assets = append(release.Assets, Asset{Name: ChecksumsAsset, URL: server.URL + "/download/" + ChecksumsAsset})
+	mux.HandleFunc("/download/"+ChecksumsAsset, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(checksums))
+	})
+
+	if signature != nil {
+		release.Assets = append(release.Assets, Asset{Name: SignatureAsset, URL: server.URL + "/download/" + SignatureAsset})
+		mux.HandleFunc("/download/"+SignatureAsset, func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write(signature)
+		})
+	}
+
+	mux.HandleFunc("/latest", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(release)
+	})
+
+	return server
+}
+
+// TestArchiveName tests if archive names follow the goreleaser name template
+func TestArchiveName(t *testing.T) {
+	assert.Equal(t, "pt_Linux_x86_64.tar.gz", ArchiveName("linux", "amd64"))
+	assert.Equal(t, "pt_Darwin_arm64.zip", ArchiveName("darwin", "arm64"))
+	assert.Equal(t, "pt_Windows_i386.zip", ArchiveName("windows", "386"))
+}
+
+// TestIsNewer tests the comparison of release versions
+func TestIsNewer(t *testing.T) {
+	tests := []struct {
+		latest   string
+		current  string
+		expected bool
+	}{
+		{latest: "v1.2.0", current: "v1.1.9", expected: true},
+		{latest: "v1.10.0", current: "v1.9.0", expected: true},
+		{latest: "v1.2.0", current: "v1.2.0", expected: false},
+		{latest: "v1.2.0", current: "v2.0.0", expected: false},
+		{latest: "v1.2.0", current: "dev", expected: true},
+		{latest: "v1.2.1", current: "v1.2.0-3-gabc123-dirty", expected: true},
+		{latest: "nightly", current: "v1.2.0", expected: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.latest+" "+test.current, func(t *testing.T) {
+			assert.Equal(t, test.expected, IsNewer(test.latest, test.current))
+		})
+	}
+}
+
+// TestDownload tests if the binary is extracted from a release archive that matches its checksum
+func TestDownload(t *testing.T) {
+	binary := []byte("new pt binary")
+	linuxArchive := tarGzArchive(t, "pt", binary)
+	windowsArchive := zipArchive(t, "pt.exe", binary)
+	checksums := checksum(linuxArchive, "pt_Linux_x86_64.tar.gz") + checksum(windowsArchive, "pt_Windows_x86_64.zip")
+	mismatch := checksum([]byte("other"), "pt_Linux_x86_64.tar.gz")
+
+	publicKey, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	sign := func(checksums string) []byte { return ed25519.Sign(key, []byte(checksums)) }
+
+	tests := []struct {
+		name      string
+		goos      string
+		checksums string
+		signature []byte
+		expectErr error
+	}{
+		{name: "tar.gz archive", goos: "linux", checksums: checksums, signature: sign(checksums), expectErr: nil},
+		{name: "zip archive", goos: "windows", checksums: checksums, signature: sign(checksums), expectErr: nil},
+		{name: "no build for the platform", goos: "darwin", checksums: checksums, signature: sign(checksums), expectErr: error_msgs.Err21},
+		{name: "checksum mismatch", goos: "linux", checksums: mismatch, signature: sign(mismatch), expectErr: error_msgs.Err20},
+		{name: "archive not in checksums", goos: "linux", checksums: "", signature: sign(""), expectErr: error_msgs.Err20},
+		{name: "checksums not signed", goos: "linux", checksums: checksums, expectErr: error_msgs.Err52},
+		{name: "checksums changed after signing", goos: "linux", checksums: checksums, signature: sign(mismatch), expectErr: error_msgs.Err52},
+		{name: "checksums signed by another key", goos: "linux", checksums: checksums,
+			signature: ed25519.Sign(otherKey, []byte(checksums)), expectErr: error_msgs.Err52},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := newReleaseServer(t, "v1.2.0", map[string][]byte{
+				"pt_Linux_x86_64.tar.gz": linuxArchive,
+				"pt_Windows_x86_64.zip":  windowsArchive,
+			}, test.checksums, test.signature)
+
+			updater := &Updater{Client: server.Client(), ReleaseURL: server.URL + "/latest", GOOS: test.goos, GOARCH: "amd64",
+				PublicKey: publicKey}
+
+			release, err := updater.Latest(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, "v1.2.0", release.TagName)
+
+			downloaded, err := updater.Download(context.Background(), release)
+			require.ErrorIs(t, err, test.expectErr)
+
+			if test.expectErr == nil {
+				assert.Equal(t, binary, downloaded)
+			}
+		})
+	}
+}
+
+// TestReleaseKey tests that the checksums are checked with the release key built into pt when the Updater has
+// no public key, and that they are refused instead of the command failing when the built in key is not valid
+func TestReleaseKey(t *testing.T) {
+	defer func(data []byte) { releaseKeyPEM = data }(releaseKeyPEM)
+
+	publicKey, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	require.NoError(t, err)
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ecdsaDER, err := x509.MarshalPKIXPublicKey(ecdsaKey.Public())
+	require.NoError(t, err)
+
+	checksums := []byte(checksum([]byte("archive"), "pt_Linux_x86_64.tar.gz"))
+	signature := ed25519.Sign(key, checksums)
+
+	tests := []struct {
+		name      string
+		pem       []byte
+		expectErr error
+	}{
+		{name: "release key", pem: pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), expectErr: nil},
+		{name: "no release key", pem: nil, expectErr: error_msgs.Err54},
+		{name: "not PEM", pem: []byte("not a key"), expectErr: error_msgs.Err54},
+		{name: "not a public key", pem: pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: []byte("der")}),
+			expectErr: error_msgs.Err54},
+		{name: "not Ed25519", pem: pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: ecdsaDER}),
+			expectErr: error_msgs.Err54},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			releaseKeyPEM = test.pem
+
+			assert.ErrorIs(t, VerifySignature(checksums, signature, New().PublicKey), test.expectErr)
+		})
+	}
+}
+
+// TestExtractBinaryMissing tests if an archive without the pt binary is rejected
+func TestExtractBinaryMissing(t *testing.T) {
+	archive := tarGzArchive(t, "README.md", []byte("readme"))
+
+	_, err := ExtractBinary(archive, "pt_Linux_x86_64.tar.gz", "pt")
+	assert.ErrorIs(t, err, error_msgs.Err22)
+}
+
+// TestReplace tests if the binary is replaced and keeps its permissions
+func TestReplace(t *testing.T) {
+	exePath := filepath.Join(t.TempDir(), "pt")
+	require.NoError(t, os.WriteFile(exePath, []byte("old pt binary"), 0755))
+
+	err := Replace(exePath, []byte("new pt binary"))
+	require.NoError(t, err)
+
+	contents, err := os.ReadFile(exePath)
+	require.NoError(t, err)
+	assert.Equal(t, "new pt binary", string(contents))
+
+	info, err := os.Stat(exePath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+
+	// Only the replaced binary should be left in the directory
+	entries, err := os.ReadDir(filepath.Dir(exePath))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
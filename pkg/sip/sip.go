@@ -0,0 +1,249 @@
+/*
+The sip package packages a pairtree object as a zipped submission information package for
+repository ingest. The package has a top level folder named after the encoded ID of the object with
+the files of the object in objects/, a SHA-256 checksum manifest of them, and a metadata stub written
+from a template.
+*/
+package sip
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/UCLALibrary/pt-tools/pkg/checksum"
+)
+
+const (
+	// ObjectsDir is the folder of the package that has the files of the object
+	ObjectsDir = "objects"
+	// ManifestName is the name of the checksum manifest, which has a line for each file with its
+	// SHA-256 checksum and its path in the package
+	ManifestName = "manifest-sha256.txt"
+	// DefaultMetadataName is the name of the metadata stub written from the default template
+	DefaultMetadataName = "metadata.xml"
+)
+
+// DefaultTemplate is the template of the metadata stub, a Dublin Core record to be completed by the repository
+const DefaultTemplate = `<?xml version="1.0" encoding="UTF-8"?>
+<metadata xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:dcterms="http://purl.org/dc/terms/">
+  <dc:identifier>{{xml .ID}}</dc:identifier>
+  <dc:title></dc:title>
+  <dcterms:created>{{.Created.Format "2006-01-02T15:04:05Z07:00"}}</dcterms:created>
+  <dcterms:extent>{{.Files}} files, {{.Bytes}} bytes</dcterms:extent>
+</metadata>
+`
+
+// Metadata is what a metadata template can use to describe the package
+type Metadata struct {
+	ID      string
+	Created time.Time
+	Files   int
+	Bytes   int64
+	Agent   string
+}
+
+// ParseTemplate parses a metadata template, which can escape text for XML with the xml function
+func ParseTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(template.FuncMap{"xml": escapeXML}).Parse(text)
+}
+
+// Write writes the object at objPath as a zipped package to the writer, with a metadata stub called
+// metadataName written from the template. Hidden files are only packaged when includeHidden is true.
+// The files are hashed for the manifest in parallel with zipping them, as bounded by the options, or
+// as they are zipped when the options have a cache.
+func Write(ctx context.Context, writer io.Writer, id, name, objPath, agent string, tmpl *template.Template,
+	metadataName string, includeHidden bool, opts checksum.Options) (err error) {
+	zipWriter := zip.NewWriter(writer)
+	defer func() {
+		if closeErr := zipWriter.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	metadata := Metadata{ID: id, Created: time.Now().UTC(), Agent: agent}
+
+	// The files are listed first so they can be hashed while they are zipped
+	var paths, relPaths []string
+
+	err = filepath.WalkDir(objPath, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// Stop before the next file once the context is canceled
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if filePath != objPath && !includeHidden && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(objPath, filePath)
+		if err != nil {
+			return err
+		}
+
+		paths = append(paths, filePath)
+		relPaths = append(relPaths, path.Join(ObjectsDir, filepath.ToSlash(rel)))
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	hashCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var sums []checksum.File
+	var hashErr error
+	hashed := make(chan struct{})
+	hash := func() {
+		defer close(hashed)
+		sums, hashErr = checksum.Files(hashCtx, paths, opts)
+	}
+
+	// Without a cache the files are hashed while they are zipped, reading each of them twice. With one
+	// they are hashed as they are zipped and the manifest reuses their checksums.
+	if opts.Cache == nil {
+		go hash()
+	}
+
+	if err := addFiles(ctx, zipWriter, name, paths, relPaths, &metadata, opts.Cache); err != nil {
+		cancel()
+		if opts.Cache == nil {
+			<-hashed
+		}
+		return err
+	}
+
+	if opts.Cache != nil {
+		hash()
+	}
+	<-hashed
+
+	if hashErr != nil {
+		return hashErr
+	}
+
+	var manifest bytes.Buffer
+	for i, sum := range sums {
+		fmt.Fprintf(&manifest, "%s  %s\n", sum.Checksum, relPaths[i])
+	}
+
+	var stub bytes.Buffer
+	if err := tmpl.Execute(&stub, metadata); err != nil {
+		return err
+	}
+
+	if err := addBytes(zipWriter, path.Join(name, metadataName), stub.Bytes(), metadata.Created); err != nil {
+		return err
+	}
+
+	return addBytes(zipWriter, path.Join(name, ManifestName), manifest.Bytes(), metadata.Created)
+}
+
+// addFiles adds the files at the paths to the zip under their relPaths in the folder with the name,
+// counting them in the metadata, and keeps their checksums in the cache when there is one
+func addFiles(ctx context.Context, zipWriter *zip.Writer, name string, paths, relPaths []string,
+	metadata *Metadata, cache *checksum.Cache) error {
+	for i, filePath := range paths {
+		// Stop before the next file once the context is canceled
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		size, err := addFile(zipWriter, filePath, path.Join(name, relPaths[i]), cache)
+		if err != nil {
+			return err
+		}
+
+		metadata.Files++
+		metadata.Bytes += size
+	}
+
+	return nil
+}
+
+// addFile adds the file to the zip under the name and returns its size, hashing it into the cache
+// as it is added when there is one
+func addFile(zipWriter *zip.Writer, filePath, name string, cache *checksum.Cache) (int64, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return 0, err
+	}
+	header.Name = name
+	header.Method = zip.Deflate
+
+	entry, err := zipWriter.CreateHeader(header)
+	if err != nil {
+		return 0, err
+	}
+
+	if cache == nil {
+		return io.Copy(entry, file)
+	}
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(entry, hash), file)
+	if err != nil {
+		return 0, err
+	}
+	cache.Put(checksum.File{Path: filePath, Checksum: hex.EncodeToString(hash.Sum(nil)), Algorithm: checksum.SHA256,
+		Size: size}, info)
+
+	return size, nil
+}
+
+// addBytes adds a file with the content to the zip under the name
+func addBytes(zipWriter *zip.Writer, name string, content []byte, modified time.Time) error {
+	entry, err := zipWriter.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return err
+	}
+
+	_, err = entry.Write(content)
+	return err
+}
+
+// escapeXML escapes the text so it can be used in XML content or attributes
+func escapeXML(text string) (string, error) {
+	var buf bytes.Buffer
+	if err := xml.EscapeText(&buf, []byte(text)); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
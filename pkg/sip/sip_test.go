@@ -0,0 +1,152 @@
+package sip
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/UCLALibrary/pt-tools/pkg/checksum"
+	"github.com/UCLALibrary/pt-tools/pkg/pttest"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readZip returns the content of each file in the zip by its name
+func readZip(t *testing.T, data []byte) map[string]string {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+
+	files := map[string]string{}
+	for _, file := range reader.File {
+		entry, err := file.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(entry)
+		require.NoError(t, err)
+		require.NoError(t, entry.Close())
+
+		files[file.Name] = string(content)
+	}
+
+	return files
+}
+
+// TestWrite tests if the package has the files of the object, their checksums, and the metadata stub
+func TestWrite(t *testing.T) {
+	builder := pttest.NewPairtreeBuilder().
+		WithFile("ark:/a&b", "file.txt", []byte("hello\n")).
+		WithFile("ark:/a&b", "folder/inner.txt", []byte("")).
+		WithFile("ark:/a&b", ".hidden.txt", []byte("secret"))
+	ptRoot := builder.BuildTemp(t, afero.NewOsFs())
+	objPath := builder.ObjectPath(ptRoot, "ark:/a&b")
+	name := filepath.Base(objPath)
+
+	tests := []struct {
+		name          string
+		includeHidden bool
+		expectFiles   []string
+		expectExtent  string
+	}{
+		{name: "without hidden files", expectFiles: []string{"objects/file.txt", "objects/folder/inner.txt"},
+			expectExtent: "2 files, 6 bytes"},
+		{name: "with hidden files", includeHidden: true,
+			expectFiles:  []string{"objects/.hidden.txt", "objects/file.txt", "objects/folder/inner.txt"},
+			expectExtent: "3 files, 12 bytes"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			tmpl, err := ParseTemplate(DefaultMetadataName, DefaultTemplate)
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			err = Write(context.Background(), &buf, "ark:/a&b", name, objPath, "pt test", tmpl,
+				DefaultMetadataName, test.includeHidden, checksum.Options{})
+			require.NoError(t, err)
+
+			files := readZip(t, buf.Bytes())
+			assert.Len(t, files, len(test.expectFiles)+2)
+			for _, file := range test.expectFiles {
+				assert.Contains(t, files, name+"/"+file)
+			}
+			assert.Equal(t, "hello\n", files[name+"/objects/file.txt"])
+
+			metadata := files[name+"/"+DefaultMetadataName]
+			assert.Contains(t, metadata, "<dc:identifier>ark:/a&amp;b</dc:identifier>")
+			assert.Contains(t, metadata, test.expectExtent)
+
+			manifest := files[name+"/"+ManifestName]
+			assert.Contains(t, manifest,
+				"5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03  objects/file.txt\n")
+			assert.Contains(t, manifest,
+				"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855  objects/folder/inner.txt\n")
+		})
+	}
+}
+
+// TestWriteTemplate tests if the metadata stub is written from a custom template
+func TestWriteTemplate(t *testing.T) {
+	builder := pttest.NewPairtreeBuilder().WithFile("ark:/a5388", "a5388.txt", []byte("12345"))
+	ptRoot := builder.BuildTemp(t, afero.NewOsFs())
+
+	tmpl, err := ParseTemplate("sip.json", `{"id": "{{.ID}}", "files": {{.Files}}, "agent": "{{.Agent}}"}`)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	err = Write(context.Background(), &buf, "ark:/a5388", "a5388", builder.ObjectPath(ptRoot, "ark:/a5388"),
+		"pt test", tmpl, "sip.json", false, checksum.Options{})
+	require.NoError(t, err)
+
+	files := readZip(t, buf.Bytes())
+	assert.JSONEq(t, `{"id": "ark:/a5388", "files": 1, "agent": "pt test"}`, files["a5388/sip.json"])
+}
+
+// TestWriteCanceled tests if packaging stops when the context is canceled
+func TestWriteCanceled(t *testing.T) {
+	builder := pttest.NewPairtreeBuilder().WithObject("ark:/a5388", "a5388.txt")
+	ptRoot := builder.BuildTemp(t, afero.NewOsFs())
+
+	tmpl, err := ParseTemplate(DefaultMetadataName, DefaultTemplate)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = Write(ctx, io.Discard, "ark:/a5388", "a5388", builder.ObjectPath(ptRoot, "ark:/a5388"), "pt test", tmpl,
+		DefaultMetadataName, false, checksum.Options{})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+// TestWriteWarmCache tests that the files are hashed into the cache as they are zipped and that the
+// manifest has their checksums
+func TestWriteWarmCache(t *testing.T) {
+	builder := pttest.NewPairtreeBuilder().WithFile("ark:/a5388", "a5388.txt", []byte("hello\n"))
+	ptRoot := builder.BuildTemp(t, afero.NewOsFs())
+	objPath := builder.ObjectPath(ptRoot, "ark:/a5388")
+
+	tmpl, err := ParseTemplate(DefaultMetadataName, DefaultTemplate)
+	require.NoError(t, err)
+
+	cache := checksum.NewCache()
+
+	var buf bytes.Buffer
+	err = Write(context.Background(), &buf, "ark:/a5388", "a5388", objPath, "pt test", tmpl,
+		DefaultMetadataName, false, checksum.Options{Cache: cache})
+	require.NoError(t, err)
+
+	files := readZip(t, buf.Bytes())
+	assert.Equal(t, "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03  objects/a5388.txt\n",
+		files["a5388/"+ManifestName])
+
+	info, err := os.Stat(filepath.Join(objPath, "a5388.txt"))
+	require.NoError(t, err)
+	cached, ok := cache.Get(filepath.Join(objPath, "a5388.txt"), info)
+	require.True(t, ok)
+	assert.Equal(t, int64(6), cached.Size)
+}
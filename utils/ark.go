@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"context"
+	"os"
+
+	"github.com/UCLALibrary/pt-tools/pkg/ark"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// Names of the flags of the commands that ingest objects and can check their ARKs first
+const (
+	ResolveFlag       = "resolve"
+	ResolverFlag      = "resolver"
+	ResolveTargetFlag = "resolve-target"
+)
+
+// AddResolveFlags adds the flags that check an object's ARK against a resolver before it is ingested
+func AddResolveFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool(ResolveFlag, false, "Check that the ARK resolves before ingesting the object")
+	cmd.Flags().String(ResolverFlag, "", "Resolver to check ARKs against (defaults to ENV PT_ARK_RESOLVER or N2T)")
+	cmd.Flags().String(ResolveTargetFlag, "", "URL prefix ARKs must resolve to (defaults to ENV PT_ARK_TARGET)")
+}
+
+// ResolverFromFlags returns the resolver to check ARKs against, or nil when --resolve is not used
+func ResolverFromFlags(cmd *cobra.Command) *ark.Resolver {
+	if resolve, _ := cmd.Flags().GetBool(ResolveFlag); !resolve {
+		return nil
+	}
+
+	baseURL, _ := cmd.Flags().GetString(ResolverFlag)
+	if baseURL == "" {
+		baseURL = os.Getenv("PT_ARK_RESOLVER")
+	}
+	if baseURL == "" {
+		baseURL = ark.DefaultResolver
+	}
+
+	target, _ := cmd.Flags().GetString(ResolveTargetFlag)
+	if target == "" {
+		target = os.Getenv("PT_ARK_TARGET")
+	}
+
+	return ark.NewResolver(baseURL, target)
+}
+
+// CheckARK checks that the ID resolves before an object is ingested under it. IDs that are not
+// ARKs can not be resolved so they are only warned about.
+func CheckARK(ctx context.Context, resolver *ark.Resolver, id string, out *Output, logger *zap.Logger) error {
+	if resolver == nil {
+		return nil
+	}
+
+	if !ark.IsARK(id) {
+		out.Warning("%s is not an ARK so it was not checked against the resolver", id)
+		return nil
+	}
+
+	target, err := resolver.Resolve(ctx, id)
+	if err != nil {
+		logger.Error("Error resolving ARK", zap.String("id", id), zap.String("resolver", resolver.BaseURL),
+			zap.Error(err))
+		return err
+	}
+
+	logger.Info("ARK resolves", zap.String("id", id), zap.String("target", target))
+	return nil
+}
@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/spf13/cobra"
+)
+
+// Formats that errors can be written in with --errors
+const (
+	ErrorsText = "text"
+	ErrorsJSON = "json"
+)
+
+// JSONError is the structure of an error written with --errors=json
+type JSONError struct {
+	Code        int      `json:"code"`
+	Message     string   `json:"message"`
+	ID          string   `json:"id,omitempty"`
+	Path        string   `json:"path,omitempty"`
+	Suggestions []string `json:"suggestions,omitempty"`
+}
+
+// ErrorsAsJSON determines if errors should be written as JSON
+func ErrorsAsJSON(cmd *cobra.Command) bool {
+	format, _ := cmd.Flags().GetString(ErrorsFlag)
+	return format == ErrorsJSON
+}
+
+// WriteJSONError writes the error to the writer as a single JSON object
+func WriteJSONError(writer io.Writer, err error) error {
+	jsonErr := JSONError{
+		Code:    ExitCode(err),
+		Message: err.Error(),
+	}
+
+	var ptErr *error_msgs.PtError
+	if errors.As(err, &ptErr) {
+		jsonErr.ID = ptErr.ID
+		jsonErr.Path = ptErr.Path
+		jsonErr.Suggestions = ptErr.Suggestions
+	}
+
+	jsonData, err := json.Marshal(jsonErr)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(writer, string(jsonData))
+	return err
+}
+
+// applyErrorsFormat checks the --errors flag is set to a supported format
+func applyErrorsFormat(cmd *cobra.Command) error {
+	format, err := cmd.Flags().GetString(ErrorsFlag)
+	if err != nil {
+		return err
+	}
+
+	// Execute leaves errors written as JSON to its caller
+	if format != ErrorsText && format != ErrorsJSON {
+		return fmt.Errorf("%w: %s", error_msgs.Err18, format)
+	}
+
+	return nil
+}
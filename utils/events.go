@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"errors"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/premis"
+	"go.uber.org/zap"
+)
+
+// RecordEvent records the preservation event of a command in the history of its object with pt as
+// the agent. The command has already acted on the object, so an event that can not be recorded is
+// a warning rather than a failure of the command.
+func RecordEvent(ptRoot, prefix string, event premis.Event, out *Output, logger *zap.Logger) {
+	event.Agent = "pt " + Version
+
+	if err := premis.Record(ptRoot, prefix, event); err != nil {
+		out.Warning("The %s of %s could not be recorded in its event history: %v", event.Type, event.Object, err)
+		logger.Error("Error recording PREMIS event", zap.String("eventType", event.Type),
+			zap.String("id", event.Object), zap.Error(err))
+		return
+	}
+
+	logger.Info("Recorded PREMIS event", zap.String("eventType", event.Type),
+		zap.String("id", event.Object), zap.String("eventOutcome", event.Outcome))
+}
+
+// RecordFixityCheck records the comparison of the checksums of an object's files with those of a copy of
+// it, or of its manifest, as a fixity check of the object. The outcome is a failure when err is Err48 or
+// Err49 for files that do not match, and any other error stopped the command before the checksums were
+// compared, so no fixity check is recorded for it.
+func RecordFixityCheck(ptRoot, prefix, id, detail string, err error, out *Output, logger *zap.Logger) {
+	if err != nil && !errors.Is(err, error_msgs.Err48) && !errors.Is(err, error_msgs.Err49) {
+		return
+	}
+
+	RecordEvent(ptRoot, prefix, premis.NewEvent(premis.FixityCheck, id, detail, err), out, logger)
+}
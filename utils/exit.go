@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+)
+
+// Exit codes shared by every pt command so scripts can branch on the kind of failure
+const (
+	ExitOK           = 0   // the command succeeded
+	ExitFailure      = 1   // an error that does not fit one of the categories below
+	ExitUsage        = 2   // the command line was invalid (missing or extra arguments, bad flags)
+	ExitNotFound     = 3   // the pairtree, object, or path does not exist
+	ExitConflict     = 4   // the destination already exists
+	ExitIO           = 5   // reading or writing the filesystem failed
+	ExitVerification = 6   // the pairtree or an archive failed a structure or content check
+	ExitTimeout      = 124 // the command did not finish before the --timeout
+	ExitInterrupted  = 130 // the command was interrupted by SIGINT or SIGTERM
+)
+
+// Errors that are caused by how the command was invoked
+var usageErrors = []error{
+	error_msgs.Err3,
+	error_msgs.Err4,
+	error_msgs.Err5,
+	error_msgs.Err6,
+	error_msgs.Err7,
+	error_msgs.Err8,
+	error_msgs.Err9,
+	error_msgs.Err10,
+	error_msgs.Err11,
+	error_msgs.Err15,
+	error_msgs.Err16,
+	error_msgs.Err17,
+	error_msgs.Err18,
+	error_msgs.Err24,
+	error_msgs.Err25,
+	error_msgs.Err27,
+	error_msgs.Err31,
+	error_msgs.Err33,
+	error_msgs.Err38,
+	error_msgs.Err41,
+	error_msgs.Err44,
+	error_msgs.Err46,
+	error_msgs.Err51,
+	error_msgs.Err53,
+}
+
+// Errors that are caused by a pairtree or archive not matching what is expected
+var verificationErrors = []error{
+	error_msgs.Err1,
+	error_msgs.Err2,
+	error_msgs.Err12,
+	error_msgs.Err13,
+	error_msgs.Err20,
+	error_msgs.Err22,
+	error_msgs.Err26,
+	error_msgs.Err28,
+	error_msgs.Err29,
+	error_msgs.Err30,
+	error_msgs.Err34,
+	error_msgs.Err35,
+	error_msgs.Err36,
+	error_msgs.Err39,
+	error_msgs.Err40,
+	error_msgs.Err42,
+	error_msgs.Err43,
+	error_msgs.Err48,
+	error_msgs.Err49,
+	error_msgs.Err50,
+	error_msgs.Err52,
+	error_msgs.Err54,
+}
+
+// ExitCode maps an error returned by a command to the exit code of its category
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	for _, usageErr := range usageErrors {
+		if errors.Is(err, usageErr) {
+			return ExitUsage
+		}
+	}
+
+	for _, verificationErr := range verificationErrors {
+		if errors.Is(err, verificationErr) {
+			return ExitVerification
+		}
+	}
+
+	var pathErr *fs.PathError
+	var linkErr *os.LinkError
+
+	switch {
+	case errors.Is(err, error_msgs.Err47):
+		// pt exists answers that an object is not there like test(1) does, apart from the errors above
+		return ExitFailure
+	case errors.Is(err, error_msgs.Err19), errors.Is(err, context.DeadlineExceeded):
+		return ExitTimeout
+	case errors.Is(err, context.Canceled):
+		return ExitInterrupted
+	case errors.Is(err, fs.ErrNotExist), errors.Is(err, error_msgs.Err21), errors.Is(err, error_msgs.Err45):
+		return ExitNotFound
+	case errors.Is(err, fs.ErrExist):
+		return ExitConflict
+	case errors.As(err, &pathErr), errors.As(err, &linkErr):
+		return ExitIO
+	default:
+		return ExitFailure
+	}
+}
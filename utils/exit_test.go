@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"testing"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestExitCode tests if errors are mapped to the exit code of their category
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{name: "no error", err: nil, expected: ExitOK},
+		{name: "unknown error", err: errors.New("unknown"), expected: ExitFailure},
+		{name: "missing ID", err: error_msgs.Err6, expected: ExitUsage},
+		{name: "wrapped usage error", err: fmt.Errorf("%w: unknown flag: --x", error_msgs.Err17), expected: ExitUsage},
+		{name: "not found", err: &fs.PathError{Op: "open", Path: "id", Err: fs.ErrNotExist}, expected: ExitNotFound},
+		{name: "conflict", err: &fs.PathError{Op: "mkdir", Path: "id", Err: fs.ErrExist}, expected: ExitConflict},
+		{name: "I/O failure", err: &fs.PathError{Op: "write", Path: "id", Err: os.ErrPermission}, expected: ExitIO},
+		{name: "verification failure", err: error_msgs.Err13, expected: ExitVerification},
+		{name: "object does not exist", err: &error_msgs.PtError{ID: "ark:/a5389", Err: error_msgs.Err47}, expected: ExitFailure},
+		{name: "interrupted", err: fmt.Errorf("copying: %w", context.Canceled), expected: ExitInterrupted},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, ExitCode(test.err))
+		})
+	}
+}
+
+// TestWriteJSONError tests if errors are written as a single JSON object with the ID and path
+func TestWriteJSONError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{
+			name:     "plain error",
+			err:      error_msgs.Err6,
+			expected: `{"code":2,"message":"no ID was provided to process"}`,
+		},
+		{
+			name: "pairtree error",
+			err: &error_msgs.PtError{ID: "ark:/a5388", Path: "pairtree_root/a5/38/8/a5388",
+				Err: &fs.PathError{Op: "open", Path: "pairtree_root/a5/38/8/a5388", Err: fs.ErrNotExist}},
+			expected: `{"code":3,"message":"open pairtree_root/a5/38/8/a5388: file does not exist",` +
+				`"id":"ark:/a5388","path":"pairtree_root/a5/38/8/a5388"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			assert.NoError(t, WriteJSONError(&buf, test.err))
+			assert.JSONEq(t, test.expected, buf.String())
+		})
+	}
+}
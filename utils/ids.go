@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// IDsFromFlag is the name of the flag of the commands that can read the IDs they work on from a file
+const IDsFromFlag = "ids-from"
+
+// AddIDsFromFlag adds the flag that reads the IDs a command works on from a file or standard input
+func AddIDsFromFlag(cmd *cobra.Command) {
+	cmd.Flags().String(IDsFromFlag, "", "Read IDs from a file, one per line, or from standard input with -")
+}
+
+// IDsFromFlags returns the IDs listed in the file of --ids-from, read from the input of the command
+// when it is "-", or nil when the flag is not used
+func IDsFromFlags(cmd *cobra.Command) ([]string, error) {
+	path, _ := cmd.Flags().GetString(IDsFromFlag)
+	if path == "" {
+		return nil, nil
+	}
+
+	if path == "-" {
+		return ReadIDs(cmd.InOrStdin())
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ReadIDs(file)
+}
+
+// ReadIDs reads IDs one per line, trimming the space around them and skipping blank lines and
+// comments that start with #
+func ReadIDs(r io.Reader) ([]string, error) {
+	var ids []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		id := strings.TrimSpace(scanner.Text())
+		if id == "" || strings.HasPrefix(id, "#") {
+			continue
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, scanner.Err()
+}
@@ -0,0 +1,20 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestReadIDs tests that IDs are read one per line without blank lines, comments, or the space around them
+func TestReadIDs(t *testing.T) {
+	ids, err := ReadIDs(strings.NewReader("ark:/a5388\n\n  ark:/b5488 \r\n# ark:/a54892\nark:/c5498"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ark:/a5388", "ark:/b5488", "ark:/c5498"}, ids)
+
+	ids, err = ReadIDs(strings.NewReader(""))
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+}
@@ -0,0 +1,191 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/i18n"
+	"github.com/spf13/cobra"
+)
+
+// InteractiveFlag is the name of the flag of the commands that delete or overwrite that prompts before each of them
+const InteractiveFlag = "interactive"
+
+// openTerminal opens the terminal of the process to read the answers to interactive prompts from
+var openTerminal = func() (io.ReadCloser, error) {
+	if runtime.GOOS == "windows" {
+		return os.Open("CONIN$")
+	}
+
+	return os.Open("/dev/tty")
+}
+
+// Output writes the messages a command shows its user to the command's writer. Messages are
+// translated and styled by their level, and info and success messages are left out with --quiet.
+// Confirmation prompts are answered from the reader unless they are assumed with --yes, or from
+// the terminal with --interactive, which is kept open until the Output is closed.
+type Output struct {
+	writer      io.Writer
+	style       *Styler
+	printer     i18n.Printer
+	quiet       bool
+	reader      io.Reader
+	answers     *bufio.Reader
+	terminal    io.Closer
+	assumeYes   bool
+	interactive bool
+}
+
+// NewOutput creates an Output that writes to the writer in the language of the environment
+func NewOutput(writer io.Writer, style *Styler, quiet bool) *Output {
+	return &Output{writer: writer, style: style, printer: i18n.NewPrinter(""), quiet: quiet}
+}
+
+// OutputFromFlags creates an Output for the writer that respects the --quiet, --no-color, --yes, --lang,
+// and --interactive flags, and reads the answers to prompts from the command's input. --interactive asks
+// even when --yes is given.
+func OutputFromFlags(cmd *cobra.Command, writer io.Writer) *Output {
+	quiet, _ := cmd.Flags().GetBool(QuietFlag)
+	assumeYes, _ := cmd.Flags().GetBool(YesFlag)
+	interactive, _ := cmd.Flags().GetBool(InteractiveFlag)
+
+	out := NewOutput(writer, StylerFromFlags(cmd, writer), quiet)
+	out.printer = PrinterFromFlags(cmd)
+	out.reader = cmd.InOrStdin()
+	out.assumeYes = assumeYes && !interactive
+	out.interactive = interactive
+
+	return out
+}
+
+// PrinterFromFlags returns the Printer of the language of the --lang flag, or of the environment when
+// the flag is not used
+func PrinterFromFlags(cmd *cobra.Command) i18n.Printer {
+	lang, _ := cmd.Flags().GetString(LangFlag)
+	return i18n.NewPrinter(lang)
+}
+
+// AddInteractiveFlag adds the -i flag that prompts before every deletion or overwrite of the command
+func AddInteractiveFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolP(InteractiveFlag, "i", false, "Prompt before every deletion or overwrite, reading the answer from the terminal")
+}
+
+// Close closes the terminal opened to answer the prompts with --interactive, if one was opened
+func (o *Output) Close() error {
+	if o.terminal == nil {
+		return nil
+	}
+
+	err := o.terminal.Close()
+	o.terminal = nil
+	o.answers = nil
+
+	return err
+}
+
+// Interactive reports whether the command was asked to prompt before every deletion or overwrite
+func (o *Output) Interactive() bool {
+	return o.interactive
+}
+
+// Info writes an informational message about what the command is doing
+func (o *Output) Info(format string, args ...any) {
+	if !o.quiet {
+		fmt.Fprintln(o.writer, o.printer.T(format, args...))
+	}
+}
+
+// Success writes a message reporting a completed operation
+func (o *Output) Success(format string, args ...any) {
+	if !o.quiet {
+		fmt.Fprintln(o.writer, o.style.Success(o.printer.T(format, args...)))
+	}
+}
+
+// Warning writes a message about something the user should know, even with --quiet
+func (o *Output) Warning(format string, args ...any) {
+	fmt.Fprintln(o.writer, o.style.Warning(o.printer.T(format, args...)))
+}
+
+// DryRun writes what a command run with --dry-run would have done, even with --quiet
+func (o *Output) DryRun(format string, args ...any) {
+	fmt.Fprintln(o.writer, o.style.Warning(o.printer.T(format, args...)))
+}
+
+// Error writes a message explaining why the command failed, even with --quiet
+func (o *Output) Error(format string, args ...any) {
+	fmt.Fprintln(o.writer, o.style.Error(o.printer.T(format, args...)))
+}
+
+// T translates the message into the language of the output, for commands that write their results
+// themselves
+func (o *Output) T(format string, args ...any) string {
+	return o.printer.T(format, args...)
+}
+
+// Style returns the Styler used for the output, for commands that style their results
+func (o *Output) Style() *Styler {
+	return o.style
+}
+
+// Confirm asks the user to confirm a destructive operation and returns Err23 if they do not.
+// The prompt is skipped with --yes, and when the input is a file that is not a terminal, like a pipe or
+// /dev/null in a script, since the command is then run as a batch with no one to ask. A missing
+// answer is a no. With --interactive an input that is not a terminal, like piped IDs, is not read for
+// the answer, which is read from the terminal of the process instead.
+func (o *Output) Confirm(format string, args ...any) error {
+	if o.assumeYes {
+		return nil
+	}
+
+	if _, ok := o.reader.(*os.File); ok && !o.interactive && !isTerminalFile(o.reader) {
+		return nil
+	}
+
+	fmt.Fprintf(o.writer, "%s %s ", o.style.Warning(o.printer.T(format, args...)), o.printer.T("[y/N]:"))
+
+	var answer string
+	if answers := o.answerReader(); answers != nil {
+		answer, _ = answers.ReadString('\n')
+	}
+
+	// End the prompt line when there was no answer to end it
+	if !strings.HasSuffix(answer, "\n") {
+		fmt.Fprintln(o.writer)
+	}
+
+	if o.printer.IsYes(answer) {
+		return nil
+	}
+
+	return error_msgs.Err23
+}
+
+// answerReader returns the reader the answers to prompts are read from. It is made once and kept, so the
+// answers it buffers ahead of the one it returns, like piped answers to several prompts, are read by the
+// prompts after it. The terminal opened with --interactive is kept open for the prompts after it too.
+func (o *Output) answerReader() *bufio.Reader {
+	if o.answers != nil {
+		return o.answers
+	}
+
+	reader := o.reader
+	if o.interactive && !isTerminalFile(reader) {
+		reader = nil
+		if terminal, err := openTerminal(); err == nil {
+			reader = terminal
+			o.terminal = terminal
+		}
+	}
+
+	if reader != nil {
+		o.answers = bufio.NewReader(reader)
+	}
+
+	return o.answers
+}
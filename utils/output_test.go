@@ -0,0 +1,223 @@
+package utils
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/i18n"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestOutput tests if messages are written to the writer at their level and left out with --quiet
+func TestOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		quiet    bool
+		expected string
+	}{
+		{name: "all messages", quiet: false, expected: "info a5388\nsuccess\nwarning\ndry run\nerror\n"},
+		{name: "quiet", quiet: true, expected: "warning\ndry run\nerror\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			out := NewOutput(&buf, &Styler{}, test.quiet)
+
+			out.Info("info %s", "a5388")
+			out.Success("success")
+			out.Warning("warning")
+			out.DryRun("dry run")
+			out.Error("error")
+
+			assert.Equal(t, test.expected, buf.String())
+		})
+	}
+}
+
+// TestConfirm tests if prompts are answered from the reader or assumed with --yes
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		name      string
+		answer    string
+		assumeYes bool
+		expectErr error
+	}{
+		{name: "yes", answer: "yes\n", expectErr: nil},
+		{name: "yes in the locale", answer: "sí\n", expectErr: nil},
+		{name: "no", answer: "n\n", expectErr: error_msgs.Err23},
+		{name: "no answer", answer: "", expectErr: error_msgs.Err23},
+		{name: "assumed with --yes", answer: "", assumeYes: true, expectErr: nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			out := NewOutput(&buf, &Styler{}, true)
+			out.printer = i18n.NewPrinter(i18n.Spanish)
+			out.reader = strings.NewReader(test.answer)
+			out.assumeYes = test.assumeYes
+
+			err := out.Confirm("Overwrite %s?", "a5388")
+			assert.ErrorIs(t, err, test.expectErr)
+			assert.Equal(t, !test.assumeYes, strings.Contains(buf.String(), "a5388"))
+		})
+	}
+}
+
+// TestConfirmBatch tests that a command whose input is a file that is not a terminal is not prompted, unless
+// it is asked to with --interactive
+func TestConfirmBatch(t *testing.T) {
+	defer func(open func() (io.ReadCloser, error)) { openTerminal = open }(openTerminal)
+	openTerminal = func() (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader("n\n")), nil
+	}
+
+	input, err := os.Open(os.DevNull)
+	require.NoError(t, err)
+	defer input.Close()
+
+	var buf bytes.Buffer
+	out := NewOutput(&buf, &Styler{}, true)
+	out.reader = input
+
+	assert.NoError(t, out.Confirm("Delete %s?", "a5388"))
+	assert.Empty(t, buf.String())
+
+	out.interactive = true
+	assert.ErrorIs(t, out.Confirm("Delete %s?", "a5388"), error_msgs.Err23)
+	assert.Contains(t, buf.String(), "Delete a5388?")
+}
+
+// TestConfirmMany tests that each prompt is answered by the next line of the input, rather than the lines
+// after the first being lost to the prompt that read them ahead
+func TestConfirmMany(t *testing.T) {
+	defer func(open func() (io.ReadCloser, error)) { openTerminal = open }(openTerminal)
+
+	var opened int
+	openTerminal = func() (io.ReadCloser, error) {
+		opened++
+		return io.NopCloser(strings.NewReader("y\nn\ny\n")), nil
+	}
+
+	for _, interactive := range []bool{false, true} {
+		out := NewOutput(io.Discard, &Styler{}, true)
+		out.reader = strings.NewReader("y\nn\ny\n")
+		out.interactive = interactive
+
+		assert.NoError(t, out.Confirm("Delete %s?", "a5388"))
+		assert.ErrorIs(t, out.Confirm("Delete %s?", "b5488"), error_msgs.Err23)
+		assert.NoError(t, out.Confirm("Delete %s?", "a54892"))
+		assert.ErrorIs(t, out.Confirm("Delete %s?", "c5498"), error_msgs.Err23)
+	}
+
+	// The terminal is opened once for all of the prompts
+	assert.Equal(t, 1, opened)
+}
+
+// TestInteractive tests that interactive prompts are answered from the terminal when the input is not one
+func TestInteractive(t *testing.T) {
+	defer func(open func() (io.ReadCloser, error)) { openTerminal = open }(openTerminal)
+
+	tests := []struct {
+		name      string
+		terminal  string
+		noTTY     bool
+		expectErr error
+	}{
+		{name: "yes on the terminal", terminal: "y\n", expectErr: nil},
+		{name: "no on the terminal", terminal: "n\n", expectErr: error_msgs.Err23},
+		{name: "no terminal", noTTY: true, expectErr: error_msgs.Err23},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			openTerminal = func() (io.ReadCloser, error) {
+				if test.noTTY {
+					return nil, os.ErrNotExist
+				}
+				return io.NopCloser(strings.NewReader(test.terminal)), nil
+			}
+
+			// The piped input is not read for the answer
+			var buf bytes.Buffer
+			piped := "y\n"
+			input := strings.NewReader(piped)
+			out := NewOutput(&buf, &Styler{}, true)
+			out.reader = input
+			out.interactive = true
+
+			err := out.Confirm("Delete %s?", "a5388")
+			assert.ErrorIs(t, err, test.expectErr)
+			assert.Contains(t, buf.String(), "Delete a5388?")
+			assert.Equal(t, len(piped), input.Len())
+		})
+	}
+
+	// --interactive asks even when --yes is given
+	cmd := &cobra.Command{}
+	cmd.Flags().BoolP(YesFlag, "y", false, "")
+	AddInteractiveFlag(cmd)
+	require.NoError(t, cmd.ParseFlags([]string{"--yes", "-i"}))
+
+	out := OutputFromFlags(cmd, io.Discard)
+	assert.True(t, out.Interactive())
+	assert.False(t, out.assumeYes)
+}
+
+// terminal is a terminal that answers prompts from its input and records whether it was closed
+type terminal struct {
+	io.Reader
+	closed bool
+}
+
+func (t *terminal) Close() error {
+	t.closed = true
+	return nil
+}
+
+// TestInteractivePipe tests that with -i the prompts of a command whose input is piped are answered from the
+// terminal, and that the terminal is closed when the command's Output is
+func TestInteractivePipe(t *testing.T) {
+	defer func(open func() (io.ReadCloser, error)) { openTerminal = open }(openTerminal)
+
+	tty := &terminal{Reader: strings.NewReader("y\n")}
+	openTerminal = func() (io.ReadCloser, error) {
+		return tty, nil
+	}
+
+	reader, writer, err := os.Pipe()
+	require.NoError(t, err)
+	defer reader.Close()
+
+	_, err = writer.WriteString("n\n")
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	cmd := &cobra.Command{}
+	AddInteractiveFlag(cmd)
+	cmd.SetIn(reader)
+	require.NoError(t, cmd.ParseFlags([]string{"-i"}))
+
+	var buf bytes.Buffer
+	out := OutputFromFlags(cmd, &buf)
+
+	assert.NoError(t, out.Confirm("Delete %s?", "a5388"))
+	assert.Contains(t, buf.String(), "Delete a5388?")
+	assert.False(t, tty.closed)
+
+	// The piped input was left for the command to read
+	piped, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "n\n", string(piped))
+
+	assert.NoError(t, out.Close())
+	assert.True(t, tty.closed)
+	assert.NoError(t, out.Close())
+}
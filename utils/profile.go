@@ -0,0 +1,126 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"sync"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/spf13/cobra"
+)
+
+// profilesKey is the context key of the profiles recorded for a run of a command
+type profilesKey struct{}
+
+// profiles are the profiles being recorded for one run of a command, Execute stops them when the run ends
+type profiles struct {
+	sync.Mutex
+	cpu     *os.File
+	trace   *os.File
+	memPath string
+}
+
+// startProfiles starts the CPU profile and execution trace, and records where the memory
+// profile is written, for the --cpuprofile, --trace, and --memprofile flags
+func startProfiles(cmd *cobra.Command) (err error) {
+	cpuPath, _ := cmd.Flags().GetString(CPUProfileFlag)
+	tracePath, _ := cmd.Flags().GetString(TraceFlag)
+	memPath, _ := cmd.Flags().GetString(MemProfileFlag)
+
+	if cpuPath == "" && tracePath == "" && memPath == "" {
+		return nil
+	}
+
+	run, ok := cmd.Context().Value(profilesKey{}).(*profiles)
+	if !ok {
+		return fmt.Errorf("%w: profiles are only recorded for commands run by pt", error_msgs.Err17)
+	}
+
+	run.Lock()
+	defer run.Unlock()
+
+	// Stop anything already started if a later profile can not be started
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, run.stopLocked())
+		}
+	}()
+
+	run.memPath = memPath
+
+	if cpuPath != "" {
+		if run.cpu, err = os.Create(cpuPath); err != nil {
+			return fmt.Errorf("could not create CPU profile: %w", err)
+		}
+
+		if err = pprof.StartCPUProfile(run.cpu); err != nil {
+			return fmt.Errorf("could not start CPU profile: %w", err)
+		}
+	}
+
+	if tracePath != "" {
+		if run.trace, err = os.Create(tracePath); err != nil {
+			return fmt.Errorf("could not create trace: %w", err)
+		}
+
+		if err = trace.Start(run.trace); err != nil {
+			return fmt.Errorf("could not start trace: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// stop stops the CPU profile and trace and writes the memory profile
+func (p *profiles) stop() error {
+	p.Lock()
+	defer p.Unlock()
+
+	return p.stopLocked()
+}
+
+// stopLocked stops the profiles while their lock is held
+func (p *profiles) stopLocked() error {
+	var err error
+
+	if p.cpu != nil {
+		pprof.StopCPUProfile()
+		err = errors.Join(err, p.cpu.Close())
+		p.cpu = nil
+	}
+
+	if p.trace != nil {
+		trace.Stop()
+		err = errors.Join(err, p.trace.Close())
+		p.trace = nil
+	}
+
+	if p.memPath != "" {
+		err = errors.Join(err, writeMemProfile(p.memPath))
+		p.memPath = ""
+	}
+
+	return err
+}
+
+// writeMemProfile writes a heap profile of the memory allocated by the command
+func writeMemProfile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("could not create memory profile: %w", err)
+	}
+	defer file.Close()
+
+	// Collect garbage so the profile has up-to-date statistics
+	runtime.GC()
+
+	if err := pprof.WriteHeapProfile(file); err != nil {
+		return fmt.Errorf("could not write memory profile: %w", err)
+	}
+
+	return nil
+}
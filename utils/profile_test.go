@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestProfiles tests if the profiles are written for a command that succeeds or fails
+func TestProfiles(t *testing.T) {
+	tests := []struct {
+		name      string
+		expectErr error
+	}{
+		{name: "command succeeds", expectErr: nil},
+		{name: "command fails", expectErr: os.ErrNotExist},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			dir := t.TempDir()
+
+			subCmd := &cobra.Command{
+				Use: "work",
+				RunE: func(cmd *cobra.Command, args []string) error {
+					return test.expectErr
+				},
+			}
+
+			args := []string{
+				"--cpuprofile", filepath.Join(dir, "cpu.pprof"),
+				"--memprofile", filepath.Join(dir, "mem.pprof"),
+				"--trace", filepath.Join(dir, "trace.out"),
+			}
+
+			err := RunSubcommand(subCmd, args, &buf)
+			assert.ErrorIs(t, err, test.expectErr)
+
+			for _, name := range []string{"cpu.pprof", "mem.pprof", "trace.out"} {
+				info, err := os.Stat(filepath.Join(dir, name))
+				require.NoError(t, err, "%s was not written", name)
+				assert.NotZero(t, info.Size(), "%s is empty", name)
+			}
+		})
+	}
+}
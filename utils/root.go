@@ -0,0 +1,274 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pairtree"
+	"github.com/spf13/cobra"
+)
+
+// Names of the persistent flags shared by every pt subcommand
+const (
+	PairtreeFlag      = "pairtree"
+	LogLevelFlag      = "log-level"
+	LogFileFlag       = "log-file"
+	NoLogFileFlag     = "no-log-file"
+	LogFormatFlag     = "log-format"
+	LogMaxSizeFlag    = "log-max-size"
+	LogMaxAgeFlag     = "log-max-age"
+	LogMaxBackupsFlag = "log-max-backups"
+	LogCompressFlag   = "log-compress"
+	QuietFlag         = "quiet"
+	JSONFlag          = "json"
+	NoColorFlag       = "no-color"
+	ErrorsFlag        = "errors"
+	VerboseFlag       = "verbose"
+	LangFlag          = "lang"
+	TimeoutFlag       = "timeout"
+	CPUProfileFlag    = "cpuprofile"
+	MemProfileFlag    = "memprofile"
+	TraceFlag         = "trace"
+	YesFlag           = "yes"
+)
+
+// S3Annotation marks a command that can work with a pairtree in S3, which the other commands refuse
+const S3Annotation = "pt.s3"
+
+const rootLong = `pt facilitates interactions with a Pairtree without the user needing to know about the Pairtree’s internal structure.
+
+Please refer to the README(https://github.com/UCLALibrary/pt-tools) for more detailed instructions`
+
+// NewRootCmd creates the pt root command with the persistent flags shared by all subcommands
+func NewRootCmd(writer io.Writer) *cobra.Command {
+	var rootCmd = &cobra.Command{
+		Use:   "pt [command]",
+		Short: "pt is a tool to interact with a Pairtree",
+		Long:  rootLong,
+		// Adds a --version flag, pt version --build reports the rest of the build metadata
+		Version: Version,
+		// Suggest subcommands within this edit distance of an unknown command
+		SuggestionsMinimumDistance: 2,
+		// The root command only runs when no subcommand or an unknown one is given
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				return fmt.Errorf("%w: unknown command %q for %q%s", error_msgs.Err17, args[0],
+					cmd.CommandPath(), suggestions(cmd, args[0]))
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return fmt.Errorf("%w: a command must be provided", error_msgs.Err17)
+		},
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := applyErrorsFormat(cmd); err != nil {
+				return err
+			}
+
+			if err := applyTimeout(cmd); err != nil {
+				return err
+			}
+
+			// Each command applies the console level to its own logger, an invalid level is refused for all of them
+			if _, err := consoleLevel(cmd); err != nil {
+				return err
+			}
+
+			return startProfiles(cmd)
+		},
+	}
+
+	rootCmd.PersistentFlags().StringP(PairtreeFlag, "p", "", "Set pairtree root directory")
+	rootCmd.PersistentFlags().String(LogLevelFlag, "error", "Set the console log level (debug, info, warn, error)")
+	rootCmd.PersistentFlags().String(LogFileFlag, "", "Also write logs to this file (defaults to ENV PT_LOG_FILE)")
+	rootCmd.PersistentFlags().Bool(NoLogFileFlag, false, "Do not write logs to a file even if PT_LOG_FILE is set")
+	rootCmd.PersistentFlags().String(LogFormatFlag, "", "Set the log encoding to json or console (defaults to ENV PT_LOG_FORMAT)")
+	rootCmd.PersistentFlags().Int(LogMaxSizeFlag, DefaultLogRotation.MaxSize, "Megabytes the log file may grow to before it is rotated")
+	rootCmd.PersistentFlags().Int(LogMaxAgeFlag, DefaultLogRotation.MaxAge, "Days to keep rotated log files, 0 keeps them regardless of age")
+	rootCmd.PersistentFlags().Int(LogMaxBackupsFlag, DefaultLogRotation.MaxBackups, "Number of rotated log files to keep, 0 keeps all of them")
+	rootCmd.PersistentFlags().Bool(LogCompressFlag, false, "Compress rotated log files with gzip")
+	rootCmd.PersistentFlags().BoolP(QuietFlag, "q", false, "Suppress informational output")
+	rootCmd.PersistentFlags().CountP(VerboseFlag, "v", "Increase log detail on the console (-v for info, -vv for debug)")
+	rootCmd.PersistentFlags().BoolP(YesFlag, "y", false, "Do not ask for confirmation before deleting or overwriting")
+	rootCmd.PersistentFlags().Bool(JSONFlag, false, "Output in JSON format where supported")
+	rootCmd.PersistentFlags().String(ErrorsFlag, ErrorsText, "Write errors as text or as a json object on stderr")
+	rootCmd.PersistentFlags().Bool(NoColorFlag, false, "Do not color the output (also disabled by ENV NO_COLOR)")
+	rootCmd.PersistentFlags().Duration(TimeoutFlag, 0, "Stop the command if it runs longer than this, like 30m (0 for no limit)")
+	rootCmd.PersistentFlags().String(LangFlag, "", "Set the language of messages to en or es (defaults to ENV LANG)")
+
+	// Profiling is for diagnosing performance problems so it is left out of the help
+	rootCmd.PersistentFlags().String(CPUProfileFlag, "", "Write a pprof CPU profile of the command to this file")
+	rootCmd.PersistentFlags().String(MemProfileFlag, "", "Write a pprof memory profile at the end of the command to this file")
+	rootCmd.PersistentFlags().String(TraceFlag, "", "Write a runtime execution trace of the command to this file")
+	for _, name := range []string{CPUProfileFlag, MemProfileFlag, TraceFlag} {
+		_ = rootCmd.PersistentFlags().MarkHidden(name)
+	}
+
+	rootCmd.SetVersionTemplate("pt version {{.Version}}\n")
+
+	rootCmd.SetOut(writer)
+	rootCmd.SetErr(writer)
+
+	// Errors are translated before they are printed so Execute prints them and the usage
+	// that follows them instead of cobra
+	rootCmd.SilenceErrors = true
+	rootCmd.SilenceUsage = true
+
+	// Mark flag parsing errors as usage errors so they map to the usage exit code
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return fmt.Errorf("%w: %w", error_msgs.Err17, err)
+	})
+
+	return rootCmd
+}
+
+// RunSubcommand executes a single subcommand underneath a new pt root command so that the
+// persistent flags are parsed the same way they are when pt is run from the command line
+func RunSubcommand(subCmd *cobra.Command, args []string, writer io.Writer) error {
+	return RunSubcommandWithInput(subCmd, args, nil, writer)
+}
+
+// RunSubcommandWithInput executes a single subcommand like RunSubcommand, answering its
+// confirmation prompts from the reader instead of stdin
+func RunSubcommandWithInput(subCmd *cobra.Command, args []string, reader io.Reader, writer io.Writer) error {
+	rootCmd := NewRootCmd(writer)
+	rootCmd.SetIn(reader)
+	rootCmd.AddCommand(subCmd)
+	rootCmd.SetArgs(append([]string{subCmd.Name()}, args...))
+
+	_, err := Execute(context.Background(), rootCmd)
+	return err
+}
+
+// Execute runs the root command with the context and prints a returned error in the user's
+// language, unless the error is to be written as JSON by the caller. When a --timeout expires and
+// the command does not stop within the grace period, Execute returns without waiting for it.
+func Execute(ctx context.Context, rootCmd *cobra.Command) (*cobra.Command, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Commands report the start of their timeout and the profiles they record through the context
+	started := make(chan timeoutStart, 1)
+	ctx = context.WithValue(ctx, timeoutKey{}, started)
+	run := &profiles{}
+	ctx = context.WithValue(ctx, profilesKey{}, run)
+
+	done := make(chan commandResult, 1)
+	go func() {
+		cmd, err := rootCmd.ExecuteContextC(ctx)
+		done <- commandResult{cmd: cmd, err: err}
+	}()
+
+	result, timeout, abandoned := waitForCommand(done, started)
+
+	// Profiles are written even when the command fails or is abandoned, that is when they are most useful
+	if err := run.stop(); err != nil {
+		result.err = errors.Join(result.err, err)
+	}
+	if abandoned {
+		// The command is still running so only what was read from it when its timeout started is used
+		if !timeout.asJSON {
+			fmt.Fprintln(timeout.errOut, timeout.style.Error(timeout.printer.T("Error:")), timeout.printer.Error(result.err))
+		}
+
+		return result.cmd, result.err
+	}
+
+	cmd, err := result.cmd, result.err
+	if err != nil {
+		addSuggestions(cmd, err)
+	}
+
+	// pt exists answers with its exit code alone, so an object not existing is not written as an error
+	if err != nil && !ErrorsAsJSON(cmd) && !errors.Is(err, error_msgs.Err47) {
+		style, printer := StylerFromFlags(cmd, cmd.ErrOrStderr()), PrinterFromFlags(cmd)
+		fmt.Fprintln(cmd.ErrOrStderr(), style.Error(printer.T("Error:")), printer.Error(err))
+
+		var ptErr *error_msgs.PtError
+		if errors.As(err, &ptErr) && len(ptErr.Suggestions) > 0 {
+			fmt.Fprintln(cmd.ErrOrStderr(), printer.T("Did you mean %s?", strings.Join(ptErr.Suggestions, ", ")))
+		}
+
+		// Commands silence the usage with SilenceUsage once their arguments have been validated
+		if !cmd.SilenceUsage {
+			cmd.Println(cmd.UsageString())
+		}
+	}
+
+	return cmd, err
+}
+
+// SilenceUsage is called by a command once its arguments are valid, so Execute does not print the usage of
+// the command with the errors it returns after that point
+func SilenceUsage(cmd *cobra.Command) {
+	cmd.SilenceUsage = true
+}
+
+// SignalContext returns a context that is canceled on SIGINT or SIGTERM so commands can clean up
+// what they were writing, a second signal stops pt immediately
+func SignalContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
+	// Restore the default behavior once the first signal is received
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	return ctx, stop
+}
+
+// GetPtRoot returns the pairtree root from the --pairtree flag or the PAIRTREE_ROOT environment variable.
+// A root in S3 is only returned for commands with the S3Annotation.
+func GetPtRoot(cmd *cobra.Command, writer io.Writer) (string, error) {
+	if _, err := cmd.Flags().GetString(PairtreeFlag); err != nil {
+		return "", err
+	}
+
+	ptRoot := lookupPtRoot(cmd)
+	if ptRoot == "" {
+		fmt.Fprintln(writer, StylerFromFlags(cmd, writer).Error(PrinterFromFlags(cmd).Error(error_msgs.Err7)))
+		return "", error_msgs.Err7
+	}
+
+	if pairtree.IsS3(ptRoot) && cmd.Annotations[S3Annotation] == "" {
+		return "", fmt.Errorf("%w: pt %s", error_msgs.Err41, cmd.Name())
+	}
+
+	return ptRoot, nil
+}
+
+// lookupPtRoot returns the pairtree root from the --pairtree flag or the PAIRTREE_ROOT environment
+// variable, or an empty string when neither is set
+func lookupPtRoot(cmd *cobra.Command) string {
+	ptRoot, _ := cmd.Flags().GetString(PairtreeFlag)
+
+	// If the root has not been set yet check the ENV vars
+	if ptRoot == "" {
+		ptRoot = os.Getenv("PAIRTREE_ROOT")
+	}
+
+	return ptRoot
+}
+
+// suggestions lists the subcommands with names close to the unknown command
+func suggestions(cmd *cobra.Command, typedName string) string {
+	suggested := cmd.SuggestionsFor(typedName)
+	if len(suggested) == 0 {
+		return ""
+	}
+
+	text := "\n\nDid you mean this?\n"
+	for _, name := range suggested {
+		text += fmt.Sprintf("\t%v\n", name)
+	}
+
+	return text
+}
@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHelp tests if asking for help writes it to the writer and returns instead of exiting
+func TestHelp(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "help flag", args: []string{"work", "--help"}},
+		{name: "help shorthand", args: []string{"work", "-h"}},
+		{name: "help command", args: []string{"help", "work"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			ran := false
+
+			rootCmd := NewRootCmd(&buf)
+			rootCmd.AddCommand(&cobra.Command{
+				Use:   "work",
+				Short: "work is a command used to test help",
+				Run:   func(cmd *cobra.Command, args []string) { ran = true },
+			})
+			rootCmd.SetArgs(test.args)
+
+			_, err := Execute(context.Background(), rootCmd)
+			require.NoError(t, err)
+
+			assert.False(t, ran, "The command should not run when help is requested")
+			assert.Contains(t, buf.String(), "work is a command used to test help")
+			assert.Contains(t, buf.String(), "Usage:")
+		})
+	}
+}
+
+// TestLanguage tests that runs at the same time write their errors in the language of their own --lang
+func TestLanguage(t *testing.T) {
+	tests := []struct {
+		lang     string
+		expected string
+	}{
+		{lang: "en", expected: "--pairtree flag or PAIRTREE_ROOT environment variable must be set"},
+		{lang: "es", expected: "se debe establecer la opción --pairtree o la variable de entorno PAIRTREE_ROOT"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.lang, func(t *testing.T) {
+			t.Parallel()
+
+			for range 10 {
+				var buf bytes.Buffer
+
+				rootCmd := NewRootCmd(&buf)
+				rootCmd.AddCommand(&cobra.Command{
+					Use:  "work",
+					RunE: func(cmd *cobra.Command, args []string) error { return error_msgs.Err7 },
+				})
+				rootCmd.SetArgs([]string{"work", "--lang", test.lang})
+
+				_, err := Execute(context.Background(), rootCmd)
+				require.ErrorIs(t, err, error_msgs.Err7)
+				assert.Contains(t, buf.String(), test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"crypto/ed25519"
+
+	"github.com/UCLALibrary/pt-tools/pkg/audit"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// SignKeyFlag is the flag of the commands that sign the files they write with an Ed25519 private key
+const SignKeyFlag = "sign-key"
+
+// AddSignKeyFlag adds the --sign-key flag to a command that signs the files it writes
+func AddSignKeyFlag(cmd *cobra.Command) {
+	cmd.Flags().String(SignKeyFlag, "", "Sign what is written with the Ed25519 private key of this PEM file")
+}
+
+// SignKeyFromFlags returns the private key of the --sign-key flag, or nil when the flag is not set
+func SignKeyFromFlags(cmd *cobra.Command) (ed25519.PrivateKey, error) {
+	keyPath, _ := cmd.Flags().GetString(SignKeyFlag)
+	if keyPath == "" {
+		return nil, nil
+	}
+
+	return audit.LoadKey(keyPath)
+}
+
+// SignFile writes a detached signature of the file beside it. The file has already been written, so the
+// error is returned for the command to fail with while the file is kept.
+func SignFile(key ed25519.PrivateKey, path string, out *Output, logger *zap.Logger) error {
+	sigPath, err := audit.SignFile(key, path)
+	if err != nil {
+		logger.Error("Error signing the file", zap.String("path", path), zap.Error(err))
+		return err
+	}
+
+	out.Success("Signed %s in %s", path, sigPath)
+	logger.Info("Signed the file", zap.String("path", path), zap.String("signature", sigPath))
+
+	return nil
+}
@@ -0,0 +1,23 @@
+package utils
+
+import "fmt"
+
+// sizeUnits are the binary units of sizes that are a KiB or larger
+var sizeUnits = []string{"KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+
+// FormatSize returns the number of bytes in the largest binary unit it is at least one of, with one
+// decimal place, like ls -lh. Sizes under a KiB are given in bytes.
+func FormatSize(bytes int64) string {
+	if bytes < 1024 {
+		return fmt.Sprintf("%d B", bytes)
+	}
+
+	size := float64(bytes) / 1024
+	unit := 0
+	for size >= 1024 && unit < len(sizeUnits)-1 {
+		size /= 1024
+		unit++
+	}
+
+	return fmt.Sprintf("%.1f %s", size, sizeUnits[unit])
+}
@@ -0,0 +1,18 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestFormatSize tests that sizes are given in the largest binary unit they are at least one of
+func TestFormatSize(t *testing.T) {
+	assert.Equal(t, "0 B", FormatSize(0))
+	assert.Equal(t, "1023 B", FormatSize(1023))
+	assert.Equal(t, "1.0 KiB", FormatSize(1024))
+	assert.Equal(t, "1.5 KiB", FormatSize(1536))
+	assert.Equal(t, "5.0 MiB", FormatSize(5<<20))
+	assert.Equal(t, "2.0 TiB", FormatSize(2<<40))
+	assert.Equal(t, "8.0 EiB", FormatSize(1<<63-1))
+}
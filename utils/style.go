@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// ANSI escape codes used to style output
+const (
+	ansiReset  = "\033[0m"
+	ansiRed    = "\033[31m"
+	ansiGreen  = "\033[32m"
+	ansiYellow = "\033[33m"
+	ansiBlue   = "\033[1;34m"
+)
+
+// Styler applies consistent colors to the output of every command. Colors are only used
+// when the output is a terminal, the NO_COLOR environment variable is not set, and the
+// --no-color flag is not used.
+type Styler struct {
+	enabled bool
+}
+
+// NewStyler creates a Styler for output written to the writer
+func NewStyler(writer io.Writer, noColor bool) *Styler {
+	if noColor {
+		return &Styler{}
+	}
+
+	// https://no-color.org asks for color to be disabled when NO_COLOR is set to any value
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return &Styler{}
+	}
+
+	return &Styler{enabled: IsTerminal(writer)}
+}
+
+// StylerFromFlags creates a Styler for the writer that respects the --no-color flag
+func StylerFromFlags(cmd *cobra.Command, writer io.Writer) *Styler {
+	noColor, _ := cmd.Flags().GetBool(NoColorFlag)
+	return NewStyler(writer, noColor)
+}
+
+// IsTerminal determines if the writer is a terminal
+func IsTerminal(writer io.Writer) bool {
+	return isCharDevice(writer)
+}
+
+// isTerminalFile determines if the reader is a file of a terminal, rather than a pipe, a regular file, or
+// another device like /dev/null
+func isTerminalFile(reader io.Reader) bool {
+	file, ok := reader.(*os.File)
+	return ok && term.IsTerminal(int(file.Fd()))
+}
+
+// isCharDevice determines if the reader or writer is a file of a character device, like a terminal
+func isCharDevice(v any) bool {
+	file, ok := v.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// Error styles an error message
+func (s *Styler) Error(text string) string {
+	return s.apply(ansiRed, text)
+}
+
+// Warning styles a warning message
+func (s *Styler) Warning(text string) string {
+	return s.apply(ansiYellow, text)
+}
+
+// Success styles a message reporting a completed operation
+func (s *Styler) Success(text string) string {
+	return s.apply(ansiGreen, text)
+}
+
+// Directory styles a directory in a listing
+func (s *Styler) Directory(text string) string {
+	return s.apply(ansiBlue, text)
+}
+
+// apply wraps the text in the color code when styling is enabled
+func (s *Styler) apply(color, text string) string {
+	if !s.enabled {
+		return text
+	}
+
+	return color + text + ansiReset
+}
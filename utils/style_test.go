@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestStyler tests if colors are only applied when styling is enabled
+func TestStyler(t *testing.T) {
+	tests := []struct {
+		name     string
+		styler   *Styler
+		style    func(s *Styler, text string) string
+		expected string
+	}{
+		{name: "error", styler: &Styler{enabled: true}, style: (*Styler).Error, expected: ansiRed + "text" + ansiReset},
+		{name: "warning", styler: &Styler{enabled: true}, style: (*Styler).Warning, expected: ansiYellow + "text" + ansiReset},
+		{name: "success", styler: &Styler{enabled: true}, style: (*Styler).Success, expected: ansiGreen + "text" + ansiReset},
+		{name: "directory", styler: &Styler{enabled: true}, style: (*Styler).Directory, expected: ansiBlue + "text" + ansiReset},
+		{name: "disabled", styler: &Styler{}, style: (*Styler).Error, expected: "text"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, test.style(test.styler, "text"))
+		})
+	}
+}
+
+// TestNewStyler tests if styling is disabled for output that is not a terminal or when it is turned off
+func TestNewStyler(t *testing.T) {
+	var buf bytes.Buffer
+
+	assert.False(t, NewStyler(&buf, false).enabled, "a buffer is not a terminal")
+	assert.False(t, NewStyler(&buf, true).enabled, "--no-color disables styling")
+
+	t.Setenv("NO_COLOR", "")
+	assert.False(t, NewStyler(&buf, false).enabled, "NO_COLOR disables styling even when empty")
+}
@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"errors"
+	"io/fs"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/pairtree"
+	"github.com/spf13/cobra"
+)
+
+// maxSuggestions is the most IDs suggested for an ID that is not in the pairtree
+const maxSuggestions = 5
+
+// addSuggestions adds the IDs that were likely meant to the error of an object that does not exist,
+// so a mistyped ID is reported with the IDs of the pairtree that are close to it
+func addSuggestions(cmd *cobra.Command, err error) {
+	var ptErr *error_msgs.PtError
+	if !errors.Is(err, fs.ErrNotExist) || !errors.As(err, &ptErr) || ptErr.ID == "" || ptErr.Suggestions != nil {
+		return
+	}
+
+	ptRoot, prefix, ok := rootAndPrefix(cmd)
+	if !ok {
+		return
+	}
+
+	// A pairtree that can not be searched leaves the error as it was
+	if ids, err := pairtree.SuggestIDs(ptRoot, prefix, ptErr.ID, maxSuggestions); err == nil {
+		ptErr.Suggestions = ids
+	}
+}
+
+// CompleteIDs completes an argument with the IDs of the objects in the pairtree that start with it,
+// for the shell completion of commands that take an ID. An argument that is not the start of an ID
+// is left to the shell's completion of file names.
+func CompleteIDs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	ptRoot, prefix, ok := rootAndPrefix(cmd)
+	if !ok {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+
+	ids, err := pairtree.CompleteIDs(ptRoot, prefix, toComplete)
+	if err != nil || len(ids) == 0 {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+
+	// The prefix alone is completed without a space so the rest of the ID can be typed after it
+	if len(ids) == 1 && ids[0] == prefix {
+		return ids, cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
+	}
+
+	return ids, cobra.ShellCompDirectiveNoFileComp
+}
+
+// rootAndPrefix returns the pairtree root set for the command and the prefix of its IDs, without
+// reporting a root that is not set or not a pairtree, which the command itself reports
+func rootAndPrefix(cmd *cobra.Command) (string, string, bool) {
+	ptRoot := lookupPtRoot(cmd)
+	if ptRoot == "" {
+		return "", "", false
+	}
+
+	prefix, err := pairtree.GetPrefix(ptRoot)
+	if err != nil {
+		return "", "", false
+	}
+	if prefix == "" {
+		prefix = pairtree.PtPrefix
+	}
+
+	return ptRoot, prefix, true
+}
@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSuggestions tests that an ID that is not in the pairtree is reported with the IDs that were likely meant
+func TestSuggestions(t *testing.T) {
+	ptRoot := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(ptRoot, "pairtree_prefix"), []byte("ark:/"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(ptRoot, "pairtree_root", "a5", "38", "8", "a5388"), 0755))
+
+	var buf bytes.Buffer
+	rootCmd := NewRootCmd(&buf)
+	rootCmd.AddCommand(&cobra.Command{
+		Use:               "work [ID]",
+		Short:             "work is a command used to test suggestions",
+		ValidArgsFunction: CompleteIDs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return &error_msgs.PtError{ID: args[0], Err: fmt.Errorf("open %s: %w", args[0], fs.ErrNotExist)}
+		},
+	})
+	rootCmd.SetArgs([]string{"work", "-p", ptRoot, "ark:/a5389"})
+
+	_, err := Execute(context.Background(), rootCmd)
+	require.ErrorIs(t, err, fs.ErrNotExist)
+	assert.Contains(t, buf.String(), "Did you mean ark:/a5388?")
+
+	var ptErr *error_msgs.PtError
+	require.ErrorAs(t, err, &ptErr)
+	assert.Equal(t, []string{"ark:/a5388"}, ptErr.Suggestions)
+
+	// The shell completion of the command completes the IDs of the pairtree
+	buf.Reset()
+	rootCmd.SetArgs([]string{cobra.ShellCompRequestCmd, "work", "-p", ptRoot, "ark:/a"})
+	_, err = Execute(context.Background(), rootCmd)
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "ark:/a5388\n")
+}
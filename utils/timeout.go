@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/UCLALibrary/pt-tools/pkg/i18n"
+	"github.com/spf13/cobra"
+)
+
+// TimeoutGrace is how long a command that has timed out is given to clean up before Execute
+// stops waiting for it, a command blocked on a stuck mount may never return
+var TimeoutGrace = 10 * time.Second
+
+// timeoutKey is the context key of the channel a command's timeout is reported on
+type timeoutKey struct{}
+
+// timeoutStart reports a command whose context has a timeout, along with how to report its
+// error should the command have to be abandoned while it is still running
+type timeoutStart struct {
+	cmd     *cobra.Command
+	ctx     context.Context
+	asJSON  bool
+	errOut  io.Writer
+	style   *Styler
+	printer i18n.Printer
+}
+
+// commandResult is what a command returned when it finished
+type commandResult struct {
+	cmd *cobra.Command
+	err error
+}
+
+// applyTimeout bounds the command's context by the --timeout flag
+func applyTimeout(cmd *cobra.Command) error {
+	timeout, err := cmd.Flags().GetDuration(TimeoutFlag)
+	if err != nil {
+		return err
+	}
+
+	if timeout <= 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeoutCause(cmd.Context(), timeout, error_msgs.Err19)
+	// The timer is also stopped when Execute cancels the parent context
+	context.AfterFunc(ctx, cancel)
+	cmd.SetContext(ctx)
+
+	if started, ok := ctx.Value(timeoutKey{}).(chan timeoutStart); ok {
+		started <- timeoutStart{
+			cmd:     cmd,
+			ctx:     ctx,
+			asJSON:  ErrorsAsJSON(cmd),
+			errOut:  cmd.ErrOrStderr(),
+			style:   StylerFromFlags(cmd, cmd.ErrOrStderr()),
+			printer: PrinterFromFlags(cmd),
+		}
+	}
+
+	return nil
+}
+
+// waitForCommand waits for the command to finish, or for the grace period after its timeout
+// in which case the command is abandoned
+func waitForCommand(done <-chan commandResult, started <-chan timeoutStart) (commandResult, timeoutStart, bool) {
+	var timedOut <-chan struct{}
+	var abandon <-chan time.Time
+	var timeout timeoutStart
+
+	for {
+		select {
+		case result := <-done:
+			// Commands return the context's error when they stop for the timeout
+			if errors.Is(result.err, context.DeadlineExceeded) {
+				result.err = fmt.Errorf("%w: %w", error_msgs.Err19, result.err)
+			}
+			return result, timeout, false
+		case timeout = <-started:
+			timedOut = timeout.ctx.Done()
+		case <-timedOut:
+			timedOut = nil
+			if context.Cause(timeout.ctx) == error_msgs.Err19 {
+				abandon = time.After(TimeoutGrace)
+			}
+		case <-abandon:
+			return commandResult{cmd: timeout.cmd, err: error_msgs.Err19}, timeout, true
+		}
+	}
+}
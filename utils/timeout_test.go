@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTimeout tests if commands are stopped by --timeout and report the timeout exit code
+func TestTimeout(t *testing.T) {
+	defer func(grace time.Duration) { TimeoutGrace = grace }(TimeoutGrace)
+	TimeoutGrace = 50 * time.Millisecond
+
+	tests := []struct {
+		name      string
+		args      []string
+		run       func(ctx context.Context) error
+		expectErr error
+	}{
+		{
+			name:      "finishes before the timeout",
+			args:      []string{"--timeout", "1s"},
+			run:       func(ctx context.Context) error { return nil },
+			expectErr: nil,
+		},
+		{
+			name: "stops for the timeout",
+			args: []string{"--timeout", "10ms"},
+			run: func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+			expectErr: error_msgs.Err19,
+		},
+		{
+			name: "ignores the timeout",
+			args: []string{"--timeout", "10ms"},
+			run: func(ctx context.Context) error {
+				time.Sleep(time.Second)
+				return nil
+			},
+			expectErr: error_msgs.Err19,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			subCmd := &cobra.Command{
+				Use: "wait",
+				RunE: func(cmd *cobra.Command, args []string) error {
+					return test.run(cmd.Context())
+				},
+			}
+
+			err := RunSubcommand(subCmd, test.args, &buf)
+			assert.ErrorIs(t, err, test.expectErr)
+
+			if test.expectErr != nil {
+				assert.Equal(t, ExitTimeout, ExitCode(err))
+			}
+		})
+	}
+}
@@ -1,48 +1,221 @@
 package utils
 
 import (
+	"fmt"
 	"os"
 
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
-// Logger creates logger with output of info and debug to file and error to stdout
-func Logger(logFile string) *zap.Logger {
+// LogRotation holds the limits used to rotate and retain the log file
+type LogRotation struct {
+	MaxSize    int  // megabytes a log file may grow to before it is rotated
+	MaxAge     int  // days a rotated log file is kept, 0 keeps them regardless of age
+	MaxBackups int  // number of rotated log files kept, 0 keeps all of them
+	Compress   bool // gzip rotated log files
+}
+
+// DefaultLogRotation is used when no rotation limits are set on the command line
+var DefaultLogRotation = LogRotation{MaxSize: 100, MaxAge: 30, MaxBackups: 5}
+
+// Log formats that can be selected with --log-format
+const (
+	LogFormatJSON    = "json"
+	LogFormatConsole = "console"
+)
+
+// consoleCore writes the messages at or above the level of one run of a command to the console,
+// so that runs with different levels do not change each other's
+type consoleCore struct {
+	zapcore.Core
+	level zapcore.LevelEnabler
+}
+
+func (c *consoleCore) Enabled(level zapcore.Level) bool {
+	return c.level.Enabled(level)
+}
+
+func (c *consoleCore) Level() zapcore.Level {
+	return zapcore.LevelOf(c.level)
+}
+
+func (c *consoleCore) With(fields []zapcore.Field) zapcore.Core {
+	return &consoleCore{Core: c.Core.With(fields), level: c.level}
+}
+
+func (c *consoleCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+
+	return checked
+}
+
+// Logger creates a logger that writes to stderr at the console level and, when a log file
+// is provided, writes info and debug messages to that file, rotating it using the given limits.
+// An empty format writes readable console logs to stderr and JSON to the log file, otherwise
+// every output uses the given format.
+func Logger(logFile, format string, level zapcore.Level, rotation LogRotation) (*zap.Logger, error) {
 	pe := zap.NewDevelopmentEncoderConfig()
 
 	fileEncoder := zapcore.NewJSONEncoder(pe)
 
 	pe.EncodeTime = zapcore.ISO8601TimeEncoder // The encoder can be customized for each output
 
-	// Console encoder (for stdout)
+	// Console encoder (for stderr)
 	consoleEncoder := zapcore.NewConsoleEncoder(pe)
 
-	// Create file core
-	file, err := os.Create(logFile)
-	if err != nil {
-		panic(err)
+	switch format {
+	case "":
+	case LogFormatJSON:
+		consoleEncoder = zapcore.NewJSONEncoder(pe)
+		fileEncoder = consoleEncoder
+	case LogFormatConsole:
+		fileEncoder = consoleEncoder
+	default:
+		return nil, fmt.Errorf("%w: %s", error_msgs.Err16, format)
 	}
 
-	fileCore := zapcore.NewCore(fileEncoder, zapcore.AddSync(file), zap.DebugLevel)
-
 	// Console core for errors
-	consoleCore := zapcore.NewCore(consoleEncoder, zapcore.AddSync(os.Stdout), zapcore.ErrorLevel)
+	var core zapcore.Core = &consoleCore{
+		Core:  zapcore.NewCore(consoleEncoder, zapcore.AddSync(os.Stderr), zap.DebugLevel),
+		level: level,
+	}
+
+	if logFile != "" {
+		// Check the log file can be written to before handing it to the rotating writer
+		file, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("could not open log file: %w", err)
+		}
+		file.Close()
+
+		writer := &lumberjack.Logger{
+			Filename:   logFile,
+			MaxSize:    rotation.MaxSize,
+			MaxAge:     rotation.MaxAge,
+			MaxBackups: rotation.MaxBackups,
+			Compress:   rotation.Compress,
+		}
+
+		// Create file core
+		fileCore := zapcore.NewCore(fileEncoder, zapcore.AddSync(writer), zap.DebugLevel)
+
+		// Combine the cores
+		core = zapcore.NewTee(fileCore, core)
+	}
 
-	// Combine the cores
-	core := zapcore.NewTee(fileCore, consoleCore)
-	// Create a logger with two cores
+	// Create a logger with the cores
 	logger := zap.New(core, zap.AddCaller())
 
+	return logger, nil
+}
+
+// ConsoleLogger creates a logger that only writes to stderr, this can not fail so it is
+// used as the default logger of each command before the flags have been parsed
+func ConsoleLogger() *zap.Logger {
+	logger, _ := Logger("", "", zap.ErrorLevel, DefaultLogRotation)
 	return logger
 }
 
-// ApplyExitOnHelp exits out of program if --help is flag
-func ApplyExitOnHelp(c *cobra.Command, exitCode int) {
-	helpFunc := c.HelpFunc()
-	c.SetHelpFunc(func(c *cobra.Command, s []string) {
-		helpFunc(c, s)
-		os.Exit(exitCode)
-	})
+// ConfigureLogger replaces the logger with one that writes to the console at the level of the
+// --log-level and -v flags, uses the --log-format, and also writes to a log file when file logging
+// has been enabled with --log-file or the PT_LOG_FILE environment variable
+func ConfigureLogger(cmd *cobra.Command, logger **zap.Logger) error {
+	level, err := consoleLevel(cmd)
+	if err != nil {
+		return err
+	}
+
+	format, err := cmd.Flags().GetString(LogFormatFlag)
+	if err != nil {
+		return err
+	}
+
+	if format == "" {
+		format = os.Getenv("PT_LOG_FORMAT")
+	}
+
+	logFile, err := cmd.Flags().GetString(LogFileFlag)
+	if err != nil {
+		return err
+	}
+
+	noLogFile, err := cmd.Flags().GetBool(NoLogFileFlag)
+	if err != nil {
+		return err
+	}
+
+	if logFile == "" {
+		logFile = os.Getenv("PT_LOG_FILE")
+	}
+
+	if noLogFile {
+		logFile = ""
+	}
+
+	if logFile == "" && format == "" {
+		// Only the console level of the logger changes, loggers that do not write to the console keep theirs
+		*logger = (*logger).WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			if console, ok := core.(*consoleCore); ok {
+				return &consoleCore{Core: console.Core, level: level}
+			}
+			return core
+		}))
+		return nil
+	}
+
+	rotation := DefaultLogRotation
+	if rotation.MaxSize, err = cmd.Flags().GetInt(LogMaxSizeFlag); err != nil {
+		return err
+	}
+	if rotation.MaxAge, err = cmd.Flags().GetInt(LogMaxAgeFlag); err != nil {
+		return err
+	}
+	if rotation.MaxBackups, err = cmd.Flags().GetInt(LogMaxBackupsFlag); err != nil {
+		return err
+	}
+	if rotation.Compress, err = cmd.Flags().GetBool(LogCompressFlag); err != nil {
+		return err
+	}
+
+	newLogger, err := Logger(logFile, format, level, rotation)
+	if err != nil {
+		return err
+	}
+
+	*logger = newLogger
+	return nil
+}
+
+// consoleLevel returns the level at which the run writes log messages to the console, an explicit
+// --log-level takes precedence over -v and --errors=json
+func consoleLevel(cmd *cobra.Command) (zapcore.Level, error) {
+	level, err := cmd.Flags().GetString(LogLevelFlag)
+	if err != nil {
+		return zap.ErrorLevel, err
+	}
+
+	verbosity, err := cmd.Flags().GetCount(VerboseFlag)
+	if err != nil {
+		return zap.ErrorLevel, err
+	}
+
+	if !cmd.Flags().Changed(LogLevelFlag) {
+		switch {
+		case verbosity >= 2:
+			level = "debug"
+		case verbosity == 1:
+			level = "info"
+		case ErrorsAsJSON(cmd):
+			// Keep stderr to the single JSON error object
+			level = "fatal"
+		}
+	}
+
+	return zapcore.ParseLevel(level)
 }
@@ -0,0 +1,157 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	error_msgs "github.com/UCLALibrary/pt-tools/pkg/error-msgs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// TestLogger tests if the logger only creates a log file when one is provided
+func TestLogger(t *testing.T) {
+	tests := []struct {
+		name       string
+		logFile    string
+		expectFile bool
+		expectErr  bool
+	}{
+		{name: "console only", logFile: "", expectFile: false, expectErr: false},
+		{name: "log file", logFile: "pt.log", expectFile: true, expectErr: false},
+		{name: "log file in missing directory", logFile: filepath.Join("missing", "pt.log"), expectErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			logFile := test.logFile
+			if logFile != "" {
+				logFile = filepath.Join(tempDir, logFile)
+			}
+
+			logger, err := Logger(logFile, "", zap.ErrorLevel, DefaultLogRotation)
+			if test.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			logger.Info("test message")
+			_ = logger.Sync()
+
+			if test.expectFile {
+				content, err := os.ReadFile(logFile)
+				require.NoError(t, err)
+				assert.Contains(t, string(content), "test message")
+			}
+
+			entries, err := os.ReadDir(tempDir)
+			require.NoError(t, err)
+			assert.Equal(t, test.expectFile, len(entries) == 1)
+		})
+	}
+}
+
+// TestConfigureLoggerLevel tests if each run writes to the console at the level of its own flags, and
+// that invalid levels are refused
+func TestConfigureLoggerLevel(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		expectLevel zapcore.Level
+		expectErr   bool
+	}{
+		{name: "default", args: []string{}, expectLevel: zap.ErrorLevel},
+		{name: "--log-level", args: []string{"--log-level", "warn"}, expectLevel: zap.WarnLevel},
+		{name: "-v", args: []string{"-v"}, expectLevel: zap.InfoLevel},
+		{name: "-vv", args: []string{"-vv"}, expectLevel: zap.DebugLevel},
+		{name: "--log-level over -v", args: []string{"-vv", "--log-level", "error"}, expectLevel: zap.ErrorLevel},
+		{name: "--errors=json", args: []string{"--errors", "json"}, expectLevel: zap.FatalLevel},
+		{name: "invalid level", args: []string{"--log-level", "loud"}, expectErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			cmd := NewRootCmd(io.Discard)
+			require.NoError(t, cmd.ParseFlags(test.args))
+
+			logger := ConsoleLogger()
+			err := ConfigureLogger(cmd, &logger)
+			if test.expectErr {
+				assert.Error(t, err)
+				assert.Equal(t, zap.ErrorLevel, logger.Level())
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectLevel, logger.Level())
+		})
+	}
+}
+
+// TestLoggerRotation tests if the log file is rotated once it grows past the maximum size
+func TestLoggerRotation(t *testing.T) {
+	tempDir := t.TempDir()
+	logFile := filepath.Join(tempDir, "pt.log")
+
+	logger, err := Logger(logFile, "", zap.ErrorLevel, LogRotation{MaxSize: 1, MaxBackups: 1})
+	require.NoError(t, err)
+
+	// Write a little over two megabytes so the log file is rotated twice
+	message := strings.Repeat("x", 1024)
+	for i := 0; i < 2100; i++ {
+		logger.Info(message)
+	}
+	_ = logger.Sync()
+
+	// Wait for the background removal of the oldest backup to finish
+	assert.Eventually(t, func() bool {
+		entries, err := os.ReadDir(tempDir)
+		return err == nil && len(entries) == 2
+	}, 5*time.Second, 10*time.Millisecond, "expected the log file and a single backup")
+}
+
+// TestLoggerFormat tests if the log file is written in the selected format
+func TestLoggerFormat(t *testing.T) {
+	tests := []struct {
+		name      string
+		format    string
+		expectErr error
+		isJSON    bool
+	}{
+		{name: "default", format: "", isJSON: true},
+		{name: "json", format: LogFormatJSON, isJSON: true},
+		{name: "console", format: LogFormatConsole, isJSON: false},
+		{name: "unknown", format: "xml", expectErr: error_msgs.Err16},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			logFile := filepath.Join(t.TempDir(), "pt.log")
+
+			logger, err := Logger(logFile, test.format, zap.ErrorLevel, DefaultLogRotation)
+			if test.expectErr != nil {
+				assert.ErrorIs(t, err, test.expectErr)
+				return
+			}
+			require.NoError(t, err)
+
+			logger.Info("test message")
+			_ = logger.Sync()
+
+			content, err := os.ReadFile(logFile)
+			require.NoError(t, err)
+			assert.Equal(t, test.isJSON, json.Valid(bytes.TrimSpace(content)))
+		})
+	}
+}
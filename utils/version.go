@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// Build metadata that is set at build time with ldflags, for example
+//
+//	go build -ldflags "-X github.com/UCLALibrary/pt-tools/utils.Version=v1.2.0"
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildDate = ""
+)
+
+// BuildInfo describes the build of pt that is running
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildDate string `json:"buildDate,omitempty"`
+	GoVersion string `json:"goVersion,omitempty"`
+	Platform  string `json:"platform,omitempty"`
+}
+
+// GetBuildInfo returns the build metadata, using the VCS details the Go toolchain records in
+// the binary when they were not set with ldflags
+func GetBuildInfo() BuildInfo {
+	info := BuildInfo{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+
+	if buildInfo, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range buildInfo.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.BuildDate == "":
+				info.BuildDate = setting.Value
+			}
+		}
+	}
+
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+
+	if info.BuildDate == "" {
+		info.BuildDate = "unknown"
+	}
+
+	return info
+}
+
+// String formats the build metadata with one detail on each line
+func (b BuildInfo) String() string {
+	return fmt.Sprintf("pt version %s\ncommit: %s\nbuilt: %s\ngo: %s\nplatform: %s\n",
+		b.Version, b.Commit, b.BuildDate, b.GoVersion, b.Platform)
+}